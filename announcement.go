@@ -0,0 +1,163 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrEventMismatch is returned by VerifyAttestation when the
+	// attestation is for another event than the announcement
+	ErrEventMismatch = errors.New("attestation is for another event")
+
+	// ErrInvalidOutcome is returned by VerifyAttestation when the signed
+	// message isn't a possible outcome of the announced event
+	ErrInvalidOutcome = errors.New("attested message is not a possible outcome")
+
+	// ErrInvalidAttestation is returned by VerifyAttestation when the
+	// signature doesn't match the announced R point
+	ErrInvalidAttestation = errors.New("invalid attestation signature")
+)
+
+// announcementTag separates announcement signatures from any other
+// message signed with the oracle's key
+const announcementTag = "DLC/oracle/announcement"
+
+// Announcement is the oracle's public commitment to attest to the outcome
+// of an event. It contains the R point that will be used to sign the
+// outcome, so contract participants can compute the signature pubkeys for
+// every possible outcome beforehand. The oracle signs the announcement so
+// the R point can't be swapped out on its way to the participants.
+type Announcement struct {
+	EventID      string
+	OraclePubKey [33]byte
+	RPoint       [33]byte
+	Maturity     time.Time
+
+	// RPoints are the R points of each digit of a digits event, most
+	// significant first, see DigitRPoints. RPoint is the first of them.
+	RPoints [][33]byte
+
+	// MaturityHeight is the Bitcoin block height the event matures at,
+	// or zero for events maturing at Maturity
+	MaturityHeight uint32
+
+	Descriptor EventDescriptor
+	Metadata   EventMetadata
+	Signature  [65]byte
+}
+
+// SigningHash returns the digest of the announcement's contents that the
+// oracle signs. Maturity is committed to with a precision of seconds.
+// Variable length fields are length prefixed so no two announcements
+// share an encoding.
+func (a Announcement) SigningHash() [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	h.Write([]byte(announcementTag))
+	binary.BigEndian.PutUint64(buf[:], uint64(len(a.EventID)))
+	h.Write(buf[:])
+	h.Write([]byte(a.EventID))
+	h.Write(a.OraclePubKey[:])
+	h.Write(a.RPoint[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(a.Maturity.Unix()))
+	h.Write(buf[:])
+	binary.BigEndian.PutUint32(buf[:4], a.MaturityHeight)
+	h.Write(buf[:4])
+	writeDescriptor(h, a.Descriptor)
+	if len(a.RPoints) > 0 {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(a.RPoints)))
+		h.Write(buf[:])
+		for _, R := range a.RPoints {
+			h.Write(R[:])
+		}
+	}
+	// Only written if set, after a byte that can't start the descriptor's
+	// optional fields or the R points, so earlier announcements keep
+	// their hashes
+	if !a.Metadata.IsZero() {
+		h.Write([]byte{2})
+		writeMetadata(h, a.Metadata)
+	}
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// Event returns the event the announcement is for
+func (a Announcement) Event() Event {
+	return Event{
+		ID:             a.EventID,
+		Maturity:       a.Maturity,
+		MaturityHeight: a.MaturityHeight,
+		Descriptor:     a.Descriptor,
+		Metadata:       a.Metadata,
+	}
+}
+
+// Verify checks the oracle's signature on the announcement
+func (a Announcement) Verify() error {
+	digest := a.SigningHash()
+	err := VerifyMessage(a.OraclePubKey, digest[:], a.Signature)
+	if err != nil {
+		return fmt.Errorf("announcement of %s: %w", a.EventID, err)
+	}
+	return nil
+}
+
+// Attestation is the oracle's signature over the outcome of an event
+// it has announced before.
+type Attestation struct {
+	EventID   string
+	Message   []byte
+	Signature [32]byte
+
+	// Signatures are the signatures of each digit of a digits event, in
+	// the order of Announcement.RPoints. Signature is the first of them.
+	Signatures [][32]byte
+}
+
+// VerifyAttestation checks that att attests to a possible outcome of the
+// event announced in a, signed with the announced R point, and returns
+// that outcome. The errors wrap ErrEventMismatch, ErrInvalidRecord for a
+// malformed descriptor or mismatched nonces and signatures,
+// ErrInvalidOutcome or ErrInvalidAttestation. The announcement's
+// signature is not checked; call a.Verify first if it comes from an
+// untrusted source.
+func VerifyAttestation(a Announcement, att Attestation) (Outcome, error) {
+	if att.EventID != a.EventID {
+		return Outcome{}, fmt.Errorf("%w: attestation of %q, announcement of %q",
+			ErrEventMismatch, att.EventID, a.EventID)
+	}
+	err := a.Descriptor.Validate()
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: event %q: %v", ErrInvalidRecord, a.EventID, err)
+	}
+	err = a.checkNonces()
+	if err != nil {
+		return Outcome{}, err
+	}
+	outcome, err := a.Descriptor.ParseOutcome(att.Message)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: %s event %q: %v", ErrInvalidOutcome,
+			a.Descriptor.Type, a.EventID, err)
+	}
+	if a.Descriptor.Type == EventTypeDigits {
+		if len(att.Message) == 0 || len(att.Signatures) != len(att.Message) || len(a.RPoints) != len(att.Message) {
+			return Outcome{}, fmt.Errorf("%w: %d digits with %d r points and %d signatures", ErrInvalidRecord,
+				len(att.Message), len(a.RPoints), len(att.Signatures))
+		}
+		err = verifyDigits(a, att)
+	} else {
+		err = VerifySignature(a.OraclePubKey, a.RPoint, att.Message, att.Signature)
+	}
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: event %q, r point %x: %v", ErrInvalidAttestation,
+			a.EventID, a.RPoint, err)
+	}
+	return outcome, nil
+}
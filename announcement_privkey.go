@@ -0,0 +1,44 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "fmt"
+
+// Sign signs the announcement with the oracle's private key, which must
+// match OraclePubKey. The options are passed on to SignMessage.
+func (a *Announcement) Sign(privKey [32]byte, opts ...SignOption) error {
+	if PublicKeyFromPrivateKey(privKey) != a.OraclePubKey {
+		return fmt.Errorf("private key does not match oracle pubkey")
+	}
+	digest := a.SigningHash()
+	sig, err := SignMessage(privKey, digest[:], opts...)
+	if err != nil {
+		return err
+	}
+	a.Signature = sig
+	return nil
+}
+
+// NewAnnouncement returns the signed announcement of ev, committing to
+// the R points of EventRPoints: the R point of oneTimeSigningKey, or for
+// digits events the R points of the digit keys derived from it
+func NewAnnouncement(privKey, oneTimeSigningKey [32]byte, ev Event) (Announcement, error) {
+	a := Announcement{
+		EventID:        ev.ID,
+		OraclePubKey:   PublicKeyFromPrivateKey(privKey),
+		Maturity:       ev.Maturity,
+		MaturityHeight: ev.MaturityHeight,
+		Descriptor:     ev.Descriptor,
+		Metadata:       ev.Metadata,
+	}
+	points, err := EventRPoints(oneTimeSigningKey, ev.Descriptor)
+	if err != nil {
+		return a, err
+	}
+	a.RPoint = points[0]
+	if ev.Descriptor.Type == EventTypeDigits {
+		a.RPoints = points
+	}
+	err = a.Sign(privKey)
+	return a, err
+}
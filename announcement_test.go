@@ -0,0 +1,130 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func testAnnouncement(t *testing.T) (Announcement, [32]byte) {
+	var priv [32]byte
+	priv[31] = 1
+	k, err := GenerateOneTimeSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Announcement{
+		EventID:      "event",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       PublicKeyFromPrivateKey(k),
+		Maturity:     time.Unix(1000, 0).UTC(),
+	}
+	err = a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, priv
+}
+
+func TestAnnouncementSignature(t *testing.T) {
+	a, _ := testAnnouncement(t)
+	err := a.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A swapped R point must be detected
+	swapped := a
+	swapped.RPoint = a.OraclePubKey
+	if swapped.Verify() == nil {
+		t.Fatal("announcement with swapped R point verified")
+	}
+
+	swapped = a
+	swapped.Maturity = a.Maturity.Add(time.Hour)
+	if swapped.Verify() == nil {
+		t.Fatal("announcement with changed maturity verified")
+	}
+}
+
+func TestAnnouncementSignWrongKey(t *testing.T) {
+	a, _ := testAnnouncement(t)
+	var other [32]byte
+	other[31] = 2
+	if a.Sign(other) == nil {
+		t.Fatal("signed announcement with a foreign key")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	k, err := GenerateOneTimeSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := GenerateNumericMessage(42)
+	sig, err := ComputeSignature(priv, k, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := PublicKeyFromPrivateKey(priv)
+	R := PublicKeyFromPrivateKey(k)
+	err = VerifySignature(pub, R, msg, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifySignature(pub, R, GenerateNumericMessage(43), sig) == nil {
+		t.Fatal("signature verified for another message")
+	}
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	k, err := DeriveOneTimeSigningKey(priv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Announcement{
+		EventID:      "event",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       PublicKeyFromPrivateKey(k),
+		Descriptor:   EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	attest := func(msg string) Attestation {
+		sig, err := ComputeSignature(priv, k, []byte(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Attestation{EventID: "event", Message: []byte(msg), Signature: sig}
+	}
+
+	att := attest("yes")
+	outcome, err := VerifyAttestation(a, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Label != "yes" {
+		t.Fatalf("outcome %+v", outcome)
+	}
+
+	other := att
+	other.EventID = "other"
+	_, err = VerifyAttestation(a, other)
+	if !errors.Is(err, ErrEventMismatch) {
+		t.Fatalf("expected ErrEventMismatch, got %v", err)
+	}
+	_, err = VerifyAttestation(a, attest("maybe"))
+	if !errors.Is(err, ErrInvalidOutcome) {
+		t.Fatalf("expected ErrInvalidOutcome, got %v", err)
+	}
+	forged := att
+	forged.Message = []byte("no")
+	_, err = VerifyAttestation(a, forged)
+	if !errors.Is(err, ErrInvalidAttestation) {
+		t.Fatalf("expected ErrInvalidAttestation, got %v", err)
+	}
+}
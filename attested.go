@@ -0,0 +1,91 @@
+package dlcoracle
+
+import "fmt"
+
+// AttestedNonce is one signature of an attestation with what it signs:
+// Message, under the R point at Index of Announcement.NoncePoints. Value
+// is the message in readable form, the digit for digits events and the
+// whole outcome for others.
+type AttestedNonce struct {
+	Index     int
+	RPoint    [33]byte
+	Value     string
+	Message   []byte
+	Signature [32]byte
+}
+
+// NewAttestation assembles the attestation of outcome as the result of
+// the event announced in a from its raw signatures, the results of
+// ComputeSignature with each R point of a.NoncePoints in order. The
+// signatures aren't checked; VerifyAttestation does that.
+func NewAttestation(a Announcement, outcome Outcome, sigs [][32]byte) (Attestation, error) {
+	msg, err := a.Descriptor.OutcomeMessage(outcome)
+	if err != nil {
+		return Attestation{}, err
+	}
+	if n := len(a.NoncePoints()); len(sigs) != n {
+		return Attestation{}, fmt.Errorf("%d signatures for the %d r points of %s", len(sigs), n, a.EventID)
+	}
+	att := Attestation{EventID: a.EventID, Message: msg, Signature: sigs[0]}
+	if a.Descriptor.Type == EventTypeDigits {
+		att.Signatures = append([][32]byte(nil), sigs...)
+	}
+	return att, nil
+}
+
+// NonceSignatures returns the attestation's raw signatures in the order
+// of Announcement.NoncePoints: Signatures if it has them, otherwise just
+// Signature
+func (att Attestation) NonceSignatures() [][32]byte {
+	if len(att.Signatures) > 0 {
+		return att.Signatures
+	}
+	return [][32]byte{att.Signature}
+}
+
+// Nonces splits the attestation of the event announced in a into its
+// signatures, each with its R point and the message it signs. The errors
+// wrap the same errors as VerifyAttestation's, including ErrInvalidRecord
+// for R points not matching the descriptor or the digits, but the
+// signatures aren't verified.
+func (att Attestation) Nonces(a Announcement) ([]AttestedNonce, error) {
+	if att.EventID != a.EventID {
+		return nil, fmt.Errorf("%w: attestation of %q, announcement of %q",
+			ErrEventMismatch, att.EventID, a.EventID)
+	}
+	err := a.Descriptor.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: event %q: %v", ErrInvalidRecord, a.EventID, err)
+	}
+	err = a.checkNonces()
+	if err != nil {
+		return nil, err
+	}
+	outcome, err := a.Descriptor.ParseOutcome(att.Message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s event %q: %v", ErrInvalidOutcome,
+			a.Descriptor.Type, a.EventID, err)
+	}
+	points, sigs := a.NoncePoints(), att.NonceSignatures()
+	if len(sigs) != len(points) {
+		return nil, fmt.Errorf("%w: event %q: %d signatures for %d r points",
+			ErrInvalidAttestation, a.EventID, len(sigs), len(points))
+	}
+	digits := a.Descriptor.Type == EventTypeDigits
+	if digits && len(points) != len(att.Message) {
+		return nil, fmt.Errorf("%w: event %q: %d digits for %d r points",
+			ErrInvalidRecord, a.EventID, len(att.Message), len(points))
+	}
+	nonces := make([]AttestedNonce, len(points))
+	for i := range points {
+		n := AttestedNonce{Index: i, RPoint: points[i], Message: att.Message, Signature: sigs[i]}
+		if digits {
+			n.Message = DigitMessage(att.Message[i])
+			n.Value = string(n.Message)
+		} else {
+			n.Value = a.Descriptor.FormatOutcome(outcome)
+		}
+		nonces[i] = n
+	}
+	return nonces, nil
+}
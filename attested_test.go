@@ -0,0 +1,89 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttestedNonces(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 42
+	k, _ := DeriveOneTimeSigningKey(priv, 5)
+	d := EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 3, Unit: "usd/btc"}
+	a, err := NewAnnouncement(priv, k, Event{ID: "price", Maturity: time.Unix(1000, 0), Descriptor: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// raw signatures of each digit of 421, in the order of the r points
+	keys, _ := EventSigningKeys(k, d)
+	var sigs [][32]byte
+	for i, digit := range []byte{4, 2, 1} {
+		sig, err := ComputeSignature(priv, keys[i], DigitMessage(digit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+	att, err := NewAttestation(a, Outcome{Value: 421}, sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, _ := SignOutcome(priv, k, a, Outcome{Value: 421})
+	if att.Signature != signed.Signature || len(att.Signatures) != 3 || att.Signatures[2] != signed.Signatures[2] {
+		t.Fatal("assembled attestation differs from SignOutcome's")
+	}
+	_, err = VerifyAttestation(a, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewAttestation(a, Outcome{Value: 421}, sigs[:2])
+	if err == nil {
+		t.Fatal("assembled an attestation with a missing signature")
+	}
+
+	nonces, err := att.Nonces(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range nonces {
+		if n.Index != i || n.RPoint != a.RPoints[i] || n.Signature != sigs[i] || n.Value != "421"[i:i+1] {
+			t.Fatalf("nonce %d: %+v", i, n)
+		}
+		err = VerifySignature(a.OraclePubKey, n.RPoint, n.Message, n.Signature)
+		if err != nil {
+			t.Fatalf("nonce %d: %v", i, err)
+		}
+	}
+
+	// R points not matching the digits are rejected rather than indexed
+	short := a
+	short.Descriptor.Digits = 2
+	_, err = Attestation{EventID: "price", Message: []byte{4, 2}, Signatures: sigs}.Nonces(short)
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord for 3 r points of 2 digits, got %v", err)
+	}
+	short.Descriptor.Digits, short.RPoints = 0, nil
+	_, err = Attestation{EventID: "price"}.Nonces(short)
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord for no digits, got %v", err)
+	}
+
+	enum := EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	b, _ := NewAnnouncement(priv, k, Event{ID: "rain", Maturity: time.Unix(1000, 0), Descriptor: enum})
+	battr, _ := SignOutcome(priv, k, b, Outcome{Label: "yes"})
+	nonces, err = battr.Nonces(b)
+	if err != nil || len(nonces) != 1 || nonces[0].Value != "yes" || nonces[0].RPoint != b.RPoint {
+		t.Fatalf("enum nonces %+v %v", nonces, err)
+	}
+	if len(battr.NonceSignatures()) != 1 || battr.NonceSignatures()[0] != battr.Signature {
+		t.Fatal("unexpected raw signatures")
+	}
+	_, err = battr.Nonces(a)
+	if !errors.Is(err, ErrEventMismatch) {
+		t.Fatalf("split the attestation of another event: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+// Package audit keeps an append-only log of every signature an oracle
+// produces. Each entry commits to the hash of the one before it, so
+// entries can't be removed, reordered or changed without breaking the
+// chain, and the log proves exactly what the oracle's key has signed.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kind is the kind of object a signature was produced for
+type Kind string
+
+// The kinds of signatures an oracle produces
+const (
+	KindAnnouncement     Kind = "announcement"
+	KindAttestation      Kind = "attestation"
+	KindRevocation       Kind = "revocation"
+	KindECDSAAttestation Kind = "ecdsa-attestation"
+	KindEvidence         Kind = "evidence"
+)
+
+// hashTag separates the hashes of entries from other hashes
+const hashTag = "DLC/oracle/audit"
+
+var (
+	// ErrBrokenChain is returned when an entry of a log doesn't follow
+	// the entry before it
+	ErrBrokenChain = errors.New("audit log chain is broken")
+
+	// ErrClosed is returned when appending to a closed log
+	ErrClosed = errors.New("audit log is closed")
+)
+
+// Entry records one signature. Message is what was signed: the signing
+// hash of announcements and revocations and the outcome message of
+// attestations. The signatures of digits attestations are concatenated.
+type Entry struct {
+	Seq       uint64
+	Time      time.Time
+	Kind      Kind
+	EventID   string
+	Message   []byte
+	Signature []byte
+
+	// Prev is the hash of the previous entry, zero for the first one
+	Prev [32]byte
+	Hash [32]byte
+}
+
+// ComputeHash returns the hash of the entry, committing to all its fields
+// but Hash
+func (e Entry) ComputeHash() [32]byte {
+	h := sha256.New()
+	h.Write([]byte(hashTag))
+	h.Write(e.Prev[:])
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], e.Seq)
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(e.Time.UnixNano()))
+	h.Write(buf[:])
+	writeBytes(h, []byte(e.Kind))
+	writeBytes(h, []byte(e.EventID))
+	writeBytes(h, e.Message)
+	writeBytes(h, e.Signature)
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// writeBytes writes b prefixed with its length
+func writeBytes(h hash.Hash, b []byte) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(b)))
+	h.Write(buf[:])
+	h.Write(b)
+}
+
+type entryJSON struct {
+	Seq       uint64 `json:"seq"`
+	Time      string `json:"time"`
+	Kind      Kind   `json:"kind"`
+	EventID   string `json:"eventId"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	Prev      string `json:"prev"`
+	Hash      string `json:"hash"`
+}
+
+// MarshalJSON encodes the entry with hex encoded bytes
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(entryJSON{
+		Seq:       e.Seq,
+		Time:      e.Time.UTC().Format(time.RFC3339Nano),
+		Kind:      e.Kind,
+		EventID:   e.EventID,
+		Message:   hex.EncodeToString(e.Message),
+		Signature: hex.EncodeToString(e.Signature),
+		Prev:      hex.EncodeToString(e.Prev[:]),
+		Hash:      hex.EncodeToString(e.Hash[:]),
+	})
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var j entryJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	e.Seq, e.Kind, e.EventID = j.Seq, j.Kind, j.EventID
+	e.Time, err = time.Parse(time.RFC3339Nano, j.Time)
+	if err != nil {
+		return err
+	}
+	e.Message, err = hex.DecodeString(j.Message)
+	if err != nil {
+		return fmt.Errorf("message: %v", err)
+	}
+	e.Signature, err = hex.DecodeString(j.Signature)
+	if err != nil {
+		return fmt.Errorf("signature: %v", err)
+	}
+	for _, f := range []struct {
+		name string
+		s    string
+		dst  *[32]byte
+	}{{"prev", j.Prev, &e.Prev}, {"hash", j.Hash, &e.Hash}} {
+		b, err := hex.DecodeString(f.s)
+		if err != nil || len(b) != 32 {
+			return fmt.Errorf("%s must be 32 hex encoded bytes", f.name)
+		}
+		copy(f.dst[:], b)
+	}
+	return nil
+}
+
+// Verify reads a log of JSON encoded entries, one per line, and checks
+// that every entry follows the one before it. It returns the number of
+// entries and the last one. Errors about the chain wrap ErrBrokenChain.
+func Verify(r io.Reader) (int, Entry, error) {
+	return verify(r, func(Entry) {})
+}
+
+// verify implements Verify, calling fn with each entry that follows the
+// one before it
+func verify(r io.Reader, fn func(Entry)) (int, Entry, error) {
+	var last Entry
+	n := 0
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var e Entry
+		err := json.Unmarshal(sc.Bytes(), &e)
+		if err != nil {
+			return n, last, fmt.Errorf("entry %d: %v", n, err)
+		}
+		switch {
+		case e.Seq != uint64(n):
+			return n, last, fmt.Errorf("%w: entry %d has sequence number %d", ErrBrokenChain, n, e.Seq)
+		case n > 0 && e.Prev != last.Hash:
+			return n, last, fmt.Errorf("%w: entry %d doesn't link to the entry before it", ErrBrokenChain, n)
+		case n == 0 && e.Prev != [32]byte{}:
+			return n, last, fmt.Errorf("%w: first entry links to a previous one", ErrBrokenChain)
+		case e.ComputeHash() != e.Hash:
+			return n, last, fmt.Errorf("%w: entry %d doesn't match its hash", ErrBrokenChain, n)
+		}
+		fn(e)
+		last = e
+		n++
+	}
+	return n, last, sc.Err()
+}
+
+// Log is an audit log appended to a file
+type Log struct {
+	mtx    sync.Mutex
+	f      *os.File
+	next   uint64
+	prev   [32]byte
+	closed bool
+
+	// attestations are the entries of the attestations in the log, by
+	// event ID
+	attestations map[string]Entry
+}
+
+// Open opens the log at path, creating it if it doesn't exist. The
+// existing entries are verified first: a log whose chain is broken isn't
+// appended to. A last entry cut off by a crash while it was written is
+// dropped, as Append never returned it.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = dropTornEntry(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l := &Log{f: f, attestations: make(map[string]Entry)}
+	n, last, err := verify(f, l.index)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l.next, l.prev = uint64(n), last.Hash
+	return l, nil
+}
+
+// dropTornEntry truncates f after its last complete line and leaves it
+// positioned at its start
+func dropTornEntry(f *os.File) error {
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	if end := bytes.LastIndexByte(b, '\n') + 1; end != len(b) {
+		err = f.Truncate(int64(end))
+		if err != nil {
+			return err
+		}
+		err = f.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// index records the entry if it is an attestation
+func (l *Log) index(e Entry) {
+	if e.Kind == KindAttestation {
+		l.attestations[e.EventID] = e
+	}
+}
+
+// Attestation returns the entry recording the attestation of an event,
+// if the log has one
+func (l *Log) Attestation(eventID string) (Entry, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	e, ok := l.attestations[eventID]
+	return e, ok
+}
+
+// Attestations returns the entries recording attestations, in no
+// particular order
+func (l *Log) Attestations() []Entry {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	list := make([]Entry, 0, len(l.attestations))
+	for _, e := range l.attestations {
+		list = append(list, e)
+	}
+	return list
+}
+
+// Close waits for an entry being appended, syncs the log and closes its
+// file. Appending afterwards fails with ErrClosed.
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	err := l.f.Sync()
+	closeErr := l.f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Append records a signature, and returns once the entry is synced to
+// disk
+func (l *Log) Append(kind Kind, eventID string, message, signature []byte) (Entry, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.closed {
+		return Entry{}, ErrClosed
+	}
+	e := Entry{
+		Seq:       l.next,
+		Time:      time.Now().UTC(),
+		Kind:      kind,
+		EventID:   eventID,
+		Message:   message,
+		Signature: signature,
+	}
+	if e.Seq > 0 {
+		e.Prev = l.prev
+	}
+	e.Hash = e.ComputeHash()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	_, err = l.f.Write(append(b, '\n'))
+	if err != nil {
+		return e, err
+	}
+	err = l.f.Sync()
+	if err != nil {
+		return e, err
+	}
+	l.next++
+	l.prev = e.Hash
+	l.index(e)
+	return e, nil
+}
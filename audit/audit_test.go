@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := l.Append(KindAnnouncement, "event", []byte{1}, []byte{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// Reopening continues the chain
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := l.Append(KindAttestation, "event", []byte{3}, []byte{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if second.Seq != 1 || second.Prev != first.Hash {
+		t.Fatalf("entry %+v doesn't follow %+v", second, first)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, last, err := Verify(bytes.NewReader(b))
+	if err != nil || n != 2 || last.Hash != second.Hash {
+		t.Fatalf("unexpected verification %d %+v %v", n, last, err)
+	}
+
+	// Rewriting what was signed breaks the chain
+	tampered := bytes.Replace(b, []byte(`"message":"03"`), []byte(`"message":"05"`), 1)
+	_, _, err = Verify(bytes.NewReader(tampered))
+	if !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("expected ErrBrokenChain, got %v", err)
+	}
+	// and so does removing an entry
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	_, _, err = Verify(bytes.NewReader(lines[1]))
+	if !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("expected ErrBrokenChain, got %v", err)
+	}
+	os.WriteFile(path, tampered, 0600)
+	_, err = Open(path)
+	if !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("opened a broken log: %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := l.Append(KindAttestation, "event", []byte{3}, []byte{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = l.Append(KindAttestation, "other", []byte{5}, []byte{6})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	// A crash while an entry was written leaves part of it, which is
+	// dropped on reopening
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":1,"kind":"attestation","eventId":"oth`)
+	f.Close()
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got, ok := l.Attestation("event")
+	if !ok || got.Hash != want.Hash {
+		t.Fatalf("attestation entry %+v %v", got, ok)
+	}
+	if _, ok := l.Attestation("other"); ok {
+		t.Fatal("indexed the torn entry")
+	}
+	next, err := l.Append(KindAttestation, "other", []byte{5}, []byte{6})
+	if err != nil || next.Seq != 1 || next.Prev != want.Hash {
+		t.Fatalf("appended %+v %v", next, err)
+	}
+	if len(l.Attestations()) != 2 {
+		t.Fatalf("attestations %+v", l.Attestations())
+	}
+}
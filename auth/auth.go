@@ -0,0 +1,273 @@
+// Package auth issues and checks the tokens that authorize calls to an
+// oracle's administrative RPCs.
+//
+// Tokens work like macaroons: a token carries a list of caveats, such as
+// the scopes it grants or its expiry, chained into an HMAC that starts from
+// the authority's root key. Anyone holding a token can attenuate it by
+// adding caveats, for instance to hand a read-only or short-lived token to
+// a monitoring job, but nobody can remove caveats without the root key.
+//
+// Roles name the usual sets of scopes: viewer, event-creator, attester
+// and admin. A Keyring holds API keys, each with a role, that can be
+// created, changed and revoked while the oracle runs.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tokenPrefix marks the token format and its version
+const tokenPrefix = "dlco1_"
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or were
+	// not issued by the authority
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrExpired is returned for tokens past their expiry caveat
+	ErrExpired = errors.New("token expired")
+
+	// ErrPermissionDenied is returned when a valid token lacks the
+	// required scope
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Scope is a set of permissions
+type Scope uint8
+
+const (
+	// ScopeRead allows reading announcements and attestations where
+	// reads aren't public
+	ScopeRead Scope = 1 << iota
+	// ScopeCreateEvent allows announcing new events
+	ScopeCreateEvent
+	// ScopeAttest allows signing outcomes manually
+	ScopeAttest
+	// ScopeAdmin allows everything, including managing the oracle
+	ScopeAdmin
+
+	// ScopeAll is every scope
+	ScopeAll = ScopeRead | ScopeCreateEvent | ScopeAttest | ScopeAdmin
+)
+
+var scopeNames = []struct {
+	scope Scope
+	name  string
+}{
+	{ScopeRead, "read"},
+	{ScopeCreateEvent, "create-event"},
+	{ScopeAttest, "attest"},
+	{ScopeAdmin, "admin"},
+}
+
+// Has reports whether s includes all of required. ScopeAdmin includes
+// every other scope.
+func (s Scope) Has(required Scope) bool {
+	if s&ScopeAdmin != 0 {
+		return true
+	}
+	return s&required == required
+}
+
+// String returns the comma separated names of the scopes in s
+func (s Scope) String() string {
+	var names []string
+	for _, n := range scopeNames {
+		if s&n.scope != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ParseScope parses comma separated scope names as returned by String
+func ParseScope(str string) (Scope, error) {
+	var s Scope
+	if str == "" {
+		return s, nil
+	}
+	for _, name := range strings.Split(str, ",") {
+		found := false
+		for _, n := range scopeNames {
+			if strings.TrimSpace(name) == n.name {
+				s |= n.scope
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown scope %q", name)
+		}
+	}
+	return s, nil
+}
+
+// Caveats restricting a token. Every caveat is "<kind>=<value>".
+const (
+	caveatScope   = "scope"
+	caveatExpires = "expires"
+)
+
+// ScopeCaveat restricts a token to scope
+func ScopeCaveat(scope Scope) string {
+	return caveatScope + "=" + scope.String()
+}
+
+// ExpiryCaveat makes a token invalid after t
+func ExpiryCaveat(t time.Time) string {
+	return caveatExpires + "=" + strconv.FormatInt(t.Unix(), 10)
+}
+
+type token struct {
+	ID      string   `json:"id"`
+	Caveats []string `json:"caveats"`
+	MAC     string   `json:"mac"`
+}
+
+func (t *token) encode() string {
+	b, _ := json.Marshal(t)
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeToken(s string) (*token, error) {
+	if !strings.HasPrefix(s, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, tokenPrefix))
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var t token
+	err = json.Unmarshal(b, &t)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &t, nil
+}
+
+func chain(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Attenuate returns tok with additional caveats. It needs no secret, so
+// any holder of a token can restrict it further before passing it on.
+func Attenuate(tok string, caveats ...string) (string, error) {
+	t, err := decodeToken(tok)
+	if err != nil {
+		return "", err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	for _, c := range caveats {
+		mac = chain(mac, c)
+		t.Caveats = append(t.Caveats, c)
+	}
+	t.MAC = hex.EncodeToString(mac)
+	return t.encode(), nil
+}
+
+// Authority issues tokens and verifies them with its root key
+type Authority struct {
+	rootKey [32]byte
+}
+
+// NewAuthority returns an authority with the given root key. Changing the
+// root key revokes all tokens issued with the old one.
+func NewAuthority(rootKey [32]byte) *Authority {
+	return &Authority{rootKey: rootKey}
+}
+
+// Issue returns a new token with the given caveats. A token without a
+// scope caveat grants every scope.
+func (a *Authority) Issue(caveats ...string) (string, error) {
+	var id [16]byte
+	_, err := rand.Read(id[:])
+	if err != nil {
+		return "", err
+	}
+	t := &token{ID: hex.EncodeToString(id[:])}
+	t.MAC = hex.EncodeToString(chain(a.rootKey[:], t.ID))
+	return Attenuate(t.encode(), caveats...)
+}
+
+// Verify checks tok and returns the scopes it grants
+func (a *Authority) Verify(tok string) (Scope, error) {
+	t, err := decodeToken(tok)
+	if err != nil {
+		return 0, err
+	}
+	mac := chain(a.rootKey[:], t.ID)
+	for _, c := range t.Caveats {
+		mac = chain(mac, c)
+	}
+	given, err := hex.DecodeString(t.MAC)
+	if err != nil || !hmac.Equal(mac, given) {
+		return 0, ErrInvalidToken
+	}
+
+	scope := ScopeAll
+	for _, c := range t.Caveats {
+		kind, value, ok := strings.Cut(c, "=")
+		if !ok {
+			return 0, ErrInvalidToken
+		}
+		switch kind {
+		case caveatScope:
+			s, err := ParseScope(value)
+			if err != nil {
+				return 0, ErrInvalidToken
+			}
+			// Caveats only ever narrow a token down
+			scope = restrict(scope, s)
+		case caveatExpires:
+			exp, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return 0, ErrInvalidToken
+			}
+			if time.Now().Unix() >= exp {
+				return 0, ErrExpired
+			}
+		default:
+			// Unknown caveats can't be checked, so the token is refused
+			return 0, ErrInvalidToken
+		}
+	}
+	return scope, nil
+}
+
+// restrict intersects two sets of scopes, expanding ScopeAdmin in either
+// of them to every scope
+func restrict(a, b Scope) Scope {
+	if a&ScopeAdmin != 0 {
+		a = ScopeAll
+	}
+	if b&ScopeAdmin != 0 {
+		b = ScopeAll
+	}
+	return a & b
+}
+
+// Authorize verifies tok and checks that it grants required
+func (a *Authority) Authorize(tok string, required Scope) error {
+	scope, err := a.Verify(tok)
+	if err != nil {
+		return err
+	}
+	if !scope.Has(required) {
+		return fmt.Errorf("token grants %s, need %s: %w", scope, required, ErrPermissionDenied)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopes(t *testing.T) {
+	s, err := ParseScope("read, attest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != ScopeRead|ScopeAttest || s.String() != "read,attest" {
+		t.Fatalf("unexpected scope %s", s)
+	}
+	if s.Has(ScopeCreateEvent) || !s.Has(ScopeAttest) || !ScopeAdmin.Has(ScopeCreateEvent) {
+		t.Fatal("wrong scope checks")
+	}
+	_, err = ParseScope("root")
+	if err == nil {
+		t.Fatal("parsed unknown scope")
+	}
+}
+
+func TestIssueAndAttenuate(t *testing.T) {
+	a := NewAuthority([32]byte{1})
+	tok, err := a.Issue(ScopeCaveat(ScopeCreateEvent | ScopeAttest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Authorize(tok, ScopeAttest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Authorize(tok, ScopeAdmin)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+
+	// Attenuating without the root key narrows the token down
+	narrow, err := Attenuate(tok, ScopeCaveat(ScopeCreateEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := a.Verify(narrow)
+	if err != nil || s != ScopeCreateEvent {
+		t.Fatalf("unexpected scope %s %v", s, err)
+	}
+	// A caveat can't widen the scope again
+	wide, err := Attenuate(narrow, ScopeCaveat(ScopeAdmin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = a.Verify(wide)
+	if err != nil || s != ScopeCreateEvent {
+		t.Fatalf("caveat widened the scope to %s %v", s, err)
+	}
+
+	expired, err := Attenuate(tok, ExpiryCaveat(time.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.Verify(expired)
+	if err != ErrExpired {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestForgedTokens(t *testing.T) {
+	a := NewAuthority([32]byte{1})
+	tok, err := a.Issue(ScopeCaveat(ScopeRead))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewAuthority([32]byte{2}).Verify(tok)
+	if err != ErrInvalidToken {
+		t.Fatalf("token verified with another root key: %v", err)
+	}
+
+	// Dropping a caveat breaks the MAC chain
+	d, err := decodeToken(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Caveats = nil
+	_, err = a.Verify(d.encode())
+	if err != ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+
+	for _, bad := range []string{"", "secret", strings.TrimPrefix(tok, tokenPrefix)} {
+		_, err = a.Verify(bad)
+		if err != ErrInvalidToken {
+			t.Fatalf("expected ErrInvalidToken for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestRoles(t *testing.T) {
+	a := NewAuthority([32]byte{1})
+	tok, err := a.Issue(RoleCaveat(RoleAttester))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Authorize(tok, ScopeRead) != nil || a.Authorize(tok, ScopeAttest) != nil {
+		t.Fatal("attester denied reading or attesting")
+	}
+	if !errors.Is(a.Authorize(tok, ScopeCreateEvent), ErrPermissionDenied) {
+		t.Fatal("attester allowed to create events")
+	}
+	if !RoleAdmin.Scope().Has(ScopeCreateEvent) || RoleViewer.Scope().Has(ScopeAttest) {
+		t.Fatal("wrong role scopes")
+	}
+	_, err = ParseRole("root")
+	if !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("expected ErrUnknownRole, got %v", err)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	k, err := OpenKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, secret, err := k.Create("scheduler", RoleEventCreator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsAPIKey(secret) || key.Role != RoleEventCreator {
+		t.Fatalf("unexpected key %+v %s", key, secret)
+	}
+	_, _, err = k.Create("ops", "root")
+	if !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("expected ErrUnknownRole, got %v", err)
+	}
+	err = k.Authorize(secret, ScopeCreateEvent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.Authorize(secret, ScopeAttest)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+
+	// Changes are saved and take effect at once
+	_, err = k.SetRole(key.ID, RoleAttester)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err = OpenKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Authorize(secret, ScopeAttest); err != nil {
+		t.Fatal(err)
+	}
+	if keys := k.List(); len(keys) != 1 || keys[0].Name != "scheduler" || keys[0].Role != RoleAttester {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), secret) {
+		t.Fatal("keyring file holds the secret")
+	}
+
+	forged := secret[:len(secret)-1] + "0"
+	if forged == secret {
+		forged = secret[:len(secret)-1] + "1"
+	}
+	for _, bad := range []string{forged, "dlcok_", "secret"} {
+		_, err = k.Verify(bad)
+		if err != ErrInvalidToken {
+			t.Fatalf("expected ErrInvalidToken for %q, got %v", bad, err)
+		}
+	}
+
+	err = k.Revoke(key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Authorize(secret, ScopeRead) != ErrInvalidToken {
+		t.Fatal("revoked key accepted")
+	}
+	if !errors.Is(k.Revoke(key.ID), ErrUnknownKey) {
+		t.Fatal("revoked an unknown key")
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyPrefix marks API keys, as opposed to tokens
+const keyPrefix = "dlcok_"
+
+// ErrUnknownKey is returned for API key IDs the keyring doesn't hold
+var ErrUnknownKey = errors.New("unknown API key")
+
+// IsAPIKey reports whether secret has the form of an API key rather than
+// a token
+func IsAPIKey(secret string) bool {
+	return strings.HasPrefix(secret, keyPrefix)
+}
+
+// APIKey describes an API key. Its secret is only returned when the key
+// is created; the keyring keeps a hash of it.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type storedKey struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+// Keyring holds the API keys that authenticate callers, each with a role
+// that can be changed, or the key revoked, while the oracle runs. Unlike
+// tokens, which are checked against the authority's root key alone, API
+// keys only work while they are in the keyring.
+type Keyring struct {
+	mu   sync.RWMutex
+	path string
+	keys map[string]storedKey
+}
+
+// NewKeyring returns an empty keyring held in memory
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]storedKey)}
+}
+
+// OpenKeyring returns a keyring saved to the file at path after every
+// change, loading the keys it holds if it exists
+func OpenKeyring(path string) (*Keyring, error) {
+	k := NewKeyring()
+	k.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []storedKey
+	err = json.Unmarshal(b, &keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, key := range keys {
+		k.keys[key.ID] = key
+	}
+	return k, nil
+}
+
+// save writes the keys to the keyring's file, if it has one. The caller
+// holds the write lock.
+func (k *Keyring) save() error {
+	if k.path == "" {
+		return nil
+	}
+	keys := make([]storedKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	b, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := k.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, k.path)
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+// Create adds a key with the given name and role and returns it along
+// with its secret, which callers present as a bearer token
+func (k *Keyring) Create(name string, role Role) (APIKey, string, error) {
+	_, err := ParseRole(string(role))
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	var b [40]byte
+	_, err = rand.Read(b[:])
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	id := hex.EncodeToString(b[:8])
+	secret := keyPrefix + id + "_" + hex.EncodeToString(b[8:])
+	key := APIKey{ID: id, Name: name, Role: role, CreatedAt: time.Now().UTC().Truncate(time.Second)}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = storedKey{APIKey: key, Hash: hashSecret(secret)}
+	err = k.save()
+	if err != nil {
+		delete(k.keys, id)
+		return APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// List returns all keys, oldest first
+func (k *Keyring) List() []APIKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]APIKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key.APIKey)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys
+}
+
+// SetRole changes the role of the key with the given ID
+func (k *Keyring) SetRole(id string, role Role) (APIKey, error) {
+	_, err := ParseRole(string(role))
+	if err != nil {
+		return APIKey{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return APIKey{}, fmt.Errorf("%s: %w", id, ErrUnknownKey)
+	}
+	old := key
+	key.Role = role
+	k.keys[id] = key
+	err = k.save()
+	if err != nil {
+		k.keys[id] = old
+		return APIKey{}, err
+	}
+	return key.APIKey, nil
+}
+
+// Revoke removes the key with the given ID
+func (k *Keyring) Revoke(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrUnknownKey)
+	}
+	delete(k.keys, id)
+	err := k.save()
+	if err != nil {
+		k.keys[id] = key
+		return err
+	}
+	return nil
+}
+
+// Verify returns the key secret belongs to
+func (k *Keyring) Verify(secret string) (APIKey, error) {
+	rest, ok := strings.CutPrefix(secret, keyPrefix)
+	if !ok {
+		return APIKey{}, ErrInvalidToken
+	}
+	id, _, _ := strings.Cut(rest, "_")
+	k.mu.RLock()
+	key, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 {
+		return APIKey{}, ErrInvalidToken
+	}
+	return key.APIKey, nil
+}
+
+// Authorize verifies secret and checks that the role of its key grants
+// required
+func (k *Keyring) Authorize(secret string, required Scope) error {
+	key, err := k.Verify(secret)
+	if err != nil {
+		return err
+	}
+	if !key.Role.Scope().Has(required) {
+		return fmt.Errorf("key %s has role %s, need %s: %w", key.ID, key.Role, required, ErrPermissionDenied)
+	}
+	return nil
+}
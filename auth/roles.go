@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownRole is returned for role names that aren't defined
+var ErrUnknownRole = errors.New("unknown role")
+
+// Role is a named set of scopes, granted to API keys and tokens
+type Role string
+
+// The roles
+const (
+	// RoleViewer may read announcements, attestations and updates
+	RoleViewer Role = "viewer"
+	// RoleEventCreator may also announce new events
+	RoleEventCreator Role = "event-creator"
+	// RoleAttester may also sign outcomes manually
+	RoleAttester Role = "attester"
+	// RoleAdmin may do everything, including managing API keys
+	RoleAdmin Role = "admin"
+)
+
+var roleScopes = map[Role]Scope{
+	RoleViewer:       ScopeRead,
+	RoleEventCreator: ScopeRead | ScopeCreateEvent,
+	RoleAttester:     ScopeRead | ScopeAttest,
+	RoleAdmin:        ScopeAdmin,
+}
+
+// Scope returns the scopes r grants, none for unknown roles
+func (r Role) Scope() Scope {
+	return roleScopes[r]
+}
+
+// ParseRole returns the role named s
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleScopes[r]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownRole, s)
+	}
+	return r, nil
+}
+
+// RoleCaveat restricts a token to the scopes of role
+func RoleCaveat(r Role) string {
+	return ScopeCaveat(r.Scope())
+}
@@ -0,0 +1,139 @@
+package dlcoracle
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// The point arithmetic of public keys and signature points is done by a
+// backend: the maintained secp256k1 package of btcd, on fixed size field
+// and scalar types, or the math/big arithmetic of the adiabat/btcd fork
+// that LIT was built with. Both give the same results; the fork stays
+// available with SetBackend for checking that they do. Builds with the
+// nolegacycurve tag leave it out.
+
+// Backend is an implementation of the curve arithmetic
+type Backend uint8
+
+const (
+	// BackendLegacy computes with the adiabat/btcd fork. Builds with
+	// the nolegacycurve tag don't have it.
+	BackendLegacy Backend = iota
+
+	// BackendSecp256k1 computes with github.com/btcsuite/btcd/btcec/v2,
+	// the default
+	BackendSecp256k1
+)
+
+const defaultBackend = BackendSecp256k1
+
+// String returns the name of the backend
+func (b Backend) String() string {
+	switch b {
+	case BackendLegacy:
+		return "legacy"
+	case BackendSecp256k1:
+		return "secp256k1"
+	}
+	return fmt.Sprintf("backend(%d)", uint8(b))
+}
+
+// curve is the arithmetic a backend implements
+type curve interface {
+	// baseMult returns the compressed point kG, with k reduced modulo
+	// the curve order. The point at infinity is 0x02 followed by zeros.
+	baseMult(k [32]byte) [33]byte
+
+	// signaturePoint returns the compressed point R - eA of affine A
+	// and R, and false if it is the point at infinity
+	signaturePoint(A, R *btcecv2.JacobianPoint, e *btcecv2.ModNScalar) ([33]byte, bool)
+}
+
+type curveHolder struct {
+	backend Backend
+	curve
+}
+
+var packageCurve atomic.Value
+
+func init() {
+	packageCurve.Store(curveHolder{defaultBackend, backendCurve(defaultBackend)})
+}
+
+// backendCurve returns the arithmetic of b, or nil if it isn't compiled in
+func backendCurve(b Backend) curve {
+	switch b {
+	case BackendLegacy:
+		return legacyCurve
+	case BackendSecp256k1:
+		return secp256k1Curve{}
+	}
+	return nil
+}
+
+// SetBackend sets the curve arithmetic of this package. It fails for
+// BackendLegacy in builds with the nolegacycurve tag.
+func SetBackend(b Backend) error {
+	c := backendCurve(b)
+	if c == nil {
+		return fmt.Errorf("%s backend not available in this build", b)
+	}
+	packageCurve.Store(curveHolder{b, c})
+	return nil
+}
+
+// CurrentBackend returns the backend set with SetBackend, or the default
+func CurrentBackend() Backend {
+	return packageCurve.Load().(curveHolder).backend
+}
+
+func arithmetic() curve {
+	return packageCurve.Load().(curveHolder).curve
+}
+
+// secp256k1Curve is the arithmetic of btcec/v2
+type secp256k1Curve struct{}
+
+func (secp256k1Curve) baseMult(k [32]byte) [33]byte {
+	var s btcecv2.ModNScalar
+	s.SetBytes(&k)
+	var p btcecv2.JacobianPoint
+	btcecv2.ScalarBaseMultNonConst(&s, &p)
+	return serializeJacobian(&p)
+}
+
+func (secp256k1Curve) signaturePoint(A, R *btcecv2.JacobianPoint, e *btcecv2.ModNScalar) ([33]byte, bool) {
+	var eA, sum btcecv2.JacobianPoint
+	var negE btcecv2.ModNScalar
+	negE.NegateVal(e)
+	btcecv2.ScalarMultNonConst(&negE, A, &eA)
+	btcecv2.AddNonConst(R, &eA, &sum)
+	if isInfinity(&sum) {
+		return [33]byte{}, false
+	}
+	return serializeJacobian(&sum), true
+}
+
+// serializeJacobian returns the compressed encoding of p, or 0x02
+// followed by zeros for the point at infinity
+func serializeJacobian(p *btcecv2.JacobianPoint) [33]byte {
+	if isInfinity(p) {
+		return [33]byte{0x02}
+	}
+	p.ToAffine()
+	return encodeAffine(p)
+}
+
+// encodeAffine returns the compressed encoding of p, in normalized affine
+// form
+func encodeAffine(p *btcecv2.JacobianPoint) [33]byte {
+	var out [33]byte
+	out[0] = 0x02
+	if p.Y.IsOdd() {
+		out[0] = 0x03
+	}
+	p.X.PutBytesUnchecked(out[1:])
+	return out
+}
@@ -0,0 +1,48 @@
+//go:build !nolegacycurve
+
+package dlcoracle
+
+import (
+	"math/big"
+
+	"github.com/adiabat/btcd/btcec"
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+var legacyCurve curve = adiabatCurve{}
+
+// adiabatCurve is the arithmetic of the adiabat/btcd fork, on math/big
+type adiabatCurve struct{}
+
+func (adiabatCurve) baseMult(k [32]byte) [33]byte {
+	var out [33]byte
+	_, pk := btcec.PrivKeyFromBytes(btcec.S256(), k[:])
+	copy(out[:], pk.SerializeCompressed())
+	return out
+}
+
+func (adiabatCurve) signaturePoint(A, R *btcecv2.JacobianPoint, e *btcecv2.ModNScalar) ([33]byte, bool) {
+	var out [33]byte
+	curve := btcec.S256()
+	eBytes := e.Bytes()
+
+	// e * A, negated
+	x, y := curve.ScalarMult(bigField(&A.X), bigField(&A.Y), eBytes[:])
+	y.Neg(y)
+	y.Mod(y, curve.P)
+
+	// add to R
+	P := new(btcec.PublicKey)
+	P.X, P.Y = curve.Add(x, y, bigField(&R.X), bigField(&R.Y))
+	if P.X.Sign() == 0 && P.Y.Sign() == 0 {
+		return out, false
+	}
+	copy(out[:], P.SerializeCompressed())
+	return out, true
+}
+
+// bigField converts a normalized field value for the fork's math/big API
+func bigField(f *btcecv2.FieldVal) *big.Int {
+	b := f.Bytes()
+	return new(big.Int).SetBytes(b[:])
+}
@@ -0,0 +1,6 @@
+//go:build nolegacycurve
+
+package dlcoracle
+
+// legacyCurve is nil: the adiabat/btcd fork isn't compiled in
+var legacyCurve curve
@@ -0,0 +1,85 @@
+package dlcoracle
+
+import (
+	"crypto/rand"
+	"testing"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+func TestBackends(t *testing.T) {
+	if backendCurve(BackendLegacy) == nil {
+		if SetBackend(BackendLegacy) == nil {
+			t.Fatal("set a backend that isn't compiled in")
+		}
+		t.Skip("legacy backend not compiled in")
+	}
+	legacy, secp := backendCurve(BackendLegacy), backendCurve(BackendSecp256k1)
+
+	var n, nPlusOne, nMinusOne, high [32]byte
+	btcecv2.S256().N.FillBytes(n[:])
+	nPlusOne, nMinusOne = n, n
+	nPlusOne[31]++
+	nMinusOne[31]--
+	for i := range high {
+		high[i] = 0xff
+	}
+	keys := [][32]byte{{}, {31: 1}, {31: 2}, n, nPlusOne, nMinusOne, high}
+	for i := 0; i < 50; i++ {
+		var k [32]byte
+		rand.Read(k[:])
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if a, b := legacy.baseMult(k), secp.baseMult(k); a != b {
+			t.Fatalf("kG of %x: legacy %x, secp256k1 %x", k, a, b)
+		}
+	}
+
+	for i := 0; i+2 < len(keys); i++ {
+		// zero keys and multiples of N give no point
+		p, err := newSignaturePointer(secp.baseMult(keys[i+1]), secp.baseMult(keys[i+2]), SHA256)
+		if err != nil {
+			continue
+		}
+		var e btcecv2.ModNScalar
+		e.SetBytes(&keys[i])
+		a, okA := legacy.signaturePoint(&p.A, &p.R, &e)
+		b, okB := secp.signaturePoint(&p.A, &p.R, &e)
+		if a != b || okA != okB {
+			t.Fatalf("signature point %d: legacy %x %v, secp256k1 %x %v", i, a, okA, b, okB)
+		}
+	}
+
+	// R = eA gives the point at infinity
+	one := secp.baseMult([32]byte{31: 1})
+	p, _ := newSignaturePointer(one, one, SHA256)
+	var e btcecv2.ModNScalar
+	e.SetInt(1)
+	for _, c := range []curve{legacy, secp} {
+		if _, ok := c.signaturePoint(&p.A, &p.R, &e); ok {
+			t.Fatal("no point at infinity")
+		}
+	}
+
+	t.Cleanup(func() { SetBackend(defaultBackend) })
+	pub, r := secp.baseMult(keys[8]), secp.baseMult(keys[9])
+	var points [][33]byte
+	for _, b := range []Backend{BackendLegacy, BackendSecp256k1} {
+		err := SetBackend(b)
+		if err != nil || CurrentBackend() != b {
+			t.Fatalf("setting %s: %v", b, err)
+		}
+		p, err := ComputeSignaturePubKey(pub, r, []byte("msg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		points = append(points, p)
+	}
+	if points[0] != points[1] {
+		t.Fatalf("signature points differ: %x", points)
+	}
+	if SetBackend(Backend(9)) == nil {
+		t.Fatal("set an unknown backend")
+	}
+}
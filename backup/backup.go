@@ -0,0 +1,328 @@
+// Package backup periodically uploads encrypted backups of an oracle's
+// state to S3-compatible storage, prunes them by age and count, and
+// fetches them back for restoring.
+//
+// A backup holds a storage.Snapshot of the store and the audit log. Like
+// a snapshot it contains no keys: after restoring it, the one-time
+// signing keys of pending events are derived again from the oracle's
+// private key. It is still encrypted, with a data key of its own wrapped
+// by a seal.KeyWrapper, so the bucket doesn't reveal pending events or
+// the audit trail.
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+)
+
+// Version is the version of the archive format Encode writes
+const Version = 1
+
+// magic starts every backup, and is authenticated with its contents
+var magic = []byte("DLCBAK\x00\x01")
+
+// nameLayout names backups after the time they were made, so they sort
+// chronologically
+const nameLayout = "20060102T150405Z"
+
+// retryInterval is how long Run waits after a failed backup
+const retryInterval = 5 * time.Minute
+
+// Archive is the contents of a backup
+type Archive struct {
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Snapshot  storage.Snapshot `json:"snapshot"`
+	// Audit is the audit log, up to its last complete entry
+	Audit []byte `json:"audit,omitempty"`
+}
+
+// Encode compresses and encrypts a, wrapping its data key with w
+func Encode(ctx context.Context, w seal.KeyWrapper, a Archive) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err := json.NewEncoder(zw).Encode(a)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sealer, wrapped, err := seal.New(ctx, w, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("wrapped data key too long")
+	}
+	out := append([]byte(nil), magic...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(wrapped)))
+	out = append(out, wrapped...)
+	sealed, err := sealer.Seal(buf.Bytes(), magic)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, sealed...), nil
+}
+
+// Decode decrypts a backup written by Encode
+func Decode(ctx context.Context, w seal.KeyWrapper, b []byte) (Archive, error) {
+	var a Archive
+	if !bytes.HasPrefix(b, magic) || len(b) < len(magic)+2 {
+		return a, fmt.Errorf("not a backup")
+	}
+	b = b[len(magic):]
+	n := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < n {
+		return a, fmt.Errorf("truncated backup")
+	}
+	sealer, _, err := seal.New(ctx, w, b[:n])
+	if err != nil {
+		return a, err
+	}
+	plain, err := sealer.Open(b[n:], magic)
+	if err != nil {
+		return a, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		return a, err
+	}
+	err = json.NewDecoder(zr).Decode(&a)
+	if err != nil {
+		return a, err
+	}
+	if a.Version != Version {
+		return a, fmt.Errorf("unsupported backup version %d", a.Version)
+	}
+	return a, nil
+}
+
+// Retention decides which backups are deleted after a new one was made.
+// Zero values keep everything; the newest backup is always kept.
+type Retention struct {
+	// Keep is how many of the newest backups are kept
+	Keep int
+	// MaxAge is how long backups are kept
+	MaxAge time.Duration
+}
+
+// Manager makes backups of a store and prunes old ones
+type Manager struct {
+	bucket    Bucket
+	prefix    string
+	wrapper   seal.KeyWrapper
+	store     storage.Store
+	auditPath string
+	retention Retention
+	logger    dlcoracle.Logger
+	now       func() time.Time
+}
+
+// NewManager returns a manager uploading backups of store to bucket,
+// under keys starting with prefix, encrypted with data keys wrapped by w.
+// The store has to implement storage.NonceIndexStore.
+func NewManager(bucket Bucket, prefix string, w seal.KeyWrapper, store storage.Store) *Manager {
+	return &Manager{
+		bucket:  bucket,
+		prefix:  prefix,
+		wrapper: w,
+		store:   store,
+		logger:  dlcoracle.NopLogger(),
+		now:     time.Now,
+	}
+}
+
+// SetAuditLog includes the audit log at path in backups
+func (m *Manager) SetAuditLog(path string) {
+	m.auditPath = path
+}
+
+// SetRetention sets which backups are deleted after a new one was made
+func (m *Manager) SetRetention(r Retention) {
+	m.retention = r
+}
+
+// SetLogger sets the logger backups made by Run are reported to
+func (m *Manager) SetLogger(l dlcoracle.Logger) {
+	m.logger = l
+}
+
+// readAudit returns the audit log up to its last complete entry, which
+// leaves out an entry being appended while it is read
+func (m *Manager) readAudit() ([]byte, error) {
+	if m.auditPath == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(m.auditPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b[:bytes.LastIndexByte(b, '\n')+1], nil
+}
+
+// Backup uploads a backup of the store and the audit log, then deletes
+// the backups the retention policy doesn't keep. It returns the key of
+// the new backup.
+func (m *Manager) Backup(ctx context.Context) (string, error) {
+	a := Archive{Version: Version, CreatedAt: m.now().UTC().Truncate(time.Second)}
+	var err error
+	a.Snapshot, err = storage.Export(m.store)
+	if err != nil {
+		return "", err
+	}
+	a.Audit, err = m.readAudit()
+	if err != nil {
+		return "", err
+	}
+	b, err := Encode(ctx, m.wrapper, a)
+	if err != nil {
+		return "", err
+	}
+	key := m.prefix + "oracle-" + a.CreatedAt.Format(nameLayout) + ".bak"
+	err = m.bucket.Put(ctx, key, b)
+	if err != nil {
+		return "", err
+	}
+	_, err = m.Prune(ctx)
+	if err != nil {
+		return key, fmt.Errorf("pruning backups: %w", err)
+	}
+	return key, nil
+}
+
+// List returns the backups in the bucket, oldest first
+func (m *Manager) List(ctx context.Context) ([]Object, error) {
+	objects, err := m.bucket.List(ctx, m.prefix)
+	if err != nil {
+		return nil, err
+	}
+	backups := objects[:0]
+	for _, o := range objects {
+		if _, ok := backupTime(m.prefix, o.Key); ok {
+			backups = append(backups, o)
+		}
+	}
+	return backups, nil
+}
+
+// backupTime returns the time the backup named key was made at, and
+// whether key names a backup
+func backupTime(prefix, key string) (time.Time, bool) {
+	name, ok := strings.CutPrefix(key, prefix+"oracle-")
+	if !ok {
+		return time.Time{}, false
+	}
+	name, ok = strings.CutSuffix(name, ".bak")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(nameLayout, name)
+	return t, err == nil
+}
+
+// Prune deletes the backups the retention policy doesn't keep and
+// returns their keys
+func (m *Manager) Prune(ctx context.Context) ([]string, error) {
+	backups, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	now := m.now()
+	// newest first, sparing the newest
+	for i := len(backups) - 2; i >= 0; i-- {
+		n := len(backups) - i
+		t, _ := backupTime(m.prefix, backups[i].Key)
+		tooMany := m.retention.Keep > 0 && n > m.retention.Keep
+		tooOld := m.retention.MaxAge > 0 && now.Sub(t) > m.retention.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		err := m.bucket.Delete(ctx, backups[i].Key)
+		if err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, backups[i].Key)
+	}
+	return deleted, nil
+}
+
+// Fetch downloads and decrypts the backup with the given key, or the
+// newest backup if key is empty
+func (m *Manager) Fetch(ctx context.Context, key string) (Archive, error) {
+	if key == "" {
+		backups, err := m.List(ctx)
+		if err != nil {
+			return Archive{}, err
+		}
+		if len(backups) == 0 {
+			return Archive{}, fmt.Errorf("no backups under %q: %w", m.prefix, ErrNotFound)
+		}
+		key = backups[len(backups)-1].Key
+	}
+	b, err := m.bucket.Get(ctx, key)
+	if err != nil {
+		return Archive{}, err
+	}
+	a, err := Decode(ctx, m.wrapper, b)
+	if err != nil {
+		return Archive{}, fmt.Errorf("%s: %w", key, err)
+	}
+	return a, nil
+}
+
+// Run makes a backup every interval until ctx is cancelled. The first is
+// made once interval has passed since the newest backup in the bucket,
+// so restarts don't delay or multiply backups. Failed backups are
+// retried after a few minutes.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	next := m.now()
+	backups, err := m.List(ctx)
+	if err != nil {
+		m.logger.Log(dlcoracle.LevelWarn, "listing backups failed", dlcoracle.F("err", err))
+	} else if len(backups) > 0 {
+		t, _ := backupTime(m.prefix, backups[len(backups)-1].Key)
+		next = t.Add(interval)
+	}
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		key, err := m.Backup(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case key == "":
+			m.logger.Log(dlcoracle.LevelError, "backup failed", dlcoracle.F("err", err))
+			next = m.now().Add(min(interval, retryInterval))
+			continue
+		case err != nil:
+			m.logger.Log(dlcoracle.LevelWarn, "backup made, pruning failed",
+				dlcoracle.F("key", key), dlcoracle.F("err", err))
+		default:
+			m.logger.Log(dlcoracle.LevelInfo, "backup made", dlcoracle.F("key", key))
+		}
+		next = m.now().Add(interval)
+	}
+}
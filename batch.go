@@ -0,0 +1,56 @@
+package dlcoracle
+
+import (
+	"context"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// ComputeSignaturePubKeys returns the signature point of every message
+// under the oracle's public key and R point, the table of anticipation
+// points a contract's execution transactions are built from. It stops
+// with the context's error once ctx is done.
+func ComputeSignaturePubKeys(ctx context.Context, oraclePubA, oraclePubR [33]byte, messages [][]byte) ([][33]byte, error) {
+	p, err := newSignaturePointer(oraclePubA, oraclePubR, SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return p.points(ctx, messages)
+}
+
+// points computes the signature points of messages one after another
+func (p *signaturePointer) points(ctx context.Context, messages [][]byte) ([][33]byte, error) {
+	p.precompute(len(messages))
+	defer p.release()
+	points := make([][33]byte, len(messages))
+	if p.table == nil {
+		for i, msg := range messages {
+			err := ctx.Err()
+			if err != nil {
+				return nil, err
+			}
+			points[i], err = p.point(msg)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return points, nil
+	}
+
+	sums := make([]btcecv2.JacobianPoint, len(messages))
+	for i, msg := range messages {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		err = p.sum(msg, &sums[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := serializeBatch(sums, points)
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
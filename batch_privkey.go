@@ -0,0 +1,29 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "context"
+
+// SignRequest is a message to sign with its one-time signing key, such as
+// one digit of a numeric outcome
+type SignRequest struct {
+	OneTimeSigningKey [32]byte
+	Message           []byte
+}
+
+// ComputeSignatures signs every request with the private key, like
+// ComputeSignature. It stops with the context's error once ctx is done.
+func ComputeSignatures(ctx context.Context, privKey [32]byte, reqs []SignRequest) ([][32]byte, error) {
+	sigs := make([][32]byte, len(reqs))
+	for i, r := range reqs {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		sigs[i], err = ComputeSignature(privKey, r.OneTimeSigningKey, r.Message)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
@@ -0,0 +1,125 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+func TestBatch(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	reqs := make([]SignRequest, 5)
+	messages := make([][]byte, len(reqs))
+	for i := range reqs {
+		reqs[i].OneTimeSigningKey[31] = byte(i + 2)
+		reqs[i].Message = GenerateNumericMessage(uint64(i))
+		messages[i] = reqs[i].Message
+	}
+	ctx := context.Background()
+
+	sigs, err := ComputeSignatures(ctx, priv, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, sig := range sigs {
+		err = VerifySignature(pub, PublicKeyFromPrivateKey(reqs[i].OneTimeSigningKey), reqs[i].Message, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	R := PublicKeyFromPrivateKey(reqs[0].OneTimeSigningKey)
+	points, err := ComputeSignaturePubKeys(ctx, pub, R, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PublicKeyFromPrivateKey(sigs[0]) != points[0] {
+		t.Fatal("anticipation point doesn't match signature")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = ComputeSignatures(cancelled, priv, reqs)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	_, err = ComputeSignaturePubKeys(cancelled, pub, R, messages)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// Batches big enough for a table of multiples of the public key give
+// the same points as one at a time
+func TestComputeSignaturePubKeysTable(t *testing.T) {
+	var priv, k [32]byte
+	rand.Read(priv[:])
+	rand.Read(k[:])
+	A, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	messages := make([][]byte, 2*minTableBatch)
+	for i := range messages {
+		messages[i] = GenerateNumericMessage(uint64(i))
+	}
+	pool := NewSignerPool(2)
+	defer pool.Close()
+	for round := 0; round < 2; round++ {
+		points, err := ComputeSignaturePubKeys(context.Background(), A, R, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pooled, err := pool.ComputeSignaturePubKeys(context.Background(), A, R, messages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, msg := range messages {
+			want, err := ComputeSignaturePubKey(A, R, msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if points[i] != want || pooled[i] != want {
+				t.Fatalf("message %d: %x and %x, expected %x", i, points[i], pooled[i], want)
+			}
+		}
+		// the second round reuses the tables of the first
+		A, R = R, A
+	}
+}
+
+func TestSerializeBatchInfinity(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	p, err := newSignaturePointer(PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(priv), SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := []btcecv2.JacobianPoint{p.A, {}}
+	err = serializeBatch(points, make([][33]byte, 2))
+	if !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+}
+
+func BenchmarkComputeSignaturePubKeys(b *testing.B) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	A, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	messages := make([][]byte, 1000)
+	for i := range messages {
+		messages[i] = GenerateNumericMessage(uint64(i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := ComputeSignaturePubKeys(context.Background(), A, R, messages)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
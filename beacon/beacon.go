@@ -0,0 +1,144 @@
+package beacon
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+)
+
+// beaconTag separates beacon values from other hashes of R points
+const beaconTag = "DLC/oracle/beacon"
+
+// EntropySource provides the external entropy mixed into a beacon value
+// at maturity. Public entropy nobody can predict, such as a block hash
+// mined after the announcement, lets anyone check the derivation.
+type EntropySource interface {
+	Entropy(ev dlcoracle.Event) ([]byte, error)
+}
+
+// RandomEntropy draws the entropy from crypto/rand
+type RandomEntropy struct{}
+
+// Entropy implements EntropySource
+func (RandomEntropy) Entropy(dlcoracle.Event) ([]byte, error) {
+	var b [32]byte
+	_, err := rand.Read(b[:])
+	return b[:], err
+}
+
+// Beacon periodically attests to an unpredictable random number. It
+// announces numeric events "<prefix><unix time>" for the coming periods
+// ahead of time, and as a data source resolves them to a value derived
+// from the event's R point and external entropy. Route its prefix to it
+// in the datasource.Registry used by the scheduler.
+type Beacon struct {
+	oracle   *oracle.Oracle
+	sched    *scheduler.Scheduler
+	prefix   string
+	interval time.Duration
+	ahead    int
+	entropy  EntropySource
+}
+
+// New returns a beacon announcing an event every interval, ahead periods
+// in advance, through sched
+func New(o *oracle.Oracle, sched *scheduler.Scheduler, prefix string,
+	interval time.Duration, ahead int) *Beacon {
+
+	return &Beacon{
+		oracle:   o,
+		sched:    sched,
+		prefix:   prefix,
+		interval: interval,
+		ahead:    ahead,
+		entropy:  RandomEntropy{},
+	}
+}
+
+// SetEntropySource replaces the default crypto/rand entropy
+func (b *Beacon) SetEntropySource(e EntropySource) {
+	b.entropy = e
+}
+
+// ID implements datasource.DataSource
+func (b *Beacon) ID() string {
+	return "beacon"
+}
+
+// Describe implements datasource.DataSource
+func (b *Beacon) Describe() string {
+	return fmt.Sprintf("random beacon every %s", b.interval)
+}
+
+// EventID returns the ID of the beacon event maturing at t
+func (b *Beacon) EventID(t time.Time) string {
+	return fmt.Sprintf("%s%d", b.prefix, t.Unix())
+}
+
+// Announce makes sure the events of the next ahead periods after now are
+// announced
+func (b *Beacon) Announce(now time.Time) error {
+	next := now.Truncate(b.interval).Add(b.interval)
+	for i := 0; i < b.ahead; i++ {
+		maturity := next.Add(time.Duration(i) * b.interval).UTC()
+		_, err := b.sched.Schedule(dlcoracle.Event{
+			ID:       b.EventID(maturity),
+			Maturity: maturity,
+		})
+		if err != nil && !errors.Is(err, oracle.ErrEventExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run keeps announcing upcoming events every interval until ctx is
+// cancelled
+func (b *Beacon) Run(ctx context.Context) error {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		err := b.Announce(time.Now())
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// FetchOutcome implements datasource.DataSource by deriving the random
+// value for ev
+func (b *Beacon) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	a, err := b.oracle.Store().Announcement(ev.ID)
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	entropy, err := b.entropy.Entropy(ev)
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	return dlcoracle.Outcome{Value: Value(a.RPoint, entropy)}, nil
+}
+
+// Value derives the beacon value from the event's R point and the
+// external entropy: the first 63 bits of
+// SHA256("DLC/oracle/beacon" || R || entropy)
+func Value(rPoint [33]byte, entropy []byte) int64 {
+	h := sha256.New()
+	h.Write([]byte(beaconTag))
+	h.Write(rPoint[:])
+	h.Write(entropy)
+	return int64(binary.BigEndian.Uint64(h.Sum(nil)) >> 1)
+}
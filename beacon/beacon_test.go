@@ -0,0 +1,74 @@
+package beacon
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+type fixedEntropy []byte
+
+func (e fixedEntropy) Entropy(dlcoracle.Event) ([]byte, error) {
+	return e, nil
+}
+
+func TestBeacon(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	reg := datasource.NewRegistry()
+	sched := scheduler.New(o, reg)
+	b := New(o, sched, "beacon-", time.Hour, 3)
+	b.SetEntropySource(fixedEntropy("block hash"))
+	reg.Register(b)
+	reg.Route("beacon-", b.ID())
+
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	err := b.Announce(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Announcing again must not fail on the existing events
+	err = b.Announce(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := sched.Pending()
+	if len(pending) != 3 {
+		t.Fatalf("expected 3 announced events, got %d", len(pending))
+	}
+	first := pending[0].Event
+	if !first.Maturity.Equal(time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first maturity %s", first.Maturity)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.Run(ctx)
+
+	a, err := o.Store().Announcement(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Value(a.RPoint, []byte("block hash"))
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		att, err := o.Store().Attestation(first.ID)
+		if err == nil {
+			err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint,
+				dlcoracle.GenerateNumericMessage(uint64(expected)), att.Signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("beacon event was not attested")
+}
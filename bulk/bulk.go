@@ -0,0 +1,325 @@
+// Package bulk encodes announcements in a compact binary stream, for
+// mirroring an oracle's whole history without the overhead of JSON and
+// hex.
+//
+// A stream starts with the magic bytes "DLCB" and a version byte, followed
+// by frames of a uvarint length and a record of that many bytes. A frame
+// of length zero ends the stream, so a truncated stream is told apart
+// from a complete one. Records encode the fields of an announcement in
+// this order, with strings and lists prefixed by their uvarint length and
+// integers as uvarints, or varints where they may be negative:
+//
+//	event ID, oracle public key (33 bytes), R point (33 bytes),
+//	maturity (unix seconds, varint), maturity height,
+//	descriptor type (1 byte), outcomes, base, digits, unit,
+//	unit exponent (varint), precision,
+//	R points (33 bytes each), description, category, tags,
+//	signature (65 bytes), rounding (1 byte, omitted for half-up)
+//
+// Fields may be appended to records without a new version, so readers
+// ignore the bytes after the fields they know. Records aren't verified when read: check them with
+// Announcement.Verify against the oracle's public key.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+const (
+	// Version is the version of the stream format
+	Version = 1
+
+	// ContentType is the media type of streams served over HTTP
+	ContentType = "application/vnd.dlc-oracle.announcements"
+
+	// MaxRecordSize bounds the size of a record, well above that of any
+	// valid announcement
+	MaxRecordSize = 1 << 20
+)
+
+var magic = []byte("DLCB")
+
+// Writer writes announcements to a stream
+type Writer struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+// NewWriter writes the header of a stream to w and returns a writer for
+// its records. Close must be called to end the stream.
+func NewWriter(w io.Writer) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	bw.Write(magic)
+	err := bw.WriteByte(Version)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: bw}, nil
+}
+
+// Write appends an announcement to the stream
+func (w *Writer) Write(a dlcoracle.Announcement) error {
+	w.buf = appendAnnouncement(w.buf[:0], a)
+	var n [binary.MaxVarintLen64]byte
+	_, err := w.w.Write(n[:binary.PutUvarint(n[:], uint64(len(w.buf)))])
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(w.buf)
+	return err
+}
+
+// Flush writes the buffered records to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close ends the stream and flushes it. It doesn't close the underlying
+// writer.
+func (w *Writer) Close() error {
+	err := w.w.WriteByte(0)
+	if err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendStrings(b []byte, list []string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(list)))
+	for _, s := range list {
+		b = appendString(b, s)
+	}
+	return b
+}
+
+func appendAnnouncement(b []byte, a dlcoracle.Announcement) []byte {
+	b = appendString(b, a.EventID)
+	b = append(b, a.OraclePubKey[:]...)
+	b = append(b, a.RPoint[:]...)
+	b = binary.AppendVarint(b, a.Maturity.Unix())
+	b = binary.AppendUvarint(b, uint64(a.MaturityHeight))
+	d := a.Descriptor
+	b = append(b, byte(d.Type))
+	b = appendStrings(b, d.Outcomes)
+	b = binary.AppendUvarint(b, uint64(d.Base))
+	b = binary.AppendUvarint(b, uint64(d.Digits))
+	b = appendString(b, d.Unit)
+	b = binary.AppendVarint(b, int64(d.UnitExponent))
+	b = binary.AppendUvarint(b, d.Precision)
+	b = binary.AppendUvarint(b, uint64(len(a.RPoints)))
+	for _, R := range a.RPoints {
+		b = append(b, R[:]...)
+	}
+	b = appendString(b, a.Metadata.Description)
+	b = appendString(b, a.Metadata.Category)
+	b = appendStrings(b, a.Metadata.Tags)
+	b = append(b, a.Signature[:]...)
+	if d.Rounding != dlcoracle.RoundHalfUp {
+		b = append(b, byte(d.Rounding))
+	}
+	return b
+}
+
+// Reader reads announcements from a stream
+type Reader struct {
+	r    *bufio.Reader
+	buf  []byte
+	done bool
+}
+
+// NewReader reads the header of a stream from r and returns a reader for
+// its records
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var header [5]byte
+	_, err := io.ReadFull(br, header[:])
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if !bytes.Equal(header[:4], magic) {
+		return nil, fmt.Errorf("not an announcement stream")
+	}
+	if header[4] != Version {
+		return nil, fmt.Errorf("unsupported stream version %d", header[4])
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next announcement of the stream. It returns io.EOF at
+// the end of the stream, and io.ErrUnexpectedEOF if the stream was cut
+// off before its end.
+func (r *Reader) Next() (dlcoracle.Announcement, error) {
+	var a dlcoracle.Announcement
+	if r.done {
+		return a, io.EOF
+	}
+	n, err := binary.ReadUvarint(r.r)
+	if err == io.EOF {
+		return a, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return a, err
+	}
+	if n == 0 {
+		r.done = true
+		return a, io.EOF
+	}
+	if n > MaxRecordSize {
+		return a, fmt.Errorf("record of %d bytes, at most %d", n, MaxRecordSize)
+	}
+	if uint64(cap(r.buf)) < n {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:n]
+	_, err = io.ReadFull(r.r, r.buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return a, err
+	}
+	d := decoder{b: r.buf}
+	a = d.announcement()
+	if d.err != nil {
+		return a, fmt.Errorf("decoding record: %w", d.err)
+	}
+	return a, nil
+}
+
+// errShort is the error of records ending before their last field
+var errShort = errors.New("record too short")
+
+// decoder reads the fields of a record, remembering the first error
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.b)) < n {
+		d.err = errShort
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errShort
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errShort
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uint32() uint32 {
+	v := d.uvarint()
+	if v > 1<<32-1 && d.err == nil {
+		d.err = fmt.Errorf("%d overflows 32 bits", v)
+	}
+	return uint32(v)
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
+
+// count reads the length of a list of items of at least size bytes,
+// checking that the record can hold them
+func (d *decoder) count(size int) int {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(len(d.b)/size) {
+		d.err = errShort
+	}
+	return int(n)
+}
+
+func (d *decoder) strings() []string {
+	n := d.count(1)
+	if n == 0 {
+		return nil
+	}
+	list := make([]string, n)
+	for i := range list {
+		list[i] = d.string()
+	}
+	return list
+}
+
+func (d *decoder) announcement() dlcoracle.Announcement {
+	var a dlcoracle.Announcement
+	a.EventID = d.string()
+	copy(a.OraclePubKey[:], d.bytes(33))
+	copy(a.RPoint[:], d.bytes(33))
+	a.Maturity = time.Unix(d.varint(), 0).UTC()
+	a.MaturityHeight = d.uint32()
+	desc := &a.Descriptor
+	if t := d.bytes(1); t != nil {
+		desc.Type = dlcoracle.EventType(t[0])
+	}
+	desc.Outcomes = d.strings()
+	desc.Base = d.uint32()
+	desc.Digits = d.uint32()
+	desc.Unit = d.string()
+	exp := d.varint()
+	if (exp < -1<<31 || exp > 1<<31-1) && d.err == nil {
+		d.err = fmt.Errorf("unit exponent %d overflows 32 bits", exp)
+	}
+	desc.UnitExponent = int32(exp)
+	desc.Precision = d.uvarint()
+	if n := d.count(33); n != 0 {
+		a.RPoints = make([][33]byte, n)
+		for i := range a.RPoints {
+			copy(a.RPoints[i][:], d.bytes(33))
+		}
+	}
+	a.Metadata.Description = d.string()
+	a.Metadata.Category = d.string()
+	a.Metadata.Tags = d.strings()
+	copy(a.Signature[:], d.bytes(65))
+	if d.err == nil && len(d.b) > 0 {
+		desc.Rounding = dlcoracle.Rounding(d.b[0])
+	}
+	if d.err == nil {
+		d.err = desc.Validate()
+	}
+	if d.err == nil {
+		d.err = a.Metadata.Validate()
+	}
+	return a
+}
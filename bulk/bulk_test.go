@@ -0,0 +1,123 @@
+//go:build !verifyonly
+
+package bulk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func testAnnouncements(t *testing.T) []dlcoracle.Announcement {
+	priv := [32]byte{31: 42}
+	digits := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 3, Unit: "usd/btc", UnitExponent: -2, Precision: 5, Rounding: dlcoracle.RoundFloor}
+	enum := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	var list []dlcoracle.Announcement
+	for i, ev := range []dlcoracle.Event{
+		{ID: "price", Maturity: time.Unix(1893456000, 0), Descriptor: digits,
+			Metadata: dlcoracle.EventMetadata{Description: "BTC/USD", Category: "crypto", Tags: []string{"btc", "usd"}}},
+		{ID: "rain", Maturity: time.Unix(1893456000, 0), MaturityHeight: 900000, Descriptor: enum},
+		{ID: "", Maturity: time.Unix(-1, 0), Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric}},
+	} {
+		a, err := dlcoracle.NewAnnouncement(priv, [32]byte{31: byte(i + 1)}, ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = append(list, a)
+	}
+	return list
+}
+
+func TestRoundTrip(t *testing.T) {
+	list := testAnnouncements(t)
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range list {
+		err = w.Write(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := buf.Bytes()
+	jsonSize := 0
+	for _, a := range list {
+		b, _ := json.Marshal(a)
+		jsonSize += len(b)
+	}
+	if len(stream) >= jsonSize/2 {
+		t.Fatalf("stream of %d bytes for %d bytes of JSON", len(stream), jsonSize)
+	}
+
+	r, err := NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range list {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Fatalf("decoded\n%s\nwant\n%s", gotJSON, wantJSON)
+		}
+		if got.Verify() != nil {
+			t.Fatalf("%s doesn't verify", got.EventID)
+		}
+	}
+	_, err = r.Next()
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF at the end, got %v", err)
+	}
+
+	// A stream cut off anywhere fails rather than ending early
+	for n := 5; n < len(stream); n++ {
+		r, _ := NewReader(bytes.NewReader(stream[:n]))
+		for err == nil || err == io.EOF {
+			_, err = r.Next()
+			if err == io.EOF {
+				t.Fatalf("stream cut at %d bytes of %d ended cleanly", n, len(stream))
+			}
+		}
+		err = nil
+	}
+}
+
+func TestInvalidStreams(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("DLCB\x02")))
+	if err == nil {
+		t.Fatal("read a stream of another version")
+	}
+	_, err = NewReader(bytes.NewReader([]byte(`[{"eventId":"a"}]`)))
+	if err == nil {
+		t.Fatal("read JSON as a stream")
+	}
+
+	// A record that claims more outcomes than it holds
+	record := appendString(nil, "ev")
+	record = append(record, make([]byte, 66)...)
+	record = append(record, 0, 0, byte(dlcoracle.EventTypeEnum), 0xff, 0x01)
+	stream := append([]byte("DLCB\x01"), byte(len(record)))
+	stream = append(stream, record...)
+	r, err := NewReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.Next()
+	if !errors.Is(err, errShort) {
+		t.Fatalf("expected a short record, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Command capi exports the oracle's crypto core through a C ABI, so
+// Python, C# and C++ projects can use the reference implementation
+// directly. Build it as a shared library, which also writes the header:
+//
+//	go build -buildmode=c-shared -o libdlcoracle.so ./capi
+//
+// Keys and points are fixed size buffers: 32 bytes for private keys and
+// signatures, 33 bytes for compressed public keys and points, 65 bytes
+// for message signatures. Announcements, attestations and outcomes are
+// NUL-terminated JSON in the format of the REST API.
+//
+// Functions return NULL on success and an error message otherwise. The
+// message, like any string returned through an output parameter, is
+// allocated with malloc and must be released with dlc_free.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/mit-dci/dlc-oracle-go/mobile"
+)
+
+func main() {}
+
+// goBytes copies n bytes from a C buffer
+func goBytes(p *C.uint8_t, n C.size_t) []byte {
+	if p == nil || n == 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(p), C.int(n))
+}
+
+// put copies b into the C buffer out, which the caller sized for it
+func put(out *C.uint8_t, b []byte) {
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(out)), len(b)), b)
+}
+
+func cError(err error) *C.char {
+	if err == nil {
+		return nil
+	}
+	return C.CString(err.Error())
+}
+
+//export dlc_free
+func dlc_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+//export dlc_compute_signature_pubkey
+func dlc_compute_signature_pubkey(pubKey, rPoint, msg *C.uint8_t, msgLen C.size_t, out *C.uint8_t) *C.char {
+	point, err := mobile.ComputeSignaturePubKey(goBytes(pubKey, 33), goBytes(rPoint, 33), goBytes(msg, msgLen))
+	if err != nil {
+		return cError(err)
+	}
+	put(out, point)
+	return nil
+}
+
+//export dlc_verify_signature
+func dlc_verify_signature(pubKey, rPoint, msg *C.uint8_t, msgLen C.size_t, sig *C.uint8_t) *C.char {
+	return cError(mobile.VerifySignature(goBytes(pubKey, 33), goBytes(rPoint, 33),
+		goBytes(msg, msgLen), goBytes(sig, 32)))
+}
+
+//export dlc_verify_message
+func dlc_verify_message(pubKey, msg *C.uint8_t, msgLen C.size_t, sig *C.uint8_t) *C.char {
+	return cError(mobile.VerifyMessage(goBytes(pubKey, 33), goBytes(msg, msgLen), goBytes(sig, 65)))
+}
+
+//export dlc_numeric_message
+func dlc_numeric_message(value C.int64_t, out *C.uint8_t) *C.char {
+	msg, err := mobile.NumericMessage(int64(value))
+	if err != nil {
+		return cError(err)
+	}
+	put(out, msg)
+	return nil
+}
+
+// dlc_parse_announcement verifies an announcement and extracts its keys
+// and maturity. Output parameters may be NULL.
+//
+//export dlc_parse_announcement
+func dlc_parse_announcement(announcement *C.char, oraclePubKey, rPoint *C.uint8_t, maturity *C.int64_t, maturityHeight *C.uint32_t) *C.char {
+	a, err := mobile.ParseAnnouncement([]byte(C.GoString(announcement)))
+	if err != nil {
+		return cError(err)
+	}
+	if oraclePubKey != nil {
+		put(oraclePubKey, a.OraclePubKey())
+	}
+	if rPoint != nil {
+		put(rPoint, a.RPoint())
+	}
+	if maturity != nil {
+		*maturity = C.int64_t(a.Maturity())
+	}
+	if maturityHeight != nil {
+		*maturityHeight = C.uint32_t(a.MaturityHeight())
+	}
+	return nil
+}
+
+//export dlc_anticipation_point
+func dlc_anticipation_point(announcement, outcome *C.char, out *C.uint8_t) *C.char {
+	a, err := mobile.ParseAnnouncement([]byte(C.GoString(announcement)))
+	if err != nil {
+		return cError(err)
+	}
+	o, err := mobile.ParseOutcome([]byte(C.GoString(outcome)))
+	if err != nil {
+		return cError(err)
+	}
+	point, err := a.AnticipationPoint(o)
+	if err != nil {
+		return cError(err)
+	}
+	put(out, point)
+	return nil
+}
+
+// dlc_verify_attestation checks an attestation against its announcement
+// and stores the attested outcome, as JSON, in *outcome
+//
+//export dlc_verify_attestation
+func dlc_verify_attestation(announcement, attestation *C.char, outcome **C.char) *C.char {
+	a, err := mobile.ParseAnnouncement([]byte(C.GoString(announcement)))
+	if err != nil {
+		return cError(err)
+	}
+	o, err := a.VerifyAttestation([]byte(C.GoString(attestation)))
+	if err != nil {
+		return cError(err)
+	}
+	b, err := o.MarshalJSON()
+	if err != nil {
+		return cError(err)
+	}
+	*outcome = C.CString(string(b))
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build !verifyonly
+
+package main
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import "github.com/mit-dci/dlc-oracle-go/mobile"
+
+//export dlc_public_key
+func dlc_public_key(privKey, out *C.uint8_t) *C.char {
+	pub, err := mobile.PublicKey(goBytes(privKey, 32))
+	if err != nil {
+		return cError(err)
+	}
+	put(out, pub)
+	return nil
+}
+
+//export dlc_compute_signature
+func dlc_compute_signature(privKey, oneTimeKey, msg *C.uint8_t, msgLen C.size_t, out *C.uint8_t) *C.char {
+	sig, err := mobile.ComputeSignature(goBytes(privKey, 32), goBytes(oneTimeKey, 32), goBytes(msg, msgLen))
+	if err != nil {
+		return cError(err)
+	}
+	put(out, sig)
+	return nil
+}
+
+//export dlc_sign_message
+func dlc_sign_message(privKey, msg *C.uint8_t, msgLen C.size_t, out *C.uint8_t) *C.char {
+	sig, err := mobile.SignMessage(goBytes(privKey, 32), goBytes(msg, msgLen))
+	if err != nil {
+		return cError(err)
+	}
+	put(out, sig)
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build !verifyonly
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// TestExample builds the shared library and runs the C example against it
+func TestExample(t *testing.T) {
+	if testing.Short() || runtime.GOOS != "linux" {
+		t.Skip("builds a shared library")
+	}
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler")
+	}
+	dir := t.TempDir()
+	out, err := exec.Command("go", "build", "-buildmode=c-shared",
+		"-o", filepath.Join(dir, "libdlcoracle.so"), ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("building library: %v\n%s", err, out)
+	}
+	example := filepath.Join(dir, "example")
+	out, err = exec.Command(cc, "example/example.c", "-I", dir, "-L", dir,
+		"-ldlcoracle", "-o", example).CombinedOutput()
+	if err != nil {
+		t.Fatalf("building example: %v\n%s", err, out)
+	}
+
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	ann := dlcoracle.Announcement{
+		EventID:      "ev",
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey(k),
+		Maturity:     time.Unix(1000, 0),
+		Descriptor:   dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric},
+	}
+	err = ann.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annJSON, err := json.Marshal(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(example)
+	cmd.Env = append(os.Environ(), "LD_LIBRARY_PATH="+dir)
+	cmd.Stdin = strings.NewReader(string(annJSON) + "\n")
+	out, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("running example: %v\n%s", err, out)
+	}
+	if strings.TrimSpace(string(out)) != `{"value":42}` {
+		t.Fatalf("unexpected output %s", out)
+	}
+}
@@ -0,0 +1,61 @@
+// example.c computes an anticipation point and verifies an attestation
+// with libdlcoracle. Build the library, then:
+//
+//   cc example.c -I. -L. -ldlcoracle -o example
+#include <stdio.h>
+#include <string.h>
+
+#include "libdlcoracle.h"
+
+static int check(char *err, const char *what) {
+	if (err == NULL) {
+		return 0;
+	}
+	fprintf(stderr, "%s: %s\n", what, err);
+	dlc_free(err);
+	return 1;
+}
+
+int main(void) {
+	uint8_t priv[32] = {0}, k[32] = {0};
+	uint8_t pub[33], r[33], point[33], expected[33], msg[32], sig[32];
+	char announcement[1024], attestation[512], hex[65];
+	char *outcome = NULL;
+	int i;
+
+	priv[31] = 1;
+	k[31] = 2;
+	if (check(dlc_public_key(priv, pub), "public key") ||
+	    check(dlc_public_key(k, r), "R point") ||
+	    check(dlc_numeric_message(42, msg), "numeric message") ||
+	    check(dlc_compute_signature(priv, k, msg, sizeof msg, sig), "sign") ||
+	    check(dlc_verify_signature(pub, r, msg, sizeof msg, sig), "verify") ||
+	    check(dlc_compute_signature_pubkey(pub, r, msg, sizeof msg, expected), "signature point")) {
+		return 1;
+	}
+
+	// The announcement is read from stdin, signed by the key above with R
+	// point r for a numeric event
+	if (fgets(announcement, sizeof announcement, stdin) == NULL) {
+		return 1;
+	}
+	if (check(dlc_anticipation_point(announcement, "{\"value\":42}", point), "anticipation point")) {
+		return 1;
+	}
+	if (memcmp(point, expected, sizeof point) != 0) {
+		fprintf(stderr, "anticipation point mismatch\n");
+		return 1;
+	}
+
+	for (i = 0; i < 32; i++) {
+		sprintf(hex + 2 * i, "%02x", sig[i]);
+	}
+	snprintf(attestation, sizeof attestation,
+		"{\"eventId\":\"ev\",\"message\":\"%064x\",\"signature\":\"%s\"}", 42, hex);
+	if (check(dlc_verify_attestation(announcement, attestation, &outcome), "attestation")) {
+		return 1;
+	}
+	printf("%s\n", outcome);
+	dlc_free(outcome);
+	return 0;
+}
@@ -0,0 +1,151 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+// rpcErrInvalidParameter is bitcoind's error code for a block height out
+// of range
+const rpcErrInvalidParameter = -8
+
+// Bitcoind is a Backend talking to bitcoind's JSON-RPC interface
+type Bitcoind struct {
+	url      string
+	user     string
+	password string
+	client   *http.Client
+	id       atomic.Uint64
+}
+
+// NewBitcoind returns a backend for the bitcoind RPC server at url (for
+// instance http://localhost:8332), authenticating with user and password
+func NewBitcoind(url, user, password string) *Bitcoind {
+	return &Bitcoind{
+		url:      url,
+		user:     user,
+		password: password,
+		client:   http.DefaultClient,
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for RPC calls
+func (b *Bitcoind) SetHTTPClient(c *http.Client) {
+	b.client = c
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("bitcoind error %d: %s", e.Code, e.Message)
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func (b *Bitcoind) call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "1.0",
+		ID:      b.id.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(b.user, b.password)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("bitcoind rejected the RPC credentials")
+	}
+
+	// bitcoind answers errors with a non-200 status and a JSON body
+	var r rpcResponse
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return fmt.Errorf("%s: decoding bitcoind response (%s): %w", method, resp.Status, err)
+	}
+	if r.Error != nil {
+		return r.Error
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// BlockHeight implements Backend
+func (b *Bitcoind) BlockHeight(ctx context.Context) (uint32, error) {
+	var height uint32
+	err := b.call(ctx, "getblockcount", &height)
+	return height, err
+}
+
+// BlockHash implements Backend
+func (b *Bitcoind) BlockHash(ctx context.Context, height uint32) ([32]byte, error) {
+	var hash [32]byte
+	var s string
+	err := b.call(ctx, "getblockhash", &s, height)
+	if e, ok := err.(*rpcError); ok && e.Code == rpcErrInvalidParameter {
+		return hash, ErrBlockNotFound
+	}
+	if err != nil {
+		return hash, err
+	}
+	return hash, decodeHash(hash[:], s)
+}
+
+// FeeRate implements Backend using estimatesmartfee
+func (b *Bitcoind) FeeRate(ctx context.Context, target int) (float64, error) {
+	var r struct {
+		FeeRate float64  `json:"feerate"`
+		Errors  []string `json:"errors"`
+	}
+	err := b.call(ctx, "estimatesmartfee", &r, target)
+	if err != nil {
+		return 0, err
+	}
+	if r.FeeRate == 0 {
+		return 0, fmt.Errorf("no fee estimate for %d blocks: %v", target, r.Errors)
+	}
+	// bitcoind reports BTC/kvB
+	return r.FeeRate * 1e8 / 1000, nil
+}
+
+func decodeHash(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("block hash has length %d", len(b))
+	}
+	copy(dst, b)
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package chain lets an oracle follow the Bitcoin chain, so events can
+// mature at a block height and data sources can attest block hashes and
+// fee rates. Backend is implemented for bitcoind's JSON-RPC interface and
+// for Esplora's REST API.
+package chain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBlockNotFound is returned for heights beyond the chain tip
+var ErrBlockNotFound = errors.New("block not found")
+
+// Backend provides the state of the Bitcoin chain
+type Backend interface {
+	// BlockHeight returns the height of the chain tip
+	BlockHeight(ctx context.Context) (uint32, error)
+
+	// BlockHash returns the hash of the block at height in the usual
+	// byte order, as displayed by block explorers
+	BlockHash(ctx context.Context, height uint32) ([32]byte, error)
+
+	// FeeRate returns the estimated fee rate in sat/vB for confirmation
+	// within target blocks
+	FeeRate(ctx context.Context, target int) (float64, error)
+}
@@ -0,0 +1,100 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+func TestBitcoind(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		if user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req rpcRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "getblockcount":
+			w.Write([]byte(`{"result":800000,"error":null,"id":1}`))
+		case "getblockhash":
+			if req.Params[0].(float64) > 800000 {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte(`{"result":null,"error":{"code":-8,"message":"Block height out of range"},"id":1}`))
+				return
+			}
+			w.Write([]byte(`{"result":"` + testHash + `","error":null,"id":1}`))
+		case "estimatesmartfee":
+			w.Write([]byte(`{"result":{"feerate":0.00012,"blocks":6},"error":null,"id":1}`))
+		}
+	}))
+	defer ts.Close()
+
+	b := NewBitcoind(ts.URL, "user", "pass")
+	ctx := context.Background()
+	h, err := b.BlockHeight(ctx)
+	if err != nil || h != 800000 {
+		t.Fatalf("unexpected height %d %v", h, err)
+	}
+	hash, err := b.BlockHash(ctx, 0)
+	if err != nil || hash[31] != 0x6f {
+		t.Fatalf("unexpected hash %x %v", hash, err)
+	}
+	_, err = b.BlockHash(ctx, 800001)
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+	rate, err := b.FeeRate(ctx, 6)
+	if err != nil || rate != 12 {
+		t.Fatalf("unexpected fee rate %v %v", rate, err)
+	}
+
+	_, err = NewBitcoind(ts.URL, "user", "wrong").BlockHeight(ctx)
+	if err == nil {
+		t.Fatal("expected authentication error")
+	}
+}
+
+func TestEsplora(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blocks/tip/height", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("800000"))
+	})
+	mux.HandleFunc("/block-height/0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testHash))
+	})
+	mux.HandleFunc("/fee-estimates", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"1":30.5,"3":20,"6":12.1,"144":1}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	e := NewEsplora(ts.URL + "/")
+	ctx := context.Background()
+	h, err := e.BlockHeight(ctx)
+	if err != nil || h != 800000 {
+		t.Fatalf("unexpected height %d %v", h, err)
+	}
+	hash, err := e.BlockHash(ctx, 0)
+	if err != nil || hash[0] != 0 || hash[31] != 0x6f {
+		t.Fatalf("unexpected hash %x %v", hash, err)
+	}
+	_, err = e.BlockHash(ctx, 1)
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+	rate, err := e.FeeRate(ctx, 10)
+	if err != nil || rate != 12.1 {
+		t.Fatalf("unexpected fee rate %v %v", rate, err)
+	}
+	_, err = e.FeeRate(ctx, 0)
+	if err == nil {
+		t.Fatal("expected error for a target below all estimates")
+	}
+}
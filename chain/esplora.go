@@ -0,0 +1,107 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Esplora is a Backend using the REST API of an Esplora instance, such as
+// https://blockstream.info/api
+type Esplora struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewEsplora returns a backend for the Esplora API at baseURL
+func NewEsplora(baseURL string) *Esplora {
+	return &Esplora{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  http.DefaultClient,
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests
+func (e *Esplora) SetHTTPClient(c *http.Client) {
+	e.client = c
+}
+
+func (e *Esplora) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBlockNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("esplora returned %s for %s", resp.Status, path)
+	}
+	return body, nil
+}
+
+// BlockHeight implements Backend
+func (e *Esplora) BlockHeight(ctx context.Context) (uint32, error) {
+	body, err := e.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	h, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 32)
+	return uint32(h), err
+}
+
+// BlockHash implements Backend
+func (e *Esplora) BlockHash(ctx context.Context, height uint32) ([32]byte, error) {
+	var hash [32]byte
+	body, err := e.get(ctx, fmt.Sprintf("/block-height/%d", height))
+	if err != nil {
+		return hash, err
+	}
+	return hash, decodeHash(hash[:], strings.TrimSpace(string(body)))
+}
+
+// FeeRate implements Backend. Esplora estimates a fixed set of targets;
+// the estimate for the largest target not above target is used.
+func (e *Esplora) FeeRate(ctx context.Context, target int) (float64, error) {
+	body, err := e.get(ctx, "/fee-estimates")
+	if err != nil {
+		return 0, err
+	}
+	var estimates map[string]float64
+	err = json.Unmarshal(body, &estimates)
+	if err != nil {
+		return 0, err
+	}
+	targets := make([]int, 0, len(estimates))
+	for k := range estimates {
+		t, err := strconv.Atoi(k)
+		if err == nil {
+			targets = append(targets, t)
+		}
+	}
+	sort.Ints(targets)
+	best := -1
+	for _, t := range targets {
+		if t <= target {
+			best = t
+		}
+	}
+	if best < 0 {
+		return 0, fmt.Errorf("no fee estimate for %d blocks", target)
+	}
+	return estimates[strconv.Itoa(best)], nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import "sync"
+
+// maxCachedResponses bounds the number of responses an HTTP backend
+// keeps for revalidation
+const maxCachedResponses = 1024
+
+// cachedResponse is a response body along with the ETag the server gave it
+type cachedResponse struct {
+	etag string
+	body []byte
+}
+
+// responseCache keeps the last response of each path that came with an
+// ETag, so requests can ask the server to answer 304 Not Modified instead
+// of sending the same body again
+type responseCache struct {
+	mtx     sync.Mutex
+	entries map[string]cachedResponse
+}
+
+func (c *responseCache) get(path string) (cachedResponse, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	r, ok := c.entries[path]
+	return r, ok
+}
+
+// put records the response of path. When the cache is full an arbitrary
+// entry makes room, which costs at most one full response later.
+func (c *responseCache) put(path string, r cachedResponse) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedResponse)
+	}
+	if _, ok := c.entries[path]; !ok && len(c.entries) >= maxCachedResponses {
+		for p := range c.entries {
+			delete(c.entries, p)
+			break
+		}
+	}
+	c.entries[path] = r
+}
@@ -0,0 +1,300 @@
+// Package client fetches announcements and attestations from a remote
+// oracle and verifies them locally, so wallets don't have to trust the
+// server they talk to. The oracle is identified by its public key, which
+// the caller pins when creating the Client; everything the server returns
+// has to be signed by that key.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+var (
+	// ErrNotFound is returned when the oracle has no announcement or
+	// attestation for an event, for instance because it hasn't attested
+	// yet
+	ErrNotFound = errors.New("not found")
+
+	// ErrWrongOracle is returned when a record is for another oracle
+	// public key than the pinned one
+	ErrWrongOracle = errors.New("record is from another oracle")
+
+	// ErrInvalidSignature is returned when a record's signature doesn't
+	// verify, or an attestation doesn't sign a possible outcome
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrEventMismatch is returned when the server answers with a record
+	// for another event than the one requested
+	ErrEventMismatch = errors.New("record is for another event")
+
+	// ErrRevoked is returned when the oracle has revoked the event and
+	// will never attest it
+	ErrRevoked = errors.New("event was revoked")
+)
+
+// RevokedError is returned for events the oracle revoked, with its
+// verified revocation. It wraps ErrRevoked.
+type RevokedError struct {
+	Revocation dlcoracle.Revocation
+}
+
+func (e *RevokedError) Error() string {
+	msg := fmt.Sprintf("event %s was revoked", e.Revocation.EventID)
+	if e.Revocation.Reason != "" {
+		msg += ": " + e.Revocation.Reason
+	}
+	if e.Revocation.SupersededBy != "" {
+		msg += fmt.Sprintf(" (superseded by %s)", e.Revocation.SupersededBy)
+	}
+	return msg
+}
+
+func (e *RevokedError) Unwrap() error {
+	return ErrRevoked
+}
+
+// ServerError is returned when the server fails a request for another
+// reason than the record not existing
+type ServerError struct {
+	// Code is the HTTP status or gRPC code
+	Code    int
+	Message string
+
+	// Temporary is set for errors worth retrying, such as overload or
+	// internal errors of the server
+	Temporary bool
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("oracle server error %d: %s", e.Code, e.Message)
+}
+
+// Backend retrieves unverified records from an oracle server. HTTP talks
+// to the REST API and GRPC to the gRPC service.
+type Backend interface {
+	Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error)
+	Announcements(ctx context.Context) ([]dlcoracle.Announcement, error)
+	Attestation(ctx context.Context, eventID string) (dlcoracle.Attestation, error)
+}
+
+// RevocationBackend is implemented by backends that can retrieve the
+// revocations of events. HTTP implements it.
+type RevocationBackend interface {
+	Revocation(ctx context.Context, eventID string) (dlcoracle.Revocation, error)
+}
+
+// Client fetches and verifies the records of a single oracle. Requests
+// to the server are retried with exponential backoff and jitter, and a
+// circuit breaker fails them early while the server is down. Concurrent
+// requests for the same record are sent only once.
+type Client struct {
+	backend Backend
+	pubKey  [33]byte
+	cache   Cache
+	retry   RetryPolicy
+	breaker *breaker
+	flight  flightGroup
+	tracker *Tracker
+}
+
+// New returns a client for the oracle with public key pubKey, reached
+// through backend
+func New(backend Backend, pubKey [33]byte) *Client {
+	return &Client{
+		backend: backend,
+		pubKey:  pubKey,
+		retry:   DefaultRetryPolicy,
+		breaker: &breaker{
+			threshold: DefaultBreakerThreshold,
+			cooldown:  DefaultBreakerCooldown,
+		},
+	}
+}
+
+// SetCache makes the client keep verified announcements and attestations
+// in c and answer from it
+func (c *Client) SetCache(cache Cache) {
+	c.cache = cache
+}
+
+// SetRetryPolicy changes how failed requests are retried
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// SetBreaker changes after how many consecutive failures the circuit
+// opens, and for how long
+func (c *Client) SetBreaker(threshold int, cooldown time.Duration) {
+	c.breaker = &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// SetTracker makes the client record every verified announcement and
+// attestation it fetches in t. Records answered from the cache aren't
+// recorded again.
+func (c *Client) SetTracker(t *Tracker) {
+	c.tracker = t
+}
+
+// PubKey returns the pinned public key of the oracle
+func (c *Client) PubKey() [33]byte {
+	return c.pubKey
+}
+
+// checkAnnouncement verifies that a is a valid announcement of the
+// pinned oracle
+func (c *Client) checkAnnouncement(a dlcoracle.Announcement) error {
+	if a.OraclePubKey != c.pubKey {
+		return fmt.Errorf("announcement of %s signed by %x: %w", a.EventID, a.OraclePubKey, ErrWrongOracle)
+	}
+	err := a.Verify()
+	if err != nil {
+		return fmt.Errorf("announcement of %s: %w", a.EventID, ErrInvalidSignature)
+	}
+	return nil
+}
+
+// Announcement fetches and verifies the announcement of an event
+func (c *Client) Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error) {
+	if c.cache != nil {
+		a, ok := c.cache.Announcement(c.pubKey, eventID)
+		if ok {
+			return a, nil
+		}
+	}
+	a, err := fetch(ctx, c, "announcement/"+eventID, func(ctx context.Context) (dlcoracle.Announcement, error) {
+		return c.backend.Announcement(ctx, eventID)
+	})
+	if err != nil {
+		return a, err
+	}
+	if a.EventID != eventID {
+		return a, fmt.Errorf("asked for %s, got %s: %w", eventID, a.EventID, ErrEventMismatch)
+	}
+	err = c.checkAnnouncement(a)
+	if err != nil {
+		return a, err
+	}
+	if c.tracker != nil {
+		c.tracker.recordAnnouncement(a)
+	}
+	if c.cache != nil {
+		c.cache.PutAnnouncement(c.pubKey, a)
+	}
+	return a, nil
+}
+
+// Announcements fetches and verifies all announcements of the oracle.
+// It fails if any of them doesn't verify.
+func (c *Client) Announcements(ctx context.Context) ([]dlcoracle.Announcement, error) {
+	list, err := fetch(ctx, c, "announcements", c.backend.Announcements)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range list {
+		err = c.checkAnnouncement(a)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.tracker != nil {
+		for _, a := range list {
+			c.tracker.recordAnnouncement(a)
+		}
+	}
+	if c.cache != nil {
+		for _, a := range list {
+			c.cache.PutAnnouncement(c.pubKey, a)
+		}
+	}
+	return list, nil
+}
+
+// Attestation fetches the attestation of an event and verifies it against
+// the event's announcement. It returns the verified announcement too,
+// since it is needed to interpret the attestation. Events the oracle
+// revoked fail with a *RevokedError.
+func (c *Client) Attestation(ctx context.Context, eventID string) (dlcoracle.Announcement, dlcoracle.Attestation, error) {
+	var att dlcoracle.Attestation
+	ann, err := c.Announcement(ctx, eventID)
+	if err != nil {
+		return ann, att, err
+	}
+	if c.cache != nil {
+		cached, ok := c.cache.Attestation(c.pubKey, eventID)
+		if ok {
+			return ann, cached, nil
+		}
+	}
+	att, err = fetch(ctx, c, "attestation/"+eventID, func(ctx context.Context) (dlcoracle.Attestation, error) {
+		return c.backend.Attestation(ctx, eventID)
+	})
+	if errors.Is(err, ErrNotFound) {
+		// Tell events that will never be attested from those that
+		// weren't yet
+		r, revErr := c.revocation(ctx, ann)
+		if revErr == nil {
+			return ann, att, &RevokedError{Revocation: r}
+		}
+		if !errors.Is(revErr, ErrNotFound) {
+			return ann, att, revErr
+		}
+	}
+	if err != nil {
+		return ann, att, err
+	}
+	// The errors wrap both this package's error and the one of
+	// dlcoracle.VerifyAttestation, which tells more precisely what's wrong
+	_, err = dlcoracle.VerifyAttestation(ann, att)
+	if errors.Is(err, dlcoracle.ErrEventMismatch) {
+		return ann, att, fmt.Errorf("%w: %w", ErrEventMismatch, err)
+	}
+	if err != nil {
+		return ann, att, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if c.tracker != nil {
+		c.tracker.recordAttestation(ann, att)
+	}
+	if c.cache != nil {
+		c.cache.PutAttestation(c.pubKey, att)
+	}
+	return ann, att, nil
+}
+
+// Revocation fetches the revocation of an event and verifies it against
+// the event's announcement. It fails with ErrNotFound if the event wasn't
+// revoked, or the backend can't retrieve revocations.
+func (c *Client) Revocation(ctx context.Context, eventID string) (dlcoracle.Revocation, error) {
+	ann, err := c.Announcement(ctx, eventID)
+	if err != nil {
+		return dlcoracle.Revocation{}, err
+	}
+	return c.revocation(ctx, ann)
+}
+
+// revocation fetches and verifies the revocation of the event announced
+// in ann
+func (c *Client) revocation(ctx context.Context, ann dlcoracle.Announcement) (dlcoracle.Revocation, error) {
+	rb, ok := c.backend.(RevocationBackend)
+	if !ok {
+		return dlcoracle.Revocation{}, fmt.Errorf("revocation of %s: %w", ann.EventID, ErrNotFound)
+	}
+	r, err := fetch(ctx, c, "revocation/"+ann.EventID, func(ctx context.Context) (dlcoracle.Revocation, error) {
+		return rb.Revocation(ctx, ann.EventID)
+	})
+	if err != nil {
+		return r, err
+	}
+	err = dlcoracle.VerifyRevocation(ann, r)
+	if errors.Is(err, dlcoracle.ErrRevocationMismatch) {
+		return r, fmt.Errorf("%w: %w", ErrEventMismatch, err)
+	}
+	if err != nil {
+		return r, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,392 @@
+//go:build !verifyonly
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/rpc"
+	"github.com/mit-dci/dlc-oracle-go/server"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestOracle returns an oracle that has attested "done" and announced
+// "pending"
+func newTestOracle(t *testing.T) *oracle.Oracle {
+	t.Helper()
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	enum := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "done", Maturity: time.Unix(1000, 0), Descriptor: enum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.AttestOutcome("done", dlcoracle.Outcome{Label: "yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "pending", Maturity: time.Now().Add(time.Hour), Descriptor: enum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func newHTTPBackend(t *testing.T, o *oracle.Oracle) Backend {
+	ts := httptest.NewServer(server.NewServer(o.PubKey(), o.Store()))
+	t.Cleanup(ts.Close)
+	return NewHTTP(ts.URL)
+}
+
+func newGRPCBackend(t *testing.T, o *oracle.Oracle) Backend {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	rpc.RegisterOracleServer(s, rpc.NewServer(o))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return NewGRPC(cc)
+}
+
+func TestBackends(t *testing.T) {
+	backends := map[string]func(*testing.T, *oracle.Oracle) Backend{
+		"http": newHTTPBackend,
+		"grpc": newGRPCBackend,
+	}
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			o := newTestOracle(t)
+			c := New(newBackend(t, o), o.PubKey())
+			ctx := context.Background()
+
+			list, err := c.Announcements(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 {
+				t.Fatalf("got %d announcements", len(list))
+			}
+			_, att, err := c.Attestation(ctx, "done")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(att.Message) != "yes" {
+				t.Fatalf("attested %q", att.Message)
+			}
+			_, _, err = c.Attestation(ctx, "pending")
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			_, err = c.Announcement(ctx, "unknown")
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+
+			other := New(newBackend(t, o), dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 2}))
+			_, err = other.Announcement(ctx, "done")
+			if !errors.Is(err, ErrWrongOracle) {
+				t.Fatalf("expected ErrWrongOracle, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHTTPPubKey(t *testing.T) {
+	o := newTestOracle(t)
+	pub, err := newHTTPBackend(t, o).(*HTTP).PubKey(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub != o.PubKey() {
+		t.Fatal("wrong public key")
+	}
+}
+
+func TestHTTPRevalidation(t *testing.T) {
+	o := newTestOracle(t)
+	srv := server.NewServer(o.PubKey(), o.Store())
+	var mtx sync.Mutex
+	var statuses []int
+	srv.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			mtx.Lock()
+			statuses = append(statuses, rec.status)
+			mtx.Unlock()
+		})
+	})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	h := NewHTTP(ts.URL)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		a, err := h.Announcement(ctx, "done")
+		if err != nil || a.EventID != "done" {
+			t.Fatalf("announcement %+v %v", a, err)
+		}
+		list, err := h.Announcements(ctx)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("announcements %+v %v", list, err)
+		}
+	}
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "new", Maturity: time.Now().Add(time.Hour),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := h.Announcements(ctx)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("announcements after a new event %+v %v", list, err)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusNotModified, http.StatusNotModified, http.StatusOK}
+	ts.Close()
+	mtx.Lock()
+	defer mtx.Unlock()
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Fatalf("server answered %v, want %v", statuses, want)
+	}
+}
+
+func TestHTTPBulkAnnouncements(t *testing.T) {
+	o := newTestOracle(t)
+	h := newHTTPBackend(t, o).(*HTTP)
+	var ids []string
+	err := h.BulkAnnouncements(context.Background(), nil, func(a dlcoracle.Announcement) error {
+		ids = append(ids, a.EventID)
+		return a.Verify()
+	})
+	if err != nil || fmt.Sprint(ids) != "[done pending]" {
+		t.Fatalf("streamed %v %v", ids, err)
+	}
+
+	stop := errors.New("stop")
+	ids = nil
+	err = h.BulkAnnouncements(context.Background(), url.Values{"after": {"done"}}, func(a dlcoracle.Announcement) error {
+		ids = append(ids, a.EventID)
+		return stop
+	})
+	if err != stop || fmt.Sprint(ids) != "[pending]" {
+		t.Fatalf("streamed %v %v", ids, err)
+	}
+	err = h.BulkAnnouncements(context.Background(), url.Values{"after": {"unknown"}}, nil)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a bad request, got %v", err)
+	}
+}
+
+func TestHTTPExportHistory(t *testing.T) {
+	o := newTestOracle(t)
+	h := newHTTPBackend(t, o).(*HTTP)
+	var buf bytes.Buffer
+	err := h.ExportHistory(context.Background(), "", nil, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "event_id,maturity,maturity_height,type,unit,unit_exponent,value,label,outcome\n" +
+		"done,1970-01-01T00:16:40Z,,enum,,0,,yes,yes\n"
+	if buf.String() != want {
+		t.Fatalf("exported %q", buf.String())
+	}
+
+	buf.Reset()
+	err = h.ExportHistory(context.Background(), "json", url.Values{"type": {"enum"}}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []struct {
+		EventID string `json:"eventId"`
+		Label   string `json:"label"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &list)
+	if err != nil || len(list) != 1 || list[0].EventID != "done" || list[0].Label != "yes" {
+		t.Fatalf("exported %s: %v", buf.Bytes(), err)
+	}
+
+	err = h.ExportHistory(context.Background(), "xml", nil, &buf)
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != http.StatusBadRequest {
+		t.Fatalf("expected a bad request, got %v", err)
+	}
+}
+
+// statusRecorder records the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// tamperingBackend modifies the records of another backend
+type tamperingBackend struct {
+	Backend
+	announcement func(*dlcoracle.Announcement)
+	attestation  func(*dlcoracle.Attestation)
+}
+
+func (b tamperingBackend) Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error) {
+	a, err := b.Backend.Announcement(ctx, eventID)
+	if b.announcement != nil {
+		b.announcement(&a)
+	}
+	return a, err
+}
+
+func (b tamperingBackend) Attestation(ctx context.Context, eventID string) (dlcoracle.Attestation, error) {
+	a, err := b.Backend.Attestation(ctx, eventID)
+	if b.attestation != nil {
+		b.attestation(&a)
+	}
+	return a, err
+}
+
+func TestTampering(t *testing.T) {
+	o := newTestOracle(t)
+	inner := newHTTPBackend(t, o)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		b    tamperingBackend
+		want error
+	}{
+		{"r point", tamperingBackend{announcement: func(a *dlcoracle.Announcement) {
+			a.RPoint = dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 3})
+		}}, ErrInvalidSignature},
+		{"event id", tamperingBackend{announcement: func(a *dlcoracle.Announcement) {
+			a.EventID = "pending"
+		}}, ErrEventMismatch},
+		{"outcome", tamperingBackend{attestation: func(a *dlcoracle.Attestation) {
+			a.Message = []byte("no")
+		}}, ErrInvalidSignature},
+		{"attestation event", tamperingBackend{attestation: func(a *dlcoracle.Attestation) {
+			a.EventID = "pending"
+		}}, ErrEventMismatch},
+	}
+	for _, tt := range tests {
+		tt.b.Backend = inner
+		_, _, err := New(tt.b, o.PubKey()).Attestation(ctx, "done")
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"disk on fire"}`))
+	}))
+	defer ts.Close()
+	_, err := New(NewHTTP(ts.URL), [33]byte{}).Announcement(context.Background(), "x")
+	var se *ServerError
+	if !errors.As(err, &se) || se.Code != http.StatusInternalServerError || se.Message != "disk on fire" {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+}
+
+func TestMalformedAnnouncement(t *testing.T) {
+	o := newTestOracle(t)
+	a, err := o.Store().Announcement("pending")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// digit r points on an event that isn't a digits event decode, but
+	// don't parse
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	json.Unmarshal(b, &m)
+	m["rPoints"] = []string{m["rPoint"].(string)}
+	b, _ = json.Marshal(m)
+	var decoded dlcoracle.Announcement
+	if json.Unmarshal(b, &decoded) != nil {
+		t.Fatal("announcement doesn't decode")
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/announcements" {
+			w.Write([]byte("[" + string(b) + "]"))
+			return
+		}
+		w.Write(b)
+	}))
+	defer ts.Close()
+	h := NewHTTP(ts.URL)
+	_, err = h.Announcement(context.Background(), "pending")
+	if !errors.Is(err, dlcoracle.ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord, got %v", err)
+	}
+	_, err = h.Announcements(context.Background())
+	if !errors.Is(err, dlcoracle.ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord, got %v", err)
+	}
+}
+
+func TestRevocation(t *testing.T) {
+	o := newTestOracle(t)
+	_, err := o.Revoke("pending", "cancelled", "rescheduled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(newHTTPBackend(t, o), o.PubKey())
+	ctx := context.Background()
+
+	r, err := c.Revocation(ctx, "pending")
+	if err != nil || r.SupersededBy != "rescheduled" {
+		t.Fatalf("got %+v, %v", r, err)
+	}
+	_, _, err = c.Attestation(ctx, "pending")
+	var re *RevokedError
+	if !errors.As(err, &re) || !errors.Is(err, ErrRevoked) || re.Revocation.Reason != "cancelled" {
+		t.Fatalf("expected a revocation, got %v", err)
+	}
+	_, err = c.Revocation(ctx, "done")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Backends without revocations report events as not attested yet
+	_, _, err = New(tamperingBackend{Backend: newHTTPBackend(t, o)}, o.PubKey()).Attestation(ctx, "pending")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
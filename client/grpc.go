@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPC is a Backend using an oracle's gRPC service
+type GRPC struct {
+	client rpc.OracleClient
+	opts   []grpc.CallOption
+}
+
+// NewGRPC returns a backend for the oracle service on cc. The call
+// options, such as credentials, are added to every call.
+func NewGRPC(cc grpc.ClientConnInterface, opts ...grpc.CallOption) *GRPC {
+	return &GRPC{client: rpc.NewOracleClient(cc), opts: opts}
+}
+
+// fromStatus turns gRPC errors into the errors of this package
+func fromStatus(err error) error {
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if s.Code() == codes.NotFound {
+		return fmt.Errorf("%s: %w", s.Message(), ErrNotFound)
+	}
+	var temporary bool
+	switch s.Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown:
+		temporary = true
+	}
+	return &ServerError{Code: int(s.Code()), Message: s.Message(), Temporary: temporary}
+}
+
+// Announcement implements Backend
+func (g *GRPC) Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error) {
+	a, err := g.client.GetAnnouncement(ctx, &rpc.GetAnnouncementRequest{EventID: eventID}, g.opts...)
+	if err != nil {
+		return dlcoracle.Announcement{}, fromStatus(err)
+	}
+	return *a, nil
+}
+
+// grpcPageSize is the number of events Announcements asks for at a time
+const grpcPageSize = 1000
+
+// Announcements implements Backend, a page of events at a time. Servers
+// that don't paginate return all of them at once.
+func (g *GRPC) Announcements(ctx context.Context) ([]dlcoracle.Announcement, error) {
+	var list []dlcoracle.Announcement
+	req := &rpc.ListEventsRequest{Limit: grpcPageSize}
+	for {
+		res, err := g.client.ListEvents(ctx, req, g.opts...)
+		if err != nil {
+			return nil, fromStatus(err)
+		}
+		for _, ev := range res.Events {
+			list = append(list, ev.Announcement)
+		}
+		if res.Next == "" {
+			return list, nil
+		}
+		req.After = res.Next
+	}
+}
+
+// Attestation implements Backend. The service has no call for a single
+// attestation, so it lists the events maturing in the same second as the
+// event's announcement.
+func (g *GRPC) Attestation(ctx context.Context, eventID string) (dlcoracle.Attestation, error) {
+	a, err := g.Announcement(ctx, eventID)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	m := a.Maturity.Unix()
+	res, err := g.client.ListEvents(ctx, &rpc.ListEventsRequest{MaturesAfter: m, MaturesBefore: m + 1}, g.opts...)
+	if err != nil {
+		return dlcoracle.Attestation{}, fromStatus(err)
+	}
+	for _, ev := range res.Events {
+		if ev.Announcement.EventID != eventID {
+			continue
+		}
+		if ev.Attestation == nil {
+			break
+		}
+		return *ev.Attestation, nil
+	}
+	return dlcoracle.Attestation{}, fmt.Errorf("attestation of %s: %w", eventID, ErrNotFound)
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/bulk"
+)
+
+// maxResponseSize limits how much of a response is read
+const maxResponseSize = 16 << 20
+
+// HTTP is a Backend using an oracle's REST API. It keeps the responses
+// the server tagged with an ETag and revalidates them with If-None-Match,
+// so polling for records that haven't changed transfers almost nothing.
+type HTTP struct {
+	baseURL string
+	client  *http.Client
+	cache   responseCache
+}
+
+// NewHTTP returns a backend for the REST API at baseURL, such as
+// https://oracle.example.com
+func NewHTTP(baseURL string) *HTTP {
+	return &HTTP{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests
+func (h *HTTP) SetHTTPClient(c *http.Client) {
+	h.client = c
+}
+
+func (h *HTTP) get(ctx context.Context, path string, v interface{}) error {
+	body, err := h.getBody(ctx, path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("%s: decoding response: %v", path, err)
+	}
+	return nil
+}
+
+// getBody requests path and returns the body of the successful response,
+// from the cache if the server says it hasn't changed
+func (h *HTTP) getBody(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := h.cache.get(path)
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && ok {
+		resp.StatusCode, body = http.StatusOK, cached.body
+	} else if tag := resp.Header.Get("ETag"); resp.StatusCode == http.StatusOK && tag != "" {
+		h.cache.put(path, cachedResponse{etag: tag, body: body})
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(path, resp, body)
+	}
+	return body, nil
+}
+
+// responseError returns the error of an unsuccessful response to a
+// request for path with the body
+func responseError(path string, resp *http.Response, body []byte) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	var e struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(body, &e)
+	if e.Error == "" {
+		e.Error = resp.Status
+	}
+	return &ServerError{
+		Code:      resp.StatusCode,
+		Message:   e.Error,
+		Temporary: resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+	}
+}
+
+// PubKey fetches the public key the oracle claims. Only use it to
+// discover an oracle; verifying records against a key learned from the
+// same server proves nothing.
+func (h *HTTP) PubKey(ctx context.Context) ([33]byte, error) {
+	var pub [33]byte
+	var res struct {
+		PubKey string `json:"pubKey"`
+	}
+	err := h.get(ctx, "/api/pubkey", &res)
+	if err != nil {
+		return pub, err
+	}
+	b, err := hex.DecodeString(res.PubKey)
+	if err != nil || len(b) != len(pub) {
+		return pub, fmt.Errorf("invalid public key %q", res.PubKey)
+	}
+	copy(pub[:], b)
+	return pub, nil
+}
+
+// Announcement implements Backend. Records are decoded with
+// dlcoracle.ParseAnnouncement and dlcoracle.ParseAttestation, so malformed
+// ones are rejected before they are verified.
+func (h *HTTP) Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error) {
+	path := "/api/announcements/" + url.PathEscape(eventID)
+	body, err := h.getBody(ctx, path)
+	if err != nil {
+		return dlcoracle.Announcement{}, err
+	}
+	a, err := dlcoracle.ParseAnnouncement(body)
+	if err != nil {
+		return a, fmt.Errorf("%s: decoding response: %w", path, err)
+	}
+	return a, nil
+}
+
+// Announcements implements Backend
+func (h *HTTP) Announcements(ctx context.Context) ([]dlcoracle.Announcement, error) {
+	path := "/api/announcements"
+	var raw []json.RawMessage
+	err := h.get(ctx, path, &raw)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]dlcoracle.Announcement, len(raw))
+	for i, b := range raw {
+		list[i], err = dlcoracle.ParseAnnouncement(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: decoding announcement %d: %w", path, i, err)
+		}
+	}
+	return list, nil
+}
+
+// Attestation implements Backend
+func (h *HTTP) Attestation(ctx context.Context, eventID string) (dlcoracle.Attestation, error) {
+	path := "/api/attestations/" + url.PathEscape(eventID)
+	body, err := h.getBody(ctx, path)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	a, err := dlcoracle.ParseAttestation(body)
+	if err != nil {
+		return a, fmt.Errorf("%s: decoding response: %w", path, err)
+	}
+	return a, nil
+}
+
+// Revocation implements RevocationBackend
+func (h *HTTP) Revocation(ctx context.Context, eventID string) (dlcoracle.Revocation, error) {
+	var r dlcoracle.Revocation
+	err := h.get(ctx, "/api/revocations/"+url.PathEscape(eventID), &r)
+	return r, err
+}
+
+// BulkAnnouncements streams the announcements selected by query, with the
+// filters and pagination parameters of the REST listings, from the
+// server's bulk endpoint and calls fn with each until it returns an
+// error. The stream is compressed with zstd or gzip. Announcements aren't
+// verified; Client.Announcements is the verified way to list them.
+func (h *HTTP) BulkAnnouncements(ctx context.Context, query url.Values, fn func(dlcoracle.Announcement) error) error {
+	path := "/api/bulk/announcements"
+	body, closeBody, err := h.getStream(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer closeBody()
+	r, err := bulk.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for {
+		a, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		err = fn(a)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExportHistory copies the history of the attested events selected by
+// query, with the filters and pagination parameters of the REST listings,
+// from the server's bulk endpoint to w: as CSV with a header row, or as a
+// JSON array if format is "json". The outcomes aren't verified; check the
+// attestations with Client.Attestation where that matters.
+func (h *HTTP) ExportHistory(ctx context.Context, format string, query url.Values, w io.Writer) error {
+	path := "/api/bulk/history"
+	v := url.Values{}
+	for k, list := range query {
+		v[k] = list
+	}
+	if format != "" {
+		v.Set("format", format)
+	}
+	body, closeBody, err := h.getStream(ctx, path, v)
+	if err != nil {
+		return err
+	}
+	defer closeBody()
+	_, err = io.Copy(w, body)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// getStream requests a bulk endpoint compressed with zstd or gzip and
+// returns its decompressed body, which the returned function closes
+func (h *HTTP) getStream(ctx context.Context, path string, query url.Values) (io.Reader, func(), error) {
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept-Encoding", "zstd, gzip")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return nil, nil, responseError(path, resp, body)
+	}
+
+	var body io.Reader = resp.Body
+	closeBody := func() { resp.Body.Close() }
+	switch resp.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			closeBody()
+			return nil, nil, err
+		}
+		body = zr
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			closeBody()
+			return nil, nil, err
+		}
+		body = zr
+		closeBody = func() {
+			zr.Close()
+			resp.Body.Close()
+		}
+	default:
+		closeBody()
+		return nil, nil, fmt.Errorf("%s: unsupported content encoding %q", path, resp.Header.Get("Content-Encoding"))
+	}
+	return body, closeBody, nil
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+)
+
+// DefaultGracePeriod is how long after maturity an attestation still
+// counts as on time
+const DefaultGracePeriod = time.Hour
+
+// Evidence proves that an oracle equivocated: it signed two conflicting
+// announcements or attestations for the same event. Exactly one of the
+// pairs is set.
+type Evidence struct {
+	EventID       string
+	Announcements *[2]dlcoracle.Announcement
+	Attestations  *[2]dlcoracle.Attestation
+}
+
+// Report summarizes the observed behaviour of an oracle
+type Report struct {
+	PubKey [33]byte
+
+	// Announced counts the events observed, Matured those past their
+	// maturity and grace period
+	Announced int
+	Matured   int
+
+	// OnTime and Late count matured events by when their attestation was
+	// first observed; Missing counts those without one
+	OnTime  int
+	Late    int
+	Missing int
+
+	Equivocations []Evidence
+
+	// Score estimates how reliably the oracle attests, from 0 to 1. It
+	// is zero for oracles that equivocated and starts at 0.5 for
+	// oracles without matured events.
+	Score float64
+}
+
+type trackedEvent struct {
+	ann        dlcoracle.Announcement
+	att        *dlcoracle.Attestation
+	attestedAt time.Time
+}
+
+// Tracker records the announcements and attestations observed from
+// oracles, to rate them when choosing oracles for new contracts. Set it
+// on the clients with Client.SetTracker to observe everything they fetch.
+//
+// Lateness is judged by when an attestation was first observed, so the
+// tracker is only as precise as the clients polling for attestations.
+type Tracker struct {
+	mtx    sync.Mutex
+	grace  time.Duration
+	clock  clock.Clock
+	events map[[33]byte]map[string]*trackedEvent
+	evid   map[[33]byte][]Evidence
+}
+
+// NewTracker returns a tracker allowing attestations to arrive up to
+// grace after maturity
+func NewTracker(grace time.Duration) *Tracker {
+	return &Tracker{
+		grace:  grace,
+		clock:  clock.System(),
+		events: make(map[[33]byte]map[string]*trackedEvent),
+		evid:   make(map[[33]byte][]Evidence),
+	}
+}
+
+// SetClock replaces the clock used to timestamp observations
+func (t *Tracker) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
+// ObserveAnnouncement records an announcement. It fails if the
+// announcement's signature is invalid, so nobody can frame an oracle.
+func (t *Tracker) ObserveAnnouncement(a dlcoracle.Announcement) error {
+	err := a.Verify()
+	if err != nil {
+		return err
+	}
+	t.recordAnnouncement(a)
+	return nil
+}
+
+// recordAnnouncement records a verified announcement
+func (t *Tracker) recordAnnouncement(a dlcoracle.Announcement) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.observe(a)
+}
+
+func (t *Tracker) observe(a dlcoracle.Announcement) *trackedEvent {
+	events := t.events[a.OraclePubKey]
+	if events == nil {
+		events = make(map[string]*trackedEvent)
+		t.events[a.OraclePubKey] = events
+	}
+	ev := events[a.EventID]
+	if ev == nil {
+		ev = &trackedEvent{ann: a}
+		events[a.EventID] = ev
+		return ev
+	}
+	if ev.ann.SigningHash() != a.SigningHash() {
+		t.evid[a.OraclePubKey] = append(t.evid[a.OraclePubKey], Evidence{
+			EventID:       a.EventID,
+			Announcements: &[2]dlcoracle.Announcement{ev.ann, a},
+		})
+	}
+	return ev
+}
+
+// ObserveAttestation records an attestation along with the announcement
+// it is for. It fails if either doesn't verify.
+func (t *Tracker) ObserveAttestation(a dlcoracle.Announcement, att dlcoracle.Attestation) error {
+	err := a.Verify()
+	if err != nil {
+		return err
+	}
+	_, err = dlcoracle.VerifyAttestation(a, att)
+	if err != nil {
+		return err
+	}
+	t.recordAttestation(a, att)
+	return nil
+}
+
+// recordAttestation records a verified attestation
+func (t *Tracker) recordAttestation(a dlcoracle.Announcement, att dlcoracle.Attestation) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	ev := t.observe(a)
+	if ev.ann.RPoint != a.RPoint {
+		// Announcing two R points is recorded as equivocation already;
+		// the attestation is only comparable to the first one
+		return
+	}
+	if ev.att == nil {
+		ev.att = &att
+		ev.attestedAt = t.clock.Now()
+		return
+	}
+	if !bytes.Equal(ev.att.Message, att.Message) {
+		t.evid[a.OraclePubKey] = append(t.evid[a.OraclePubKey], Evidence{
+			EventID:      a.EventID,
+			Attestations: &[2]dlcoracle.Attestation{*ev.att, att},
+		})
+	}
+}
+
+// Report returns the report of the oracle with public key pubKey
+func (t *Tracker) Report(pubKey [33]byte) Report {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.report(pubKey, t.clock.Now())
+}
+
+func (t *Tracker) report(pubKey [33]byte, now time.Time) Report {
+	r := Report{
+		PubKey:        pubKey,
+		Announced:     len(t.events[pubKey]),
+		Equivocations: append([]Evidence(nil), t.evid[pubKey]...),
+	}
+	for _, ev := range t.events[pubKey] {
+		deadline := ev.ann.Maturity.Add(t.grace)
+		switch {
+		case ev.ann.Maturity.IsZero() && ev.att == nil:
+			// Events maturing at a block height without an estimated
+			// time can't be judged missing without following the chain
+			continue
+		case ev.att != nil && (ev.ann.Maturity.IsZero() || !ev.attestedAt.After(deadline)):
+			r.OnTime++
+		case ev.att != nil:
+			r.Late++
+		case now.After(deadline):
+			r.Missing++
+		default:
+			continue
+		}
+		r.Matured++
+	}
+	if len(r.Equivocations) == 0 {
+		// Late attestations count half; the prior of one success and
+		// one failure keeps a single observation from deciding the score
+		r.Score = (float64(r.OnTime) + float64(r.Late)/2 + 1) / float64(r.Matured+2)
+	}
+	return r
+}
+
+// Reports returns the reports of all observed oracles, best score first
+func (t *Tracker) Reports() []Report {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := t.clock.Now()
+	list := make([]Report, 0, len(t.events))
+	for pubKey := range t.events {
+		list = append(list, t.report(pubKey, now))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return bytes.Compare(list[i].PubKey[:], list[j].PubKey[:]) < 0
+	})
+	return list
+}
+
+// String summarizes the report in one line
+func (r Report) String() string {
+	return fmt.Sprintf("%x: score %.2f, %d announced, %d on time, %d late, %d missing, %d equivocations",
+		r.PubKey, r.Score, r.Announced, r.OnTime, r.Late, r.Missing, len(r.Equivocations))
+}
@@ -0,0 +1,147 @@
+//go:build !verifyonly
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+)
+
+// testAnnouncement returns an announcement of an enum event by the oracle
+// with private key 1, using the one-time signing key k
+func testAnnouncement(t *testing.T, eventID string, k byte, maturity time.Time) dlcoracle.Announcement {
+	t.Helper()
+	var priv, nonce [32]byte
+	priv[31], nonce[31] = 1, k
+	a := dlcoracle.Announcement{
+		EventID:      eventID,
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey(nonce),
+		Maturity:     maturity,
+		Descriptor:   dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	err := a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// testAttestation attests label for an announcement of testAnnouncement
+func testAttestation(t *testing.T, a dlcoracle.Announcement, k byte, label string) dlcoracle.Attestation {
+	t.Helper()
+	var priv, nonce [32]byte
+	priv[31], nonce[31] = 1, k
+	msg, err := a.Descriptor.OutcomeMessage(dlcoracle.Outcome{Label: label})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := dlcoracle.ComputeSignature(priv, nonce, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dlcoracle.Attestation{EventID: a.EventID, Message: msg, Signature: sig}
+}
+
+func TestTrackerScore(t *testing.T) {
+	now := time.Unix(10000, 0)
+	tr := NewTracker(time.Minute)
+	tr.SetClock(clock.Func(func() time.Time { return now }))
+
+	onTime := testAnnouncement(t, "on-time", 2, now.Add(-time.Second))
+	late := testAnnouncement(t, "late", 3, now.Add(-time.Hour))
+	missing := testAnnouncement(t, "missing", 4, now.Add(-time.Hour))
+	pending := testAnnouncement(t, "pending", 5, now.Add(time.Hour))
+	for _, a := range []dlcoracle.Announcement{onTime, late, missing, pending} {
+		err := tr.ObserveAnnouncement(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := tr.ObserveAttestation(onTime, testAttestation(t, onTime, 2, "yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tr.ObserveAttestation(late, testAttestation(t, late, 3, "no"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := tr.Report(onTime.OraclePubKey)
+	if r.Announced != 4 || r.Matured != 3 || r.OnTime != 1 || r.Late != 1 || r.Missing != 1 {
+		t.Fatalf("unexpected report %v", r)
+	}
+	if r.Score != 0.5 {
+		t.Fatalf("expected a score of 0.5, got %v", r.Score)
+	}
+
+	var unknown [33]byte
+	if r := tr.Report(unknown); r.Score != 0.5 || r.Announced != 0 {
+		t.Fatalf("unexpected report of an unknown oracle %v", r)
+	}
+}
+
+func TestTrackerEquivocation(t *testing.T) {
+	tr := NewTracker(DefaultGracePeriod)
+	maturity := time.Unix(1000, 0)
+	a := testAnnouncement(t, "ev", 2, maturity)
+	for _, label := range []string{"yes", "yes", "no"} {
+		err := tr.ObserveAttestation(a, testAttestation(t, a, 2, label))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := tr.Report(a.OraclePubKey)
+	if len(r.Equivocations) != 1 || r.Equivocations[0].Attestations == nil || r.Score != 0 {
+		t.Fatalf("expected an equivocating attestation, got %v", r)
+	}
+
+	// Announcing another R point for the same event is equivocating too
+	tr = NewTracker(DefaultGracePeriod)
+	for _, k := range []byte{2, 3} {
+		err := tr.ObserveAnnouncement(testAnnouncement(t, "ev", k, maturity))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r = tr.Report(a.OraclePubKey)
+	if len(r.Equivocations) != 1 || r.Equivocations[0].Announcements == nil {
+		t.Fatalf("expected an equivocating announcement, got %v", r)
+	}
+
+	// Forged records are rejected rather than held against the oracle
+	forged := testAttestation(t, a, 3, "no")
+	err := tr.ObserveAttestation(a, forged)
+	if err == nil {
+		t.Fatal("forged attestation accepted")
+	}
+	a.Maturity = a.Maturity.Add(time.Hour)
+	err = tr.ObserveAnnouncement(a)
+	if err == nil {
+		t.Fatal("forged announcement accepted")
+	}
+}
+
+func TestClientTracker(t *testing.T) {
+	o := newTestOracle(t)
+	c := New(newHTTPBackend(t, o), o.PubKey())
+	tr := NewTracker(DefaultGracePeriod)
+	c.SetTracker(tr)
+	ctx := context.Background()
+	_, err := c.Announcements(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = c.Attestation(ctx, "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := tr.Reports()
+	if len(reports) != 1 || reports[0].Announced != 2 || reports[0].Matured != 1 || reports[0].Late != 1 {
+		t.Fatalf("unexpected reports %v", reports)
+	}
+}
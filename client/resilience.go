@@ -0,0 +1,235 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// ErrCircuitOpen is returned without contacting the server while the
+// client's circuit breaker is open after repeated failures
+var ErrCircuitOpen = errors.New("oracle server unavailable, circuit open")
+
+// Cache stores verified records. Announcements and attestations never
+// change once signed, so cached records never go stale.
+type Cache interface {
+	Announcement(pubKey [33]byte, eventID string) (dlcoracle.Announcement, bool)
+	PutAnnouncement(pubKey [33]byte, a dlcoracle.Announcement)
+	Attestation(pubKey [33]byte, eventID string) (dlcoracle.Attestation, bool)
+	PutAttestation(pubKey [33]byte, a dlcoracle.Attestation)
+}
+
+type cacheKey struct {
+	pubKey  [33]byte
+	eventID string
+}
+
+// MemoryCache is a Cache keeping records in memory without bound
+type MemoryCache struct {
+	mtx           sync.RWMutex
+	announcements map[cacheKey]dlcoracle.Announcement
+	attestations  map[cacheKey]dlcoracle.Attestation
+}
+
+// NewMemoryCache returns an empty MemoryCache. It can be shared by the
+// clients of several oracles.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		announcements: make(map[cacheKey]dlcoracle.Announcement),
+		attestations:  make(map[cacheKey]dlcoracle.Attestation),
+	}
+}
+
+// Announcement implements Cache
+func (m *MemoryCache) Announcement(pubKey [33]byte, eventID string) (dlcoracle.Announcement, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	a, ok := m.announcements[cacheKey{pubKey, eventID}]
+	return a, ok
+}
+
+// PutAnnouncement implements Cache
+func (m *MemoryCache) PutAnnouncement(pubKey [33]byte, a dlcoracle.Announcement) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.announcements[cacheKey{pubKey, a.EventID}] = a
+}
+
+// Attestation implements Cache
+func (m *MemoryCache) Attestation(pubKey [33]byte, eventID string) (dlcoracle.Attestation, bool) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	a, ok := m.attestations[cacheKey{pubKey, eventID}]
+	return a, ok
+}
+
+// PutAttestation implements Cache
+func (m *MemoryCache) PutAttestation(pubKey [33]byte, a dlcoracle.Attestation) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.attestations[cacheKey{pubKey, a.EventID}] = a
+}
+
+// RetryPolicy sets how requests to a failing server are retried.
+// Attempt n waits a random time between zero and
+// min(MaxDelay, BaseDelay * 2^n) before trying again.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.MaxDelay
+	if attempt < 32 && p.BaseDelay<<attempt < d {
+		d = p.BaseDelay << attempt
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// Breaker settings of new clients
+const (
+	// DefaultBreakerThreshold is the number of consecutive failed
+	// requests that open the circuit
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is how long the circuit stays open before a
+	// request is let through to probe the server
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// breaker stops requests to a server that keeps failing, so callers
+// don't each wait through their retries
+type breaker struct {
+	mtx       sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+// allow reports whether a request may be sent. Once the cooldown is over
+// a single probe is let through; its result closes or reopens the circuit.
+func (b *breaker) allow() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// temporary reports whether err is a failure of the server or the
+// network that may go away when retrying
+func temporary(err error) bool {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *ServerError
+	if errors.As(err, &se) {
+		return se.Temporary
+	}
+	return true
+}
+
+// call is a request in flight, shared by concurrent callers
+type call struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+}
+
+// flightGroup de-duplicates concurrent requests for the same record
+type flightGroup struct {
+	mtx   sync.Mutex
+	calls map[string]*call
+}
+
+// do runs f, or waits for the result of a run of f for the same key that
+// is already in flight. Waiting callers share the outcome of the first
+// caller, including its context being cancelled.
+func (g *flightGroup) do(key string, f func() (interface{}, error)) (interface{}, error) {
+	g.mtx.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mtx.Unlock()
+		<-c.done
+		return c.val, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mtx.Unlock()
+
+	c.val, c.err = f()
+	g.mtx.Lock()
+	delete(g.calls, key)
+	g.mtx.Unlock()
+	close(c.done)
+	return c.val, c.err
+}
+
+// fetch runs a backend request, de-duplicated with concurrent requests
+// for the same key, retried according to the retry policy and guarded by
+// the circuit breaker
+func fetch[T any](ctx context.Context, c *Client, key string, f func(context.Context) (T, error)) (T, error) {
+	v, err := c.flight.do(key, func() (interface{}, error) {
+		var res T
+		var err error
+		for attempt := 0; attempt < c.retry.MaxAttempts || attempt == 0; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-ctx.Done():
+					return res, ctx.Err()
+				case <-time.After(c.retry.delay(attempt - 1)):
+				}
+			}
+			if !c.breaker.allow() {
+				return res, ErrCircuitOpen
+			}
+			res, err = f(ctx)
+			failed := err != nil && temporary(err)
+			c.breaker.record(failed)
+			if !failed {
+				return res, err
+			}
+		}
+		return res, err
+	})
+	res, _ := v.(T)
+	return res, err
+}
@@ -0,0 +1,155 @@
+//go:build !verifyonly
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// fakeBackend serves a single announcement, failing the first requests
+type fakeBackend struct {
+	ann      dlcoracle.Announcement
+	failures int32
+	calls    int32
+
+	// release, if set, blocks requests until it is closed
+	release chan struct{}
+}
+
+func (f *fakeBackend) Announcement(ctx context.Context, eventID string) (dlcoracle.Announcement, error) {
+	n := atomic.AddInt32(&f.calls, 1)
+	if f.release != nil {
+		<-f.release
+	}
+	if n <= atomic.LoadInt32(&f.failures) {
+		return dlcoracle.Announcement{}, &ServerError{Code: 503, Message: "busy", Temporary: true}
+	}
+	if eventID != f.ann.EventID {
+		return dlcoracle.Announcement{}, ErrNotFound
+	}
+	return f.ann, nil
+}
+
+func (f *fakeBackend) Announcements(ctx context.Context) ([]dlcoracle.Announcement, error) {
+	return []dlcoracle.Announcement{f.ann}, nil
+}
+
+func (f *fakeBackend) Attestation(ctx context.Context, eventID string) (dlcoracle.Attestation, error) {
+	return dlcoracle.Attestation{}, ErrNotFound
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func newFakeClient(t *testing.T, failures int32) (*Client, *fakeBackend) {
+	o := newTestOracle(t)
+	ann, err := o.Store().Announcement("done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeBackend{ann: ann, failures: failures}
+	c := New(f, o.PubKey())
+	c.SetRetryPolicy(fastRetry)
+	return c, f
+}
+
+func TestRetry(t *testing.T) {
+	c, f := newFakeClient(t, 2)
+	_, err := c.Announcement(context.Background(), "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", f.calls)
+	}
+
+	// Missing records aren't retried
+	f.calls, f.failures = 0, 0
+	_, err = c.Announcement(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) || f.calls != 1 {
+		t.Fatalf("got %v after %d calls", err, f.calls)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	c, f := newFakeClient(t, 1000)
+	c.SetBreaker(4, 50*time.Millisecond)
+	ctx := context.Background()
+
+	_, err := c.Announcement(ctx, "done")
+	var se *ServerError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+	_, err = c.Announcement(ctx, "done")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if f.calls != 4 {
+		t.Fatalf("expected the circuit to open after 4 calls, got %d", f.calls)
+	}
+
+	// After the cooldown a probe goes through and closes the circuit
+	atomic.StoreInt32(&f.failures, 0)
+	time.Sleep(60 * time.Millisecond)
+	_, err = c.Announcement(ctx, "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	c, f := newFakeClient(t, 0)
+	c.SetCache(NewMemoryCache())
+	for i := 0; i < 3; i++ {
+		_, err := c.Announcement(context.Background(), "done")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", f.calls)
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	c, f := newFakeClient(t, 0)
+	f.release = make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Announcement(context.Background(), "done")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// Let the requests pile up behind the first one
+	for atomic.LoadInt32(&f.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+	if f.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", f.calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 40; attempt++ {
+		d := p.delay(attempt)
+		if d < 0 || d > time.Second {
+			t.Fatalf("delay %v out of range for attempt %d", d, attempt)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Code generated by server.GenerateClient. DO NOT EDIT.
+
+package rest
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// PubKey is a response of the REST API
+type PubKey struct {
+	PubKey string `json:"pubKey"`
+}
+
+// History is a response of the REST API
+type History struct {
+	EventID        string `json:"eventId"`
+	Maturity       int64  `json:"maturity"`
+	MaturityHeight uint32 `json:"maturityHeight,omitempty"`
+	Type           string `json:"type"`
+	Unit           string `json:"unit,omitempty"`
+	UnitExponent   int32  `json:"unitExponent,omitempty"`
+	Value          int64  `json:"value"`
+	Label          string `json:"label,omitempty"`
+	Outcome        string `json:"outcome"`
+}
+
+// GetPubKey returns the oracle's public key. It calls GET /api/pubkey.
+func (c *Client) GetPubKey(ctx context.Context) (PubKey, error) {
+	var res PubKey
+	err := c.call(ctx, "GET", "/api/pubkey", &res)
+	return res, err
+}
+
+// ListAnnouncements lists the announcements of the oracle selected by the query parameters, all of them by default. It calls GET /api/announcements.
+func (c *Client) ListAnnouncements(ctx context.Context, query url.Values) ([]dlcoracle.Announcement, error) {
+	var res []dlcoracle.Announcement
+	path := "/api/announcements"
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+	err := c.call(ctx, "GET", path, &res)
+	return res, err
+}
+
+// GetAnnouncement returns the announcement of an event. It calls GET /api/announcements/{id}.
+func (c *Client) GetAnnouncement(ctx context.Context, id string) (dlcoracle.Announcement, error) {
+	var res dlcoracle.Announcement
+	err := c.call(ctx, "GET", "/api/announcements/"+url.PathEscape(id), &res)
+	return res, err
+}
+
+// ListAttestations lists the attestations of the events selected by the query parameters, in the order of the announcements. It calls GET /api/attestations.
+func (c *Client) ListAttestations(ctx context.Context, query url.Values) ([]dlcoracle.Attestation, error) {
+	var res []dlcoracle.Attestation
+	path := "/api/attestations"
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+	err := c.call(ctx, "GET", path, &res)
+	return res, err
+}
+
+// GetAttestation returns the attestation of an event. It calls GET /api/attestations/{id}.
+func (c *Client) GetAttestation(ctx context.Context, id string) (dlcoracle.Attestation, error) {
+	var res dlcoracle.Attestation
+	err := c.call(ctx, "GET", "/api/attestations/"+url.PathEscape(id), &res)
+	return res, err
+}
+
+// ListHistory lists the outcomes attested for the events selected by the query parameters, in the order of the announcements. It calls GET /api/history.
+func (c *Client) ListHistory(ctx context.Context, query url.Values) ([]History, error) {
+	var res []History
+	path := "/api/history"
+	if len(query) != 0 {
+		path += "?" + query.Encode()
+	}
+	err := c.call(ctx, "GET", path, &res)
+	return res, err
+}
+
+// GetRevocation returns the revocation of an event. It calls GET /api/revocations/{id}.
+func (c *Client) GetRevocation(ctx context.Context, id string) (dlcoracle.Revocation, error) {
+	var res dlcoracle.Revocation
+	err := c.call(ctx, "GET", "/api/revocations/"+url.PathEscape(id), &res)
+	return res, err
+}
+
+// GetEvidence returns the upstream API responses an event was attested from, signed by the oracle. It calls GET /api/evidence/{id}.
+func (c *Client) GetEvidence(ctx context.Context, id string) (dlcoracle.Evidence, error) {
+	var res dlcoracle.Evidence
+	err := c.call(ctx, "GET", "/api/evidence/"+url.PathEscape(id), &res)
+	return res, err
+}
@@ -0,0 +1,85 @@
+// Package rest is a typed client for the REST API of an oracle. Its
+// methods are generated from the routes of package server, which also
+// describe the API in the OpenAPI document served at /api/openapi.json.
+//
+// The client doesn't verify what the server returns. Wallets should use
+// package client, which checks every record against the oracle's pinned
+// public key.
+package rest
+
+//go:generate go test -run TestGenerated -update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxResponseSize limits how much of a response is read
+const maxResponseSize = 16 << 20
+
+// Error is returned for responses with another status than 200
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Message)
+}
+
+// Client calls the REST API of an oracle
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// New returns a client for the REST API at baseURL, such as
+// https://oracle.example.com
+func New(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used for requests
+func (c *Client) SetHTTPClient(hc *http.Client) {
+	c.client = hc
+}
+
+// call makes a request and decodes the JSON response into res
+func (c *Client) call(ctx context.Context, method, path string, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &e)
+		if e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return &Error{Status: resp.StatusCode, Message: e.Error}
+	}
+	err = json.Unmarshal(body, res)
+	if err != nil {
+		return fmt.Errorf("%s: decoding response: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+//go:build !verifyonly
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/server"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated client")
+
+// TestGenerated checks that the generated client matches the routes of
+// the server. Run go generate after changing them.
+func TestGenerated(t *testing.T) {
+	src, err := server.GenerateClient("rest", server.Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		err = os.WriteFile("api.go", src, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile("api.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, src) {
+		t.Fatal("api.go is out of date, run go generate")
+	}
+}
+
+func TestClient(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	store := storage.NewMemoryStore()
+	a := dlcoracle.Announcement{
+		EventID:      "event/1",
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 2}),
+		Maturity:     time.Unix(1000, 0).UTC(),
+	}
+	err := a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.NewServer(a.OraclePubKey, store))
+	defer ts.Close()
+
+	c := New(ts.URL)
+	ctx := context.Background()
+	pub, err := c.GetPubKey(ctx)
+	if err != nil || pub.PubKey == "" {
+		t.Fatalf("unexpected public key %+v %v", pub, err)
+	}
+	got, err := c.GetAnnouncement(ctx, "event/1")
+	if err != nil || got.Signature != a.Signature {
+		t.Fatalf("unexpected announcement %+v %v", got, err)
+	}
+	list, err := c.ListAnnouncements(ctx, nil)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("unexpected announcements %+v %v", list, err)
+	}
+	_, err = c.GetAttestation(ctx, "event/1")
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
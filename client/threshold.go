@@ -0,0 +1,315 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+var (
+	// ErrIncompatibleAnnouncements is returned when the oracles of a group
+	// announced an event with different outcomes
+	ErrIncompatibleAnnouncements = errors.New("oracles announced the event differently")
+
+	// ErrThresholdNotMet is returned when fewer oracles than the threshold
+	// answered or agree on an outcome
+	ErrThresholdNotMet = errors.New("threshold not met")
+
+	// ErrConflictingOutcomes is returned when enough oracles to meet the
+	// threshold attested to each of several outcomes
+	ErrConflictingOutcomes = errors.New("oracles attested to conflicting outcomes")
+)
+
+// Group is a set of oracles attesting to the same event, of which a
+// contract needs Threshold to agree on the outcome. Its methods take and
+// return slices parallel to Clients, with nil entries for oracles that
+// didn't answer.
+type Group struct {
+	Clients   []*Client
+	Threshold int
+}
+
+// NewGroup returns a threshold-of-len(clients) group
+func NewGroup(threshold int, clients ...*Client) (*Group, error) {
+	if threshold < 1 || threshold > len(clients) {
+		return nil, fmt.Errorf("threshold %d out of range for %d oracles", threshold, len(clients))
+	}
+	seen := make(map[[33]byte]bool, len(clients))
+	for _, c := range clients {
+		if seen[c.PubKey()] {
+			return nil, fmt.Errorf("oracle %x is in the group twice", c.PubKey())
+		}
+		seen[c.PubKey()] = true
+	}
+	return &Group{Clients: clients, Threshold: threshold}, nil
+}
+
+// each runs f for every oracle concurrently and returns its errors
+func (g *Group) each(f func(i int, c *Client) error) []error {
+	errs := make([]error, len(g.Clients))
+	var wg sync.WaitGroup
+	for i, c := range g.Clients {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			errs[i] = f(i, c)
+		}(i, c)
+	}
+	wg.Wait()
+	return errs
+}
+
+// thresholdError reports the errors of the oracles that failed, if fewer
+// than the threshold succeeded
+func (g *Group) thresholdError(errs []error) error {
+	ok := 0
+	var failed []error
+	for i, err := range errs {
+		if err == nil {
+			ok++
+			continue
+		}
+		failed = append(failed, fmt.Errorf("oracle %x: %w", g.Clients[i].PubKey(), err))
+	}
+	if ok >= g.Threshold {
+		return nil
+	}
+	return fmt.Errorf("%d of %d oracles answered, need %d: %w",
+		ok, len(g.Clients), g.Threshold, errors.Join(append([]error{ErrThresholdNotMet}, failed...)...))
+}
+
+// Announcements fetches and verifies the announcement of an event from
+// every oracle. It fails if fewer than Threshold oracles answer, or if
+// the announcements don't have the same descriptor.
+func (g *Group) Announcements(ctx context.Context, eventID string) ([]*dlcoracle.Announcement, error) {
+	anns := make([]*dlcoracle.Announcement, len(g.Clients))
+	errs := g.each(func(i int, c *Client) error {
+		a, err := c.Announcement(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		anns[i] = &a
+		return nil
+	})
+	err := g.thresholdError(errs)
+	if err != nil {
+		return anns, err
+	}
+	var first *dlcoracle.Announcement
+	for _, a := range anns {
+		if a == nil {
+			continue
+		}
+		if first == nil {
+			first = a
+		} else if !reflect.DeepEqual(first.Descriptor, a.Descriptor) {
+			return anns, fmt.Errorf("%s: %w", eventID, ErrIncompatibleAnnouncements)
+		}
+	}
+	return anns, nil
+}
+
+// Attestations fetches and verifies the attestation of an event from
+// every oracle. Oracles that haven't attested have a nil entry. It fails
+// only if fewer than Threshold oracles attested.
+func (g *Group) Attestations(ctx context.Context, eventID string) ([]*dlcoracle.Announcement, []*dlcoracle.Attestation, error) {
+	anns := make([]*dlcoracle.Announcement, len(g.Clients))
+	atts := make([]*dlcoracle.Attestation, len(g.Clients))
+	errs := g.each(func(i int, c *Client) error {
+		ann, att, err := c.Attestation(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		anns[i], atts[i] = &ann, &att
+		return nil
+	})
+	return anns, atts, g.thresholdError(errs)
+}
+
+// Combination is a set of Threshold oracles and the sum of their
+// signature points for an outcome. A contract locks the outcome's payout
+// to each combination's point, so any Threshold oracles can unlock it.
+type Combination struct {
+	// Oracles are indexes into the group's Clients
+	Oracles []int
+	Point   [33]byte
+}
+
+// AnticipationPoint returns the sum of the signature points of anns for
+// outcome, which the sum of their attestation signatures is the discrete
+// logarithm of
+func AnticipationPoint(anns []dlcoracle.Announcement, outcome dlcoracle.Outcome) ([33]byte, error) {
+	var point [33]byte
+	var sum btcec.JacobianPoint
+	for i, a := range anns {
+		msg, err := a.Descriptor.OutcomeMessage(outcome)
+		if err != nil {
+			return point, err
+		}
+		sigPoint, err := dlcoracle.ComputeSignaturePubKey(a.OraclePubKey, a.RPoint, msg)
+		if err != nil {
+			return point, err
+		}
+		P, err := btcec.ParsePubKey(sigPoint[:])
+		if err != nil {
+			return point, err
+		}
+		var p btcec.JacobianPoint
+		P.AsJacobian(&p)
+		if i == 0 {
+			sum = p
+			continue
+		}
+		var next btcec.JacobianPoint
+		btcec.AddNonConst(&sum, &p, &next)
+		sum = next
+	}
+	if len(anns) == 0 {
+		return point, fmt.Errorf("no announcements")
+	}
+	sum.ToAffine()
+	copy(point[:], btcec.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed())
+	return point, nil
+}
+
+// AnticipationPoints returns the anticipation point of outcome for every
+// combination of Threshold oracles that all announced the event
+func (g *Group) AnticipationPoints(anns []*dlcoracle.Announcement, outcome dlcoracle.Outcome) ([]Combination, error) {
+	return g.AnticipationPointsContext(context.Background(), anns, outcome)
+}
+
+// AnticipationPointsContext is AnticipationPoints, stopping with the
+// context's error once ctx is done. The number of combinations grows
+// quickly with the group's size, so tables for large groups are best
+// computed with a context.
+func (g *Group) AnticipationPointsContext(ctx context.Context, anns []*dlcoracle.Announcement, outcome dlcoracle.Outcome) ([]Combination, error) {
+	if len(anns) != len(g.Clients) {
+		return nil, fmt.Errorf("got %d announcements for %d oracles", len(anns), len(g.Clients))
+	}
+	var available []int
+	for i, a := range anns {
+		if a != nil {
+			available = append(available, i)
+		}
+	}
+	var list []Combination
+	for _, oracles := range combinations(available, g.Threshold) {
+		err := ctx.Err()
+		if err != nil {
+			return nil, err
+		}
+		subset := make([]dlcoracle.Announcement, len(oracles))
+		for j, i := range oracles {
+			subset[j] = *anns[i]
+		}
+		point, err := AnticipationPoint(subset, outcome)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Combination{Oracles: oracles, Point: point})
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("%d oracles announced, need %d: %w", len(available), g.Threshold, ErrThresholdNotMet)
+	}
+	return list, nil
+}
+
+// combinations returns all subsets of k elements of set, in
+// lexicographic order
+func combinations(set []int, k int) [][]int {
+	var list [][]int
+	var pick func(start int, chosen []int)
+	pick = func(start int, chosen []int) {
+		if len(chosen) == k {
+			list = append(list, append([]int(nil), chosen...))
+			return
+		}
+		for i := start; i <= len(set)-(k-len(chosen)); i++ {
+			pick(i+1, append(chosen, set[i]))
+		}
+	}
+	pick(0, nil)
+	return list
+}
+
+// ThresholdAttestation is the outcome of an event that Threshold oracles
+// agreed on
+type ThresholdAttestation struct {
+	Outcome dlcoracle.Outcome
+
+	// Oracles are the first Threshold oracles attesting to Outcome, as
+	// indexes into the group's Clients
+	Oracles []int
+
+	// Secret is the sum of the Oracles' signatures, the discrete
+	// logarithm of their Combination's anticipation point for Outcome
+	Secret [32]byte
+}
+
+// VerifyAttestations checks the attestations of the group's oracles
+// against their announcements, skipping oracles with a nil entry in
+// either, and returns the outcome at least Threshold of them attested to.
+func (g *Group) VerifyAttestations(anns []*dlcoracle.Announcement, atts []*dlcoracle.Attestation) (ThresholdAttestation, error) {
+	var res ThresholdAttestation
+	if len(anns) != len(g.Clients) || len(atts) != len(g.Clients) {
+		return res, fmt.Errorf("got %d announcements and %d attestations for %d oracles",
+			len(anns), len(atts), len(g.Clients))
+	}
+
+	// Group the oracles by the message they validly signed
+	var messages [][]byte
+	byMessage := make(map[string][]int)
+	for i := range g.Clients {
+		if anns[i] == nil || atts[i] == nil {
+			continue
+		}
+		if anns[i].OraclePubKey != g.Clients[i].PubKey() {
+			return res, fmt.Errorf("announcement %d: %w", i, ErrWrongOracle)
+		}
+		_, err := dlcoracle.VerifyAttestation(*anns[i], *atts[i])
+		if err != nil {
+			return res, fmt.Errorf("oracle %x: %w", anns[i].OraclePubKey, err)
+		}
+		key := string(atts[i].Message)
+		if byMessage[key] == nil {
+			messages = append(messages, atts[i].Message)
+		}
+		byMessage[key] = append(byMessage[key], i)
+	}
+
+	var agreed []byte
+	for _, msg := range messages {
+		if len(byMessage[string(msg)]) < g.Threshold {
+			continue
+		}
+		if agreed != nil && !bytes.Equal(agreed, msg) {
+			return res, ErrConflictingOutcomes
+		}
+		agreed = msg
+	}
+	if agreed == nil {
+		return res, fmt.Errorf("no outcome attested by %d oracles: %w", g.Threshold, ErrThresholdNotMet)
+	}
+
+	res.Oracles = byMessage[string(agreed)][:g.Threshold]
+	first := anns[res.Oracles[0]]
+	outcome, err := first.Descriptor.ParseOutcome(agreed)
+	if err != nil {
+		return res, err
+	}
+	res.Outcome = outcome
+	var sum btcec.ModNScalar
+	for _, i := range res.Oracles {
+		var sig btcec.ModNScalar
+		sig.SetBytes(&atts[i].Signature)
+		sum.Add(&sig)
+	}
+	res.Secret = sum.Bytes()
+	return res, nil
+}
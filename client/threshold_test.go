@@ -0,0 +1,146 @@
+//go:build !verifyonly
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/server"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// newTestGroup returns a group of oracles that announced "vote" and
+// attested to the given outcomes, with "" leaving an oracle unattested
+func newTestGroup(t *testing.T, threshold int, outcomes ...string) *Group {
+	t.Helper()
+	enum := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	var clients []*Client
+	for i, outcome := range outcomes {
+		var priv [32]byte
+		priv[31] = byte(i + 1)
+		o := oracle.New(priv, storage.NewMemoryStore())
+		_, err := o.CreateEvent(dlcoracle.Event{ID: "vote", Maturity: time.Unix(1000, 0), Descriptor: enum})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outcome != "" {
+			_, err = o.AttestOutcome("vote", dlcoracle.Outcome{Label: outcome})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		ts := httptest.NewServer(server.NewServer(o.PubKey(), o.Store()))
+		t.Cleanup(ts.Close)
+		clients = append(clients, New(NewHTTP(ts.URL), o.PubKey()))
+	}
+	g, err := NewGroup(threshold, clients...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestThreshold(t *testing.T) {
+	g := newTestGroup(t, 2, "no", "yes", "", "yes")
+	ctx := context.Background()
+
+	anns, err := g.Announcements(ctx, "vote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	points, err := g.AnticipationPoints(anns, dlcoracle.Outcome{Label: "yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 6 {
+		t.Fatalf("got %d combinations of 2 out of 4", len(points))
+	}
+
+	anns, atts, err := g.Attestations(ctx, "vote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atts[2] != nil {
+		t.Fatal("unattested oracle has an attestation")
+	}
+	res, err := g.VerifyAttestations(anns, atts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outcome.Label != "yes" || len(res.Oracles) != 2 || res.Oracles[0] != 1 || res.Oracles[1] != 3 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	// The secret unlocks the anticipation point of the attesting oracles
+	var unlocked bool
+	for _, c := range points {
+		if c.Oracles[0] == 1 && c.Oracles[1] == 3 {
+			unlocked = dlcoracle.PublicKeyFromPrivateKey(res.Secret) == c.Point
+		}
+	}
+	if !unlocked {
+		t.Fatal("secret doesn't match the anticipation point")
+	}
+}
+
+func TestThresholdFailures(t *testing.T) {
+	ctx := context.Background()
+
+	g := newTestGroup(t, 3, "yes", "yes", "")
+	_, _, err := g.Attestations(ctx, "vote")
+	if !errors.Is(err, ErrThresholdNotMet) {
+		t.Fatalf("expected ErrThresholdNotMet, got %v", err)
+	}
+
+	g = newTestGroup(t, 2, "yes", "no", "yes", "no")
+	anns, atts, err := g.Attestations(ctx, "vote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.VerifyAttestations(anns, atts)
+	if !errors.Is(err, ErrConflictingOutcomes) {
+		t.Fatalf("expected ErrConflictingOutcomes, got %v", err)
+	}
+
+	_, err = NewGroup(3, g.Clients[0], g.Clients[1])
+	if err == nil {
+		t.Fatal("accepted a threshold above the number of oracles")
+	}
+	_, err = NewGroup(1, g.Clients[0], g.Clients[0])
+	if err == nil {
+		t.Fatal("accepted the same oracle twice")
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	got := combinations([]int{0, 2, 5}, 2)
+	want := [][]int{{0, 2}, {0, 5}, {2, 5}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if got[i][0] != want[i][0] || got[i][1] != want[i][1] {
+			t.Fatalf("got %v", got)
+		}
+	}
+}
+
+func TestAnticipationPointsCancelled(t *testing.T) {
+	g := newTestGroup(t, 2, "", "", "")
+	anns, err := g.Announcements(context.Background(), "vote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = g.AnticipationPointsContext(ctx, anns, dlcoracle.Outcome{Label: "yes"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
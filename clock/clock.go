@@ -0,0 +1,55 @@
+// Package clock abstracts the time source an oracle attests by. Attesting
+// too early is an oracle failure, so besides the system clock it provides
+// a clock that checks the local time against NTP servers and refuses to
+// let the oracle attest while the drift is too large.
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrClockDrift is returned by Check when the local clock is too far off
+var ErrClockDrift = errors.New("local clock drift exceeds threshold")
+
+// Clock tells the time maturities are compared against. Event maturities
+// based on block time plug in a Clock returning the chain's median time
+// past.
+type Clock interface {
+	Now() time.Time
+}
+
+// Checker is implemented by clocks that can tell whether they are
+// trustworthy right now. Attestations are refused while Check fails.
+type Checker interface {
+	Check() error
+}
+
+// Check returns c's Check result if c implements Checker, and nil
+// otherwise
+func Check(c Clock) error {
+	checker, ok := c.(Checker)
+	if !ok {
+		return nil
+	}
+	return checker.Check()
+}
+
+type system struct{}
+
+func (system) Now() time.Time {
+	return time.Now()
+}
+
+// System returns the local system clock, unchecked
+func System() Clock {
+	return system{}
+}
+
+// Func adapts a function to a Clock
+type Func func() time.Time
+
+// Now implements Clock
+func (f Func) Now() time.Time {
+	return f()
+}
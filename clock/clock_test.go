@@ -0,0 +1,92 @@
+package clock
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNTPClockCheck(t *testing.T) {
+	c := NewNTPClock("a", "b")
+	offsets := map[string]time.Duration{"b": 3 * time.Second}
+	queries := 0
+	c.query = func(server string) (time.Duration, error) {
+		queries++
+		offset, ok := offsets[server]
+		if !ok {
+			return 0, errors.New("unreachable")
+		}
+		return offset, nil
+	}
+
+	err := c.Check()
+	if !errors.Is(err, ErrClockDrift) {
+		t.Fatalf("expected ErrClockDrift, got %v", err)
+	}
+	// The measurement is reused within the check interval
+	offsets["b"] = 0
+	err = c.Check()
+	if !errors.Is(err, ErrClockDrift) || queries != 2 {
+		t.Fatalf("expected cached drift after 2 queries, got %v after %d", err, queries)
+	}
+
+	c.CheckInterval = 0
+	err = c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delete(offsets, "b")
+	err = c.Check()
+	if err == nil || errors.Is(err, ErrClockDrift) {
+		t.Fatalf("expected unreachable servers to fail the check, got %v", err)
+	}
+}
+
+func TestQueryNTP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The fake server runs ten seconds ahead
+	go func() {
+		buf := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 4<<3 | 4
+		resp[1] = 1
+		now := time.Now().Add(10 * time.Second)
+		secs := uint32(now.Unix() + ntpEpochOffset)
+		frac := uint32((int64(now.Nanosecond()) << 32) / 1e9)
+		for _, off := range []int{32, 40} {
+			binary.BigEndian.PutUint32(resp[off:], secs)
+			binary.BigEndian.PutUint32(resp[off+4:], frac)
+		}
+		conn.WriteTo(resp, addr)
+	}()
+
+	offset, err := queryNTP(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < 9*time.Second || offset > 11*time.Second {
+		t.Fatalf("unexpected offset %s", offset)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if Check(System()) != nil {
+		t.Fatal("system clock check failed")
+	}
+	fixed := time.Unix(1000, 0)
+	if !Func(func() time.Time { return fixed }).Now().Equal(fixed) {
+		t.Fatal("Func clock returned the wrong time")
+	}
+}
@@ -0,0 +1,145 @@
+package clock
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxDrift is the largest offset from NTP time NTPClock
+	// tolerates by default
+	DefaultMaxDrift = 2 * time.Second
+
+	// DefaultCheckInterval is how long an NTP measurement is reused
+	DefaultCheckInterval = 5 * time.Minute
+
+	// ntpTimeout bounds a single NTP query
+	ntpTimeout = 5 * time.Second
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch
+	// (1900) and the Unix epoch (1970)
+	ntpEpochOffset = 2208988800
+)
+
+// NTPClock is the system clock, checked against NTP servers. Check fails
+// with ErrClockDrift if the local clock is off by more than MaxDrift, and
+// also fails if no server answers, since the drift is unknown then.
+type NTPClock struct {
+	Servers       []string
+	MaxDrift      time.Duration
+	CheckInterval time.Duration
+
+	// query measures the offset to a server. It is replaced in tests.
+	query func(server string) (time.Duration, error)
+
+	mtx       sync.Mutex
+	checkedAt time.Time
+	offset    time.Duration
+}
+
+// NewNTPClock returns a clock checking the system time against servers,
+// given as host or host:port
+func NewNTPClock(servers ...string) *NTPClock {
+	return &NTPClock{
+		Servers:       servers,
+		MaxDrift:      DefaultMaxDrift,
+		CheckInterval: DefaultCheckInterval,
+		query:         queryNTP,
+	}
+}
+
+// Now returns the system time
+func (c *NTPClock) Now() time.Time {
+	return time.Now()
+}
+
+// Offset returns the last measured offset of NTP time from the local
+// clock, measuring it if the last measurement is older than
+// CheckInterval
+func (c *NTPClock) Offset() (time.Duration, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.CheckInterval {
+		return c.offset, nil
+	}
+
+	var lastErr error
+	for _, server := range c.Servers {
+		offset, err := c.query(server)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		c.offset = offset
+		c.checkedAt = time.Now()
+		return offset, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no NTP servers configured")
+	}
+	return 0, fmt.Errorf("measuring clock drift: %w", lastErr)
+}
+
+// Check implements Checker
+func (c *NTPClock) Check() error {
+	offset, err := c.Offset()
+	if err != nil {
+		return err
+	}
+	if offset > c.MaxDrift || -offset > c.MaxDrift {
+		return fmt.Errorf("clock is off by %s: %w", offset, ErrClockDrift)
+	}
+	return nil
+}
+
+// queryNTP measures the offset to an NTP server with a single SNTP
+// (RFC 4330) request
+func queryNTP(server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ntpTimeout))
+
+	req := make([]byte, 48)
+	// Leap indicator 0, version 4, client mode
+	req[0] = 0<<6 | 4<<3 | 3
+	sent := time.Now()
+	_, err = conn.Write(req)
+	if err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	if n < 48 {
+		return 0, fmt.Errorf("short NTP response from %s", server)
+	}
+	if resp[0]&0x7 != 4 || resp[1] == 0 {
+		return 0, fmt.Errorf("invalid NTP response from %s", server)
+	}
+	return sntpOffset(sent, ntpTime(resp[32:40]), ntpTime(resp[40:48]), received), nil
+}
+
+// sntpOffset computes the clock offset from the client's send and receive
+// times and the server's receive and transmit times
+func sntpOffset(t1, t2, t3, t4 time.Time) time.Duration {
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2
+}
+
+// ntpTime decodes a 64-bit NTP timestamp
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(secs, frac*1e9>>32)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func announcementCreate(args []string, out io.Writer) error {
+	fs := newFlagSet("announcement create")
+	keyFile := fs.String("key", "", "oracle key file")
+	index := fs.Int64("index", -1, "index of the one-time signing key to commit to")
+	id := fs.String("id", "", "event ID, derived from the descriptor and maturity if empty")
+	maturity := fs.String("maturity", "", "maturity as RFC 3339 time")
+	height := fs.Uint("height", 0, "Bitcoin block height the event matures at")
+	typ := fs.String("type", "numeric", "event type: numeric, enum, bytes or digits")
+	outcomes := fs.String("outcomes", "", "comma separated outcomes of an enum event")
+	base := fs.Uint("base", 0, "base of a digits event")
+	digits := fs.Uint("digits", 0, "number of digits of a digits event")
+	unit := fs.String("unit", "", "unit of a numeric or digits event, such as usd/btc")
+	exponent := fs.Int("unit-exponent", 0, "power of ten the value is multiplied by to get the unit, -2 for cents")
+	precision := fs.Uint64("precision", 0, "values are multiples of this")
+	rounding := fs.String("rounding", "half-up", "how values are rounded to the precision: half-up, half-even, floor or ceil")
+	description := fs.String("description", "", "description of the event")
+	category := fs.String("category", "", "category of the event, such as crypto")
+	tags := fs.String("tags", "", "comma separated tags of the event")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" || *index < 0 {
+		return fmt.Errorf("-key and -index are required")
+	}
+	if *maturity == "" && *height == 0 {
+		return fmt.Errorf("give -maturity, -height or both")
+	}
+
+	a := dlcoracle.Announcement{
+		EventID:        *id,
+		MaturityHeight: uint32(*height),
+	}
+	if *maturity != "" {
+		a.Maturity, err = time.Parse(time.RFC3339, *maturity)
+		if err != nil {
+			return fmt.Errorf("invalid -maturity: %v", err)
+		}
+	}
+	err = a.Descriptor.Type.UnmarshalText([]byte(*typ))
+	if err != nil {
+		return err
+	}
+	if *outcomes != "" {
+		a.Descriptor.Outcomes = strings.Split(*outcomes, ",")
+	}
+	a.Descriptor.Base, a.Descriptor.Digits = uint32(*base), uint32(*digits)
+	if *exponent < -dlcoracle.MaxUnitExponent || *exponent > dlcoracle.MaxUnitExponent {
+		return fmt.Errorf("-unit-exponent out of range")
+	}
+	a.Descriptor.Unit, a.Descriptor.UnitExponent, a.Descriptor.Precision = *unit, int32(*exponent), *precision
+	err = a.Descriptor.Rounding.UnmarshalText([]byte(*rounding))
+	if err != nil {
+		return err
+	}
+	err = a.Descriptor.Validate()
+	if err != nil {
+		return err
+	}
+	a.Metadata = dlcoracle.EventMetadata{Description: *description, Category: *category}
+	if *tags != "" {
+		a.Metadata.Tags = strings.Split(*tags, ",")
+	}
+	err = a.Metadata.Validate()
+	if err != nil {
+		return err
+	}
+	if a.EventID == "" {
+		a.EventID = dlcoracle.EventID(a.Descriptor, a.Maturity)
+	}
+
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(priv, uint64(*index))
+	if err != nil {
+		return err
+	}
+	a, err = dlcoracle.NewAnnouncement(priv, k, a.Event())
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, a)
+}
+
+func announcementInspect(args []string, out io.Writer) error {
+	fs := newFlagSet("announcement inspect")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	var a dlcoracle.Announcement
+	err = readJSON(fs.Arg(0), &a)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "event:      %s\n", a.EventID)
+	fmt.Fprintf(out, "oracle:     %x\n", a.OraclePubKey)
+	fmt.Fprintf(out, "rpoint:     %x\n", a.RPoint)
+	fmt.Fprintf(out, "maturity:   %s\n", a.Maturity.Format(time.RFC3339))
+	if a.MaturityHeight != 0 {
+		fmt.Fprintf(out, "height:     %d\n", a.MaturityHeight)
+	}
+	fmt.Fprintf(out, "type:       %s\n", a.Descriptor.Type)
+	if len(a.Descriptor.Outcomes) != 0 {
+		fmt.Fprintf(out, "outcomes:   %s\n", strings.Join(a.Descriptor.Outcomes, ", "))
+	}
+	if a.Descriptor.Type == dlcoracle.EventTypeDigits {
+		fmt.Fprintf(out, "digits:     %d in base %d\n", a.Descriptor.Digits, a.Descriptor.Base)
+	}
+	if a.Descriptor.Unit != "" || a.Descriptor.UnitExponent != 0 {
+		fmt.Fprintf(out, "unit:       %s\n", a.Descriptor.FormatValue(1))
+	}
+	if a.Descriptor.Precision > 1 {
+		fmt.Fprintf(out, "precision:  %s, rounded %s\n", a.Descriptor.FormatValue(int64(a.Descriptor.Precision)), a.Descriptor.Rounding)
+	}
+	if a.Metadata.Description != "" {
+		fmt.Fprintf(out, "about:      %s\n", a.Metadata.Description)
+	}
+	if a.Metadata.Category != "" {
+		fmt.Fprintf(out, "category:   %s\n", a.Metadata.Category)
+	}
+	if len(a.Metadata.Tags) != 0 {
+		fmt.Fprintf(out, "tags:       %s\n", strings.Join(a.Metadata.Tags, ", "))
+	}
+	err = a.Verify()
+	if err != nil {
+		fmt.Fprintf(out, "signature:  INVALID\n")
+		return err
+	}
+	fmt.Fprintf(out, "signature:  valid\n")
+	return nil
+}
+
+func attestationVerify(args []string, out io.Writer) error {
+	fs := newFlagSet("attestation verify")
+	annFile := fs.String("announcement", "", "announcement JSON file")
+	attFile := fs.String("attestation", "", "attestation JSON file")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *annFile == "" || *attFile == "" {
+		return fmt.Errorf("-announcement and -attestation are required")
+	}
+	var ann dlcoracle.Announcement
+	err = readJSON(*annFile, &ann)
+	if err != nil {
+		return err
+	}
+	var att dlcoracle.Attestation
+	err = readJSON(*attFile, &att)
+	if err != nil {
+		return err
+	}
+
+	err = ann.Verify()
+	if err != nil {
+		return err
+	}
+	outcome, err := dlcoracle.VerifyAttestation(ann, att)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "attestation valid, outcome %s\n", formatOutcome(ann.Descriptor, outcome))
+	return nil
+}
+
+func formatOutcome(d dlcoracle.EventDescriptor, o dlcoracle.Outcome) string {
+	switch d.Type {
+	case dlcoracle.EventTypeNumeric, dlcoracle.EventTypeDigits:
+		return d.FormatValue(o.Value)
+	case dlcoracle.EventTypeEnum:
+		return fmt.Sprintf("%q", o.Label)
+	}
+	return fmt.Sprintf("%x", o.Bytes)
+}
+
+// readJSON decodes the JSON in a file, or on stdin if filename is empty
+// or "-"
+func readJSON(filename string, v interface{}) error {
+	var b []byte
+	var err error
+	if filename == "" || filename == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func writeJSON(out io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", b)
+	return err
+}
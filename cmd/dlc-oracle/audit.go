@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/audit"
+)
+
+func auditVerify(args []string, out io.Writer) error {
+	fs := newFlagSet("audit verify")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("give the audit log file")
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, last, err := audit.Verify(f)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintf(out, "audit log is empty\n")
+		return nil
+	}
+	fmt.Fprintf(out, "%d signatures, chain intact\n", n)
+	fmt.Fprintf(out, "last:  %s of %s at %s\n", last.Kind, last.EventID, last.Time.Format(time.RFC3339))
+	fmt.Fprintf(out, "head:  %x\n", last.Hash)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/discovery"
+)
+
+func dnsRecord(args []string, out io.Writer) error {
+	fs := newFlagSet("dns record")
+	keyFile := fs.String("key", "", "oracle key file")
+	domain := fs.String("domain", "", "domain to bind to the oracle")
+	endpoints := fs.String("endpoints", "", "comma separated URLs the oracle serves at")
+	ttl := fs.Int("ttl", 3600, "time to live of the record in seconds")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" || *domain == "" {
+		return fmt.Errorf("-key and -domain are required")
+	}
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	r := discovery.Record{Domain: *domain}
+	if *endpoints != "" {
+		r.Endpoints = strings.Split(*endpoints, ",")
+	}
+	err = r.Sign(priv)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, r.ZoneEntry(*ttl))
+	return nil
+}
+
+func dnsLookup(args []string, out io.Writer) error {
+	fs := newFlagSet("dns lookup")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("give the domain")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := discovery.NewResolver(nil).Lookup(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "pubkey:    %x\n", r.PubKey)
+	for _, e := range r.Endpoints {
+		fmt.Fprintf(out, "endpoint:  %s\n", e)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// loadKey reads the oracle's private key from a key file
+func loadKey(filename string) ([32]byte, error) {
+	var priv *[32]byte
+	var err error
+	pass, ok := os.LookupEnv(passphraseEnv)
+	if ok {
+		priv, err = dlcoracle.LoadKeyFromFileArg(filename, []byte(pass))
+	} else {
+		priv, err = dlcoracle.LoadKeyFromFileInteractive(filename)
+	}
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return *priv, nil
+}
+
+func keygen(args []string, out io.Writer) error {
+	fs := newFlagSet("keygen")
+	keyFile := fs.String("key", "", "key file to create")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	_, err = os.Stat(*keyFile)
+	if err == nil {
+		return fmt.Errorf("%s already exists", *keyFile)
+	}
+
+	priv, err := dlcoracle.GenerateOneTimeSigningKey()
+	if err != nil {
+		return err
+	}
+	pass, ok := os.LookupEnv(passphraseEnv)
+	if ok {
+		err = dlcoracle.SaveKeyToFileArg(*keyFile, &priv, []byte(pass))
+	} else {
+		err = dlcoracle.SaveKeyToFileInteractive(*keyFile, &priv)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%x\n", dlcoracle.PublicKeyFromPrivateKey(priv))
+	return nil
+}
+
+func pubkey(args []string, out io.Writer) error {
+	fs := newFlagSet("pubkey")
+	keyFile := fs.String("key", "", "oracle key file")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%x\n", dlcoracle.PublicKeyFromPrivateKey(priv))
+	return nil
+}
+
+func nonceDerive(args []string, out io.Writer) error {
+	fs := newFlagSet("nonce derive")
+	keyFile := fs.String("key", "", "oracle key file")
+	index := fs.Uint64("index", 0, "index of the one-time signing key")
+	private := fs.Bool("private", false, "also print the one-time signing key itself")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(priv, *index)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "rpoint: %x\n", dlcoracle.PublicKeyFromPrivateKey(k))
+	if *private {
+		fmt.Fprintf(out, "key:    %x\n", k)
+	}
+	return nil
+}
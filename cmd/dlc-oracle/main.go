@@ -0,0 +1,101 @@
+// Command dlc-oracle exercises the oracle library from the command line:
+// it creates and inspects keys, derives one-time signing keys, signs and
+// verifies messages, builds, inspects and checks announcements and
+// attestations, verifies audit logs, creates and looks up DNS discovery
+// records, and announces and attests on the offline machine of an oracle
+// whose daemon runs without its key.
+//
+// Key files use the format of dlcoracle.SaveKeyToFileArg. Encrypted key
+// files are decrypted with the passphrase in the DLC_ORACLE_PASSPHRASE
+// environment variable, or a prompt if it isn't set.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// passphraseEnv names the environment variable holding the key file
+// passphrase for non-interactive use
+const passphraseEnv = "DLC_ORACLE_PASSPHRASE"
+
+type command struct {
+	usage string
+	run   func(args []string, out io.Writer) error
+}
+
+// commands is filled in init, as the subcommands refer back to it for
+// their usage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"keygen":               {"keygen -key FILE", keygen},
+		"pubkey":               {"pubkey -key FILE", pubkey},
+		"nonce derive":         {"nonce derive -key FILE -index N [-private]", nonceDerive},
+		"sign":                 {"sign -key FILE [-index N] (-message HEX | -value N | -label S)", sign},
+		"verify":               {"verify -pubkey HEX [-rpoint HEX] -sig HEX (-message HEX | -value N | -label S)", verify},
+		"announcement create":  {"announcement create -key FILE -index N -id ID (-maturity TIME | -height H) [-type T] [-outcomes A,B] [-base B -digits N]", announcementCreate},
+		"announcement inspect": {"announcement inspect [FILE]", announcementInspect},
+		"attestation verify":   {"attestation verify -announcement FILE -attestation FILE", attestationVerify},
+		"audit verify":         {"audit verify FILE", auditVerify},
+		"dns record":           {"dns record -key FILE -domain D [-endpoints URL,URL] [-ttl S]", dnsRecord},
+		"dns lookup":           {"dns lookup DOMAIN", dnsLookup},
+		"offline prepare":      {"offline prepare -key FILE -ledger FILE [EVENTS]", offlinePrepare},
+		"offline sign":         {"offline sign -key FILE -ledger FILE [-list] [REQUESTS]", offlineSign},
+	}
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dlc-oracle: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches args to the subcommand they name, which may be one or
+// two words long
+func run(args []string, out io.Writer) error {
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		cmd, ok := commands[name]
+		if ok {
+			return cmd.run(args[n:], out)
+		}
+	}
+	printUsage(os.Stderr)
+	return flag.ErrHelp
+}
+
+func printUsage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage: dlc-oracle <command> [flags]\n\ncommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+}
+
+// newFlagSet returns the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: dlc-oracle %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
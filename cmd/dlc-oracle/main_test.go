@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/discovery"
+	"github.com/mit-dci/dlc-oracle-go/offline"
+)
+
+func runOK(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	err := run(args, &out)
+	if err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// field returns the value of a "name: value" line
+func field(t *testing.T, out, name string) string {
+	t.Helper()
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && k == name {
+			return strings.TrimSpace(v)
+		}
+	}
+	t.Fatalf("no %s in %q", name, out)
+	return ""
+}
+
+func TestKeysAndSignatures(t *testing.T) {
+	t.Setenv(passphraseEnv, "correct horse")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "oracle.key")
+
+	pub := strings.TrimSpace(runOK(t, "keygen", "-key", keyFile))
+	if got := strings.TrimSpace(runOK(t, "pubkey", "-key", keyFile)); got != pub {
+		t.Fatalf("pubkey %s, keygen printed %s", got, pub)
+	}
+	err := run([]string{"keygen", "-key", keyFile}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("keygen overwrote an existing key file")
+	}
+
+	out := runOK(t, "sign", "-key", keyFile, "-index", "7", "-value", "42")
+	rpoint := field(t, out, "rpoint")
+	sig := field(t, out, "signature")
+	derived := runOK(t, "nonce", "derive", "-key", keyFile, "-index", "7")
+	if field(t, derived, "rpoint") != rpoint {
+		t.Fatal("sign used a different R point than nonce derive")
+	}
+	runOK(t, "verify", "-pubkey", pub, "-rpoint", rpoint, "-sig", sig, "-value", "42")
+	err = run([]string{"verify", "-pubkey", pub, "-rpoint", rpoint, "-sig", sig, "-value", "43"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("signature verified for another value")
+	}
+
+	sig = strings.TrimSpace(runOK(t, "sign", "-key", keyFile, "-message", "c0ffee"))
+	runOK(t, "verify", "-pubkey", pub, "-sig", sig, "-message", "c0ffee")
+
+	err = run([]string{"sign", "-key", keyFile, "-value", "1", "-label", "x"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("sign accepted two messages")
+	}
+}
+
+func TestAnnouncementAndAttestation(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "oracle.key")
+	runOK(t, "keygen", "-key", keyFile)
+
+	ann := runOK(t, "announcement", "create", "-key", keyFile, "-index", "3",
+		"-id", "election", "-maturity", "2030-01-01T00:00:00Z",
+		"-type", "enum", "-outcomes", "yes,no")
+	annFile := filepath.Join(dir, "announcement.json")
+	err := os.WriteFile(annFile, []byte(ann), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := runOK(t, "announcement", "inspect", annFile)
+	if field(t, out, "signature") != "valid" || field(t, out, "outcomes") != "yes, no" {
+		t.Fatalf("unexpected inspection:\n%s", out)
+	}
+
+	signed := runOK(t, "sign", "-key", keyFile, "-index", "3", "-label", "no")
+	var att dlcoracle.Attestation
+	att.EventID = "election"
+	att.Message = []byte("no")
+	err = decodeHexFlag(att.Signature[:], "sig", field(t, signed, "signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attFile := filepath.Join(dir, "attestation.json")
+	err = os.WriteFile(attFile, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = runOK(t, "attestation", "verify", "-announcement", annFile, "-attestation", attFile)
+	if !strings.Contains(out, `outcome "no"`) {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	// A signature with another index doesn't match the announced R point
+	signed = runOK(t, "sign", "-key", keyFile, "-index", "4", "-label", "no")
+	decodeHexFlag(att.Signature[:], "sig", field(t, signed, "signature"))
+	b, _ = json.Marshal(att)
+	os.WriteFile(attFile, b, 0600)
+	err = run([]string{"attestation", "verify", "-announcement", annFile, "-attestation", attFile}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("attestation verified with the wrong R point")
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	err := run([]string{"frobnicate"}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("unknown command accepted")
+	}
+}
+
+func TestDigitsAnnouncement(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "oracle.key")
+	runOK(t, "keygen", "-key", keyFile)
+
+	ann := runOK(t, "announcement", "create", "-key", keyFile, "-index", "0",
+		"-id", "price", "-maturity", "2030-01-01T00:00:00Z",
+		"-type", "digits", "-base", "2", "-digits", "8")
+	annFile := filepath.Join(dir, "announcement.json")
+	err := os.WriteFile(annFile, []byte(ann), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := runOK(t, "announcement", "inspect", annFile)
+	if field(t, out, "signature") != "valid" || field(t, out, "digits") != "8 in base 2" {
+		t.Fatalf("unexpected inspection:\n%s", out)
+	}
+
+	var a dlcoracle.Announcement
+	err = json.Unmarshal([]byte(ann), &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := loadKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(priv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := dlcoracle.SignOutcome(priv, k, a, dlcoracle.Outcome{Value: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attFile := filepath.Join(dir, "attestation.json")
+	err = os.WriteFile(attFile, b, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = runOK(t, "attestation", "verify", "-announcement", annFile, "-attestation", attFile)
+	if !strings.Contains(out, "outcome 200") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestDerivedEventID(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	keyFile := filepath.Join(t.TempDir(), "oracle.key")
+	runOK(t, "keygen", "-key", keyFile)
+
+	ann := runOK(t, "announcement", "create", "-key", keyFile, "-index", "0",
+		"-maturity", "2030-01-01T00:00:00Z", "-type", "enum", "-outcomes", "yes,no")
+	var a dlcoracle.Announcement
+	err := json.Unmarshal([]byte(ann), &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.EventID != dlcoracle.EventID(a.Descriptor, a.Maturity) {
+		t.Fatalf("unexpected event id %s", a.EventID)
+	}
+}
+
+func TestAuditVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append(audit.KindAnnouncement, "event", []byte{1}, []byte{2})
+	e, _ := l.Append(audit.KindAttestation, "event", []byte{3}, []byte{4})
+	l.Close()
+
+	out := runOK(t, "audit", "verify", path)
+	if !strings.HasPrefix(out, "2 signatures") || field(t, out, "head") != fmt.Sprintf("%x", e.Hash) {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	b, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(b, []byte(`"seq":1`), []byte(`"seq":2`), 1), 0600)
+	err = run([]string{"audit", "verify", path}, &bytes.Buffer{})
+	if !errors.Is(err, audit.ErrBrokenChain) {
+		t.Fatalf("expected ErrBrokenChain, got %v", err)
+	}
+}
+
+func TestDNSRecord(t *testing.T) {
+	t.Setenv(passphraseEnv, "correct horse")
+	keyFile := filepath.Join(t.TempDir(), "oracle.key")
+	pub := strings.TrimSpace(runOK(t, "keygen", "-key", keyFile))
+
+	out := runOK(t, "dns", "record", "-key", keyFile, "-domain", "oracle.example.com", "-endpoints", "https://oracle.example.com")
+	name, txt, ok := strings.Cut(strings.TrimSpace(out), " 3600 IN TXT ")
+	if !ok || name != "_dlc-oracle.oracle.example.com." {
+		t.Fatalf("unexpected output %q", out)
+	}
+	var parts []string
+	for _, s := range strings.Split(txt, "\" \"") {
+		parts = append(parts, strings.Trim(s, "\""))
+	}
+	r, err := discovery.Parse("oracle.example.com", strings.Join(parts, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Verify() != nil || fmt.Sprintf("%x", r.PubKey) != pub || r.Endpoints[0] != "https://oracle.example.com" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	t.Setenv(passphraseEnv, "")
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "oracle.key")
+	ledgerFile := filepath.Join(dir, "ledger.json")
+	runOK(t, "keygen", "-key", keyFile)
+
+	eventsFile := filepath.Join(dir, "events.json")
+	os.WriteFile(eventsFile, []byte(`[
+		{"id": "rain", "maturity": "2020-01-01T00:00:00Z", "descriptor": {"type": "enum", "outcomes": ["yes", "no"]}},
+		{"maturity": "2030-01-01T00:00:00Z", "descriptor": {"type": "numeric"}}
+	]`), 0600)
+	out := runOK(t, "offline", "prepare", "-key", keyFile, "-ledger", ledgerFile, eventsFile)
+	var b offline.Bundle
+	err := json.Unmarshal([]byte(out), &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Announcements) != 2 || b.Announcements[0].EventID != "rain" || b.Announcements[1].Verify() != nil {
+		t.Fatalf("unexpected bundle %+v", b)
+	}
+	err = run([]string{"offline", "prepare", "-key", keyFile, "-ledger", ledgerFile, eventsFile}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatal("announced the events twice")
+	}
+
+	reqsFile := filepath.Join(dir, "requests.json")
+	offline.WriteFile(reqsFile, &offline.Requests{Version: offline.Version, Requests: []offline.Request{
+		{Announcement: b.Announcements[0], Message: []byte("no")},
+	}})
+	out = runOK(t, "offline", "sign", "-list", reqsFile)
+	if out != "rain\t\"no\"\n" {
+		t.Fatalf("unexpected list %q", out)
+	}
+	out = runOK(t, "offline", "sign", "-key", keyFile, "-ledger", ledgerFile, reqsFile)
+	var signed offline.Bundle
+	err = json.Unmarshal([]byte(out), &signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := dlcoracle.VerifyAttestation(b.Announcements[0], signed.Attestations[0])
+	if err != nil || o.Label != "no" {
+		t.Fatalf("attested %+v %v", o, err)
+	}
+}
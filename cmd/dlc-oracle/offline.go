@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/offline"
+)
+
+// eventJSON is an event to announce with offline prepare
+type eventJSON struct {
+	ID             string                    `json:"id"`
+	Maturity       time.Time                 `json:"maturity"`
+	MaturityHeight uint32                    `json:"maturityHeight"`
+	Descriptor     dlcoracle.EventDescriptor `json:"descriptor"`
+	Metadata       dlcoracle.EventMetadata   `json:"metadata"`
+}
+
+func offlinePrepare(args []string, out io.Writer) error {
+	fs := newFlagSet("offline prepare")
+	keyFile := fs.String("key", "", "oracle key file")
+	ledgerFile := fs.String("ledger", "", "ledger of announced events, created if missing")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" || *ledgerFile == "" {
+		return fmt.Errorf("-key and -ledger are required")
+	}
+	var list []eventJSON
+	err = readJSON(fs.Arg(0), &list)
+	if err != nil {
+		return err
+	}
+	events := make([]dlcoracle.Event, len(list))
+	for i, e := range list {
+		err = e.Descriptor.Validate()
+		if err != nil {
+			return fmt.Errorf("event %d: %v", i, err)
+		}
+		err = e.Metadata.Validate()
+		if err != nil {
+			return fmt.Errorf("event %d: %v", i, err)
+		}
+		if e.ID == "" {
+			e.ID = dlcoracle.EventID(e.Descriptor, e.Maturity)
+		}
+		events[i] = dlcoracle.Event{ID: e.ID, Maturity: e.Maturity, MaturityHeight: e.MaturityHeight, Descriptor: e.Descriptor, Metadata: e.Metadata}
+	}
+
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	s, err := offline.NewSigner(priv, *ledgerFile)
+	if err != nil {
+		return err
+	}
+	b, err := s.Prepare(events)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, b)
+}
+
+func offlineSign(args []string, out io.Writer) error {
+	fs := newFlagSet("offline sign")
+	keyFile := fs.String("key", "", "oracle key file")
+	ledgerFile := fs.String("ledger", "", "ledger of announced events")
+	list := fs.Bool("list", false, "list the requested outcomes instead of signing")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	var reqs offline.Requests
+	err = readJSON(fs.Arg(0), &reqs)
+	if err != nil {
+		return err
+	}
+	if reqs.Version != offline.Version {
+		return fmt.Errorf("unsupported requests version %d", reqs.Version)
+	}
+	if *list {
+		for _, r := range reqs.Requests {
+			d := r.Announcement.Descriptor
+			o, err := d.ParseOutcome(r.Message)
+			if err != nil {
+				fmt.Fprintf(out, "%s\tinvalid outcome %x\n", r.Announcement.EventID, r.Message)
+				continue
+			}
+			fmt.Fprintf(out, "%s\t%s\n", r.Announcement.EventID, formatOutcome(d, o))
+		}
+		return nil
+	}
+	if *keyFile == "" || *ledgerFile == "" {
+		return fmt.Errorf("-key and -ledger are required")
+	}
+
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	s, err := offline.NewSigner(priv, *ledgerFile)
+	if err != nil {
+		return err
+	}
+	b, err := s.Sign(reqs)
+	if err != nil {
+		return err
+	}
+	return writeJSON(out, b)
+}
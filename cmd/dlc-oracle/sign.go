@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// messageFlags are the ways to give the message to sign or verify
+type messageFlags struct {
+	hex   *string
+	value *int64
+	label *string
+}
+
+func addMessageFlags(fs *flag.FlagSet) *messageFlags {
+	return &messageFlags{
+		hex:   fs.String("message", "", "hex encoded message"),
+		value: fs.Int64("value", -1, "numeric outcome, encoded like numeric events"),
+		label: fs.String("label", "", "enum outcome, encoded like enum events"),
+	}
+}
+
+// message returns the message given by exactly one of the flags
+func (m *messageFlags) message() ([]byte, error) {
+	var msg []byte
+	given := 0
+	if *m.hex != "" {
+		b, err := hex.DecodeString(*m.hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -message: %v", err)
+		}
+		msg = b
+		given++
+	}
+	if *m.value >= 0 {
+		msg = dlcoracle.GenerateNumericMessage(uint64(*m.value))
+		given++
+	}
+	if *m.label != "" {
+		msg = []byte(*m.label)
+		given++
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("give exactly one of -message, -value and -label")
+	}
+	return msg, nil
+}
+
+// decodeHexFlag decodes a hex flag value into dst, which it must fill
+func decodeHexFlag(dst []byte, name, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid -%s: %v", name, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid -%s: expected %d bytes, got %d", name, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
+
+// sign signs a message with a derived one-time signing key, as the oracle
+// attests to outcomes, or with a fresh one as dlcoracle.SignMessage does.
+// Signing two different messages with the same index reveals the
+// oracle's private key.
+func sign(args []string, out io.Writer) error {
+	fs := newFlagSet("sign")
+	keyFile := fs.String("key", "", "oracle key file")
+	index := fs.Int64("index", -1, "index of the one-time signing key to attest with")
+	m := addMessageFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return fmt.Errorf("-key is required")
+	}
+	msg, err := m.message()
+	if err != nil {
+		return err
+	}
+	priv, err := loadKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	if *index < 0 {
+		sig, err := dlcoracle.SignMessage(priv, msg)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%x\n", sig)
+		return nil
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(priv, uint64(*index))
+	if err != nil {
+		return err
+	}
+	sig, err := dlcoracle.ComputeSignature(priv, k, msg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "rpoint:    %x\n", dlcoracle.PublicKeyFromPrivateKey(k))
+	fmt.Fprintf(out, "signature: %x\n", sig)
+	return nil
+}
+
+// verify checks a 32 byte signature against an R point, or a 65 byte
+// signature from SignMessage if no R point is given
+func verify(args []string, out io.Writer) error {
+	fs := newFlagSet("verify")
+	pubHex := fs.String("pubkey", "", "oracle public key")
+	rHex := fs.String("rpoint", "", "R point the message was signed with")
+	sigHex := fs.String("sig", "", "signature")
+	m := addMessageFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	msg, err := m.message()
+	if err != nil {
+		return err
+	}
+	var pub [33]byte
+	err = decodeHexFlag(pub[:], "pubkey", *pubHex)
+	if err != nil {
+		return err
+	}
+
+	if *rHex == "" {
+		var sig [65]byte
+		err = decodeHexFlag(sig[:], "sig", *sigHex)
+		if err != nil {
+			return err
+		}
+		err = dlcoracle.VerifyMessage(pub, msg, sig)
+	} else {
+		var r [33]byte
+		var sig [32]byte
+		err = decodeHexFlag(r[:], "rpoint", *rHex)
+		if err != nil {
+			return err
+		}
+		err = decodeHexFlag(sig[:], "sig", *sigHex)
+		if err != nil {
+			return err
+		}
+		err = dlcoracle.VerifySignature(pub, r, msg, sig)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	fmt.Fprintf(out, "signature valid\n")
+	return nil
+}
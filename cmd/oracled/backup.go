@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/backup"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// newBackupManager returns the backup manager configured in cfg for
+// store, nil if backups aren't configured
+func newBackupManager(cfg Config, store storage.Store) (*backup.Manager, error) {
+	bc := cfg.Backup
+	if bc.S3.Bucket == "" {
+		return nil, nil
+	}
+	if bc.S3.Endpoint == "" {
+		return nil, fmt.Errorf("backup needs an s3 endpoint")
+	}
+	w, err := keyWrapper(bc.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %v", err)
+	}
+	if w == nil {
+		return nil, fmt.Errorf("backup needs an encryption passphrase or vault key")
+	}
+	bucket := &backup.S3{
+		Endpoint:  bc.S3.Endpoint,
+		Region:    bc.S3.Region,
+		Bucket:    bc.S3.Bucket,
+		AccessKey: bc.S3.AccessKey,
+		SecretKey: bc.S3.SecretKey,
+		PathStyle: bc.S3.PathStyle,
+	}
+	m := backup.NewManager(bucket, bc.Prefix, w, store)
+	m.SetAuditLog(cfg.Audit.Path)
+	m.SetRetention(backup.Retention{Keep: bc.Keep, MaxAge: bc.MaxAge})
+	return m, nil
+}
+
+// backupNow makes a backup right away
+func backupNow(cfg Config, out io.Writer) error {
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	m, err := newBackupManager(cfg, store)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("backup.s3.bucket isn't set")
+	}
+	key, err := m.Backup(context.Background())
+	if key != "" {
+		fmt.Fprintf(out, "uploaded %s\n", key)
+	}
+	return err
+}
+
+// restoreBackup lists the backups, or imports the newest one or the one
+// named in args into the store, derives the one-time signing keys of its
+// events and writes its audit log to the configured path
+func restoreBackup(cfg Config, priv [32]byte, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list the backups instead of restoring one")
+	force := fs.Bool("force", false, "replace an existing audit log")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	m, err := newBackupManager(cfg, store)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("backup.s3.bucket isn't set")
+	}
+	ctx := context.Background()
+	if *list {
+		backups, err := m.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			fmt.Fprintf(out, "%s\t%d\n", b.Key, b.Size)
+		}
+		return nil
+	}
+
+	a, err := m.Fetch(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	restoreAudit := len(a.Audit) != 0 && cfg.Audit.Path != ""
+	if restoreAudit {
+		_, _, err = audit.Verify(bytes.NewReader(a.Audit))
+		if err != nil {
+			return fmt.Errorf("audit log in backup: %w", err)
+		}
+		fi, err := os.Stat(cfg.Audit.Path)
+		if err == nil && fi.Size() != 0 && !*force {
+			return fmt.Errorf("audit log %s exists, restore with -force to replace it", cfg.Audit.Path)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "restoring backup of %s\n", a.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	err = importSnapshot(store, priv, a.Snapshot, out)
+	if err != nil {
+		return err
+	}
+	if !restoreAudit {
+		return nil
+	}
+	tmp := cfg.Audit.Path + ".tmp"
+	err = os.WriteFile(tmp, a.Audit, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, cfg.Audit.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "restored audit log %s\n", cfg.Audit.Path)
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go/config"
+)
+
+// Validate checks the settings that are wrong whatever the state on disk,
+// above all the files that must never be shared: the store holds the
+// one-time signing keys and the index the next one is derived from, and a
+// store overwritten by another file, or two of them in one file, can
+// lead the oracle to sign two outcomes with the same key and reveal its
+// private key.
+func (cfg *Config) Validate() error {
+	var errs config.Errors
+	fail := func(path, format string, args ...interface{}) {
+		errs = append(errs, &config.Error{Source: "config", Path: path, Err: fmt.Errorf(format, args...)})
+	}
+
+	switch cfg.Store.Driver {
+	case "memory":
+	case "bolt":
+		if cfg.Store.Path == "" {
+			fail("store.path", "the bolt store needs a path")
+		}
+	case "sqlite", "postgres":
+		if cfg.Store.DSN == "" {
+			fail("store.dsn", "the %s store needs a dsn", cfg.Store.Driver)
+		}
+	default:
+		fail("store.driver", "unknown driver %q, expected memory, bolt, sqlite or postgres", cfg.Store.Driver)
+	}
+
+	if cfg.Offline.PubKey != "" {
+		b, err := hex.DecodeString(cfg.Offline.PubKey)
+		if err != nil || len(b) != 33 {
+			fail("offline.pub_key", "expected 33 hex encoded bytes")
+		}
+		if cfg.Offline.Requests == "" {
+			fail("offline.requests", "an offline oracle needs a requests file")
+		}
+		if cfg.Offline.Inbox == "" {
+			fail("offline.inbox", "an offline oracle needs an inbox directory")
+		}
+	} else if cfg.Key.File == "" {
+		fail("key.file", "the key file is required unless offline.pub_key is set")
+	}
+
+	var level slog.Level
+	if level.UnmarshalText([]byte(cfg.Log.Level)) != nil {
+		fail("log.level", "unknown level %q, expected debug, info, warn or error", cfg.Log.Level)
+	}
+	if cfg.Log.Format != "" && cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		fail("log.format", "unknown format %q, expected text or json", cfg.Log.Format)
+	}
+
+	if cfg.Tracing.URL != "" {
+		u, err := url.Parse(cfg.Tracing.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("tracing.url", "expected an http or https URL, such as http://collector:4318/v1/traces")
+		}
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "%v is not between 0 and 1", cfg.Tracing.SampleRatio)
+	}
+
+	for i, tc := range cfg.Scheduler.Templates {
+		tmpl, err := newTemplate(tc)
+		if err == nil {
+			err = tmpl.Validate()
+		}
+		if err != nil {
+			fail(fmt.Sprintf("scheduler.templates[%d]", i), "%v", err)
+		}
+	}
+
+	// Every file the daemon writes must be its own
+	seen := make(map[string]string)
+	for _, f := range cfg.files() {
+		abs, err := filepath.Abs(f.name)
+		if err != nil {
+			fail(f.path, "%v", err)
+			continue
+		}
+		if other, ok := seen[abs]; ok {
+			fail(f.path, "%s is also %s", f.name, other)
+			continue
+		}
+		seen[abs] = f.path
+	}
+
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// configFile is a file named in the configuration
+type configFile struct {
+	path, name string
+}
+
+// files returns the files the daemon reads and writes, the store's first
+func (cfg *Config) files() []configFile {
+	var list []configFile
+	add := func(path, name string) {
+		if name != "" {
+			list = append(list, configFile{path, name})
+		}
+	}
+	add(cfg.storeFile())
+	if cfg.Offline.PubKey == "" {
+		add("key.file", cfg.Key.File)
+	}
+	add("audit.path", cfg.Audit.Path)
+	add("offline.requests", cfg.Offline.Requests)
+	add("offline.inbox", cfg.Offline.Inbox)
+	if cfg.Tor.Control != "" {
+		add("tor.key_file", cfg.Tor.KeyFile)
+	}
+	add("tls.cert_file", cfg.TLS.CertFile)
+	add("tls.key_file", cfg.TLS.KeyFile)
+	add("tls.acme.cache_dir", cfg.TLS.ACME.CacheDir)
+	add("grpc.api_keys_file", cfg.GRPC.APIKeysFile)
+	add("scheduler.outcome_script", cfg.Scheduler.OutcomeScript)
+	return list
+}
+
+// storeFile returns the setting and name of the store's file, if it keeps
+// one: bolt's path or the file of a SQLite DSN such as
+// file:oracle.db?_pragma=busy_timeout(5000)
+func (cfg *Config) storeFile() (string, string) {
+	switch cfg.Store.Driver {
+	case "bolt":
+		return "store.path", cfg.Store.Path
+	case "sqlite":
+		name := strings.TrimPrefix(cfg.Store.DSN, "file:")
+		name, _, _ = strings.Cut(name, "?")
+		if name == ":memory:" {
+			return "store.dsn", ""
+		}
+		return "store.dsn", name
+	}
+	return "", ""
+}
+
+// Warnings returns the settings that are likely mistakes given the files
+// on disk, though they are right for a new oracle
+func (cfg *Config) Warnings() []string {
+	var list []string
+	if cfg.Store.Driver == "memory" && cfg.Offline.PubKey == "" && !cfg.Key.AuxRand {
+		list = append(list, "store.driver: the memory store forgets the one-time signing keys it "+
+			"handed out, so events announced after a restart reuse them; use it for testing only")
+	}
+	path, name := cfg.storeFile()
+	if name == "" {
+		return list
+	}
+	if !filepath.IsAbs(name) {
+		list = append(list, fmt.Sprintf("%s: %s is relative to the working directory, "+
+			"starting elsewhere opens another store", path, name))
+	}
+	_, err := os.Stat(name)
+	if !errors.Is(err, os.ErrNotExist) {
+		return list
+	}
+	if _, err := os.Stat(cfg.Audit.Path); cfg.Audit.Path != "" && err == nil {
+		list = append(list, fmt.Sprintf("%s: %s doesn't exist but the audit log does: a new store "+
+			"derives one-time signing keys from index 0 again, reusing those of the events announced before", path, name))
+	} else if _, err := os.Stat(cfg.Key.File); cfg.Offline.PubKey == "" && err == nil {
+		list = append(list, fmt.Sprintf("%s: %s doesn't exist, a new store will be created; "+
+			"if the key announced events before, restore their store instead", path, name))
+	}
+	return list
+}
+
+// checkConfig loads the configuration like the daemon does and writes
+// every problem and warning to out, failing if there are problems
+func checkConfig(path string, set []string, out io.Writer) error {
+	cfg, err := LoadConfig(path, set...)
+	var errs config.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			fmt.Fprintf(out, "error: %v\n", e)
+		}
+		return fmt.Errorf("configuration is invalid")
+	}
+	if err != nil {
+		return err
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Fprintf(out, "warning: %s\n", w)
+	}
+	fmt.Fprintln(out, "configuration is valid")
+	return nil
+}
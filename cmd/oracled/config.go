@@ -0,0 +1,361 @@
+package main
+
+import (
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/config"
+)
+
+// envPrefix starts the names of environment variables overriding the
+// config file. ORACLED_STORE_DRIVER overrides store.driver, for example.
+const envPrefix = "ORACLED"
+
+// Config is the daemon's configuration, read from a YAML file
+type Config struct {
+	Key       KeyConfig       `yaml:"key"`
+	Offline   OfflineConfig   `yaml:"offline"`
+	Store     StoreConfig     `yaml:"store"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Backup    BackupConfig    `yaml:"backup"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	TLS       TLSConfig       `yaml:"tls"`
+	REST      RESTConfig      `yaml:"rest"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Tor       TorConfig       `yaml:"tor"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Clock     ClockConfig     `yaml:"clock"`
+	Chain     ChainConfig     `yaml:"chain"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Nostr     NostrConfig     `yaml:"nostr"`
+	Webhooks  []WebhookConfig `yaml:"webhooks"`
+	Alerts    AlertsConfig    `yaml:"alerts"`
+	Sources   []SourceConfig  `yaml:"sources"`
+}
+
+// KeyConfig locates the oracle's private key. The passphrase of an
+// encrypted key file is best given as ORACLED_KEY_PASSPHRASE; without
+// one it is prompted for. AuxRand mixes fresh randomness into the
+// one-time signing keys of new events.
+type KeyConfig struct {
+	File       string `yaml:"file"`
+	Passphrase string `yaml:"passphrase"`
+	AuxRand    bool   `yaml:"aux_rand"`
+}
+
+// OfflineConfig runs the daemon without the private key, which is kept
+// on an offline machine, if PubKey is set. Announcements and attestations
+// signed there are imported from bundles dropped into the Inbox directory;
+// attestation requests are written to the Requests file for the operator
+// to carry over. See package offline.
+type OfflineConfig struct {
+	PubKey   string `yaml:"pub_key"`
+	Requests string `yaml:"requests"`
+	Inbox    string `yaml:"inbox"`
+}
+
+// StoreConfig selects the store: "memory", "bolt" with a Path, or
+// "sqlite" or "postgres" with a DSN
+type StoreConfig struct {
+	Driver     string           `yaml:"driver"`
+	Path       string           `yaml:"path"`
+	DSN        string           `yaml:"dsn"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+}
+
+// EncryptionConfig encrypts the one-time signing keys in the store with a
+// data key wrapped by a Passphrase, best given as
+// ORACLED_STORE_ENCRYPTION_PASSPHRASE, or by a key of a Vault transit
+// engine. Once a store is encrypted it can't be opened without it.
+type EncryptionConfig struct {
+	Passphrase string      `yaml:"passphrase"`
+	Vault      VaultConfig `yaml:"vault"`
+}
+
+// VaultConfig names a transit key of a HashiCorp Vault or OpenBao server,
+// if Addr is set. Mount defaults to "transit".
+type VaultConfig struct {
+	Addr  string `yaml:"addr"`
+	Token string `yaml:"token"`
+	Key   string `yaml:"key"`
+	Mount string `yaml:"mount"`
+}
+
+// AuditConfig locates the hash chained log every signature is recorded
+// in, if Path is set
+type AuditConfig struct {
+	Path string `yaml:"path"`
+}
+
+// BackupConfig uploads encrypted backups of the store and the audit log
+// to an S3-compatible bucket every Interval, if S3.Bucket is set. Backups
+// beyond the Keep newest and older than MaxAge are deleted; zero keeps
+// them. Encryption is required, and best given as
+// ORACLED_BACKUP_ENCRYPTION_PASSPHRASE.
+type BackupConfig struct {
+	Interval   time.Duration    `yaml:"interval"`
+	Keep       int              `yaml:"keep"`
+	MaxAge     time.Duration    `yaml:"max_age"`
+	Prefix     string           `yaml:"prefix"`
+	S3         S3Config         `yaml:"s3"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+}
+
+// S3Config locates a bucket, see backup.S3. The secret key is best given
+// as ORACLED_BACKUP_S3_SECRET_KEY.
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	PathStyle bool   `yaml:"path_style"`
+}
+
+// LogConfig sets the minimum level ("debug", "info", "warn", "error") and
+// format ("text" or "json") of the log written to stderr
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig exports OpenTelemetry traces of the request path to the
+// OTLP/HTTP endpoint URL, such as http://collector:4318/v1/traces, if it
+// is set. Headers are sent with each export, for instance to
+// authenticate. SampleRatio is the share of new traces recorded, all of
+// them when zero; traces continued from a caller follow its decision.
+// ServiceName defaults to "oracled".
+type TracingConfig struct {
+	URL         string            `yaml:"url"`
+	Headers     map[string]string `yaml:"headers"`
+	SampleRatio float64           `yaml:"sample_ratio"`
+	ServiceName string            `yaml:"service_name"`
+}
+
+// TLSConfig serves both APIs over TLS, with the certificate in CertFile
+// and KeyFile or with certificates obtained over ACME. ClientCAFile
+// lets clients with a certificate signed by one of its CAs use the admin
+// endpoints: the administrative RPCs and /metrics, which then requires a
+// certificate.
+type TLSConfig struct {
+	CertFile     string     `yaml:"cert_file"`
+	KeyFile      string     `yaml:"key_file"`
+	ACME         ACMEConfig `yaml:"acme"`
+	ClientCAFile string     `yaml:"client_ca_file"`
+}
+
+// ACMEConfig obtains and renews certificates for Domains from an ACME CA,
+// Let's Encrypt unless DirectoryURL is set. The CA checks the domains on
+// the REST listener, which must be reachable on port 443, or over plain
+// HTTP on HTTPListen, which must be reachable on port 80.
+type ACMEConfig struct {
+	Domains      []string `yaml:"domains"`
+	Email        string   `yaml:"email"`
+	CacheDir     string   `yaml:"cache_dir"`
+	DirectoryURL string   `yaml:"directory_url"`
+	HTTPListen   string   `yaml:"http_listen"`
+}
+
+// RESTConfig configures the REST server. Metrics are served on it at
+// /metrics when enabled.
+type RESTConfig struct {
+	Listen  string `yaml:"listen"`
+	Metrics bool   `yaml:"metrics"`
+}
+
+// GRPCConfig configures the gRPC service. AuthRootKey is the hex encoded
+// root key of the token authority. APIKeysFile keeps the API keys created
+// with the key management RPCs, which are disabled without it.
+type GRPCConfig struct {
+	Listen       string `yaml:"listen"`
+	AdminToken   string `yaml:"admin_token"`
+	AuthRootKey  string `yaml:"auth_root_key"`
+	APIKeysFile  string `yaml:"api_keys_file"`
+	PrivateReads bool   `yaml:"private_reads"`
+}
+
+// RateLimitConfig limits the requests to both servers, see
+// ratelimit.Config
+type RateLimitConfig struct {
+	GlobalRate        float64 `yaml:"global_rate"`
+	GlobalBurst       int     `yaml:"global_burst"`
+	PerIPRate         float64 `yaml:"per_ip_rate"`
+	PerIPBurst        int     `yaml:"per_ip_burst"`
+	TrustForwardedFor bool    `yaml:"trust_forwarded_for"`
+}
+
+// ClockConfig checks the system clock against NTP servers before
+// attesting, if any are given
+type ClockConfig struct {
+	NTPServers []string      `yaml:"ntp_servers"`
+	MaxDrift   time.Duration `yaml:"max_drift"`
+}
+
+// ChainConfig selects the chain backend for events maturing at a block
+// height: "bitcoind" or "esplora"
+type ChainConfig struct {
+	Backend  string `yaml:"backend"`
+	URL      string `yaml:"url"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+}
+
+// SchedulerConfig tunes the scheduler and lists the recurring events it
+// announces
+type SchedulerConfig struct {
+	RetryInterval     time.Duration    `yaml:"retry_interval"`
+	ChainPollInterval time.Duration    `yaml:"chain_poll_interval"`
+	Templates         []TemplateConfig `yaml:"templates"`
+
+	// OutcomeScript is a Starlark file computing the outcomes to attest
+	// from those fetched, see the script package
+	OutcomeScript string `yaml:"outcome_script"`
+
+	// OnDisagreement is what to do when the sources of an event disagree,
+	// "retry" (the default), "abort" or "median", see
+	// scheduler.DisagreementPolicy. Templates may override it.
+	OnDisagreement string `yaml:"on_disagreement"`
+
+	// CaptureEvidence stores the responses of the price and jsonapi
+	// sources each event was attested from, signed by the oracle, and
+	// serves them at /api/evidence/{id}. API keys must then be set in
+	// headers rather than URLs, as the URLs are published.
+	CaptureEvidence bool `yaml:"capture_evidence"`
+}
+
+// TemplateConfig configures a recurring event, see scheduler.Template.
+// Cron, evaluated in Timezone (UTC by default), replaces Period. Type,
+// Outcomes, Base and Digits make up its descriptor.
+type TemplateConfig struct {
+	Prefix   string        `yaml:"prefix"`
+	Start    time.Time     `yaml:"start"`
+	Period   time.Duration `yaml:"period"`
+	Cron     string        `yaml:"cron"`
+	Timezone string        `yaml:"timezone"`
+	Ahead    int           `yaml:"ahead"`
+	Type     string        `yaml:"type"`
+	Outcomes []string      `yaml:"outcomes"`
+	Base     uint32        `yaml:"base"`
+	Digits   uint32        `yaml:"digits"`
+	// Unit, UnitExponent, Precision and Rounding describe the value of
+	// numeric and digits events, see dlcoracle.EventDescriptor
+	Unit         string `yaml:"unit"`
+	UnitExponent int32  `yaml:"unit_exponent"`
+	Precision    uint64 `yaml:"precision"`
+	Rounding     string `yaml:"rounding"`
+
+	// OnDisagreement overrides the scheduler's for the template's events
+	OnDisagreement string `yaml:"on_disagreement"`
+
+	Description string   `yaml:"description"`
+	Category    string   `yaml:"category"`
+	Tags        []string `yaml:"tags"`
+}
+
+// TorConfig publishes the REST API on port 80, or 443 with TLS, and the
+// gRPC service on its own port as a Tor onion service if Control is set to
+// the address of a Tor control port. Password authenticates to it; without
+// one the controller uses Tor's cookie file. The service key is kept in
+// KeyFile, created on first use, so the onion address stays the same.
+type TorConfig struct {
+	Control  string `yaml:"control"`
+	Password string `yaml:"password"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// NostrConfig publishes to Nostr relays if any are given. Key is the hex
+// encoded Nostr private key.
+type NostrConfig struct {
+	Key    string   `yaml:"key"`
+	Relays []string `yaml:"relays"`
+}
+
+// WebhookConfig is a URL notified of the oracle's activity, see
+// webhook.Endpoint. Types lists the payload types it receives, all if
+// empty.
+type WebhookConfig struct {
+	URL    string   `yaml:"url"`
+	Secret string   `yaml:"secret"`
+	Types  []string `yaml:"types"`
+}
+
+// AlertsConfig sends the scheduler's alerts, raised when an event is
+// overdue or its data sources disagree, to a Slack-compatible incoming
+// webhook and by email. Webhooks receive them as "alert" payloads.
+type AlertsConfig struct {
+	OverdueAfter time.Duration `yaml:"overdue_after"`
+	SlackURL     string        `yaml:"slack_url"`
+	SMTP         SMTPConfig    `yaml:"smtp"`
+}
+
+// SMTPConfig configures the mail server alerts are sent through, if Addr
+// is set. Username and Password authenticate with PLAIN auth.
+type SMTPConfig struct {
+	Addr     string   `yaml:"addr"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+// SourceConfig configures a data source. Type is one of "manual",
+// "price", "jsonapi", "block-hash", "fee-rate", "plugin" and "median";
+// events whose ID starts with one of Prefixes are routed to it.
+type SourceConfig struct {
+	ID       string   `yaml:"id"`
+	Type     string   `yaml:"type"`
+	Prefixes []string `yaml:"prefixes"`
+
+	// Precision is the number of decimals of numeric outcomes
+	Precision int `yaml:"precision"`
+
+	// Exchange, Pair and PollInterval configure price sources
+	Exchange     string        `yaml:"exchange"`
+	Pair         string        `yaml:"pair"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+
+	// URL, Headers, Path and Outcomes configure jsonapi sources
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	Path     string            `yaml:"path"`
+	Outcomes map[string]string `yaml:"outcomes"`
+
+	// Target is the confirmation target of fee-rate sources
+	Target int `yaml:"target"`
+
+	// Command, Env and Timeout configure plugin sources: the program to
+	// run and its arguments, the variables added to its environment and
+	// how long it may take to answer
+	Command []string          `yaml:"command"`
+	Env     map[string]string `yaml:"env"`
+	Timeout time.Duration     `yaml:"timeout"`
+
+	// Sources, Quorum and MaxDeviation configure median sources, see
+	// datasource.Median. The sources must be configured before it.
+	Sources      []string `yaml:"sources"`
+	Quorum       int      `yaml:"quorum"`
+	MaxDeviation float64  `yaml:"max_deviation"`
+}
+
+// defaultConfig returns the configuration used for anything the config
+// file doesn't set
+func defaultConfig() Config {
+	return Config{
+		Key:    KeyConfig{File: "oracle.key"},
+		Store:  StoreConfig{Driver: "bolt", Path: "oracle.db"},
+		Log:    LogConfig{Level: "info", Format: "text"},
+		REST:   RESTConfig{Listen: ":8080"},
+		Tor:    TorConfig{KeyFile: "onion.key"},
+		Backup: BackupConfig{Interval: 24 * time.Hour, Prefix: "oracled/"},
+	}
+}
+
+// LoadConfig reads the config file at path, if path isn't empty, applies
+// the overrides from the environment and the path=value settings in set,
+// and validates the result. Every problem found is reported, as
+// config.Errors.
+func LoadConfig(path string, set ...string) (Config, error) {
+	cfg := defaultConfig()
+	err := config.Load(&cfg, config.Options{File: path, EnvPrefix: envPrefix, Set: set})
+	return cfg, err
+}
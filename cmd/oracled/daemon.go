@@ -0,0 +1,828 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+	"github.com/mit-dci/dlc-oracle-go/backup"
+	"github.com/mit-dci/dlc-oracle-go/chain"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/datasource/block"
+	"github.com/mit-dci/dlc-oracle-go/datasource/jsonapi"
+	"github.com/mit-dci/dlc-oracle-go/datasource/plugin"
+	"github.com/mit-dci/dlc-oracle-go/datasource/price"
+	"github.com/mit-dci/dlc-oracle-go/evidence"
+	"github.com/mit-dci/dlc-oracle-go/health"
+	"github.com/mit-dci/dlc-oracle-go/metrics"
+	"github.com/mit-dci/dlc-oracle-go/nostr"
+	"github.com/mit-dci/dlc-oracle-go/notify"
+	"github.com/mit-dci/dlc-oracle-go/offline"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/ratelimit"
+	"github.com/mit-dci/dlc-oracle-go/rpc"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/mit-dci/dlc-oracle-go/script"
+	"github.com/mit-dci/dlc-oracle-go/server"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/boltstore"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+	"github.com/mit-dci/dlc-oracle-go/storage/sqlstore"
+	"github.com/mit-dci/dlc-oracle-go/tor"
+	"github.com/mit-dci/dlc-oracle-go/tracing"
+	"github.com/mit-dci/dlc-oracle-go/webhook"
+	"golang.org/x/crypto/acme/autocert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// readHeaderTimeout cuts off REST clients that are slow to send their
+	// request headers
+	readHeaderTimeout = 10 * time.Second
+
+	// shutdownTimeout bounds how long open requests may take to finish
+	// on shutdown
+	shutdownTimeout = 10 * time.Second
+)
+
+// newLogger returns the logger configured in cfg, writing to stderr
+func newLogger(cfg LogConfig) (dlcoracle.Logger, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(cfg.Level))
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch cfg.Format {
+	case "text", "":
+		return dlcoracle.SlogLogger(slog.New(slog.NewTextHandler(os.Stderr, opts))), nil
+	case "json":
+		return dlcoracle.SlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, opts))), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+}
+
+// openStore opens the configured store, migrating it to the current
+// schema, and unlocks it if encryption is configured. The returned
+// function closes it.
+func openStore(cfg StoreConfig) (storage.Store, func() error, error) {
+	s, closeStore, err := openDriver(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := keyWrapper(cfg.Encryption)
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	if w == nil {
+		return s, closeStore, nil
+	}
+	es, ok := s.(storage.EncryptingStore)
+	if !ok {
+		closeStore()
+		return nil, nil, fmt.Errorf("%s store doesn't support encryption", cfg.Driver)
+	}
+	err = es.Encrypt(context.Background(), w)
+	if err != nil {
+		closeStore()
+		return nil, nil, err
+	}
+	return s, closeStore, nil
+}
+
+// keyWrapper returns the wrapper of the store's data key, nil if the
+// store isn't encrypted
+func keyWrapper(cfg EncryptionConfig) (seal.KeyWrapper, error) {
+	switch {
+	case cfg.Passphrase != "" && cfg.Vault.Addr != "":
+		return nil, fmt.Errorf("store encryption needs either a passphrase or vault, not both")
+	case cfg.Passphrase != "":
+		return seal.Passphrase([]byte(cfg.Passphrase)), nil
+	case cfg.Vault.Addr != "":
+		if cfg.Vault.Key == "" {
+			return nil, fmt.Errorf("store encryption with vault needs a key")
+		}
+		return &seal.VaultTransit{Addr: cfg.Vault.Addr, Token: cfg.Vault.Token,
+			Key: cfg.Vault.Key, Mount: cfg.Vault.Mount}, nil
+	}
+	return nil, nil
+}
+
+// openDriver opens the store selected by cfg.Driver
+func openDriver(cfg StoreConfig) (storage.Store, func() error, error) {
+	nop := func() error { return nil }
+	switch cfg.Driver {
+	case "memory":
+		return storage.NewMemoryStore(), nop, nil
+	case "bolt":
+		s, err := boltstore.Open(cfg.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case "sqlite", "postgres":
+		driver, dialect := "sqlite3", sqlstore.SQLite
+		if cfg.Driver == "postgres" {
+			driver, dialect = "postgres", sqlstore.Postgres
+		}
+		db, err := sql.Open(driver, cfg.DSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, err := sqlstore.New(db, dialect)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return s, db.Close, nil
+	}
+	return nil, nil, fmt.Errorf("unknown store driver %q", cfg.Driver)
+}
+
+// loadKey reads the oracle's private key
+func loadKey(cfg KeyConfig) ([32]byte, error) {
+	var priv *[32]byte
+	var err error
+	if cfg.Passphrase != "" {
+		priv, err = dlcoracle.LoadKeyFromFileArg(cfg.File, []byte(cfg.Passphrase))
+	} else {
+		priv, err = dlcoracle.LoadKeyFromFileInteractive(cfg.File)
+	}
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return *priv, nil
+}
+
+// decodeKey decodes a hex encoded 32 byte key from the config
+func decodeKey(name, s string) ([32]byte, error) {
+	var key [32]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(key) {
+		return key, fmt.Errorf("%s must be 32 hex encoded bytes", name)
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+func newChain(cfg ChainConfig) (chain.Backend, error) {
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "bitcoind":
+		return chain.NewBitcoind(cfg.URL, cfg.User, cfg.Password), nil
+	case "esplora":
+		return chain.NewEsplora(cfg.URL), nil
+	}
+	return nil, fmt.Errorf("unknown chain backend %q", cfg.Backend)
+}
+
+// daemon holds the wired up components of a running oracle
+type daemon struct {
+	cfg     Config
+	logger  dlcoracle.Logger
+	store   storage.Store
+	close   func() error
+	oracle  *oracle.Oracle
+	sources *datasource.Registry
+	sched   *scheduler.Scheduler
+	metrics *metrics.Metrics
+	limiter *ratelimit.Limiter
+	rest    *server.Server
+	grpc    *grpc.Server
+	nostr   *nostr.Publisher
+	hooks   *webhook.Notifier
+	backup  *backup.Manager
+	inbox   *offline.Inbox
+	tls     *tls.Config
+	acme    *autocert.Manager
+
+	// ready answers /readyz, with the checks of the current data sources
+	ready *health.Checker
+
+	// evidence records the API responses of the data sources if
+	// capture_evidence is set
+	evidence *evidence.Recorder
+
+	// sourceSet runs the data sources registered in sources, which
+	// reloadSources replaces with those of the configuration loadConfig
+	// reads again
+	sourceSet  sourceSet
+	loadConfig func() (Config, error)
+	reloadMtx  sync.Mutex
+}
+
+// newDaemon wires up the components configured in cfg around the oracle
+// key priv, which is ignored if the key is kept offline
+func newDaemon(cfg Config, priv [32]byte, logger dlcoracle.Logger) (*daemon, error) {
+	d := &daemon{cfg: cfg, logger: logger}
+	var err error
+	d.store, d.close, err = openStore(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+	err = d.wire(priv)
+	if err != nil {
+		d.close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *daemon) wire(priv [32]byte) error {
+	cfg := d.cfg
+	dlcoracle.SetLogger(d.logger)
+	err := d.setupTracing()
+	if err != nil {
+		return err
+	}
+	if cfg.Offline.PubKey != "" {
+		d.oracle, d.inbox, err = newOfflineOracle(cfg.Offline, d.store)
+		if err != nil {
+			return err
+		}
+		d.inbox.SetLogger(d.logger)
+	} else {
+		d.oracle = oracle.New(priv, d.store)
+	}
+	d.oracle.SetLogger(d.logger)
+	if cfg.Key.AuxRand {
+		d.oracle.SetSignOptions(dlcoracle.WithAuxRand(rand.Reader))
+	}
+	if cfg.Audit.Path != "" {
+		l, err := audit.Open(cfg.Audit.Path)
+		if err != nil {
+			return err
+		}
+		closeStore := d.close
+		d.close = func() error {
+			err := l.Close()
+			storeErr := closeStore()
+			if err == nil {
+				err = storeErr
+			}
+			return err
+		}
+		d.oracle.SetAuditLog(l)
+		completed, err := d.oracle.CompleteAttestations()
+		if err != nil {
+			return err
+		}
+		for _, id := range completed {
+			d.logger.Log(dlcoracle.LevelWarn, "completed interrupted attestation", dlcoracle.F("event_id", id))
+		}
+	}
+	if len(cfg.Clock.NTPServers) != 0 {
+		c := clock.NewNTPClock(cfg.Clock.NTPServers...)
+		if cfg.Clock.MaxDrift != 0 {
+			c.MaxDrift = cfg.Clock.MaxDrift
+		}
+		d.oracle.SetClock(c)
+	}
+	backend, err := newChain(cfg.Chain)
+	if err != nil {
+		return err
+	}
+	if backend != nil {
+		d.oracle.SetChain(backend)
+	}
+
+	if len(cfg.Webhooks) != 0 {
+		d.hooks, err = newWebhooks(cfg.Webhooks)
+		if err != nil {
+			return err
+		}
+		d.hooks.SetLogger(d.logger)
+	}
+	d.backup, err = newBackupManager(cfg, d.store)
+	if err != nil {
+		return err
+	}
+	if d.backup != nil {
+		d.backup.SetLogger(d.logger)
+	}
+
+	// Liveness only needs the store and the key, readiness also the
+	// clock, the chain and every data source that can be pinged
+	keyCheck := health.Check{Name: "key", Run: func(context.Context) error {
+		return d.oracle.CheckKey()
+	}}
+	liveChecks := []health.Check{health.Store(d.store), keyCheck}
+	readyChecks := []health.Check{health.Store(d.store), keyCheck}
+	if len(cfg.Clock.NTPServers) != 0 {
+		readyChecks = append(readyChecks, health.Clock(d.oracle.Clock()))
+	}
+	if backend != nil {
+		readyChecks = append(readyChecks, health.Check{Name: "chain", Run: func(ctx context.Context) error {
+			_, err := backend.BlockHeight(ctx)
+			return err
+		}})
+	}
+
+	d.metrics = metrics.New()
+	d.oracle.SetSigningObserver(d.metrics)
+	d.ready = health.New(readyChecks...)
+	d.sources = datasource.NewRegistry()
+	if cfg.Scheduler.CaptureEvidence {
+		if _, ok := d.store.(storage.EvidenceStore); !ok {
+			return fmt.Errorf("store can't keep evidence")
+		}
+		d.evidence = evidence.NewRecorder()
+	}
+	err = d.setSources(cfg.Sources)
+	if err != nil {
+		return err
+	}
+	closeStore := d.close
+	d.close = func() error {
+		d.closeSources()
+		return closeStore()
+	}
+
+	d.sched = scheduler.New(d.oracle, d.sources)
+	d.sched.SetLogger(d.logger)
+	if cfg.Scheduler.RetryInterval != 0 {
+		d.sched.SetRetryInterval(cfg.Scheduler.RetryInterval)
+	}
+	if cfg.Scheduler.ChainPollInterval != 0 {
+		d.sched.SetChainPollInterval(cfg.Scheduler.ChainPollInterval)
+	}
+	if d.evidence != nil {
+		d.sched.SetEvidenceCollector(d.evidence)
+	}
+	if cfg.Scheduler.OutcomeScript != "" {
+		s, err := script.Load(cfg.Scheduler.OutcomeScript, d.sources)
+		if err != nil {
+			return err
+		}
+		d.sched.SetOutcomeHook(s.Outcome)
+	}
+	if cfg.Scheduler.OnDisagreement != "" {
+		var p scheduler.DisagreementPolicy
+		err := p.UnmarshalText([]byte(cfg.Scheduler.OnDisagreement))
+		if err != nil {
+			return err
+		}
+		d.sched.SetDisagreementPolicy("", p)
+	}
+	for _, tc := range cfg.Scheduler.Templates {
+		t, err := newTemplate(tc)
+		if err != nil {
+			return err
+		}
+		err = d.sched.AddTemplate(t)
+		if err != nil {
+			return err
+		}
+		if tc.OnDisagreement != "" {
+			var p scheduler.DisagreementPolicy
+			err := p.UnmarshalText([]byte(tc.OnDisagreement))
+			if err != nil {
+				return fmt.Errorf("template %s: %v", tc.Prefix, err)
+			}
+			d.sched.SetDisagreementPolicy(tc.Prefix, p)
+		}
+	}
+	if cfg.Alerts.OverdueAfter != 0 {
+		d.sched.SetOverdueAfter(cfg.Alerts.OverdueAfter)
+	}
+	n, err := newNotifier(cfg.Alerts, d.hooks)
+	if err != nil {
+		return err
+	}
+	if n != nil {
+		d.sched.SetNotifier(n)
+	}
+	d.metrics.WatchScheduler(d.sched)
+
+	d.tls, d.acme, err = newTLS(cfg.TLS)
+	if err != nil {
+		return err
+	}
+
+	rl := cfg.RateLimit
+	d.limiter = ratelimit.New(ratelimit.Config{
+		GlobalRate:        rl.GlobalRate,
+		GlobalBurst:       rl.GlobalBurst,
+		PerIPRate:         rl.PerIPRate,
+		PerIPBurst:        rl.PerIPBurst,
+		TrustForwardedFor: rl.TrustForwardedFor,
+	})
+
+	if cfg.REST.Listen != "" {
+		d.rest = server.NewServer(d.oracle.PubKey(), d.store)
+		d.rest.Use(d.limiter.Middleware)
+		if cfg.TLS.ClientCAFile != "" {
+			d.rest.Use(requireClientCert("/metrics"))
+		}
+		d.rest.Handle("GET /healthz", health.New(liveChecks...))
+		d.rest.Handle("GET /readyz", d.ready)
+		if cfg.REST.Metrics {
+			d.rest.Use(d.metrics.InstrumentHTTP)
+			d.rest.Handle("GET /metrics", d.metrics.Handler())
+		}
+		if cfg.Tracing.URL != "" {
+			d.rest.Use(tracing.Middleware)
+		}
+	}
+
+	if cfg.GRPC.Listen != "" {
+		srv := rpc.NewServer(d.oracle)
+		srv.SetAdminToken(cfg.GRPC.AdminToken)
+		srv.SetPrivateReads(cfg.GRPC.PrivateReads)
+		srv.SetSourceReloader(d.reloadSources)
+		srv.SetClientCertAuth(cfg.TLS.ClientCAFile != "")
+		if cfg.GRPC.AuthRootKey != "" {
+			rootKey, err := decodeKey("grpc.auth_root_key", cfg.GRPC.AuthRootKey)
+			if err != nil {
+				return err
+			}
+			srv.SetAuthority(auth.NewAuthority(rootKey))
+		}
+		if cfg.GRPC.APIKeysFile != "" {
+			keys, err := auth.OpenKeyring(cfg.GRPC.APIKeysFile)
+			if err != nil {
+				return err
+			}
+			srv.SetKeyring(keys)
+		}
+		unary := []grpc.UnaryServerInterceptor{d.limiter.UnaryInterceptor()}
+		stream := []grpc.StreamServerInterceptor{d.limiter.StreamInterceptor()}
+		if cfg.Tracing.URL != "" {
+			unary = append([]grpc.UnaryServerInterceptor{tracing.UnaryServerInterceptor()}, unary...)
+			stream = append([]grpc.StreamServerInterceptor{tracing.StreamServerInterceptor()}, stream...)
+		}
+		opts := []grpc.ServerOption{
+			grpc.ChainUnaryInterceptor(unary...),
+			grpc.ChainStreamInterceptor(stream...),
+		}
+		if d.tls != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(d.tls)))
+		}
+		d.grpc = grpc.NewServer(opts...)
+		rpc.RegisterOracleServer(d.grpc, srv)
+	}
+
+	if len(cfg.Nostr.Relays) != 0 {
+		key, err := decodeKey("nostr.key", cfg.Nostr.Key)
+		if err != nil {
+			return err
+		}
+		d.nostr = nostr.NewPublisher(key, cfg.Nostr.Relays)
+		d.nostr.SetLogger(d.logger)
+	}
+	return nil
+}
+
+// newSource returns the data source configured in sc and, for polled
+// price sources, the function polling it until its context is done
+func (d *daemon) newSource(sc SourceConfig, backend chain.Backend) (datasource.DataSource, func(ctx context.Context), error) {
+	switch sc.Type {
+	case "price":
+		exchange, err := price.ExchangeByName(sc.Exchange)
+		if err != nil {
+			return nil, nil, err
+		}
+		pair, err := price.ParsePair(sc.Pair)
+		if err != nil {
+			return nil, nil, err
+		}
+		s := price.NewSource(sc.ID, exchange, pair, sc.Precision)
+		if d.evidence != nil {
+			s.SetHTTPClient(d.evidence.Client(sc.ID, &http.Client{Timeout: 10 * time.Second}))
+		}
+		if sc.PollInterval == 0 {
+			return s, nil, nil
+		}
+		interval := sc.PollInterval
+		return s, func(ctx context.Context) {
+			s.Poll(ctx, interval, 2*interval)
+		}, nil
+	case "jsonapi":
+		ds, err := jsonapi.New(jsonapi.Config{
+			ID:        sc.ID,
+			URL:       sc.URL,
+			Headers:   sc.Headers,
+			Path:      sc.Path,
+			Precision: sc.Precision,
+			Outcomes:  sc.Outcomes,
+		})
+		if err == nil && d.evidence != nil {
+			ds.SetHTTPClient(d.evidence.Client(sc.ID, &http.Client{Timeout: 10 * time.Second}))
+		}
+		return ds, nil, err
+	case "plugin":
+		var env []string
+		for k, v := range sc.Env {
+			env = append(env, k+"="+v)
+		}
+		sort.Strings(env)
+		ds, err := plugin.New(plugin.Config{
+			ID:      sc.ID,
+			Command: sc.Command,
+			Env:     env,
+			Timeout: sc.Timeout,
+			Logger:  d.logger,
+		})
+		return ds, nil, err
+	case "block-hash", "fee-rate":
+		if backend == nil {
+			return nil, nil, fmt.Errorf("%s source needs a chain backend", sc.Type)
+		}
+		if sc.Type == "block-hash" {
+			return block.NewHashSource(sc.ID, backend), nil, nil
+		}
+		return block.NewFeeRateSource(sc.ID, backend, sc.Target, sc.Precision), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown source type %q", sc.Type)
+}
+
+// newWebhooks returns a notifier for the configured webhooks
+func newWebhooks(configs []WebhookConfig) (*webhook.Notifier, error) {
+	var endpoints []webhook.Endpoint
+	for _, wc := range configs {
+		if wc.URL == "" || wc.Secret == "" {
+			return nil, fmt.Errorf("webhooks need a url and a secret")
+		}
+		e := webhook.Endpoint{URL: wc.URL, Secret: []byte(wc.Secret)}
+		for _, t := range wc.Types {
+			switch webhook.Type(t) {
+			case webhook.TypeAnnouncement, webhook.TypeAttestation, webhook.TypeSourceFailure,
+				webhook.TypeAlert:
+				e.Types = append(e.Types, webhook.Type(t))
+			default:
+				return nil, fmt.Errorf("webhook %s: unknown type %q", wc.URL, t)
+			}
+		}
+		endpoints = append(endpoints, e)
+	}
+	return webhook.New(endpoints...), nil
+}
+
+// newNotifier returns the notifier for the configured alerts, which
+// include the webhooks if there are any, or nil if alerts go nowhere
+func newNotifier(cfg AlertsConfig, hooks *webhook.Notifier) (notify.Notifier, error) {
+	var notifiers []notify.Notifier
+	if hooks != nil {
+		notifiers = append(notifiers, hooks)
+	}
+	if cfg.SlackURL != "" {
+		notifiers = append(notifiers, &notify.Slack{URL: cfg.SlackURL})
+	}
+	if sc := cfg.SMTP; sc.Addr != "" {
+		host, _, err := net.SplitHostPort(sc.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("alerts smtp addr: %v", err)
+		}
+		if sc.From == "" || len(sc.To) == 0 {
+			return nil, fmt.Errorf("alerts smtp needs a from and a to address")
+		}
+		s := &notify.SMTP{Addr: sc.Addr, From: sc.From, To: sc.To}
+		if sc.Username != "" {
+			s.Auth = smtp.PlainAuth("", sc.Username, sc.Password, host)
+		}
+		notifiers = append(notifiers, s)
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return notify.Multi(notifiers...), nil
+}
+
+// newTemplate returns the recurring event configured in tc
+func newTemplate(tc TemplateConfig) (scheduler.Template, error) {
+	t := scheduler.Template{
+		Prefix: tc.Prefix,
+		Start:  tc.Start,
+		Period: tc.Period,
+		Ahead:  tc.Ahead,
+		Descriptor: dlcoracle.EventDescriptor{
+			Outcomes:     tc.Outcomes,
+			Base:         tc.Base,
+			Digits:       tc.Digits,
+			Unit:         tc.Unit,
+			UnitExponent: tc.UnitExponent,
+			Precision:    tc.Precision,
+		},
+		Metadata: dlcoracle.EventMetadata{
+			Description: tc.Description,
+			Category:    tc.Category,
+			Tags:        tc.Tags,
+		},
+	}
+	if tc.Type != "" {
+		err := t.Descriptor.Type.UnmarshalText([]byte(tc.Type))
+		if err != nil {
+			return t, fmt.Errorf("template %s: %v", tc.Prefix, err)
+		}
+	}
+	if tc.Rounding != "" {
+		err := t.Descriptor.Rounding.UnmarshalText([]byte(tc.Rounding))
+		if err != nil {
+			return t, fmt.Errorf("template %s: %v", tc.Prefix, err)
+		}
+	}
+	if tc.Cron != "" {
+		loc, err := time.LoadLocation(tc.Timezone)
+		if err != nil {
+			return t, fmt.Errorf("template %s: %v", tc.Prefix, err)
+		}
+		t.Recurrence, err = scheduler.ParseCron(tc.Cron, loc)
+		if err != nil {
+			return t, fmt.Errorf("template %s: %v", tc.Prefix, err)
+		}
+	} else if tc.Timezone != "" {
+		return t, fmt.Errorf("template %s has a timezone but no cron expression", tc.Prefix)
+	}
+	return t, nil
+}
+
+// Run serves the oracle until ctx is cancelled, then stops signing once
+// the signature in progress is stored, shuts the servers down gracefully
+// and closes the audit log and the store
+func (d *daemon) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// rest, grpc, the ACME challenge server, the onion service and the
+	// scheduler report at most one error each
+	errs := make(chan error, 5)
+	var wg sync.WaitGroup
+	goRun := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	var restSrv *http.Server
+	var onionPorts []tor.Port
+	if d.rest != nil {
+		ln, err := net.Listen("tcp", d.cfg.REST.Listen)
+		if err != nil {
+			return err
+		}
+		restSrv = &http.Server{Handler: d.rest, ReadHeaderTimeout: readHeaderTimeout, TLSConfig: d.tls}
+		goRun(func() {
+			var err error
+			if d.tls != nil {
+				err = restSrv.ServeTLS(ln, "", "")
+			} else {
+				err = restSrv.Serve(ln)
+			}
+			if err != http.ErrServerClosed {
+				errs <- fmt.Errorf("rest: %v", err)
+			}
+		})
+		d.logger.Log(dlcoracle.LevelInfo, "serving rest api", dlcoracle.F("addr", ln.Addr().String()))
+		virtual := 80
+		if d.tls != nil {
+			virtual = 443
+		}
+		onionPorts = append(onionPorts, onionPort(virtual, ln.Addr()))
+	}
+	if d.grpc != nil {
+		ln, err := net.Listen("tcp", d.cfg.GRPC.Listen)
+		if err != nil {
+			if restSrv != nil {
+				restSrv.Close()
+			}
+			return err
+		}
+		goRun(func() {
+			err := d.grpc.Serve(ln)
+			if err != nil {
+				errs <- fmt.Errorf("grpc: %v", err)
+			}
+		})
+		d.logger.Log(dlcoracle.LevelInfo, "serving grpc", dlcoracle.F("addr", ln.Addr().String()))
+		onionPorts = append(onionPorts, onionPort(ln.Addr().(*net.TCPAddr).Port, ln.Addr()))
+	}
+	var acmeSrv *http.Server
+	if d.acme != nil && d.cfg.TLS.ACME.HTTPListen != "" {
+		ln, err := net.Listen("tcp", d.cfg.TLS.ACME.HTTPListen)
+		if err != nil {
+			if restSrv != nil {
+				restSrv.Close()
+			}
+			if d.grpc != nil {
+				d.grpc.Stop()
+			}
+			return err
+		}
+		// Answers HTTP-01 challenges and redirects anything else to HTTPS
+		acmeSrv = &http.Server{Handler: d.acme.HTTPHandler(nil), ReadHeaderTimeout: readHeaderTimeout}
+		goRun(func() {
+			err := acmeSrv.Serve(ln)
+			if err != http.ErrServerClosed {
+				errs <- fmt.Errorf("acme: %v", err)
+			}
+		})
+	}
+
+	if d.cfg.Tor.Control != "" {
+		goRun(func() {
+			err := d.serveOnion(ctx, onionPorts)
+			if err != nil {
+				errs <- fmt.Errorf("onion service: %v", err)
+			}
+		})
+	}
+
+	goRun(func() {
+		err := d.sched.Run(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			errs <- fmt.Errorf("scheduler: %v", err)
+		}
+	})
+	goRun(func() { d.metrics.WatchOracle(ctx, d.oracle) })
+	if d.nostr != nil {
+		goRun(func() { d.nostr.Run(ctx, d.oracle) })
+	}
+	if d.hooks != nil {
+		goRun(func() { d.hooks.Run(ctx, d.oracle) })
+	}
+	if d.backup != nil && d.cfg.Backup.Interval > 0 {
+		goRun(func() { d.backup.Run(ctx, d.cfg.Backup.Interval) })
+	}
+	if d.inbox != nil {
+		goRun(func() { d.inbox.Run(ctx, inboxInterval) })
+	}
+	d.runSources(ctx)
+
+	// SIGHUP reloads the data sources
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var err error
+	for err == nil && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case err = <-errs:
+		case <-hup:
+			_, reloadErr := d.reloadSources(ctx)
+			if reloadErr != nil {
+				d.logger.Log(dlcoracle.LevelError, "reloading data sources failed, keeping them",
+					dlcoracle.F("error", reloadErr.Error()))
+			}
+		}
+	}
+	d.logger.Log(dlcoracle.LevelInfo, "shutting down")
+	cancel()
+	// Requests still being served fail rather than sign after this
+	d.oracle.Stop()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if restSrv != nil {
+		restSrv.Shutdown(shutdownCtx)
+	}
+	if acmeSrv != nil {
+		acmeSrv.Shutdown(shutdownCtx)
+	}
+	if d.grpc != nil {
+		// GracefulStop waits for update streams, which only end when
+		// their clients go away
+		stopped := make(chan struct{})
+		go func() {
+			d.grpc.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			d.grpc.Stop()
+		}
+	}
+	wg.Wait()
+	d.sourceSet.wg.Wait()
+
+	closeErr := d.close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,317 @@
+// Command oracled runs an oracle: it wires the store, data sources,
+// scheduler and servers configured in a YAML file, and serves until it
+// receives SIGINT or SIGTERM.
+//
+// Usage:
+//
+//	oracled [-config FILE] [-set PATH=VALUE]... [run]
+//	oracled [-config FILE] [-set PATH=VALUE]... config check
+//	oracled [-config FILE] migrate
+//	oracled [-config FILE] import-key [HEXFILE]
+//	oracled [-config FILE] recover [-first N] [-last N] [-published FILE] [-repair]
+//	oracled [-config FILE] export [FILE]
+//	oracled [-config FILE] import FILE
+//	oracled [-config FILE] backup
+//	oracled [-config FILE] restore [-list] [-force] [KEY]
+//
+// Every setting can be overridden with an environment variable named
+// after its path in the config file, such as ORACLED_STORE_DSN for
+// store.dsn, and with -set, as in -set store.dsn=oracle.db, which takes
+// precedence. config check reports every problem with the configuration.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/config"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func main() {
+	configPath := flag.String("config", "", "config file")
+	var set config.Flags
+	flag.Var(&set, "set", "override a setting, as `path=value`; repeatable")
+	flag.Parse()
+
+	if flag.Arg(0) == "config" {
+		if flag.Arg(1) != "check" {
+			fatal(fmt.Errorf("unknown config command %q, expected check", flag.Arg(1)))
+		}
+		err := checkConfig(*configPath, set, os.Stdout)
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	cfg, err := LoadConfig(*configPath, set...)
+	if err != nil {
+		fatal(err)
+	}
+	cmd := flag.Arg(0)
+	switch cmd {
+	case "", "run":
+		err = runDaemon(cfg, *configPath, set)
+	case "migrate":
+		err = migrate(cfg, os.Stdout)
+	case "import-key":
+		err = importKey(cfg, flag.Arg(1))
+	case "recover":
+		var priv [32]byte
+		priv, err = loadKey(cfg.Key)
+		if err == nil {
+			err = recoverState(cfg, priv, flag.Args()[1:], os.Stdout)
+		}
+	case "export":
+		err = exportState(cfg, flag.Arg(1), os.Stdout)
+	case "import":
+		var priv [32]byte
+		priv, err = loadKey(cfg.Key)
+		if err == nil {
+			err = importState(cfg, priv, flag.Arg(1), os.Stdout)
+		}
+	case "backup":
+		err = backupNow(cfg, os.Stdout)
+	case "restore":
+		var priv [32]byte
+		priv, err = loadKey(cfg.Key)
+		if err == nil {
+			err = restoreBackup(cfg, priv, flag.Args()[1:], os.Stdout)
+		}
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "oracled: %v\n", err)
+	os.Exit(1)
+}
+
+// runDaemon runs the daemon configured in cfg, which was loaded from
+// configPath and set, until SIGINT or SIGTERM
+func runDaemon(cfg Config, configPath string, set []string) error {
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		return err
+	}
+	var priv [32]byte
+	if cfg.Offline.PubKey == "" {
+		priv, err = loadKey(cfg.Key)
+		if err != nil {
+			return err
+		}
+	}
+	for _, w := range cfg.Warnings() {
+		logger.Log(dlcoracle.LevelWarn, "check the configuration", dlcoracle.F("warning", w))
+	}
+	d, err := newDaemon(cfg, priv, logger)
+	if err != nil {
+		return err
+	}
+	d.loadConfig = func() (Config, error) {
+		return LoadConfig(configPath, set...)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	logger.Log(dlcoracle.LevelInfo, "oracle started",
+		dlcoracle.F("pubkey", fmt.Sprintf("%x", d.oracle.PubKey())))
+	return d.Run(ctx)
+}
+
+// migrate upgrades the store to the current schema version. Opening a
+// store migrates it, so this only opens and closes it again.
+func migrate(cfg Config, out io.Writer) error {
+	if cfg.Store.Driver == "memory" {
+		return fmt.Errorf("the memory store has nothing to migrate")
+	}
+	_, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s store is at the current schema version\n", cfg.Store.Driver)
+	return closeStore()
+}
+
+// importKey reads a hex encoded private key from filename, or stdin if it
+// is empty, and saves it to the configured key file, encrypted with the
+// configured passphrase or one prompted for
+func importKey(cfg Config, filename string) error {
+	_, err := os.Stat(cfg.Key.File)
+	if err == nil {
+		return fmt.Errorf("%s already exists", cfg.Key.File)
+	}
+	var in io.Reader = os.Stdin
+	if filename != "" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	var priv [32]byte
+	b, err := hex.DecodeString(strings.TrimSpace(line))
+	if err != nil || len(b) != len(priv) {
+		return fmt.Errorf("expected a hex encoded 32 byte private key")
+	}
+	copy(priv[:], b)
+
+	if cfg.Key.Passphrase != "" {
+		err = dlcoracle.SaveKeyToFileArg(cfg.Key.File, &priv, []byte(cfg.Key.Passphrase))
+	} else {
+		err = dlcoracle.SaveKeyToFileInteractive(cfg.Key.File, &priv)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported key with public key %x\n", dlcoracle.PublicKeyFromPrivateKey(priv))
+	return nil
+}
+
+// recoverState re-derives the one-time signing keys of the oracle and
+// reconciles them with the announcements in the store and, with
+// -published, a JSON list of announcements such as the one served at
+// /api/announcements. With -repair recovered keys and announcements are
+// written to the store.
+func recoverState(cfg Config, priv [32]byte, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("recover", flag.ContinueOnError)
+	first := fs.Uint64("first", 0, "first one-time signing key index")
+	last := fs.Uint64("last", 0, "last one-time signing key index, found by a gap of 100 unused indices if 0")
+	published := fs.String("published", "", "JSON file with the announcements the oracle published")
+	repair := fs.Bool("repair", false, "write recovered keys and announcements to the store")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	opts := oracle.RecoverOptions{First: *first, Last: *last, Repair: *repair}
+	if *published != "" {
+		b, err := os.ReadFile(*published)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(b, &opts.Published)
+		if err != nil {
+			return fmt.Errorf("%s: %v", *published, err)
+		}
+	}
+
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	report, err := oracle.New(priv, store).Recover(opts)
+	if err != nil {
+		return err
+	}
+	printRecovery(out, report)
+	return nil
+}
+
+// printRecovery writes a recovery report for humans
+func printRecovery(out io.Writer, report oracle.RecoveryReport) {
+	for _, k := range report.Keys {
+		fmt.Fprintf(out, "index %d: %s\n", k.Index, k.EventID)
+	}
+	for _, i := range report.Gaps {
+		fmt.Fprintf(out, "index %d: unused\n", i)
+	}
+	for _, id := range report.Unmatched {
+		fmt.Fprintf(out, "event %s: key not found, can't be attested\n", id)
+	}
+	for _, id := range report.Mismatched {
+		fmt.Fprintf(out, "event %s: stored key or ledger doesn't match the announcement\n", id)
+	}
+	for _, id := range report.Reused {
+		fmt.Fprintf(out, "event %s: R point announced for another event too\n", id)
+	}
+	for _, id := range report.Restored {
+		fmt.Fprintf(out, "event %s: restored\n", id)
+	}
+	fmt.Fprintf(out, "next index %d\n", report.NextIndex)
+}
+
+// exportState writes the state of the oracle in the store as a
+// storage.Snapshot to filename, or out if it is empty or "-"
+func exportState(cfg Config, filename string, out io.Writer) error {
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	snap, err := storage.Export(store)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if filename == "" || filename == "-" {
+		_, err = out.Write(b)
+		return err
+	}
+	return os.WriteFile(filename, b, 0600)
+}
+
+// importState imports a snapshot written by exportState into the store,
+// then derives the one-time signing keys of the imported events from the
+// oracle's private key
+func importState(cfg Config, priv [32]byte, filename string, out io.Writer) error {
+	if filename == "" {
+		return fmt.Errorf("import needs a snapshot file")
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var snap storage.Snapshot
+	err = json.Unmarshal(b, &snap)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	return importSnapshot(store, priv, snap, out)
+}
+
+// importSnapshot imports snap into store and derives the one-time
+// signing keys of its events from priv
+func importSnapshot(store storage.Store, priv [32]byte, snap storage.Snapshot, out io.Writer) error {
+	err := storage.Import(store, snap)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "imported %d announcements, %d attestations and %d revocations\n",
+		len(snap.Announcements), len(snap.Attestations), len(snap.Revocations))
+	report, err := oracle.New(priv, store).Recover(oracle.RecoverOptions{Repair: true})
+	if err != nil {
+		return err
+	}
+	printRecovery(out, report)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/offline"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// inboxInterval is how often the inbox is scanned for bundles
+const inboxInterval = 10 * time.Second
+
+// newOfflineOracle returns an oracle whose private key is kept offline as
+// configured in cfg, and the inbox its bundles are imported from
+func newOfflineOracle(cfg OfflineConfig, store storage.Store) (*oracle.Oracle, *offline.Inbox, error) {
+	var pub [33]byte
+	b, err := hex.DecodeString(cfg.PubKey)
+	if err != nil || len(b) != len(pub) {
+		return nil, nil, fmt.Errorf("offline.pub_key must be 33 hex encoded bytes")
+	}
+	copy(pub[:], b)
+	if cfg.Requests == "" || cfg.Inbox == "" {
+		return nil, nil, fmt.Errorf("offline needs a requests file and an inbox directory")
+	}
+	q, err := offline.OpenQueue(cfg.Requests)
+	if err != nil {
+		return nil, nil, err
+	}
+	o := oracle.NewOffline(pub, store, q)
+	return o, offline.NewInbox(cfg.Inbox, o, q), nil
+}
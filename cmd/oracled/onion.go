@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/tor"
+)
+
+// serveOnion publishes ports as an onion service until ctx is cancelled.
+// Tor removes the service when the control connection closes.
+func (d *daemon) serveOnion(ctx context.Context, ports []tor.Port) error {
+	cfg := d.cfg.Tor
+	key := tor.NewKey
+	b, err := os.ReadFile(cfg.KeyFile)
+	if err == nil {
+		key = strings.TrimSpace(string(b))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	ctrl, err := tor.DialController(ctx, cfg.Control)
+	if err != nil {
+		return err
+	}
+	defer ctrl.Close()
+	err = ctrl.Authenticate(cfg.Password)
+	if err != nil {
+		return err
+	}
+	onion, err := ctrl.AddOnion(key, ports...)
+	if err != nil {
+		return err
+	}
+	if onion.PrivateKey != "" {
+		err = os.WriteFile(cfg.KeyFile, []byte(onion.PrivateKey+"\n"), 0600)
+		if err != nil {
+			return fmt.Errorf("saving onion key: %v", err)
+		}
+	}
+	d.logger.Log(dlcoracle.LevelInfo, "serving onion service", dlcoracle.F("addr", onion.Address()))
+	<-ctx.Done()
+	return nil
+}
+
+// onionPort returns the port forwarding virtual to the listener at addr,
+// reaching listeners on all interfaces over the loopback interface
+func onionPort(virtual int, addr net.Addr) tor.Port {
+	target := addr.String()
+	if tcp, ok := addr.(*net.TCPAddr); ok && tcp.IP.IsUnspecified() {
+		target = fmt.Sprintf("127.0.0.1:%d", tcp.Port)
+	}
+	return tor.Port{Virtual: virtual, Target: target}
+}
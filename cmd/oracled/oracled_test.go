@@ -0,0 +1,1049 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/datasource/plugin"
+	"github.com/mit-dci/dlc-oracle-go/health"
+	"github.com/mit-dci/dlc-oracle-go/offline"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "oracled.yaml")
+	err := os.WriteFile(path, []byte(yaml), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+store:
+  driver: memory
+grpc:
+  listen: 127.0.0.1:9090
+clock:
+  max_drift: 1s
+sources:
+  - id: btcusd
+    type: price
+    exchange: kraken
+    pair: BTC/USD
+    prefixes: [btcusd-]
+`)
+	t.Setenv("ORACLED_GRPC_ADMIN_TOKEN", "secret")
+	t.Setenv("ORACLED_CLOCK_NTP_SERVERS", "pool.ntp.org, time.google.com")
+	t.Setenv("ORACLED_RATE_LIMIT_PER_IP_RATE", "2.5")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Store.Driver != "memory" || cfg.GRPC.Listen != "127.0.0.1:9090" {
+		t.Fatalf("config file not applied: %+v", cfg)
+	}
+	if cfg.REST.Listen != ":8080" || cfg.Log.Level != "info" {
+		t.Fatalf("defaults not kept: %+v", cfg)
+	}
+	if cfg.Clock.MaxDrift != time.Second {
+		t.Fatalf("max drift %v", cfg.Clock.MaxDrift)
+	}
+	if cfg.GRPC.AdminToken != "secret" || cfg.RateLimit.PerIPRate != 2.5 {
+		t.Fatalf("environment not applied: %+v", cfg)
+	}
+	if len(cfg.Clock.NTPServers) != 2 || cfg.Clock.NTPServers[1] != "time.google.com" {
+		t.Fatalf("ntp servers %q", cfg.Clock.NTPServers)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0].Prefixes[0] != "btcusd-" {
+		t.Fatalf("sources %+v", cfg.Sources)
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg, err := LoadConfig("oracled.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Scheduler.Templates) != 2 {
+		t.Fatalf("templates %+v", cfg.Scheduler.Templates)
+	}
+	for _, tc := range cfg.Scheduler.Templates {
+		tmpl, err := newTemplate(tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = tmpl.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl, err := newTemplate(cfg.Scheduler.Templates[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tmpl.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Period != 24*time.Hour || tmpl.Descriptor.Digits != 6 || tmpl.Descriptor.Rounding != dlcoracle.RoundHalfEven || !tmpl.Start.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected template %+v", tmpl)
+	}
+	if cfg.Scheduler.OnDisagreement != "retry" || cfg.Scheduler.Templates[0].OnDisagreement != "abort" ||
+		!cfg.Scheduler.CaptureEvidence {
+		t.Fatalf("unexpected disagreement policies %+v", cfg.Scheduler)
+	}
+	median := cfg.Sources[3]
+	if median.Type != "median" || len(median.Sources) != 3 || median.Quorum != 2 || median.MaxDeviation != 0.01 {
+		t.Fatalf("unexpected median source %+v", median)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, "store:\n  drivr: memory\n"))
+	if err == nil {
+		t.Fatal("misspelt key accepted")
+	}
+	t.Setenv("ORACLED_REST_METRICS", "maybe")
+	_, err = LoadConfig("")
+	if err == nil || !strings.Contains(err.Error(), "ORACLED_REST_METRICS") {
+		t.Fatalf("expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, fmt.Sprintf(`
+store:
+  driver: bolt
+  path: %[1]s/oracle.db
+audit:
+  path: %[1]s/oracle.db
+backup:
+  interval: 1d
+log:
+  level: loud
+`, dir))
+	var out bytes.Buffer
+	err := checkConfig(path, nil, &out)
+	if err == nil {
+		t.Fatal("invalid configuration passed")
+	}
+	if !strings.Contains(out.String(), "backup.interval: invalid duration") {
+		t.Fatalf("duration not reported:\n%s", out.String())
+	}
+
+	// Once the file is valid, its settings are validated
+	out.Reset()
+	path = writeConfig(t, fmt.Sprintf("store:\n  path: %[1]s/oracle.db\naudit:\n  path: %[1]s/oracle.db\n", dir))
+	err = checkConfig(path, []string{"log.level=loud", "tracing.url=collector:4318", "tracing.sample_ratio=2"}, &out)
+	if err == nil || !strings.Contains(out.String(), "audit.path: "+dir+"/oracle.db is also store.path") ||
+		!strings.Contains(out.String(), `log.level: unknown level "loud"`) ||
+		!strings.Contains(out.String(), "tracing.url: expected an http or https URL") ||
+		!strings.Contains(out.String(), "tracing.sample_ratio: 2 is not between 0 and 1") {
+		t.Fatalf("problems not reported: %v\n%s", err, out.String())
+	}
+
+	// A missing store next to an existing audit log is a warning
+	out.Reset()
+	os.WriteFile(filepath.Join(dir, "audit.log"), nil, 0600)
+	err = checkConfig(path, []string{"audit.path=" + dir + "/audit.log"}, &out)
+	if err != nil || !strings.Contains(out.String(), "reusing those of the events announced before") ||
+		!strings.Contains(out.String(), "configuration is valid") {
+		t.Fatalf("warning not reported: %v\n%s", err, out.String())
+	}
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func TestDaemon(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = freeAddr(t)
+	cfg.REST.Metrics = true
+	cfg.GRPC.Listen = freeAddr(t)
+	cfg.Audit.Path = filepath.Join(t.TempDir(), "audit.log")
+
+	var priv [32]byte
+	priv[31] = 1
+	d, err := newDaemon(cfg, priv, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		resp, err = http.Get("http://" + cfg.REST.Listen + "/api/pubkey")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct{ PubKey string }
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if body.PubKey != fmt.Sprintf("%x", dlcoracle.PublicKeyFromPrivateKey(priv)) {
+		t.Fatalf("pubkey %q", body.PubKey)
+	}
+	resp, err = http.Get("http://" + cfg.REST.Listen + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("metrics: HTTP %d", resp.StatusCode)
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err = http.Get("http://" + cfg.REST.Listen + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report health.Report
+		json.NewDecoder(resp.Body).Decode(&report)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || report.Status != health.StatusOK || len(report.Checks) != 2 {
+			t.Fatalf("%s: HTTP %d, %+v", path, resp.StatusCode, report)
+		}
+	}
+	_, err = d.oracle.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon didn't shut down")
+	}
+	f, err := os.Open(cfg.Audit.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n, last, err := audit.Verify(f)
+	if err != nil || n != 1 || last.EventID != "event" {
+		t.Fatalf("unexpected audit log: %d entries, last %+v, %v", n, last, err)
+	}
+}
+
+// issueCert writes a certificate for name signed by parent, or a CA
+// certificate if parent is nil, and returns it with its key
+func issueCert(t *testing.T, dir, name string, parent *tls.Certificate) (tls.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestDaemonTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caFile, _ := issueCert(t, dir, "ca", nil)
+	_, certFile, keyFile := issueCert(t, dir, "server", &ca)
+	clientCert, _, _ := issueCert(t, dir, "client", &ca)
+
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = freeAddr(t)
+	cfg.REST.Metrics = true
+	cfg.TLS = TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}
+	d, err := newDaemon(cfg, [32]byte{31: 1}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	anon := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	admin := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	base := "https://" + cfg.REST.Listen
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		resp, err = anon.Get(base + "/api/pubkey")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("pubkey: HTTP %d", resp.StatusCode)
+	}
+
+	for _, c := range []struct {
+		client *http.Client
+		status int
+	}{{anon, http.StatusForbidden}, {admin, http.StatusOK}} {
+		resp, err = c.client.Get(base + "/metrics")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("metrics: HTTP %d, expected %d", resp.StatusCode, c.status)
+		}
+	}
+
+	resp, err = http.Get("http://" + cfg.REST.Listen + "/api/pubkey")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal("plain HTTP request succeeded")
+		}
+	}
+}
+
+func TestDaemonOnion(t *testing.T) {
+	// A control port accepting any command and creating a new service
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	added := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PROTOCOLINFO"):
+				fmt.Fprintf(conn, "250-AUTH METHODS=NULL\r\n250 OK\r\n")
+			case strings.HasPrefix(line, "ADD_ONION"):
+				added <- strings.TrimSpace(line)
+				fmt.Fprintf(conn, "250-ServiceID=exampleonion\r\n250-PrivateKey=ED25519-V3:a2V5\r\n250 OK\r\n")
+			default:
+				fmt.Fprintf(conn, "250 OK\r\n")
+			}
+		}
+	}()
+
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = "127.0.0.1:0"
+	cfg.Tor = TorConfig{Control: ln.Addr().String(), KeyFile: filepath.Join(t.TempDir(), "onion.key")}
+	d, err := newDaemon(cfg, [32]byte{31: 1}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	select {
+	case cmd := <-added:
+		if !strings.HasPrefix(cmd, "ADD_ONION NEW:ED25519-V3 Port=80,127.0.0.1:") {
+			t.Fatalf("unexpected command %q", cmd)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no onion service added")
+	}
+	var key []byte
+	for i := 0; i < 50 && len(key) == 0; i++ {
+		key, _ = os.ReadFile(cfg.Tor.KeyFile)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if string(key) != "ED25519-V3:a2V5\n" {
+		t.Fatalf("onion key %q", key)
+	}
+	cancel()
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDaemonConfigErrors(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.Sources = []SourceConfig{{ID: "hash", Type: "block-hash"}}
+	_, err := newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("block source accepted without a chain backend")
+	}
+
+	cfg.Sources = nil
+	cfg.Scheduler.Templates = []TemplateConfig{{Prefix: "x-", Period: time.Hour, Ahead: 1}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("template without a start accepted")
+	}
+
+	cfg.Scheduler.Templates = []TemplateConfig{{Prefix: "x-", Cron: "@daily", Timezone: "Mars/Olympus_Mons", Ahead: 1}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("unknown timezone accepted")
+	}
+
+	cfg.Scheduler.Templates = nil
+	cfg.Scheduler.OutcomeScript = writeConfig(t, "def outcome(event, value)\n")
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("invalid outcome script accepted")
+	}
+
+	cfg.Scheduler.OutcomeScript = ""
+	cfg.Scheduler.OnDisagreement = "ignore"
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("unknown disagreement policy accepted")
+	}
+
+	cfg.Scheduler.OnDisagreement = ""
+	cfg.Webhooks = []WebhookConfig{{URL: "https://example.com/hook"}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("webhook without a secret accepted")
+	}
+	cfg.Webhooks = []WebhookConfig{{URL: "https://example.com/hook", Secret: "s", Types: []string{"event.deleted"}}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("unknown webhook type accepted")
+	}
+
+	cfg.Webhooks = nil
+	cfg.Alerts.SMTP = SMTPConfig{Addr: "smtp.example.com:587", From: "oracle@example.com"}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("smtp alerts without recipients accepted")
+	}
+	cfg.Alerts.SMTP = SMTPConfig{Addr: "smtp.example.com", From: "oracle@example.com", To: []string{"ops@example.com"}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("smtp address without a port accepted")
+	}
+
+	cfg.Alerts = AlertsConfig{}
+	cfg.TLS = TLSConfig{ClientCAFile: "ca.crt"}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("client CA accepted without TLS")
+	}
+	cfg.TLS = TLSConfig{CertFile: "server.crt", KeyFile: "server.key", ACME: ACMEConfig{Domains: []string{"oracle.example.com"}}}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("certificate files and ACME accepted together")
+	}
+	cfg.TLS = TLSConfig{CertFile: "server.crt"}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("certificate without a key accepted")
+	}
+
+	cfg.TLS = TLSConfig{}
+	cfg.Offline = OfflineConfig{PubKey: "02ab", Requests: "requests.json", Inbox: "inbox"}
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("short offline public key accepted")
+	}
+
+	cfg.Offline = OfflineConfig{}
+	cfg.Store.Driver = "floppy"
+	_, err = newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err == nil {
+		t.Fatal("unknown store driver accepted")
+	}
+}
+
+func TestOfflineDaemon(t *testing.T) {
+	dir := t.TempDir()
+	priv := [32]byte{31: 9}
+	signer, err := offline.NewSigner(priv, filepath.Join(dir, "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := signer.Prepare([]dlcoracle.Event{{ID: "event", Maturity: time.Unix(1000, 0)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox := filepath.Join(dir, "inbox")
+	os.Mkdir(inbox, 0700)
+	offline.WriteFile(filepath.Join(inbox, "announcements.json"), &b)
+
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	cfg.Offline = OfflineConfig{
+		PubKey:   hex.EncodeToString(pub[:]),
+		Requests: filepath.Join(dir, "requests.json"),
+		Inbox:    inbox,
+	}
+	d, err := newDaemon(cfg, [32]byte{}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+	if d.oracle.PubKey() != pub {
+		t.Fatalf("unexpected public key %x", d.oracle.PubKey())
+	}
+	err = d.inbox.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.store.Announcement("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.oracle.Attest("event", dlcoracle.GenerateNumericMessage(5))
+	if !errors.Is(err, oracle.ErrAttestationPending) {
+		t.Fatalf("attested without the key: %v", err)
+	}
+	var reqs offline.Requests
+	err = offline.ReadFile(cfg.Offline.Requests, &reqs)
+	if err != nil || len(reqs.Requests) != 1 {
+		t.Fatalf("requests %+v %v", reqs, err)
+	}
+}
+
+func TestStoreEncryption(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Store.Path = filepath.Join(t.TempDir(), "oracle.db")
+	cfg.Store.Encryption.Passphrase = "hunter2"
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutNonce("event", [32]byte{1})
+	closeStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Store.Encryption.Passphrase = ""
+	store, closeStore, err = openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Nonce("event")
+	closeStore()
+	if !errors.Is(err, storage.ErrEncrypted) {
+		t.Fatalf("read without passphrase: %v", err)
+	}
+
+	cfg.Store.Encryption.Passphrase = "wrong"
+	_, _, err = openStore(cfg.Store)
+	if err == nil {
+		t.Fatal("wrong passphrase accepted")
+	}
+
+	cfg.Store = StoreConfig{Driver: "memory"}
+	cfg.Store.Encryption.Passphrase = "hunter2"
+	_, _, err = openStore(cfg.Store)
+	if err == nil {
+		t.Fatal("encrypted memory store accepted")
+	}
+	cfg.Store.Encryption.Vault.Addr = "http://127.0.0.1:8200"
+	_, err = keyWrapper(cfg.Store.Encryption)
+	if err == nil {
+		t.Fatal("passphrase and vault accepted together")
+	}
+}
+
+func TestMigrateAndImportKey(t *testing.T) {
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.Store.Path = filepath.Join(dir, "oracle.db")
+	var out bytes.Buffer
+	err := migrate(cfg, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(cfg.Store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Key.File = filepath.Join(dir, "oracle.key")
+	cfg.Key.Passphrase = "hunter2"
+	hexFile := filepath.Join(dir, "key.hex")
+	var priv [32]byte
+	priv[31] = 7
+	os.WriteFile(hexFile, []byte(fmt.Sprintf("%x\n", priv)), 0600)
+	err = importKey(cfg, hexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadKey(cfg.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != priv {
+		t.Fatal("imported key doesn't match")
+	}
+	err = importKey(cfg, hexFile)
+	if err == nil {
+		t.Fatal("import overwrote the key file")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 7
+	o := oracle.New(priv, storage.NewMemoryStore())
+	for _, id := range []string{"a", "b"} {
+		_, err := o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, _ := o.Store().Announcements()
+	b, _ := json.Marshal(list)
+	dir := t.TempDir()
+	published := filepath.Join(dir, "announcements.json")
+	os.WriteFile(published, b, 0600)
+
+	cfg := defaultConfig()
+	cfg.Store.Path = filepath.Join(dir, "oracle.db")
+	var out bytes.Buffer
+	err := recoverState(cfg, priv, []string{"-published", published, "-repair"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "index 0: a\nindex 1: b\nevent a: restored\nevent b: restored\nnext index 2\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	// The store is complete now
+	out.Reset()
+	err = recoverState(cfg, priv, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "index 1: b\nnext index 2\n") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 7
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.Store.Path = filepath.Join(dir, "source.db")
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := oracle.New(priv, store)
+	for _, id := range []string{"a", "b"} {
+		_, err = o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = o.Attest("a", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	closeStore()
+
+	snapshot := filepath.Join(dir, "snapshot.json")
+	err = exportState(cfg, snapshot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(snapshot)
+	if bytes.Contains(b, []byte(fmt.Sprintf("%x", priv))) {
+		t.Fatal("snapshot contains the private key")
+	}
+
+	cfg.Store.Path = filepath.Join(dir, "target.db")
+	var out bytes.Buffer
+	err = importState(cfg, priv, snapshot, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "imported 2 announcements, 1 attestations") ||
+		!strings.Contains(out.String(), "event b: restored") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	// The events can be attested on the new store
+	store, closeStore, err = openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore()
+	_, err = oracle.New(priv, store).Attest("b", dlcoracle.GenerateNumericMessage(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// memoryS3 serves a path style bucket named "backups" from memory
+func memoryS3(t *testing.T) string {
+	var mtx sync.Mutex
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		key := strings.TrimPrefix(r.URL.Path, "/backups/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/backups":
+			fmt.Fprint(w, "<ListBucketResult>")
+			for k, v := range objects {
+				if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+					fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", k, len(v))
+				}
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+		case r.Method == http.MethodPut:
+			objects[key], _ = io.ReadAll(r.Body)
+		case r.Method == http.MethodGet:
+			w.Write(objects[key])
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestBackupRestore(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 7
+	dir := t.TempDir()
+	cfg := defaultConfig()
+	cfg.Store.Path = filepath.Join(dir, "source.db")
+	cfg.Audit.Path = filepath.Join(dir, "audit.log")
+	cfg.Backup.S3 = S3Config{Endpoint: memoryS3(t), Bucket: "backups", PathStyle: true}
+	var out bytes.Buffer
+	err := backupNow(cfg, &out)
+	if err == nil || !strings.Contains(err.Error(), "encryption") {
+		t.Fatalf("unencrypted backup: %v", err)
+	}
+	cfg.Backup.Encryption.Passphrase = "hunter2"
+
+	store, closeStore, err := openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := oracle.New(priv, store)
+	l, err := audit.Open(cfg.Audit.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetAuditLog(l)
+	for _, id := range []string{"a", "b"} {
+		_, err = o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = o.Attest("a", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	closeStore()
+
+	err = backupNow(cfg, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "uploaded oracled/oracle-") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	// restore onto a new machine
+	cfg.Store.Path = filepath.Join(dir, "target.db")
+	out.Reset()
+	err = restoreBackup(cfg, priv, nil, &out)
+	if err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Fatalf("existing audit log replaced: %v", err)
+	}
+	os.Remove(cfg.Audit.Path)
+	err = restoreBackup(cfg, priv, nil, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "imported 2 announcements, 1 attestations") ||
+		!strings.Contains(out.String(), "restored audit log") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	l, err = audit.Open(cfg.Audit.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	store, closeStore, err = openStore(cfg.Store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore()
+	_, err = oracle.New(priv, store).Attest("b", dlcoracle.GenerateNumericMessage(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadSources(t *testing.T) {
+	source := func(id, url string) SourceConfig {
+		return SourceConfig{ID: id, Type: "jsonapi", URL: url, Path: "$.outcome", Prefixes: []string{id + "-"}}
+	}
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = ""
+	cfg.Sources = []SourceConfig{source("rain", "https://rain.example.com"), source("score", "https://score.example.com")}
+	d, err := newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+	rain, _ := d.sources.Get("rain")
+	score, _ := d.sources.Get("score")
+
+	_, err = d.reloadSources(context.Background())
+	if err == nil {
+		t.Fatal("reloaded without a configuration")
+	}
+
+	// An unchanged source is kept, a changed one rebuilt and a removed
+	// one unregistered
+	next := cfg
+	next.Sources = []SourceConfig{source("rain", "https://rain.example.com"), source("temp", "https://temp.example.com")}
+	next.Sources[0].Prefixes = append(next.Sources[0].Prefixes, "weather-")
+	d.loadConfig = func() (Config, error) { return next, nil }
+	ids, err := d.reloadSources(context.Background())
+	if err != nil || len(ids) != 2 || ids[0] != "rain" || ids[1] != "temp" {
+		t.Fatalf("reloaded %v %v", ids, err)
+	}
+	if ds, _ := d.sources.Get("rain"); ds == rain {
+		t.Fatal("changed source kept")
+	}
+	ds, err := d.sources.SourceFor(dlcoracle.Event{ID: "weather-1"})
+	if err != nil || ds.ID() != "rain" {
+		t.Fatalf("routed to %v %v", ds, err)
+	}
+	if _, err := d.sources.SourceFor(dlcoracle.Event{ID: "score-1"}); err == nil {
+		t.Fatal("removed source still routed")
+	}
+	rain, _ = d.sources.Get("rain")
+	next.Sources = append(next.Sources, source("score", "https://score.example.com"))
+	d.reloadSources(context.Background())
+	if ds, _ := d.sources.Get("rain"); ds != rain {
+		t.Fatal("unchanged source rebuilt")
+	}
+	if ds, _ := d.sources.Get("score"); ds == nil || ds == score {
+		t.Fatal("source added back wasn't rebuilt")
+	}
+
+	// A configuration that doesn't build changes nothing
+	next.Sources = []SourceConfig{{ID: "hash", Type: "block-hash"}}
+	_, err = d.reloadSources(context.Background())
+	if err == nil || len(d.sources.List()) != 3 {
+		t.Fatalf("invalid sources applied: %v %v", err, d.sources.List())
+	}
+}
+
+func TestPluginSource(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = ""
+	cfg.Sources = []SourceConfig{{ID: "match", Type: "plugin", Prefixes: []string{"match-"},
+		Command: []string{"sh", "-c", `echo '{"protocol":1,"description":"'$DESC'"}'; cat >/dev/null`},
+		Env:     map[string]string{"DESC": "matches"}}}
+	d, err := newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+	ds, _ := d.sources.Get("match")
+	if ds == nil || ds.Describe() != "matches" {
+		t.Fatalf("plugin source %v", ds)
+	}
+	p := d.sourceSet.running["match"].closer.(*plugin.Source)
+
+	// Removing the source stops the plugin
+	next := cfg
+	next.Sources = nil
+	d.loadConfig = func() (Config, error) { return next, nil }
+	_, err = d.reloadSources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Ping(context.Background()) != plugin.ErrClosed {
+		t.Fatal("removed plugin still running")
+	}
+}
+
+func TestMedianSource(t *testing.T) {
+	source := func(id string, value int) SourceConfig {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"outcome":%d}`, value)
+		}))
+		t.Cleanup(srv.Close)
+		return SourceConfig{ID: id, Type: "jsonapi", URL: srv.URL, Path: "$.outcome"}
+	}
+	cfg := defaultConfig()
+	cfg.Store.Driver = "memory"
+	cfg.REST.Listen = ""
+	cfg.Scheduler.OnDisagreement = "median"
+	cfg.Scheduler.CaptureEvidence = true
+	cfg.Sources = []SourceConfig{source("a", 100), source("b", 120), source("c", 200),
+		{ID: "price", Type: "median", Sources: []string{"a", "b", "c"}, MaxDeviation: 0.01, Prefixes: []string{"price-"}}}
+	d, err := newDaemon(cfg, [32]byte{1}, dlcoracle.NopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.close()
+
+	// The median is rebuilt over the sources replacing its own
+	cfg.Sources[1] = source("b", 110)
+	d.loadConfig = func() (Config, error) { return cfg, nil }
+	_, err = d.reloadSources(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sources disagree, so the median of all values is attested
+	a, err := d.oracle.CreateEvent(dlcoracle.Event{ID: "price-1", Maturity: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.sched.Run(ctx)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		att, err := d.oracle.Store().Attestation("price-1")
+		if err == nil {
+			err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint,
+				dlcoracle.GenerateNumericMessage(110), att.Signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("event not attested")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The responses of all three sources are kept as evidence
+	for {
+		e, err := d.store.(storage.EvidenceStore).Evidence("price-1")
+		if err == nil {
+			if len(e.Responses) != 3 || e.Verify() != nil {
+				t.Fatalf("unexpected evidence %+v", e)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no evidence stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cfg.Sources = []SourceConfig{{ID: "price", Type: "median", Sources: []string{"a"}}}
+	_, err = d.reloadSources(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unknown source a") {
+		t.Fatalf("expected an unknown source, got %v", err)
+	}
+	cfg.Sources = []SourceConfig{source("a", 100), {ID: "price", Type: "median", Sources: []string{"a"}, Quorum: 2}}
+	_, err = d.reloadSources(context.Background())
+	if err == nil {
+		t.Fatal("quorum larger than the sources accepted")
+	}
+}
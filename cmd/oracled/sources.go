@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/health"
+)
+
+// runningSource is a data source of the daemon, kept across reloads for as
+// long as its configuration doesn't change
+type runningSource struct {
+	cfg SourceConfig
+	ds  datasource.DataSource
+
+	// check is its readiness check, if it can be pinged, and poll keeps
+	// its prices fresh, if it is polled
+	check *health.Check
+	poll  func(ctx context.Context)
+	stop  context.CancelFunc
+
+	// closer stops sources such as plugins that hold a process
+	closer io.Closer
+}
+
+// sourceSet holds the running data sources, which SIGHUP and the
+// ReloadSources RPC replace without restarting the daemon
+type sourceSet struct {
+	mtx     sync.Mutex
+	running map[string]*runningSource
+
+	// ctx is the context pollers run in, nil until the daemon runs;
+	// wg waits for them
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+// setSources builds the data sources configured in configs, keeping those
+// whose configuration is unchanged, and replaces the registered ones with
+// them at once. Nothing changes if one of them can't be built.
+func (d *daemon) setSources(configs []SourceConfig) error {
+	d.sourceSet.mtx.Lock()
+	defer d.sourceSet.mtx.Unlock()
+
+	next := datasource.NewRegistry()
+	running := make(map[string]*runningSource, len(configs))
+	var built []*runningSource
+	fail := func(err error) error {
+		for _, rs := range built {
+			rs.close()
+		}
+		return err
+	}
+	for _, sc := range configs {
+		rs, ok := d.sourceSet.running[sc.ID]
+		if sc.Type == "median" {
+			// Medians hold nothing but their sources, which may
+			// have been replaced, so they are always rebuilt
+			var err error
+			rs, err = d.newMedianSource(sc, next)
+			if err != nil {
+				return fail(fmt.Errorf("source %s: %v", sc.ID, err))
+			}
+		} else if !ok || !reflect.DeepEqual(rs.cfg, sc) {
+			var err error
+			rs, err = d.newRunningSource(sc)
+			if err != nil {
+				return fail(fmt.Errorf("source %s: %v", sc.ID, err))
+			}
+			built = append(built, rs)
+		}
+		err := next.Register(rs.ds)
+		if err != nil {
+			return fail(err)
+		}
+		for _, prefix := range sc.Prefixes {
+			next.Route(prefix, sc.ID)
+		}
+		running[sc.ID] = rs
+	}
+	d.sources.Replace(next)
+
+	for id, rs := range d.sourceSet.running {
+		if running[id] == rs {
+			continue
+		}
+		rs.close()
+		if rs.check != nil {
+			d.ready.Remove(rs.check.Name)
+		}
+	}
+	for id, rs := range running {
+		if d.sourceSet.running[id] == rs {
+			continue
+		}
+		if rs.check != nil {
+			d.ready.Add(rs.check.Name, rs.check.Run)
+		}
+		d.startPoller(rs)
+	}
+	d.sourceSet.running = running
+	return nil
+}
+
+// newRunningSource builds the data source configured in sc, without
+// starting it
+func (d *daemon) newRunningSource(sc SourceConfig) (*runningSource, error) {
+	ds, poll, err := d.newSource(sc, d.oracle.Chain())
+	if err != nil {
+		return nil, err
+	}
+	rs := &runningSource{cfg: sc, poll: poll}
+	if c, ok := ds.(io.Closer); ok {
+		rs.closer = c
+	}
+	if c, ok := health.Source(ds); ok {
+		rs.check = &c
+	}
+	if d.evidence != nil {
+		ds = d.evidence.InstrumentSource(ds)
+	}
+	if d.hooks != nil {
+		ds = d.hooks.InstrumentSource(ds)
+	}
+	rs.ds = d.metrics.InstrumentSource(ds)
+	return rs, nil
+}
+
+// newMedianSource builds the median source configured in sc over sources
+// registered in reg
+func (d *daemon) newMedianSource(sc SourceConfig, reg *datasource.Registry) (*runningSource, error) {
+	if len(sc.Sources) == 0 {
+		return nil, fmt.Errorf("median source needs sources")
+	}
+	var members []datasource.DataSource
+	for _, id := range sc.Sources {
+		ds, ok := reg.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown source %s, configure it before the median", id)
+		}
+		members = append(members, ds)
+	}
+	m := datasource.NewMedian(sc.ID, members...)
+	if sc.Quorum != 0 {
+		if sc.Quorum < 0 || sc.Quorum > len(members) {
+			return nil, fmt.Errorf("quorum %d of %d sources", sc.Quorum, len(members))
+		}
+		m.Quorum = sc.Quorum
+	}
+	if sc.MaxDeviation < 0 {
+		return nil, fmt.Errorf("negative max deviation %g", sc.MaxDeviation)
+	}
+	m.MaxDeviation = sc.MaxDeviation
+	var ds datasource.DataSource = m
+	if d.hooks != nil {
+		ds = d.hooks.InstrumentSource(ds)
+	}
+	return &runningSource{cfg: sc, ds: d.metrics.InstrumentSource(ds)}, nil
+}
+
+// close stops the poller of rs and closes its data source
+func (rs *runningSource) close() {
+	if rs.stop != nil {
+		rs.stop()
+	}
+	if rs.closer != nil {
+		rs.closer.Close()
+	}
+}
+
+// closeSources closes the running data sources when the daemon exits
+func (d *daemon) closeSources() {
+	d.sourceSet.mtx.Lock()
+	defer d.sourceSet.mtx.Unlock()
+	for _, rs := range d.sourceSet.running {
+		rs.close()
+	}
+	d.sourceSet.running = nil
+}
+
+// startPoller starts the poller of rs, if it has one and the daemon is
+// running. The caller holds d.sourceSet.mtx.
+func (d *daemon) startPoller(rs *runningSource) {
+	if rs.poll == nil || d.sourceSet.ctx == nil {
+		return
+	}
+	ctx, stop := context.WithCancel(d.sourceSet.ctx)
+	rs.stop = stop
+	d.sourceSet.wg.Add(1)
+	go func() {
+		defer d.sourceSet.wg.Done()
+		rs.poll(ctx)
+	}()
+}
+
+// runSources starts the pollers of the data sources, and of those
+// replacing them, until ctx is cancelled
+func (d *daemon) runSources(ctx context.Context) {
+	d.sourceSet.mtx.Lock()
+	d.sourceSet.ctx = ctx
+	for _, rs := range d.sourceSet.running {
+		d.startPoller(rs)
+	}
+	d.sourceSet.mtx.Unlock()
+}
+
+// reloadSources rereads the configuration and replaces the data sources
+// with those it configures, returning their IDs. The rest of the
+// configuration only takes effect on restart.
+func (d *daemon) reloadSources(ctx context.Context) ([]string, error) {
+	d.reloadMtx.Lock()
+	defer d.reloadMtx.Unlock()
+	if d.loadConfig == nil {
+		return nil, fmt.Errorf("the daemon has no configuration to reload")
+	}
+	cfg, err := d.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	err = d.setSources(cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	d.cfg.Sources = cfg.Sources
+	if !reflect.DeepEqual(d.cfg, cfg) {
+		d.logger.Log(dlcoracle.LevelWarn, "only data sources were reloaded, restart to apply the other changes")
+	}
+
+	var ids []string
+	for _, ds := range d.sources.List() {
+		ids = append(ids, ds.ID())
+	}
+	d.logger.Log(dlcoracle.LevelInfo, "reloaded data sources", dlcoracle.F("sources", fmt.Sprint(ids)))
+	return ids, nil
+}
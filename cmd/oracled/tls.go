@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newTLS returns the TLS configuration of both servers, or nil if they
+// serve plain text. With ACME it also returns the manager obtaining the
+// certificates.
+func newTLS(cfg TLSConfig) (*tls.Config, *autocert.Manager, error) {
+	files := cfg.CertFile != "" || cfg.KeyFile != ""
+	if files && len(cfg.ACME.Domains) != 0 {
+		return nil, nil, fmt.Errorf("tls: set either cert_file and key_file or acme.domains")
+	}
+
+	var tc *tls.Config
+	var m *autocert.Manager
+	switch {
+	case files:
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, nil, fmt.Errorf("tls: cert_file and key_file go together")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tls: %v", err)
+		}
+		tc = &tls.Config{Certificates: []tls.Certificate{cert}}
+	case len(cfg.ACME.Domains) != 0:
+		m = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.ACME.Domains...),
+			Email:      cfg.ACME.Email,
+		}
+		if cfg.ACME.CacheDir != "" {
+			m.Cache = autocert.DirCache(cfg.ACME.CacheDir)
+		}
+		if cfg.ACME.DirectoryURL != "" {
+			m.Client = &acme.Client{DirectoryURL: cfg.ACME.DirectoryURL}
+		}
+		tc = m.TLSConfig()
+	default:
+		if cfg.ClientCAFile != "" {
+			return nil, nil, fmt.Errorf("tls: client_ca_file needs a certificate")
+		}
+		return nil, nil, nil
+	}
+	tc.MinVersion = tls.VersionTLS12
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("tls: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("tls: no certificates in %s", cfg.ClientCAFile)
+		}
+		// Clients without a certificate can still use the public API
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tc, m, nil
+}
+
+// requireClientCert refuses requests for paths from clients that didn't
+// present a verified certificate
+func requireClientCert(paths ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, p := range paths {
+				if r.URL.Path == p && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+					http.Error(w, "client certificate required", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
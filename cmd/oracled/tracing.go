@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// tracingShutdownTimeout bounds exporting the spans still buffered when
+// the daemon closes
+const tracingShutdownTimeout = 5 * time.Second
+
+// setupTracing installs a TracerProvider exporting the spans of the
+// request path as cfg.Tracing says, and the W3C propagators continuing
+// the traces of callers. The daemon's close then flushes it.
+func (d *daemon) setupTracing() error {
+	cfg := d.cfg.Tracing
+	if cfg.URL == "" {
+		return nil
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.URL)}
+	if len(cfg.Headers) != 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exp, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return err
+	}
+	name := cfg.ServiceName
+	if name == "" {
+		name = "oracled"
+	}
+	ratio := cfg.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(name))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	closeStore := d.close
+	d.close = func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		err := tp.Shutdown(ctx)
+		storeErr := closeStore()
+		if err == nil {
+			err = storeErr
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,475 @@
+// Package config loads a program's configuration into a struct in layers:
+// the struct's own values are the defaults, a YAML file overrides them,
+// environment variables override the file and flags override everything.
+//
+// Fields are named after their YAML keys. The environment variable of a
+// field is the prefix and the keys of its path in upper case, joined by
+// underscores, such as ORACLED_STORE_DSN for store.dsn; flags name the
+// path with dots, as in -set store.dsn=postgres://localhost. Lists are
+// comma separated outside the file, and maps and lists of structs can only
+// be set in it.
+//
+// The file is checked against the struct before it is decoded, so every
+// unknown key, invalid duration and mistyped value is reported at once,
+// with its line and path, rather than the first one only.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem with one setting
+type Error struct {
+	// Source is the file, environment variable or flag that set it
+	Source string
+	// Line is the line of the setting in the file, 0 outside files
+	Line int
+	// Path is the dotted path of the setting, such as store.dsn
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Source)
+	if e.Line != 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+	}
+	if e.Path != "" && e.Path != e.Source {
+		fmt.Fprintf(&b, ": %s", e.Path)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errors are all the problems found loading a configuration
+type Errors []error
+
+func (errs Errors) Error() string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (errs Errors) Unwrap() []error {
+	return errs
+}
+
+// ErrUnknownKey is wrapped by the errors of keys the struct has no field
+// for
+var ErrUnknownKey = errors.New("unknown key")
+
+// Validator is implemented by configurations that check their settings
+// once loaded, beyond their types
+type Validator interface {
+	Validate() error
+}
+
+// Options are the layers Load applies over the defaults
+type Options struct {
+	// File is the YAML file to read, none if empty
+	File string
+
+	// EnvPrefix names environment variables, which aren't read if it is
+	// empty. Lookup looks them up, os.LookupEnv if nil.
+	EnvPrefix string
+	Lookup    func(string) (string, bool)
+
+	// Set are path=value settings, such as those of Flags
+	Set []string
+}
+
+// Flags collects path=value settings from a repeatable flag:
+//
+//	var set config.Flags
+//	flag.Var(&set, "set", "override a setting, as path=value")
+type Flags []string
+
+func (f *Flags) String() string {
+	return strings.Join(*f, " ")
+}
+
+// Set adds a setting, which must have the form path=value
+func (f *Flags) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("expected path=value, got %q", s)
+	}
+	*f = append(*f, s)
+	return nil
+}
+
+// Load applies the layers in opts to v, a pointer to a struct holding the
+// defaults, and validates it if it is a Validator. Problems with the
+// settings are returned as Errors.
+func Load(v interface{}, opts Options) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: %T isn't a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+
+	if opts.File != "" {
+		err := loadFile(v, opts.File)
+		if err != nil {
+			return err
+		}
+	}
+	var errs Errors
+	if opts.EnvPrefix != "" {
+		lookup := opts.Lookup
+		if lookup == nil {
+			lookup = os.LookupEnv
+		}
+		errs = applyEnv(errs, rv, opts.EnvPrefix, "", lookup)
+	}
+	for _, s := range opts.Set {
+		path, value, _ := strings.Cut(s, "=")
+		err := set(rv, path, value)
+		if err != nil {
+			errs = append(errs, &Error{Source: "-set", Path: path, Err: err})
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	if val, ok := v.(Validator); ok {
+		return val.Validate()
+	}
+	return nil
+}
+
+// loadFile checks the YAML file at path against v and decodes it into v
+func loadFile(v interface{}, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return &Error{Source: path, Err: err}
+	}
+	// An empty file has no content and leaves the defaults
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	c := checker{file: path}
+	c.check(doc.Content[0], reflect.TypeOf(v).Elem(), "")
+	if len(c.errs) != 0 {
+		return c.errs
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err = dec.Decode(v)
+	if err != nil && err != io.EOF {
+		return &Error{Source: path, Err: err}
+	}
+	return nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// checker collects the problems of a YAML file
+type checker struct {
+	file string
+	errs Errors
+}
+
+func (c *checker) fail(n *yaml.Node, path string, err error) {
+	c.errs = append(c.errs, &Error{Source: c.file, Line: n.Line, Path: path, Err: err})
+}
+
+// check checks that the node n at path can be decoded into a t
+func (c *checker) check(n *yaml.Node, t reflect.Type, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	// null leaves the value as it is
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	switch {
+	case t == durationType:
+		if n.Kind != yaml.ScalarNode {
+			c.fail(n, path, fmt.Errorf("expected a duration"))
+			return
+		}
+		_, err := time.ParseDuration(n.Value)
+		if err != nil {
+			c.fail(n, path, fmt.Errorf("invalid duration %q, expected a number with a unit such as 90s, 15m or 24h", n.Value))
+		}
+	case t == timeType:
+		var tm time.Time
+		if n.Kind != yaml.ScalarNode || n.Decode(&tm) != nil {
+			c.fail(n, path, fmt.Errorf("invalid time %q, expected RFC 3339 such as 2030-01-01T00:00:00Z", n.Value))
+		}
+	case t.Kind() == reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			c.fail(n, path, fmt.Errorf("expected a mapping of %s", strings.Join(keys(t), ", ")))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			f, ok := field(t, k.Value)
+			if !ok {
+				err := fmt.Errorf("%w", ErrUnknownKey)
+				if s := suggest(k.Value, keys(t)); s != "" {
+					err = fmt.Errorf("%w, did you mean %s?", ErrUnknownKey, s)
+				}
+				c.fail(k, join(path, k.Value), err)
+				continue
+			}
+			c.check(val, f.Type, join(path, k.Value))
+		}
+	case t.Kind() == reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			c.fail(n, path, fmt.Errorf("expected a list"))
+			return
+		}
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case t.Kind() == reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			c.fail(n, path, fmt.Errorf("expected a mapping"))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			c.check(n.Content[i+1], t.Elem(), join(path, n.Content[i].Value))
+		}
+	default:
+		if n.Kind != yaml.ScalarNode {
+			c.fail(n, path, fmt.Errorf("expected %s", kindName(t)))
+			return
+		}
+		err := n.Decode(reflect.New(t).Interface())
+		if err != nil {
+			c.fail(n, path, fmt.Errorf("invalid value %q, expected %s", n.Value, kindName(t)))
+		}
+	}
+}
+
+// kindName describes the values of t
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	}
+	return "a " + t.String()
+}
+
+// key returns the YAML key of the struct field f, or "" if it has none
+func key(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	k := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	if k == "-" {
+		return ""
+	}
+	if k == "" {
+		k = strings.ToLower(f.Name)
+	}
+	return k
+}
+
+// keys returns the YAML keys of the struct type t
+func keys(t reflect.Type) []string {
+	var list []string
+	for i := 0; i < t.NumField(); i++ {
+		if k := key(t.Field(i)); k != "" {
+			list = append(list, k)
+		}
+	}
+	return list
+}
+
+// field returns the field of the struct type t with the YAML key k
+func field(t reflect.Type, k string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); key(f) == k {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func join(path, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+// suggest returns the key closest to a misspelt one, if it is close
+// enough to be what was meant
+func suggest(k string, keys []string) string {
+	best, bestDist := "", 3
+	for _, cand := range keys {
+		if d := distance(k, cand); d < bestDist && d < len(k) {
+			best, bestDist = cand, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// applyEnv overrides the fields of the struct v from the environment
+// variables named after their paths, adding the problems to errs
+func applyEnv(errs Errors, v reflect.Value, prefix, path string, lookup func(string) (string, bool)) Errors {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		k := key(t.Field(i))
+		if k == "" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(k)
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct && f.Type() != timeType {
+			errs = applyEnv(errs, f, name, join(path, k), lookup)
+			continue
+		}
+		s, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		err := setValue(f, s)
+		if err != nil {
+			errs = append(errs, &Error{Source: name, Path: join(path, k), Err: err})
+		}
+	}
+	return errs
+}
+
+// set sets the field at the dotted path in the struct v
+func set(v reflect.Value, path, s string) error {
+	for _, k := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct || v.Type() == timeType {
+			return fmt.Errorf("%w", ErrUnknownKey)
+		}
+		f, ok := field(v.Type(), k)
+		if !ok {
+			if s := suggest(k, keys(v.Type())); s != "" {
+				return fmt.Errorf("%w, did you mean %s?", ErrUnknownKey, s)
+			}
+			return fmt.Errorf("%w", ErrUnknownKey)
+		}
+		v = v.FieldByIndex(f.Index)
+	}
+	if v.Kind() == reflect.Struct && v.Type() != timeType {
+		return fmt.Errorf("is a section, set its keys: %s", strings.Join(keys(v.Type()), ", "))
+	}
+	return setValue(v, s)
+}
+
+// setValue parses s into f
+func setValue(f reflect.Value, s string) error {
+	switch {
+	case f.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, expected a number with a unit such as 90s, 15m or 24h", s)
+		}
+		f.SetInt(int64(d))
+		return nil
+	case f.Type() == timeType:
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q, expected RFC 3339 such as 2030-01-01T00:00:00Z", s)
+		}
+		f.Set(reflect.ValueOf(tm))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid value %q, expected true or false", s)
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q, expected %s", s, kindName(f.Type()))
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q, expected %s", s, kindName(f.Type()))
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q, expected %s", s, kindName(f.Type()))
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can only be set in the config file")
+		}
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		f.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("can only be set in the config file")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Store struct {
+		Driver string `yaml:"driver"`
+		Path   string `yaml:"path"`
+	} `yaml:"store"`
+	Interval time.Duration `yaml:"interval"`
+	Servers  []string      `yaml:"servers"`
+	Limit    int           `yaml:"limit"`
+	Sources  []struct {
+		ID   string        `yaml:"id"`
+		Poll time.Duration `yaml:"poll"`
+	} `yaml:"sources"`
+
+	invalid bool
+}
+
+func (c *testConfig) Validate() error {
+	if c.invalid {
+		return errors.New("invalid")
+	}
+	return nil
+}
+
+func writeFile(t *testing.T, s string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(s), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLayers(t *testing.T) {
+	var c testConfig
+	c.Store.Driver = "memory"
+	c.Limit = 5
+	env := map[string]string{
+		"APP_STORE_PATH": "env.db",
+		"APP_SERVERS":    "a, b",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	err := Load(&c, Options{
+		File:      writeFile(t, "store:\n  driver: bolt\n  path: file.db\ninterval: 1m\nsources:\n  - id: x\n    poll: 5s\n"),
+		EnvPrefix: "APP",
+		Lookup:    lookup,
+		Set:       []string{"interval=2h"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Store.Driver != "bolt" || c.Store.Path != "env.db" || c.Interval != 2*time.Hour || c.Limit != 5 {
+		t.Fatalf("layers applied as %+v", c)
+	}
+	if len(c.Servers) != 2 || c.Servers[1] != "b" || len(c.Sources) != 1 || c.Sources[0].Poll != 5*time.Second {
+		t.Fatalf("lists applied as %+v", c)
+	}
+
+	c.invalid = true
+	err = Load(&c, Options{})
+	if err == nil || err.Error() != "invalid" {
+		t.Fatalf("expected the validation error, got %v", err)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	path := writeFile(t, `store:
+  drivr: bolt
+interval: 10x
+limit: many
+sources:
+  - id: x
+    pol: 5s
+  - id: y
+    poll: 1d
+servers: a
+`)
+	var c testConfig
+	err := Load(&c, Options{File: path})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	want := []string{
+		path + ":2: store.drivr: unknown key, did you mean driver?",
+		path + `:3: interval: invalid duration "10x"`,
+		path + `:4: limit: invalid value "many", expected an integer`,
+		path + ":7: sources[0].pol: unknown key, did you mean poll?",
+		path + `:9: sources[1].poll: invalid duration "1d"`,
+		path + ":10: servers: expected a list",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors:\n%v", len(errs), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d is %q, expected %q", i, errs[i], w)
+		}
+	}
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("unknown keys don't wrap ErrUnknownKey")
+	}
+	if c.Store.Driver != "" {
+		t.Fatal("decoded an invalid file")
+	}
+
+	// An empty file keeps the defaults
+	c.Limit = 3
+	err = Load(&c, Options{File: writeFile(t, "")})
+	if err != nil || c.Limit != 3 {
+		t.Fatalf("empty file: %+v %v", c, err)
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	env := map[string]string{"APP_LIMIT": "1.5"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+	var c testConfig
+	err := Load(&c, Options{EnvPrefix: "APP", Lookup: lookup,
+		Set: []string{"store.pth=x", "store=bolt", "sources=x", "interval=soon"}})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatalf("expected 5 errors, got %v", err)
+	}
+	want := []string{
+		`APP_LIMIT: limit: invalid value "1.5"`,
+		"-set: store.pth: unknown key, did you mean path?",
+		"-set: store: is a section",
+		"-set: sources: can only be set in the config file",
+		`-set: interval: invalid duration "soon"`,
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d is %q, expected %q", i, errs[i], w)
+		}
+	}
+
+	var f Flags
+	if f.Set("store.path") == nil {
+		t.Fatal("accepted a setting without a value")
+	}
+	if f.Set("store.path=a=b") != nil || f[0] != "store.path=a=b" {
+		t.Fatalf("flags %q", f)
+	}
+}
@@ -0,0 +1,279 @@
+// Package conformance checks that an alternative backend of the oracle's
+// signatures, such as an HSM signer, the WebAssembly build or one built
+// on another curve library, is equivalent to this library. It runs an
+// Implementation against the golden vectors of package testvectors:
+// signing, verifying and anticipation points of single messages, and
+// encoding, decoding and verifying whole announced and attested events,
+// including that tampered signatures are rejected.
+//
+// Call Run from a test of the backend:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, myBackend{})
+//	}
+//
+// Backends implementing only part of the operations can embed Library
+// for the rest.
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/testvectors"
+)
+
+// Implementation is the verification side of a backend. Keys and points
+// are those of the library: 32 byte scalars and 33 byte compressed points,
+// and announcements are encoded in the JSON of the REST API.
+type Implementation interface {
+	// Verify checks the signature sig of message for the oracle's public
+	// key and the R point of the one-time signing key
+	Verify(pubKey, rPoint [33]byte, message []byte, sig [32]byte) error
+
+	// AnticipationPoint returns the point sig * G the signature of
+	// message will have, computed from the public keys alone
+	AnticipationPoint(pubKey, rPoint [33]byte, message []byte) ([33]byte, error)
+
+	// EncodeAnnouncement returns the JSON of an announcement
+	EncodeAnnouncement(a dlcoracle.Announcement) ([]byte, error)
+
+	// DecodeAnnouncement parses the JSON of an announcement
+	DecodeAnnouncement(b []byte) (dlcoracle.Announcement, error)
+
+	// VerifyAnnouncement checks the oracle's signature on an announcement
+	VerifyAnnouncement(a dlcoracle.Announcement) error
+
+	// VerifyAttestation checks an attestation of the event announced in
+	// a and returns its outcome
+	VerifyAttestation(a dlcoracle.Announcement, att dlcoracle.Attestation) (dlcoracle.Outcome, error)
+}
+
+// Signer is implemented by backends that sign. The vectors' private keys
+// are passed in; an HSM backend imports them for the test.
+type Signer interface {
+	// Sign returns the signature of message with the oracle's private
+	// key and a one-time signing key
+	Sign(privKey, oneTimeSigningKey [32]byte, message []byte) ([32]byte, error)
+}
+
+// errSkip is returned by checks that don't apply to an implementation
+var errSkip = errors.New("not implemented")
+
+// check is one property checked against the vectors
+type check struct {
+	name string
+	run  func(impl Implementation) error
+}
+
+// checks lists the properties in the order they are checked
+var checks = []check{
+	{"signatures/sign", checkSign},
+	{"signatures/verify", checkVerify},
+	{"signatures/anticipation-point", checkAnticipationPoint},
+	{"announcements/encode", checkEncode},
+	{"announcements/decode", checkDecode},
+	{"announcements/verify", checkVerifyAnnouncement},
+	{"attestations/verify", checkVerifyAttestation},
+	{"attestations/anticipation-point", checkAttestationPoint},
+}
+
+// Run checks impl against the vectors in a subtest per property. Signing
+// is skipped unless impl implements Signer.
+func Run(t *testing.T, impl Implementation) {
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.run(impl)
+			if errors.Is(err, errSkip) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Check checks impl against the vectors like Run, outside of tests, and
+// returns the first mismatch of each property that fails
+func Check(impl Implementation) error {
+	var errs []error
+	for _, c := range checks {
+		err := c.run(impl)
+		if err != nil && !errors.Is(err, errSkip) {
+			errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkSign(impl Implementation) error {
+	s, ok := impl.(Signer)
+	if !ok {
+		return fmt.Errorf("signing: %w", errSkip)
+	}
+	for i, v := range testvectors.Signatures() {
+		sig, err := s.Sign(v.PrivKey, v.OneTimeSigningKey, v.Message)
+		if err != nil {
+			return fmt.Errorf("vector %d: %v", i, err)
+		}
+		if sig != v.Signature {
+			return fmt.Errorf("vector %d: signature %x, expected %x", i, sig, v.Signature)
+		}
+	}
+	return nil
+}
+
+func checkVerify(impl Implementation) error {
+	for i, v := range testvectors.Signatures() {
+		err := impl.Verify(v.PubKey, v.RPoint, v.Message, v.Signature)
+		if err != nil {
+			return fmt.Errorf("vector %d: %v", i, err)
+		}
+		tampered := v.Signature
+		tampered[31] ^= 1
+		if impl.Verify(v.PubKey, v.RPoint, v.Message, tampered) == nil {
+			return fmt.Errorf("vector %d: tampered signature verifies", i)
+		}
+		other := append([]byte{}, v.Message...)
+		other[0] ^= 1
+		if impl.Verify(v.PubKey, v.RPoint, other, v.Signature) == nil {
+			return fmt.Errorf("vector %d: signature verifies for another message", i)
+		}
+	}
+	return nil
+}
+
+func checkAnticipationPoint(impl Implementation) error {
+	for i, v := range testvectors.Signatures() {
+		point, err := impl.AnticipationPoint(v.PubKey, v.RPoint, v.Message)
+		if err != nil {
+			return fmt.Errorf("vector %d: %v", i, err)
+		}
+		if point != v.SignaturePubKeyFromMessage {
+			return fmt.Errorf("vector %d: anticipation point %x, expected %x", i, point, v.SignaturePubKeyFromMessage)
+		}
+	}
+	return nil
+}
+
+// sameAnnouncement reports whether two announcements encode alike in the
+// library
+func sameAnnouncement(a, b dlcoracle.Announcement) (bool, error) {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ja, jb), nil
+}
+
+func checkEncode(impl Implementation) error {
+	records, _ := testvectors.Records()
+	for i, r := range records {
+		b, err := impl.EncodeAnnouncement(r.Announcement)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		a, err := dlcoracle.ParseAnnouncement(b)
+		if err != nil {
+			return fmt.Errorf("record %d: %v in %s", i, err, b)
+		}
+		same, err := sameAnnouncement(a, r.Announcement)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		if !same {
+			return fmt.Errorf("record %d: encoded as %s", i, b)
+		}
+	}
+	return nil
+}
+
+func checkDecode(impl Implementation) error {
+	records, _ := testvectors.Records()
+	for i, r := range records {
+		b, err := json.Marshal(r.Announcement)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		a, err := impl.DecodeAnnouncement(b)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		same, err := sameAnnouncement(a, r.Announcement)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		if !same {
+			return fmt.Errorf("record %d: %s decoded as %+v", i, b, a)
+		}
+	}
+	return nil
+}
+
+func checkVerifyAnnouncement(impl Implementation) error {
+	records, _ := testvectors.Records()
+	for i, r := range records {
+		err := impl.VerifyAnnouncement(r.Announcement)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		tampered := r.Announcement
+		tampered.Maturity = tampered.Maturity.Add(time.Second)
+		if impl.VerifyAnnouncement(tampered) == nil {
+			return fmt.Errorf("record %d: announcement with another maturity verifies", i)
+		}
+	}
+	return nil
+}
+
+func checkVerifyAttestation(impl Implementation) error {
+	records, _ := testvectors.Records()
+	for i, r := range records {
+		outcome, err := impl.VerifyAttestation(r.Announcement, r.Attestation)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		msg, err := r.Announcement.Descriptor.OutcomeMessage(outcome)
+		if err != nil || !bytes.Equal(msg, r.OutcomeMessage) {
+			return fmt.Errorf("record %d: attested %+v, expected %+v", i, outcome, r.Outcome)
+		}
+		tampered := r.Attestation
+		tampered.Signature[31] ^= 1
+		if len(tampered.Signatures) != 0 {
+			tampered.Signatures = append([][32]byte{tampered.Signature}, tampered.Signatures[1:]...)
+		}
+		if _, err = impl.VerifyAttestation(r.Announcement, tampered); err == nil {
+			return fmt.Errorf("record %d: tampered attestation verifies", i)
+		}
+	}
+	return nil
+}
+
+func checkAttestationPoint(impl Implementation) error {
+	records, _ := testvectors.Records()
+	for i, r := range records {
+		// digits events sign each digit, the point is that of the first
+		signed := r.OutcomeMessage
+		if r.Announcement.Descriptor.Type == dlcoracle.EventTypeDigits {
+			signed = dlcoracle.DigitMessage(signed[0])
+		}
+		point, err := impl.AnticipationPoint(r.Announcement.OraclePubKey, r.Announcement.RPoint, signed)
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		if point != r.SignaturePoint {
+			return fmt.Errorf("record %d: anticipation point %x, expected %x", i, point, r.SignaturePoint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package conformance
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestLibrary(t *testing.T) {
+	Run(t, Library{})
+}
+
+// lenient accepts any attestation of a possible outcome, without checking
+// its signature
+type lenient struct {
+	Library
+}
+
+func (lenient) VerifyAttestation(a dlcoracle.Announcement, att dlcoracle.Attestation) (dlcoracle.Outcome, error) {
+	return a.Descriptor.ParseOutcome(att.Message)
+}
+
+// wrongPoint computes anticipation points for another message
+type wrongPoint struct {
+	Library
+}
+
+func (wrongPoint) AnticipationPoint(pubKey, rPoint [33]byte, message []byte) ([33]byte, error) {
+	return dlcoracle.ComputeSignaturePubKey(pubKey, rPoint, append(message, 0))
+}
+
+// verifier doesn't sign
+type verifier struct {
+	Implementation
+}
+
+func TestCheck(t *testing.T) {
+	err := Check(verifier{Library{}})
+	if err != nil {
+		t.Fatalf("verification-only implementation failed: %v", err)
+	}
+
+	err = Check(lenient{})
+	if err == nil || !strings.Contains(err.Error(), "attestations/verify: record 0: tampered attestation verifies") {
+		t.Fatalf("unchecked attestations not reported: %v", err)
+	}
+
+	err = Check(wrongPoint{})
+	if err == nil {
+		t.Fatal("wrong anticipation points not reported")
+	}
+	for _, name := range []string{"signatures/anticipation-point: vector 0", "attestations/anticipation-point: record 0"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("%s not reported: %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "signatures/verify") {
+		t.Fatalf("passing property reported: %v", err)
+	}
+	if !errors.Is(checkSign(verifier{Library{}}), errSkip) {
+		t.Fatal("signing checked without a Signer")
+	}
+}
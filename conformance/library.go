@@ -0,0 +1,41 @@
+package conformance
+
+import (
+	"encoding/json"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Library is the Implementation of this library, the reference the
+// vectors were generated with. In regular builds it is also a Signer.
+type Library struct{}
+
+// Verify implements Implementation
+func (Library) Verify(pubKey, rPoint [33]byte, message []byte, sig [32]byte) error {
+	return dlcoracle.VerifySignature(pubKey, rPoint, message, sig)
+}
+
+// AnticipationPoint implements Implementation
+func (Library) AnticipationPoint(pubKey, rPoint [33]byte, message []byte) ([33]byte, error) {
+	return dlcoracle.ComputeSignaturePubKey(pubKey, rPoint, message)
+}
+
+// EncodeAnnouncement implements Implementation
+func (Library) EncodeAnnouncement(a dlcoracle.Announcement) ([]byte, error) {
+	return json.Marshal(a)
+}
+
+// DecodeAnnouncement implements Implementation
+func (Library) DecodeAnnouncement(b []byte) (dlcoracle.Announcement, error) {
+	return dlcoracle.ParseAnnouncement(b)
+}
+
+// VerifyAnnouncement implements Implementation
+func (Library) VerifyAnnouncement(a dlcoracle.Announcement) error {
+	return a.Verify()
+}
+
+// VerifyAttestation implements Implementation
+func (Library) VerifyAttestation(a dlcoracle.Announcement, att dlcoracle.Attestation) (dlcoracle.Outcome, error) {
+	return dlcoracle.VerifyAttestation(a, att)
+}
@@ -0,0 +1,10 @@
+//go:build !verifyonly
+
+package conformance
+
+import "github.com/mit-dci/dlc-oracle-go"
+
+// Sign implements Signer
+func (Library) Sign(privKey, oneTimeSigningKey [32]byte, message []byte) ([32]byte, error) {
+	return dlcoracle.ComputeSignature(privKey, oneTimeSigningKey, message)
+}
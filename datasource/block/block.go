@@ -0,0 +1,119 @@
+// Package block provides data sources attesting to the state of the
+// Bitcoin chain: the hash of the block at an event's maturity height, and
+// the estimated fee rate at maturity.
+package block
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/chain"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// fetchTimeout bounds a single query to the chain backend
+const fetchTimeout = 30 * time.Second
+
+// HashSource attests the hash of the block at the maturity height of an
+// event. Events using it need a MaturityHeight and the bytes type; the
+// attested message is the 32-byte hash in display byte order.
+type HashSource struct {
+	id      string
+	backend chain.Backend
+}
+
+// NewHashSource returns a block hash data source reading from backend
+func NewHashSource(id string, backend chain.Backend) *HashSource {
+	return &HashSource{id: id, backend: backend}
+}
+
+// ID implements datasource.DataSource
+func (s *HashSource) ID() string {
+	return s.id
+}
+
+// Describe implements datasource.DataSource
+func (s *HashSource) Describe() string {
+	return "Bitcoin block hash at the maturity height"
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *HashSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	if ev.MaturityHeight == 0 {
+		return dlcoracle.Outcome{}, fmt.Errorf("event %s has no maturity height", ev.ID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	hash, err := s.backend.BlockHash(ctx, ev.MaturityHeight)
+	if errors.Is(err, chain.ErrBlockNotFound) {
+		return dlcoracle.Outcome{}, datasource.ErrNotAvailable
+	}
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	return dlcoracle.Outcome{Bytes: hash[:]}, nil
+}
+
+// Ping implements datasource.Pinger by asking the backend for the chain
+// height
+func (s *HashSource) Ping(ctx context.Context) error {
+	_, err := s.backend.BlockHeight(ctx)
+	return err
+}
+
+// FeeRateSource attests the estimated fee rate for confirmation within a
+// number of blocks at the time the event matures, in sat/vB scaled by
+// 10^precision, for numeric events
+type FeeRateSource struct {
+	id        string
+	backend   chain.Backend
+	target    int
+	precision int
+}
+
+// NewFeeRateSource returns a fee rate data source reading estimates for
+// target blocks from backend
+func NewFeeRateSource(id string, backend chain.Backend, target, precision int) *FeeRateSource {
+	return &FeeRateSource{
+		id:        id,
+		backend:   backend,
+		target:    target,
+		precision: precision,
+	}
+}
+
+// ID implements datasource.DataSource
+func (s *FeeRateSource) ID() string {
+	return s.id
+}
+
+// Describe implements datasource.DataSource
+func (s *FeeRateSource) Describe() string {
+	return fmt.Sprintf("Bitcoin fee rate for %d blocks in sat/vB with %d decimals", s.target, s.precision)
+}
+
+// Ping implements datasource.Pinger by asking the backend for the chain
+// height
+func (s *FeeRateSource) Ping(ctx context.Context) error {
+	_, err := s.backend.BlockHeight(ctx)
+	return err
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *FeeRateSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	rate, err := s.backend.FeeRate(ctx, s.target)
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	scaled := math.Round(rate * math.Pow10(s.precision))
+	if scaled < 0 || scaled > math.MaxInt64 {
+		return dlcoracle.Outcome{}, fmt.Errorf("fee rate %v out of range", rate)
+	}
+	return dlcoracle.Outcome{Value: int64(scaled)}, nil
+}
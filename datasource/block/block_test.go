@@ -0,0 +1,111 @@
+package block
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/chain"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+type fakeChain struct {
+	mtx    sync.Mutex
+	height uint32
+}
+
+func (c *fakeChain) setHeight(h uint32) {
+	c.mtx.Lock()
+	c.height = h
+	c.mtx.Unlock()
+}
+
+func (c *fakeChain) BlockHeight(ctx context.Context) (uint32, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.height, nil
+}
+
+func (c *fakeChain) BlockHash(ctx context.Context, height uint32) ([32]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if height > c.height {
+		return [32]byte{}, chain.ErrBlockNotFound
+	}
+	return [32]byte{byte(height)}, nil
+}
+
+func (c *fakeChain) FeeRate(ctx context.Context, target int) (float64, error) {
+	return 12.345, nil
+}
+
+func TestFeeRateSource(t *testing.T) {
+	s := NewFeeRateSource("fees", &fakeChain{}, 6, 2)
+	o, err := s.FetchOutcome(dlcoracle.Event{})
+	if err != nil || o.Value != 1235 {
+		t.Fatalf("unexpected outcome %+v %v", o, err)
+	}
+}
+
+func TestHashAtMaturityHeight(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	c := &fakeChain{height: 99}
+	o.SetChain(c)
+
+	reg := datasource.NewRegistry()
+	reg.Register(NewHashSource("blockhash", c))
+	reg.Route("blockhash-", "blockhash")
+	s := scheduler.New(o, reg)
+	s.SetChainPollInterval(10 * time.Millisecond)
+
+	ann, err := s.Schedule(dlcoracle.Event{
+		ID:             "blockhash-100",
+		MaturityHeight: 100,
+		Descriptor:     dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeBytes},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ann.MaturityHeight != 100 || ann.Verify() != nil {
+		t.Fatalf("unexpected announcement %+v", ann)
+	}
+	_, err = o.AttestOutcome("blockhash-100", dlcoracle.Outcome{Bytes: []byte{1}})
+	if err == nil {
+		t.Fatal("attested before the maturity height")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	time.Sleep(50 * time.Millisecond)
+	if p := s.Pending(); len(p) != 1 || p[0].Attempts != 0 {
+		t.Fatalf("waiting for the chain should not count as failure: %+v", p)
+	}
+
+	c.setHeight(100)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		att, err := o.Store().Attestation("blockhash-100")
+		if err == nil {
+			if att.Message[0] != 100 || len(att.Message) != 32 {
+				t.Fatalf("unexpected message %x", att.Message)
+			}
+			err = dlcoracle.VerifySignature(ann.OraclePubKey, ann.RPoint, att.Message, att.Signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("event was not attested at its maturity height")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,171 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/tracing"
+)
+
+// ErrNotAvailable is returned by a data source that doesn't know the
+// outcome of an event yet. The scheduler keeps retrying.
+var ErrNotAvailable = errors.New("outcome not available yet")
+
+// ErrDisagreement is returned by data sources combining several others
+// when too few of them agree on the outcome
+var ErrDisagreement = errors.New("data sources disagree")
+
+// DataSource looks up the real-world outcome of events, for instance
+// from a price feed, a sports API or an operator entering it by hand
+type DataSource interface {
+	// ID uniquely identifies the data source within an oracle
+	ID() string
+
+	// Describe returns a human readable description of the data source
+	Describe() string
+
+	// FetchOutcome returns the outcome of an event that has matured
+	FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error)
+}
+
+// Pinger is implemented by data sources that can check they are
+// reachable without resolving an event
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type route struct {
+	prefix   string
+	sourceID string
+}
+
+// Registry holds the data sources of an oracle and decides which one
+// resolves an event, based on the prefix of the event's ID. It implements
+// scheduler.OutcomeFetcher.
+type Registry struct {
+	mtx     sync.RWMutex
+	sources map[string]DataSource
+	routes  []route
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]DataSource)}
+}
+
+// Register adds a data source to the registry
+func (r *Registry) Register(ds DataSource) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.sources[ds.ID()]; ok {
+		return fmt.Errorf("data source %s already registered", ds.ID())
+	}
+	r.sources[ds.ID()] = ds
+	return nil
+}
+
+// Unregister removes a data source from the registry
+func (r *Registry) Unregister(id string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.sources, id)
+}
+
+// Get returns the data source with the given ID
+func (r *Registry) Get(id string) (DataSource, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	ds, ok := r.sources[id]
+	return ds, ok
+}
+
+// List returns all registered data sources ordered by ID
+func (r *Registry) List() []DataSource {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	list := make([]DataSource, 0, len(r.sources))
+	for _, ds := range r.sources {
+		list = append(list, ds)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID() < list[j].ID() })
+	return list
+}
+
+// Route resolves events whose ID starts with prefix using the data source
+// sourceID. When several prefixes match, the longest one wins.
+func (r *Registry) Route(prefix, sourceID string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for i := range r.routes {
+		if r.routes[i].prefix == prefix {
+			r.routes[i].sourceID = sourceID
+			return
+		}
+	}
+	r.routes = append(r.routes, route{prefix, sourceID})
+	sort.Slice(r.routes, func(i, j int) bool {
+		return len(r.routes[i].prefix) > len(r.routes[j].prefix)
+	})
+}
+
+// Replace makes r hold the data sources and routes of next instead of its
+// own, all at once, so no event is resolved with half of each. Fetches in
+// progress finish with the source they started with.
+func (r *Registry) Replace(next *Registry) {
+	next.mtx.RLock()
+	sources := make(map[string]DataSource, len(next.sources))
+	for id, ds := range next.sources {
+		sources[id] = ds
+	}
+	routes := append([]route(nil), next.routes...)
+	next.mtx.RUnlock()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.sources, r.routes = sources, routes
+}
+
+// SourceFor returns the data source resolving ev
+func (r *Registry) SourceFor(ev dlcoracle.Event) (DataSource, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, rt := range r.routes {
+		if strings.HasPrefix(ev.ID, rt.prefix) {
+			ds, ok := r.sources[rt.sourceID]
+			if !ok {
+				return nil, fmt.Errorf("data source %s for event %s is not registered",
+					rt.sourceID, ev.ID)
+			}
+			return ds, nil
+		}
+	}
+	return nil, fmt.Errorf("no data source for event %s", ev.ID)
+}
+
+// FetchOutcome fetches the outcome of ev from the data source it is
+// routed to
+func (r *Registry) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	ds, err := r.SourceFor(ev)
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	return ds.FetchOutcome(ev)
+}
+
+// FetchOutcomeContext is FetchOutcome in a span of the trace in ctx, which
+// names the data source the event is routed to
+func (r *Registry) FetchOutcomeContext(ctx context.Context, ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	ds, err := r.SourceFor(ev)
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	_, span := tracing.Start(ctx, "datasource.fetch", tracing.EventID(ev.ID), tracing.SourceID(ds.ID()))
+	o, err := ds.FetchOutcome(ev)
+	tracing.End(span, err)
+	return o, err
+}
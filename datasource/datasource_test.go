@@ -0,0 +1,70 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestRouting(t *testing.T) {
+	r := NewRegistry()
+	btc := NewManual("btc")
+	btcusd := NewManual("btcusd")
+	for _, ds := range []DataSource{btc, btcusd} {
+		err := r.Register(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.Register(NewManual("btc")) == nil {
+		t.Fatal("registered a duplicate id")
+	}
+	r.Route("btc", "btc")
+	r.Route("btcusd-", "btcusd")
+
+	btcusd.Set("btcusd-1", dlcoracle.Outcome{Value: 7})
+	o, err := r.FetchOutcome(dlcoracle.Event{ID: "btcusd-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Value != 7 {
+		t.Fatalf("unexpected outcome %+v", o)
+	}
+
+	_, err = r.FetchOutcome(dlcoracle.Event{ID: "btceur-1"})
+	if err != ErrNotAvailable {
+		t.Fatalf("expected ErrNotAvailable from btc source, got %v", err)
+	}
+	_, err = r.FetchOutcome(dlcoracle.Event{ID: "eth-1"})
+	if err == nil {
+		t.Fatal("fetched outcome for an unrouted event")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	r := NewRegistry()
+	old := NewManual("btc")
+	r.Register(old)
+	r.Route("btc", "btc")
+
+	next := NewRegistry()
+	eth := NewManual("eth")
+	next.Register(eth)
+	next.Route("eth-", "eth")
+	r.Replace(next)
+	// Changes to next afterwards don't leak into r
+	next.Route("btc", "eth")
+
+	eth.Set("eth-1", dlcoracle.Outcome{Value: 3})
+	o, err := r.FetchOutcome(dlcoracle.Event{ID: "eth-1"})
+	if err != nil || o.Value != 3 {
+		t.Fatalf("outcome %+v %v", o, err)
+	}
+	_, err = r.FetchOutcome(dlcoracle.Event{ID: "btc-1"})
+	if err == nil {
+		t.Fatal("resolved an event with a replaced source")
+	}
+	if _, ok := r.Get("btc"); ok || len(r.List()) != 1 {
+		t.Fatalf("sources %v", r.List())
+	}
+}
@@ -0,0 +1,173 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource/price"
+)
+
+// maxResponseSize limits how much of an API response is read
+const maxResponseSize = 1 << 20
+
+// Config describes how to resolve events from a JSON API without
+// writing Go code
+type Config struct {
+	ID          string
+	Description string
+
+	// URL is a text/template executed with the dlcoracle.Event being
+	// resolved, for instance
+	// https://api.example.com/matches/{{.ID}}?date={{.Maturity.Format "2006-01-02"}}
+	URL string
+
+	// Headers are added to every request, for instance for API keys
+	Headers map[string]string
+
+	// Path points at the outcome in the response, in JSONPath syntax
+	// such as $.data.result or $.items[0]['home score']
+	Path string
+
+	// Precision is the number of decimals numeric outcomes are scaled by
+	Precision int
+
+	// Outcomes maps the values found at Path to the outcomes of enum
+	// events. Without it the value itself must be one of the outcomes.
+	Outcomes map[string]string
+}
+
+// Source is a data source resolving events from a JSON API
+type Source struct {
+	cfg    Config
+	url    *template.Template
+	path   []pathStep
+	client *http.Client
+}
+
+// New returns a data source for cfg
+func New(cfg Config) (*Source, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("data source needs an id")
+	}
+	tmpl, err := template.New(cfg.ID).Option("missingkey=error").Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("url template: %v", err)
+	}
+	path, err := parsePath(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		cfg:    cfg,
+		url:    tmpl,
+		path:   path,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// SetHTTPClient replaces the HTTP client used to query the API
+func (s *Source) SetHTTPClient(c *http.Client) {
+	s.client = c
+}
+
+// ID implements datasource.DataSource
+func (s *Source) ID() string {
+	return s.cfg.ID
+}
+
+// Describe implements datasource.DataSource
+func (s *Source) Describe() string {
+	if s.cfg.Description != "" {
+		return s.cfg.Description
+	}
+	return fmt.Sprintf("%s at %s", s.cfg.Path, s.cfg.URL)
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *Source) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	var o dlcoracle.Outcome
+	v, err := s.fetchValue(ev)
+	if err != nil {
+		return o, err
+	}
+	str, err := valueString(v)
+	if err != nil {
+		return o, err
+	}
+
+	switch ev.Descriptor.Type {
+	case dlcoracle.EventTypeNumeric:
+		o.Value, err = price.ScaleDecimal(str, s.cfg.Precision)
+		return o, err
+	case dlcoracle.EventTypeEnum:
+		o.Label = str
+		if len(s.cfg.Outcomes) > 0 {
+			label, ok := s.cfg.Outcomes[str]
+			if !ok {
+				return o, fmt.Errorf("no outcome mapped for value %q", str)
+			}
+			o.Label = label
+		}
+		return o, nil
+	}
+	return o, fmt.Errorf("unsupported event type %s", ev.Descriptor.Type)
+}
+
+func (s *Source) fetchValue(ev dlcoracle.Event) (interface{}, error) {
+	var url bytes.Buffer
+	err := s.url.Execute(&url, ev)
+	if err != nil {
+		return nil, fmt.Errorf("url template: %v", err)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %d", s.cfg.ID, resp.StatusCode)
+	}
+
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	// Keep numbers as their decimal text so no precision is lost
+	dec.UseNumber()
+	var doc interface{}
+	err = dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	v, err := evalPath(doc, s.path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", s.cfg.Path, err)
+	}
+	return v, nil
+}
+
+// valueString converts a scalar JSON value to a string
+func valueString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("value %v is not a string, number or boolean", v)
+}
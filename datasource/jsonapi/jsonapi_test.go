@@ -0,0 +1,85 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/match/final-2024-07-14":
+			w.Write([]byte(`{"match":{"teams":["A","B"],"result":{"winner":"home"}}}`))
+		case "/price/2024-07-14":
+			w.Write([]byte(`{"data":[{"close":12345.675}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestEnumMapping(t *testing.T) {
+	ts := newTestAPI(t)
+	s, err := New(Config{
+		ID:       "sports",
+		URL:      ts.URL + "/match/{{.ID}}",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+		Path:     "$.match['result'].winner",
+		Outcomes: map[string]string{"home": "A wins", "away": "B wins"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := s.FetchOutcome(dlcoracle.Event{
+		ID:         "final-2024-07-14",
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Label != "A wins" {
+		t.Fatalf("unexpected outcome %q", o.Label)
+	}
+}
+
+func TestNumericScaling(t *testing.T) {
+	ts := newTestAPI(t)
+	s, err := New(Config{
+		ID:        "close",
+		URL:       ts.URL + `/price/{{.Maturity.Format "2006-01-02"}}`,
+		Headers:   map[string]string{"X-Api-Key": "key"},
+		Path:      "$.data[0].close",
+		Precision: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := s.FetchOutcome(dlcoracle.Event{
+		Maturity: time.Date(2024, 7, 14, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Value != 1234568 {
+		t.Fatalf("unexpected outcome %d", o.Value)
+	}
+}
+
+func TestBadPaths(t *testing.T) {
+	for _, p := range []string{"data", "$.", "$[x]", "$['a'", "$.a[-1]"} {
+		_, err := parsePath(p)
+		if err == nil {
+			t.Fatalf("parsed invalid path %q", p)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathStep is a single step of a path: a member name or an array index
+type pathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parsePath parses the subset of JSONPath needed to point at a single
+// value: $.a.b, $.a[0].b and $['a b'] with member names and array indexes
+func parsePath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	var steps []pathStep
+	rest := path[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q has an empty member name", path)
+			}
+			steps = append(steps, pathStep{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("path %q has an unterminated [", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if len(inner) >= 2 && inner[0] == '\'' && inner[len(inner)-1] == '\'' {
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("path %q has an invalid index %q", path, inner)
+			}
+			steps = append(steps, pathStep{index: i, isIdx: true})
+		default:
+			return nil, fmt.Errorf("path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// evalPath follows steps through a document decoded by encoding/json
+func evalPath(doc interface{}, steps []pathStep) (interface{}, error) {
+	cur := doc
+	for _, s := range steps {
+		if s.isIdx {
+			arr, ok := cur.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("[%d]: not an array", s.index)
+			}
+			if s.index >= len(arr) {
+				return nil, fmt.Errorf("[%d]: index out of range", s.index)
+			}
+			cur = arr[s.index]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: not an object", s.key)
+		}
+		cur, ok = obj[s.key]
+		if !ok {
+			return nil, fmt.Errorf("%s: no such member", s.key)
+		}
+	}
+	return cur, nil
+}
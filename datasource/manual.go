@@ -0,0 +1,47 @@
+package datasource
+
+import (
+	"sync"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Manual is a data source whose outcomes are entered by the operator
+type Manual struct {
+	id       string
+	mtx      sync.Mutex
+	outcomes map[string]dlcoracle.Outcome
+}
+
+// NewManual returns a manual data source with the given ID
+func NewManual(id string) *Manual {
+	return &Manual{id: id, outcomes: make(map[string]dlcoracle.Outcome)}
+}
+
+// ID implements DataSource
+func (m *Manual) ID() string {
+	return m.id
+}
+
+// Describe implements DataSource
+func (m *Manual) Describe() string {
+	return "outcomes entered by the operator"
+}
+
+// Set enters the outcome of an event
+func (m *Manual) Set(eventID string, outcome dlcoracle.Outcome) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.outcomes[eventID] = outcome
+}
+
+// FetchOutcome returns the outcome entered for ev, or ErrNotAvailable
+func (m *Manual) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	o, ok := m.outcomes[ev.ID]
+	if !ok {
+		return o, ErrNotAvailable
+	}
+	return o, nil
+}
@@ -0,0 +1,177 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Median is a data source for numeric events that queries several
+// underlying sources, drops values that deviate too far from the median
+// of all responses, and returns the median of the rest. It fails unless at
+// least Quorum sources agree, so a single failing or manipulated source
+// can't skew the outcome.
+type Median struct {
+	id      string
+	sources []DataSource
+
+	// Quorum is the minimum number of sources that must respond and
+	// agree. It defaults to a majority of the sources.
+	Quorum int
+
+	// MaxDeviation is the largest relative distance from the median, for
+	// instance 0.01 for 1%, a value may have without being dropped as an
+	// outlier. Zero keeps all values.
+	MaxDeviation float64
+}
+
+// NewMedian returns a median data source over sources, requiring a
+// majority of them to agree
+func NewMedian(id string, sources ...DataSource) *Median {
+	return &Median{
+		id:      id,
+		sources: sources,
+		Quorum:  len(sources)/2 + 1,
+	}
+}
+
+// ID implements DataSource
+func (m *Median) ID() string {
+	return m.id
+}
+
+// Describe implements DataSource
+func (m *Median) Describe() string {
+	ids := make([]string, len(m.sources))
+	for i, ds := range m.sources {
+		ids[i] = ds.ID()
+	}
+	return fmt.Sprintf("median of %s, %d of %d required", strings.Join(ids, ", "),
+		m.Quorum, len(m.sources))
+}
+
+// DisagreementError is returned by Median when too few of the values of
+// its sources are within MaxDeviation of their median. It wraps
+// ErrDisagreement.
+type DisagreementError struct {
+	// Values are the values the sources responded with, and Median
+	// their median, outliers included
+	Values []int64
+	Median int64
+
+	// Kept is the number of values within MaxDeviation of the median,
+	// fewer than Quorum
+	Kept, Quorum int
+	MaxDeviation float64
+}
+
+// Error implements error
+func (e *DisagreementError) Error() string {
+	return fmt.Sprintf("%v: only %d of %d values within %g of the median, %d required",
+		ErrDisagreement, e.Kept, len(e.Values), e.MaxDeviation, e.Quorum)
+}
+
+// Unwrap returns ErrDisagreement
+func (e *DisagreementError) Unwrap() error {
+	return ErrDisagreement
+}
+
+// FetchOutcome queries all sources concurrently and returns the median of
+// the values that aren't outliers, or a *DisagreementError if too many are
+func (m *Median) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	values, errs := m.fetchAll(ev)
+	if len(values) < m.Quorum {
+		return dlcoracle.Outcome{}, fmt.Errorf("only %d of %d sources responded, "+
+			"%d required: %s", len(values), len(m.sources), m.Quorum, strings.Join(errs, "; "))
+	}
+
+	kept := DropOutliers(values, m.MaxDeviation)
+	if len(kept) < m.Quorum {
+		return dlcoracle.Outcome{}, &DisagreementError{
+			Values:       values,
+			Median:       MedianOf(values),
+			Kept:         len(kept),
+			Quorum:       m.Quorum,
+			MaxDeviation: m.MaxDeviation,
+		}
+	}
+	return dlcoracle.Outcome{Value: MedianOf(kept)}, nil
+}
+
+func (m *Median) fetchAll(ev dlcoracle.Event) ([]int64, []string) {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var values []int64
+	var errs []string
+	for _, ds := range m.sources {
+		wg.Add(1)
+		go func(ds DataSource) {
+			defer wg.Done()
+			o, err := ds.FetchOutcome(ev)
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", ds.ID(), err))
+				return
+			}
+			values = append(values, o.Value)
+		}(ds)
+	}
+	wg.Wait()
+	sort.Strings(errs)
+	return values, errs
+}
+
+// MedianOf returns the median of values, rounding the mean of the two
+// middle values half away from zero for an even count. values must not
+// be empty.
+func MedianOf(values []int64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	a, b := sorted[n/2-1], sorted[n/2]
+	// a/2 + b/2 avoids overflowing, then add the rounded remainders
+	sum := a/2 + b/2
+	rem := a%2 + b%2
+	switch {
+	case rem >= 1:
+		sum++
+	case rem <= -1:
+		sum--
+	}
+	return sum
+}
+
+// DropOutliers returns the values whose relative distance from the median
+// of values is at most maxDeviation. A maxDeviation of zero keeps all
+// values.
+func DropOutliers(values []int64, maxDeviation float64) []int64 {
+	if maxDeviation == 0 || len(values) == 0 {
+		return values
+	}
+	median := float64(MedianOf(values))
+	var kept []int64
+	for _, v := range values {
+		dev := float64(v) - median
+		if dev < 0 {
+			dev = -dev
+		}
+		if median == 0 && dev == 0 || median != 0 && dev/abs(median) <= maxDeviation {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,78 @@
+package datasource
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+type fixedSource struct {
+	id    string
+	value int64
+	err   error
+}
+
+func (s fixedSource) ID() string       { return s.id }
+func (s fixedSource) Describe() string { return "fixed" }
+func (s fixedSource) FetchOutcome(dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{Value: s.value}, s.err
+}
+
+func TestMedianOf(t *testing.T) {
+	if m := MedianOf([]int64{5, 1, 3}); m != 3 {
+		t.Fatalf("unexpected median %d", m)
+	}
+	if m := MedianOf([]int64{1, 2, 3, 4}); m != 3 {
+		t.Fatalf("unexpected median %d", m)
+	}
+	if m := MedianOf([]int64{-1, -2}); m != -2 {
+		t.Fatalf("unexpected median %d", m)
+	}
+}
+
+func TestMedianDropsOutliers(t *testing.T) {
+	m := NewMedian("median",
+		fixedSource{id: "a", value: 100},
+		fixedSource{id: "b", value: 101},
+		fixedSource{id: "c", value: 99},
+		fixedSource{id: "d", value: 500},
+		fixedSource{id: "e", err: errors.New("down")},
+	)
+	m.Quorum = 3
+	m.MaxDeviation = 0.05
+	o, err := m.FetchOutcome(dlcoracle.Event{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Value != 100 {
+		t.Fatalf("unexpected outcome %d", o.Value)
+	}
+}
+
+func TestMedianQuorum(t *testing.T) {
+	m := NewMedian("median",
+		fixedSource{id: "a", value: 100},
+		fixedSource{id: "b", err: errors.New("down")},
+		fixedSource{id: "c", err: errors.New("down")},
+	)
+	_, err := m.FetchOutcome(dlcoracle.Event{})
+	if err == nil {
+		t.Fatal("returned an outcome without quorum")
+	}
+
+	m = NewMedian("median",
+		fixedSource{id: "a", value: 100},
+		fixedSource{id: "b", value: 200},
+		fixedSource{id: "c", value: 300},
+	)
+	m.MaxDeviation = 0.1
+	_, err = m.FetchOutcome(dlcoracle.Event{})
+	var de *DisagreementError
+	if !errors.As(err, &de) || !errors.Is(err, ErrDisagreement) {
+		t.Fatalf("expected a disagreement, got %v", err)
+	}
+	if de.Median != 200 || len(de.Values) != 3 || de.Kept != 1 || de.Quorum != 2 {
+		t.Fatalf("unexpected disagreement %+v", de)
+	}
+}
@@ -0,0 +1,368 @@
+// Package plugin runs data sources as separate programs, so outcome
+// fetchers can be written in Python, Node or any other language without
+// forking the oracle.
+//
+// The oracle starts the plugin's command with DLC_ORACLE_PLUGIN set to the
+// protocol version, 1, and talks to it over its standard input and output,
+// one JSON object per line. The plugin first writes its handshake:
+//
+//	{"protocol":1,"description":"match results from example.com"}
+//
+// after which it answers every request with a response carrying the
+// same id:
+//
+//	{"id":1,"method":"fetch_outcome","event":{"id":"final-2024","maturity":"2024-07-14T20:00:00Z","descriptor":{"type":"enum","outcomes":["home","away"]}}}
+//	{"id":1,"outcome":{"label":"home"}}
+//	{"id":2,"method":"ping"}
+//	{"id":2,"error":"api.example.com unreachable"}
+//
+// A fetch_outcome error with "not_available":true means the outcome isn't
+// known yet, so the scheduler retries later. Whatever the plugin writes to
+// standard error is logged. A plugin that exits is started again on the
+// next request, and one that doesn't answer in time is killed. Go programs
+// can implement a plugin with Serve.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// Protocol is the version of the plugin protocol
+const Protocol = 1
+
+// EnvVar is the environment variable the oracle sets to Protocol when
+// starting a plugin
+const EnvVar = "DLC_ORACLE_PLUGIN"
+
+// maxLineSize limits the length of the lines a plugin writes
+const maxLineSize = 1 << 20
+
+// ErrClosed is returned by a source used after Close
+var ErrClosed = errors.New("plugin closed")
+
+// Config describes how to run a plugin
+type Config struct {
+	ID          string
+	Description string
+
+	// Command is the program to run and its arguments
+	Command []string
+
+	// Env holds KEY=value settings added to the oracle's environment,
+	// for instance for API keys
+	Env []string
+
+	// Timeout bounds the handshake and every request, 30 seconds if zero
+	Timeout time.Duration
+
+	// Logger receives what the plugin writes to standard error
+	Logger dlcoracle.Logger
+}
+
+// Source is a data source implemented by a plugin
+type Source struct {
+	cfg Config
+
+	mtx    sync.Mutex
+	proc   *process
+	desc   string
+	nextID uint64
+	closed bool
+}
+
+// process is a running plugin
+type process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan []byte
+
+	// quit is closed once no more lines are read, and done once the
+	// plugin exited, with its error in err
+	quit chan struct{}
+	done chan struct{}
+	err  error
+}
+
+// handshake is the first line a plugin writes
+type handshake struct {
+	Protocol    int    `json:"protocol"`
+	Description string `json:"description"`
+}
+
+// Event is an event as sent to plugins
+type Event struct {
+	ID             string                    `json:"id"`
+	Maturity       time.Time                 `json:"maturity"`
+	MaturityHeight uint32                    `json:"maturity_height,omitempty"`
+	Descriptor     dlcoracle.EventDescriptor `json:"descriptor"`
+	Metadata       dlcoracle.EventMetadata   `json:"metadata"`
+}
+
+// request is a line the oracle writes to a plugin
+type request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	Event  *Event `json:"event,omitempty"`
+}
+
+// response is a line a plugin writes in answer to a request
+type response struct {
+	ID           uint64             `json:"id"`
+	Outcome      *dlcoracle.Outcome `json:"outcome,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	NotAvailable bool               `json:"not_available,omitempty"`
+}
+
+// New starts the plugin configured in cfg and returns it as a data source
+func New(cfg Config) (*Source, error) {
+	if cfg.ID == "" {
+		return nil, fmt.Errorf("data source needs an id")
+	}
+	if len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("plugin needs a command")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = dlcoracle.NopLogger()
+	}
+	s := &Source{cfg: cfg}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := s.running()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ID implements datasource.DataSource
+func (s *Source) ID() string {
+	return s.cfg.ID
+}
+
+// Describe implements datasource.DataSource
+func (s *Source) Describe() string {
+	if s.cfg.Description != "" {
+		return s.cfg.Description
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.desc != "" {
+		return s.desc
+	}
+	return "plugin " + strings.Join(s.cfg.Command, " ")
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *Source) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	resp, err := s.call(context.Background(), request{Method: "fetch_outcome", Event: &Event{
+		ID:             ev.ID,
+		Maturity:       ev.Maturity,
+		MaturityHeight: ev.MaturityHeight,
+		Descriptor:     ev.Descriptor,
+		Metadata:       ev.Metadata,
+	}})
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	if resp.Outcome == nil {
+		return dlcoracle.Outcome{}, fmt.Errorf("%s: response without an outcome", s.cfg.ID)
+	}
+	return *resp.Outcome, nil
+}
+
+// Ping implements datasource.Pinger
+func (s *Source) Ping(ctx context.Context) error {
+	_, err := s.call(ctx, request{Method: "ping"})
+	return err
+}
+
+// Close stops the plugin, killing it if it doesn't exit within the
+// timeout once its standard input is closed
+func (s *Source) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.closed = true
+	if s.proc == nil {
+		return nil
+	}
+	p := s.proc
+	s.proc = nil
+	p.stdin.Close()
+	close(p.quit)
+	select {
+	case <-p.done:
+	case <-time.After(s.cfg.Timeout):
+		p.cmd.Process.Kill()
+		<-p.done
+	}
+	return nil
+}
+
+// call sends req to the plugin, starting it if it isn't running, and
+// returns its response. Requests are sent one at a time.
+func (s *Source) call(ctx context.Context, req request) (response, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	p, err := s.running()
+	if err != nil {
+		return response{}, err
+	}
+	s.nextID++
+	req.ID = s.nextID
+	line, err := json.Marshal(req)
+	if err != nil {
+		return response{}, err
+	}
+	_, err = p.stdin.Write(append(line, '\n'))
+	if err != nil {
+		s.stop(p)
+		return response{}, fmt.Errorf("%s: %v", s.cfg.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	var resp response
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			<-p.done
+			s.stop(p)
+			return response{}, fmt.Errorf("%s: plugin exited: %v", s.cfg.ID, p.err)
+		}
+		err = json.Unmarshal(line, &resp)
+		if err == nil && resp.ID != req.ID {
+			err = fmt.Errorf("response to request %d instead of %d", resp.ID, req.ID)
+		}
+		if err != nil {
+			// The plugin's responses can't be matched to requests anymore
+			s.stop(p)
+			return response{}, fmt.Errorf("%s: %v", s.cfg.ID, err)
+		}
+	case <-ctx.Done():
+		s.stop(p)
+		return response{}, fmt.Errorf("%s: %s: %v", s.cfg.ID, req.Method, ctx.Err())
+	}
+
+	if resp.NotAvailable {
+		return response{}, datasource.ErrNotAvailable
+	}
+	if resp.Error != "" {
+		return response{}, fmt.Errorf("%s: %s", s.cfg.ID, resp.Error)
+	}
+	return resp, nil
+}
+
+// running returns the plugin's process, starting it if it isn't running.
+// The caller holds s.mtx.
+func (s *Source) running() (*process, error) {
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.proc != nil {
+		return s.proc, nil
+	}
+
+	cmd := exec.Command(s.cfg.Command[0], s.cfg.Command[1:]...)
+	cmd.Env = append(os.Environ(), s.cfg.Env...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", EnvVar, Protocol))
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", s.cfg.ID, err)
+	}
+	p := &process{
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan []byte),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	var output sync.WaitGroup
+	output.Add(2)
+	go func() {
+		defer output.Done()
+		defer close(p.lines)
+		sc := bufio.NewScanner(stdout)
+		sc.Buffer(nil, maxLineSize)
+		for sc.Scan() {
+			select {
+			case p.lines <- append([]byte(nil), sc.Bytes()...):
+			case <-p.quit:
+			}
+		}
+	}()
+	go func() {
+		defer output.Done()
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			s.cfg.Logger.Log(dlcoracle.LevelInfo, sc.Text(), dlcoracle.F("source", s.cfg.ID))
+		}
+	}()
+	go func() {
+		output.Wait()
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	var hs handshake
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			<-p.done
+			return nil, fmt.Errorf("%s: plugin exited before its handshake: %v", s.cfg.ID, p.err)
+		}
+		err = json.Unmarshal(line, &hs)
+		if err == nil && hs.Protocol != Protocol {
+			err = fmt.Errorf("plugin speaks protocol %d, expected %d", hs.Protocol, Protocol)
+		}
+	case <-time.After(s.cfg.Timeout):
+		err = fmt.Errorf("no handshake within %v", s.cfg.Timeout)
+	}
+	if err != nil {
+		s.stop(p)
+		return nil, fmt.Errorf("%s: %v", s.cfg.ID, err)
+	}
+	s.proc = p
+	s.desc = hs.Description
+	return p, nil
+}
+
+// stop kills the plugin's process, which is started again on the next
+// request. The caller holds s.mtx.
+func (s *Source) stop(p *process) {
+	p.cmd.Process.Kill()
+	p.stdin.Close()
+	close(p.quit)
+	<-p.done
+	if s.proc == p {
+		s.proc = nil
+	}
+	s.cfg.Logger.Log(dlcoracle.LevelWarn, "plugin stopped", dlcoracle.F("source", s.cfg.ID))
+}
@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// testSource is the data source of the test binary run as a plugin
+type testSource struct{}
+
+func (testSource) ID() string       { return "test" }
+func (testSource) Describe() string { return "test plugin" }
+
+func (testSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	fmt.Fprintln(os.Stderr, "fetching", ev.ID)
+	switch ev.ID {
+	case "final":
+		return dlcoracle.Outcome{Label: ev.Descriptor.Outcomes[0]}, nil
+	case "later":
+		return dlcoracle.Outcome{}, datasource.ErrNotAvailable
+	case "crash":
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Hour)
+	}
+	return dlcoracle.Outcome{}, errors.New("unknown event")
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(EnvVar) != "" {
+		switch os.Getenv("PLUGIN_TEST_MODE") {
+		case "mute":
+			time.Sleep(time.Hour)
+		case "protocol2":
+			fmt.Println(`{"protocol":2}`)
+			time.Sleep(time.Hour)
+		}
+		err := Serve(testSource{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type testLogger struct {
+	mtx  sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Log(level dlcoracle.Level, msg string, fields ...dlcoracle.Field) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.msgs = append(l.msgs, msg)
+}
+
+func (l *testLogger) logged(msg string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	for _, m := range l.msgs {
+		if m == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestPlugin(t *testing.T, env ...string) (*Source, *testLogger) {
+	t.Helper()
+	logger := &testLogger{}
+	s, err := New(Config{ID: "test", Command: []string{os.Args[0]}, Env: env, Timeout: time.Second, Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, logger
+}
+
+func TestFetchOutcome(t *testing.T) {
+	s, logger := newTestPlugin(t)
+	if s.Describe() != "test plugin" {
+		t.Fatalf("described as %q", s.Describe())
+	}
+	ev := dlcoracle.Event{ID: "final", Descriptor: dlcoracle.EventDescriptor{
+		Type: dlcoracle.EventTypeEnum, Outcomes: []string{"home", "away"}}}
+	o, err := s.FetchOutcome(ev)
+	if err != nil || o.Label != "home" {
+		t.Fatalf("fetched %+v %v", o, err)
+	}
+	err = s.Ping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev.ID = "later"
+	_, err = s.FetchOutcome(ev)
+	if err != datasource.ErrNotAvailable {
+		t.Fatalf("expected ErrNotAvailable, got %v", err)
+	}
+	ev.ID = "other"
+	_, err = s.FetchOutcome(ev)
+	if err == nil || !strings.Contains(err.Error(), "unknown event") {
+		t.Fatalf("expected the plugin's error, got %v", err)
+	}
+	// Standard error is read apart from the responses
+	for i := 0; !logger.logged("fetching other"); i++ {
+		if i == 100 {
+			t.Fatal("standard error wasn't logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Close()
+	_, err = s.FetchOutcome(ev)
+	if err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	s, _ := newTestPlugin(t)
+	ev := dlcoracle.Event{Descriptor: dlcoracle.EventDescriptor{
+		Type: dlcoracle.EventTypeEnum, Outcomes: []string{"home", "away"}}}
+
+	// A plugin that exits or hangs is started again for the next request
+	for _, id := range []string{"crash", "hang"} {
+		ev.ID = id
+		_, err := s.FetchOutcome(ev)
+		if err == nil {
+			t.Fatalf("%s: fetched an outcome", id)
+		}
+		ev.ID = "final"
+		o, err := s.FetchOutcome(ev)
+		if err != nil || o.Label != "home" {
+			t.Fatalf("after %s: fetched %+v %v", id, o, err)
+		}
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	_, err := New(Config{ID: "test", Command: []string{os.Args[0]}, Env: []string{"PLUGIN_TEST_MODE=mute"},
+		Timeout: 100 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "no handshake") {
+		t.Fatalf("expected a handshake timeout, got %v", err)
+	}
+	_, err = New(Config{ID: "test", Command: []string{"true"}})
+	if err == nil || !strings.Contains(err.Error(), "exited before its handshake") {
+		t.Fatalf("expected an early exit, got %v", err)
+	}
+	_, err = New(Config{ID: "test", Command: []string{os.Args[0]}, Env: []string{"PLUGIN_TEST_MODE=protocol2"}})
+	if err == nil || !strings.Contains(err.Error(), "protocol 2") {
+		t.Fatalf("expected a protocol mismatch, got %v", err)
+	}
+	os.Setenv(EnvVar, "2")
+	err = Serve(testSource{})
+	os.Unsetenv(EnvVar)
+	if err == nil {
+		t.Fatal("served another protocol")
+	}
+	err = Serve(testSource{})
+	if err != ErrNotPlugin {
+		t.Fatalf("expected ErrNotPlugin, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// ErrNotPlugin is returned by Serve in a program the oracle didn't start
+// as a plugin
+var ErrNotPlugin = errors.New("not started as a plugin: " + EnvVar + " is not set")
+
+// Serve answers the oracle's requests with ds over standard input and
+// output until the oracle closes standard input. Data sources
+// implementing datasource.Pinger answer pings too.
+func Serve(ds datasource.DataSource) error {
+	version, err := strconv.Atoi(os.Getenv(EnvVar))
+	if err != nil {
+		return ErrNotPlugin
+	}
+	if version != Protocol {
+		return fmt.Errorf("the oracle speaks protocol %d, expected %d", version, Protocol)
+	}
+	return serve(ds, os.Stdin, os.Stdout)
+}
+
+// serve answers the requests read from r with ds, writing the handshake
+// and responses to w
+func serve(ds datasource.DataSource, r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	err := enc.Encode(handshake{Protocol: Protocol, Description: ds.Describe()})
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxLineSize)
+	for sc.Scan() {
+		var req request
+		err := json.Unmarshal(sc.Bytes(), &req)
+		if err != nil {
+			return fmt.Errorf("invalid request: %v", err)
+		}
+		err = enc.Encode(handle(ds, req))
+		if err != nil {
+			return err
+		}
+	}
+	return sc.Err()
+}
+
+// handle answers a request with ds
+func handle(ds datasource.DataSource, req request) response {
+	resp := response{ID: req.ID}
+	var err error
+	switch req.Method {
+	case "fetch_outcome":
+		if req.Event == nil {
+			err = errors.New("request without an event")
+			break
+		}
+		var o dlcoracle.Outcome
+		o, err = ds.FetchOutcome(dlcoracle.Event{
+			ID:             req.Event.ID,
+			Maturity:       req.Event.Maturity,
+			MaturityHeight: req.Event.MaturityHeight,
+			Descriptor:     req.Event.Descriptor,
+			Metadata:       req.Event.Metadata,
+		})
+		if err == nil {
+			resp.Outcome = &o
+		}
+	case "ping":
+		if p, ok := ds.(datasource.Pinger); ok {
+			err = p.Ping(context.Background())
+		}
+	default:
+		err = fmt.Errorf("unknown method %q", req.Method)
+	}
+	if errors.Is(err, datasource.ErrNotAvailable) {
+		resp.NotAvailable = true
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
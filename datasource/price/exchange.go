@@ -0,0 +1,196 @@
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Pair is a trading pair such as BTC/USD
+type Pair struct {
+	Base  string
+	Quote string
+}
+
+// ParsePair parses a pair written as "BASE/QUOTE"
+func ParsePair(s string) (Pair, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Pair{}, fmt.Errorf("invalid pair %q, expected BASE/QUOTE", s)
+	}
+	return Pair{strings.ToUpper(parts[0]), strings.ToUpper(parts[1])}, nil
+}
+
+// String returns the pair as "BASE/QUOTE"
+func (p Pair) String() string {
+	return p.Base + "/" + p.Quote
+}
+
+// Exchange knows how to ask one exchange's public API for a spot price
+type Exchange interface {
+	// Name identifies the exchange
+	Name() string
+
+	// URL returns the API URL returning the ticker for pair
+	URL(pair Pair) string
+
+	// ParsePrice extracts the price from the API response as a decimal
+	// string
+	ParsePrice(body []byte) (string, error)
+}
+
+// Coinbase queries the Coinbase spot price API
+type Coinbase struct {
+	// BaseURL overrides https://api.coinbase.com
+	BaseURL string
+}
+
+// Name implements Exchange
+func (Coinbase) Name() string { return "coinbase" }
+
+// URL implements Exchange
+func (e Coinbase) URL(pair Pair) string {
+	return baseURL(e.BaseURL, "https://api.coinbase.com") +
+		fmt.Sprintf("/v2/prices/%s-%s/spot", pair.Base, pair.Quote)
+}
+
+// ParsePrice implements Exchange
+func (Coinbase) ParsePrice(body []byte) (string, error) {
+	var res struct {
+		Data struct {
+			Amount string `json:"amount"`
+		} `json:"data"`
+	}
+	err := json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+	return nonEmpty(res.Data.Amount)
+}
+
+// Kraken queries the Kraken public ticker API
+type Kraken struct {
+	// BaseURL overrides https://api.kraken.com
+	BaseURL string
+}
+
+// Name implements Exchange
+func (Kraken) Name() string { return "kraken" }
+
+// URL implements Exchange. Kraken calls bitcoin XBT.
+func (e Kraken) URL(pair Pair) string {
+	base := pair.Base
+	if base == "BTC" {
+		base = "XBT"
+	}
+	return baseURL(e.BaseURL, "https://api.kraken.com") +
+		fmt.Sprintf("/0/public/Ticker?pair=%s%s", base, pair.Quote)
+}
+
+// ParsePrice implements Exchange, returning the last trade price
+func (Kraken) ParsePrice(body []byte) (string, error) {
+	var res struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			Last []string `json:"c"`
+		} `json:"result"`
+	}
+	err := json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Error) > 0 {
+		return "", fmt.Errorf("kraken: %s", strings.Join(res.Error, ", "))
+	}
+	for _, t := range res.Result {
+		if len(t.Last) > 0 {
+			return nonEmpty(t.Last[0])
+		}
+	}
+	return "", fmt.Errorf("kraken: no ticker in response")
+}
+
+// Binance queries the Binance ticker price API. Binance has no USD
+// markets, so pairs are usually quoted in USDT.
+type Binance struct {
+	// BaseURL overrides https://api.binance.com
+	BaseURL string
+}
+
+// Name implements Exchange
+func (Binance) Name() string { return "binance" }
+
+// URL implements Exchange
+func (e Binance) URL(pair Pair) string {
+	return baseURL(e.BaseURL, "https://api.binance.com") +
+		fmt.Sprintf("/api/v3/ticker/price?symbol=%s%s", pair.Base, pair.Quote)
+}
+
+// ParsePrice implements Exchange
+func (Binance) ParsePrice(body []byte) (string, error) {
+	var res struct {
+		Price string `json:"price"`
+	}
+	err := json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+	return nonEmpty(res.Price)
+}
+
+// Bitstamp queries the Bitstamp ticker API
+type Bitstamp struct {
+	// BaseURL overrides https://www.bitstamp.net
+	BaseURL string
+}
+
+// Name implements Exchange
+func (Bitstamp) Name() string { return "bitstamp" }
+
+// URL implements Exchange
+func (e Bitstamp) URL(pair Pair) string {
+	return baseURL(e.BaseURL, "https://www.bitstamp.net") +
+		fmt.Sprintf("/api/v2/ticker/%s%s/", strings.ToLower(pair.Base),
+			strings.ToLower(pair.Quote))
+}
+
+// ParsePrice implements Exchange, returning the last trade price
+func (Bitstamp) ParsePrice(body []byte) (string, error) {
+	var res struct {
+		Last string `json:"last"`
+	}
+	err := json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+	return nonEmpty(res.Last)
+}
+
+// ExchangeByName returns the built-in exchange with the given name
+func ExchangeByName(name string) (Exchange, error) {
+	switch strings.ToLower(name) {
+	case "coinbase":
+		return Coinbase{}, nil
+	case "kraken":
+		return Kraken{}, nil
+	case "binance":
+		return Binance{}, nil
+	case "bitstamp":
+		return Bitstamp{}, nil
+	}
+	return nil, fmt.Errorf("unknown exchange %q", name)
+}
+
+func baseURL(override, def string) string {
+	if override != "" {
+		return strings.TrimSuffix(override, "/")
+	}
+	return def
+}
+
+func nonEmpty(price string) (string, error) {
+	if price == "" {
+		return "", fmt.Errorf("no price in response")
+	}
+	return price, nil
+}
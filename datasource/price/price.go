@@ -0,0 +1,166 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// maxResponseSize limits how much of an API response is read
+const maxResponseSize = 1 << 20
+
+// Source is a data source attesting to the spot price of a trading pair
+// on one exchange. Prices are attested as integers: the price multiplied
+// by 10^Precision and rounded to the nearest integer.
+type Source struct {
+	id        string
+	exchange  Exchange
+	pair      Pair
+	precision int
+	client    *http.Client
+
+	// maxAge is how old a polled price may be to be used for an outcome
+	maxAge time.Duration
+
+	mtx    sync.Mutex
+	last   int64
+	lastAt time.Time
+}
+
+// NewSource returns a data source for pair on exchange, attesting prices
+// with precision decimal places
+func NewSource(id string, exchange Exchange, pair Pair, precision int) *Source {
+	return &Source{
+		id:        id,
+		exchange:  exchange,
+		pair:      pair,
+		precision: precision,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetHTTPClient replaces the HTTP client used to query the exchange
+func (s *Source) SetHTTPClient(c *http.Client) {
+	s.client = c
+}
+
+// ID implements datasource.DataSource
+func (s *Source) ID() string {
+	return s.id
+}
+
+// Describe implements datasource.DataSource
+func (s *Source) Describe() string {
+	return fmt.Sprintf("%s spot price on %s, %d decimals", s.pair, s.exchange.Name(),
+		s.precision)
+}
+
+// Price queries the exchange for the current price
+func (s *Source) Price(ctx context.Context) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.exchange.URL(s.pair), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: HTTP %d", s.exchange.Name(), resp.StatusCode)
+	}
+	str, err := s.exchange.ParsePrice(body)
+	if err != nil {
+		return 0, err
+	}
+	return ScaleDecimal(str, s.precision)
+}
+
+// Poll queries the price every interval until ctx is cancelled. While
+// polling, FetchOutcome uses the last price if it is at most maxAge old
+// instead of querying the exchange at maturity.
+func (s *Source) Poll(ctx context.Context, interval, maxAge time.Duration) {
+	s.mtx.Lock()
+	s.maxAge = maxAge
+	s.mtx.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p, err := s.Price(ctx)
+		if err == nil {
+			s.mtx.Lock()
+			s.last, s.lastAt = p, time.Now()
+			s.mtx.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ping implements datasource.Pinger. While polling, a price recent enough
+// to be used for an outcome shows the exchange is reachable; otherwise
+// the exchange is queried.
+func (s *Source) Ping(ctx context.Context) error {
+	s.mtx.Lock()
+	fresh := !s.lastAt.IsZero() && time.Since(s.lastAt) <= s.maxAge
+	s.mtx.Unlock()
+	if fresh {
+		return nil
+	}
+	_, err := s.Price(ctx)
+	return err
+}
+
+// FetchOutcome implements datasource.DataSource with the current price
+func (s *Source) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	s.mtx.Lock()
+	if !s.lastAt.IsZero() && time.Since(s.lastAt) <= s.maxAge {
+		p := s.last
+		s.mtx.Unlock()
+		return dlcoracle.Outcome{Value: p}, nil
+	}
+	s.mtx.Unlock()
+
+	p, err := s.Price(context.Background())
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	return dlcoracle.Outcome{Value: p}, nil
+}
+
+// ScaleDecimal converts a decimal string to an integer number of
+// 10^-precision units, rounding half away from zero
+func ScaleDecimal(s string, precision int) (int64, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid decimal %q", s)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+
+	// round half away from zero: trunc(r + sign(r)/2)
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		half.Neg(half)
+	}
+	r.Add(r, half)
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("decimal %q out of range", s)
+	}
+	return n.Int64(), nil
+}
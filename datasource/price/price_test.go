@@ -0,0 +1,90 @@
+package price
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestScaleDecimal(t *testing.T) {
+	tests := []struct {
+		in        string
+		precision int
+		out       int64
+	}{
+		{"12345.678", 2, 1234568},
+		{"12345.674", 2, 1234567},
+		{"1", 0, 1},
+		{"0.5", 0, 1},
+		{"-0.5", 0, -1},
+		{"42000", 0, 42000},
+	}
+	for _, tc := range tests {
+		got, err := ScaleDecimal(tc.in, tc.precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.out {
+			t.Fatalf("ScaleDecimal(%s, %d) = %d, expected %d", tc.in, tc.precision, got, tc.out)
+		}
+	}
+	_, err := ScaleDecimal("abc", 0)
+	if err == nil {
+		t.Fatal("parsed invalid decimal")
+	}
+}
+
+func TestExchanges(t *testing.T) {
+	responses := map[string]string{
+		"/v2/prices/BTC-USD/spot": `{"data":{"amount":"100.25","base":"BTC","currency":"USD"}}`,
+		"/0/public/Ticker":        `{"error":[],"result":{"XXBTZUSD":{"c":["100.25","1"]}}}`,
+		"/api/v3/ticker/price":    `{"symbol":"BTCUSD","price":"100.25"}`,
+		"/api/v2/ticker/btcusd/":  `{"last":"100.25"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	pair, err := ParsePair("btc/usd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exchanges := []Exchange{
+		Coinbase{BaseURL: ts.URL},
+		Kraken{BaseURL: ts.URL},
+		Binance{BaseURL: ts.URL},
+		Bitstamp{BaseURL: ts.URL},
+	}
+	for _, e := range exchanges {
+		s := NewSource(e.Name(), e, pair, 1)
+		o, err := s.FetchOutcome(dlcoracle.Event{ID: "event"})
+		if err != nil {
+			t.Fatalf("%s: %v", e.Name(), err)
+		}
+		if o.Value != 1003 {
+			t.Fatalf("%s: unexpected price %d", e.Name(), o.Value)
+		}
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+	s := NewSource("cb", Coinbase{BaseURL: ts.URL}, Pair{"BTC", "USD"}, 0)
+	_, err := s.FetchOutcome(dlcoracle.Event{})
+	if err == nil {
+		t.Fatal("expected an error for HTTP 404")
+	}
+	if s.Ping(context.Background()) == nil {
+		t.Fatal("unreachable exchange pinged")
+	}
+}
@@ -2,17 +2,11 @@ package dlcoracle
 
 import (
 	"bytes"
-	"crypto/rand"
 	"encoding/binary"
 	"fmt"
-	"math/big"
+	"sync"
 
-	"github.com/adiabat/btcd/btcec"
-	"github.com/adiabat/btcd/chaincfg/chainhash"
-)
-
-var (
-	bigZero = new(big.Int).SetInt64(0)
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
 )
 
 // GenerateNumericMessage returns a zero-padded message
@@ -29,22 +23,7 @@ func GenerateNumericMessage(value uint64) []byte {
 
 // PublicKeyFromPrivateKey derives the public key to a private key
 func PublicKeyFromPrivateKey(privateKey [32]byte) [33]byte {
-	var pubKey [33]byte
-
-	_, pk := btcec.PrivKeyFromBytes(btcec.S256(), privateKey[:])
-	copy(pubKey[:], pk.SerializeCompressed())
-	return pubKey
-}
-
-// GenerateOneTimeSigningKey will return a new random private scalar
-// to be used when signing a new message
-func GenerateOneTimeSigningKey() ([32]byte, error) {
-	var privKey [32]byte
-	_, err := rand.Read(privKey[:])
-	if err != nil {
-		return privKey, err
-	}
-	return privKey, nil
+	return arithmetic().baseMult(privateKey)
 }
 
 // ComputeSignaturePubKey calculates the signature multipled by the generator
@@ -54,118 +33,139 @@ func GenerateOneTimeSigningKey() ([32]byte, error) {
 // to the oracle's possible signatures beforehand. Can be calculated with just
 // public keys, so by anyone.
 func ComputeSignaturePubKey(oraclePubA, oraclePubR [33]byte, message []byte) ([33]byte, error) {
-	var returnValue [33]byte
-
-	// Hardcode curve
-	curve := btcec.S256()
+	return computeSignaturePubKey(oraclePubA, oraclePubR, message, SHA256)
+}
 
-	A, err := btcec.ParsePubKey(oraclePubA[:], curve)
+// computeSignaturePubKey is ComputeSignaturePubKey with challenge hash h
+func computeSignaturePubKey(oraclePubA, oraclePubR [33]byte, message []byte, h HashFunc) ([33]byte, error) {
+	p, err := newSignaturePointer(oraclePubA, oraclePubR, h)
 	if err != nil {
-		return returnValue, err
+		return [33]byte{}, err
 	}
+	return p.point(message)
+}
 
-	R, err := btcec.ParsePubKey(oraclePubR[:], curve)
+// signaturePointer computes the signature points of messages under one
+// public key and R point, parsed once for a whole batch
+type signaturePointer struct {
+	A, R  btcecv2.JacobianPoint
+	h     HashFunc
+	table *pointTable
+}
+
+func newSignaturePointer(oraclePubA, oraclePubR [33]byte, h HashFunc) (*signaturePointer, error) {
+	p := &signaturePointer{h: h}
+	err := parseJacobian("oracle pubkey", oraclePubA, &p.A)
 	if err != nil {
-		return returnValue, err
+		return nil, err
 	}
-
-	// e = Hash(messageType, oraclePubQ)
-	var hashInput []byte
-	hashInput = append(message, R.X.Bytes()...)
-	e := chainhash.HashB(hashInput)
-
-	bigE := new(big.Int).SetBytes(e)
-
-	if bigE.Cmp(curve.N) >= 0 {
-		return returnValue, fmt.Errorf("hash of (msg, pubR) too big")
+	err = parseJacobian("r point", oraclePubR, &p.R)
+	if err != nil {
+		return nil, err
 	}
-
-	// e * B
-	A.X, A.Y = curve.ScalarMult(A.X, A.Y, e)
-
-	A.Y.Neg(A.Y)
-
-	A.Y.Mod(A.Y, curve.P)
-
-	P := new(btcec.PublicKey)
-
-	// add to R
-	P.X, P.Y = curve.Add(A.X, A.Y, R.X, R.Y)
-	copy(returnValue[:], P.SerializeCompressed())
-	return returnValue, nil
+	return p, nil
 }
 
-// ComputeSignature Computes the signature for an arbitrary message based on two private scalars:
-// The one-time signing key and the oracle's private key.
-func ComputeSignature(privKey, oneTimeSigningKey [32]byte, message []byte) ([32]byte, error) {
-	var empty, s [32]byte
-
-	// Hardcode curve
-	curve := btcec.S256()
-
-	bigPriv := new(big.Int).SetBytes(privKey[:])
-	privKey = empty
-	bigK := new(big.Int).SetBytes(oneTimeSigningKey[:])
+// precompute builds a table of multiples of A for a batch of n messages,
+// if it is big enough and the secp256k1 backend is in use. release
+// returns the table to its pool.
+func (p *signaturePointer) precompute(n int) {
+	if _, ok := arithmetic().(secp256k1Curve); !ok || n < minTableBatch {
+		return
+	}
+	p.table = tablePool.Get().(*pointTable)
+	p.table.build(&p.A)
+}
 
-	if bigPriv.Cmp(bigZero) == 0 {
-		return empty, fmt.Errorf("priv scalar is zero")
+func (p *signaturePointer) release() {
+	if p.table != nil {
+		tablePool.Put(p.table)
+		p.table = nil
 	}
-	if bigPriv.Cmp(curve.N) >= 0 {
-		return empty, fmt.Errorf("priv scalar is out of bounds")
+}
+
+// point returns R - eA with e the challenge hash of message
+func (p *signaturePointer) point(message []byte) ([33]byte, error) {
+	e, err := challenge(p.h, message, &p.R.X)
+	if err != nil {
+		return [33]byte{}, err
 	}
-	if bigK.Cmp(bigZero) == 0 {
-		return empty, fmt.Errorf("k scalar is zero")
+	if p.table != nil {
+		var sum btcecv2.JacobianPoint
+		p.table.sum(&p.R, &e, &sum)
+		if isInfinity(&sum) {
+			return [33]byte{}, fmt.Errorf("signature point: %w", ErrPointAtInfinity)
+		}
+		return serializeJacobian(&sum), nil
 	}
-	if bigK.Cmp(curve.N) >= 0 {
-		return empty, fmt.Errorf("k scalar is out of bounds")
+	// a copy, so that only this path moves e to the heap
+	e2 := e
+	P, ok := arithmetic().signaturePoint(&p.A, &p.R, &e2)
+	if !ok {
+		return [33]byte{}, fmt.Errorf("signature point: %w", ErrPointAtInfinity)
 	}
+	return P, nil
+}
 
-	// re-derive R = kG
-	var Rx *big.Int
-	Rx, _ = curve.ScalarBaseMult(oneTimeSigningKey[:])
-
-	// Ry is always even.  Make it even if it's not.
-	//	if Ry.Bit(0) == 1 {
-	//		bigK.Mod(bigK, curve.N)
-	//		bigK.Sub(curve.N, bigK)
-	//	}
-
-	// e = Hash(r, m)
+// sum sets result to R - eA in Jacobian form, for batches converting
+// their points to affine form together. It needs the table.
+func (p *signaturePointer) sum(message []byte, result *btcecv2.JacobianPoint) error {
+	e, err := challenge(p.h, message, &p.R.X)
+	if err != nil {
+		return err
+	}
+	p.table.sum(&p.R, &e, result)
+	return nil
+}
 
-	e := chainhash.HashB(append(message[:], Rx.Bytes()...))
-	bigE := new(big.Int).SetBytes(e)
+// challengeBufs holds buffers for the data challenge hashes
+var challengeBufs = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 64)
+		return &b
+	},
+}
 
-	// If the hash is bigger than N, fail.  Note that N is
-	// FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141
-	// So this happens about once every 2**128 signatures.
-	if bigE.Cmp(curve.N) >= 0 {
-		return empty, fmt.Errorf("hash of (m, R) too big")
+// challenge returns e = h(message || R.x). LIT hashes R's X coordinate
+// without leading zero bytes, as math/big encoded it, and so does this
+// to keep its signatures.
+func challenge(h HashFunc, message []byte, rx *btcecv2.FieldVal) (btcecv2.ModNScalar, error) {
+	var e btcecv2.ModNScalar
+	var x [32]byte
+	rx.PutBytes(&x)
+	trimmed := x[:]
+	for len(trimmed) > 0 && trimmed[0] == 0 {
+		trimmed = trimmed[1:]
 	}
-	//	fmt.Printf("e: %x\n", e)
-	// s = k + e*a
-	bigS := new(big.Int)
-	// e*a
-	bigS.Mul(bigE, bigPriv)
-	// k + (e*a)
-	bigS.Sub(bigK, bigS)
-	bigS.Mod(bigS, curve.N)
-
-	// check if s is 0, and fail if it is.  Can't see how this would happen;
-	// looks like it would happen about once every 2**256 signatures
-	if bigS.Cmp(bigZero) == 0 {
-		str := fmt.Errorf("sig s %v is zero", bigS)
-		return empty, str
+	buf := challengeBufs.Get().(*[]byte)
+	data := append(append((*buf)[:0], message...), trimmed...)
+	digest := h(data)
+	*buf = data
+	challengeBufs.Put(buf)
+	if e.SetBytes(&digest) != 0 {
+		return e, ErrHashOutOfRange
 	}
+	return e, nil
+}
 
-	// Zero out private key and k in array and bigint form
-	// who knows if this really helps...  can't hurt though.
-	bigK.SetInt64(0)
-	oneTimeSigningKey = empty
-	bigPriv.SetInt64(0)
-
-	byteOffset := (256 - bigS.BitLen()) / 8
-
-	copy(s[byteOffset:], bigS.Bytes())
+// VerifySignature checks that sig is the signature of message under the
+// oracle's public key A and the R point of the one-time signing key used.
+func VerifySignature(oraclePubA, oraclePubR [33]byte, message []byte, sig [32]byte) error {
+	expected, err := ComputeSignaturePubKey(oraclePubA, oraclePubR, message)
+	if err != nil {
+		return err
+	}
+	if PublicKeyFromPrivateKey(sig) != expected {
+		return fmt.Errorf("%w: does not match message", ErrInvalidSignature)
+	}
+	return nil
+}
 
-	return s, nil
+// VerifyMessage checks a signature produced by SignMessage
+func VerifyMessage(pubKey [33]byte, message []byte, sig [65]byte) error {
+	var R [33]byte
+	var s [32]byte
+	copy(R[:], sig[:33])
+	copy(s[:], sig[33:])
+	return VerifySignature(pubKey, R, message, s)
 }
@@ -0,0 +1,81 @@
+//go:build !verifyonly && !nolegacycurve
+
+package dlcoracle
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/adiabat/btcd/btcec"
+	"github.com/adiabat/btcd/chaincfg/chainhash"
+)
+
+// The signatures are cross-checked against the adiabat/btcd fork, which
+// isn't compiled in with the nolegacycurve tag
+
+// referenceSignature is the math/big computation ComputeSignature used
+// before, s = k - e*a mod N
+func referenceSignature(priv, k [32]byte, message []byte) [32]byte {
+	curve := btcec.S256()
+	Rx, _ := curve.ScalarBaseMult(k[:])
+	e := new(big.Int).SetBytes(chainhash.HashB(append(append([]byte(nil), message...), Rx.Bytes()...)))
+	s := new(big.Int).Mul(e, new(big.Int).SetBytes(priv[:]))
+	s.Sub(new(big.Int).SetBytes(k[:]), s)
+	s.Mod(s, curve.N)
+	var sig [32]byte
+	s.FillBytes(sig[:])
+	return sig
+}
+
+func TestComputeSignatureReference(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		var priv, k [32]byte
+		rand.Read(priv[:])
+		rand.Read(k[:])
+		msg := GenerateNumericMessage(uint64(i))
+		sig, err := ComputeSignature(priv, k, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != referenceSignature(priv, k, msg) {
+			t.Fatalf("signature differs from reference for priv %x, k %x", priv, k)
+		}
+		err = VerifySignature(PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k), msg, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// R points whose X coordinate starts with a zero byte are hashed without
+// it, as by LIT
+func TestComputeSignatureLeadingZeroRx(t *testing.T) {
+	var priv, k [32]byte
+	priv[31] = 3
+	for i := 1; ; i++ {
+		binary.BigEndian.PutUint32(k[28:], uint32(i))
+		if PublicKeyFromPrivateKey(k)[1] == 0 {
+			break
+		}
+	}
+	msg := []byte("outcome")
+	sig, err := ComputeSignature(priv, k, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig != referenceSignature(priv, k, msg) {
+		t.Fatalf("signature differs from reference for k %x", k)
+	}
+	t.Cleanup(func() { SetBackend(defaultBackend) })
+	for _, b := range []Backend{BackendLegacy, BackendSecp256k1} {
+		if SetBackend(b) != nil {
+			continue
+		}
+		err = VerifySignature(PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k), msg, sig)
+		if err != nil {
+			t.Fatalf("%s: %v", b, err)
+		}
+	}
+}
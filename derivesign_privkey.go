@@ -0,0 +1,172 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// nonceDerivationTag separates derived one-time signing keys from other
+// uses of the oracle's private key as HMAC key
+const nonceDerivationTag = "DLC/oracle/nonce"
+
+// GenerateOneTimeSigningKey will return a new random private scalar
+// to be used when signing a new message. The entropy comes from
+// crypto/rand unless replaced with SetRandReader.
+func GenerateOneTimeSigningKey() ([32]byte, error) {
+	return GenerateOneTimeSigningKeyFrom(randReader())
+}
+
+// ComputeSignature Computes the signature for an arbitrary message based on two private scalars:
+// The one-time signing key and the oracle's private key.
+//
+// The scalar arithmetic uses the constant time ModNScalar of the secp256k1
+// package rather than math/big, whose run time depends on the bit lengths
+// of the secret scalars. Deriving R = kG still uses that package's table
+// based base point multiplication, which is not constant time, and which
+// the secp256k1 package has no constant time variant of. Signers that know
+// the announced R point use ComputeSignatureForPoint, which doesn't
+// multiply the key out again.
+func ComputeSignature(privKey, oneTimeSigningKey [32]byte, message []byte) ([32]byte, error) {
+	return computeSignature(privKey, oneTimeSigningKey, nil, message, SHA256)
+}
+
+// ComputeSignatureForPoint is ComputeSignature for the one-time signing
+// key whose point rPoint was announced. It takes the challenge from
+// rPoint instead of deriving R = kG from the key in variable time; only
+// public values are multiplied, to verify the signature against rPoint
+// and pubKey before it is returned, so a point that isn't the key's is
+// refused rather than signed for. pubKey has to be the oracle's own key,
+// not one read from a record the point came from.
+func ComputeSignatureForPoint(privKey, oneTimeSigningKey [32]byte, pubKey, rPoint [33]byte, message []byte) ([32]byte, error) {
+	sig, err := computeSignature(privKey, oneTimeSigningKey, &rPoint, message, SHA256)
+	if err != nil {
+		return sig, err
+	}
+	err = VerifySignature(pubKey, rPoint, message, sig)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("r point %x doesn't belong to the one-time signing key: %w", rPoint, err)
+	}
+	return sig, nil
+}
+
+// computeSignature is ComputeSignature with challenge hash h, for the R
+// point rPoint if it is known, or else R = kG
+func computeSignature(privKey, oneTimeSigningKey [32]byte, rPoint *[33]byte, message []byte, h HashFunc) ([32]byte, error) {
+	var empty [32]byte
+	var a, k, s btcecv2.ModNScalar
+
+	// Only whether the scalars are valid is branched on, which leaks
+	// nothing about valid keys
+	overflow := a.SetBytes(&privKey)
+	privKey = empty
+	defer a.Zero()
+	if a.IsZero() && overflow == 0 {
+		return empty, &ScalarError{Name: "priv", Err: ErrZeroScalar}
+	}
+	if overflow != 0 {
+		return empty, &ScalarError{Name: "priv", Err: ErrScalarOutOfRange}
+	}
+	overflow = k.SetBytes(&oneTimeSigningKey)
+	defer k.Zero()
+	if k.IsZero() && overflow == 0 {
+		return empty, &ScalarError{Name: "k", Err: ErrZeroScalar}
+	}
+	if overflow != 0 {
+		return empty, &ScalarError{Name: "k", Err: ErrScalarOutOfRange}
+	}
+
+	// re-derive R = kG unless it is given
+	var R btcecv2.JacobianPoint
+	if rPoint != nil {
+		err := parseJacobian("R", *rPoint, &R)
+		if err != nil {
+			return empty, err
+		}
+	} else {
+		btcecv2.ScalarBaseMultNonConst(&k, &R)
+		R.ToAffine()
+	}
+	oneTimeSigningKey = empty
+
+	// If the hash is bigger than N, fail.  Note that N is
+	// FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141
+	// So this happens about once every 2**128 signatures.
+	e, err := challenge(h, message, &R.X)
+	if err != nil {
+		return empty, err
+	}
+
+	// s = k - e*a
+	s.Mul2(&e, &a).Negate().Add(&k)
+
+	// check if s is 0, and fail if it is.  Can't see how this would happen;
+	// looks like it would happen about once every 2**256 signatures
+	if s.IsZero() {
+		return empty, &ScalarError{Name: "signature", Err: ErrZeroScalar}
+	}
+
+	logger().Log(LevelDebug, "computed signature",
+		F("message", fmt.Sprintf("%x", message)), F("r_x", fmt.Sprintf("%x", R.X.Bytes()[:])))
+	return s.Bytes(), nil
+}
+
+// SignMessage signs an arbitrary message with the oracle's private key,
+// using a one-time signing key synthesized from the private key, the
+// message and fresh randomness (see WithAuxRand and SetRandReader). The
+// returned signature is the R point of that key followed by the 32 byte
+// signature, so it can be verified with VerifyMessage without knowing R
+// beforehand. It must not be used to attest to event outcomes, whose R points are
+// committed to in announcements.
+func SignMessage(privKey [32]byte, message []byte, opts ...SignOption) ([65]byte, error) {
+	var sig [65]byte
+	k, err := newSignConfig(randReader(), opts).syntheticNonce(privKey, nil, message)
+	if err != nil {
+		return sig, err
+	}
+	R := PublicKeyFromPrivateKey(k)
+	s, err := computeSignature(privKey, k, &R, message, SHA256)
+	if err != nil {
+		return sig, err
+	}
+	copy(sig[:33], R[:])
+	copy(sig[33:], s[:])
+	return sig, nil
+}
+
+// DeriveOneTimeSigningKey deterministically derives the one-time signing
+// key with the given index from the oracle's private key, so the R points
+// of all events can be recomputed from the private key alone. Each index
+// must only ever be used for a single event.
+//
+// With WithAuxRand the key is synthesized from the index and auxiliary
+// randomness instead. It then can't be recomputed and has to be stored
+// until the event is attested.
+func DeriveOneTimeSigningKey(privKey [32]byte, index uint64, opts ...SignOption) ([32]byte, error) {
+	c := newSignConfig(nil, opts)
+	if c.aux != nil {
+		return c.syntheticIndexNonce(privKey, index)
+	}
+	var k [32]byte
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], index)
+
+	mac := hmac.New(sha256.New, privKey[:])
+	mac.Write([]byte(nonceDerivationTag))
+	mac.Write(buf[:])
+	copy(k[:], mac.Sum(nil))
+
+	// Like a hash bigger than N this happens about once every 2**128
+	// indexes; skipping the index is up to the caller
+	var s btcecv2.ModNScalar
+	defer s.Zero()
+	if s.SetBytes(&k) != 0 || s.IsZero() {
+		return [32]byte{}, &ScalarError{Name: fmt.Sprintf("derived key %d", index), Err: ErrScalarOutOfRange}
+	}
+	return k, nil
+}
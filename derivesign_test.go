@@ -0,0 +1,78 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"crypto/rand"
+	"testing"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+func TestComputeSignatureInvalidScalars(t *testing.T) {
+	var zero, one, n [32]byte
+	one[31] = 1
+	btcecv2.S256().N.FillBytes(n[:])
+	cases := []struct {
+		name    string
+		priv, k [32]byte
+	}{
+		{"zero private key", zero, one},
+		{"private key N", n, one},
+		{"zero k", one, zero},
+		{"k N", one, n},
+	}
+	for _, c := range cases {
+		_, err := ComputeSignature(c.priv, c.k, []byte("message"))
+		if err == nil {
+			t.Errorf("%s: accepted", c.name)
+		}
+	}
+}
+
+func TestComputeSignatureForPoint(t *testing.T) {
+	var priv, k, other [32]byte
+	priv[31], k[31], other[31] = 1, 2, 3
+	A, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	msg := GenerateNumericMessage(42)
+	want, err := ComputeSignature(priv, k, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := ComputeSignatureForPoint(priv, k, A, R, msg)
+	if err != nil || sig != want {
+		t.Fatalf("signature %x, want %x: %v", sig, want, err)
+	}
+
+	// A point that isn't the key's, or another oracle key, is refused
+	for _, c := range []struct{ A, R [33]byte }{
+		{A, PublicKeyFromPrivateKey(other)},
+		{PublicKeyFromPrivateKey(other), R},
+	} {
+		sig, err = ComputeSignatureForPoint(priv, k, c.A, c.R, msg)
+		if err == nil || sig != ([32]byte{}) {
+			t.Fatalf("signed for %x: %x", c.R, sig)
+		}
+	}
+}
+
+func BenchmarkComputeSignature(b *testing.B) {
+	var priv, k [32]byte
+	rand.Read(priv[:])
+	rand.Read(k[:])
+	msg := GenerateNumericMessage(42)
+	for i := 0; i < b.N; i++ {
+		ComputeSignature(priv, k, msg)
+	}
+}
+
+func BenchmarkComputeSignaturePubKey(b *testing.B) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	A, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	msg := GenerateNumericMessage(42)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ComputeSignaturePubKey(A, R, msg)
+	}
+}
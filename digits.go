@@ -0,0 +1,76 @@
+package dlcoracle
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// MaxDigitBase is the largest base of digits events, whose digits
+	// are encoded as one byte each
+	MaxDigitBase = 256
+
+	// MaxDigits is the largest number of digits of an event, enough for
+	// any int64 in base 2
+	MaxDigits = 63
+)
+
+// DigitMessage returns the message signed for a single digit, its value
+// in decimal as in the DLC specifications
+func DigitMessage(digit byte) []byte {
+	return []byte(strconv.Itoa(int(digit)))
+}
+
+// decompose returns the digits of value, most significant first
+func (d EventDescriptor) decompose(value int64) ([]byte, error) {
+	if value < 0 {
+		return nil, fmt.Errorf("digits outcome %d is negative", value)
+	}
+	digits := make([]byte, d.Digits)
+	v := uint64(value)
+	for i := len(digits) - 1; i >= 0; i-- {
+		digits[i] = byte(v % uint64(d.Base))
+		v /= uint64(d.Base)
+	}
+	if v != 0 {
+		return nil, fmt.Errorf("outcome %d doesn't fit %d digits of base %d", value, d.Digits, d.Base)
+	}
+	return digits, nil
+}
+
+// compose is the inverse of decompose
+func (d EventDescriptor) compose(digits []byte) (int64, error) {
+	if len(digits) != int(d.Digits) {
+		return 0, fmt.Errorf("digits message has %d digits, expected %d", len(digits), d.Digits)
+	}
+	var v uint64
+	for _, digit := range digits {
+		if uint32(digit) >= d.Base {
+			return 0, fmt.Errorf("digit %d out of range for base %d", digit, d.Base)
+		}
+		if v > (math.MaxInt64-uint64(digit))/uint64(d.Base) {
+			return 0, fmt.Errorf("digits outcome exceeds 63 bits")
+		}
+		v = v*uint64(d.Base) + uint64(digit)
+	}
+	return int64(v), nil
+}
+
+// verifyDigits checks the signatures of each digit of a digits event
+func verifyDigits(a Announcement, att Attestation) error {
+	if len(att.Message) == 0 || len(a.RPoints) != len(att.Message) || len(att.Signatures) != len(att.Message) {
+		return fmt.Errorf("%d digits with %d r points and %d signatures",
+			len(att.Message), len(a.RPoints), len(att.Signatures))
+	}
+	if a.RPoint != a.RPoints[0] || att.Signature != att.Signatures[0] {
+		return fmt.Errorf("first digit differs from r point or signature")
+	}
+	for i, digit := range att.Message {
+		err := VerifySignature(a.OraclePubKey, a.RPoints[i], DigitMessage(digit), att.Signatures[i])
+		if err != nil {
+			return fmt.Errorf("digit %d: %w", i, err)
+		}
+	}
+	return nil
+}
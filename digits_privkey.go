@@ -0,0 +1,103 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// digitKeyTag separates the one-time signing keys of digits from other
+// hashes of an event's one-time signing key
+const digitKeyTag = "DLC/oracle/digit"
+
+// DeriveDigitSigningKeys derives the one-time signing keys of the digits
+// of an event from the event's one-time signing key, so the oracle only
+// has to store a single key per event
+func DeriveDigitSigningKeys(oneTimeSigningKey [32]byte, digits int) ([][32]byte, error) {
+	err := checkScalar("k", oneTimeSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][32]byte, digits)
+	var buf [4]byte
+	for i := range keys {
+		binary.BigEndian.PutUint32(buf[:], uint32(i))
+		keys[i] = taggedHash(digitKeyTag, oneTimeSigningKey[:], buf[:])
+		// Like a hash bigger than N this happens about once every 2**128
+		// keys
+		var s btcecv2.ModNScalar
+		if s.SetBytes(&keys[i]) != 0 || s.IsZero() {
+			return nil, &ScalarError{Name: fmt.Sprintf("digit %d key", i), Err: ErrScalarOutOfRange}
+		}
+		s.Zero()
+	}
+	return keys, nil
+}
+
+// DigitRPoints returns the R points of the digits of an event, to
+// announce in Announcement.RPoints
+func DigitRPoints(oneTimeSigningKey [32]byte, digits int) ([][33]byte, error) {
+	return EventRPoints(oneTimeSigningKey, EventDescriptor{Type: EventTypeDigits, Digits: uint32(digits)})
+}
+
+// SignOutcome attests to outcome as the result of the event announced in
+// a, signed with the event's one-time signing key. Digits events are
+// decomposed and each digit signed with its own key from
+// DeriveDigitSigningKeys; other events get a single signature. The
+// outcome is checked against the descriptor, and the keys against the
+// announced R points.
+func SignOutcome(privKey, oneTimeSigningKey [32]byte, a Announcement, outcome Outcome) (Attestation, error) {
+	return signOutcome(privKey, oneTimeSigningKey, nil, a, outcome)
+}
+
+// SignOutcomeForPoints is SignOutcome for an oracle with public key
+// pubKey, signing with ComputeSignatureForPoint: the keys are checked
+// against the announced R points by verifying their signatures rather
+// than by deriving the points from them in variable time. pubKey has to
+// be the oracle's own key, not a.OraclePubKey.
+func SignOutcomeForPoints(privKey, oneTimeSigningKey [32]byte, pubKey [33]byte, a Announcement, outcome Outcome) (Attestation, error) {
+	return signOutcome(privKey, oneTimeSigningKey, &pubKey, a, outcome)
+}
+
+// signOutcome is SignOutcome, or SignOutcomeForPoints if pubKey is set
+func signOutcome(privKey, oneTimeSigningKey [32]byte, pubKey *[33]byte, a Announcement, outcome Outcome) (Attestation, error) {
+	msg, err := a.Descriptor.OutcomeMessage(outcome)
+	if err != nil {
+		return Attestation{}, err
+	}
+	keys, err := EventSigningKeys(oneTimeSigningKey, a.Descriptor)
+	if err != nil {
+		return Attestation{}, err
+	}
+	points := a.NoncePoints()
+	if len(points) != len(keys) {
+		return Attestation{}, fmt.Errorf("announcement of %s has %d r points for %d nonces",
+			a.EventID, len(points), len(keys))
+	}
+	digits := a.Descriptor.Type == EventTypeDigits
+	sigs := make([][32]byte, len(keys))
+	for i, k := range keys {
+		m := msg
+		if digits {
+			m = DigitMessage(msg[i])
+		}
+		if pubKey != nil {
+			sigs[i], err = ComputeSignatureForPoint(privKey, k, *pubKey, points[i], m)
+			if err != nil {
+				return Attestation{}, fmt.Errorf("nonce %d of %s: %w", i, a.EventID, err)
+			}
+			continue
+		}
+		if PublicKeyFromPrivateKey(k) != points[i] {
+			return Attestation{}, fmt.Errorf("nonce %d key does not match r point of %s", i, a.EventID)
+		}
+		sigs[i], err = ComputeSignature(privKey, k, m)
+		if err != nil {
+			return Attestation{}, err
+		}
+	}
+	return NewAttestation(a, outcome, sigs)
+}
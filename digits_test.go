@@ -0,0 +1,201 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testDigitsAnnouncement(t *testing.T, base, digits uint32) (Announcement, [32]byte, [32]byte) {
+	t.Helper()
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	rPoints, err := DigitRPoints(k, int(digits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Announcement{
+		EventID:      "price",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       rPoints[0],
+		RPoints:      rPoints,
+		Descriptor:   EventDescriptor{Type: EventTypeDigits, Base: base, Digits: digits},
+	}
+	err = a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, priv, k
+}
+
+func TestDecompose(t *testing.T) {
+	d := EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 5}
+	msg, err := d.OutcomeMessage(Outcome{Value: 1234})
+	if err != nil || string(msg) != "\x00\x01\x02\x03\x04" {
+		t.Fatalf("got %x, %v", msg, err)
+	}
+	o, err := d.ParseOutcome(msg)
+	if err != nil || o.Value != 1234 {
+		t.Fatalf("got %+v, %v", o, err)
+	}
+	_, err = d.OutcomeMessage(Outcome{Value: 100000})
+	if err == nil {
+		t.Fatal("value too large for the digits accepted")
+	}
+	_, err = d.ParseOutcome([]byte{0, 0, 0, 0, 10})
+	if err == nil {
+		t.Fatal("digit out of base accepted")
+	}
+
+	// 63 binary digits hold any int64
+	b := EventDescriptor{Type: EventTypeDigits, Base: 2, Digits: MaxDigits}
+	msg, err = b.OutcomeMessage(Outcome{Value: 1<<63 - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err = b.ParseOutcome(msg)
+	if err != nil || o.Value != 1<<63-1 {
+		t.Fatalf("got %+v, %v", o, err)
+	}
+	big := EventDescriptor{Type: EventTypeDigits, Base: 256, Digits: 9}
+	_, err = big.ParseOutcome([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0})
+	if err == nil {
+		t.Fatal("outcome beyond int64 accepted")
+	}
+
+	for _, bad := range []EventDescriptor{
+		{Type: EventTypeDigits, Base: 1, Digits: 3},
+		{Type: EventTypeDigits, Base: 10},
+		{Type: EventTypeNumeric, Base: 10, Digits: 3},
+	} {
+		if bad.Validate() == nil {
+			t.Fatalf("invalid descriptor %+v accepted", bad)
+		}
+	}
+}
+
+func TestSignOutcomeDigits(t *testing.T) {
+	a, priv, k := testDigitsAnnouncement(t, 10, 3)
+	err := a.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := SignOutcome(priv, k, a, Outcome{Value: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := VerifyAttestation(a, att)
+	if err != nil || outcome.Value != 42 {
+		t.Fatalf("got %+v, %v", outcome, err)
+	}
+
+	// Each digit's signature is the usual one of its decimal message
+	err = VerifySignature(a.OraclePubKey, a.RPoints[2], []byte("2"), att.Signatures[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The records survive JSON and parsing
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseAnnouncement(b)
+	if err != nil || parsed.SigningHash() != a.SigningHash() || parsed.Verify() != nil {
+		t.Fatalf("announcement changed: %v", err)
+	}
+	b, err = json.Marshal(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsedAtt, err := ParseAttestation(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyAttestation(parsed, parsedAtt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swapping digit signatures is caught
+	att.Signatures[1], att.Signatures[2] = att.Signatures[2], att.Signatures[1]
+	_, err = VerifyAttestation(a, att)
+	if !errors.Is(err, ErrInvalidAttestation) {
+		t.Fatalf("expected ErrInvalidAttestation, got %v", err)
+	}
+
+	// Missing signatures are rejected rather than indexed
+	att.Signatures = att.Signatures[:1]
+	_, err = VerifyAttestation(a, att)
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("expected ErrInvalidRecord, got %v", err)
+	}
+
+	// The R points are committed to
+	a.RPoints[2] = a.RPoints[1]
+	if a.Verify() == nil {
+		t.Fatal("changed r points verified")
+	}
+}
+
+func TestVerifyAttestationNoDigits(t *testing.T) {
+	a, _, _ := testDigitsAnnouncement(t, 10, 3)
+	a.Descriptor.Digits = 0
+	a.RPoints = nil
+	for _, att := range []Attestation{
+		{EventID: a.EventID},
+		{EventID: a.EventID, Message: []byte{1}, Signatures: [][32]byte{{1}}},
+	} {
+		_, err := VerifyAttestation(a, att)
+		if !errors.Is(err, ErrInvalidRecord) {
+			t.Fatalf("expected ErrInvalidRecord, got %v", err)
+		}
+	}
+}
+
+func TestSignOutcomeSingle(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	a := Announcement{
+		EventID:      "event",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       PublicKeyFromPrivateKey(k),
+		Descriptor:   EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	att, err := SignOutcome(priv, k, a, Outcome{Label: "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := VerifyAttestation(a, att)
+	if err != nil || outcome.Label != "no" || att.Signatures != nil {
+		t.Fatalf("got %+v, %v", att, err)
+	}
+	k[31] = 3
+	_, err = SignOutcome(priv, k, a, Outcome{Label: "no"})
+	if err == nil {
+		t.Fatal("signed with a key not matching the r point")
+	}
+	_, err = SignOutcomeForPoints(priv, k, a.OraclePubKey, a, Outcome{Label: "no"})
+	if err == nil {
+		t.Fatal("signed with a key not matching the r point")
+	}
+}
+
+func TestSignOutcomeForPoints(t *testing.T) {
+	a, priv, k := testDigitsAnnouncement(t, 10, 3)
+	want, err := SignOutcome(priv, k, a, Outcome{Value: 421})
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := SignOutcomeForPoints(priv, k, a.OraclePubKey, a, Outcome{Value: 421})
+	if err != nil || att.Signature != want.Signature || len(att.Signatures) != 3 || att.Signatures[2] != want.Signatures[2] {
+		t.Fatalf("got %+v, want %+v: %v", att, want, err)
+	}
+	a.RPoints[2] = a.RPoints[1]
+	_, err = SignOutcomeForPoints(priv, k, a.OraclePubKey, a, Outcome{Value: 421})
+	if err == nil {
+		t.Fatal("signed a digit for another digit's r point")
+	}
+}
@@ -0,0 +1,190 @@
+// Package discovery binds a domain to an oracle with a DNS TXT record, so
+// that clients can find the public key and endpoints of
+// oracle.example.com from the name alone, without exchanging the key out
+// of band.
+//
+// The record lives at _dlc-oracle.<domain> and reads
+//
+//	v=dlco1; pk=<hex public key>; ep=<URL>; ep=<URL>; sig=<hex signature>
+//
+// The oracle signs the domain and endpoints with its key, so a record
+// copied to another domain or with other endpoints doesn't verify. The
+// binding is only as trustworthy as the DNS answer, so resolvers should
+// validate DNSSEC.
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Prefix is the label the record is published under
+const Prefix = "_dlc-oracle"
+
+// Version starts every record
+const Version = "dlco1"
+
+// signTag separates record signatures from any other message signed with
+// the oracle's key
+const signTag = "DLC/oracle/dns"
+
+// maxString is the longest character string a TXT record holds; longer
+// values are split over several
+const maxString = 255
+
+// ErrNoRecord is returned when a domain publishes no oracle record
+var ErrNoRecord = errors.New("no oracle record")
+
+// Record binds Domain to the oracle with key PubKey serving at Endpoints
+type Record struct {
+	Domain    string
+	PubKey    [33]byte
+	Endpoints []string
+	Signature [65]byte
+}
+
+// Name returns the fully qualified name the record of domain is
+// published under
+func Name(domain string) string {
+	return Prefix + "." + canonical(domain) + "."
+}
+
+// canonical returns domain in lower case without a trailing dot
+func canonical(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// SigningHash returns the digest of the record's contents that the oracle
+// signs. The domain is committed to in canonical form.
+func (r Record) SigningHash() [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	write := func(s string) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.Write([]byte(s))
+	}
+	h.Write([]byte(signTag))
+	write(canonical(r.Domain))
+	h.Write(r.PubKey[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(len(r.Endpoints)))
+	h.Write(buf[:])
+	for _, e := range r.Endpoints {
+		write(e)
+	}
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// Verify checks the oracle's signature on the record
+func (r Record) Verify() error {
+	digest := r.SigningHash()
+	err := dlcoracle.VerifyMessage(r.PubKey, digest[:], r.Signature)
+	if err != nil {
+		return fmt.Errorf("record of %s: %w", r.Domain, err)
+	}
+	return nil
+}
+
+// check rejects records that can't be encoded
+func (r Record) check() error {
+	if canonical(r.Domain) == "" {
+		return fmt.Errorf("record without a domain")
+	}
+	for _, e := range r.Endpoints {
+		if e == "" || strings.ContainsAny(e, "; \t\"\\") {
+			return fmt.Errorf("invalid endpoint %q", e)
+		}
+	}
+	return nil
+}
+
+// String returns the text of the TXT record
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "v=%s; pk=%x", Version, r.PubKey)
+	for _, e := range r.Endpoints {
+		fmt.Fprintf(&b, "; ep=%s", e)
+	}
+	fmt.Fprintf(&b, "; sig=%x", r.Signature)
+	return b.String()
+}
+
+// TXT returns the text of the record split into the character strings of
+// a TXT record, which DNS limits to 255 bytes each
+func (r Record) TXT() []string {
+	s := r.String()
+	var parts []string
+	for len(s) > maxString {
+		parts = append(parts, s[:maxString])
+		s = s[maxString:]
+	}
+	return append(parts, s)
+}
+
+// ZoneEntry returns the record as a line of a zone file, with time to
+// live ttl in seconds
+func (r Record) ZoneEntry(ttl int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d IN TXT", Name(r.Domain), ttl)
+	for _, s := range r.TXT() {
+		fmt.Fprintf(&b, " %q", s)
+	}
+	return b.String()
+}
+
+// Parse decodes the text of a TXT record found for domain. It doesn't
+// verify the signature.
+func Parse(domain, txt string) (Record, error) {
+	r := Record{Domain: canonical(domain)}
+	tags := strings.Split(txt, ";")
+	for i, tag := range tags {
+		name, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok {
+			return r, fmt.Errorf("malformed tag %q", tag)
+		}
+		if i == 0 {
+			if name != "v" || value != Version {
+				return r, fmt.Errorf("not a %s record", Version)
+			}
+			continue
+		}
+		var err error
+		switch name {
+		case "pk":
+			err = decodeHex(r.PubKey[:], value)
+		case "ep":
+			r.Endpoints = append(r.Endpoints, value)
+		case "sig":
+			err = decodeHex(r.Signature[:], value)
+		}
+		// Unknown tags are ignored, so later versions can add some
+		if err != nil {
+			return r, fmt.Errorf("tag %s: %v", name, err)
+		}
+	}
+	if r.PubKey == ([33]byte{}) || r.Signature == ([65]byte{}) {
+		return r, fmt.Errorf("record without a key or signature")
+	}
+	return r, nil
+}
+
+func decodeHex(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("expected %d bytes, got %d", len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
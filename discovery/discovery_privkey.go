@@ -0,0 +1,22 @@
+//go:build !verifyonly
+
+package discovery
+
+import "github.com/mit-dci/dlc-oracle-go"
+
+// Sign signs the record with the oracle's private key and sets PubKey to
+// the matching public key
+func (r *Record) Sign(privKey [32]byte) error {
+	err := r.check()
+	if err != nil {
+		return err
+	}
+	r.PubKey = dlcoracle.PublicKeyFromPrivateKey(privKey)
+	digest := r.SigningHash()
+	sig, err := dlcoracle.SignMessage(privKey, digest[:])
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	return nil
+}
@@ -0,0 +1,170 @@
+//go:build !verifyonly
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func signedRecord(t *testing.T, priv [32]byte, domain string, endpoints ...string) Record {
+	t.Helper()
+	r := Record{Domain: domain, Endpoints: endpoints}
+	err := r.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRecord(t *testing.T) {
+	r := signedRecord(t, [32]byte{31: 1}, "Oracle.Example.com.", "https://oracle.example.com", "grpc://oracle.example.com:9090")
+	if r.PubKey != dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 1}) {
+		t.Fatal("sign didn't set the public key")
+	}
+	err := r.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := Parse("oracle.example.com", strings.Join(r.TXT(), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = parsed.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.PubKey != r.PubKey || len(parsed.Endpoints) != 2 || parsed.Endpoints[1] != r.Endpoints[1] {
+		t.Fatalf("parsed %+v", parsed)
+	}
+
+	// The signature binds the domain and the endpoints
+	moved, _ := Parse("oracle.example.org", r.String())
+	if moved.Verify() == nil {
+		t.Fatal("record verified for another domain")
+	}
+	parsed.Endpoints[0] = "https://evil.example.com"
+	if parsed.Verify() == nil {
+		t.Fatal("record verified with another endpoint")
+	}
+
+	for _, part := range r.TXT() {
+		if len(part) > 255 {
+			t.Fatalf("character string of %d bytes", len(part))
+		}
+	}
+	entry := r.ZoneEntry(3600)
+	if !strings.HasPrefix(entry, "_dlc-oracle.oracle.example.com. 3600 IN TXT \"v=dlco1; pk=") {
+		t.Fatalf("zone entry %s", entry)
+	}
+
+	err = (&Record{Domain: "example.com", Endpoints: []string{"https://a; sig=00"}}).Sign([32]byte{31: 1})
+	if err == nil {
+		t.Fatal("endpoint with a separator accepted")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	r := signedRecord(t, [32]byte{31: 1}, "example.com")
+	for _, txt := range []string{
+		"v=spf1 -all",
+		"v=dlco1; pk=02",
+		"v=dlco1; sig=" + strings.Repeat("00", 65),
+		"v=dlco1; pk; " + r.String()[len("v=dlco1; "):],
+	} {
+		_, err := Parse("example.com", txt)
+		if err == nil {
+			t.Errorf("%q accepted", txt)
+		}
+	}
+	_, err := Parse("example.com", r.String()+"; x=later")
+	if err != nil {
+		t.Fatalf("unknown tag rejected: %v", err)
+	}
+}
+
+// staticResolver answers TXT lookups from a map
+func staticResolver(records map[string][]string) *Resolver {
+	return &Resolver{lookupTXT: func(ctx context.Context, name string) ([]string, error) {
+		txts, ok := records[name]
+		if !ok {
+			return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+		}
+		return txts, nil
+	}}
+}
+
+func TestLookup(t *testing.T) {
+	a := signedRecord(t, [32]byte{31: 1}, "a.example.com", "https://a.example.com")
+	b := signedRecord(t, [32]byte{31: 2}, "a.example.com", "https://b.example.com")
+	forged := a
+	forged.Endpoints = []string{"https://evil.example.com"}
+	res := staticResolver(map[string][]string{
+		"_dlc-oracle.a.example.com.":      {"v=spf1 -all", a.String()},
+		"_dlc-oracle.forged.example.com.": {forged.String()},
+		"_dlc-oracle.two.example.com.":    {a.String(), b.String()},
+		"_dlc-oracle.other.example.com.":  {"google-site-verification=abc"},
+	})
+
+	got, err := res.Lookup(context.Background(), "A.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PubKey != a.PubKey || got.Endpoints[0] != "https://a.example.com" {
+		t.Fatalf("got %+v", got)
+	}
+	_, err = res.Lookup(context.Background(), "forged.example.com")
+	if !errors.Is(err, dlcoracle.ErrInvalidSignature) {
+		t.Fatalf("forged record: %v", err)
+	}
+	_, err = res.Lookup(context.Background(), "two.example.com")
+	if err == nil {
+		t.Fatal("records of two oracles accepted")
+	}
+	for _, domain := range []string{"other.example.com", "missing.example.com"} {
+		_, err = res.Lookup(context.Background(), domain)
+		if !errors.Is(err, ErrNoRecord) {
+			t.Fatalf("%s: %v", domain, err)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	priv := [32]byte{31: 1}
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	ann := dlcoracle.Announcement{EventID: "event", OraclePubKey: pub, RPoint: pub}
+	err := ann.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ann)
+	}))
+	defer srv.Close()
+
+	rec := signedRecord(t, priv, "example.com", "grpc://example.com:9090", srv.URL)
+	res := staticResolver(map[string][]string{"_dlc-oracle.example.com.": {rec.String()}})
+	c, err := res.Client(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PubKey() != pub {
+		t.Fatal("client not pinned to the record's key")
+	}
+	got, err := c.Announcement(context.Background(), "event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EventID != "event" {
+		t.Fatalf("announcement %+v", got)
+	}
+}
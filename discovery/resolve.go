@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go/client"
+)
+
+// Resolver looks up the oracle records of domains
+type Resolver struct {
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// NewResolver returns a resolver querying DNS with r, or the default
+// resolver if r is nil
+func NewResolver(r *net.Resolver) *Resolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &Resolver{lookupTXT: r.LookupTXT}
+}
+
+// Lookup returns the verified oracle record of domain. Other TXT records
+// are ignored. It fails with ErrNoRecord if there is none, and if records
+// name different keys, as the domain then doesn't identify one oracle.
+func (r *Resolver) Lookup(ctx context.Context, domain string) (Record, error) {
+	txts, err := r.lookupTXT(ctx, Name(domain))
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return Record{}, fmt.Errorf("%s: %w", domain, ErrNoRecord)
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	var found *Record
+	var invalid error
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, "v="+Version) {
+			continue
+		}
+		rec, err := Parse(domain, txt)
+		if err == nil {
+			err = rec.Verify()
+		}
+		if err != nil {
+			invalid = err
+			continue
+		}
+		if found != nil && found.PubKey != rec.PubKey {
+			return Record{}, fmt.Errorf("%s: records name different oracles", domain)
+		}
+		if found == nil {
+			found = &rec
+		}
+	}
+	if found == nil && invalid != nil {
+		return Record{}, invalid
+	}
+	if found == nil {
+		return Record{}, fmt.Errorf("%s: %w", domain, ErrNoRecord)
+	}
+	return *found, nil
+}
+
+// Client looks up the record of domain and returns a client for its
+// first HTTP endpoint, pinned to the key the record names
+func (r *Resolver) Client(ctx context.Context, domain string) (*client.Client, error) {
+	rec, err := r.Lookup(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range rec.Endpoints {
+		if strings.HasPrefix(e, "https://") || strings.HasPrefix(e, "http://") {
+			return client.New(client.NewHTTP(e), rec.PubKey), nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no HTTP endpoint", domain)
+}
@@ -0,0 +1,133 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// ecdsaAttestationTag separates ECDSA attestations from any other message
+// signed with the oracle's key
+const ecdsaAttestationTag = "DLC/oracle/ecdsa-attestation"
+
+// ecdsaScheme marks ECDSA attestations in JSON
+const ecdsaScheme = "ecdsa"
+
+// ECDSAAttestation is a standard ECDSA signature by the oracle's key over
+// the outcome of an event, for systems that can't verify the DLC scheme.
+// It is not an Attestation: its signature reveals nothing that settles a
+// DLC, and it is a distinct type so the two can't be mixed up.
+type ECDSAAttestation struct {
+	EventID string
+	Message []byte
+
+	// Signature is r followed by s, with s in the lower half of the
+	// order as Bitcoin requires
+	Signature [64]byte
+}
+
+// SigningHash returns the digest signed with ECDSA: the SHA-256 of a tag,
+// the length prefixed event ID and the message. Committing to the event
+// ID keeps an attestation from being replayed for another event.
+func (a ECDSAAttestation) SigningHash() [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	h.Write([]byte(ecdsaAttestationTag))
+	binary.BigEndian.PutUint64(buf[:], uint64(len(a.EventID)))
+	h.Write(buf[:])
+	h.Write([]byte(a.EventID))
+	h.Write(a.Message)
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// VerifyECDSAAttestation checks that att is an ECDSA signature by the
+// oracle of a possible outcome of the event announced in a, and returns
+// that outcome. The errors wrap the errors of VerifyAttestation, and the
+// announcement itself is not checked either.
+func VerifyECDSAAttestation(a Announcement, att ECDSAAttestation) (Outcome, error) {
+	if att.EventID != a.EventID {
+		return Outcome{}, fmt.Errorf("%w: attestation of %q, announcement of %q",
+			ErrEventMismatch, att.EventID, a.EventID)
+	}
+	outcome, err := a.Descriptor.ParseOutcome(att.Message)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: %s event %q: %v", ErrInvalidOutcome,
+			a.Descriptor.Type, a.EventID, err)
+	}
+	err = verifyECDSA(a.OraclePubKey, att.SigningHash(), att.Signature)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: event %q: %v", ErrInvalidAttestation, a.EventID, err)
+	}
+	return outcome, nil
+}
+
+func verifyECDSA(pubKey [33]byte, digest [32]byte, sig [64]byte) error {
+	pub, err := parsePubKey("oracle pubkey", pubKey)
+	if err != nil {
+		return err
+	}
+	var r, s btcecv2.ModNScalar
+	var rb, sb [32]byte
+	copy(rb[:], sig[:32])
+	copy(sb[:], sig[32:])
+	if r.SetBytes(&rb) != 0 || s.SetBytes(&sb) != 0 || r.IsZero() || s.IsZero() {
+		return fmt.Errorf("%w: scalar out of range", ErrInvalidSignature)
+	}
+	if s.IsOverHalfOrder() {
+		return fmt.Errorf("%w: s is not in the lower half of the order", ErrInvalidSignature)
+	}
+	if !ecdsa.NewSignature(&r, &s).Verify(digest[:], pub) {
+		return fmt.Errorf("%w: ecdsa signature does not match message", ErrInvalidSignature)
+	}
+	return nil
+}
+
+type ecdsaAttestationJSON struct {
+	Scheme    string `json:"scheme"`
+	EventID   string `json:"eventId"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// MarshalJSON encodes the attestation like an Attestation, with a scheme
+// field set to "ecdsa"
+func (a ECDSAAttestation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ecdsaAttestationJSON{
+		Scheme:    ecdsaScheme,
+		EventID:   a.EventID,
+		Message:   hex.EncodeToString(a.Message),
+		Signature: hex.EncodeToString(a.Signature[:]),
+	})
+}
+
+// UnmarshalJSON decodes an attestation encoded by MarshalJSON. It fails
+// if the scheme field isn't "ecdsa".
+func (a *ECDSAAttestation) UnmarshalJSON(b []byte) error {
+	var j ecdsaAttestationJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	if j.Scheme != ecdsaScheme {
+		return fmt.Errorf("not an ecdsa attestation, scheme %q", j.Scheme)
+	}
+	msg, err := hex.DecodeString(j.Message)
+	if err != nil {
+		return err
+	}
+	err = decodeHexFixed(a.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	a.EventID = j.EventID
+	a.Message = msg
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// SignECDSAAttestation signs message as the outcome of an event with
+// ECDSA, using RFC 6979 nonces. Signing different messages for the same
+// event doesn't leak the key as with the DLC scheme, but it is
+// equivocating all the same; only sign the message attested to.
+func SignECDSAAttestation(privKey [32]byte, eventID string, message []byte) (ECDSAAttestation, error) {
+	a := ECDSAAttestation{EventID: eventID, Message: message}
+	err := checkScalar("priv", privKey)
+	if err != nil {
+		return a, err
+	}
+	priv, _ := btcecv2.PrivKeyFromBytes(privKey[:])
+	defer priv.Zero()
+	digest := a.SigningHash()
+	compact, err := ecdsa.SignCompact(priv, digest[:], true)
+	if err != nil {
+		return a, err
+	}
+	// The first byte is the recovery code
+	copy(a.Signature[:], compact[1:])
+	return a, nil
+}
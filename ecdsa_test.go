@@ -0,0 +1,75 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestECDSAAttestation(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	a := Announcement{
+		EventID:      "ev",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		Maturity:     time.Unix(1000, 0),
+		Descriptor:   EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	att, err := SignECDSAAttestation(priv, "ev", []byte("yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := VerifyECDSAAttestation(a, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Label != "yes" {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+
+	// RFC 6979 nonces make signatures deterministic
+	again, err := SignECDSAAttestation(priv, "ev", []byte("yes"))
+	if err != nil || again.Signature != att.Signature {
+		t.Fatal("signature not deterministic")
+	}
+
+	b, err := json.Marshal(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"scheme":"ecdsa"`) {
+		t.Fatalf("scheme missing from %s", b)
+	}
+	var decoded ECDSAAttestation
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = VerifyECDSAAttestation(a, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A DLC attestation doesn't decode as an ECDSA one
+	plain, err := json.Marshal(Attestation{EventID: "ev", Message: []byte("yes")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(plain, &decoded)
+	if err == nil {
+		t.Fatal("attestation decoded as an ECDSA attestation")
+	}
+
+	// The signature commits to the event
+	moved := att
+	moved.EventID = "other"
+	a.EventID = "other"
+	_, err = VerifyECDSAAttestation(a, moved)
+	if !errors.Is(err, ErrInvalidAttestation) {
+		t.Fatalf("expected ErrInvalidAttestation, got %v", err)
+	}
+}
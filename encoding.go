@@ -0,0 +1,76 @@
+package dlcoracle
+
+import (
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+)
+
+// CompactSignature combines the R point and the 32 byte signature s into
+// the 64 byte layout of BIP-340 signatures, the X coordinate of R followed
+// by s. The layout can't represent an R with an odd Y, so it fails for
+// those; signatures made with ComputeSignatureXOnly always fit.
+func CompactSignature(rPoint [33]byte, sig [32]byte) ([64]byte, error) {
+	var b [64]byte
+	if !HasEvenY(rPoint) {
+		return b, fmt.Errorf("r point %x has an odd Y", rPoint)
+	}
+	copy(b[:32], rPoint[1:])
+	copy(b[32:], sig[:])
+	return b, nil
+}
+
+// ParseCompactSignature splits a signature encoded by CompactSignature
+// into its R point and s, checking that both are valid
+func ParseCompactSignature(b [64]byte) ([33]byte, [32]byte, error) {
+	var x, sig [32]byte
+	copy(x[:], b[:32])
+	copy(sig[:], b[32:])
+	rPoint, err := ParseXOnly(x)
+	if err != nil {
+		return rPoint, sig, err
+	}
+	var s btcecv2.ModNScalar
+	if s.SetBytes(&sig) != 0 {
+		return rPoint, sig, &ScalarError{Name: "signature", Err: ErrScalarOutOfRange}
+	}
+	return rPoint, sig, nil
+}
+
+// DERSignature returns the attestation's signature in the DER encoding
+// most ECDSA tooling expects
+func (a ECDSAAttestation) DERSignature() []byte {
+	var r, s btcecv2.ModNScalar
+	var rb, sb [32]byte
+	copy(rb[:], a.Signature[:32])
+	copy(sb[:], a.Signature[32:])
+	r.SetBytes(&rb)
+	s.SetBytes(&sb)
+	return ecdsa.NewSignature(&r, &s).Serialize()
+}
+
+// ParseDERSignature decodes a strictly DER encoded ECDSA signature into
+// the r and s layout of ECDSAAttestation.Signature
+func ParseDERSignature(der []byte) ([64]byte, error) {
+	var sig [64]byte
+	_, err := ecdsa.ParseDERSignature(der)
+	if err != nil {
+		return sig, err
+	}
+	// The encoding is valid, so it is 0x30 <len> 0x02 <len r> <r> 0x02
+	// <len s> <s>, where r and s have at most one zero byte of padding
+	rLen := int(der[3])
+	r := trimZero(der[4 : 4+rLen])
+	s := trimZero(der[6+rLen:])
+	copy(sig[32-len(r):32], r)
+	copy(sig[64-len(s):], s)
+	return sig, nil
+}
+
+func trimZero(b []byte) []byte {
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
@@ -0,0 +1,73 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"testing"
+)
+
+func TestCompactSignature(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 3
+	msg := []byte("message")
+	sig, err := ComputeSignatureXOnly(priv, k, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rPoint, err := ParseXOnly(XOnlyPublicKeyFromPrivateKey(k))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CompactSignature(rPoint, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := ParseCompactSignature(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != rPoint || s != sig {
+		t.Fatal("compact signature didn't round trip")
+	}
+	err = VerifySignature(PublicKeyFromPrivateKey(evenY(priv)), r, msg, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rPoint[0] = 0x03
+	_, err = CompactSignature(rPoint, sig)
+	if err == nil {
+		t.Fatal("odd R point encoded")
+	}
+	for i := range b[32:] {
+		b[32+i] = 0xff
+	}
+	_, _, err = ParseCompactSignature(b)
+	if err == nil {
+		t.Fatal("s out of range accepted")
+	}
+}
+
+func TestDERSignature(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	// Vary the message so r and s with high bits and leading zeros occur
+	for i := 0; i < 64; i++ {
+		att, err := SignECDSAAttestation(priv, "ev", GenerateNumericMessage(uint64(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		der := att.DERSignature()
+		sig, err := ParseDERSignature(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != att.Signature {
+			t.Fatalf("DER signature %x didn't round trip", der)
+		}
+	}
+	_, err := ParseDERSignature([]byte{0x30, 0x00})
+	if err == nil {
+		t.Fatal("invalid DER accepted")
+	}
+}
@@ -0,0 +1,57 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// maxScalarAttempts bounds how often GenerateOneTimeSigningKeyFrom reads
+// again after an invalid scalar. A working source yields one about every
+// 2**128 reads, so running out means the source is broken.
+const maxScalarAttempts = 8
+
+type readerHolder struct {
+	io.Reader
+}
+
+var packageRand atomic.Value
+
+func init() {
+	packageRand.Store(readerHolder{rand.Reader})
+}
+
+// SetRandReader sets the entropy source of GenerateOneTimeSigningKey and
+// the auxiliary randomness of SignMessage, such as a hardware RNG, or a
+// deterministic reader in tests. A nil reader restores crypto/rand.
+func SetRandReader(r io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	packageRand.Store(readerHolder{r})
+}
+
+func randReader() io.Reader {
+	return packageRand.Load().(readerHolder).Reader
+}
+
+// GenerateOneTimeSigningKeyFrom returns a new private scalar read from r,
+// reading again if the bytes aren't a valid scalar
+func GenerateOneTimeSigningKeyFrom(r io.Reader) ([32]byte, error) {
+	var key [32]byte
+	for i := 0; i < maxScalarAttempts; i++ {
+		_, err := io.ReadFull(r, key[:])
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("reading entropy: %w", err)
+		}
+		err = checkScalar("random key", key)
+		if err == nil {
+			return key, nil
+		}
+	}
+	return [32]byte{}, fmt.Errorf("entropy source returned %d invalid scalars: %w",
+		maxScalarAttempts, &ScalarError{Name: "random key", Err: ErrScalarOutOfRange})
+}
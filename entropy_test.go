@@ -0,0 +1,69 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestGenerateOneTimeSigningKeyFrom(t *testing.T) {
+	// Out of range bytes are skipped
+	src := append(bytes.Repeat([]byte{0xff}, 32), bytes.Repeat([]byte{7}, 32)...)
+	key, err := GenerateOneTimeSigningKeyFrom(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key[0] != 7 {
+		t.Fatalf("got %x", key)
+	}
+
+	_, err = GenerateOneTimeSigningKeyFrom(bytes.NewReader(make([]byte, 16)))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a short read, got %v", err)
+	}
+	_, err = GenerateOneTimeSigningKeyFrom(zeroReader{})
+	if !errors.Is(err, ErrScalarOutOfRange) {
+		t.Fatalf("expected a broken source to fail, got %v", err)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestSetRandReader(t *testing.T) {
+	defer SetRandReader(nil)
+	SetRandReader(bytes.NewReader(bytes.Repeat([]byte{3}, 32)))
+	key, err := GenerateOneTimeSigningKey()
+	if err != nil || key != [32]byte(bytes.Repeat([]byte{3}, 32)) {
+		t.Fatalf("got %x, %v", key, err)
+	}
+
+	// SignMessage draws its auxiliary randomness from the same source
+	var priv [32]byte
+	priv[31] = 1
+	SetRandReader(bytes.NewReader(make([]byte, 64)))
+	a, err := SignMessage(priv, []byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetRandReader(bytes.NewReader(make([]byte, 64)))
+	b, err := SignMessage(priv, []byte("msg"))
+	if err != nil || a != b {
+		t.Fatalf("signatures with the same randomness differ: %v", err)
+	}
+
+	SetRandReader(nil)
+	_, err = GenerateOneTimeSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+package dlcoracle
+
+import (
+	"errors"
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+var (
+	// ErrZeroScalar is wrapped by errors of private keys, one-time
+	// signing keys and signatures that are zero
+	ErrZeroScalar = errors.New("scalar is zero")
+
+	// ErrScalarOutOfRange is wrapped by errors of scalars that aren't
+	// below the curve order
+	ErrScalarOutOfRange = errors.New("scalar out of range")
+
+	// ErrInvalidPubKey is wrapped by errors of public keys and R points
+	// that aren't points on the curve
+	ErrInvalidPubKey = errors.New("invalid public key")
+
+	// ErrInvalidPrefix is wrapped by errors of public keys and R points
+	// whose first byte isn't 0x02 or 0x03
+	ErrInvalidPrefix = errors.New("invalid prefix")
+
+	// ErrNotOnCurve is wrapped by errors of public keys and R points
+	// whose X coordinate isn't that of a point on the curve
+	ErrNotOnCurve = errors.New("x not on curve")
+
+	// ErrHashOutOfRange is returned when the hash of a message and R
+	// point isn't below the curve order, which happens about once every
+	// 2**128 messages. Signing with another one-time signing key helps.
+	ErrHashOutOfRange = errors.New("hash of (msg, R) out of range")
+
+	// ErrInvalidSignature is wrapped by errors of signatures that don't
+	// match their message
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrPointAtInfinity is wrapped by errors of public keys and R points
+	// encoded as the point at infinity, all zeros
+	ErrPointAtInfinity = errors.New("point at infinity")
+
+	// ErrMessageTooLong is returned by strict verification for messages
+	// longer than MaxMessageLength
+	ErrMessageTooLong = errors.New("message too long")
+)
+
+// ScalarError is returned for invalid private scalars. It wraps
+// ErrZeroScalar or ErrScalarOutOfRange.
+type ScalarError struct {
+	// Name is the role of the scalar, such as "priv" or "k"
+	Name string
+	Err  error
+}
+
+func (e *ScalarError) Error() string {
+	return e.Name + " " + e.Err.Error()
+}
+
+func (e *ScalarError) Unwrap() error {
+	return e.Err
+}
+
+// PubKeyError is returned for public keys and R points that fail to
+// parse. It wraps ErrInvalidPubKey and an error saying why, which wraps
+// ErrInvalidLength, ErrInvalidPrefix, ErrNotOnCurve or
+// ErrPointAtInfinity.
+type PubKeyError struct {
+	// Name is the role of the key, such as "oracle pubkey" or "r point"
+	Name string
+	Key  []byte
+	Err  error
+}
+
+func (e *PubKeyError) Error() string {
+	return fmt.Sprintf("invalid %s %x: %v", e.Name, e.Key, e.Err)
+}
+
+func (e *PubKeyError) Unwrap() []error {
+	return []error{ErrInvalidPubKey, e.Err}
+}
+
+// checkScalar returns a ScalarError if key isn't a valid private scalar
+func checkScalar(name string, key [32]byte) error {
+	var s btcecv2.ModNScalar
+	defer s.Zero()
+	switch {
+	case s.SetBytes(&key) != 0:
+		return &ScalarError{Name: name, Err: ErrScalarOutOfRange}
+	case s.IsZero():
+		return &ScalarError{Name: name, Err: ErrZeroScalar}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	var zero, one, high [32]byte
+	one[31] = 1
+	for i := range high {
+		high[i] = 0xff
+	}
+	pub := PublicKeyFromPrivateKey(one)
+
+	_, err := ComputeSignature(zero, one, []byte("msg"))
+	var se *ScalarError
+	if !errors.Is(err, ErrZeroScalar) || !errors.As(err, &se) || se.Name != "priv" {
+		t.Fatalf("expected a zero priv scalar, got %v", err)
+	}
+	_, err = ComputeSignature(one, high, []byte("msg"))
+	if !errors.Is(err, ErrScalarOutOfRange) || !errors.As(err, &se) || se.Name != "k" {
+		t.Fatalf("expected an out of range k scalar, got %v", err)
+	}
+	_, err = TweakPrivateKey(high, nil)
+	if !errors.Is(err, ErrScalarOutOfRange) {
+		t.Fatalf("expected ErrScalarOutOfRange, got %v", err)
+	}
+	_, err = SignECDSAAttestation(zero, "event", nil)
+	if !errors.Is(err, ErrZeroScalar) {
+		t.Fatalf("expected ErrZeroScalar, got %v", err)
+	}
+
+	var bad [33]byte
+	bad[0] = 0x02
+	_, err = ComputeSignaturePubKey(pub, bad, []byte("msg"))
+	var pe *PubKeyError
+	if !errors.Is(err, ErrInvalidPubKey) || !errors.As(err, &pe) || pe.Name != "r point" {
+		t.Fatalf("expected an invalid r point, got %v", err)
+	}
+	if !errors.Is(err, ErrNotOnCurve) {
+		t.Fatalf("expected ErrNotOnCurve, got %v", err)
+	}
+	bad[0] = 0x05
+	_, err = ComputeSignaturePubKey(bad, pub, []byte("msg"))
+	if !errors.Is(err, ErrInvalidPrefix) || !errors.As(err, &pe) || pe.Name != "oracle pubkey" {
+		t.Fatalf("expected an invalid oracle pubkey prefix, got %v", err)
+	}
+	_, err = ComputeSignaturePubKey(pub, [33]byte{}, []byte("msg"))
+	if !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+	_, err = ParseXOnly([32]byte{})
+	if !errors.Is(err, ErrInvalidPubKey) {
+		t.Fatalf("expected ErrInvalidPubKey, got %v", err)
+	}
+
+	sig, err := ComputeSignature(one, one, []byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(pub, pub, []byte("other"), sig)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	a, _ := testAnnouncement(t)
+	a.EventID = "changed"
+	err = a.Verify()
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
@@ -0,0 +1,514 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType determines how the outcome of an event is turned into the
+// message the oracle signs
+type EventType uint8
+
+const (
+	// EventTypeNumeric events resolve to a non-negative integer, signed
+	// as the 256-bit message from GenerateNumericMessage like LIT expects
+	EventTypeNumeric EventType = iota
+
+	// EventTypeEnum events resolve to one of a fixed list of outcomes,
+	// signed as the UTF-8 bytes of the outcome
+	EventTypeEnum
+
+	// EventTypeBytes events resolve to an arbitrary byte string, such as
+	// a block hash, signed as is
+	EventTypeBytes
+
+	// EventTypeDigits events resolve to a non-negative integer that is
+	// decomposed into digits, each signed with its own R point, so
+	// contracts can cover ranges of values with few transactions
+	EventTypeDigits
+)
+
+// String returns the name of the event type as used in JSON
+func (t EventType) String() string {
+	switch t {
+	case EventTypeNumeric:
+		return "numeric"
+	case EventTypeEnum:
+		return "enum"
+	case EventTypeBytes:
+		return "bytes"
+	case EventTypeDigits:
+		return "digits"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (t EventType) MarshalText() ([]byte, error) {
+	switch t {
+	case EventTypeNumeric, EventTypeEnum, EventTypeBytes, EventTypeDigits:
+		return []byte(t.String()), nil
+	}
+	return nil, fmt.Errorf("unknown event type %d", uint8(t))
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *EventType) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "numeric":
+		*t = EventTypeNumeric
+	case "enum":
+		*t = EventTypeEnum
+	case "bytes":
+		*t = EventTypeBytes
+	case "digits":
+		*t = EventTypeDigits
+	default:
+		return fmt.Errorf("unknown event type %q", b)
+	}
+	return nil
+}
+
+// Rounding is how the value a data source reports for a numeric or digits
+// event is rounded to a multiple of the event's precision
+type Rounding uint8
+
+const (
+	// RoundHalfUp rounds to the nearest multiple, halves up. It is the
+	// default.
+	RoundHalfUp Rounding = iota
+
+	// RoundHalfEven rounds to the nearest multiple, halves to the even
+	// one, so rounding errors don't add up in one direction
+	RoundHalfEven
+
+	// RoundFloor and RoundCeil round down and up
+	RoundFloor
+	RoundCeil
+)
+
+// String returns the name of the rounding as used in JSON
+func (r Rounding) String() string {
+	switch r {
+	case RoundHalfUp:
+		return "half-up"
+	case RoundHalfEven:
+		return "half-even"
+	case RoundFloor:
+		return "floor"
+	case RoundCeil:
+		return "ceil"
+	}
+	return fmt.Sprintf("unknown(%d)", uint8(r))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r Rounding) MarshalText() ([]byte, error) {
+	if r > RoundCeil {
+		return nil, fmt.Errorf("unknown rounding %d", uint8(r))
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *Rounding) UnmarshalText(b []byte) error {
+	for v := RoundHalfUp; v <= RoundCeil; v++ {
+		if string(b) == v.String() {
+			*r = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown rounding %q, expected half-up, half-even, floor or ceil", b)
+}
+
+// EventDescriptor describes the outcomes an event can resolve to
+type EventDescriptor struct {
+	Type EventType `json:"type"`
+
+	// Outcomes lists the possible outcomes of an enumerated event
+	Outcomes []string `json:"outcomes,omitempty"`
+
+	// Base and Digits are the base of the decomposition of a digits
+	// event and its number of digits
+	Base   uint32 `json:"base,omitempty"`
+	Digits uint32 `json:"digits,omitempty"`
+
+	// Unit, UnitExponent and Precision say what the value of a numeric
+	// or digits event is: Value*10^UnitExponent in Unit, such as
+	// "usd/btc" with an exponent of -2 for a price in cents. Values are
+	// multiples of Precision if it is set, so a price in cents rounded
+	// to whole dollars has a Precision of 100. They are committed to in
+	// the announcement.
+	Unit         string `json:"unit,omitempty"`
+	UnitExponent int32  `json:"unitExponent,omitempty"`
+	Precision    uint64 `json:"precision,omitempty"`
+
+	// Rounding is how values are rounded to the precision before they
+	// are attested. It is committed to in the announcement, so clients
+	// know how the attested value was derived from the source's.
+	Rounding Rounding `json:"rounding,omitempty"`
+}
+
+// MaxUnitLength and MaxUnitExponent bound the unit of numeric and digits
+// events
+const (
+	MaxUnitLength   = 32
+	MaxUnitExponent = 18
+)
+
+// monetary reports whether the descriptor has a unit, exponent or
+// precision
+func (d EventDescriptor) monetary() bool {
+	return d.Unit != "" || d.UnitExponent != 0 || d.Precision != 0
+}
+
+// Validate checks that the descriptor is well-formed
+func (d EventDescriptor) Validate() error {
+	if d.Type != EventTypeDigits && (d.Base != 0 || d.Digits != 0) {
+		return fmt.Errorf("%s event can't have a base or digits", d.Type)
+	}
+	if d.Type != EventTypeNumeric && d.Type != EventTypeDigits && d.monetary() {
+		return fmt.Errorf("%s event can't have a unit or precision", d.Type)
+	}
+	if len(d.Unit) > MaxUnitLength {
+		return fmt.Errorf("unit is %d bytes, at most %d", len(d.Unit), MaxUnitLength)
+	}
+	for _, c := range d.Unit {
+		if c <= ' ' || c > '~' {
+			return fmt.Errorf("unit %q has a character other than printable ASCII", d.Unit)
+		}
+	}
+	if d.UnitExponent < -MaxUnitExponent || d.UnitExponent > MaxUnitExponent {
+		return fmt.Errorf("unit exponent %d out of range %d to %d", d.UnitExponent, -MaxUnitExponent, MaxUnitExponent)
+	}
+	if d.Precision > math.MaxInt64 {
+		return fmt.Errorf("precision %d out of range", d.Precision)
+	}
+	if d.Rounding > RoundCeil {
+		return fmt.Errorf("unknown rounding %d", uint8(d.Rounding))
+	}
+	if d.Rounding != RoundHalfUp && d.Precision < 2 {
+		return fmt.Errorf("%s rounding needs a precision", d.Rounding)
+	}
+	switch d.Type {
+	case EventTypeNumeric, EventTypeBytes:
+		if len(d.Outcomes) != 0 {
+			return fmt.Errorf("%s event can't list outcomes", d.Type)
+		}
+	case EventTypeDigits:
+		if len(d.Outcomes) != 0 {
+			return fmt.Errorf("%s event can't list outcomes", d.Type)
+		}
+		if d.Base < 2 || d.Base > MaxDigitBase {
+			return fmt.Errorf("digit base %d out of range 2 to %d", d.Base, MaxDigitBase)
+		}
+		if d.Digits == 0 || d.Digits > MaxDigits {
+			return fmt.Errorf("%d digits out of range 1 to %d", d.Digits, MaxDigits)
+		}
+	case EventTypeEnum:
+		if len(d.Outcomes) == 0 {
+			return fmt.Errorf("enum event needs at least one outcome")
+		}
+		seen := make(map[string]bool, len(d.Outcomes))
+		for _, o := range d.Outcomes {
+			if seen[o] {
+				return fmt.Errorf("duplicate outcome %q", o)
+			}
+			seen[o] = true
+		}
+	default:
+		return fmt.Errorf("unknown event type %d", uint8(d.Type))
+	}
+	return nil
+}
+
+// Outcome is the value an event resolved to: Value for numeric events,
+// Label for enumerated ones and Bytes for byte string events
+type Outcome struct {
+	Value int64  `json:"value,omitempty"`
+	Label string `json:"label,omitempty"`
+	Bytes []byte `json:"bytes,omitempty"`
+}
+
+// OutcomeMessage returns the message the oracle signs to attest to
+// outcome, after checking that outcome is possible for the event. For
+// digits events it is the digits, one byte each and most significant
+// first, which are signed one by one as DigitMessage.
+func (d EventDescriptor) OutcomeMessage(outcome Outcome) ([]byte, error) {
+	switch d.Type {
+	case EventTypeNumeric:
+		if outcome.Value < 0 {
+			return nil, fmt.Errorf("numeric outcome %d is negative", outcome.Value)
+		}
+		err := d.checkPrecision(outcome.Value)
+		if err != nil {
+			return nil, err
+		}
+		return GenerateNumericMessage(uint64(outcome.Value)), nil
+	case EventTypeEnum:
+		for _, o := range d.Outcomes {
+			if o == outcome.Label {
+				return []byte(o), nil
+			}
+		}
+		return nil, fmt.Errorf("outcome %q is not a possible outcome", outcome.Label)
+	case EventTypeBytes:
+		if len(outcome.Bytes) == 0 {
+			return nil, fmt.Errorf("byte string outcome is empty")
+		}
+		return outcome.Bytes, nil
+	case EventTypeDigits:
+		err := d.checkPrecision(outcome.Value)
+		if err != nil {
+			return nil, err
+		}
+		return d.decompose(outcome.Value)
+	}
+	return nil, fmt.Errorf("unknown event type %d", uint8(d.Type))
+}
+
+// ParseOutcome returns the outcome a signed message stands for. It is the
+// inverse of OutcomeMessage and fails for messages that aren't a possible
+// outcome of the event: numeric messages must be the 256-bit encoding of a
+// value in the range of Outcome.Value, and digits messages one byte per
+// digit.
+func (d EventDescriptor) ParseOutcome(msg []byte) (Outcome, error) {
+	switch d.Type {
+	case EventTypeNumeric:
+		if len(msg) != 32 {
+			return Outcome{}, fmt.Errorf("numeric message is %d bytes, expected 32", len(msg))
+		}
+		for _, b := range msg[:24] {
+			if b != 0 {
+				return Outcome{}, fmt.Errorf("numeric message exceeds 64 bits")
+			}
+		}
+		v := binary.BigEndian.Uint64(msg[24:])
+		if v > math.MaxInt64 {
+			return Outcome{}, fmt.Errorf("numeric outcome %d out of range", v)
+		}
+		err := d.checkPrecision(int64(v))
+		if err != nil {
+			return Outcome{}, err
+		}
+		return Outcome{Value: int64(v)}, nil
+	case EventTypeEnum:
+		for _, o := range d.Outcomes {
+			if o == string(msg) {
+				return Outcome{Label: o}, nil
+			}
+		}
+		return Outcome{}, fmt.Errorf("%q is not a possible outcome", msg)
+	case EventTypeBytes:
+		if len(msg) == 0 {
+			return Outcome{}, fmt.Errorf("byte string outcome is empty")
+		}
+		return Outcome{Bytes: msg}, nil
+	case EventTypeDigits:
+		v, err := d.compose(msg)
+		if err != nil {
+			return Outcome{}, err
+		}
+		err = d.checkPrecision(v)
+		if err != nil {
+			return Outcome{}, err
+		}
+		return Outcome{Value: v}, nil
+	}
+	return Outcome{}, fmt.Errorf("unknown event type %d", uint8(d.Type))
+}
+
+// checkPrecision fails if v isn't a multiple of the precision
+func (d EventDescriptor) checkPrecision(v int64) error {
+	if d.Precision > 1 && v%int64(d.Precision) != 0 {
+		return fmt.Errorf("outcome %d is not a multiple of the precision %d", v, d.Precision)
+	}
+	return nil
+}
+
+// Round rounds the value of a numeric or digits outcome to a multiple of
+// the precision, as the descriptor's Rounding says
+func (d EventDescriptor) Round(o Outcome) Outcome {
+	if (d.Type != EventTypeNumeric && d.Type != EventTypeDigits) || d.Precision < 2 {
+		return o
+	}
+	p := int64(d.Precision)
+	// r is the distance to the multiple below, which can't overflow as
+	// no multiple of p is below it
+	r := o.Value % p
+	if r < 0 {
+		r += p
+		if o.Value-r > o.Value {
+			return o
+		}
+	}
+	if r == 0 {
+		return o
+	}
+	below := o.Value - r
+	var up bool
+	switch d.Rounding {
+	case RoundFloor:
+	case RoundCeil:
+		up = true
+	case RoundHalfEven:
+		up = r > p-r || r == p-r && (below/p)%2 != 0
+	default:
+		up = r >= p-r
+	}
+	o.Value = below
+	if up && below <= math.MaxInt64-p {
+		o.Value += p
+	}
+	return o
+}
+
+// FormatValue returns the value of a numeric or digits outcome in its
+// unit, such as "1234.56 usd/btc" for 123456 with an exponent of -2
+func (d EventDescriptor) FormatValue(v int64) string {
+	s := strconv.FormatInt(v, 10)
+	if d.UnitExponent > 0 {
+		s += strings.Repeat("0", int(d.UnitExponent))
+	} else if d.UnitExponent < 0 {
+		sign := ""
+		if v < 0 {
+			sign, s = "-", s[1:]
+		}
+		n := int(-d.UnitExponent)
+		if len(s) <= n {
+			s = strings.Repeat("0", n-len(s)+1) + s
+		}
+		s = sign + s[:len(s)-n] + "." + s[len(s)-n:]
+	}
+	if d.Unit != "" {
+		s += " " + d.Unit
+	}
+	return s
+}
+
+// FormatOutcome returns a readable form of an outcome: the label of an
+// enum outcome, the hex encoded bytes of a bytes outcome and the value in
+// its unit, as FormatValue writes it, of others
+func (d EventDescriptor) FormatOutcome(o Outcome) string {
+	switch d.Type {
+	case EventTypeEnum:
+		return o.Label
+	case EventTypeBytes:
+		return hex.EncodeToString(o.Bytes)
+	}
+	return d.FormatValue(o.Value)
+}
+
+// Event is something the oracle will attest to the outcome of. Events
+// with a MaturityHeight mature once the Bitcoin chain reaches that height;
+// their Maturity is only an estimate used for ordering and scheduling.
+type Event struct {
+	ID             string
+	Maturity       time.Time
+	MaturityHeight uint32
+	Descriptor     EventDescriptor
+	Metadata       EventMetadata
+}
+
+// MaxDescriptionLength, MaxCategoryLength, MaxTags and MaxTagLength bound
+// the metadata of events
+const (
+	MaxDescriptionLength = 1024
+	MaxCategoryLength    = 64
+	MaxTags              = 16
+	MaxTagLength         = 64
+)
+
+// EventMetadata describes an event to people and lets them find it. It
+// has no bearing on the outcomes, but is committed to in the
+// announcement like the rest of the event.
+type EventMetadata struct {
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// IsZero reports whether the metadata is empty
+func (m EventMetadata) IsZero() bool {
+	return m.Description == "" && m.Category == "" && len(m.Tags) == 0
+}
+
+// HasTag reports whether the metadata has the tag
+func (m EventMetadata) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the metadata's lengths, and that tags are neither
+// empty nor repeated
+func (m EventMetadata) Validate() error {
+	if len(m.Description) > MaxDescriptionLength {
+		return fmt.Errorf("description is %d bytes, at most %d", len(m.Description), MaxDescriptionLength)
+	}
+	if len(m.Category) > MaxCategoryLength {
+		return fmt.Errorf("category is %d bytes, at most %d", len(m.Category), MaxCategoryLength)
+	}
+	if len(m.Tags) > MaxTags {
+		return fmt.Errorf("%d tags, at most %d", len(m.Tags), MaxTags)
+	}
+	seen := make(map[string]bool)
+	for _, t := range m.Tags {
+		if t == "" || len(t) > MaxTagLength {
+			return fmt.Errorf("tag %q is empty or longer than %d bytes", t, MaxTagLength)
+		}
+		if seen[t] {
+			return fmt.Errorf("duplicate tag %q", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// writeMetadata writes the canonical encoding of m to h
+func writeMetadata(h hash.Hash, m EventMetadata) {
+	var buf [8]byte
+	writeString := func(s string) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.Write([]byte(s))
+	}
+	writeString(m.Description)
+	writeString(m.Category)
+	binary.BigEndian.PutUint64(buf[:], uint64(len(m.Tags)))
+	h.Write(buf[:])
+	for _, t := range m.Tags {
+		writeString(t)
+	}
+}
+
+// eventIDTag separates event IDs from other hashes of events
+const eventIDTag = "DLC/oracle/event-id"
+
+// EventID returns an ID derived from the descriptor and maturity alone,
+// the hex encoded tagged hash of their canonical encoding, so parties
+// referring to the same event independently agree on its ID. Maturity
+// is encoded with a precision of seconds. Events only differing in what
+// they measure, such as two prices maturing at once, get the same ID and
+// need IDs of their own.
+func EventID(d EventDescriptor, maturity time.Time) string {
+	var buf [8]byte
+	h := sha256.New()
+	tagHash := sha256.Sum256([]byte(eventIDTag))
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(maturity.Unix()))
+	h.Write(buf[:])
+	writeDescriptor(h, d)
+	return hex.EncodeToString(h.Sum(nil))
+}
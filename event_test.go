@@ -0,0 +1,324 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOutcomeMessage(t *testing.T) {
+	enum := EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	msg, err := enum.OutcomeMessage(Outcome{Label: "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg) != "no" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	_, err = enum.OutcomeMessage(Outcome{Label: "maybe"})
+	if err == nil {
+		t.Fatal("accepted an impossible outcome")
+	}
+
+	numeric := EventDescriptor{Type: EventTypeNumeric}
+	msg, err = numeric.OutcomeMessage(Outcome{Value: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, GenerateNumericMessage(42)) {
+		t.Fatalf("unexpected message %x", msg)
+	}
+	_, err = numeric.OutcomeMessage(Outcome{Value: -1})
+	if err == nil {
+		t.Fatal("accepted a negative numeric outcome")
+	}
+
+	raw := EventDescriptor{Type: EventTypeBytes}
+	msg, err = raw.OutcomeMessage(Outcome{Bytes: []byte{1, 2}})
+	if err != nil || !bytes.Equal(msg, []byte{1, 2}) {
+		t.Fatalf("unexpected message %x %v", msg, err)
+	}
+	_, err = raw.OutcomeMessage(Outcome{})
+	if err == nil {
+		t.Fatal("accepted an empty byte string outcome")
+	}
+}
+
+func TestValidateDescriptor(t *testing.T) {
+	bad := []EventDescriptor{
+		{Type: EventTypeEnum},
+		{Type: EventTypeEnum, Outcomes: []string{"a", "a"}},
+		{Type: EventTypeNumeric, Outcomes: []string{"a"}},
+		{Type: EventTypeBytes, Outcomes: []string{"a"}},
+		{Type: EventTypeEnum, Outcomes: []string{"a"}, Unit: "usd"},
+		{Type: EventTypeNumeric, Unit: "usd btc"},
+		{Type: EventTypeNumeric, UnitExponent: 19},
+		{Type: 7},
+	}
+	for _, d := range bad {
+		if d.Validate() == nil {
+			t.Fatalf("descriptor %+v validated", d)
+		}
+	}
+}
+
+func TestDeriveOneTimeSigningKey(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	k0, err := DeriveOneTimeSigningKey(priv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := DeriveOneTimeSigningKey(priv, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1, err := DeriveOneTimeSigningKey(priv, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k0 != again {
+		t.Fatal("derivation is not deterministic")
+	}
+	if k0 == k1 {
+		t.Fatal("different indexes derived the same key")
+	}
+}
+
+func TestParseOutcome(t *testing.T) {
+	numeric := EventDescriptor{Type: EventTypeNumeric}
+	o, err := numeric.ParseOutcome(GenerateNumericMessage(42))
+	if err != nil || o.Value != 42 {
+		t.Fatalf("parsed %+v, %v", o, err)
+	}
+	tooBig := GenerateNumericMessage(1)
+	tooBig[23] = 1
+	_, err = numeric.ParseOutcome(tooBig)
+	if err == nil {
+		t.Fatal("accepted a value over 64 bits")
+	}
+	_, err = numeric.ParseOutcome(GenerateNumericMessage(1 << 63))
+	if err == nil {
+		t.Fatal("accepted a value out of int64 range")
+	}
+	_, err = numeric.ParseOutcome([]byte{42})
+	if err == nil {
+		t.Fatal("accepted a short numeric message")
+	}
+
+	enum := EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	o, err = enum.ParseOutcome([]byte("no"))
+	if err != nil || o.Label != "no" {
+		t.Fatalf("parsed %+v, %v", o, err)
+	}
+	_, err = enum.ParseOutcome([]byte("maybe"))
+	if err == nil {
+		t.Fatal("accepted an impossible outcome")
+	}
+
+	raw := EventDescriptor{Type: EventTypeBytes}
+	o, err = raw.ParseOutcome([]byte{1, 2})
+	if err != nil || !bytes.Equal(o.Bytes, []byte{1, 2}) {
+		t.Fatalf("parsed %+v, %v", o, err)
+	}
+}
+
+func TestEventID(t *testing.T) {
+	d := EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	maturity := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := EventID(d, maturity)
+	if len(id) != 64 {
+		t.Fatalf("unexpected id %s", id)
+	}
+
+	// Parties derive the same ID whatever the location and sub-second
+	// precision of their maturity
+	same := EventID(EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}},
+		maturity.Add(time.Millisecond).In(time.FixedZone("UTC+2", 7200)))
+	if same != id {
+		t.Fatal("same event has another id")
+	}
+
+	others := []string{
+		EventID(d, maturity.Add(time.Second)),
+		EventID(EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"no", "yes"}}, maturity),
+		EventID(EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yesno"}}, maturity),
+		EventID(EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 5}, maturity),
+		EventID(EventDescriptor{Type: EventTypeDigits, Base: 2, Digits: 5}, maturity),
+	}
+	seen := map[string]bool{id: true}
+	for i, other := range others {
+		if seen[other] {
+			t.Fatalf("event %d shares an id", i)
+		}
+		seen[other] = true
+	}
+}
+
+func TestMonetaryDescriptor(t *testing.T) {
+	d := EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 6, Unit: "usd/btc", UnitExponent: -2, Precision: 100}
+	err := d.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		value int64
+		s     string
+	}{
+		{123400, "1234.00 usd/btc"},
+		{5, "0.05 usd/btc"},
+		{-5, "-0.05 usd/btc"},
+	} {
+		if s := d.FormatValue(v.value); s != v.s {
+			t.Fatalf("formatted %d as %q, want %q", v.value, s, v.s)
+		}
+	}
+	if s := (EventDescriptor{Type: EventTypeNumeric, UnitExponent: 3}).FormatValue(12); s != "12000" {
+		t.Fatalf("formatted 12 as %q", s)
+	}
+	for v, want := range map[int64]int64{123449: 123400, 123450: 123500, 123400: 123400, -150: -100, -151: -200} {
+		if got := d.Round(Outcome{Value: v}).Value; got != want {
+			t.Fatalf("rounded %d to %d, want %d", v, got, want)
+		}
+	}
+
+	_, err = d.OutcomeMessage(Outcome{Value: 123456})
+	if err == nil {
+		t.Fatal("signed a value that isn't a multiple of the precision")
+	}
+	msg, err := d.OutcomeMessage(Outcome{Value: 123400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := d.ParseOutcome(msg)
+	if err != nil || o.Value != 123400 {
+		t.Fatalf("parsed %+v %v", o, err)
+	}
+	loose := d
+	loose.Precision = 0
+	msg, _ = loose.OutcomeMessage(Outcome{Value: 123456})
+	_, err = d.ParseOutcome(msg)
+	if err == nil {
+		t.Fatal("parsed a value that isn't a multiple of the precision")
+	}
+
+	// The unit is committed to, and leaves other descriptors' hashes
+	// as they were
+	ev := Event{ID: "price", Maturity: time.Unix(1000, 0), Descriptor: d}
+	plain := ev
+	plain.Descriptor = EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 6}
+	seen := map[[32]byte]bool{EventCommitment(plain): true}
+	for _, change := range []func(*EventDescriptor){
+		func(d *EventDescriptor) {},
+		func(d *EventDescriptor) { d.Unit = "usd" },
+		func(d *EventDescriptor) { d.UnitExponent = 0 },
+		func(d *EventDescriptor) { d.Precision = 1 },
+		func(d *EventDescriptor) { d.Rounding = RoundFloor },
+		func(d *EventDescriptor) { d.Rounding = RoundCeil },
+	} {
+		e := ev
+		change(&e.Descriptor)
+		c := EventCommitment(e)
+		if seen[c] {
+			t.Fatalf("descriptor %+v has the commitment of another", e.Descriptor)
+		}
+		seen[c] = true
+	}
+}
+
+func TestRounding(t *testing.T) {
+	for _, c := range []struct {
+		rounding Rounding
+		want     map[int64]int64
+	}{
+		{RoundHalfUp, map[int64]int64{149: 100, 150: 200, 250: 300, -150: -100, -151: -200}},
+		{RoundHalfEven, map[int64]int64{149: 100, 150: 200, 250: 200, 251: 300, -150: -200, -250: -200}},
+		// MaxInt64 is 7 past a multiple of 100 and can't be rounded up
+		{RoundFloor, map[int64]int64{199: 100, 100: 100, -1: -100, math.MaxInt64: math.MaxInt64 - 7}},
+		{RoundCeil, map[int64]int64{101: 200, 100: 100, -199: -100, math.MaxInt64: math.MaxInt64 - 7}},
+	} {
+		d := EventDescriptor{Type: EventTypeNumeric, Precision: 100, Rounding: c.rounding}
+		for v, want := range c.want {
+			if got := d.Round(Outcome{Value: v}).Value; got != want {
+				t.Errorf("%s rounded %d to %d, want %d", c.rounding, v, got, want)
+			}
+		}
+
+		b, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parsed EventDescriptor
+		err = json.Unmarshal(b, &parsed)
+		if err != nil || parsed.Rounding != d.Rounding {
+			t.Fatalf("%s: %s parsed as %+v %v", c.rounding, b, parsed, err)
+		}
+	}
+
+	if (EventDescriptor{Type: EventTypeNumeric, Rounding: RoundFloor}).Validate() == nil {
+		t.Fatal("rounding accepted without a precision")
+	}
+	if (EventDescriptor{Type: EventTypeNumeric, Precision: 10, Rounding: 9}).Validate() == nil {
+		t.Fatal("unknown rounding accepted")
+	}
+	var r Rounding
+	if r.UnmarshalText([]byte("up")) == nil {
+		t.Fatal("unknown rounding parsed")
+	}
+}
+
+func TestEventMetadata(t *testing.T) {
+	long := string(make([]byte, MaxTagLength+1))
+	for _, m := range []EventMetadata{
+		{Description: string(make([]byte, MaxDescriptionLength+1))},
+		{Category: string(make([]byte, MaxCategoryLength+1))},
+		{Tags: make([]string, MaxTags+1)},
+		{Tags: []string{""}},
+		{Tags: []string{long}},
+		{Tags: []string{"btc", "btc"}},
+	} {
+		if m.Validate() == nil {
+			t.Fatalf("metadata %+v validated", m)
+		}
+	}
+
+	var priv, k [32]byte
+	priv[31], k[31] = 42, 7
+	ev := Event{ID: "price", Maturity: time.Unix(1000, 0), Descriptor: EventDescriptor{Type: EventTypeNumeric}}
+	plain, err := NewAnnouncement(priv, k, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(plain)
+	if bytes.Contains(b, []byte("metadata")) {
+		t.Fatalf("announcement without metadata encoded as %s", b)
+	}
+
+	ev.Metadata = EventMetadata{Description: "BTC/USD close", Category: "crypto", Tags: []string{"btc", "usd"}}
+	a, err := NewAnnouncement(priv, k, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SigningHash() == plain.SigningHash() {
+		t.Fatal("metadata isn't committed to")
+	}
+	b, _ = json.Marshal(a)
+	parsed, err := ParseAnnouncement(b)
+	if err != nil || parsed.Verify() != nil || !parsed.Metadata.HasTag("usd") || parsed.Event().Metadata.Category != "crypto" {
+		t.Fatalf("parsed %+v %v", parsed, err)
+	}
+	parsed.Metadata.Tags = []string{"btc"}
+	if parsed.Verify() == nil {
+		t.Fatal("announcement with changed tags verified")
+	}
+	b = bytes.Replace(b, []byte(`"usd"]`), []byte(`"btc"]`), 1)
+	_, err = ParseAnnouncement(b)
+	if !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("parsed duplicate tags: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"sort"
+	"time"
+)
+
+// evidenceTag separates evidence signatures from any other message signed
+// with the oracle's key
+const evidenceTag = "DLC/oracle/evidence"
+
+// Evidence is the oracle's signed record of what the APIs of its data
+// sources responded when it attested an event, so disputes about the
+// outcome can be investigated. The signature only shows the oracle
+// claims to have received these responses, not that the APIs sent them.
+type Evidence struct {
+	EventID      string
+	OraclePubKey [33]byte
+	Responses    []UpstreamResponse
+	CapturedAt   time.Time
+	Signature    [65]byte
+}
+
+// UpstreamResponse is a response of the HTTP API of a data source. Times
+// are committed to with a precision of milliseconds.
+type UpstreamResponse struct {
+	// Source is the ID of the data source that made the request
+	Source string
+	Method string
+	URL    string
+
+	Status int
+	Header map[string][]string
+	Body   []byte
+
+	// Truncated is set if the body was cut off at the capture limit
+	Truncated bool
+
+	RequestedAt time.Time
+	ReceivedAt  time.Time
+}
+
+// SigningHash returns the digest of the evidence that the oracle signs
+func (e Evidence) SigningHash() [32]byte {
+	h := sha256.New()
+	h.Write([]byte(evidenceTag))
+	writeString(h, e.EventID)
+	h.Write(e.OraclePubKey[:])
+	writeUint64(h, uint64(e.CapturedAt.UnixMilli()))
+	writeUint64(h, uint64(len(e.Responses)))
+	for _, r := range e.Responses {
+		writeString(h, r.Source)
+		writeString(h, r.Method)
+		writeString(h, r.URL)
+		writeUint64(h, uint64(r.Status))
+		names := make([]string, 0, len(r.Header))
+		for name := range r.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		writeUint64(h, uint64(len(names)))
+		for _, name := range names {
+			writeString(h, name)
+			writeUint64(h, uint64(len(r.Header[name])))
+			for _, v := range r.Header[name] {
+				writeString(h, v)
+			}
+		}
+		writeString(h, string(r.Body))
+		if r.Truncated {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+		writeUint64(h, uint64(r.RequestedAt.UnixMilli()))
+		writeUint64(h, uint64(r.ReceivedAt.UnixMilli()))
+	}
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// writeUint64 writes a big endian integer to a hash
+func writeUint64(h hash.Hash, i uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	h.Write(buf[:])
+}
+
+// Verify checks the oracle's signature on the evidence
+func (e Evidence) Verify() error {
+	digest := e.SigningHash()
+	err := VerifyMessage(e.OraclePubKey, digest[:], e.Signature)
+	if err != nil {
+		return fmt.Errorf("evidence of %s: %w", e.EventID, err)
+	}
+	return nil
+}
+
+type evidenceJSON struct {
+	EventID      string                 `json:"eventId"`
+	OraclePubKey string                 `json:"oraclePubKey"`
+	Responses    []upstreamResponseJSON `json:"responses"`
+	CapturedAt   int64                  `json:"capturedAt"`
+	Signature    string                 `json:"signature"`
+}
+
+type upstreamResponseJSON struct {
+	Source      string              `json:"source"`
+	Method      string              `json:"method"`
+	URL         string              `json:"url"`
+	Status      int                 `json:"status"`
+	Header      map[string][]string `json:"header"`
+	Body        []byte              `json:"body"`
+	Truncated   bool                `json:"truncated,omitempty"`
+	RequestedAt int64               `json:"requestedAt"`
+	ReceivedAt  int64               `json:"receivedAt"`
+}
+
+// MarshalJSON encodes the evidence with hex encoded key and signature,
+// base64 encoded bodies and times as unix timestamps in milliseconds
+func (e Evidence) MarshalJSON() ([]byte, error) {
+	j := evidenceJSON{
+		EventID:      e.EventID,
+		OraclePubKey: hex.EncodeToString(e.OraclePubKey[:]),
+		Responses:    make([]upstreamResponseJSON, len(e.Responses)),
+		CapturedAt:   e.CapturedAt.UnixMilli(),
+		Signature:    hex.EncodeToString(e.Signature[:]),
+	}
+	for i, r := range e.Responses {
+		j.Responses[i] = upstreamResponseJSON{
+			Source:      r.Source,
+			Method:      r.Method,
+			URL:         r.URL,
+			Status:      r.Status,
+			Header:      r.Header,
+			Body:        r.Body,
+			Truncated:   r.Truncated,
+			RequestedAt: r.RequestedAt.UnixMilli(),
+			ReceivedAt:  r.ReceivedAt.UnixMilli(),
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes evidence encoded by MarshalJSON. Times are
+// returned in UTC.
+func (e *Evidence) UnmarshalJSON(b []byte) error {
+	var j evidenceJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	err = decodeHexKey(&e.OraclePubKey, j.OraclePubKey)
+	if err != nil {
+		return err
+	}
+	err = decodeHexFixed(e.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	e.EventID = j.EventID
+	e.CapturedAt = time.UnixMilli(j.CapturedAt).UTC()
+	e.Responses = make([]UpstreamResponse, len(j.Responses))
+	for i, r := range j.Responses {
+		e.Responses[i] = UpstreamResponse{
+			Source:      r.Source,
+			Method:      r.Method,
+			URL:         r.URL,
+			Status:      r.Status,
+			Header:      r.Header,
+			Body:        r.Body,
+			Truncated:   r.Truncated,
+			RequestedAt: time.UnixMilli(r.RequestedAt).UTC(),
+			ReceivedAt:  time.UnixMilli(r.ReceivedAt).UTC(),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+// Package evidence captures the responses of the HTTP APIs data sources
+// query, so the oracle can sign and keep what they said when it attested
+// an event. A Recorder wraps the HTTP clients of the data sources and the
+// data sources themselves; the scheduler takes the responses an outcome
+// was fetched from after attesting it and stores them with
+// oracle.PutEvidence.
+//
+// Evidence is published, so API keys must be sent in request headers,
+// which aren't captured, rather than in URLs. Set-Cookie headers are
+// dropped.
+package evidence
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// MaxBodySize limits how much of each response body is captured
+const MaxBodySize = 1 << 20
+
+// keptPerSource is how many of the latest responses of each data source
+// are kept until a fetch claims them
+const keptPerSource = 16
+
+// Recorder captures the API responses of data sources and keeps those each
+// event's outcome was fetched from until they are taken. It is safe for
+// concurrent use.
+type Recorder struct {
+	mtx     sync.Mutex
+	seq     uint64
+	latest  map[string][]recorded
+	pending map[string][]dlcoracle.UpstreamResponse
+}
+
+// recorded is a captured response with the sequence number it was
+// recorded under
+type recorded struct {
+	seq  uint64
+	resp dlcoracle.UpstreamResponse
+}
+
+// NewRecorder returns an empty recorder. Responses are timed by the
+// system clock.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		latest:  make(map[string][]recorded),
+		pending: make(map[string][]dlcoracle.UpstreamResponse),
+	}
+}
+
+// Client returns a copy of c, or of http.DefaultClient if c is nil, that
+// records the responses to the requests of the data source with ID source
+func (r *Recorder) Client(source string, c *http.Client) *http.Client {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	next := c.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	copied := *c
+	copied.Transport = &transport{r: r, source: source, next: next}
+	return &copied
+}
+
+// transport is an http.RoundTripper recording the responses of a source
+type transport struct {
+	r      *Recorder
+	source string
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper. The captured part of the body is
+// read before returning, the rest is left to the caller.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requested := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	truncated := len(body) > MaxBodySize
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+	if truncated {
+		body = body[:MaxBodySize]
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	t.r.record(t.source, dlcoracle.UpstreamResponse{
+		Source:      t.source,
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        body,
+		Truncated:   truncated,
+		RequestedAt: requested.UTC().Truncate(time.Millisecond),
+		ReceivedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	})
+	return resp, nil
+}
+
+// record keeps resp as the latest response of source
+func (r *Recorder) record(source string, resp dlcoracle.UpstreamResponse) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.seq++
+	list := append(r.latest[source], recorded{seq: r.seq, resp: resp})
+	if len(list) > keptPerSource {
+		list = list[len(list)-keptPerSource:]
+	}
+	r.latest[source] = list
+}
+
+// InstrumentSource wraps ds so the responses recorded for it while it
+// fetches an outcome, or its latest one if it answers from a poll, are
+// kept for the event until Take. Register the returned data source
+// instead of ds, and have ds make its requests with a client from Client.
+func (r *Recorder) InstrumentSource(ds datasource.DataSource) datasource.DataSource {
+	return &recordingSource{DataSource: ds, r: r}
+}
+
+type recordingSource struct {
+	datasource.DataSource
+	r *Recorder
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *recordingSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	s.r.mtx.Lock()
+	since := s.r.seq
+	s.r.mtx.Unlock()
+	o, err := s.DataSource.FetchOutcome(ev)
+	if err == nil {
+		s.r.keep(ev.ID, s.ID(), since)
+	}
+	return o, err
+}
+
+// keep adds the responses of source recorded after the sequence number
+// since, or its latest one if there are none, to the evidence of an event
+func (r *Recorder) keep(eventID, source string, since uint64) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	list := r.latest[source]
+	if len(list) == 0 {
+		return
+	}
+	first := len(list) - 1
+	for first > 0 && list[first-1].seq > since {
+		first--
+	}
+	for _, rec := range list[first:] {
+		r.pending[eventID] = append(r.pending[eventID], rec.resp)
+	}
+}
+
+// Take returns the responses the outcome of an event was fetched from and
+// forgets them. The scheduler takes them after every attempt to attest
+// the event.
+func (r *Recorder) Take(eventID string) []dlcoracle.UpstreamResponse {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	list := r.pending[eventID]
+	delete(r.pending, eventID)
+	return list
+}
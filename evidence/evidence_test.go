@@ -0,0 +1,120 @@
+package evidence
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource/jsonapi"
+	"github.com/mit-dci/dlc-oracle-go/datasource/price"
+)
+
+func TestRecordFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		w.Header().Set("X-Request-Id", "42")
+		fmt.Fprintf(w, `{"outcome":%q}`, strings.TrimPrefix(r.URL.Path, "/"))
+	}))
+	defer srv.Close()
+
+	r := NewRecorder()
+	js, err := jsonapi.New(jsonapi.Config{ID: "api", URL: srv.URL + "/{{.ID}}", Path: "$.outcome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	js.SetHTTPClient(r.Client("api", nil))
+	ds := r.InstrumentSource(js)
+
+	ev := dlcoracle.Event{ID: "home", Descriptor: dlcoracle.EventDescriptor{
+		Type: dlcoracle.EventTypeEnum, Outcomes: []string{"home", "away"}}}
+	o, err := ds.FetchOutcome(ev)
+	if err != nil || o.Label != "home" {
+		t.Fatalf("fetched %+v %v", o, err)
+	}
+	list := r.Take("home")
+	if len(list) != 1 {
+		t.Fatalf("captured %+v", list)
+	}
+	resp := list[0]
+	if resp.Source != "api" || resp.URL != srv.URL+"/home" || resp.Status != 200 ||
+		string(resp.Body) != `{"outcome":"home"}` || http.Header(resp.Header).Get("X-Request-Id") != "42" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if _, ok := resp.Header["Set-Cookie"]; ok {
+		t.Fatal("cookie captured")
+	}
+	if resp.ReceivedAt.Before(resp.RequestedAt) {
+		t.Fatalf("received at %v before requested at %v", resp.ReceivedAt, resp.RequestedAt)
+	}
+	if r.Take("home") != nil {
+		t.Fatal("responses taken twice")
+	}
+
+	// Failed fetches keep nothing
+	ev.ID = "missing"
+	_, err = ds.FetchOutcome(ev)
+	if err == nil {
+		t.Fatal("fetched a missing outcome")
+	}
+	if r.Take("missing") != nil {
+		t.Fatal("kept the responses of a failed fetch")
+	}
+}
+
+func TestRecordPoll(t *testing.T) {
+	var mtx sync.Mutex
+	prices := []string{"100.5", "101.5"}
+	served := make(chan struct{}, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		fmt.Fprintf(w, `{"data":{"amount":%q}}`, prices[0])
+		if len(prices) > 1 {
+			prices = prices[1:]
+		}
+		mtx.Unlock()
+		served <- struct{}{}
+	}))
+	defer srv.Close()
+
+	r := NewRecorder()
+	pair, err := price.ParsePair("BTC/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := price.NewSource("coinbase", price.Coinbase{BaseURL: srv.URL}, pair, 1)
+	ps.SetHTTPClient(r.Client("coinbase", nil))
+	ds := r.InstrumentSource(ps)
+
+	// A polled source answers from its last price, which the latest
+	// response is the evidence of. The third request is only made once
+	// the second price is stored.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ps.Poll(ctx, 10*time.Millisecond, time.Hour)
+		close(done)
+	}()
+	<-served
+	<-served
+	<-served
+	cancel()
+	<-done
+	o, err := ds.FetchOutcome(dlcoracle.Event{ID: "btcusd"})
+	if err != nil || o.Value != 1015 {
+		t.Fatalf("fetched %+v %v", o, err)
+	}
+	list := r.Take("btcusd")
+	if len(list) != 1 || !strings.Contains(string(list[0].Body), "101.5") {
+		t.Fatalf("captured %+v", list)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "fmt"
+
+// Sign signs the evidence with the oracle's private key, which must match
+// OraclePubKey. The options are passed on to SignMessage.
+func (e *Evidence) Sign(privKey [32]byte, opts ...SignOption) error {
+	if PublicKeyFromPrivateKey(privKey) != e.OraclePubKey {
+		return fmt.Errorf("private key does not match oracle pubkey")
+	}
+	digest := e.SigningHash()
+	sig, err := SignMessage(privKey, digest[:], opts...)
+	if err != nil {
+		return err
+	}
+	e.Signature = sig
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvidence(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 3
+	e := Evidence{
+		EventID:      "btcusd-2030-01-01",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		Responses: []UpstreamResponse{{
+			Source:      "kraken",
+			Method:      "GET",
+			URL:         "https://api.kraken.com/0/public/Ticker?pair=XBTUSD",
+			Status:      200,
+			Header:      map[string][]string{"Content-Type": {"application/json"}, "Date": {"Tue, 01 Jan 2030 00:00:00 GMT"}},
+			Body:        []byte(`{"result":{"XXBTZUSD":{"c":["104321.5","0.1"]}}}`),
+			RequestedAt: time.UnixMilli(1893456000123).UTC(),
+			ReceivedAt:  time.UnixMilli(1893456000456).UTC(),
+		}, {
+			Source:    "bitstamp",
+			Method:    "GET",
+			URL:       "https://www.bitstamp.net/api/v2/ticker/btcusd/",
+			Status:    503,
+			Truncated: true,
+		}},
+		CapturedAt: time.UnixMilli(1893456001000).UTC(),
+	}
+	err := e.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Evidence
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decoded.Verify()
+	if err != nil {
+		t.Fatalf("decoded evidence: %v", err)
+	}
+	if decoded.Responses[0].Header["Date"][0] != e.Responses[0].Header["Date"][0] ||
+		!decoded.Responses[0].ReceivedAt.Equal(e.Responses[0].ReceivedAt) {
+		t.Fatalf("got %+v from %s", decoded, b)
+	}
+
+	// Every part of a response is signed
+	for _, tamper := range []func(r *UpstreamResponse){
+		func(r *UpstreamResponse) { r.Body = []byte(`{"result":{"XXBTZUSD":{"c":["1","0.1"]}}}`) },
+		func(r *UpstreamResponse) { r.Header = map[string][]string{"Content-Type": {"text/plain"}} },
+		func(r *UpstreamResponse) { r.Status = 500 },
+		func(r *UpstreamResponse) { r.ReceivedAt = r.ReceivedAt.Add(time.Second) },
+	} {
+		tampered := e
+		tampered.Responses = append([]UpstreamResponse(nil), e.Responses...)
+		tamper(&tampered.Responses[0])
+		if reflect.DeepEqual(tampered.Responses, e.Responses) || tampered.Verify() == nil {
+			t.Fatalf("tampered evidence %+v verified", tampered.Responses[0])
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package explorer encodes and decodes the JSON of oracle explorers' v2
+// APIs, so announcements and attestations fetched from an explorer can be
+// checked with this library and served back unchanged.
+//
+// Explorers nest the event in the announcement and the descriptor in the
+// event, under the camelCase names of the DLC specifications' messages:
+//
+//	{"announcementSignature":"<hex>","oraclePublicKey":"<hex>",
+//	 "oracleEvent":{"oracleNonces":["<hex>"],"eventMaturityEpoch":1893456000,
+//	  "eventDescriptor":{"enumEvent":{"outcomes":["yes","no"]}},"eventId":"rain"}}
+//
+// Keys and nonces are x-only and signatures 64 bytes, in lowercase hex.
+// Decoding accepts either case; encoding writes the fields in the
+// explorers' order, so a payload decodes and encodes back to the same
+// bytes once compacted. Signatures are carried as they are: explorers'
+// oracles sign the specifications' TLV encodings, not this library's
+// records.
+package explorer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// XOnlyKey is an x-only public key or nonce, hex encoded
+type XOnlyKey [32]byte
+
+// MarshalText encodes the key in lowercase hex
+func (k XOnlyKey) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(k[:])), nil
+}
+
+// UnmarshalText decodes a key in hex of either case
+func (k *XOnlyKey) UnmarshalText(b []byte) error {
+	return decodeHex(k[:], b)
+}
+
+// Point returns the compressed encoding of the key, with an even Y,
+// after checking that it is on the curve
+func (k XOnlyKey) Point() ([33]byte, error) {
+	return dlcoracle.ParseXOnly(k)
+}
+
+// Signature is a 64 byte Schnorr signature, hex encoded
+type Signature [64]byte
+
+// MarshalText encodes the signature in lowercase hex
+func (s Signature) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(s[:])), nil
+}
+
+// UnmarshalText decodes a signature in hex of either case
+func (s *Signature) UnmarshalText(b []byte) error {
+	return decodeHex(s[:], b)
+}
+
+// decodeHex decodes b into dst, failing if it doesn't decode into
+// exactly len(dst) bytes
+func decodeHex(dst, b []byte) error {
+	if hex.DecodedLen(len(b)) != len(dst) {
+		return fmt.Errorf("expected %d hex encoded bytes, got %d characters", len(dst), len(b))
+	}
+	_, err := hex.Decode(dst, b)
+	return err
+}
+
+// Announcement is an announcement as explorers serve it
+type Announcement struct {
+	AnnouncementSignature Signature `json:"announcementSignature"`
+	OraclePublicKey       XOnlyKey  `json:"oraclePublicKey"`
+	OracleEvent           Event     `json:"oracleEvent"`
+}
+
+// Event is the event of an explorer announcement
+type Event struct {
+	OracleNonces       []XOnlyKey      `json:"oracleNonces"`
+	EventMaturityEpoch uint32          `json:"eventMaturityEpoch"`
+	EventDescriptor    EventDescriptor `json:"eventDescriptor"`
+	EventID            string          `json:"eventId"`
+}
+
+// EventDescriptor holds exactly one of the descriptors of an explorer
+// event
+type EventDescriptor struct {
+	EnumEvent               *EnumEvent               `json:"enumEvent,omitempty"`
+	DigitDecompositionEvent *DigitDecompositionEvent `json:"digitDecompositionEvent,omitempty"`
+}
+
+// EnumEvent describes an event with one of a list of outcomes
+type EnumEvent struct {
+	Outcomes []string `json:"outcomes"`
+}
+
+// DigitDecompositionEvent describes a numeric event signed digit by
+// digit. Its value is in Unit multiplied by 10 to the power of Precision.
+type DigitDecompositionEvent struct {
+	Base      uint32 `json:"base"`
+	IsSigned  bool   `json:"isSigned"`
+	Unit      string `json:"unit"`
+	Precision int32  `json:"precision"`
+	NbDigits  uint32 `json:"nbDigits"`
+}
+
+// Attestation is an attestation as explorers serve it: the signature of
+// each nonce of the event and the outcome string it signs
+type Attestation struct {
+	EventID         string      `json:"eventId"`
+	OraclePublicKey XOnlyKey    `json:"oraclePublicKey"`
+	Signatures      []Signature `json:"signatures"`
+	Outcomes        []string    `json:"outcomes"`
+}
+
+// Descriptor converts the descriptor to this library's. Signed digit
+// decompositions have no counterpart.
+func (d EventDescriptor) Descriptor() (dlcoracle.EventDescriptor, error) {
+	var desc dlcoracle.EventDescriptor
+	switch {
+	case d.EnumEvent != nil && d.DigitDecompositionEvent != nil:
+		return desc, fmt.Errorf("descriptor is both an enum and a digit decomposition")
+	case d.EnumEvent != nil:
+		desc = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: d.EnumEvent.Outcomes}
+	case d.DigitDecompositionEvent != nil:
+		dd := d.DigitDecompositionEvent
+		if dd.IsSigned {
+			return desc, fmt.Errorf("signed digit decompositions are not supported")
+		}
+		desc = dlcoracle.EventDescriptor{
+			Type:         dlcoracle.EventTypeDigits,
+			Base:         dd.Base,
+			Digits:       dd.NbDigits,
+			Unit:         dd.Unit,
+			UnitExponent: dd.Precision,
+		}
+	default:
+		return desc, fmt.Errorf("empty descriptor")
+	}
+	return desc, desc.Validate()
+}
+
+// NewEventDescriptor converts an enum or digits descriptor to the
+// explorers'. The precision of digits events must be unset, as explorers
+// have no place for it.
+func NewEventDescriptor(d dlcoracle.EventDescriptor) (EventDescriptor, error) {
+	switch {
+	case d.Type == dlcoracle.EventTypeEnum:
+		return EventDescriptor{EnumEvent: &EnumEvent{Outcomes: d.Outcomes}}, nil
+	case d.Type == dlcoracle.EventTypeDigits && d.Precision <= 1:
+		return EventDescriptor{DigitDecompositionEvent: &DigitDecompositionEvent{
+			Base:      d.Base,
+			Unit:      d.Unit,
+			Precision: d.UnitExponent,
+			NbDigits:  d.Digits,
+		}}, nil
+	case d.Type == dlcoracle.EventTypeDigits:
+		return EventDescriptor{}, fmt.Errorf("explorers can't describe a precision of %d", d.Precision)
+	}
+	return EventDescriptor{}, fmt.Errorf("explorers can't describe %s events", d.Type)
+}
+
+// Event converts the event to this library's, checking that it has as
+// many nonces as its descriptor needs
+func (e Event) Event() (dlcoracle.Event, error) {
+	d, err := e.EventDescriptor.Descriptor()
+	if err != nil {
+		return dlcoracle.Event{}, fmt.Errorf("event %q: %w", e.EventID, err)
+	}
+	if len(e.OracleNonces) != d.NonceCount() {
+		return dlcoracle.Event{}, fmt.Errorf("event %q has %d nonces for %d", e.EventID, len(e.OracleNonces), d.NonceCount())
+	}
+	return dlcoracle.Event{
+		ID:         e.EventID,
+		Maturity:   time.Unix(int64(e.EventMaturityEpoch), 0).UTC(),
+		Descriptor: d,
+	}, nil
+}
+
+// Nonces returns the compressed encodings of the event's nonces, in the
+// order of their signatures
+func (e Event) Nonces() ([][33]byte, error) {
+	points := make([][33]byte, len(e.OracleNonces))
+	for i, k := range e.OracleNonces {
+		var err error
+		points[i], err = k.Point()
+		if err != nil {
+			return nil, fmt.Errorf("nonce %d: %w", i, err)
+		}
+	}
+	return points, nil
+}
+
+// Outcome returns the outcome the attestation signs, as an outcome of
+// the event e, after checking that it belongs to e and has a signature
+// per nonce. The signatures aren't verified.
+func (att Attestation) Outcome(e Event) (dlcoracle.Outcome, error) {
+	if att.EventID != e.EventID {
+		return dlcoracle.Outcome{}, fmt.Errorf("%w: attestation of %q, event %q",
+			dlcoracle.ErrEventMismatch, att.EventID, e.EventID)
+	}
+	ev, err := e.Event()
+	if err != nil {
+		return dlcoracle.Outcome{}, err
+	}
+	n := len(e.OracleNonces)
+	if len(att.Signatures) != n || len(att.Outcomes) != n {
+		return dlcoracle.Outcome{}, fmt.Errorf("%w: %d signatures and %d outcomes for %d nonces",
+			dlcoracle.ErrInvalidAttestation, len(att.Signatures), len(att.Outcomes), n)
+	}
+	msg := []byte(att.Outcomes[0])
+	if ev.Descriptor.Type == dlcoracle.EventTypeDigits {
+		msg = make([]byte, n)
+		for i, s := range att.Outcomes {
+			digit, err := strconv.ParseUint(s, 10, 8)
+			if err != nil || s != string(dlcoracle.DigitMessage(byte(digit))) {
+				return dlcoracle.Outcome{}, fmt.Errorf("%w: digit %d is %q", dlcoracle.ErrInvalidOutcome, i, s)
+			}
+			msg[i] = byte(digit)
+		}
+	}
+	o, err := ev.Descriptor.ParseOutcome(msg)
+	if err != nil {
+		return dlcoracle.Outcome{}, fmt.Errorf("%w: %v", dlcoracle.ErrInvalidOutcome, err)
+	}
+	return o, nil
+}
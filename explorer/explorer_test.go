@@ -0,0 +1,166 @@
+package explorer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Payloads in the layout explorers serve, indented as their APIs pretty
+// print them
+const (
+	goldenEnum = `{
+  "announcementSignature": "1d0c9a7e2b5f4c3a8e6d0b1f2a3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e",
+  "oraclePublicKey": "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+  "oracleEvent": {
+    "oracleNonces": [
+      "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+    ],
+    "eventMaturityEpoch": 1893456000,
+    "eventDescriptor": {
+      "enumEvent": {
+        "outcomes": ["yes", "no"]
+      }
+    },
+    "eventId": "rain"
+  }
+}`
+	goldenDigits = `{
+  "announcementSignature": "5e4d3c2b1a0998877665544332211000ffeeddccbbaa99887766554433221100112233445566778899aabbccddeeff00112233445566778899aabbccddeeff00",
+  "oraclePublicKey": "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+  "oracleEvent": {
+    "oracleNonces": [
+      "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+      "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+      "e493dbf1c10d80f3581e4904930b1404cc6c13900ee0758474fa94abe8c4cd13"
+    ],
+    "eventMaturityEpoch": 1893456000,
+    "eventDescriptor": {
+      "digitDecompositionEvent": {
+        "base": 10,
+        "isSigned": false,
+        "unit": "usd/btc",
+        "precision": 2,
+        "nbDigits": 3
+      }
+    },
+    "eventId": "price"
+  }
+}`
+	goldenAttestation = `{
+  "eventId": "price",
+  "oraclePublicKey": "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+  "signatures": [
+    "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee50000000000000000000000000000000000000000000000000000000000000001",
+    "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f90000000000000000000000000000000000000000000000000000000000000002",
+    "e493dbf1c10d80f3581e4904930b1404cc6c13900ee0758474fa94abe8c4cd130000000000000000000000000000000000000000000000000000000000000003"
+  ],
+  "outcomes": ["4", "2", "1"]
+}`
+)
+
+// roundTrip decodes golden into v and checks that encoding v gives the
+// compacted golden payload back
+func roundTrip(t *testing.T, golden string, v interface{}) {
+	t.Helper()
+	err := json.Unmarshal([]byte(golden), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	json.Compact(&want, []byte(golden))
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Fatalf("round trip changed the payload:\n%s\n%s", got, want.Bytes())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var enum, digits Announcement
+	roundTrip(t, goldenEnum, &enum)
+	roundTrip(t, goldenDigits, &digits)
+	var att Attestation
+	roundTrip(t, goldenAttestation, &att)
+
+	// Uppercase hex decodes to the same key and encodes back in lowercase
+	var upper Announcement
+	err := json.Unmarshal([]byte(strings.Replace(goldenEnum, "79be667ef9dcbbac", "79BE667EF9DCBBAC", 1)), &upper)
+	if err != nil || upper.OraclePublicKey != enum.OraclePublicKey {
+		t.Fatalf("uppercase key %x %v", upper.OraclePublicKey, err)
+	}
+	err = json.Unmarshal([]byte(strings.Replace(goldenEnum, "79be667e", "79be66", 1)), &upper)
+	if err == nil {
+		t.Fatal("decoded a short key")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	var a Announcement
+	json.Unmarshal([]byte(goldenDigits), &a)
+	ev, err := a.OracleEvent.Event()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := ev.Descriptor
+	if ev.ID != "price" || ev.Maturity.Unix() != 1893456000 || d.Type != dlcoracle.EventTypeDigits ||
+		d.Base != 10 || d.Digits != 3 || d.Unit != "usd/btc" || d.UnitExponent != 2 {
+		t.Fatalf("converted to %+v", ev)
+	}
+	back, err := NewEventDescriptor(d)
+	if err != nil || *back.DigitDecompositionEvent != *a.OracleEvent.EventDescriptor.DigitDecompositionEvent {
+		t.Fatalf("converted back to %+v %v", back, err)
+	}
+	nonces, err := a.OracleEvent.Nonces()
+	if err != nil || len(nonces) != 3 || nonces[1][0] != 0x02 {
+		t.Fatalf("nonces %x %v", nonces, err)
+	}
+
+	var att Attestation
+	json.Unmarshal([]byte(goldenAttestation), &att)
+	o, err := att.Outcome(a.OracleEvent)
+	if err != nil || o.Value != 421 {
+		t.Fatalf("outcome %+v %v", o, err)
+	}
+	if d.FormatValue(o.Value) != "42100 usd/btc" {
+		t.Fatalf("formatted %q", d.FormatValue(o.Value))
+	}
+	att.Outcomes[1] = "02"
+	_, err = att.Outcome(a.OracleEvent)
+	if !errors.Is(err, dlcoracle.ErrInvalidOutcome) {
+		t.Fatalf("padded digit: %v", err)
+	}
+	att.Outcomes = att.Outcomes[:2]
+	_, err = att.Outcome(a.OracleEvent)
+	if !errors.Is(err, dlcoracle.ErrInvalidAttestation) {
+		t.Fatalf("missing outcome: %v", err)
+	}
+
+	var enum Announcement
+	json.Unmarshal([]byte(goldenEnum), &enum)
+	enumAtt := Attestation{EventID: "rain", Signatures: make([]Signature, 1), Outcomes: []string{"no"}}
+	o, err = enumAtt.Outcome(enum.OracleEvent)
+	if err != nil || o.Label != "no" {
+		t.Fatalf("enum outcome %+v %v", o, err)
+	}
+	_, err = enumAtt.Outcome(a.OracleEvent)
+	if !errors.Is(err, dlcoracle.ErrEventMismatch) {
+		t.Fatalf("attestation of another event: %v", err)
+	}
+
+	_, err = NewEventDescriptor(dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric})
+	if err == nil {
+		t.Fatal("converted a numeric descriptor")
+	}
+	signed := EventDescriptor{DigitDecompositionEvent: &DigitDecompositionEvent{Base: 2, IsSigned: true, NbDigits: 4}}
+	_, err = signed.Descriptor()
+	if err == nil {
+		t.Fatal("converted a signed digit decomposition")
+	}
+}
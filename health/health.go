@@ -0,0 +1,197 @@
+// Package health checks the dependencies of a running oracle, such as
+// its store, its data sources, its key and its clock, for the liveness
+// and readiness probes of orchestration systems. A Checker runs its checks
+// concurrently on every request and answers with the result of each as
+// JSON, with status 200 if all passed and 503 otherwise.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// DefaultTimeout bounds each check
+const DefaultTimeout = 5 * time.Second
+
+// Status is the outcome of a check, or of all of them
+type Status string
+
+// The statuses
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Check is a named dependency check. Run returns nil if the dependency is
+// usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	// Duration is how long the check took, such as "1.2ms"
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all checks of a Checker. Its status is
+// StatusOK only if every check passed.
+type Report struct {
+	Status Status    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Result  `json:"checks"`
+}
+
+// Checker runs a set of checks
+type Checker struct {
+	mtx     sync.Mutex
+	checks  []Check
+	timeout time.Duration
+}
+
+// New returns a checker running checks
+func New(checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: DefaultTimeout}
+}
+
+// Add adds a check
+func (c *Checker) Add(name string, run func(ctx context.Context) error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.checks = append(c.checks, Check{Name: name, Run: run})
+}
+
+// Remove removes the checks with the names, such as those of data sources
+// that were removed
+func (c *Checker) Remove(names ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	kept := c.checks[:0:0]
+	for _, check := range c.checks {
+		removed := false
+		for _, name := range names {
+			removed = removed || check.Name == name
+		}
+		if !removed {
+			kept = append(kept, check)
+		}
+	}
+	c.checks = kept
+}
+
+// SetTimeout changes how long a check may take before it fails
+func (c *Checker) SetTimeout(d time.Duration) {
+	c.timeout = d
+}
+
+// Run runs all checks concurrently and returns their results ordered by
+// name
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mtx.Lock()
+	checks := c.checks
+	c.mtx.Unlock()
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	r := Report{Status: StatusOK, Time: time.Now().UTC(), Checks: results}
+	for _, res := range results {
+		if res.Status != StatusOK {
+			r.Status = StatusFail
+		}
+	}
+	return r
+}
+
+// run runs a single check, failing it if it doesn't return in time
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := Result{
+		Name:     check.Name,
+		Status:   StatusOK,
+		Duration: time.Since(start).Round(time.Microsecond).String(),
+	}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// ServeHTTP runs the checks and writes the report
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// Store checks that s is reachable. Stores that don't implement
+// storage.Pinger are checked by looking up an announcement.
+func Store(s storage.Store) Check {
+	return Check{Name: "store", Run: func(ctx context.Context) error {
+		if p, ok := s.(storage.Pinger); ok {
+			return p.Ping(ctx)
+		}
+		_, err := s.Announcement("health check")
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		return err
+	}}
+}
+
+// Source checks that the data source ds is reachable. It returns false if
+// ds doesn't implement datasource.Pinger, as there is no way to tell then.
+func Source(ds datasource.DataSource) (Check, bool) {
+	p, ok := ds.(datasource.Pinger)
+	if !ok {
+		return Check{}, false
+	}
+	return Check{Name: "source:" + ds.ID(), Run: p.Ping}, true
+}
+
+// Clock checks that c is trustworthy, see clock.Checker
+func Clock(c clock.Clock) Check {
+	return Check{Name: "clock", Run: func(ctx context.Context) error {
+		return clock.Check(c)
+	}}
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func get(t *testing.T, c *Checker) (int, Report) {
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var r Report
+	err := json.Unmarshal(rec.Body.Bytes(), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec.Code, r
+}
+
+func TestChecker(t *testing.T) {
+	c := New(Store(storage.NewMemoryStore()), Clock(clock.System()))
+	code, r := get(t, c)
+	if code != http.StatusOK || r.Status != StatusOK || len(r.Checks) != 2 ||
+		r.Checks[0].Name != "clock" || r.Checks[1].Name != "store" {
+		t.Fatalf("unexpected report %d %+v", code, r)
+	}
+
+	c.Add("exchange", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	c.Add("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	c.SetTimeout(20 * time.Millisecond)
+	code, r = get(t, c)
+	if code != http.StatusServiceUnavailable || r.Status != StatusFail {
+		t.Fatalf("unexpected report %d %+v", code, r)
+	}
+	failed := make(map[string]string)
+	for _, res := range r.Checks {
+		if res.Status == StatusFail {
+			failed[res.Name] = res.Error
+		}
+	}
+	if len(failed) != 2 || failed["exchange"] != "connection refused" ||
+		failed["slow"] != context.DeadlineExceeded.Error() {
+		t.Fatalf("unexpected failures %v", failed)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	c := New(Store(storage.NewMemoryStore()), Clock(clock.System()))
+	c.Add("source:exchange", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+	c.Remove("source:exchange", "source:unknown")
+	code, r := get(t, c)
+	if code != http.StatusOK || len(r.Checks) != 2 {
+		t.Fatalf("unexpected report %d %+v", code, r)
+	}
+}
+
+type pingingSource struct{ err error }
+
+func (pingingSource) ID() string       { return "exchange" }
+func (pingingSource) Describe() string { return "pings" }
+func (pingingSource) FetchOutcome(dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, nil
+}
+func (s pingingSource) Ping(ctx context.Context) error { return s.err }
+
+type silentSource struct{ pingingSource }
+
+func (silentSource) Ping() {}
+
+func TestSource(t *testing.T) {
+	down := errors.New("down")
+	check, ok := Source(pingingSource{err: down})
+	if !ok || check.Name != "source:exchange" || check.Run(context.Background()) != down {
+		t.Fatalf("unexpected check %v %v", check, ok)
+	}
+	_, ok = Source(silentSource{})
+	if ok {
+		t.Fatal("source without Ping checked")
+	}
+}
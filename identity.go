@@ -0,0 +1,128 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"time"
+)
+
+// identityTag separates identity signatures from any other message
+// signed with the oracle's key
+const identityTag = "DLC/oracle/identity"
+
+// OracleIdentity describes an oracle, signed with its long-term key so
+// clients can pin the key and display the oracle's name and endpoints
+// knowing the oracle published them.
+type OracleIdentity struct {
+	Name        string
+	Description string
+	PubKey      [33]byte
+
+	// Endpoints are the URLs the oracle serves announcements and
+	// attestations at
+	Endpoints []string
+
+	// Schemes are the signature schemes the oracle signs with
+	Schemes []Scheme
+
+	CreatedAt time.Time
+	Signature [65]byte
+}
+
+// SigningHash returns the digest of the identity's contents that the
+// oracle signs. CreatedAt is committed to with a precision of seconds.
+func (id OracleIdentity) SigningHash() [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	h.Write([]byte(identityTag))
+	writeString(h, id.Name)
+	writeString(h, id.Description)
+	h.Write(id.PubKey[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(len(id.Endpoints)))
+	h.Write(buf[:])
+	for _, e := range id.Endpoints {
+		writeString(h, e)
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(len(id.Schemes)))
+	h.Write(buf[:])
+	for _, s := range id.Schemes {
+		h.Write([]byte{byte(s)})
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(id.CreatedAt.Unix()))
+	h.Write(buf[:])
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// writeString writes a length prefixed string to a hash
+func writeString(h hash.Hash, s string) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+	h.Write(buf[:])
+	h.Write([]byte(s))
+}
+
+// Verify checks the oracle's signature on the identity. Clients pinning
+// an oracle also check that PubKey is the key they pinned.
+func (id OracleIdentity) Verify() error {
+	digest := id.SigningHash()
+	err := VerifyMessage(id.PubKey, digest[:], id.Signature)
+	if err != nil {
+		return fmt.Errorf("identity of %s: %w", id.Name, err)
+	}
+	return nil
+}
+
+type identityJSON struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	PubKey      string   `json:"pubKey"`
+	Endpoints   []string `json:"endpoints,omitempty"`
+	Schemes     []Scheme `json:"schemes,omitempty"`
+	CreatedAt   int64    `json:"createdAt"`
+	Signature   string   `json:"signature"`
+}
+
+// MarshalJSON encodes the identity with hex encoded key and signature,
+// schemes by name and the creation time as a unix timestamp in seconds
+func (id OracleIdentity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(identityJSON{
+		Name:        id.Name,
+		Description: id.Description,
+		PubKey:      hex.EncodeToString(id.PubKey[:]),
+		Endpoints:   id.Endpoints,
+		Schemes:     id.Schemes,
+		CreatedAt:   id.CreatedAt.Unix(),
+		Signature:   hex.EncodeToString(id.Signature[:]),
+	})
+}
+
+// UnmarshalJSON decodes an identity encoded by MarshalJSON. CreatedAt is
+// returned in UTC.
+func (id *OracleIdentity) UnmarshalJSON(b []byte) error {
+	var j identityJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	err = decodeHexKey(&id.PubKey, j.PubKey)
+	if err != nil {
+		return err
+	}
+	err = decodeHexFixed(id.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	id.Name = j.Name
+	id.Description = j.Description
+	id.Endpoints = j.Endpoints
+	id.Schemes = j.Schemes
+	id.CreatedAt = time.Unix(j.CreatedAt, 0).UTC()
+	return nil
+}
@@ -0,0 +1,20 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "fmt"
+
+// Sign signs the identity with the oracle's private key, which must match
+// PubKey. The options are passed on to SignMessage.
+func (id *OracleIdentity) Sign(privKey [32]byte, opts ...SignOption) error {
+	if PublicKeyFromPrivateKey(privKey) != id.PubKey {
+		return fmt.Errorf("private key does not match oracle pubkey")
+	}
+	digest := id.SigningHash()
+	sig, err := SignMessage(privKey, digest[:], opts...)
+	if err != nil {
+		return err
+	}
+	id.Signature = sig
+	return nil
+}
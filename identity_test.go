@@ -0,0 +1,93 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testIdentity(t *testing.T) (OracleIdentity, [32]byte) {
+	t.Helper()
+	var priv [32]byte
+	priv[31] = 7
+	id := OracleIdentity{
+		Name:        "example",
+		Description: "BTC/USD prices",
+		PubKey:      PublicKeyFromPrivateKey(priv),
+		Endpoints:   []string{"https://oracle.example.com", "nostr:npub1example"},
+		Schemes:     []Scheme{SchemeLIT, SchemeBIP340},
+		CreatedAt:   time.Date(2024, 1, 1, 0, 0, 0, 500, time.UTC),
+	}
+	err := id.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, priv
+}
+
+func TestOracleIdentity(t *testing.T) {
+	id, _ := testIdentity(t)
+	err := id.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []func(*OracleIdentity){
+		func(id *OracleIdentity) { id.Name = "other" },
+		func(id *OracleIdentity) { id.Description = "" },
+		func(id *OracleIdentity) { id.Endpoints = id.Endpoints[:1] },
+		func(id *OracleIdentity) { id.Schemes = []Scheme{SchemeECDSA} },
+		func(id *OracleIdentity) { id.CreatedAt = id.CreatedAt.Add(time.Second) },
+	}
+	for i, tamper := range tampered {
+		other := id
+		tamper(&other)
+		if other.Verify() == nil {
+			t.Errorf("tampered identity %d verified", i)
+		}
+	}
+
+	// Length prefixes keep bytes from moving between fields
+	moved := id
+	moved.Name, moved.Description = id.Name+id.Description, ""
+	if moved.SigningHash() == id.SigningHash() {
+		t.Fatal("moved bytes keep the signing hash")
+	}
+
+	var priv [32]byte
+	priv[31] = 8
+	if id.Sign(priv) == nil {
+		t.Fatal("signed with another key")
+	}
+}
+
+func TestOracleIdentityJSON(t *testing.T) {
+	id, _ := testIdentity(t)
+	b, err := json.Marshal(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"schemes":["lit","bip340"]`) {
+		t.Fatalf("schemes not encoded by name in %s", b)
+	}
+	var decoded OracleIdentity
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decoded.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bad := strings.Replace(string(b), `"bip340"`, `"rsa"`, 1)
+	if json.Unmarshal([]byte(bad), &decoded) == nil {
+		t.Fatal("unknown scheme accepted")
+	}
+	if _, err := json.Marshal(OracleIdentity{Schemes: []Scheme{9}}); err == nil {
+		t.Fatal("unknown scheme encoded")
+	}
+}
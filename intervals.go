@@ -0,0 +1,285 @@
+package dlcoracle
+
+import (
+	"fmt"
+	"math"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// Contracts on digits events don't need a transaction per outcome: the
+// outcomes from a value with its last k digits zero to the same value with
+// those digits at their maximum share a prefix, and the sum of the
+// anticipation points of the prefix digits locks a transaction that the
+// sum of their signatures unlocks. An interval of outcomes is covered by
+// few such prefixes, as in the DLC specifications' CET compression.
+
+// Interval is a range of outcomes, both ends included
+type Interval struct {
+	Start, End int64
+}
+
+// PrefixPoint is a digit prefix covering an interval of outcomes, and
+// its anticipation point
+type PrefixPoint struct {
+	Prefix   []byte
+	Interval Interval
+	Point    [33]byte
+}
+
+// CoverInterval returns the fewest digit prefixes covering the outcomes
+// from start to end of a digits event, in increasing order. An interval
+// of every outcome returns a single empty prefix.
+func (d EventDescriptor) CoverInterval(start, end int64) ([][]byte, error) {
+	var prefixes [][]byte
+	err := d.cover(start, end, func(prefix []byte, _ Interval) {
+		prefixes = append(prefixes, prefix)
+	})
+	return prefixes, err
+}
+
+// cover calls f with each prefix covering the interval, and the outcomes
+// it covers
+func (d EventDescriptor) cover(start, end int64, f func([]byte, Interval)) error {
+	if d.Type != EventTypeDigits {
+		return fmt.Errorf("%s event has no digits", d.Type)
+	}
+	if start < 0 || start > end {
+		return fmt.Errorf("invalid interval %d to %d", start, end)
+	}
+	// The end must have as many digits as the event
+	_, err := d.decompose(end)
+	if err != nil {
+		return err
+	}
+
+	base := uint64(d.Base)
+	v, stop := uint64(start), uint64(end)
+	for {
+		// Find the largest block of base**k outcomes starting at v
+		// that ends within the interval
+		k, size := 0, uint64(1)
+		for k < int(d.Digits) && size <= math.MaxUint64/base && v%(size*base) == 0 && size*base-1 <= stop-v {
+			k++
+			size *= base
+		}
+		digits, err := d.decompose(int64(v))
+		if err != nil {
+			return err
+		}
+		f(digits[:len(digits)-k], Interval{Start: int64(v), End: int64(v + size - 1)})
+		if v+size-1 >= stop {
+			return nil
+		}
+		v += size
+	}
+}
+
+// IntervalAnticipationPoints returns the digit prefixes covering the
+// outcomes from start to end of the digits event announced in a, with
+// the anticipation point of each: the sum of the anticipation points of
+// its digits. The anticipation points of single digits are computed
+// once and reused across prefixes.
+func IntervalAnticipationPoints(a Announcement, start, end int64) ([]PrefixPoint, error) {
+	if len(a.RPoints) != int(a.Descriptor.Digits) {
+		return nil, fmt.Errorf("announcement of %s has %d r points for %d digits",
+			a.EventID, len(a.RPoints), a.Descriptor.Digits)
+	}
+	c := digitPoints{a: a, points: make(map[[2]int]*btcecv2.JacobianPoint)}
+	var list []PrefixPoint
+	var err error
+	coverErr := a.Descriptor.cover(start, end, func(prefix []byte, iv Interval) {
+		if err != nil {
+			return
+		}
+		var point [33]byte
+		point, err = c.sum(prefix)
+		list = append(list, PrefixPoint{Prefix: prefix, Interval: iv, Point: point})
+	})
+	if coverErr != nil {
+		return nil, coverErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// PrefixAnticipationPoint returns the anticipation point of a digit
+// prefix of the digits event announced in a
+func PrefixAnticipationPoint(a Announcement, prefix []byte) ([33]byte, error) {
+	if len(prefix) > len(a.RPoints) {
+		return [33]byte{}, fmt.Errorf("prefix of %d digits, event has %d r points", len(prefix), len(a.RPoints))
+	}
+	c := digitPoints{a: a, points: make(map[[2]int]*btcecv2.JacobianPoint)}
+	return c.sum(prefix)
+}
+
+// digitPoints caches the anticipation points of single digits
+type digitPoints struct {
+	a      Announcement
+	points map[[2]int]*btcecv2.JacobianPoint
+}
+
+func (c digitPoints) point(i int, digit byte) (*btcecv2.JacobianPoint, error) {
+	key := [2]int{i, int(digit)}
+	if p, ok := c.points[key]; ok {
+		return p, nil
+	}
+	if uint32(digit) >= c.a.Descriptor.Base {
+		return nil, fmt.Errorf("digit %d out of range for base %d", digit, c.a.Descriptor.Base)
+	}
+	b, err := ComputeSignaturePubKey(c.a.OraclePubKey, c.a.RPoints[i], DigitMessage(digit))
+	if err != nil {
+		return nil, err
+	}
+	pub, err := parsePubKey("anticipation point", b)
+	if err != nil {
+		return nil, err
+	}
+	p := new(btcecv2.JacobianPoint)
+	pub.AsJacobian(p)
+	c.points[key] = p
+	return p, nil
+}
+
+func (c digitPoints) sum(prefix []byte) ([33]byte, error) {
+	var out [33]byte
+	if len(prefix) == 0 {
+		return out, fmt.Errorf("empty prefix covers every outcome and needs no signature")
+	}
+	var sum btcecv2.JacobianPoint
+	for i, digit := range prefix {
+		p, err := c.point(i, digit)
+		if err != nil {
+			return out, err
+		}
+		if i == 0 {
+			sum.Set(p)
+			continue
+		}
+		var next btcecv2.JacobianPoint
+		btcecv2.AddNonConst(&sum, p, &next)
+		sum.Set(&next)
+	}
+	if (sum.X.IsZero() && sum.Y.IsZero()) || sum.Z.IsZero() {
+		return out, fmt.Errorf("anticipation point is the point at infinity")
+	}
+	sum.ToAffine()
+	copy(out[:], btcecv2.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed())
+	return out, nil
+}
+
+// PrefixSignature returns the sum of the digit signatures of an
+// attestation of a digits event for the first n digits, the private key
+// of the anticipation point of that prefix
+func PrefixSignature(att Attestation, n int) ([32]byte, error) {
+	if n <= 0 || n > len(att.Signatures) {
+		return [32]byte{}, fmt.Errorf("prefix of %d digits, attestation has %d signatures", n, len(att.Signatures))
+	}
+	var sum btcecv2.ModNScalar
+	for _, sig := range att.Signatures[:n] {
+		var s btcecv2.ModNScalar
+		if s.SetBytes(&sig) != 0 {
+			return [32]byte{}, &ScalarError{Name: "signature", Err: ErrScalarOutOfRange}
+		}
+		sum.Add(&s)
+	}
+	return sum.Bytes(), nil
+}
+
+// RoundingInterval rounds outcomes from Begin up to the next interval's
+// Begin to the nearest multiple of Modulus, halves rounding up
+type RoundingInterval struct {
+	Begin   int64
+	Modulus int64
+}
+
+// RoundingIntervals lists rounding intervals by increasing Begin. Outcomes
+// before the first interval aren't rounded.
+type RoundingIntervals []RoundingInterval
+
+// Validate checks that the intervals are ordered and their moduli positive
+func (r RoundingIntervals) Validate() error {
+	for i, iv := range r {
+		if iv.Modulus <= 0 {
+			return fmt.Errorf("rounding interval %d has modulus %d", i, iv.Modulus)
+		}
+		if i > 0 && iv.Begin <= r[i-1].Begin {
+			return fmt.Errorf("rounding interval %d doesn't begin after the previous one", i)
+		}
+	}
+	return nil
+}
+
+// interval returns the modulus applying to x and the last outcome it
+// applies to
+func (r RoundingIntervals) interval(x int64) (int64, int64) {
+	mod, last := int64(1), int64(math.MaxInt64)
+	for _, iv := range r {
+		if iv.Begin > x {
+			last = iv.Begin - 1
+			break
+		}
+		mod = iv.Modulus
+	}
+	return mod, last
+}
+
+// Round returns the value outcome x is rounded to
+func (r RoundingIntervals) Round(x int64) int64 {
+	mod, _ := r.interval(x)
+	rem := (x%mod + mod) % mod
+	if 2*rem < mod {
+		return x - rem
+	}
+	if x > math.MaxInt64-(mod-rem) {
+		return x - rem
+	}
+	return x + mod - rem
+}
+
+// RoundedInterval is an interval of outcomes rounding to the same Value
+type RoundedInterval struct {
+	Interval
+	Value int64
+}
+
+// Intervals splits the outcomes from start to end into intervals of
+// outcomes rounding to the same value. Each needs a single transaction,
+// whose outcomes IntervalAnticipationPoints covers.
+func (r RoundingIntervals) Intervals(start, end int64) ([]RoundedInterval, error) {
+	err := r.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || start > end {
+		return nil, fmt.Errorf("invalid interval %d to %d", start, end)
+	}
+	var list []RoundedInterval
+	for v := start; ; {
+		value := r.Round(v)
+		mod, last := r.interval(v)
+		// Outcomes up to value + (mod-1)/2 round to value
+		hi := int64(math.MaxInt64)
+		if value <= math.MaxInt64-(mod-1)/2 {
+			hi = value + (mod-1)/2
+		}
+		if last < hi {
+			hi = last
+		}
+		if end < hi {
+			hi = end
+		}
+		n := len(list)
+		if n > 0 && list[n-1].Value == value && list[n-1].End == v-1 {
+			list[n-1].End = hi
+		} else {
+			list = append(list, RoundedInterval{Interval: Interval{Start: v, End: hi}, Value: value})
+		}
+		if hi >= end {
+			return list, nil
+		}
+		v = hi + 1
+	}
+}
@@ -0,0 +1,123 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCoverInterval(t *testing.T) {
+	d := EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 3}
+	cases := []struct {
+		start, end int64
+		want       string
+	}{
+		{0, 999, "[[]]"},
+		{0, 99, "[[0]]"},
+		{5, 5, "[[0 0 5]]"},
+		{15, 234, "[[0 1 5] [0 1 6] [0 1 7] [0 1 8] [0 1 9] [0 2] [0 3] [0 4] [0 5] [0 6] [0 7] [0 8] [0 9] [1] [2 0] [2 1] [2 2] [2 3 0] [2 3 1] [2 3 2] [2 3 3] [2 3 4]]"},
+	}
+	for _, c := range cases {
+		prefixes, err := d.CoverInterval(c.start, c.end)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(prefixes); got != c.want {
+			t.Errorf("%d to %d: got %s, want %s", c.start, c.end, got, c.want)
+		}
+	}
+
+	for _, iv := range []Interval{{-1, 5}, {6, 5}, {0, 1000}} {
+		_, err := d.CoverInterval(iv.Start, iv.End)
+		if err == nil {
+			t.Errorf("interval %+v accepted", iv)
+		}
+	}
+	_, err := EventDescriptor{Type: EventTypeNumeric}.CoverInterval(0, 1)
+	if err == nil {
+		t.Fatal("numeric event covered")
+	}
+
+	// The largest binary events don't overflow
+	b := EventDescriptor{Type: EventTypeDigits, Base: 2, Digits: MaxDigits}
+	prefixes, err := b.CoverInterval(1<<62, 1<<63-1)
+	if err != nil || fmt.Sprint(prefixes) != "[[1]]" {
+		t.Fatalf("got %v, %v", prefixes, err)
+	}
+}
+
+func TestIntervalAnticipationPoints(t *testing.T) {
+	a, priv, k := testDigitsAnnouncement(t, 2, 8)
+	points, err := IntervalAnticipationPoints(a, 40, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 40-47, 48-63, 64-95, 96-99 and 100
+	if len(points) != 5 {
+		t.Fatalf("got %d prefixes", len(points))
+	}
+	for _, value := range []int64{40, 63, 64, 99, 100} {
+		att, err := SignOutcome(priv, k, a, Outcome{Value: value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, p := range points {
+			if value < p.Interval.Start || value > p.Interval.End {
+				continue
+			}
+			found = true
+			sig, err := PrefixSignature(att, len(p.Prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if PublicKeyFromPrivateKey(sig) != p.Point {
+				t.Fatalf("outcome %d doesn't unlock prefix %v", value, p.Prefix)
+			}
+			point, err := PrefixAnticipationPoint(a, p.Prefix)
+			if err != nil || point != p.Point {
+				t.Fatalf("prefix %v: got %x, %v", p.Prefix, point, err)
+			}
+		}
+		if !found {
+			t.Fatalf("outcome %d not covered", value)
+		}
+	}
+
+	_, err = PrefixAnticipationPoint(a, []byte{2})
+	if err == nil {
+		t.Fatal("digit out of base accepted")
+	}
+	_, err = PrefixAnticipationPoint(a, nil)
+	if err == nil {
+		t.Fatal("empty prefix accepted")
+	}
+}
+
+func TestRoundingIntervals(t *testing.T) {
+	r := RoundingIntervals{{Begin: 0, Modulus: 1}, {Begin: 10, Modulus: 4}, {Begin: 20, Modulus: 10}}
+	for x, want := range map[int64]int64{5: 5, 11: 12, 13: 12, 14: 16, 24: 20, 25: 30} {
+		if got := r.Round(x); got != want {
+			t.Errorf("%d rounds to %d, want %d", x, got, want)
+		}
+	}
+
+	intervals, err := r.Intervals(8, 31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[{{8 8} 8} {{9 9} 9} {{10 13} 12} {{14 17} 16} {{18 24} 20} {{25 31} 30}]"
+	if got := fmt.Sprint(intervals); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	_, err = RoundingIntervals{{Begin: 0, Modulus: 0}}.Intervals(0, 1)
+	if err == nil {
+		t.Fatal("zero modulus accepted")
+	}
+	_, err = RoundingIntervals{{Begin: 5, Modulus: 2}, {Begin: 5, Modulus: 3}}.Intervals(0, 1)
+	if err == nil {
+		t.Fatal("unordered intervals accepted")
+	}
+}
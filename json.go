@@ -0,0 +1,199 @@
+package dlcoracle
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type announcementJSON struct {
+	EventID        string          `json:"eventId"`
+	OraclePubKey   string          `json:"oraclePubKey"`
+	RPoint         string          `json:"rPoint"`
+	RPoints        []string        `json:"rPoints,omitempty"`
+	Maturity       int64           `json:"maturity"`
+	MaturityHeight uint32          `json:"maturityHeight,omitempty"`
+	Descriptor     EventDescriptor `json:"descriptor"`
+	Metadata       *EventMetadata  `json:"metadata,omitempty"`
+	Signature      string          `json:"signature"`
+}
+
+// metadataJSON returns m to encode, or nil if it is empty
+func metadataJSON(m EventMetadata) *EventMetadata {
+	if m.IsZero() {
+		return nil
+	}
+	return &m
+}
+
+// MarshalJSON encodes the announcement with hex encoded keys and the
+// maturity as a unix timestamp in seconds. Sub-second precision is
+// dropped, as it is in the signed announcement.
+func (a Announcement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(announcementJSON{
+		EventID:        a.EventID,
+		OraclePubKey:   hex.EncodeToString(a.OraclePubKey[:]),
+		RPoint:         hex.EncodeToString(a.RPoint[:]),
+		RPoints:        encodeKeys(a.RPoints, false),
+		Maturity:       a.Maturity.Unix(),
+		MaturityHeight: a.MaturityHeight,
+		Descriptor:     a.Descriptor,
+		Metadata:       metadataJSON(a.Metadata),
+		Signature:      hex.EncodeToString(a.Signature[:]),
+	})
+}
+
+// MarshalXOnlyJSON encodes the announcement like MarshalJSON, but with
+// x-only public key and R point. It fails if either has an odd Y, which
+// the x-only encoding can't represent.
+func (a Announcement) MarshalXOnlyJSON() ([]byte, error) {
+	odd := !HasEvenY(a.OraclePubKey) || !HasEvenY(a.RPoint)
+	for _, R := range a.RPoints {
+		odd = odd || !HasEvenY(R)
+	}
+	if odd {
+		return nil, fmt.Errorf("announcement of %s has keys with an odd Y", a.EventID)
+	}
+	pubKey, rPoint := XOnly(a.OraclePubKey), XOnly(a.RPoint)
+	return json.Marshal(announcementJSON{
+		EventID:        a.EventID,
+		OraclePubKey:   hex.EncodeToString(pubKey[:]),
+		RPoint:         hex.EncodeToString(rPoint[:]),
+		RPoints:        encodeKeys(a.RPoints, true),
+		Maturity:       a.Maturity.Unix(),
+		MaturityHeight: a.MaturityHeight,
+		Descriptor:     a.Descriptor,
+		Metadata:       metadataJSON(a.Metadata),
+		Signature:      hex.EncodeToString(a.Signature[:]),
+	})
+}
+
+// UnmarshalJSON decodes an announcement encoded by MarshalJSON or
+// MarshalXOnlyJSON. Maturity is returned in UTC.
+func (a *Announcement) UnmarshalJSON(b []byte) error {
+	var j announcementJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	err = decodeHexKey(&a.OraclePubKey, j.OraclePubKey)
+	if err != nil {
+		return err
+	}
+	err = decodeHexKey(&a.RPoint, j.RPoint)
+	if err != nil {
+		return err
+	}
+	a.RPoints = nil
+	if len(j.RPoints) > 0 {
+		a.RPoints = make([][33]byte, len(j.RPoints))
+	}
+	for i, s := range j.RPoints {
+		err = decodeHexKey(&a.RPoints[i], s)
+		if err != nil {
+			return err
+		}
+	}
+	err = decodeHexFixed(a.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	a.EventID = j.EventID
+	a.Maturity = time.Unix(j.Maturity, 0).UTC()
+	a.MaturityHeight = j.MaturityHeight
+	a.Descriptor = j.Descriptor
+	a.Metadata = EventMetadata{}
+	if j.Metadata != nil {
+		a.Metadata = *j.Metadata
+	}
+	return nil
+}
+
+type attestationJSON struct {
+	EventID    string   `json:"eventId"`
+	Message    string   `json:"message"`
+	Signature  string   `json:"signature"`
+	Signatures []string `json:"signatures,omitempty"`
+}
+
+// MarshalJSON encodes the attestation with hex encoded message and
+// signatures
+func (a Attestation) MarshalJSON() ([]byte, error) {
+	j := attestationJSON{
+		EventID:   a.EventID,
+		Message:   hex.EncodeToString(a.Message),
+		Signature: hex.EncodeToString(a.Signature[:]),
+	}
+	for _, sig := range a.Signatures {
+		j.Signatures = append(j.Signatures, hex.EncodeToString(sig[:]))
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes an attestation encoded by MarshalJSON
+func (a *Attestation) UnmarshalJSON(b []byte) error {
+	var j attestationJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	msg, err := hex.DecodeString(j.Message)
+	if err != nil {
+		return err
+	}
+	err = decodeHexFixed(a.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	a.Signatures = nil
+	if len(j.Signatures) > 0 {
+		a.Signatures = make([][32]byte, len(j.Signatures))
+	}
+	for i, s := range j.Signatures {
+		err = decodeHexFixed(a.Signatures[i][:], s)
+		if err != nil {
+			return err
+		}
+	}
+	a.EventID = j.EventID
+	a.Message = msg
+	return nil
+}
+
+// encodeKeys hex encodes keys, x-only if xOnly is set
+func encodeKeys(keys [][33]byte, xOnly bool) []string {
+	var list []string
+	for _, k := range keys {
+		if xOnly {
+			list = append(list, hex.EncodeToString(k[1:]))
+		} else {
+			list = append(list, hex.EncodeToString(k[:]))
+		}
+	}
+	return list
+}
+
+// decodeHexKey decodes a compressed or x-only public key. X-only keys are
+// returned with the prefix of an even Y.
+func decodeHexKey(dst *[33]byte, s string) error {
+	if len(s) == 2*32 {
+		dst[0] = 0x02
+		return decodeHexFixed(dst[1:], s)
+	}
+	return decodeHexFixed(dst[:], s)
+}
+
+// decodeHexFixed decodes s into dst, failing if it doesn't decode into
+// exactly len(dst) bytes
+func decodeHexFixed(dst []byte, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("expected %d bytes, got %d", len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
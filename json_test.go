@@ -0,0 +1,68 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnnouncementJSONRoundTrip(t *testing.T) {
+	a, priv := testAnnouncement(t)
+	a.Descriptor = EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	a.MaturityHeight = 800000
+	err := a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Maturity = time.Unix(1000, 0).In(time.FixedZone("test", 3600))
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Announcement
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Maturity.Location() != time.UTC || !got.Maturity.Equal(a.Maturity) {
+		t.Fatalf("maturity %v did not round trip to UTC", got.Maturity)
+	}
+	a.Maturity = a.Maturity.UTC()
+	if !reflect.DeepEqual(got, a) {
+		t.Fatalf("announcement did not round trip: %+v", got)
+	}
+}
+
+func TestAttestationJSONRoundTrip(t *testing.T) {
+	a := Attestation{
+		EventID:   "event",
+		Message:   GenerateNumericMessage(7),
+		Signature: [32]byte{1, 2, 3},
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Attestation
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EventID != a.EventID || !bytes.Equal(got.Message, a.Message) ||
+		got.Signature != a.Signature {
+		t.Fatalf("attestation did not round trip: %+v", got)
+	}
+}
+
+func TestAnnouncementJSONBadKey(t *testing.T) {
+	var a Announcement
+	err := json.Unmarshal([]byte(`{"oraclePubKey":"00"}`), &a)
+	if err == nil {
+		t.Fatal("accepted a short pubkey")
+	}
+}
@@ -0,0 +1,52 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// The oracle of mit-dci/lit derives the one-time signing key of each
+// event from the oracle's private key, the index of the data source or
+// key the event belongs to and the event's timestamp, without a tag.
+// Oracles migrating from it keep serving the R points they published
+// with the functions below.
+
+// DeriveLITOneTimeSigningKey returns the one-time signing key lit's
+// oracle derives for a key index and timestamp: HMAC-SHA256 keyed with
+// the private key of both, as big-endian 64-bit integers
+func DeriveLITOneTimeSigningKey(privKey [32]byte, keyIndex, timestamp uint64) ([32]byte, error) {
+	err := checkScalar("private key", privKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[:8], keyIndex)
+	binary.BigEndian.PutUint64(buf[8:], timestamp)
+
+	var k [32]byte
+	mac := hmac.New(sha256.New, privKey[:])
+	mac.Write(buf[:])
+	copy(k[:], mac.Sum(nil))
+
+	// Like a hash bigger than N this happens about once every 2**128
+	// keys
+	err = checkScalar(fmt.Sprintf("lit key %d at %d", keyIndex, timestamp), k)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return k, nil
+}
+
+// LITRPoint returns the R point lit's oracle publishes for a key index
+// and timestamp
+func LITRPoint(privKey [32]byte, keyIndex, timestamp uint64) ([33]byte, error) {
+	k, err := DeriveLITOneTimeSigningKey(privKey, keyIndex, timestamp)
+	if err != nil {
+		return [33]byte{}, err
+	}
+	return PublicKeyFromPrivateKey(k), nil
+}
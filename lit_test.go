@@ -0,0 +1,36 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestDeriveLITOneTimeSigningKey(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	k, err := DeriveLITOneTimeSigningKey(priv, 3, 1700000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(k[:]) != "41dc8963f2e88553e6bf847dc52e51cf59d7f4d72873133db5777a5c3b0de71a" {
+		t.Fatalf("got %x", k)
+	}
+	R, err := LITRPoint(priv, 3, 1700000000)
+	if err != nil || R != PublicKeyFromPrivateKey(k) {
+		t.Fatalf("got %x, %v", R, err)
+	}
+
+	// Key index and timestamp are not interchangeable
+	other, err := DeriveLITOneTimeSigningKey(priv, 1700000000, 3)
+	if err != nil || other == k {
+		t.Fatalf("got %x, %v", other, err)
+	}
+
+	_, err = DeriveLITOneTimeSigningKey([32]byte{}, 3, 1700000000)
+	if !errors.Is(err, ErrZeroScalar) {
+		t.Fatalf("zero private key: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package dlcoracle
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels, from least to most severe
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// Field is a key/value pair attached to a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a log field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger receives the log entries of the oracle. Implement it to route
+// them into zap, zerolog or another logging library; SlogLogger adapts
+// log/slog. Every message the oracle signs is logged at LevelInfo, so the
+// log doubles as an audit trail.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...Field) {}
+
+// NopLogger returns a logger discarding everything. It is the default.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// SlogLogger returns a Logger writing to l
+func SlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Log(level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	s.l.LogAttrs(context.Background(), slog.Level((level-LevelInfo)*4), msg, attrs...)
+}
+
+type loggerHolder struct {
+	Logger
+}
+
+var packageLogger atomic.Value
+
+func init() {
+	packageLogger.Store(loggerHolder{NopLogger()})
+}
+
+// SetLogger sets the logger of the signing functions in this package. A
+// nil logger discards the log.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NopLogger()
+	}
+	packageLogger.Store(loggerHolder{l})
+}
+
+func logger() Logger {
+	return packageLogger.Load().(loggerHolder).Logger
+}
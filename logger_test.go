@@ -0,0 +1,48 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type recordingLogger struct {
+	entries []string
+}
+
+func (r *recordingLogger) Log(level Level, msg string, fields ...Field) {
+	r.entries = append(r.entries, level.String()+" "+msg)
+}
+
+func TestSetLogger(t *testing.T) {
+	var rec recordingLogger
+	SetLogger(&rec)
+	defer SetLogger(nil)
+
+	var priv, k [32]byte
+	priv[31] = 1
+	k[31] = 2
+	_, err := ComputeSignature(priv, k, []byte("message"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.entries) != 1 || rec.entries[0] != "debug computed signature" {
+		t.Fatalf("unexpected log entries %v", rec.entries)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := SlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	l.Log(LevelDebug, "low", F("n", 1))
+	l.Log(LevelWarn, "high", F("event_id", "e"))
+	out := buf.String()
+	for _, s := range []string{"level=DEBUG msg=low n=1", "level=WARN msg=high event_id=e"} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("missing %q in %q", s, out)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package metrics instruments an oracle with Prometheus metrics: the
+// signatures it produces, how long attestations take after maturity, the
+// latency and failures of data sources, pending scheduler jobs and HTTP
+// requests. The signing path has metrics of its own, apart from the
+// requests leading to it: how long signing takes, how many signatures
+// each operation produces, verification failures and rejected one-time
+// signing keys.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes all metric names
+const namespace = "dlcoracle"
+
+// Metrics holds the oracle's collectors in their own registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	signatures     *prometheus.CounterVec
+	timeToAttest   prometheus.Histogram
+	sourceLatency  *prometheus.HistogramVec
+	sourceFailures *prometheus.CounterVec
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+
+	signDuration         *prometheus.HistogramVec
+	signErrors           *prometheus.CounterVec
+	signBatchSize        *prometheus.HistogramVec
+	verificationFailures *prometheus.CounterVec
+	nonceRejections      *prometheus.CounterVec
+}
+
+// New returns a new set of oracle metrics, registered together with the
+// Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		signatures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "signatures_total",
+			Help:      "Signatures produced by the oracle, by kind.",
+		}, []string{"kind"}),
+		timeToAttest: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "time_to_attest_seconds",
+			Help:      "Time between an event's maturity and its attestation.",
+			Buckets:   []float64{1, 5, 15, 60, 300, 900, 3600, 4 * 3600, 24 * 3600},
+		}),
+		sourceLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "datasource_fetch_seconds",
+			Help:      "Latency of fetching outcomes from data sources.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"source"}),
+		sourceFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "datasource_failures_total",
+			Help:      "Failed outcome fetches, by data source.",
+		}, []string{"source"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method and status code.",
+		}, []string{"method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+		signDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "signing",
+			Name:      "duration_seconds",
+			Help:      "Time taken by signing operations, by kind of record.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 9),
+		}, []string{"kind"}),
+		signErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "signing",
+			Name:      "errors_total",
+			Help:      "Failed signing operations, by kind of record.",
+		}, []string{"kind"}),
+		signBatchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "signing",
+			Name:      "batch_size",
+			Help:      "Signatures produced by each signing operation, such as one per digit of a digits event, by kind of record.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 9),
+		}, []string{"kind"}),
+		verificationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "signing",
+			Name:      "verification_failures_total",
+			Help:      "Records signed elsewhere that failed verification, by kind of record.",
+		}, []string{"kind"}),
+		nonceRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "signing",
+			Name:      "nonce_rejections_total",
+			Help:      "One-time signing keys the oracle refused to sign with, by reason.",
+		}, []string{"reason"}),
+	}
+	m.registry.MustRegister(
+		m.signatures,
+		m.timeToAttest,
+		m.sourceLatency,
+		m.sourceFailures,
+		m.httpRequests,
+		m.httpDuration,
+		m.signDuration,
+		m.signErrors,
+		m.signBatchSize,
+		m.verificationFailures,
+		m.nonceRejections,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are registered in, for adding
+// collectors of the embedding application
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns the handler serving the metrics, to be exposed at
+// /metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentHTTP wraps h so its requests are counted and timed. It fits
+// server.Server.Use.
+func (m *Metrics) InstrumentHTTP(h http.Handler) http.Handler {
+	return promhttp.InstrumentHandlerCounter(m.httpRequests,
+		promhttp.InstrumentHandlerDuration(m.httpDuration, h))
+}
+
+// WatchScheduler exports the number of jobs pending in s
+func (m *Metrics) WatchScheduler(s *scheduler.Scheduler) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_events",
+		Help:      "Announced events waiting to be attested.",
+	}, func() float64 {
+		return float64(len(s.Pending()))
+	}))
+}
+
+// WatchOracle counts the announcements and attestations o signs and
+// measures how long after maturity each event is attested, until ctx is
+// cancelled. If it falls behind and o drops its subscription, it
+// subscribes again, so counts may be slightly low under heavy load.
+func (m *Metrics) WatchOracle(ctx context.Context, o *oracle.Oracle) {
+	for ctx.Err() == nil {
+		updates, cancel := o.Subscribe()
+		m.watch(ctx, o, updates)
+		cancel()
+	}
+}
+
+func (m *Metrics) watch(ctx context.Context, o *oracle.Oracle, updates <-chan oracle.Update) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			if u.Announcement != nil {
+				m.signatures.WithLabelValues("announcement").Inc()
+				continue
+			}
+			m.signatures.WithLabelValues("attestation").Inc()
+			a, err := o.Store().Announcement(u.Attestation.EventID)
+			if err == nil {
+				m.timeToAttest.Observe(time.Since(a.Maturity).Seconds())
+			}
+		}
+	}
+}
+
+// ObserveSigning implements oracle.SigningObserver. Failed operations
+// are counted but not timed.
+func (m *Metrics) ObserveSigning(kind string, signatures int, d time.Duration, err error) {
+	if err != nil {
+		m.signErrors.WithLabelValues(kind).Inc()
+		return
+	}
+	m.signDuration.WithLabelValues(kind).Observe(d.Seconds())
+	m.signBatchSize.WithLabelValues(kind).Observe(float64(signatures))
+}
+
+// ObserveVerificationFailure implements oracle.SigningObserver
+func (m *Metrics) ObserveVerificationFailure(kind string) {
+	m.verificationFailures.WithLabelValues(kind).Inc()
+}
+
+// ObserveNonceRejection implements oracle.SigningObserver
+func (m *Metrics) ObserveNonceRejection(reason string) {
+	m.nonceRejections.WithLabelValues(reason).Inc()
+}
+
+// WatchSignerPool exports the counters of p: the batches it computed and
+// their items, failed batches and the mean latency of items and batches
+func (m *Metrics) WatchSignerPool(p *dlcoracle.SignerPool) {
+	counter := func(name, help string, f func(s dlcoracle.PoolStats) float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "signer_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return f(p.Stats()) })
+	}
+	gauge := func(name, help string, f func(s dlcoracle.PoolStats) float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "signer_pool",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return f(p.Stats()) })
+	}
+	m.registry.MustRegister(
+		counter("batches_total", "Batches the signer pool completed.",
+			func(s dlcoracle.PoolStats) float64 { return float64(s.Batches) }),
+		counter("items_total", "Signatures and points computed in completed batches.",
+			func(s dlcoracle.PoolStats) float64 { return float64(s.Items) }),
+		counter("failed_batches_total", "Batches of the signer pool that failed or were cancelled.",
+			func(s dlcoracle.PoolStats) float64 { return float64(s.Failed) }),
+		gauge("item_latency_seconds", "Mean time a worker spent on an item.",
+			func(s dlcoracle.PoolStats) float64 { return s.ItemLatency.Seconds() }),
+		gauge("batch_latency_seconds", "Mean time from submitting a batch to its result.",
+			func(s dlcoracle.PoolStats) float64 { return s.BatchLatency.Seconds() }),
+	)
+}
+
+// instrumentedSource records the latency and failures of a data source.
+// An outcome that isn't available yet is not a failure.
+type instrumentedSource struct {
+	datasource.DataSource
+	latency  prometheus.Observer
+	failures prometheus.Counter
+}
+
+// InstrumentSource wraps ds so its fetch latency and failures are
+// recorded. Register the returned data source instead of ds.
+func (m *Metrics) InstrumentSource(ds datasource.DataSource) datasource.DataSource {
+	return &instrumentedSource{
+		DataSource: ds,
+		latency:    m.sourceLatency.WithLabelValues(ds.ID()),
+		failures:   m.sourceFailures.WithLabelValues(ds.ID()),
+	}
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *instrumentedSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	start := time.Now()
+	o, err := s.DataSource.FetchOutcome(ev)
+	s.latency.Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, datasource.ErrNotAvailable) {
+		s.failures.Inc()
+	}
+	return o, err
+}
@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/mit-dci/dlc-oracle-go/server"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type failingSource struct{ err error }
+
+func (s failingSource) ID() string       { return "failing" }
+func (s failingSource) Describe() string { return "always fails" }
+func (s failingSource) FetchOutcome(dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, s.err
+}
+
+func TestInstrumentSource(t *testing.T) {
+	m := New()
+	ds := m.InstrumentSource(failingSource{err: errors.New("down")})
+	if ds.ID() != "failing" {
+		t.Fatalf("unexpected id %s", ds.ID())
+	}
+	ds.FetchOutcome(dlcoracle.Event{})
+	m.InstrumentSource(failingSource{err: datasource.ErrNotAvailable}).FetchOutcome(dlcoracle.Event{})
+	if n := testutil.ToFloat64(m.sourceFailures.WithLabelValues("failing")); n != 1 {
+		t.Fatalf("expected 1 failure, got %v", n)
+	}
+}
+
+func TestWatchOracle(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	m := New()
+	sched := scheduler.New(o, datasource.NewRegistry())
+	m.WatchScheduler(sched)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.WatchOracle(ctx, o)
+	// Give the watcher time to subscribe
+	time.Sleep(50 * time.Millisecond)
+
+	_, err := sched.Schedule(dlcoracle.Event{ID: "event", Maturity: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.AttestOutcome("event", dlcoracle.Outcome{Value: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(m.signatures.WithLabelValues("attestation")) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("attestation was not counted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := testutil.ToFloat64(m.signatures.WithLabelValues("announcement")); n != 1 {
+		t.Fatalf("expected 1 announcement, got %v", n)
+	}
+
+	srv := server.NewServer(o.PubKey(), o.Store())
+	srv.Use(m.InstrumentHTTP)
+	srv.Handle("GET /metrics", m.Handler())
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, name := range []string{
+		"dlcoracle_signatures_total",
+		"dlcoracle_time_to_attest_seconds",
+		"dlcoracle_pending_events 1",
+	} {
+		if !strings.Contains(string(body), name) {
+			t.Fatalf("metrics output is missing %s", name)
+		}
+	}
+}
+
+func TestSigningMetrics(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	m := New()
+	o.SetSigningObserver(m)
+
+	digits := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 4}
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0), Descriptor: digits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.AttestOutcome("event", dlcoracle.Outcome{Value: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.AttestOutcome("event", dlcoracle.Outcome{Value: 43})
+	if !errors.Is(err, oracle.ErrAlreadyAttested) {
+		t.Fatalf("expected ErrAlreadyAttested, got %v", err)
+	}
+	err = o.ImportAttestation(dlcoracle.Attestation{EventID: "event", Message: []byte{0, 0, 4, 3}})
+	if err == nil {
+		t.Fatal("imported a forged attestation")
+	}
+
+	if n := testutil.CollectAndCount(m.signDuration); n != 2 {
+		t.Fatalf("expected durations of 2 kinds, got %d", n)
+	}
+	if n := testutil.ToFloat64(m.nonceRejections.WithLabelValues(oracle.NonceUsed)); n != 1 {
+		t.Fatalf("expected 1 rejected nonce, got %v", n)
+	}
+	if n := testutil.ToFloat64(m.verificationFailures.WithLabelValues("attestation")); n != 1 {
+		t.Fatalf("expected 1 verification failure, got %v", n)
+	}
+	expected := `
+# HELP dlcoracle_signing_batch_size Signatures produced by each signing operation, such as one per digit of a digits event, by kind of record.
+# TYPE dlcoracle_signing_batch_size histogram
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="1"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="2"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="4"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="8"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="16"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="32"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="64"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="128"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="256"} 1
+dlcoracle_signing_batch_size_bucket{kind="announcement",le="+Inf"} 1
+dlcoracle_signing_batch_size_sum{kind="announcement"} 1
+dlcoracle_signing_batch_size_count{kind="announcement"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="1"} 0
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="2"} 0
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="4"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="8"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="16"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="32"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="64"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="128"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="256"} 1
+dlcoracle_signing_batch_size_bucket{kind="attestation",le="+Inf"} 1
+dlcoracle_signing_batch_size_sum{kind="attestation"} 4
+dlcoracle_signing_batch_size_count{kind="attestation"} 1
+`
+	err = testutil.CollectAndCompare(m.signBatchSize, strings.NewReader(expected), "dlcoracle_signing_batch_size")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchSignerPool(t *testing.T) {
+	p := dlcoracle.NewSignerPool(2)
+	defer p.Close()
+	m := New()
+	m.WatchSignerPool(p)
+	_, err := p.ComputeSignatures(context.Background(), [32]byte{31: 1},
+		[]dlcoracle.SignRequest{{OneTimeSigningKey: [32]byte{31: 2}, Message: []byte("a")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := testutil.GatherAndCount(m.Registry(), "dlcoracle_signer_pool_items_total")
+	if err != nil || n != 1 {
+		t.Fatalf("%d series: %v", n, err)
+	}
+	ts := httptest.NewServer(m.Handler())
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "dlcoracle_signer_pool_items_total 1") {
+		t.Fatalf("pool items not exported:\n%s", body)
+	}
+}
@@ -0,0 +1,211 @@
+// Package mobile is a binding layer for gomobile, so mobile DLC wallets
+// can compute anticipation points and verify attestations on the device:
+//
+//	gomobile bind -target=android ./mobile
+//	gomobile bind -target=ios ./mobile
+//
+// Exported signatures only use the types gomobile supports: keys, points,
+// messages and signatures are byte slices, announcements and attestations
+// are JSON in the format of the REST API, and times are Unix timestamps.
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// copyFixed copies b into dst, which it must fill exactly
+func copyFixed(dst []byte, name string, b []byte) error {
+	if len(b) != len(dst) {
+		return fmt.Errorf("%s is %d bytes, expected %d", name, len(b), len(dst))
+	}
+	copy(dst, b)
+	return nil
+}
+
+// ComputeSignaturePubKey returns the point the oracle's signature of
+// message with R point rPoint will be the discrete log of
+func ComputeSignaturePubKey(pubKey, rPoint, message []byte) ([]byte, error) {
+	var pub, r [33]byte
+	err := copyFixed(pub[:], "public key", pubKey)
+	if err != nil {
+		return nil, err
+	}
+	err = copyFixed(r[:], "R point", rPoint)
+	if err != nil {
+		return nil, err
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(pub, r, message)
+	if err != nil {
+		return nil, err
+	}
+	return point[:], nil
+}
+
+// VerifySignature checks a 32-byte signature of message made with the R
+// point rPoint
+func VerifySignature(pubKey, rPoint, message, signature []byte) error {
+	var pub, r [33]byte
+	var sig [32]byte
+	err := copyFixed(pub[:], "public key", pubKey)
+	if err != nil {
+		return err
+	}
+	err = copyFixed(r[:], "R point", rPoint)
+	if err != nil {
+		return err
+	}
+	err = copyFixed(sig[:], "signature", signature)
+	if err != nil {
+		return err
+	}
+	return dlcoracle.VerifySignature(pub, r, message, sig)
+}
+
+// VerifyMessage checks a 65-byte signature of message
+func VerifyMessage(pubKey, message, signature []byte) error {
+	var pub [33]byte
+	var sig [65]byte
+	err := copyFixed(pub[:], "public key", pubKey)
+	if err != nil {
+		return err
+	}
+	err = copyFixed(sig[:], "signature", signature)
+	if err != nil {
+		return err
+	}
+	return dlcoracle.VerifyMessage(pub, message, sig)
+}
+
+// NumericMessage returns the message the oracle signs for a numeric
+// outcome
+func NumericMessage(value int64) ([]byte, error) {
+	if value < 0 {
+		return nil, fmt.Errorf("numeric outcome %d is negative", value)
+	}
+	return dlcoracle.GenerateNumericMessage(uint64(value)), nil
+}
+
+// Outcome is the value an event resolved to: Value for numeric events,
+// Label for enumerated ones and Bytes for byte string events
+type Outcome struct {
+	Value int64
+	Label string
+	Bytes []byte
+}
+
+// ParseOutcome decodes an outcome from JSON, such as {"value":42} or
+// {"label":"yes"}
+func ParseOutcome(b []byte) (*Outcome, error) {
+	var o dlcoracle.Outcome
+	err := json.Unmarshal(b, &o)
+	if err != nil {
+		return nil, fmt.Errorf("decoding outcome: %v", err)
+	}
+	return &Outcome{Value: o.Value, Label: o.Label, Bytes: o.Bytes}, nil
+}
+
+// MarshalJSON encodes the outcome like the REST API
+func (o *Outcome) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dlcoracle.Outcome{Value: o.Value, Label: o.Label, Bytes: o.Bytes})
+}
+
+// Announcement is a verified oracle announcement
+type Announcement struct {
+	a dlcoracle.Announcement
+}
+
+// ParseAnnouncement decodes an announcement from JSON and verifies the
+// oracle's signature on it
+func ParseAnnouncement(b []byte) (*Announcement, error) {
+	a, err := dlcoracle.ParseAnnouncement(b)
+	if err != nil {
+		return nil, err
+	}
+	err = a.Verify()
+	if err != nil {
+		return nil, err
+	}
+	return &Announcement{a: a}, nil
+}
+
+// EventID returns the ID of the announced event
+func (a *Announcement) EventID() string {
+	return a.a.EventID
+}
+
+// OraclePubKey returns the public key of the oracle
+func (a *Announcement) OraclePubKey() []byte {
+	return a.a.OraclePubKey[:]
+}
+
+// RPoint returns the R point the outcome will be signed with
+func (a *Announcement) RPoint() []byte {
+	return a.a.RPoint[:]
+}
+
+// Maturity returns the Unix time the event matures at, or an estimate
+// for events maturing at a block height
+func (a *Announcement) Maturity() int64 {
+	return a.a.Maturity.Unix()
+}
+
+// MaturityHeight returns the block height the event matures at, or zero
+func (a *Announcement) MaturityHeight() int64 {
+	return int64(a.a.MaturityHeight)
+}
+
+// EventType returns "numeric", "enum", "bytes" or "digits"
+func (a *Announcement) EventType() string {
+	return a.a.Descriptor.Type.String()
+}
+
+// NumOutcomes returns the number of possible outcomes of an enumerated
+// event
+func (a *Announcement) NumOutcomes() int {
+	return len(a.a.Descriptor.Outcomes)
+}
+
+// OutcomeLabel returns possible outcome i of an enumerated event
+func (a *Announcement) OutcomeLabel(i int) (string, error) {
+	if i < 0 || i >= len(a.a.Descriptor.Outcomes) {
+		return "", fmt.Errorf("outcome %d out of range", i)
+	}
+	return a.a.Descriptor.Outcomes[i], nil
+}
+
+// AnticipationPoint returns the signature point of an outcome
+func (a *Announcement) AnticipationPoint(outcome *Outcome) ([]byte, error) {
+	if outcome == nil {
+		return nil, fmt.Errorf("no outcome")
+	}
+	msg, err := a.a.Descriptor.OutcomeMessage(dlcoracle.Outcome{
+		Value: outcome.Value,
+		Label: outcome.Label,
+		Bytes: outcome.Bytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(a.a.OraclePubKey, a.a.RPoint, msg)
+	if err != nil {
+		return nil, err
+	}
+	return point[:], nil
+}
+
+// VerifyAttestation decodes an attestation from JSON, checks it against
+// the announcement and returns the attested outcome
+func (a *Announcement) VerifyAttestation(b []byte) (*Outcome, error) {
+	att, err := dlcoracle.ParseAttestation(b)
+	if err != nil {
+		return nil, err
+	}
+	o, err := dlcoracle.VerifyAttestation(a.a, att)
+	if err != nil {
+		return nil, err
+	}
+	return &Outcome{Value: o.Value, Label: o.Label, Bytes: o.Bytes}, nil
+}
@@ -0,0 +1,51 @@
+//go:build !verifyonly
+
+package mobile
+
+import "github.com/mit-dci/dlc-oracle-go"
+
+// PublicKey returns the 33-byte compressed public key of a 32-byte
+// private key
+func PublicKey(privKey []byte) ([]byte, error) {
+	var priv [32]byte
+	err := copyFixed(priv[:], "private key", privKey)
+	if err != nil {
+		return nil, err
+	}
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	return pub[:], nil
+}
+
+// ComputeSignature signs message with the private key and the one-time
+// signing key of the R point
+func ComputeSignature(privKey, oneTimeKey, message []byte) ([]byte, error) {
+	var priv, k [32]byte
+	err := copyFixed(priv[:], "private key", privKey)
+	if err != nil {
+		return nil, err
+	}
+	err = copyFixed(k[:], "one-time signing key", oneTimeKey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := dlcoracle.ComputeSignature(priv, k, message)
+	if err != nil {
+		return nil, err
+	}
+	return sig[:], nil
+}
+
+// SignMessage signs message with the private key, returning a 65-byte
+// signature
+func SignMessage(privKey, message []byte) ([]byte, error) {
+	var priv [32]byte
+	err := copyFixed(priv[:], "private key", privKey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := dlcoracle.SignMessage(priv, message)
+	if err != nil {
+		return nil, err
+	}
+	return sig[:], nil
+}
@@ -0,0 +1,109 @@
+//go:build !verifyonly
+
+package mobile
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestAnnouncement(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	ann := dlcoracle.Announcement{
+		EventID:      "ev",
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey(k),
+		Maturity:     time.Unix(1000, 0),
+		Descriptor:   dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	err := ann.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annJSON, err := json.Marshal(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ParseAnnouncement(annJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.EventID() != "ev" || a.EventType() != "enum" || a.NumOutcomes() != 2 || a.Maturity() != 1000 {
+		t.Fatalf("unexpected announcement %+v", a.a)
+	}
+	label, err := a.OutcomeLabel(1)
+	if err != nil || label != "no" {
+		t.Fatalf("got outcome %q, %v", label, err)
+	}
+
+	point, err := a.AnticipationPoint(&Outcome{Label: "no"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ComputeSignaturePubKey(a.OraclePubKey(), a.RPoint(), []byte("no"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(point, expected) {
+		t.Fatalf("anticipation point %x, expected %x", point, expected)
+	}
+
+	sig, err := ComputeSignature(priv[:], k[:], []byte("no"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(a.OraclePubKey(), a.RPoint(), []byte("no"), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var att dlcoracle.Attestation
+	att.EventID, att.Message = "ev", []byte("no")
+	copy(att.Signature[:], sig)
+	attJSON, err := json.Marshal(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := a.VerifyAttestation(attJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Label != "no" {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+	b, err := outcome.MarshalJSON()
+	if err != nil || string(b) != `{"label":"no"}` {
+		t.Fatalf("encoded outcome as %s, %v", b, err)
+	}
+	parsed, err := ParseOutcome(b)
+	if err != nil || parsed.Label != "no" {
+		t.Fatalf("parsed outcome %+v, %v", parsed, err)
+	}
+
+	// Tampered announcements are rejected
+	annJSON = bytes.Replace(annJSON, []byte(`"ev"`), []byte(`"ev2"`), 1)
+	_, err = ParseAnnouncement(annJSON)
+	if err == nil {
+		t.Fatal("tampered announcement accepted")
+	}
+}
+
+func TestInvalidLengths(t *testing.T) {
+	_, err := PublicKey(make([]byte, 31))
+	if err == nil {
+		t.Fatal("short private key accepted")
+	}
+	_, err = ComputeSignaturePubKey(make([]byte, 33), nil, []byte("x"))
+	if err == nil {
+		t.Fatal("missing R point accepted")
+	}
+	_, err = NumericMessage(-1)
+	if err == nil {
+		t.Fatal("negative value accepted")
+	}
+}
@@ -0,0 +1,44 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"io"
+)
+
+// SignOption configures how messages are signed and verified, and how
+// one-time signing keys are generated
+type SignOption func(*signConfig)
+
+type signConfig struct {
+	// aux is read for auxiliary randomness, or nil for none
+	aux io.Reader
+
+	// The options of Sign and Verify
+	scheme Scheme
+	norm   Normalization
+	nonce  NonceDerivation
+	hash   HashFunc
+	strict bool
+}
+
+func newSignConfig(aux io.Reader, opts []SignOption) signConfig {
+	c := signConfig{aux: aux, hash: SHA256}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// taggedHash is the BIP-340 tagged hash of the concatenated data
+func taggedHash(tag string, data ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
@@ -0,0 +1,95 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// Tags of the hashes deriving synthetic nonces, after BIP-340
+const (
+	auxTag            = "DLC/oracle/aux"
+	syntheticNonceTag = "DLC/oracle/nonce/synthetic"
+
+	// configNonceTag derives the nonces of Sign configured otherwise
+	// than SignMessage signs, with the configuration as a domain
+	configNonceTag = "DLC/oracle/nonce/config"
+)
+
+// WithAuxRand mixes 32 bytes of auxiliary randomness read from r into
+// the one-time signing key, which is otherwise derived deterministically
+// from the private key. Like BIP-340 nonces the result stays safe if r
+// is broken, while a fault injected into the deterministic derivation
+// can no longer make the oracle reuse a nonce with another message.
+func WithAuxRand(r io.Reader) SignOption {
+	return func(c *signConfig) {
+		c.aux = r
+	}
+}
+
+// WithDeterministicNonce derives one-time signing keys without auxiliary
+// randomness, for reproducible signatures
+func WithDeterministicNonce() SignOption {
+	return WithAuxRand(nil)
+}
+
+// nonceDomain returns the domain separating the nonces of Sign's
+// configurations: the scheme, the normalizations that apply and the hash
+// of configNonceTag under the hash function, which tells hash functions
+// apart. It is nil for the configuration SignMessage signs with, whose
+// nonces are kept. Without a domain, signing the same message in two
+// configurations would reuse k with different challenges, and with a
+// deterministic or broken auxiliary randomness reveal the private key.
+func (c signConfig) nonceDomain() []byte {
+	fingerprint := c.hash([]byte(configNonceTag))
+	if c.scheme == SchemeLIT && c.normalization() == 0 && fingerprint == SHA256([]byte(configNonceTag)) {
+		return nil
+	}
+	return append([]byte{byte(c.scheme), byte(c.normalization())}, fingerprint[:]...)
+}
+
+// syntheticNonce derives a one-time signing key from the private key,
+// the data it will sign and auxiliary randomness read from c.aux, or
+// zeros if it is nil. It follows the nonce generation of BIP-340. A
+// domain, as returned by nonceDomain, is hashed under a tag of its own
+// between the public key and the data.
+func (c signConfig) syntheticNonce(privKey [32]byte, domain, data []byte) ([32]byte, error) {
+	var aux, t [32]byte
+	if c.aux != nil {
+		_, err := io.ReadFull(c.aux, aux[:])
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("reading auxiliary randomness: %v", err)
+		}
+	}
+	auxHash := taggedHash(auxTag, aux[:])
+	for i := range t {
+		t[i] = privKey[i] ^ auxHash[i]
+	}
+	pubKey := PublicKeyFromPrivateKey(privKey)
+	var k [32]byte
+	if domain == nil {
+		k = taggedHash(syntheticNonceTag, t[:], pubKey[:], data)
+	} else {
+		k = taggedHash(configNonceTag, t[:], pubKey[:], domain, data)
+	}
+
+	// Like a hash bigger than N this happens about once every 2**128
+	// keys
+	var scalar btcecv2.ModNScalar
+	if scalar.SetBytes(&k) != 0 || scalar.IsZero() {
+		return [32]byte{}, &ScalarError{Name: "synthetic nonce", Err: ErrScalarOutOfRange}
+	}
+	return k, nil
+}
+
+// syntheticIndexNonce derives the one-time signing key of a nonce index
+// with auxiliary randomness
+func (c signConfig) syntheticIndexNonce(privKey [32]byte, index uint64) ([32]byte, error) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], index)
+	return c.syntheticNonce(privKey, nil, append([]byte(nonceDerivationTag), buf[:]...))
+}
@@ -0,0 +1,94 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+func TestSignMessageNonces(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	msg := []byte("message")
+
+	// Fresh randomness by default, so signatures differ
+	a, err := SignMessage(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := SignMessage(priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("signatures with fresh randomness are equal")
+	}
+
+	// Deterministic nonces repeat for the same message only
+	c, err := SignMessage(priv, msg, WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := SignMessage(priv, msg, WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := SignMessage(priv, []byte("other"), WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != d || bytes.Equal(c[:33], e[:33]) {
+		t.Fatal("deterministic nonces don't depend on the message only")
+	}
+
+	for _, sig := range [][65]byte{a, b, c} {
+		err = VerifyMessage(pub, msg, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = SignMessage(priv, msg, WithAuxRand(failingReader{}))
+	if err == nil {
+		t.Fatal("signed without randomness")
+	}
+}
+
+func TestDeriveOneTimeSigningKeyAuxRand(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	aux := bytes.Repeat([]byte{7}, 64)
+
+	plain, err := DeriveOneTimeSigningKey(priv, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := DeriveOneTimeSigningKey(priv, 3, WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain != again {
+		t.Fatal("deterministic derivation changed")
+	}
+
+	k1, err := DeriveOneTimeSigningKey(priv, 3, WithAuxRand(bytes.NewReader(aux)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := DeriveOneTimeSigningKey(priv, 4, WithAuxRand(bytes.NewReader(aux)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k1 == plain || k1 == k2 {
+		t.Fatal("synthetic keys collide")
+	}
+}
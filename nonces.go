@@ -0,0 +1,37 @@
+package dlcoracle
+
+import "fmt"
+
+// NonceCount returns how many R points an announcement of an event with
+// this descriptor commits to: one per digit for digits events, one for
+// any other
+func (d EventDescriptor) NonceCount() int {
+	if d.Type == EventTypeDigits {
+		return int(d.Digits)
+	}
+	return 1
+}
+
+// NoncePoints returns the R points the announcement commits to, in the
+// order the outcome's signatures use them: RPoints if it has them,
+// otherwise just RPoint
+func (a Announcement) NoncePoints() [][33]byte {
+	if len(a.RPoints) > 0 {
+		return a.RPoints
+	}
+	return [][33]byte{a.RPoint}
+}
+
+// checkNonces checks that the announcement has as many R points as its
+// descriptor needs, and that RPoint is the first of them
+func (a Announcement) checkNonces() error {
+	switch {
+	case a.Descriptor.Type != EventTypeDigits && len(a.RPoints) > 0:
+		return fmt.Errorf("%w: %s event with digit r points", ErrInvalidRecord, a.Descriptor.Type)
+	case a.Descriptor.Type == EventTypeDigits && len(a.RPoints) != a.Descriptor.NonceCount():
+		return fmt.Errorf("%w: %d r points for %d digits", ErrInvalidRecord, len(a.RPoints), a.Descriptor.Digits)
+	case a.Descriptor.Type == EventTypeDigits && a.RPoints[0] != a.RPoint:
+		return fmt.Errorf("%w: r point is not the first digit's", ErrInvalidRecord)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "fmt"
+
+// EventSigningKeys returns the one-time signing keys of every R point
+// an announcement of an event with descriptor d commits to, in the order
+// of Announcement.NoncePoints: the event's oneTimeSigningKey itself, or
+// for digits events the keys DeriveDigitSigningKeys derives from it
+func EventSigningKeys(oneTimeSigningKey [32]byte, d EventDescriptor) ([][32]byte, error) {
+	if d.Type == EventTypeDigits {
+		if d.Digits == 0 {
+			return nil, fmt.Errorf("digits event without digits")
+		}
+		return DeriveDigitSigningKeys(oneTimeSigningKey, d.NonceCount())
+	}
+	err := checkScalar("k", oneTimeSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	return [][32]byte{oneTimeSigningKey}, nil
+}
+
+// DeriveEventSigningKeys derives the one-time signing key with the given
+// index from the oracle's private key, like DeriveOneTimeSigningKey, and
+// returns the keys of every R point of an event with descriptor d from
+// it, like EventSigningKeys. All of an event's nonces hang off the single
+// index, so they can be recomputed from the private key and the index.
+func DeriveEventSigningKeys(privKey [32]byte, index uint64, d EventDescriptor, opts ...SignOption) ([][32]byte, error) {
+	k, err := DeriveOneTimeSigningKey(privKey, index, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return EventSigningKeys(k, d)
+}
+
+// EventRPoints returns the R points to announce for an event with
+// descriptor d, in the order of Announcement.NoncePoints
+func EventRPoints(oneTimeSigningKey [32]byte, d EventDescriptor) ([][33]byte, error) {
+	keys, err := EventSigningKeys(oneTimeSigningKey, d)
+	if err != nil {
+		return nil, err
+	}
+	points := make([][33]byte, len(keys))
+	for i, k := range keys {
+		points[i] = PublicKeyFromPrivateKey(k)
+	}
+	return points, nil
+}
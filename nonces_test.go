@@ -0,0 +1,80 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEventNonces(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 42
+	digits := EventDescriptor{Type: EventTypeDigits, Base: 2, Digits: 4}
+	enum := EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	if digits.NonceCount() != 4 || enum.NonceCount() != 1 {
+		t.Fatalf("nonce counts %d and %d", digits.NonceCount(), enum.NonceCount())
+	}
+
+	keys, err := DeriveEventSigningKeys(priv, 3, digits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, _ := DeriveOneTimeSigningKey(priv, 3)
+	digitKeys, _ := DeriveDigitSigningKeys(k, 4)
+	for i := range keys {
+		if keys[i] != digitKeys[i] {
+			t.Fatalf("key %d differs from the digit key", i)
+		}
+	}
+	one, err := DeriveEventSigningKeys(priv, 3, enum)
+	if err != nil || len(one) != 1 || one[0] != k {
+		t.Fatalf("enum keys %x %v", one, err)
+	}
+	_, err = EventSigningKeys(k, EventDescriptor{Type: EventTypeDigits, Base: 2})
+	if err == nil {
+		t.Fatal("derived keys of a digits event without digits")
+	}
+
+	maturity := time.Unix(1000, 0)
+	for _, d := range []EventDescriptor{digits, enum} {
+		a, err := NewAnnouncement(priv, k, Event{ID: "ev", Maturity: maturity, Descriptor: d})
+		if err != nil {
+			t.Fatal(err)
+		}
+		points := a.NoncePoints()
+		if len(points) != d.NonceCount() || points[0] != a.RPoint {
+			t.Fatalf("%s: nonce points %x", d.Type, points)
+		}
+		keys, _ := EventSigningKeys(k, d)
+		for i := range points {
+			if PublicKeyFromPrivateKey(keys[i]) != points[i] {
+				t.Fatalf("%s: r point %d doesn't match its key", d.Type, i)
+			}
+		}
+		outcome := Outcome{Value: 9, Label: "no"}
+		att, err := SignOutcome(priv, k, a, outcome)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = VerifyAttestation(a, att)
+		if err != nil {
+			t.Fatalf("%s: %v", d.Type, err)
+		}
+	}
+
+	// The order of the R points is committed to
+	a, _ := NewAnnouncement(priv, k, Event{ID: "ev", Maturity: maturity, Descriptor: digits})
+	swapped := a
+	swapped.RPoints = append([][33]byte(nil), a.RPoints...)
+	swapped.RPoints[1], swapped.RPoints[2] = swapped.RPoints[2], swapped.RPoints[1]
+	if swapped.Verify() == nil {
+		t.Fatal("announcement with reordered r points verified")
+	}
+	short := a
+	short.RPoints = a.RPoints[:3]
+	if err := short.checkNonces(); !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("announcement with a missing r point: %v", err)
+	}
+}
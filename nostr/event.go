@@ -0,0 +1,104 @@
+package nostr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// Event is a Nostr event as defined in NIP-01
+type Event struct {
+	ID        string     `json:"id"`
+	PubKey    string     `json:"pubkey"`
+	CreatedAt int64      `json:"created_at"`
+	Kind      int        `json:"kind"`
+	Tags      [][]string `json:"tags"`
+	Content   string     `json:"content"`
+	Sig       string     `json:"sig"`
+}
+
+// serialize returns the canonical serialization the event ID commits to
+func (e *Event) serialize() ([]byte, error) {
+	tags := e.Tags
+	if tags == nil {
+		tags = [][]string{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// NIP-01 serializes without escaping HTML characters
+	enc.SetEscapeHTML(false)
+	err := enc.Encode([]interface{}{0, e.PubKey, e.CreatedAt, e.Kind, tags, e.Content})
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func (e *Event) hash() ([]byte, error) {
+	b, err := e.serialize()
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(b)
+	return h[:], nil
+}
+
+// PublicKey returns the x-only public key of a Nostr private key, hex
+// encoded as Nostr uses it
+func PublicKey(privKey [32]byte) string {
+	_, pub := btcec.PrivKeyFromBytes(privKey[:])
+	return hex.EncodeToString(schnorr.SerializePubKey(pub))
+}
+
+// Sign sets the event's public key, ID and BIP-340 signature
+func (e *Event) Sign(privKey [32]byte) error {
+	priv, pub := btcec.PrivKeyFromBytes(privKey[:])
+	e.PubKey = hex.EncodeToString(schnorr.SerializePubKey(pub))
+	h, err := e.hash()
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.Sign(priv, h)
+	if err != nil {
+		return err
+	}
+	e.ID = hex.EncodeToString(h)
+	e.Sig = hex.EncodeToString(sig.Serialize())
+	return nil
+}
+
+// Verify checks the event's ID and signature
+func (e *Event) Verify() error {
+	h, err := e.hash()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(h) != e.ID {
+		return fmt.Errorf("event id does not match its content")
+	}
+	pubBytes, err := hex.DecodeString(e.PubKey)
+	if err != nil {
+		return err
+	}
+	pub, err := schnorr.ParsePubKey(pubBytes)
+	if err != nil {
+		return err
+	}
+	sigBytes, err := hex.DecodeString(e.Sig)
+	if err != nil {
+		return err
+	}
+	sig, err := schnorr.ParseSignature(sigBytes)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(h, pub) {
+		return fmt.Errorf("invalid event signature")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// testRelay is a minimal relay accepting valid events. It rejects the
+// first failures events it receives.
+type testRelay struct {
+	mtx      sync.Mutex
+	failures int
+	events   []Event
+}
+
+func (r *testRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var msg []json.RawMessage
+	err = conn.ReadJSON(&msg)
+	if err != nil || len(msg) != 2 {
+		return
+	}
+	var ev Event
+	err = json.Unmarshal(msg[1], &ev)
+	if err != nil {
+		return
+	}
+
+	r.mtx.Lock()
+	ok, reason := true, ""
+	if r.failures > 0 {
+		r.failures--
+		ok, reason = false, "error: try again"
+	} else if ev.Verify() != nil {
+		ok, reason = false, "invalid: bad signature"
+	} else {
+		r.events = append(r.events, ev)
+	}
+	r.mtx.Unlock()
+	conn.WriteJSON([]interface{}{"NOTICE", "hello"})
+	conn.WriteJSON([]interface{}{"OK", ev.ID, ok, reason})
+}
+
+func (r *testRelay) received() []Event {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+func startRelay(t *testing.T, failures int) (*testRelay, string) {
+	r := &testRelay{failures: failures}
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return r, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestEventSignature(t *testing.T) {
+	ev := Event{CreatedAt: 1700000000, Kind: 1, Content: "<hello & \"nostr\">"}
+	err := ev.Sign([32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ev.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ev.serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(s), `"<hello & \"nostr\">"`) || !strings.Contains(string(s), `,[],`) {
+		t.Fatalf("unexpected serialization %s", s)
+	}
+	ev.Content = "changed"
+	if ev.Verify() == nil {
+		t.Fatal("modified event verified")
+	}
+}
+
+func TestPublishRetries(t *testing.T) {
+	relay, url := startRelay(t, 2)
+	p := NewPublisher([32]byte{1}, []string{url})
+	p.SetRetry(10*time.Millisecond, 3)
+	ev, err := p.AttestationEvent(dlcoracle.Attestation{EventID: "event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.Publish(context.Background(), ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relay.received()) != 1 {
+		t.Fatal("relay did not receive the event")
+	}
+
+	_, failing := startRelay(t, 10)
+	p = NewPublisher([32]byte{1}, []string{failing})
+	p.SetRetry(10*time.Millisecond, 2)
+	err = p.Publish(context.Background(), ev)
+	if err == nil || !strings.Contains(err.Error(), "try again") {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "old", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relay, url := startRelay(t, 0)
+	p := NewPublisher([32]byte{2}, []string{url})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Run(ctx, o)
+	// Give the publisher time to subscribe
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "new", Maturity: time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeBytes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("new", []byte("yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(relay.received()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("relay received %d events", len(relay.received()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	events := relay.received()
+	if events[0].Kind != KindAnnouncement || events[1].Kind != KindAttestation {
+		t.Fatalf("unexpected kinds %d %d", events[0].Kind, events[1].Kind)
+	}
+	if events[0].PubKey != p.PubKey() || events[0].Tags[0][1] != "new" {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+	var a dlcoracle.Announcement
+	err = json.Unmarshal([]byte(events[0].Content), &a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,371 @@
+// Package nostr publishes an oracle's announcements and attestations as
+// Nostr events, so clients can discover the oracle on public relays
+// instead of a bespoke explorer.
+//
+// Announcements are published as kind 88 and attestations as kind 89
+// events, the kinds of the draft DLC-over-Nostr convention. The content is
+// the JSON encoding used by the REST API, and a "d" tag carries the event
+// ID, so clients can filter with {"kinds": [88, 89], "#d": [<event id>]}.
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+const (
+	// KindAnnouncement is the Nostr event kind of oracle announcements
+	KindAnnouncement = 88
+
+	// KindAttestation is the Nostr event kind of oracle attestations
+	KindAttestation = 89
+)
+
+const (
+	// DefaultRetryInterval is how long to wait before publishing to a
+	// relay again after it failed
+	DefaultRetryInterval = 10 * time.Second
+
+	// DefaultMaxAttempts is how often publishing to a relay is tried
+	DefaultMaxAttempts = 5
+
+	// relayTimeout bounds a single attempt to publish to a relay
+	relayTimeout = 15 * time.Second
+
+	// catchUpInterval is how often records whose publication failed are
+	// tried again
+	catchUpInterval = 10 * time.Minute
+)
+
+// Publisher posts announcements and attestations to a set of relays
+type Publisher struct {
+	key    [32]byte
+	relays []string
+	dialer *websocket.Dialer
+	logger dlcoracle.Logger
+
+	retryInterval time.Duration
+	maxAttempts   int
+
+	mtx       sync.Mutex
+	published map[string]bool
+}
+
+// NewPublisher returns a publisher signing its Nostr events with key and
+// posting them to relays, given as ws:// or wss:// URLs. Use a dedicated
+// Nostr key rather than the oracle's signing key.
+func NewPublisher(key [32]byte, relays []string) *Publisher {
+	return &Publisher{
+		key:           key,
+		relays:        relays,
+		dialer:        websocket.DefaultDialer,
+		logger:        dlcoracle.NopLogger(),
+		retryInterval: DefaultRetryInterval,
+		maxAttempts:   DefaultMaxAttempts,
+		published:     make(map[string]bool),
+	}
+}
+
+// SetRetry changes how often and how far apart publishing to a failing
+// relay is tried
+func (p *Publisher) SetRetry(interval time.Duration, maxAttempts int) {
+	p.retryInterval = interval
+	p.maxAttempts = maxAttempts
+}
+
+// SetLogger sets the logger that records relay failures
+func (p *Publisher) SetLogger(l dlcoracle.Logger) {
+	p.logger = l
+}
+
+// PubKey returns the publisher's Nostr public key
+func (p *Publisher) PubKey() string {
+	return PublicKey(p.key)
+}
+
+func (p *Publisher) newEvent(kind int, eventID string, content interface{}) (*Event, error) {
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	ev := &Event{
+		CreatedAt: time.Now().Unix(),
+		Kind:      kind,
+		Tags:      [][]string{{"d", eventID}},
+		Content:   string(b),
+	}
+	err = ev.Sign(p.key)
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// AnnouncementEvent returns the signed Nostr event for an announcement
+func (p *Publisher) AnnouncementEvent(a dlcoracle.Announcement) (*Event, error) {
+	return p.newEvent(KindAnnouncement, a.EventID, a)
+}
+
+// AttestationEvent returns the signed Nostr event for an attestation
+func (p *Publisher) AttestationEvent(a dlcoracle.Attestation) (*Event, error) {
+	return p.newEvent(KindAttestation, a.EventID, a)
+}
+
+// Publish posts ev to all relays, retrying failed relays. It succeeds if
+// at least one relay accepted the event.
+func (p *Publisher) Publish(ctx context.Context, ev *Event) error {
+	errs := make([]error, len(p.relays))
+	var wg sync.WaitGroup
+	for i, url := range p.relays {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			errs[i] = p.publishWithRetry(ctx, url, ev)
+		}(i, url)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no relays configured")
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Publisher) publishWithRetry(ctx context.Context, url string, ev *Event) error {
+	var err error
+	for attempt := 1; attempt <= p.maxAttempts; attempt++ {
+		err = p.publishToRelay(ctx, url, ev)
+		if err == nil {
+			return nil
+		}
+		p.logger.Log(dlcoracle.LevelWarn, "publishing to nostr relay failed",
+			dlcoracle.F("relay", url),
+			dlcoracle.F("nostr_event", ev.ID),
+			dlcoracle.F("attempt", attempt),
+			dlcoracle.F("error", err.Error()))
+		if attempt == p.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.retryInterval):
+		}
+	}
+	return fmt.Errorf("relay %s: %w", url, err)
+}
+
+// publishToRelay sends ev to a relay and waits for its OK message
+func (p *Publisher) publishToRelay(ctx context.Context, url string, ev *Event) error {
+	ctx, cancel := context.WithTimeout(ctx, relayTimeout)
+	defer cancel()
+
+	conn, _, err := p.dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetWriteDeadline(deadline)
+	conn.SetReadDeadline(deadline)
+
+	err = conn.WriteJSON([]interface{}{"EVENT", ev})
+	if err != nil {
+		return err
+	}
+	for {
+		var msg []json.RawMessage
+		err = conn.ReadJSON(&msg)
+		if err != nil {
+			return err
+		}
+		var typ, id string
+		if len(msg) < 2 || json.Unmarshal(msg[0], &typ) != nil || typ != "OK" {
+			// Notices and other messages don't answer our event
+			continue
+		}
+		if json.Unmarshal(msg[1], &id) != nil || id != ev.ID {
+			continue
+		}
+		var accepted bool
+		var reason string
+		if len(msg) > 2 {
+			json.Unmarshal(msg[2], &accepted)
+		}
+		if len(msg) > 3 {
+			json.Unmarshal(msg[3], &reason)
+		}
+		if !accepted {
+			return fmt.Errorf("event rejected: %s", reason)
+		}
+		return nil
+	}
+}
+
+// Name implements transport.Transport
+func (p *Publisher) Name() string {
+	return "nostr"
+}
+
+// PublishAnnouncement publishes a to the relays, unless it was published
+// before. It implements transport.Transport.
+func (p *Publisher) PublishAnnouncement(ctx context.Context, a dlcoracle.Announcement) error {
+	return p.publishAnnouncement(ctx, a)
+}
+
+// PublishAttestation publishes a to the relays, unless it was published
+// before. It implements transport.Transport.
+func (p *Publisher) PublishAttestation(ctx context.Context, a dlcoracle.Attestation) error {
+	return p.publishAttestation(ctx, a)
+}
+
+// publishAnnouncement publishes a unless it was published before
+func (p *Publisher) publishAnnouncement(ctx context.Context, a dlcoracle.Announcement) error {
+	return p.publishOnce(ctx, "announcement/"+a.EventID, func() (*Event, error) {
+		return p.AnnouncementEvent(a)
+	})
+}
+
+// publishAttestation publishes a unless it was published before
+func (p *Publisher) publishAttestation(ctx context.Context, a dlcoracle.Attestation) error {
+	return p.publishOnce(ctx, "attestation/"+a.EventID, func() (*Event, error) {
+		return p.AttestationEvent(a)
+	})
+}
+
+func (p *Publisher) publishOnce(ctx context.Context, key string, build func() (*Event, error)) error {
+	p.mtx.Lock()
+	done := p.published[key]
+	p.mtx.Unlock()
+	if done {
+		return nil
+	}
+	ev, err := build()
+	if err != nil {
+		return err
+	}
+	err = p.Publish(ctx, ev)
+	if err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	p.published[key] = true
+	p.mtx.Unlock()
+	return nil
+}
+
+// Run publishes every announcement and attestation o makes from now on,
+// until ctx is cancelled. Records already in the store when Run starts
+// are not published again. Records that failed to publish, or that were
+// missed because the subscription fell behind, are caught up from the
+// store.
+func (p *Publisher) Run(ctx context.Context, o *oracle.Oracle) error {
+	updates, cancel := o.Subscribe()
+	err := p.markExisting(o.Store())
+	if err != nil {
+		cancel()
+		return err
+	}
+	for {
+		p.forward(ctx, updates, o.Store())
+		cancel()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		updates, cancel = o.Subscribe()
+		p.publishMissing(ctx, o.Store())
+	}
+}
+
+// forward publishes updates until the channel is closed or ctx is
+// cancelled, periodically catching up on failed publications
+func (p *Publisher) forward(ctx context.Context, updates <-chan oracle.Update, store storage.Store) {
+	ticker := time.NewTicker(catchUpInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.publishMissing(ctx, store)
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			var err error
+			if u.Announcement != nil {
+				err = p.publishAnnouncement(ctx, *u.Announcement)
+			} else {
+				err = p.publishAttestation(ctx, *u.Attestation)
+			}
+			if err != nil {
+				p.logger.Log(dlcoracle.LevelError, "publishing to nostr failed",
+					dlcoracle.F("error", err.Error()))
+			}
+		}
+	}
+}
+
+// markExisting records the announcements and attestations in store as
+// published
+func (p *Publisher) markExisting(store storage.Store) error {
+	list, err := store.Announcements()
+	if err != nil {
+		return err
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for _, a := range list {
+		p.published["announcement/"+a.EventID] = true
+		_, err := store.Attestation(a.EventID)
+		if err == nil {
+			p.published["attestation/"+a.EventID] = true
+		} else if err != storage.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishMissing publishes the records in store that haven't been
+// published yet
+func (p *Publisher) publishMissing(ctx context.Context, store storage.Store) {
+	list, err := store.Announcements()
+	if err != nil {
+		p.logger.Log(dlcoracle.LevelError, "reading announcements failed",
+			dlcoracle.F("error", err.Error()))
+		return
+	}
+	for _, a := range list {
+		err = p.publishAnnouncement(ctx, a)
+		if err == nil {
+			var att dlcoracle.Attestation
+			att, err = store.Attestation(a.EventID)
+			if err == storage.ErrNotFound {
+				continue
+			}
+			if err == nil {
+				err = p.publishAttestation(ctx, att)
+			}
+		}
+		if err != nil {
+			p.logger.Log(dlcoracle.LevelError, "publishing to nostr failed",
+				dlcoracle.F("event_id", a.EventID),
+				dlcoracle.F("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package notify alerts the operators of an oracle when something needs
+// their attention, such as an event that is overdue for attestation.
+// Notifier is implemented for email over SMTP and Slack-compatible
+// incoming webhooks here, and for signed webhooks by webhook.Notifier.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Kind classifies alerts
+type Kind string
+
+// The alerts the scheduler raises
+const (
+	// KindOverdue is raised when an event still isn't attested some time
+	// after its maturity
+	KindOverdue Kind = "attestation.overdue"
+
+	// KindDisagreement is raised when data sources disagree too much on
+	// the outcome of an event to attest it
+	KindDisagreement Kind = "sources.disagree"
+)
+
+// Alert is an operational problem with an event
+type Alert struct {
+	Kind    Kind      `json:"kind"`
+	EventID string    `json:"eventId"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// String returns a one line summary of the alert
+func (a Alert) String() string {
+	return fmt.Sprintf("%s: event %s: %s", a.Kind, a.EventID, a.Message)
+}
+
+// Notifier delivers alerts
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+type multi []Notifier
+
+// Multi returns a notifier delivering alerts to all of notifiers. It
+// fails if any of them fails, after trying all.
+func Multi(notifiers ...Notifier) Notifier {
+	return multi(notifiers)
+}
+
+func (m multi) Notify(ctx context.Context, a Alert) error {
+	var errs []error
+	for _, n := range m {
+		err := n.Notify(ctx, a)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendMail is replaced in tests
+var sendMail = smtp.SendMail
+
+// SMTP emails alerts
+type SMTP struct {
+	// Addr is the host:port of the mail server
+	Addr string
+
+	// Auth authenticates to the server, if set, for instance with
+	// smtp.PlainAuth
+	Auth smtp.Auth
+
+	From string
+	To   []string
+}
+
+// Notify implements Notifier. The context isn't honored, as net/smtp has
+// no way to cancel a delivery.
+func (s *SMTP) Notify(ctx context.Context, a Alert) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [oracle] %s: %s\r\n", a.Kind, a.EventID)
+	fmt.Fprintf(&msg, "Date: %s\r\n", a.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", a.Message)
+	err := sendMail(s.Addr, s.Auth, s.From, s.To, msg.Bytes())
+	if err != nil {
+		return fmt.Errorf("smtp %s: %w", s.Addr, err)
+	}
+	return nil
+}
+
+// Slack posts alerts to a Slack incoming webhook, or any chat service
+// accepting the same {"text": ...} payload such as Mattermost
+type Slack struct {
+	URL string
+
+	// Client makes the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Notify implements Notifier
+func (s *Slack) Notify(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(map[string]string{"text": a.String()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook: HTTP %s", resp.Status)
+	}
+	return nil
+}
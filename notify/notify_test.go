@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var alert = Alert{
+	Kind:    KindOverdue,
+	EventID: "btcusd-2024-01-01",
+	Message: "not attested 15m0s after maturity",
+	Time:    time.Date(2024, 1, 1, 0, 15, 0, 0, time.UTC),
+}
+
+func TestSMTP(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	s := &SMTP{Addr: "mail:25", From: "oracle@example.com", To: []string{"ops@example.com"}}
+	err := s.Notify(context.Background(), alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotAddr != "mail:25" || gotFrom != "oracle@example.com" || len(gotTo) != 1 {
+		t.Fatalf("sent to %s from %s to %v", gotAddr, gotFrom, gotTo)
+	}
+	msg := string(gotMsg)
+	if !strings.Contains(msg, "Subject: [oracle] attestation.overdue: btcusd-2024-01-01\r\n") ||
+		!strings.HasSuffix(msg, "\r\n\r\n"+alert.Message+"\r\n") {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		text = body["text"]
+	}))
+	defer srv.Close()
+
+	err := (&Slack{URL: srv.URL}).Notify(context.Background(), alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != alert.String() {
+		t.Fatalf("posted %q", text)
+	}
+
+	srv.Config.Handler = http.NotFoundHandler()
+	err = (&Slack{URL: srv.URL}).Notify(context.Background(), alert)
+	if err == nil {
+		t.Fatal("failed post not reported")
+	}
+}
+
+type notifierFunc func(context.Context, Alert) error
+
+func (f notifierFunc) Notify(ctx context.Context, a Alert) error {
+	return f(ctx, a)
+}
+
+func TestMulti(t *testing.T) {
+	var calls int
+	ok := notifierFunc(func(context.Context, Alert) error {
+		calls++
+		return nil
+	})
+	failed := errors.New("unreachable")
+	bad := notifierFunc(func(context.Context, Alert) error {
+		calls++
+		return failed
+	})
+	err := Multi(bad, ok).Notify(context.Background(), alert)
+	if !errors.Is(err, failed) || calls != 2 {
+		t.Fatalf("%d calls, error %v", calls, err)
+	}
+}
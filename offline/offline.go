@@ -0,0 +1,126 @@
+// Package offline splits an oracle between an offline machine holding its
+// private key and an online daemon holding none, with files carried
+// between them.
+//
+// On the offline machine a Signer derives the one-time signing keys of
+// upcoming events and signs their announcements ahead of time, in a
+// Bundle the daemon imports. The daemon publishes them and, as events
+// mature, fetches their outcomes and queues attestation requests in a
+// Queue instead of signing. The operator carries the requests to the
+// offline machine, where the Signer signs them into another Bundle, and
+// back to the daemon's Inbox, which imports and publishes the
+// attestations.
+//
+// The daemon can't be given anything that lets it sign on its own without
+// putting the private key at risk: two signatures of different outcomes
+// with the same one-time signing key reveal the private key, so
+// presigning every outcome of an event, or handing out the event's
+// signing key, would leave the key on the online machine in all but name.
+// A compromised daemon can only withhold attestations or request wrong
+// ones, which the operator reviews before signing.
+package offline
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Version is the version of the file formats
+const Version = 1
+
+// Bundle carries what the offline machine signed to the daemon
+type Bundle struct {
+	Version       int                      `json:"version"`
+	Announcements []dlcoracle.Announcement `json:"announcements,omitempty"`
+	Attestations  []dlcoracle.Attestation  `json:"attestations,omitempty"`
+}
+
+// Request asks the offline machine to attest to Message as the outcome of
+// the announced event
+type Request struct {
+	Announcement dlcoracle.Announcement
+	Message      []byte
+}
+
+type requestJSON struct {
+	Announcement dlcoracle.Announcement `json:"announcement"`
+	Message      string                 `json:"message"`
+	// Outcome is the message in a readable form, for the operator
+	// reviewing the request; it isn't read back
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// MarshalJSON encodes the request with a hex encoded message and its
+// readable outcome
+func (r Request) MarshalJSON() ([]byte, error) {
+	j := requestJSON{Announcement: r.Announcement, Message: hex.EncodeToString(r.Message)}
+	if o, err := r.Announcement.Descriptor.ParseOutcome(r.Message); err == nil {
+		j.Outcome = r.Announcement.Descriptor.FormatOutcome(o)
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a request encoded by MarshalJSON
+func (r *Request) UnmarshalJSON(b []byte) error {
+	var j requestJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	msg, err := hex.DecodeString(j.Message)
+	if err != nil {
+		return fmt.Errorf("message: %v", err)
+	}
+	r.Announcement, r.Message = j.Announcement, msg
+	return nil
+}
+
+// Requests is the file of attestation requests the daemon hands to the
+// offline machine
+type Requests struct {
+	Version  int       `json:"version"`
+	Requests []Request `json:"requests"`
+}
+
+// ReadFile decodes the JSON file at path into v, a *Bundle or *Requests,
+// and checks its version
+func ReadFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	version := 0
+	switch v := v.(type) {
+	case *Bundle:
+		version = v.Version
+	case *Requests:
+		version = v.Version
+	}
+	if version != Version {
+		return fmt.Errorf("%s: unsupported version %d", path, version)
+	}
+	return nil
+}
+
+// WriteFile writes v, such as a *Bundle or *Requests, as JSON to path,
+// replacing it atomically
+func WriteFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(b, '\n'), 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
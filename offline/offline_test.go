@@ -0,0 +1,163 @@
+package offline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func TestWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	var priv [32]byte
+	priv[31] = 7
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	ledgerPath := filepath.Join(dir, "ledger.json")
+	s, err := NewSigner(priv, ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maturity := time.Unix(1000, 0)
+	digits := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 3}
+	b, err := s.Prepare([]dlcoracle.Event{
+		{ID: "a", Maturity: maturity},
+		{ID: "b", Maturity: maturity, Descriptor: digits},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Announcements) != 2 || b.Announcements[0].RPoint == b.Announcements[1].RPoint {
+		t.Fatalf("unexpected announcements %+v", b.Announcements)
+	}
+	_, err = s.Prepare([]dlcoracle.Event{{ID: "a", Maturity: maturity}})
+	if err == nil {
+		t.Fatal("announced an event twice")
+	}
+
+	// the daemon imports the announcements and queues its attestations
+	q, err := OpenQueue(filepath.Join(dir, "requests.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := oracle.NewOffline(pub, storage.NewMemoryStore(), q)
+	err = Import(o, q, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Import(o, q, b)
+	if err != nil {
+		t.Fatalf("importing again: %v", err)
+	}
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "c", Maturity: maturity})
+	if !errors.Is(err, oracle.ErrKeyOffline) {
+		t.Fatalf("created event offline: %v", err)
+	}
+	msg := dlcoracle.GenerateNumericMessage(42)
+	_, err = o.Attest("a", msg)
+	if !errors.Is(err, oracle.ErrAttestationPending) {
+		t.Fatalf("attesting offline: %v", err)
+	}
+	_, err = o.Attest("b", []byte{1, 2, 3})
+	if !errors.Is(err, oracle.ErrAttestationPending) {
+		t.Fatalf("attesting offline: %v", err)
+	}
+
+	// the requests are carried to the offline machine, which starts from
+	// the saved ledger
+	var reqs Requests
+	err = ReadFile(filepath.Join(dir, "requests.json"), &reqs)
+	if err != nil || len(reqs.Requests) != 2 {
+		t.Fatalf("requests %+v %v", reqs, err)
+	}
+	s, err = NewSigner(priv, ledgerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := s.Sign(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.Sign(reqs)
+	if err != nil || again.Attestations[0].Signature != signed.Attestations[0].Signature {
+		t.Fatalf("signing again: %v", err)
+	}
+	conflict := Requests{Version: Version, Requests: []Request{
+		{Announcement: b.Announcements[0], Message: dlcoracle.GenerateNumericMessage(43)},
+	}}
+	_, err = s.Sign(conflict)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("attested another outcome: %v", err)
+	}
+
+	// the daemon picks up the bundle from its inbox
+	inbox := filepath.Join(dir, "inbox")
+	os.Mkdir(inbox, 0700)
+	err = WriteFile(filepath.Join(inbox, "signed.json"), &signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewInbox(inbox, o, q).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(inbox, "signed.json.imported")); err != nil {
+		t.Fatal(err)
+	}
+	att, err := o.Store().Attestation("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dlcoracle.VerifyAttestation(b.Announcements[1], att)
+	if err != nil || out.Value != 123 {
+		t.Fatalf("attested %+v %v", out, err)
+	}
+	if len(q.Requests()) != 0 {
+		t.Fatalf("requests left %+v", q.Requests())
+	}
+}
+
+func TestSignerChecks(t *testing.T) {
+	dir := t.TempDir()
+	var priv [32]byte
+	priv[31] = 7
+	s, err := NewSigner(priv, filepath.Join(dir, "ledger.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+	s.now = func() time.Time { return now }
+	b, err := s.Prepare([]dlcoracle.Event{{ID: "a", Maturity: now.Add(time.Hour)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := Request{Announcement: b.Announcements[0], Message: dlcoracle.GenerateNumericMessage(1)}
+	_, err = s.Sign(Requests{Version: Version, Requests: []Request{req}})
+	if err == nil {
+		t.Fatal("attested before maturity")
+	}
+
+	// an announcement this ledger didn't make
+	var other [32]byte
+	other[31] = 8
+	k, _ := dlcoracle.DeriveOneTimeSigningKey(priv, 100)
+	ann, _ := dlcoracle.NewAnnouncement(priv, k, dlcoracle.Event{ID: "z", Maturity: now})
+	now = now.Add(2 * time.Hour)
+	_, err = s.Sign(Requests{Version: Version, Requests: []Request{req, {Announcement: ann, Message: req.Message}}})
+	if err == nil {
+		t.Fatal("attested an unknown event")
+	}
+	if s.ledger.Events["a"].Attestation != nil {
+		t.Fatal("signed part of a failed batch")
+	}
+
+	_, err = NewSigner(other, filepath.Join(dir, "ledger.json"))
+	if err == nil {
+		t.Fatal("opened the ledger of another key")
+	}
+}
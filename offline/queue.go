@@ -0,0 +1,176 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+)
+
+// Queue is the oracle.AttestationSigner of the online daemon. It signs
+// nothing: it adds requests to a file the operator carries to the offline
+// machine, and returns an error wrapping oracle.ErrAttestationPending.
+type Queue struct {
+	mtx  sync.Mutex
+	path string
+	reqs Requests
+}
+
+// OpenQueue returns a queue keeping its requests in the file at path,
+// which is created once there is a request
+func OpenQueue(path string) (*Queue, error) {
+	q := &Queue{path: path, reqs: Requests{Version: Version}}
+	err := ReadFile(path, &q.reqs)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return q, nil
+}
+
+// SignAttestation queues a request to attest to message as the outcome of
+// a. A request queued before for the event is kept.
+func (q *Queue) SignAttestation(a dlcoracle.Announcement, message []byte) (dlcoracle.Attestation, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	for _, r := range q.reqs.Requests {
+		if r.Announcement.EventID == a.EventID {
+			return dlcoracle.Attestation{}, fmt.Errorf("event %s: %w", a.EventID, oracle.ErrAttestationPending)
+		}
+	}
+	q.reqs.Requests = append(q.reqs.Requests, Request{Announcement: a, Message: message})
+	err := WriteFile(q.path, &q.reqs)
+	if err != nil {
+		q.reqs.Requests = q.reqs.Requests[:len(q.reqs.Requests)-1]
+		return dlcoracle.Attestation{}, err
+	}
+	return dlcoracle.Attestation{}, fmt.Errorf("event %s: %w", a.EventID, oracle.ErrAttestationPending)
+}
+
+// Requests returns the queued requests
+func (q *Queue) Requests() []Request {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return append([]Request(nil), q.reqs.Requests...)
+}
+
+// remove drops the requests for the given events
+func (q *Queue) remove(eventIDs map[string]bool) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	kept := make([]Request, 0, len(q.reqs.Requests))
+	for _, r := range q.reqs.Requests {
+		if !eventIDs[r.Announcement.EventID] {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(q.reqs.Requests) {
+		return nil
+	}
+	q.reqs.Requests = kept
+	return WriteFile(q.path, &q.reqs)
+}
+
+// Import imports the announcements and attestations of b into o and, if q
+// isn't nil, drops the requests answered by its attestations. It imports
+// as much as it can and returns the errors of the rest.
+func Import(o *oracle.Oracle, q *Queue, b Bundle) error {
+	var errs []error
+	for _, a := range b.Announcements {
+		err := o.ImportAnnouncement(a)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("announcement of %s: %w", a.EventID, err))
+		}
+	}
+	answered := make(map[string]bool)
+	for _, att := range b.Attestations {
+		err := o.ImportAttestation(att)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attestation of %s: %w", att.EventID, err))
+			continue
+		}
+		answered[att.EventID] = true
+	}
+	if q != nil {
+		err := q.remove(answered)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Inbox imports the bundle files the operator drops into a directory
+type Inbox struct {
+	dir    string
+	oracle *oracle.Oracle
+	queue  *Queue
+	logger dlcoracle.Logger
+}
+
+// NewInbox returns an inbox importing the *.json bundles in dir into o,
+// dropping the requests of q they answer
+func NewInbox(dir string, o *oracle.Oracle, q *Queue) *Inbox {
+	return &Inbox{dir: dir, oracle: o, queue: q, logger: dlcoracle.NopLogger()}
+}
+
+// SetLogger sets the logger imports are reported to
+func (in *Inbox) SetLogger(l dlcoracle.Logger) {
+	in.logger = l
+}
+
+// Scan imports the bundles in the directory, in the order of their names.
+// Each is renamed with the suffix .imported once imported, or .failed if
+// any of it couldn't be, so it isn't imported again.
+func (in *Inbox) Scan() error {
+	paths, err := filepath.Glob(filepath.Join(in.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		var b Bundle
+		err := ReadFile(path, &b)
+		if err == nil {
+			err = Import(in.oracle, in.queue, b)
+		}
+		suffix := ".imported"
+		if err != nil {
+			suffix = ".failed"
+			in.logger.Log(dlcoracle.LevelError, "importing bundle failed",
+				dlcoracle.F("file", path), dlcoracle.F("err", err))
+		} else {
+			in.logger.Log(dlcoracle.LevelInfo, "imported bundle", dlcoracle.F("file", path),
+				dlcoracle.F("announcements", len(b.Announcements)),
+				dlcoracle.F("attestations", len(b.Attestations)))
+		}
+		err = os.Rename(path, path+suffix)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run scans the directory every interval until ctx is cancelled
+func (in *Inbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := in.Scan()
+		if err != nil {
+			in.logger.Log(dlcoracle.LevelError, "scanning inbox failed", dlcoracle.F("err", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
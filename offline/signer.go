@@ -0,0 +1,200 @@
+package offline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// ErrConflict is returned when asked to attest to an outcome of an event
+// that was attested to another outcome before
+var ErrConflict = errors.New("event was attested to another outcome")
+
+// ledger is what the offline machine remembers of the events it
+// announced: which one-time signing key each uses, and what it attested
+type ledger struct {
+	Version   int                     `json:"version"`
+	PubKey    string                  `json:"pubKey"`
+	NextIndex uint64                  `json:"nextIndex"`
+	Events    map[string]*ledgerEntry `json:"events"`
+}
+
+type ledgerEntry struct {
+	Index       uint64                 `json:"index"`
+	Attestation *dlcoracle.Attestation `json:"attestation,omitempty"`
+}
+
+// Signer signs announcements and attestations on the offline machine. It
+// keeps a ledger file of the one-time signing key index of every event it
+// announced, so an index is never used twice and every event is attested
+// to a single outcome.
+type Signer struct {
+	privKey [32]byte
+	pubKey  [33]byte
+	path    string
+	ledger  ledger
+	now     func() time.Time
+}
+
+// NewSigner returns a signer for privKey with the ledger at ledgerPath,
+// which is created if it doesn't exist
+func NewSigner(privKey [32]byte, ledgerPath string) (*Signer, error) {
+	s := &Signer{
+		privKey: privKey,
+		pubKey:  dlcoracle.PublicKeyFromPrivateKey(privKey),
+		path:    ledgerPath,
+		now:     time.Now,
+	}
+	b, err := os.ReadFile(ledgerPath)
+	if errors.Is(err, os.ErrNotExist) {
+		s.ledger = ledger{
+			Version: Version,
+			PubKey:  fmt.Sprintf("%x", s.pubKey),
+			Events:  make(map[string]*ledgerEntry),
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &s.ledger)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ledgerPath, err)
+	}
+	if s.ledger.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported version %d", ledgerPath, s.ledger.Version)
+	}
+	if s.ledger.PubKey != fmt.Sprintf("%x", s.pubKey) {
+		return nil, fmt.Errorf("%s is the ledger of oracle %s", ledgerPath, s.ledger.PubKey)
+	}
+	if s.ledger.Events == nil {
+		s.ledger.Events = make(map[string]*ledgerEntry)
+	}
+	return s, nil
+}
+
+// save writes the ledger, replacing it atomically
+func (s *Signer) save() error {
+	return WriteFile(s.path, &s.ledger)
+}
+
+// Prepare announces events: it assigns each the next one-time signing key
+// index of the ledger and signs its announcement. Events the ledger has
+// seen before are refused. The ledger is saved before the bundle is
+// returned.
+func (s *Signer) Prepare(events []dlcoracle.Event) (Bundle, error) {
+	b := Bundle{Version: Version}
+	next := s.ledger.NextIndex
+	indexes := make(map[string]uint64, len(events))
+	for _, ev := range events {
+		if _, ok := s.ledger.Events[ev.ID]; ok {
+			return Bundle{}, fmt.Errorf("event %s was announced before", ev.ID)
+		}
+		if _, ok := indexes[ev.ID]; ok {
+			return Bundle{}, fmt.Errorf("event %s is listed twice", ev.ID)
+		}
+		var k [32]byte
+		for {
+			var err error
+			k, err = dlcoracle.DeriveOneTimeSigningKey(s.privKey, next)
+			next++
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, dlcoracle.ErrScalarOutOfRange) {
+				return Bundle{}, err
+			}
+		}
+		a, err := dlcoracle.NewAnnouncement(s.privKey, k, ev)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("event %s: %w", ev.ID, err)
+		}
+		indexes[ev.ID] = next - 1
+		b.Announcements = append(b.Announcements, a)
+	}
+
+	s.ledger.NextIndex = next
+	for id, i := range indexes {
+		s.ledger.Events[id] = &ledgerEntry{Index: i}
+	}
+	err := s.save()
+	if err != nil {
+		return Bundle{}, err
+	}
+	return b, nil
+}
+
+// Sign attests to the outcomes requested in reqs. Every request has to be
+// for an event the ledger announced, matured by the offline machine's
+// clock unless it matures at a block height. Requesting an attestation
+// again returns the same one; requesting another outcome fails with
+// ErrConflict. Nothing is signed unless every request is valid, and the
+// ledger is saved before the bundle is returned.
+func (s *Signer) Sign(reqs Requests) (Bundle, error) {
+	b := Bundle{Version: Version}
+	signed := make(map[string]*dlcoracle.Attestation)
+	for _, r := range reqs.Requests {
+		a := r.Announcement
+		att, err := s.sign(a, r.Message, signed[a.EventID])
+		if err != nil {
+			return Bundle{}, fmt.Errorf("event %s: %w", a.EventID, err)
+		}
+		if signed[a.EventID] == nil {
+			signed[a.EventID] = &att
+			b.Attestations = append(b.Attestations, att)
+		}
+	}
+
+	for id, att := range signed {
+		s.ledger.Events[id].Attestation = att
+	}
+	err := s.save()
+	if err != nil {
+		return Bundle{}, err
+	}
+	return b, nil
+}
+
+// sign attests to message as the outcome of a, or returns the attestation
+// made before, by the ledger or earlier in the same batch
+func (s *Signer) sign(a dlcoracle.Announcement, message []byte, batch *dlcoracle.Attestation) (dlcoracle.Attestation, error) {
+	if a.OraclePubKey != s.pubKey {
+		return dlcoracle.Attestation{}, fmt.Errorf("announced by oracle %x", a.OraclePubKey)
+	}
+	err := a.Verify()
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	e, ok := s.ledger.Events[a.EventID]
+	if !ok {
+		return dlcoracle.Attestation{}, fmt.Errorf("not in the ledger")
+	}
+	prev := e.Attestation
+	if batch != nil {
+		prev = batch
+	}
+	if prev != nil {
+		if string(prev.Message) != string(message) {
+			return dlcoracle.Attestation{}, fmt.Errorf("attested %x before, requested %x: %w", prev.Message, message, ErrConflict)
+		}
+		return *prev, nil
+	}
+	if a.MaturityHeight == 0 && s.now().Before(a.Maturity) {
+		return dlcoracle.Attestation{}, fmt.Errorf("matures at %s", a.Maturity.Format(time.RFC3339))
+	}
+	outcome, err := a.Descriptor.ParseOutcome(message)
+	if err != nil {
+		return dlcoracle.Attestation{}, fmt.Errorf("%w: %v", dlcoracle.ErrInvalidOutcome, err)
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(s.privKey, e.Index)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	// SignOutcome checks k against the announced R points, so an
+	// announcement signed for another ledger entry is refused
+	return dlcoracle.SignOutcome(s.privKey, k, a, outcome)
+}
@@ -0,0 +1,73 @@
+package oracle
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/audit"
+)
+
+// Reasons for rejecting a one-time signing key reported to a
+// SigningObserver
+const (
+	// NonceAssignedElsewhere is reported when the ledger assigned the key
+	// to another event, see ErrNonceMismatch
+	NonceAssignedElsewhere = "assigned-elsewhere"
+
+	// NonceUsed is reported when the key of an event would sign a second
+	// attestation
+	NonceUsed = "used"
+)
+
+// SigningObserver is told how the oracle's signing path performs, apart
+// from the requests that lead to it, so its degradation can be alerted on.
+// metrics.Metrics implements it. Kinds are those of audit.Kind.
+type SigningObserver interface {
+	// ObserveSigning is called after each signing operation with the
+	// number of signatures it produced, such as one per digit of a
+	// digits event, how long it took and its error. An offline oracle
+	// reports the time its AttestationSigner took.
+	ObserveSigning(kind string, signatures int, d time.Duration, err error)
+
+	// ObserveVerificationFailure is called when a record signed elsewhere,
+	// imported or returned by an AttestationSigner, fails verification
+	ObserveVerificationFailure(kind string)
+
+	// ObserveNonceRejection is called when the oracle refuses to sign
+	// with a one-time signing key, with NonceAssignedElsewhere or
+	// NonceUsed
+	ObserveNonceRejection(reason string)
+}
+
+// SetSigningObserver sets the observer told about every signing
+// operation, verification failure and rejected one-time signing key
+func (o *Oracle) SetSigningObserver(obs SigningObserver) {
+	o.observer = obs
+}
+
+// observeSigning reports a signing operation that started at start
+func (o *Oracle) observeSigning(kind audit.Kind, signatures int, start time.Time, err error) {
+	if o.observer != nil {
+		o.observer.ObserveSigning(string(kind), signatures, time.Since(start), err)
+	}
+}
+
+// observeVerification reports err if a record failed verification
+func (o *Oracle) observeVerification(kind audit.Kind, err error) {
+	if err != nil && o.observer != nil {
+		o.observer.ObserveVerificationFailure(string(kind))
+	}
+}
+
+// observeNonce reports err if it rejects a one-time signing key
+func (o *Oracle) observeNonce(err error) {
+	if o.observer == nil {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrNonceMismatch):
+		o.observer.ObserveNonceRejection(NonceAssignedElsewhere)
+	case errors.Is(err, ErrAlreadyAttested):
+		o.observer.ObserveNonceRejection(NonceUsed)
+	}
+}
@@ -0,0 +1,175 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+var (
+	// ErrKeyOffline is returned by an offline oracle for operations that
+	// need the private key
+	ErrKeyOffline = errors.New("private key is kept offline")
+
+	// ErrAttestationPending is returned by Attest when the attestation
+	// was requested from an AttestationSigner that signs it later, and
+	// comes back through ImportAttestation
+	ErrAttestationPending = errors.New("attestation requested")
+)
+
+// AttestationSigner signs the attestations of an oracle that doesn't hold
+// its private key. It returns the attestation, or an error wrapping
+// ErrAttestationPending once it has taken up the request.
+type AttestationSigner interface {
+	SignAttestation(a dlcoracle.Announcement, message []byte) (dlcoracle.Attestation, error)
+}
+
+// NewOffline returns an oracle for the public key pubKey whose private
+// key is kept elsewhere. Its announcements are signed elsewhere too and
+// imported with ImportAnnouncement; Attest passes the outcome on to
+// signer. Creating events, revoking them and signing ECDSA attestations
+// fail with ErrKeyOffline.
+func NewOffline(pubKey [33]byte, store storage.Store, signer AttestationSigner) *Oracle {
+	o := New([32]byte{}, store)
+	o.pubKey = pubKey
+	o.signer = signer
+	return o
+}
+
+// checkOnline fails with ErrKeyOffline if the oracle has no private key
+func (o *Oracle) checkOnline() error {
+	if o.signer != nil {
+		return ErrKeyOffline
+	}
+	return nil
+}
+
+// ImportAnnouncement stores and publishes an announcement signed with the
+// oracle's key elsewhere, such as on an offline machine. Importing an
+// announcement that is stored already does nothing; another announcement
+// for the same event fails with ErrEventExists.
+func (o *Oracle) ImportAnnouncement(a dlcoracle.Announcement) error {
+	if a.OraclePubKey != o.pubKey {
+		return fmt.Errorf("announcement of %s is for oracle %x", a.EventID, a.OraclePubKey)
+	}
+	err := a.Verify()
+	o.observeVerification(audit.KindAnnouncement, err)
+	if err != nil {
+		return err
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err = o.checkStopped()
+	if err != nil {
+		return err
+	}
+
+	stored, err := o.store.Announcement(a.EventID)
+	if err == nil {
+		if stored.Signature == a.Signature {
+			return nil
+		}
+		return fmt.Errorf("event %s: %w", a.EventID, ErrEventExists)
+	}
+	if err != storage.ErrNotFound {
+		return err
+	}
+	err = o.assign(a.RPoint, a.Event())
+	if err != nil {
+		return err
+	}
+	digest := a.SigningHash()
+	err = o.record(audit.KindAnnouncement, a.EventID, digest[:], a.Signature[:])
+	if err != nil {
+		return err
+	}
+	err = o.store.PutAnnouncement(a)
+	if err != nil {
+		return err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "imported announcement",
+		dlcoracle.F("event_id", a.EventID),
+		dlcoracle.F("r_point", fmt.Sprintf("%x", a.RPoint)))
+	o.publish(Update{Announcement: &a})
+	return nil
+}
+
+// ImportAttestation stores and publishes an attestation signed elsewhere,
+// after verifying it against the stored announcement. Importing an
+// attestation that is stored already does nothing; another attestation of
+// the same event fails with ErrAlreadyAttested.
+func (o *Oracle) ImportAttestation(att dlcoracle.Attestation) error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err := o.checkStopped()
+	if err != nil {
+		return err
+	}
+
+	ann, err := o.store.Announcement(att.EventID)
+	if err != nil {
+		return fmt.Errorf("event %s: %w", att.EventID, err)
+	}
+	_, err = dlcoracle.VerifyAttestation(ann, att)
+	o.observeVerification(audit.KindAttestation, err)
+	if err != nil {
+		return err
+	}
+	stored, err := o.store.Attestation(att.EventID)
+	if err == nil {
+		if stored.Signature == att.Signature {
+			return nil
+		}
+		return fmt.Errorf("event %s: %w", att.EventID, ErrAlreadyAttested)
+	}
+	if err != storage.ErrNotFound {
+		return err
+	}
+	if rs, ok := o.store.(storage.RevocationStore); ok {
+		_, err = rs.Revocation(att.EventID)
+		if err == nil {
+			return fmt.Errorf("event %s: %w", att.EventID, ErrRevoked)
+		}
+		if err != storage.ErrNotFound {
+			return err
+		}
+	}
+	return o.putAttestation(att)
+}
+
+// requestAttestation passes an attestation of a checked, matured event on
+// to the oracle's signer, and checks what it returns. The caller holds
+// o.mtx.
+func (o *Oracle) requestAttestation(ann dlcoracle.Announcement, message []byte) (dlcoracle.Attestation, error) {
+	_, err := ann.Descriptor.ParseOutcome(message)
+	if err != nil {
+		return dlcoracle.Attestation{}, fmt.Errorf("event %s: %w: %v", ann.EventID, dlcoracle.ErrInvalidOutcome, err)
+	}
+	start := time.Now()
+	att, err := o.signer.SignAttestation(ann, message)
+	if !errors.Is(err, ErrAttestationPending) {
+		// Attestations signed later by hand aren't timed
+		signatures := len(att.Signatures)
+		if signatures == 0 {
+			signatures = 1
+		}
+		o.observeSigning(audit.KindAttestation, signatures, start, err)
+	}
+	if err != nil {
+		return att, err
+	}
+	_, err = dlcoracle.VerifyAttestation(ann, att)
+	o.observeVerification(audit.KindAttestation, err)
+	if err != nil {
+		return att, err
+	}
+	if string(att.Message) != string(message) {
+		return att, fmt.Errorf("signer attested %x instead of %x", att.Message, message)
+	}
+	return att, nil
+}
@@ -0,0 +1,789 @@
+package oracle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/chain"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	// ErrEventExists is returned when creating an event that was
+	// announced before
+	ErrEventExists = errors.New("event already exists")
+
+	// ErrAlreadyAttested is returned when attesting an event that has
+	// been attested before
+	ErrAlreadyAttested = errors.New("event already attested")
+
+	// ErrNotMatured is returned when attesting an event before its
+	// maturity
+	ErrNotMatured = errors.New("event has not matured yet")
+
+	// ErrRevoked is returned when attesting an event the oracle revoked
+	ErrRevoked = errors.New("event was revoked")
+
+	// ErrNonceMismatch is returned when a one-time signing key would sign
+	// for another event than the one the ledger assigned it to
+	ErrNonceMismatch = errors.New("one-time signing key is assigned to another event")
+)
+
+const (
+	// subscriberBuffer is the number of updates buffered for each
+	// subscriber
+	subscriberBuffer = 64
+
+	// chainTimeout bounds queries to the chain backend
+	chainTimeout = 30 * time.Second
+)
+
+// Update is sent to subscribers whenever the oracle publishes a new
+// announcement or attestation. Exactly one of the fields is set.
+type Update struct {
+	Announcement *dlcoracle.Announcement
+	Attestation  *dlcoracle.Attestation
+}
+
+// Oracle combines the oracle's private key with a store, and implements
+// creating events and attesting to their outcome on top of the signing
+// primitives in dlcoracle.
+type Oracle struct {
+	privKey [32]byte
+	pubKey  [33]byte
+	store   storage.Store
+	logger  dlcoracle.Logger
+	clock   clock.Clock
+	chain   chain.Backend
+	audit   *audit.Log
+	signOpt []dlcoracle.SignOption
+
+	// observer is told about the signing path, nil if there is none
+	observer SigningObserver
+
+	// signer signs the attestations of an offline oracle, nil for
+	// oracles holding their private key
+	signer AttestationSigner
+
+	// mtx serializes event creation and attestation, so an event can't
+	// be attested twice by concurrent callers
+	mtx sync.Mutex
+	// stopped is set by Stop, under mtx
+	stopped bool
+
+	subMtx      sync.Mutex
+	subscribers map[chan Update]struct{}
+}
+
+// New returns an oracle signing with privKey and keeping its state in store
+func New(privKey [32]byte, store storage.Store) *Oracle {
+	return &Oracle{
+		privKey:     privKey,
+		pubKey:      dlcoracle.PublicKeyFromPrivateKey(privKey),
+		store:       store,
+		logger:      dlcoracle.NopLogger(),
+		clock:       clock.System(),
+		subscribers: make(map[chan Update]struct{}),
+	}
+}
+
+// SetLogger sets the logger that records every announcement and
+// attestation the oracle signs
+func (o *Oracle) SetLogger(l dlcoracle.Logger) {
+	o.logger = l
+}
+
+// SetClock sets the clock maturities are checked against. If it
+// implements clock.Checker, attestations are refused while its check
+// fails.
+func (o *Oracle) SetClock(c clock.Clock) {
+	o.clock = c
+}
+
+// SetChain sets the chain backend the maturity of events with a
+// MaturityHeight is checked against. Such events can't be attested
+// without one.
+func (o *Oracle) SetChain(c chain.Backend) {
+	o.chain = c
+}
+
+// SetAuditLog sets the log every signature is recorded in before the
+// signed object is stored or published. If recording fails, the
+// signature is discarded.
+func (o *Oracle) SetAuditLog(l *audit.Log) {
+	o.audit = l
+}
+
+// record appends a signature to the audit log, if there is one
+func (o *Oracle) record(kind audit.Kind, eventID string, message, signature []byte) error {
+	if o.audit == nil {
+		return nil
+	}
+	_, err := o.audit.Append(kind, eventID, message, signature)
+	if err != nil {
+		return fmt.Errorf("recording signature of %s in the audit log: %w", eventID, err)
+	}
+	return nil
+}
+
+// SetSignOptions sets the options one-time signing keys of new events are
+// derived with. With dlcoracle.WithAuxRand the keys can't be recomputed
+// from the private key, so the store has to be backed up along with it.
+func (o *Oracle) SetSignOptions(opts ...dlcoracle.SignOption) {
+	o.signOpt = opts
+}
+
+// Chain returns the oracle's chain backend, or nil
+func (o *Oracle) Chain() chain.Backend {
+	return o.chain
+}
+
+// Clock returns the clock maturities are checked against
+func (o *Oracle) Clock() clock.Clock {
+	return o.clock
+}
+
+// PubKey returns the oracle's public key
+func (o *Oracle) PubKey() [33]byte {
+	return o.pubKey
+}
+
+// CheckKey checks that the oracle's private key is loaded and matches its
+// public key, by signing a probe message and verifying the signature
+func (o *Oracle) CheckKey() error {
+	if o.signer != nil {
+		// nothing to check, the key is elsewhere
+		return nil
+	}
+	if o.privKey == [32]byte{} {
+		return fmt.Errorf("no private key loaded")
+	}
+	probe := []byte("DLC/oracle/key check")
+	sig, err := dlcoracle.Sign(o.privKey, probe)
+	if err != nil {
+		return err
+	}
+	err = dlcoracle.Verify(o.pubKey, probe, sig)
+	if err != nil {
+		return fmt.Errorf("private key doesn't match public key: %v", err)
+	}
+	return nil
+}
+
+// Store returns the store the oracle keeps its state in
+func (o *Oracle) Store() storage.Store {
+	return o.store
+}
+
+// CreateEvent derives a one-time signing key for a new event from the
+// next unused nonce index, and publishes the signed announcement containing
+// its R point. The key is stored before the announcement; if storing the
+// announcement fails, a retry reuses the stored key, which has never been
+// published.
+func (o *Oracle) CreateEvent(ev dlcoracle.Event) (dlcoracle.Announcement, error) {
+	var a dlcoracle.Announcement
+
+	err := o.checkOnline()
+	if err != nil {
+		return a, err
+	}
+	err = ev.Descriptor.Validate()
+	if err != nil {
+		return a, err
+	}
+	err = ev.Metadata.Validate()
+	if err != nil {
+		return a, err
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err = o.checkStopped()
+	if err != nil {
+		return a, err
+	}
+
+	_, err = o.store.Announcement(ev.ID)
+	if err == nil {
+		return a, fmt.Errorf("event %s: %w", ev.ID, ErrEventExists)
+	}
+	if err != storage.ErrNotFound {
+		return a, err
+	}
+
+	k, err := o.store.Nonce(ev.ID)
+	if err == storage.ErrNotFound {
+		k, err = o.nextNonce()
+		if err != nil {
+			return a, err
+		}
+		err = o.store.PutNonce(ev.ID, k)
+	}
+	if err != nil {
+		return a, err
+	}
+	err = o.assign(dlcoracle.PublicKeyFromPrivateKey(k), ev)
+	if err != nil {
+		return a, err
+	}
+
+	start := time.Now()
+	a, err = dlcoracle.NewAnnouncement(o.privKey, k, ev)
+	o.observeSigning(audit.KindAnnouncement, 1, start, err)
+	if err != nil {
+		return a, err
+	}
+	digest := a.SigningHash()
+	err = o.record(audit.KindAnnouncement, a.EventID, digest[:], a.Signature[:])
+	if err != nil {
+		return a, err
+	}
+	err = o.store.PutAnnouncement(a)
+	if err != nil {
+		return a, err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "announced event",
+		dlcoracle.F("event_id", a.EventID),
+		dlcoracle.F("r_point", fmt.Sprintf("%x", a.RPoint)),
+		dlcoracle.F("maturity", a.Maturity.UTC().Format(time.RFC3339)))
+	o.publish(Update{Announcement: &a})
+	return a, nil
+}
+
+// assign records in the ledger that the one-time signing key with point
+// R belongs to ev, and fails with ErrNonceMismatch if it was assigned to
+// another event or to another descriptor or maturity of the same event.
+// Stores that don't implement storage.LedgerStore keep no ledger.
+func (o *Oracle) assign(R [33]byte, ev dlcoracle.Event) error {
+	ls, ok := o.store.(storage.LedgerStore)
+	if !ok {
+		return nil
+	}
+	want := storage.NonceAssignment{
+		RPoint:     R,
+		EventID:    ev.ID,
+		Commitment: dlcoracle.EventCommitment(ev),
+	}
+	err := ls.PutAssignment(want)
+	if err != storage.ErrExists {
+		return err
+	}
+	got, err := ls.Assignment(R)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		err = fmt.Errorf("key %x of event %s was assigned to %s: %w", R, ev.ID, got.EventID, ErrNonceMismatch)
+		o.observeNonce(err)
+		return err
+	}
+	return nil
+}
+
+// nextNonce derives the one-time signing key for the next unused index
+func (o *Oracle) nextNonce() ([32]byte, error) {
+	for {
+		i, err := o.store.NextNonceIndex()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		k, err := dlcoracle.DeriveOneTimeSigningKey(o.privKey, i, o.signOpt...)
+		if err == nil {
+			return k, nil
+		}
+		// The derived key was out of range, use the next index
+	}
+}
+
+// AttestOutcome signs outcome as the result of an announced event, after
+// checking it against the event's descriptor
+func (o *Oracle) AttestOutcome(eventID string, outcome dlcoracle.Outcome) (dlcoracle.Attestation, error) {
+	return o.AttestOutcomeContext(context.Background(), eventID, outcome)
+}
+
+// AttestOutcomeContext is AttestOutcome as part of the trace in ctx
+func (o *Oracle) AttestOutcomeContext(ctx context.Context, eventID string, outcome dlcoracle.Outcome) (dlcoracle.Attestation, error) {
+	ann, err := o.store.Announcement(eventID)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	msg, err := ann.Descriptor.OutcomeMessage(outcome)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	return o.AttestContext(ctx, eventID, msg)
+}
+
+// Attest signs message as the outcome of an announced event that has
+// matured. An event can only be attested once: signing two messages with
+// the same one-time signing key would reveal the oracle's private key.
+// The message has to be a possible outcome of the announced descriptor,
+// and with a storage.LedgerStore the one-time signing key has to be
+// assigned to the event as it was announced.
+func (o *Oracle) Attest(eventID string, message []byte) (dlcoracle.Attestation, error) {
+	return o.AttestContext(context.Background(), eventID, message)
+}
+
+// AttestContext is Attest as part of the trace in ctx. Its span marks when
+// the oracle's lock was acquired, and has spans of its own for the
+// maturity checks, signing and storing the attestation.
+func (o *Oracle) AttestContext(ctx context.Context, eventID string, message []byte) (dlcoracle.Attestation, error) {
+	ctx, span := tracing.Start(ctx, "oracle.attest", tracing.EventID(eventID))
+	a, err := o.attest(ctx, span, eventID, message)
+	tracing.End(span, err)
+	return a, err
+}
+
+func (o *Oracle) attest(ctx context.Context, span trace.Span, eventID string, message []byte) (dlcoracle.Attestation, error) {
+	var a dlcoracle.Attestation
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	span.AddEvent("acquired lock")
+	err := o.checkStopped()
+	if err != nil {
+		return a, err
+	}
+
+	ann, err := o.store.Announcement(eventID)
+	if err != nil {
+		return a, err
+	}
+	if rs, ok := o.store.(storage.RevocationStore); ok {
+		_, err = rs.Revocation(eventID)
+		if err == nil {
+			return a, fmt.Errorf("event %s: %w", eventID, ErrRevoked)
+		}
+		if err != storage.ErrNotFound {
+			return a, err
+		}
+	}
+	_, checkSpan := tracing.Start(ctx, "oracle.check_maturity")
+	err = clock.Check(o.clock)
+	if err != nil {
+		err = fmt.Errorf("refusing to attest %s: %w", eventID, err)
+	} else {
+		err = o.checkMatured(ann)
+	}
+	tracing.End(checkSpan, err)
+	if err != nil {
+		return a, err
+	}
+
+	_, err = o.store.Attestation(eventID)
+	if err == nil {
+		err = fmt.Errorf("event %s: %w", eventID, ErrAlreadyAttested)
+		o.observeNonce(err)
+		return a, err
+	}
+	if err != storage.ErrNotFound {
+		return a, err
+	}
+	// An attestation recorded in the audit log but not stored was
+	// interrupted by a crash: complete it rather than sign again
+	recorded, ok, err := o.recorded(eventID)
+	if err != nil {
+		return a, err
+	}
+	if ok {
+		if string(recorded.Message) != string(message) {
+			err = fmt.Errorf("event %s: %w", eventID, ErrAlreadyAttested)
+			o.observeNonce(err)
+			return a, err
+		}
+		_, storeSpan := tracing.Start(ctx, "oracle.store")
+		err = o.storeAttestation(recorded)
+		tracing.End(storeSpan, err)
+		if err != nil {
+			return a, err
+		}
+		return recorded, nil
+	}
+	outcome, err := ann.Descriptor.ParseOutcome(message)
+	if err != nil {
+		return a, fmt.Errorf("event %s: %w: %v", eventID, dlcoracle.ErrInvalidOutcome, err)
+	}
+
+	if o.signer != nil {
+		err = o.reserve(eventID, message)
+		if err != nil {
+			return a, err
+		}
+		_, signSpan := tracing.Start(ctx, "oracle.sign", attribute.Bool("dlc.offline", true))
+		a, err = o.requestAttestation(ann, message)
+		tracing.End(signSpan, err)
+		if err != nil {
+			return dlcoracle.Attestation{}, err
+		}
+		err = o.putAttestationContext(ctx, a)
+		if err != nil {
+			return dlcoracle.Attestation{}, err
+		}
+		return a, nil
+	}
+
+	k, err := o.store.Nonce(eventID)
+	if err != nil {
+		return a, err
+	}
+	// Events announced before the ledger existed are assigned their key
+	// on first use. The announced R point is the key's unless the event
+	// has digits, and signing with ComputeSignatureForPoint checks it is,
+	// so only the key of digits events, which isn't announced, is
+	// multiplied out again, in variable time.
+	R := ann.RPoint
+	if ann.Descriptor.Type == dlcoracle.EventTypeDigits {
+		R = dlcoracle.PublicKeyFromPrivateKey(k)
+	}
+	err = o.assign(R, ann.Event())
+	if err != nil {
+		return a, err
+	}
+	err = o.reserve(eventID, message)
+	if err != nil {
+		return a, err
+	}
+	_, signSpan := tracing.Start(ctx, "oracle.sign")
+	start := time.Now()
+	if ann.Descriptor.Type == dlcoracle.EventTypeDigits {
+		a, err = dlcoracle.SignOutcomeForPoints(o.privKey, k, o.pubKey, ann, outcome)
+		o.observeSigning(audit.KindAttestation, len(a.Signatures), start, err)
+	} else {
+		var sig [32]byte
+		sig, err = dlcoracle.ComputeSignatureForPoint(o.privKey, k, o.pubKey, ann.RPoint, message)
+		o.observeSigning(audit.KindAttestation, 1, start, err)
+		a = dlcoracle.Attestation{
+			EventID:   eventID,
+			Message:   message,
+			Signature: sig,
+		}
+	}
+	tracing.End(signSpan, err)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	err = o.putAttestationContext(ctx, a)
+	if err != nil {
+		return dlcoracle.Attestation{}, err
+	}
+	return a, nil
+}
+
+// reserve reserves message as the outcome of an event before it is signed,
+// in stores implementing storage.ReservationStore. Another instance
+// sharing the store having reserved a different outcome means the event
+// is attested to it; the same outcome is signed again, which gives the
+// same signature. o.mtx is enough for stores used by a single instance.
+// The caller holds o.mtx.
+func (o *Oracle) reserve(eventID string, message []byte) error {
+	rs, ok := o.store.(storage.ReservationStore)
+	if !ok {
+		return nil
+	}
+	err := rs.PutReservation(eventID, message)
+	if err != storage.ErrExists {
+		return err
+	}
+	reserved, err := rs.Reservation(eventID)
+	if err != nil {
+		return err
+	}
+	if string(reserved) != string(message) {
+		err = fmt.Errorf("event %s: %w", eventID, ErrAlreadyAttested)
+		o.observeNonce(err)
+		return err
+	}
+	return nil
+}
+
+// putAttestationContext is putAttestation in a span of the trace in ctx
+func (o *Oracle) putAttestationContext(ctx context.Context, a dlcoracle.Attestation) error {
+	_, span := tracing.Start(ctx, "oracle.store")
+	err := o.putAttestation(a)
+	tracing.End(span, err)
+	return err
+}
+
+// putAttestation records, stores and publishes an attestation. The caller
+// holds o.mtx.
+func (o *Oracle) putAttestation(a dlcoracle.Attestation) error {
+	sig := a.Signature[:]
+	if len(a.Signatures) != 0 {
+		sig = nil
+		for _, s := range a.Signatures {
+			sig = append(sig, s[:]...)
+		}
+	}
+	err := o.record(audit.KindAttestation, a.EventID, a.Message, sig)
+	if err != nil {
+		return err
+	}
+	return o.storeAttestation(a)
+}
+
+// storeAttestation stores and publishes an attestation recorded in the
+// audit log. The caller holds o.mtx.
+func (o *Oracle) storeAttestation(a dlcoracle.Attestation) error {
+	err := o.store.PutAttestation(a)
+	if err == storage.ErrExists {
+		return fmt.Errorf("event %s: %w", a.EventID, ErrAlreadyAttested)
+	}
+	if err != nil {
+		return err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "attested event",
+		dlcoracle.F("event_id", a.EventID),
+		dlcoracle.F("message", fmt.Sprintf("%x", a.Message)),
+		dlcoracle.F("signature", fmt.Sprintf("%x", a.Signature)))
+	o.publish(Update{Attestation: &a})
+	return nil
+}
+
+// Revoke signs and stores the oracle's promise never to attest an
+// announced event, with the reason and the ID of the event superseding it,
+// if any. Events that were attested can't be revoked. The store has to
+// implement storage.RevocationStore.
+func (o *Oracle) Revoke(eventID, reason, supersededBy string) (dlcoracle.Revocation, error) {
+	var r dlcoracle.Revocation
+	err := o.checkOnline()
+	if err != nil {
+		return r, err
+	}
+	rs, ok := o.store.(storage.RevocationStore)
+	if !ok {
+		return r, fmt.Errorf("store can't keep revocations")
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err = o.checkStopped()
+	if err != nil {
+		return r, err
+	}
+
+	_, err = o.store.Announcement(eventID)
+	if err != nil {
+		return r, err
+	}
+	_, err = o.store.Attestation(eventID)
+	if err == nil {
+		return r, fmt.Errorf("event %s: %w", eventID, ErrAlreadyAttested)
+	}
+	if err != storage.ErrNotFound {
+		return r, err
+	}
+	recorded, ok, err := o.recorded(eventID)
+	if err != nil {
+		return r, err
+	}
+	if ok {
+		err = o.storeAttestation(recorded)
+		if err != nil {
+			return r, err
+		}
+		return r, fmt.Errorf("event %s: %w", eventID, ErrAlreadyAttested)
+	}
+
+	r = dlcoracle.Revocation{
+		EventID:      eventID,
+		OraclePubKey: o.pubKey,
+		Reason:       reason,
+		SupersededBy: supersededBy,
+		RevokedAt:    o.clock.Now().UTC().Truncate(time.Second),
+	}
+	start := time.Now()
+	err = r.Sign(o.privKey)
+	o.observeSigning(audit.KindRevocation, 1, start, err)
+	if err != nil {
+		return r, err
+	}
+	digest := r.SigningHash()
+	err = o.record(audit.KindRevocation, eventID, digest[:], r.Signature[:])
+	if err != nil {
+		return r, err
+	}
+	err = rs.PutRevocation(r)
+	if err == storage.ErrExists {
+		return r, fmt.Errorf("event %s: %w", eventID, ErrRevoked)
+	}
+	if err != nil {
+		return r, err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "revoked event",
+		dlcoracle.F("event_id", r.EventID),
+		dlcoracle.F("reason", r.Reason),
+		dlcoracle.F("superseded_by", r.SupersededBy))
+	return r, nil
+}
+
+// ECDSAAttestation signs the attested outcome of an event with ECDSA, for
+// consumers that can't verify attestations. It fails with
+// storage.ErrNotFound until the event is attested, so it never signs
+// another outcome than the attestation.
+func (o *Oracle) ECDSAAttestation(eventID string) (dlcoracle.ECDSAAttestation, error) {
+	err := o.checkOnline()
+	if err != nil {
+		return dlcoracle.ECDSAAttestation{}, err
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err = o.checkStopped()
+	if err != nil {
+		return dlcoracle.ECDSAAttestation{}, err
+	}
+	att, err := o.store.Attestation(eventID)
+	if err != nil {
+		return dlcoracle.ECDSAAttestation{}, err
+	}
+	start := time.Now()
+	ea, err := dlcoracle.SignECDSAAttestation(o.privKey, eventID, att.Message)
+	o.observeSigning(audit.KindECDSAAttestation, 1, start, err)
+	if err != nil {
+		return ea, err
+	}
+	err = o.record(audit.KindECDSAAttestation, eventID, ea.Message, ea.Signature[:])
+	if err != nil {
+		return dlcoracle.ECDSAAttestation{}, err
+	}
+	return ea, nil
+}
+
+// PutEvidence signs and stores the upstream API responses the outcome of
+// an attested event was fetched from, so disputes about it can be
+// investigated. Evidence is kept once per event, and recorded in the
+// audit log before it is stored. The store has to implement
+// storage.EvidenceStore.
+func (o *Oracle) PutEvidence(eventID string, responses []dlcoracle.UpstreamResponse) (dlcoracle.Evidence, error) {
+	var e dlcoracle.Evidence
+	err := o.checkOnline()
+	if err != nil {
+		return e, err
+	}
+	es, ok := o.store.(storage.EvidenceStore)
+	if !ok {
+		return e, fmt.Errorf("store can't keep evidence")
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err = o.checkStopped()
+	if err != nil {
+		return e, err
+	}
+
+	_, err = o.store.Attestation(eventID)
+	if err != nil {
+		return e, err
+	}
+	// Evidence isn't signed and recorded again only to be refused
+	_, err = es.Evidence(eventID)
+	if err == nil {
+		return e, storage.ErrExists
+	}
+	if err != storage.ErrNotFound {
+		return e, err
+	}
+	e = dlcoracle.Evidence{
+		EventID:      eventID,
+		OraclePubKey: o.pubKey,
+		Responses:    responses,
+		CapturedAt:   o.clock.Now().UTC().Truncate(time.Millisecond),
+	}
+	start := time.Now()
+	err = e.Sign(o.privKey)
+	o.observeSigning(audit.KindEvidence, 1, start, err)
+	if err != nil {
+		return dlcoracle.Evidence{}, err
+	}
+	digest := e.SigningHash()
+	err = o.record(audit.KindEvidence, eventID, digest[:], e.Signature[:])
+	if err != nil {
+		return dlcoracle.Evidence{}, err
+	}
+	err = es.PutEvidence(e)
+	if err != nil {
+		return dlcoracle.Evidence{}, err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "stored evidence",
+		dlcoracle.F("event_id", eventID),
+		dlcoracle.F("responses", len(responses)))
+	return e, nil
+}
+
+// checkMatured returns ErrNotMatured if the announced event hasn't
+// matured yet, by block height or by the oracle's clock
+func (o *Oracle) checkMatured(ann dlcoracle.Announcement) error {
+	if ann.MaturityHeight == 0 {
+		if o.clock.Now().Before(ann.Maturity) {
+			return fmt.Errorf("event %s matures at %s: %w", ann.EventID,
+				ann.Maturity.UTC().Format(time.RFC3339), ErrNotMatured)
+		}
+		return nil
+	}
+
+	if o.chain == nil {
+		return fmt.Errorf("event %s matures at a block height, but no chain backend is set", ann.EventID)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chainTimeout)
+	defer cancel()
+	height, err := o.chain.BlockHeight(ctx)
+	if err != nil {
+		return err
+	}
+	if height < ann.MaturityHeight {
+		return fmt.Errorf("event %s matures at height %d, chain is at %d: %w", ann.EventID,
+			ann.MaturityHeight, height, ErrNotMatured)
+	}
+	return nil
+}
+
+// Subscribe returns a channel receiving every announcement and attestation
+// published from now on, and a function to cancel the subscription.
+//
+// Subscribers that don't keep up are not silently skipped: once a
+// subscriber's buffer is full its channel is closed, so it can tell that it
+// missed updates and has to resync from the store before subscribing again.
+func (o *Oracle) Subscribe() (<-chan Update, func()) {
+	ch := make(chan Update, subscriberBuffer)
+	o.subMtx.Lock()
+	o.subscribers[ch] = struct{}{}
+	o.subMtx.Unlock()
+
+	cancel := func() {
+		o.subMtx.Lock()
+		defer o.subMtx.Unlock()
+		o.unsubscribe(ch)
+	}
+	return ch, cancel
+}
+
+// unsubscribe removes and closes a subscriber channel. subMtx must be held.
+func (o *Oracle) unsubscribe(ch chan Update) {
+	if _, ok := o.subscribers[ch]; ok {
+		delete(o.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (o *Oracle) publish(u Update) {
+	o.subMtx.Lock()
+	defer o.subMtx.Unlock()
+	for ch := range o.subscribers {
+		select {
+		case ch <- u:
+		default:
+			// The subscriber fell behind, cut it off rather than
+			// letting it miss this update unnoticed
+			o.unsubscribe(ch)
+		}
+	}
+}
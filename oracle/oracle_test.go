@@ -0,0 +1,511 @@
+package oracle
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func newTestOracle() *Oracle {
+	var priv [32]byte
+	priv[31] = 1
+	return New(priv, storage.NewMemoryStore())
+}
+
+func TestAttest(t *testing.T) {
+	o := newTestOracle()
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := dlcoracle.GenerateNumericMessage(1)
+	att, err := o.Attest("event", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint, msg, att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = o.Attest("event", dlcoracle.GenerateNumericMessage(2))
+	if !errors.Is(err, ErrAlreadyAttested) {
+		t.Fatalf("expected ErrAlreadyAttested, got %v", err)
+	}
+}
+
+func TestCreateEventTwice(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if !errors.Is(err, ErrEventExists) {
+		t.Fatalf("expected ErrEventExists, got %v", err)
+	}
+}
+
+func TestAttestBeforeMaturity(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("event", []byte{1})
+	if !errors.Is(err, ErrNotMatured) {
+		t.Fatalf("expected ErrNotMatured, got %v", err)
+	}
+}
+
+func TestAttestUnannounced(t *testing.T) {
+	o := newTestOracle()
+	err := o.store.PutNonce("event", [32]byte{31: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("event", []byte{1})
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCreateEventReusesUnpublishedNonce(t *testing.T) {
+	o := newTestOracle()
+	k := [32]byte{31: 5}
+	err := o.store.PutNonce("event", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.RPoint != dlcoracle.PublicKeyFromPrivateKey(k) {
+		t.Fatal("stored nonce was not reused")
+	}
+}
+
+func TestSlowSubscriberIsCutOff(t *testing.T) {
+	o := newTestOracle()
+	updates, cancel := o.Subscribe()
+	defer cancel()
+	for i := 0; i <= subscriberBuffer; i++ {
+		o.publish(Update{})
+	}
+	n := 0
+	for range updates {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Fatalf("expected %d buffered updates before close, got %d", subscriberBuffer, n)
+	}
+}
+
+func TestAttestOutcome(t *testing.T) {
+	o := newTestOracle()
+	a, err := o.CreateEvent(dlcoracle.Event{
+		ID:       "event",
+		Maturity: time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{
+			Type:     dlcoracle.EventTypeEnum,
+			Outcomes: []string{"yes", "no"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.AttestOutcome("event", dlcoracle.Outcome{Label: "maybe"})
+	if err == nil {
+		t.Fatal("attested an impossible outcome")
+	}
+	att, err := o.AttestOutcome("event", dlcoracle.Outcome{Label: "yes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint, []byte("yes"), att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedStore(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	store := storage.NewMemoryStore()
+	o, other := New(priv, store), New(priv, store)
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid outcome reserves nothing
+	_, err = other.Attest("event", []byte{1})
+	if !errors.Is(err, dlcoracle.ErrInvalidOutcome) {
+		t.Fatalf("expected ErrInvalidOutcome, got %v", err)
+	}
+	// The other instance reserved an outcome but hasn't stored its
+	// attestation yet: another outcome isn't signed
+	err = store.PutReservation("event", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := o.Attest("event", dlcoracle.GenerateNumericMessage(2))
+	if !errors.Is(err, ErrAlreadyAttested) || att.Signature != ([32]byte{}) {
+		t.Fatalf("signed another outcome: %+v %v", att, err)
+	}
+	att, err = o.Attest("event", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := other.Attest("event", dlcoracle.GenerateNumericMessage(1))
+	if !errors.Is(err, ErrAlreadyAttested) || again.Signature != ([32]byte{}) {
+		t.Fatalf("attested twice: %+v %v", again, err)
+	}
+	stored, err := store.Attestation("event")
+	if err != nil || stored.Signature != att.Signature {
+		t.Fatalf("stored %+v %v", stored, err)
+	}
+}
+
+type auditLogger struct {
+	messages []string
+}
+
+func (a *auditLogger) Log(level dlcoracle.Level, msg string, fields ...dlcoracle.Field) {
+	for _, f := range fields {
+		if f.Key == "message" {
+			msg += " " + f.Value.(string)
+		}
+	}
+	a.messages = append(a.messages, msg)
+}
+
+func TestAuditLog(t *testing.T) {
+	o := newTestOracle()
+	var log auditLogger
+	o.SetLogger(&log)
+
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeBytes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("event", []byte{0xab})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.messages) != 2 || log.messages[0] != "announced event" ||
+		log.messages[1] != "attested event ab" {
+		t.Fatalf("unexpected audit log %v", log.messages)
+	}
+}
+
+type driftingClock struct{}
+
+func (driftingClock) Now() time.Time { return time.Now() }
+func (driftingClock) Check() error   { return clock.ErrClockDrift }
+
+func TestClock(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A clock that is behind the maturity holds back the attestation
+	o.SetClock(clock.Func(func() time.Time { return time.Unix(999, 0) }))
+	_, err = o.Attest("event", []byte("yes"))
+	if !errors.Is(err, ErrNotMatured) {
+		t.Fatalf("expected ErrNotMatured, got %v", err)
+	}
+
+	o.SetClock(driftingClock{})
+	_, err = o.Attest("event", []byte("yes"))
+	if !errors.Is(err, clock.ErrClockDrift) {
+		t.Fatalf("expected ErrClockDrift, got %v", err)
+	}
+}
+
+func TestAuxRandNonces(t *testing.T) {
+	o := newTestOracle()
+	o.SetSignOptions(dlcoracle.WithAuxRand(rand.Reader))
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := dlcoracle.DeriveOneTimeSigningKey(o.privKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.RPoint == dlcoracle.PublicKeyFromPrivateKey(k) {
+		t.Fatal("R point derived without auxiliary randomness")
+	}
+
+	msg := dlcoracle.GenerateNumericMessage(1)
+	att, err := o.Attest("event", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint, msg, att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestECDSAAttestation(t *testing.T) {
+	o := newTestOracle()
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.ECDSAAttestation("event")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound before attesting, got %v", err)
+	}
+	_, err = o.Attest("event", dlcoracle.GenerateNumericMessage(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := o.ECDSAAttestation("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := dlcoracle.VerifyECDSAAttestation(a, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Value != 7 {
+		t.Fatalf("unexpected outcome %+v", outcome)
+	}
+}
+
+func TestAttestDigits(t *testing.T) {
+	o := newTestOracle()
+	desc := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 4}
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "price", Maturity: time.Unix(1000, 0), Descriptor: desc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.RPoints) != 4 || a.RPoint != a.RPoints[0] {
+		t.Fatalf("unexpected r points %x", a.RPoints)
+	}
+	att, err := o.AttestOutcome("price", dlcoracle.Outcome{Value: 4207})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outcome, err := dlcoracle.VerifyAttestation(a, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome.Value != 4207 || len(att.Signatures) != 4 {
+		t.Fatalf("got %+v", att)
+	}
+	stored, err := o.Store().Attestation("price")
+	if err != nil || len(stored.Signatures) != 4 {
+		t.Fatalf("stored %+v, %v", stored, err)
+	}
+}
+
+func TestPutEvidence(t *testing.T) {
+	o := newTestOracle()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o.SetAuditLog(l)
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := []dlcoracle.UpstreamResponse{{Source: "api", URL: "https://api.example.com/event",
+		Status: 200, Body: []byte(`{"outcome":1}`)}}
+	_, err = o.PutEvidence("event", responses)
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound before the attestation, got %v", err)
+	}
+
+	_, err = o.Attest("event", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := o.PutEvidence("event", responses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := o.Store().(storage.EvidenceStore).Evidence("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stored.Verify()
+	if err != nil || stored.OraclePubKey != o.PubKey() || stored.Signature != e.Signature {
+		t.Fatalf("stored %+v: %v", stored, err)
+	}
+	_, err = o.PutEvidence("event", nil)
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+
+	// The evidence's signature is in the audit log
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n, last, err := audit.Verify(f)
+	hash := e.SigningHash()
+	if err != nil || n != 3 || last.Kind != audit.KindEvidence || string(last.Message) != string(hash[:]) {
+		t.Fatalf("unexpected audit log: %d entries, last %+v, %v", n, last, err)
+	}
+
+	o.Stop()
+	_, err = o.PutEvidence("event", responses)
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	o := newTestOracle()
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "match", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := o.Revoke("match", "cancelled", "rematch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dlcoracle.VerifyRevocation(a, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SupersededBy != "rematch" {
+		t.Fatalf("unexpected revocation %+v", r)
+	}
+
+	_, err = o.Attest("match", dlcoracle.GenerateNumericMessage(1))
+	if !errors.Is(err, ErrRevoked) {
+		t.Fatalf("expected ErrRevoked, got %v", err)
+	}
+	_, err = o.Revoke("match", "again", "")
+	if !errors.Is(err, ErrRevoked) {
+		t.Fatalf("expected ErrRevoked, got %v", err)
+	}
+
+	// Attested events stay attested
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "done", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("done", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Revoke("done", "too late", "")
+	if !errors.Is(err, ErrAlreadyAttested) {
+		t.Fatalf("expected ErrAlreadyAttested, got %v", err)
+	}
+	_, err = o.Revoke("unknown", "", "")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNonceLedger(t *testing.T) {
+	o := newTestOracle()
+	enum := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "match", Maturity: time.Unix(1000, 0), Descriptor: enum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("match", []byte("draw"))
+	if !errors.Is(err, dlcoracle.ErrInvalidOutcome) {
+		t.Fatalf("expected ErrInvalidOutcome, got %v", err)
+	}
+
+	// An operator replacing the stored announcement can't get another
+	// descriptor signed with the announced key
+	swapped := a
+	swapped.Descriptor = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no", "draw"}}
+	err = o.Store().PutAnnouncement(swapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("match", []byte("draw"))
+	if !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+	err = o.Store().PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("match", []byte("yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor can a key be moved to another event
+	k, err := o.Store().Nonce("match")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Store().PutNonce("other", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "other", Maturity: time.Unix(1000, 0), Descriptor: enum})
+	if !errors.Is(err, ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func TestAuditLogChain(t *testing.T) {
+	o := newTestOracle()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o.SetAuditLog(l)
+
+	for _, id := range []string{"a", "b"} {
+		_, err = o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = o.Attest("a", dlcoracle.GenerateNumericMessage(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Revoke("b", "cancelled", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n, last, err := audit.Verify(f)
+	if err != nil || n != 4 || last.Kind != audit.KindRevocation {
+		t.Fatalf("unexpected audit log: %d entries, last %+v, %v", n, last, err)
+	}
+}
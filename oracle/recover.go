@@ -0,0 +1,294 @@
+package oracle
+
+import (
+	"fmt"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// defaultGapLimit is the number of consecutive unused indices after which
+// Recover stops deriving keys when no last index is given
+const defaultGapLimit = 100
+
+// RecoverOptions select the one-time signing key indices Recover derives,
+// the announcements it reconciles them with and whether it repairs the
+// store
+type RecoverOptions struct {
+	// First and Last bound the indices derived. If Last is zero, keys are
+	// derived from First until GapLimit consecutive indices, 100 by
+	// default, belong to no announcement.
+	First, Last uint64
+	GapLimit    uint64
+
+	// Published are announcements known from elsewhere, such as the
+	// oracle's public API or its subscribers, reconciled along with the
+	// ones in the store. They have to be signed by the oracle.
+	Published []dlcoracle.Announcement
+
+	// Repair writes recovered one-time signing keys and published
+	// announcements missing from the store back to it, and advances the
+	// store's nonce index past the highest index in use
+	Repair bool
+}
+
+// RecoveredKey is a one-time signing key index in use by an announced
+// event
+type RecoveredKey struct {
+	Index   uint64
+	EventID string
+	RPoint  [33]byte
+}
+
+// RecoveryReport is the result of reconciling the one-time signing keys
+// derived from the oracle's private key with its announcements
+type RecoveryReport struct {
+	// Keys lists the indices in use, in order
+	Keys []RecoveredKey
+
+	// Gaps lists the indices below the highest one in use that no
+	// announcement uses. An index is skipped when announcing fails after
+	// taking it, so gaps are not necessarily lost events, but they are
+	// worth a look after restoring a backup.
+	Gaps []uint64
+
+	// Unmatched lists announced events whose key is neither in the store
+	// nor derived from any index in range, so they can't be attested.
+	// Keys derived with dlcoracle.WithAuxRand can only be recovered from
+	// a backup of the store.
+	Unmatched []string
+
+	// Mismatched lists events whose stored key or ledger assignment
+	// doesn't match the announced R point. They are never repaired.
+	Mismatched []string
+
+	// Reused lists events announced with the same R point as another
+	// event. Attesting both would reveal the oracle's private key.
+	Reused []string
+
+	// Restored lists events whose key or announcement Repair wrote back
+	// to the store
+	Restored []string
+
+	// NextIndex is the index after the highest one in use, or First if
+	// none is
+	NextIndex uint64
+}
+
+// Recover re-derives the oracle's one-time signing keys and reconciles
+// them with the announcements in its store and the published ones, as
+// after restoring the oracle from a backup of its private key and an
+// older or empty store. Unless opts.Repair is set the store is only read.
+func (o *Oracle) Recover(opts RecoverOptions) (RecoveryReport, error) {
+	report := RecoveryReport{NextIndex: opts.First}
+	if opts.Last != 0 && opts.Last < opts.First {
+		return report, fmt.Errorf("last index %d is below first index %d", opts.Last, opts.First)
+	}
+	gapLimit := opts.GapLimit
+	if gapLimit == 0 {
+		gapLimit = defaultGapLimit
+	}
+
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	err := o.checkStopped()
+	if err != nil {
+		return report, err
+	}
+
+	stored, err := o.store.Announcements()
+	if err != nil {
+		return report, err
+	}
+	inStore := make(map[string]bool, len(stored))
+	for _, a := range stored {
+		inStore[a.EventID] = true
+	}
+	announcements := stored
+	for _, a := range opts.Published {
+		if inStore[a.EventID] {
+			continue
+		}
+		if a.OraclePubKey != o.pubKey {
+			return report, fmt.Errorf("published announcement %s is not the oracle's", a.EventID)
+		}
+		err = a.Verify()
+		if err != nil {
+			return report, fmt.Errorf("published announcement %s: %w", a.EventID, err)
+		}
+		announcements = append(announcements, a)
+	}
+	storage.SortAnnouncements(announcements)
+
+	byPoint := make(map[[33]byte]dlcoracle.Announcement, len(announcements))
+	for _, a := range announcements {
+		if other, ok := byPoint[a.RPoint]; ok {
+			report.Reused = append(report.Reused, other.EventID, a.EventID)
+			continue
+		}
+		byPoint[a.RPoint] = a
+	}
+
+	keys := make(map[string][32]byte)
+	used := make(map[uint64]bool)
+	skipped := make(map[uint64]bool)
+	for i := opts.First; ; i++ {
+		if opts.Last != 0 && i > opts.Last {
+			break
+		}
+		if opts.Last == 0 && (i-report.NextIndex >= gapLimit || len(keys) == len(byPoint)) {
+			break
+		}
+		k, err := dlcoracle.DeriveOneTimeSigningKey(o.privKey, i)
+		if err != nil {
+			skipped[i] = true
+			continue
+		}
+		for _, R := range keyPoints(k) {
+			a, ok := byPoint[R]
+			if !ok {
+				continue
+			}
+			keys[a.EventID] = k
+			used[i] = true
+			report.Keys = append(report.Keys, RecoveredKey{Index: i, EventID: a.EventID, RPoint: R})
+			report.NextIndex = i + 1
+		}
+	}
+	for i := opts.First; i < report.NextIndex; i++ {
+		if !used[i] && !skipped[i] {
+			report.Gaps = append(report.Gaps, i)
+		}
+	}
+
+	for _, a := range announcements {
+		if byPoint[a.RPoint].EventID != a.EventID {
+			// Listed in Reused
+			continue
+		}
+		k, recovered := keys[a.EventID]
+		storedKey, err := o.store.Nonce(a.EventID)
+		if err != nil && err != storage.ErrNotFound {
+			return report, err
+		}
+		hasKey := err == nil
+		if hasKey {
+			if !keyMatches(storedKey, a.RPoint) {
+				report.Mismatched = append(report.Mismatched, a.EventID)
+				continue
+			}
+			k = storedKey
+		} else if !recovered {
+			report.Unmatched = append(report.Unmatched, a.EventID)
+			continue
+		}
+		ok, err := o.assigned(dlcoracle.PublicKeyFromPrivateKey(k), a.Event())
+		if err != nil {
+			return report, err
+		}
+		if !ok {
+			report.Mismatched = append(report.Mismatched, a.EventID)
+			continue
+		}
+		if !opts.Repair || (hasKey && inStore[a.EventID]) {
+			continue
+		}
+		err = o.restore(a, k, hasKey, inStore[a.EventID])
+		if err != nil {
+			return report, err
+		}
+		report.Restored = append(report.Restored, a.EventID)
+	}
+
+	if opts.Repair && len(report.Keys) != 0 {
+		err = o.advanceNonceIndex(report.NextIndex)
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// restore writes the key and announcement of an event back to the store,
+// unless they are stored already, and records the key in the ledger
+func (o *Oracle) restore(a dlcoracle.Announcement, k [32]byte, hasKey, inStore bool) error {
+	if !hasKey {
+		err := o.store.PutNonce(a.EventID, k)
+		if err != nil {
+			return err
+		}
+	}
+	if !inStore {
+		err := o.store.PutAnnouncement(a)
+		if err != nil {
+			return err
+		}
+	}
+	err := o.assign(dlcoracle.PublicKeyFromPrivateKey(k), a.Event())
+	if err != nil {
+		return err
+	}
+	o.logger.Log(dlcoracle.LevelInfo, "recovered event",
+		dlcoracle.F("event_id", a.EventID),
+		dlcoracle.F("r_point", fmt.Sprintf("%x", a.RPoint)))
+	return nil
+}
+
+// assigned reports whether the ledger assigns the one-time signing key
+// with point R to ev, or doesn't know the key. Stores that don't
+// implement storage.LedgerStore keep no ledger.
+func (o *Oracle) assigned(R [33]byte, ev dlcoracle.Event) (bool, error) {
+	ls, ok := o.store.(storage.LedgerStore)
+	if !ok {
+		return true, nil
+	}
+	got, err := ls.Assignment(R)
+	if err == storage.ErrNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return got.EventID == ev.ID && got.Commitment == dlcoracle.EventCommitment(ev), nil
+}
+
+// advanceNonceIndex raises the store's nonce index to next, so no index in
+// use is handed out again. Stores that don't implement
+// storage.NonceIndexStore hand out indices until they reach it.
+func (o *Oracle) advanceNonceIndex(next uint64) error {
+	if ns, ok := o.store.(storage.NonceIndexStore); ok {
+		return ns.RaiseNonceIndex(next)
+	}
+	for {
+		i, err := o.store.NextNonceIndex()
+		if err != nil {
+			return err
+		}
+		if i+1 >= next {
+			return nil
+		}
+	}
+}
+
+// keyPoints returns the points an event announces for a one-time signing
+// key: its own point, and the point of its first digit key for digits
+// events
+func keyPoints(k [32]byte) [][33]byte {
+	points := [][33]byte{dlcoracle.PublicKeyFromPrivateKey(k)}
+	digits, err := dlcoracle.DigitRPoints(k, 1)
+	if err == nil {
+		points = append(points, digits[0])
+	}
+	return points
+}
+
+// keyMatches reports whether k is the one-time signing key of an event
+// announced with R point R
+func keyMatches(k [32]byte, R [33]byte) bool {
+	for _, p := range keyPoints(k) {
+		if p == R {
+			return true
+		}
+	}
+	return false
+}
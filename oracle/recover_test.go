@@ -0,0 +1,115 @@
+package oracle
+
+import (
+	"crypto/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func TestRecover(t *testing.T) {
+	o := newTestOracle()
+	events := []dlcoracle.Event{
+		{ID: "a", Maturity: time.Unix(1000, 0)},
+		{ID: "b", Maturity: time.Unix(2000, 0), Descriptor: dlcoracle.EventDescriptor{
+			Type: dlcoracle.EventTypeDigits, Base: 2, Digits: 4}},
+		{ID: "c", Maturity: time.Unix(3000, 0)},
+	}
+	for i, ev := range events {
+		_, err := o.CreateEvent(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			// Announcing an event failed after taking index 1
+			o.Store().NextNonceIndex()
+		}
+	}
+	published, err := o.Store().Announcements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the key on an empty store
+	store := storage.NewMemoryStore()
+	restored := New(o.privKey, store)
+	report, err := restored.Recover(RecoverOptions{Published: published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indices []uint64
+	for _, k := range report.Keys {
+		indices = append(indices, k.Index)
+	}
+	if !reflect.DeepEqual(indices, []uint64{0, 2, 3}) || !reflect.DeepEqual(report.Gaps, []uint64{1}) ||
+		report.NextIndex != 4 || len(report.Unmatched)+len(report.Mismatched)+len(report.Restored) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	list, _ := store.Announcements()
+	if len(list) != 0 {
+		t.Fatal("recovering without repair wrote to the store")
+	}
+
+	report, err = restored.Recover(RecoverOptions{Published: published, Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Restored, []string{"a", "b", "c"}) {
+		t.Fatalf("restored %v", report.Restored)
+	}
+	att, err := restored.AttestOutcome("b", dlcoracle.Outcome{Value: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dlcoracle.VerifyAttestation(published[1], att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// New events don't reuse an index in use
+	a, err := restored.CreateEvent(dlcoracle.Event{ID: "d", Maturity: time.Unix(4000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range report.Keys {
+		if k.RPoint == a.RPoint {
+			t.Fatalf("new event reused the key of %s", k.EventID)
+		}
+	}
+}
+
+func TestRecoverFlagsProblems(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "a", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetSignOptions(dlcoracle.WithAuxRand(rand.Reader))
+	aux, err := o.CreateEvent(dlcoracle.Event{ID: "aux", Maturity: time.Unix(2000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published, _ := o.Store().Announcements()
+
+	store := storage.NewMemoryStore()
+	var wrong [32]byte
+	wrong[31] = 5
+	store.PutNonce("a", wrong)
+	report, err := New(o.privKey, store).Recover(RecoverOptions{Published: published, Last: 10, Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Mismatched, []string{"a"}) || !reflect.DeepEqual(report.Unmatched, []string{"aux"}) ||
+		len(report.Restored) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	// Published announcements have to be signed by the oracle
+	aux.Maturity = time.Unix(3000, 0)
+	_, err = New(o.privKey, storage.NewMemoryStore()).Recover(RecoverOptions{Published: []dlcoracle.Announcement{aux}})
+	if err == nil {
+		t.Fatal("accepted an announcement with an invalid signature")
+	}
+}
@@ -0,0 +1,118 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// ErrStopped is returned when signing after the oracle was stopped
+var ErrStopped = errors.New("oracle is stopped")
+
+// Stop waits for the event being created, attested or revoked, if any,
+// and makes the oracle refuse to sign anything else with ErrStopped. It is
+// called before a daemon shuts down, so no signature is left recorded in
+// the audit log but not stored.
+func (o *Oracle) Stop() {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.stopped = true
+}
+
+// checkStopped fails with ErrStopped once the oracle is stopped. The
+// caller holds o.mtx.
+func (o *Oracle) checkStopped() error {
+	if o.stopped {
+		return ErrStopped
+	}
+	return nil
+}
+
+// CompleteAttestations stores the attestations recorded in the audit log
+// but missing from the store, as left by a crash between recording and
+// storing one, and returns their event IDs. Attestations of events
+// revoked since are skipped.
+func (o *Oracle) CompleteAttestations() ([]string, error) {
+	if o.audit == nil {
+		return nil, nil
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	var completed []string
+	for _, e := range o.audit.Attestations() {
+		_, err := o.store.Attestation(e.EventID)
+		if err == nil {
+			continue
+		}
+		if err != storage.ErrNotFound {
+			return completed, err
+		}
+		if rs, ok := o.store.(storage.RevocationStore); ok {
+			_, err = rs.Revocation(e.EventID)
+			if err == nil {
+				continue
+			}
+			if err != storage.ErrNotFound {
+				return completed, err
+			}
+		}
+		a, err := o.recordedAttestation(e)
+		if err != nil {
+			return completed, err
+		}
+		err = o.storeAttestation(a)
+		if err != nil {
+			return completed, err
+		}
+		completed = append(completed, e.EventID)
+	}
+	return completed, nil
+}
+
+// recorded returns the attestation of an event recorded in the audit log,
+// if there is one. The caller holds o.mtx.
+func (o *Oracle) recorded(eventID string) (dlcoracle.Attestation, bool, error) {
+	if o.audit == nil {
+		return dlcoracle.Attestation{}, false, nil
+	}
+	e, ok := o.audit.Attestation(eventID)
+	if !ok {
+		return dlcoracle.Attestation{}, false, nil
+	}
+	a, err := o.recordedAttestation(e)
+	return a, true, err
+}
+
+// recordedAttestation rebuilds the attestation an audit log entry records
+// and verifies it against the stored announcement
+func (o *Oracle) recordedAttestation(e audit.Entry) (dlcoracle.Attestation, error) {
+	ann, err := o.store.Announcement(e.EventID)
+	if err != nil {
+		return dlcoracle.Attestation{}, fmt.Errorf("recorded attestation of %s: %w", e.EventID, err)
+	}
+	if len(e.Signature) == 0 || len(e.Signature)%32 != 0 {
+		return dlcoracle.Attestation{}, fmt.Errorf("recorded attestation of %s has %d signature bytes", e.EventID, len(e.Signature))
+	}
+	sigs := make([][32]byte, len(e.Signature)/32)
+	for i := range sigs {
+		copy(sigs[i][:], e.Signature[32*i:])
+	}
+	outcome, err := ann.Descriptor.ParseOutcome(e.Message)
+	if err != nil {
+		return dlcoracle.Attestation{}, fmt.Errorf("recorded attestation of %s: %w: %v", e.EventID, dlcoracle.ErrInvalidOutcome, err)
+	}
+	a, err := dlcoracle.NewAttestation(ann, outcome, sigs)
+	if err != nil {
+		return a, fmt.Errorf("recorded attestation of %s: %w", e.EventID, err)
+	}
+	a.Message = e.Message
+	_, err = dlcoracle.VerifyAttestation(ann, a)
+	if err != nil {
+		return a, fmt.Errorf("recorded attestation of %s: %w", e.EventID, err)
+	}
+	return a, nil
+}
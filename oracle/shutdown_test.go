@@ -0,0 +1,124 @@
+package oracle
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/audit"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// crashingStore fails to store attestations while crashed is set, as if
+// the process died between recording and storing them
+type crashingStore struct {
+	*storage.MemoryStore
+	crashed bool
+}
+
+func (s *crashingStore) PutAttestation(a dlcoracle.Attestation) error {
+	if s.crashed {
+		return errors.New("crashed")
+	}
+	return s.MemoryStore.PutAttestation(a)
+}
+
+func TestStop(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Stop()
+	_, err = o.Attest("event", dlcoracle.GenerateNumericMessage(1))
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "other", Maturity: time.Unix(1000, 0)})
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+	_, err = o.Revoke("event", "", "")
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected ErrStopped, got %v", err)
+	}
+}
+
+func TestCompleteAttestations(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	store := &crashingStore{MemoryStore: storage.NewMemoryStore()}
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := New(priv, store)
+	o.SetAuditLog(l)
+	desc := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 3}
+	for _, id := range []string{"price", "rain", "later"} {
+		ev := dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)}
+		if id == "price" {
+			ev.Descriptor = desc
+		}
+		_, err = o.CreateEvent(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store.crashed = true
+	att, err := o.AttestOutcome("price", dlcoracle.Outcome{Value: 421})
+	if err == nil || att.Signature != ([32]byte{}) {
+		t.Fatalf("stored an attestation in a crashed store: %+v %v", att, err)
+	}
+	_, err = o.Attest("rain", dlcoracle.GenerateNumericMessage(1))
+	if err == nil {
+		t.Fatal("stored an attestation in a crashed store")
+	}
+	l.Close()
+	store.crashed = false
+
+	// After a restart, another outcome is refused and the recorded one
+	// completed
+	l, err = audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	o = New(priv, store)
+	o.SetAuditLog(l)
+	_, err = o.Attest("rain", dlcoracle.GenerateNumericMessage(2))
+	if !errors.Is(err, ErrAlreadyAttested) {
+		t.Fatalf("expected ErrAlreadyAttested, got %v", err)
+	}
+	completed, err := o.CompleteAttestations()
+	if err != nil || len(completed) != 2 {
+		t.Fatalf("completed %v %v", completed, err)
+	}
+	ann, _ := store.Announcement("price")
+	k, _ := store.Nonce("price")
+	want, _ := dlcoracle.SignOutcome(priv, k, ann, dlcoracle.Outcome{Value: 421})
+	got, err := store.Attestation("price")
+	if err != nil || got.Signature != want.Signature || len(got.Signatures) != 3 || string(got.Message) != string(want.Message) {
+		t.Fatalf("stored %+v %v, want %+v", got, err, want)
+	}
+	outcome, err := dlcoracle.VerifyAttestation(ann, got)
+	if err != nil || outcome.Value != 421 {
+		t.Fatalf("completed attestation %+v %v", outcome, err)
+	}
+	att, err = o.Attest("rain", dlcoracle.GenerateNumericMessage(1))
+	if !errors.Is(err, ErrAlreadyAttested) {
+		t.Fatalf("attested a stored attestation again: %+v %v", att, err)
+	}
+	completed, err = o.CompleteAttestations()
+	if err != nil || len(completed) != 0 {
+		t.Fatalf("completed %v %v", completed, err)
+	}
+	_, err = o.Attest("later", dlcoracle.GenerateNumericMessage(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,277 @@
+package dlcoracle
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// The Parse functions decode untrusted input, such as records received
+// from an oracle over the network. They never panic, check everything
+// that can be checked without the oracle's public key, and return errors
+// of type *ParseError naming the offending field.
+
+// MaxRecordSize is the largest encoded announcement or attestation the
+// Parse functions accept
+const MaxRecordSize = 1 << 20
+
+var (
+	// ErrInvalidLength is wrapped by parse errors of input with the wrong
+	// length
+	ErrInvalidLength = errors.New("invalid length")
+
+	// ErrInvalidRecord is wrapped by parse errors of announcements and
+	// attestations that don't decode or are missing fields
+	ErrInvalidRecord = errors.New("invalid record")
+)
+
+// ParseError describes why input failed to parse
+type ParseError struct {
+	// Field is the path of the invalid value, such as
+	// "announcement.rPoint"
+	Field string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func parseErr(field string, err error) error {
+	return &ParseError{Field: field, Err: err}
+}
+
+// ParsePublicKey parses a compressed or x-only public key or R point,
+// checking that it is on the curve. X-only keys are returned with the
+// prefix of an even Y.
+func ParsePublicKey(b []byte) ([33]byte, error) {
+	pubKey, err := parsePublicKey(b)
+	if err != nil {
+		return pubKey, parseErr("public key", err)
+	}
+	return pubKey, nil
+}
+
+func parsePublicKey(b []byte) ([33]byte, error) {
+	var pubKey [33]byte
+	switch len(b) {
+	case 32:
+		pubKey[0] = 0x02
+		copy(pubKey[1:], b)
+	case 33:
+		copy(pubKey[:], b)
+	default:
+		return [33]byte{}, fmt.Errorf("%w: %d bytes, expected 32 or 33", ErrInvalidLength, len(b))
+	}
+	_, err := parsePoint(pubKey[:])
+	if err != nil {
+		return [33]byte{}, fmt.Errorf("%w: %w", ErrInvalidPubKey, err)
+	}
+	return pubKey, nil
+}
+
+// parsePubKey parses a compressed public key or R point, returning a
+// *PubKeyError with its role if it is invalid
+func parsePubKey(name string, key [33]byte) (*btcecv2.PublicKey, error) {
+	p, err := parsePoint(key[:])
+	if err != nil {
+		return nil, &PubKeyError{Name: name, Key: key[:], Err: err}
+	}
+	return p, nil
+}
+
+// parseJacobian is parsePubKey setting p to the point
+func parseJacobian(name string, key [33]byte, p *btcecv2.JacobianPoint) error {
+	pub, err := parsePubKey(name, key)
+	if err != nil {
+		return err
+	}
+	pub.AsJacobian(p)
+	return nil
+}
+
+// parsePoint parses a compressed point. Its errors tell apart the wrong
+// length, a wrong prefix, an X coordinate not on the curve and the point
+// at infinity, which btcec reports alike.
+func parsePoint(b []byte) (*btcecv2.PublicKey, error) {
+	switch {
+	case len(b) == 1 && b[0] == 0, len(b) == 33 && [33]byte(b) == [33]byte{}:
+		return nil, ErrPointAtInfinity
+	case len(b) != 33:
+		return nil, fmt.Errorf("%w: %d bytes, expected 33", ErrInvalidLength, len(b))
+	case b[0] != 0x02 && b[0] != 0x03:
+		return nil, fmt.Errorf("%w %#02x, expected 0x02 or 0x03", ErrInvalidPrefix, b[0])
+	}
+	p, err := btcecv2.ParsePubKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotOnCurve, err)
+	}
+	return p, nil
+}
+
+// ParseSignature parses a 32 byte signature, checking that it is below
+// the curve order
+func ParseSignature(b []byte) ([32]byte, error) {
+	sig, err := parseSignature(b)
+	if err != nil {
+		return sig, parseErr("signature", err)
+	}
+	return sig, nil
+}
+
+func parseSignature(b []byte) ([32]byte, error) {
+	var sig [32]byte
+	if len(b) != len(sig) {
+		return sig, fmt.Errorf("%w: %d bytes, expected 32", ErrInvalidLength, len(b))
+	}
+	copy(sig[:], b)
+	var s btcecv2.ModNScalar
+	if s.SetBytes(&sig) != 0 {
+		return [32]byte{}, ErrScalarOutOfRange
+	}
+	return sig, nil
+}
+
+// parseHex decodes s, failing for more than max bytes
+func parseHex(s string, max int) ([]byte, error) {
+	if len(s) > 2*max {
+		return nil, fmt.Errorf("%w: %d hex characters, at most %d", ErrInvalidLength, len(s), 2*max)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+	}
+	return b, nil
+}
+
+func parseHexKey(s string) ([33]byte, error) {
+	b, err := parseHex(s, 33)
+	if err != nil {
+		return [33]byte{}, err
+	}
+	return parsePublicKey(b)
+}
+
+// ParseAnnouncement parses an announcement encoded by MarshalJSON or
+// MarshalXOnlyJSON. It checks that the keys and signature are well-formed
+// and the descriptor is valid, but not the signature itself: call Verify,
+// and check that OraclePubKey is the oracle's key.
+func ParseAnnouncement(b []byte) (Announcement, error) {
+	if len(b) > MaxRecordSize {
+		return Announcement{}, parseErr("announcement", fmt.Errorf("%w: %d bytes, at most %d", ErrInvalidLength, len(b), MaxRecordSize))
+	}
+	var j announcementJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return Announcement{}, parseErr("announcement", fmt.Errorf("%w: %v", ErrInvalidRecord, err))
+	}
+	if j.EventID == "" {
+		return Announcement{}, parseErr("announcement.eventId", fmt.Errorf("%w: missing", ErrInvalidRecord))
+	}
+	a := Announcement{
+		EventID:        j.EventID,
+		Maturity:       time.Unix(j.Maturity, 0).UTC(),
+		MaturityHeight: j.MaturityHeight,
+		Descriptor:     j.Descriptor,
+	}
+	a.OraclePubKey, err = parseHexKey(j.OraclePubKey)
+	if err != nil {
+		return Announcement{}, parseErr("announcement.oraclePubKey", err)
+	}
+	a.RPoint, err = parseHexKey(j.RPoint)
+	if err != nil {
+		return Announcement{}, parseErr("announcement.rPoint", err)
+	}
+	if len(j.RPoints) > MaxDigits {
+		return Announcement{}, parseErr("announcement.rPoints", fmt.Errorf("%w: %d r points, at most %d", ErrInvalidLength, len(j.RPoints), MaxDigits))
+	}
+	for i, s := range j.RPoints {
+		R, err := parseHexKey(s)
+		if err != nil {
+			return Announcement{}, parseErr(fmt.Sprintf("announcement.rPoints[%d]", i), err)
+		}
+		a.RPoints = append(a.RPoints, R)
+	}
+	sig, err := parseHex(j.Signature, len(a.Signature))
+	if err == nil && len(sig) != len(a.Signature) {
+		err = fmt.Errorf("%w: %d bytes, expected %d", ErrInvalidLength, len(sig), len(a.Signature))
+	}
+	if err == nil {
+		_, err = parsePublicKey(sig[:33])
+	}
+	if err == nil {
+		_, err = parseSignature(sig[33:])
+	}
+	if err != nil {
+		return Announcement{}, parseErr("announcement.signature", err)
+	}
+	copy(a.Signature[:], sig)
+	err = a.Descriptor.Validate()
+	if err != nil {
+		return Announcement{}, parseErr("announcement.descriptor", fmt.Errorf("%w: %v", ErrInvalidRecord, err))
+	}
+	err = a.checkNonces()
+	if err != nil {
+		return Announcement{}, parseErr("announcement.rPoints", err)
+	}
+	if j.Metadata != nil {
+		err = j.Metadata.Validate()
+		if err != nil {
+			return Announcement{}, parseErr("announcement.metadata", fmt.Errorf("%w: %v", ErrInvalidRecord, err))
+		}
+		a.Metadata = *j.Metadata
+	}
+	return a, nil
+}
+
+// ParseAttestation parses an attestation encoded by MarshalJSON. It checks
+// that the signature is well-formed, but not that it is valid: call
+// VerifyAttestation with the event's announcement.
+func ParseAttestation(b []byte) (Attestation, error) {
+	if len(b) > MaxRecordSize {
+		return Attestation{}, parseErr("attestation", fmt.Errorf("%w: %d bytes, at most %d", ErrInvalidLength, len(b), MaxRecordSize))
+	}
+	var j attestationJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return Attestation{}, parseErr("attestation", fmt.Errorf("%w: %v", ErrInvalidRecord, err))
+	}
+	if j.EventID == "" {
+		return Attestation{}, parseErr("attestation.eventId", fmt.Errorf("%w: missing", ErrInvalidRecord))
+	}
+	a := Attestation{EventID: j.EventID}
+	a.Message, err = parseHex(j.Message, MaxRecordSize)
+	if err != nil {
+		return Attestation{}, parseErr("attestation.message", err)
+	}
+	sig, err := parseHex(j.Signature, len(a.Signature))
+	if err == nil {
+		a.Signature, err = parseSignature(sig)
+	}
+	if err != nil {
+		return Attestation{}, parseErr("attestation.signature", err)
+	}
+	if len(j.Signatures) > MaxDigits {
+		return Attestation{}, parseErr("attestation.signatures", fmt.Errorf("%w: %d signatures, at most %d", ErrInvalidLength, len(j.Signatures), MaxDigits))
+	}
+	for i, s := range j.Signatures {
+		sig, err := parseHex(s, 32)
+		var parsed [32]byte
+		if err == nil {
+			parsed, err = parseSignature(sig)
+		}
+		if err != nil {
+			return Attestation{}, parseErr(fmt.Sprintf("attestation.signatures[%d]", i), err)
+		}
+		a.Signatures = append(a.Signatures, parsed)
+	}
+	return a, nil
+}
@@ -0,0 +1,230 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	got, err := ParsePublicKey(pub[:])
+	if err != nil || got != pub {
+		t.Fatalf("got %x, %v", got, err)
+	}
+	got, err = ParsePublicKey(pub[1:])
+	if err != nil || got[0] != 0x02 || XOnly(got) != XOnly(pub) {
+		t.Fatalf("x-only: got %x, %v", got, err)
+	}
+
+	bad := pub
+	bad[0] = 0x04
+	_, err = ParsePublicKey(bad[:])
+	if !errors.Is(err, ErrInvalidPubKey) || !errors.Is(err, ErrInvalidPrefix) {
+		t.Fatalf("expected ErrInvalidPrefix, got %v", err)
+	}
+	var offCurve [33]byte
+	offCurve[0] = 0x02
+	offCurve[32] = 5
+	_, err = ParsePublicKey(offCurve[:])
+	if !errors.Is(err, ErrInvalidPubKey) || !errors.Is(err, ErrNotOnCurve) {
+		t.Fatalf("expected ErrNotOnCurve, got %v", err)
+	}
+	_, err = ParsePublicKey(make([]byte, 33))
+	if !errors.Is(err, ErrInvalidPubKey) || !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+	_, err = ParsePublicKey(pub[:20])
+	var pe *ParseError
+	if !errors.Is(err, ErrInvalidLength) || !errors.As(err, &pe) || pe.Field != "public key" {
+		t.Fatalf("expected a length error, got %v", err)
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	var sig [32]byte
+	sig[31] = 7
+	got, err := ParseSignature(sig[:])
+	if err != nil || got != sig {
+		t.Fatalf("got %x, %v", got, err)
+	}
+	for i := range sig {
+		sig[i] = 0xff
+	}
+	_, err = ParseSignature(sig[:])
+	if !errors.Is(err, ErrScalarOutOfRange) {
+		t.Fatalf("expected ErrScalarOutOfRange, got %v", err)
+	}
+	_, err = ParseSignature(nil)
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("expected ErrInvalidLength, got %v", err)
+	}
+}
+
+func TestParseAnnouncement(t *testing.T) {
+	a, _ := testAnnouncement(t)
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseAnnouncement(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SigningHash() != a.SigningHash() || got.Signature != a.Signature {
+		t.Fatalf("got %+v", got)
+	}
+
+	tests := []struct {
+		edit  func(m map[string]interface{})
+		field string
+		err   error
+	}{
+		{func(m map[string]interface{}) { delete(m, "eventId") }, "announcement.eventId", ErrInvalidRecord},
+		{func(m map[string]interface{}) { m["rPoint"] = "02" + strings.Repeat("00", 32) }, "announcement.rPoint", ErrInvalidPubKey},
+		{func(m map[string]interface{}) { m["oraclePubKey"] = "zz" }, "announcement.oraclePubKey", ErrInvalidRecord},
+		{func(m map[string]interface{}) { m["oraclePubKey"] = strings.Repeat("02", 1000) }, "announcement.oraclePubKey", ErrInvalidLength},
+		{func(m map[string]interface{}) { m["signature"] = "00" }, "announcement.signature", ErrInvalidLength},
+		{func(m map[string]interface{}) {
+			m["descriptor"] = map[string]interface{}{"type": "enum"}
+		}, "announcement.descriptor", ErrInvalidRecord},
+		{func(m map[string]interface{}) { m["maturity"] = "soon" }, "announcement", ErrInvalidRecord},
+	}
+	for i, test := range tests {
+		var m map[string]interface{}
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.edit(m)
+		edited, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ParseAnnouncement(edited)
+		var pe *ParseError
+		if !errors.Is(err, test.err) || !errors.As(err, &pe) || pe.Field != test.field {
+			t.Fatalf("test %d: expected %v in %s, got %v", i, test.err, test.field, err)
+		}
+	}
+
+	_, err = ParseAnnouncement(make([]byte, MaxRecordSize+1))
+	if !errors.Is(err, ErrInvalidLength) {
+		t.Fatalf("expected ErrInvalidLength, got %v", err)
+	}
+}
+
+func TestParseAttestation(t *testing.T) {
+	var sig [32]byte
+	sig[0] = 1
+	b, err := json.Marshal(Attestation{EventID: "event", Message: []byte("yes"), Signature: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ParseAttestation(b)
+	if err != nil || a.EventID != "event" || string(a.Message) != "yes" || a.Signature != sig {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+
+	for _, s := range []string{
+		`{"eventId":"event","message":"00","signature":"` + strings.Repeat("ff", 32) + `"}`,
+		`{"eventId":"event","message":"00","signature":"00"}`,
+		`{"eventId":"event","message":"0","signature":"` + strings.Repeat("00", 32) + `"}`,
+		`{"message":"00","signature":"` + strings.Repeat("00", 32) + `"}`,
+		`[]`,
+	} {
+		_, err = ParseAttestation([]byte(s))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("%s: expected a parse error, got %v", s, err)
+		}
+	}
+}
+
+func FuzzParsePublicKey(f *testing.F) {
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	f.Add(pub[:])
+	f.Add(pub[1:])
+	f.Fuzz(func(t *testing.T, b []byte) {
+		pub, err := ParsePublicKey(b)
+		if err != nil {
+			return
+		}
+		again, err := ParsePublicKey(pub[:])
+		if err != nil || again != pub {
+			t.Fatalf("%x parsed as %x, which doesn't parse: %v", b, pub, err)
+		}
+	})
+}
+
+func FuzzParseSignature(f *testing.F) {
+	f.Add(make([]byte, 32))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		sig, err := ParseSignature(b)
+		if err == nil && string(sig[:]) != string(b) {
+			t.Fatalf("%x parsed as %x", b, sig)
+		}
+	})
+}
+
+func FuzzParseAnnouncement(f *testing.F) {
+	var priv [32]byte
+	priv[31] = 1
+	a := Announcement{
+		EventID:      "event",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       PublicKeyFromPrivateKey(priv),
+		Descriptor:   EventDescriptor{Type: EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}
+	err := a.Sign(priv)
+	if err != nil {
+		f.Fatal(err)
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(b)
+	f.Add([]byte(`{"eventId":"x"}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		a, err := ParseAnnouncement(b)
+		if err != nil {
+			return
+		}
+		// Whatever parses can be verified and encoded again
+		a.Verify()
+		b, err = json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := ParseAnnouncement(b)
+		if err != nil || again.SigningHash() != a.SigningHash() {
+			t.Fatalf("%s doesn't parse again: %v", b, err)
+		}
+	})
+}
+
+func FuzzParseAttestation(f *testing.F) {
+	f.Add([]byte(`{"eventId":"event","message":"796573","signature":"` + strings.Repeat("01", 32) + `"}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		a, err := ParseAttestation(b)
+		if err != nil {
+			return
+		}
+		b, err = json.Marshal(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := ParseAttestation(b)
+		if err != nil || again.EventID != a.EventID || string(again.Message) != string(a.Message) {
+			t.Fatalf("%s doesn't parse again: %v", b, err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package dlcoracle
+
+import (
+	"context"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// ComputeSignaturePubKey and its variants decompress the oracle's public
+// key and the R point on every call, which services computing anticipation
+// points for many contracts under the same keys repeat needlessly. The
+// parsed key types below decompress them once and are safe for concurrent
+// use.
+
+// ParsedOraclePubKey is a decompressed oracle public key
+type ParsedOraclePubKey struct {
+	key   [33]byte
+	point btcecv2.JacobianPoint
+}
+
+// ParseOraclePubKey decompresses an oracle public key. It fails with a
+// *PubKeyError like ComputeSignaturePubKey.
+func ParseOraclePubKey(pubKey [33]byte) (*ParsedOraclePubKey, error) {
+	k := &ParsedOraclePubKey{key: pubKey}
+	err := parseJacobian("oracle pubkey", pubKey, &k.point)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Bytes returns the compressed public key
+func (k *ParsedOraclePubKey) Bytes() [33]byte {
+	return k.key
+}
+
+// ParsedNoncePoint is a decompressed R point
+type ParsedNoncePoint struct {
+	key   [33]byte
+	point btcecv2.JacobianPoint
+}
+
+// ParseNoncePoint decompresses an R point. It fails with a *PubKeyError
+// like ComputeSignaturePubKey.
+func ParseNoncePoint(rPoint [33]byte) (*ParsedNoncePoint, error) {
+	R := &ParsedNoncePoint{key: rPoint}
+	err := parseJacobian("r point", rPoint, &R.point)
+	if err != nil {
+		return nil, err
+	}
+	return R, nil
+}
+
+// Bytes returns the compressed R point
+func (R *ParsedNoncePoint) Bytes() [33]byte {
+	return R.key
+}
+
+// ComputeSignaturePubKey is ComputeSignaturePubKey with the parsed key
+// and R point
+func (k *ParsedOraclePubKey) ComputeSignaturePubKey(R *ParsedNoncePoint, message []byte) ([33]byte, error) {
+	p := signaturePointer{A: k.point, R: R.point, h: SHA256}
+	return p.point(message)
+}
+
+// ComputeSignaturePubKeys is ComputeSignaturePubKeys with the parsed key
+// and R point
+func (k *ParsedOraclePubKey) ComputeSignaturePubKeys(ctx context.Context, R *ParsedNoncePoint, messages [][]byte) ([][33]byte, error) {
+	p := signaturePointer{A: k.point, R: R.point, h: SHA256}
+	return p.points(ctx, messages)
+}
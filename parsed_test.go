@@ -0,0 +1,59 @@
+package dlcoracle
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParsedKeys(t *testing.T) {
+	A := PublicKeyFromPrivateKey([32]byte{31: 5})
+	R := PublicKeyFromPrivateKey([32]byte{31: 7})
+	key, err := ParseOraclePubKey(A)
+	if err != nil || key.Bytes() != A {
+		t.Fatalf("parsed %v %v", key, err)
+	}
+	nonce, err := ParseNoncePoint(R)
+	if err != nil || nonce.Bytes() != R {
+		t.Fatalf("parsed %v %v", nonce, err)
+	}
+
+	messages := make([][]byte, 2*minTableBatch)
+	for i := range messages {
+		messages[i] = GenerateNumericMessage(uint64(i))
+	}
+	points, err := key.ComputeSignaturePubKeys(context.Background(), nonce, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range messages {
+		want, err := ComputeSignaturePubKey(A, R, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := key.ComputeSignaturePubKey(nonce, msg)
+		if err != nil || got != want || points[i] != want {
+			t.Fatalf("message %d: %x and %x, expected %x (%v)", i, got, points[i], want, err)
+		}
+	}
+
+	_, err = ParseNoncePoint([33]byte{0x02})
+	var pe *PubKeyError
+	if !errors.Is(err, ErrNotOnCurve) || !errors.As(err, &pe) || pe.Name != "r point" {
+		t.Fatalf("expected an r point off the curve, got %v", err)
+	}
+	_, err = ParseOraclePubKey([33]byte{})
+	if !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+}
+
+func BenchmarkParsedComputeSignaturePubKey(b *testing.B) {
+	key, _ := ParseOraclePubKey(PublicKeyFromPrivateKey([32]byte{31: 1}))
+	nonce, _ := ParseNoncePoint(PublicKeyFromPrivateKey([32]byte{31: 2}))
+	msg := GenerateNumericMessage(42)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		key.ComputeSignaturePubKey(nonce, msg)
+	}
+}
@@ -0,0 +1,195 @@
+package dlcoracle
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// SignerPool spreads batches of signatures and anticipation points over a
+// fixed number of goroutines. Work is handed to the workers through a
+// queue as long as the number of workers, so memory use beyond the results
+// doesn't grow with the size of a batch.
+type SignerPool struct {
+	workers   int
+	tasks     chan func()
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	batches   atomic.Uint64
+	items     atomic.Uint64
+	failed    atomic.Uint64
+	itemTime  atomic.Int64
+	batchTime atomic.Int64
+}
+
+// PoolStats are the counters of a SignerPool since it was created
+type PoolStats struct {
+	Workers int
+
+	// Batches and Items count completed batches and the items computed
+	// in them; Failed counts batches that failed or were cancelled
+	Batches uint64
+	Items   uint64
+	Failed  uint64
+
+	// ItemLatency is the mean time a worker spent on an item, and
+	// BatchLatency the mean time from submitting a batch to its result
+	ItemLatency  time.Duration
+	BatchLatency time.Duration
+
+	// Throughput is the number of items the pool computes per second
+	// of batch time, with all workers busy
+	Throughput float64
+}
+
+// NewSignerPool starts a pool of workers goroutines, or one per CPU if
+// workers isn't positive. Close stops them.
+func NewSignerPool(workers int) *SignerPool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &SignerPool{
+		workers: workers,
+		tasks:   make(chan func(), workers),
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Close stops the workers after the queued work is done. The pool must
+// not be used afterwards.
+func (p *SignerPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.tasks)
+	})
+	p.wg.Wait()
+}
+
+// run calls f for every index up to n on the workers. It returns the
+// first error of f, or the context's error once ctx is done; either stops
+// the remaining calls.
+func (p *SignerPool) run(ctx context.Context, n int, f func(i int) error) error {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+submit:
+	for i := 0; i < n; i++ {
+		i := i
+		wg.Add(1)
+		task := func() {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				return
+			}
+			itemStart := time.Now()
+			err := f(i)
+			p.itemTime.Add(int64(time.Since(itemStart)))
+			p.items.Add(1)
+			if err != nil {
+				fail(err)
+			}
+		}
+		select {
+		case p.tasks <- task:
+		case <-ctx.Done():
+			wg.Done()
+			break submit
+		}
+	}
+	wg.Wait()
+
+	p.batchTime.Add(int64(time.Since(start)))
+	if firstErr == nil && ctx.Err() != nil {
+		// The caller's context was cancelled; cancel is only called
+		// after a failure or on return
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		p.failed.Add(1)
+		return firstErr
+	}
+	p.batches.Add(1)
+	return nil
+}
+
+// ComputeSignaturePubKeys is ComputeSignaturePubKeys on the pool's
+// workers
+func (p *SignerPool) ComputeSignaturePubKeys(ctx context.Context, oraclePubA, oraclePubR [33]byte, messages [][]byte) ([][33]byte, error) {
+	sp, err := newSignaturePointer(oraclePubA, oraclePubR, SHA256)
+	if err != nil {
+		return nil, err
+	}
+	sp.precompute(len(messages))
+	defer sp.release()
+	points := make([][33]byte, len(messages))
+	if sp.table == nil {
+		err = p.run(ctx, len(messages), func(i int) error {
+			var err error
+			points[i], err = sp.point(messages[i])
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return points, nil
+	}
+
+	sums := make([]btcecv2.JacobianPoint, len(messages))
+	err = p.run(ctx, len(messages), func(i int) error {
+		return sp.sum(messages[i], &sums[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = serializeBatch(sums, points)
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// Stats returns the pool's counters
+func (p *SignerPool) Stats() PoolStats {
+	s := PoolStats{
+		Workers: p.workers,
+		Batches: p.batches.Load(),
+		Items:   p.items.Load(),
+		Failed:  p.failed.Load(),
+	}
+	itemTime := time.Duration(p.itemTime.Load())
+	batchTime := time.Duration(p.batchTime.Load())
+	if s.Items > 0 {
+		s.ItemLatency = itemTime / time.Duration(s.Items)
+	}
+	if n := s.Batches + s.Failed; n > 0 {
+		s.BatchLatency = batchTime / time.Duration(n)
+	}
+	if batchTime > 0 {
+		s.Throughput = float64(s.Items) / batchTime.Seconds()
+	}
+	return s
+}
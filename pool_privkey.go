@@ -0,0 +1,19 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "context"
+
+// ComputeSignatures is ComputeSignatures on the pool's workers
+func (p *SignerPool) ComputeSignatures(ctx context.Context, privKey [32]byte, reqs []SignRequest) ([][32]byte, error) {
+	sigs := make([][32]byte, len(reqs))
+	err := p.run(ctx, len(reqs), func(i int) error {
+		var err error
+		sigs[i], err = ComputeSignature(privKey, reqs[i].OneTimeSigningKey, reqs[i].Message)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sigs, nil
+}
@@ -0,0 +1,92 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSignerPool(t *testing.T) {
+	p := NewSignerPool(4)
+	defer p.Close()
+
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	reqs := make([]SignRequest, 50)
+	messages := make([][]byte, len(reqs))
+	for i := range reqs {
+		reqs[i].OneTimeSigningKey[31] = 7
+		reqs[i].Message = GenerateNumericMessage(uint64(i))
+		messages[i] = reqs[i].Message
+	}
+	ctx := context.Background()
+
+	sigs, err := p.ComputeSignatures(ctx, priv, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ComputeSignatures(ctx, priv, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sigs {
+		if sigs[i] != expected[i] {
+			t.Fatalf("signature %d differs from sequential computation", i)
+		}
+	}
+
+	R := PublicKeyFromPrivateKey(reqs[0].OneTimeSigningKey)
+	points, err := p.ComputeSignaturePubKeys(ctx, pub, R, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range points {
+		if PublicKeyFromPrivateKey(sigs[i]) != points[i] {
+			t.Fatalf("point %d doesn't match signature", i)
+		}
+	}
+
+	// A failing item fails the batch
+	reqs[20].OneTimeSigningKey = [32]byte{}
+	_, err = p.ComputeSignatures(ctx, priv, reqs)
+	if err == nil {
+		t.Fatal("zero one-time signing key accepted")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = p.ComputeSignaturePubKeys(cancelled, pub, R, messages)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	s := p.Stats()
+	if s.Workers != 4 || s.Batches != 2 || s.Failed != 2 || s.Items < 100 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.ItemLatency <= 0 || s.Throughput <= 0 {
+		t.Fatalf("missing latency or throughput in %+v", s)
+	}
+}
+
+func BenchmarkSignerPool(b *testing.B) {
+	p := NewSignerPool(0)
+	defer p.Close()
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	A, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	messages := make([][]byte, 1000)
+	for i := range messages {
+		messages[i] = GenerateNumericMessage(uint64(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := p.ComputeSignaturePubKeys(context.Background(), A, R, messages)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package ratelimit limits the requests a public oracle serves, per client
+// IP and in total, so scrapers can't exhaust it. The same Limiter guards
+// the REST server as middleware and the gRPC service as interceptors.
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// idleTimeout is how long the limiter of a client IP is kept after its
+// last request
+const idleTimeout = 10 * time.Minute
+
+// Config sets the request rates a Limiter allows. A zero rate disables
+// the corresponding limit.
+type Config struct {
+	// GlobalRate and GlobalBurst limit the requests of all clients
+	// together, in requests per second
+	GlobalRate  float64
+	GlobalBurst int
+
+	// PerIPRate and PerIPBurst limit the requests of each client IP
+	PerIPRate  float64
+	PerIPBurst int
+
+	// TrustForwardedFor takes the client IP of HTTP requests from the
+	// first X-Forwarded-For entry. Only enable it behind a reverse proxy
+	// that sets the header, or clients can pick their own IP.
+	TrustForwardedFor bool
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter decides whether a request may be served
+type Limiter struct {
+	cfg    Config
+	global *rate.Limiter
+
+	mtx       sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+// New returns a limiter enforcing cfg
+func New(cfg Config) *Limiter {
+	l := &Limiter{
+		cfg:       cfg,
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+	if cfg.GlobalRate > 0 {
+		l.global = rate.NewLimiter(rate.Limit(cfg.GlobalRate), cfg.GlobalBurst)
+	}
+	return l
+}
+
+// Allow reports whether a request from ip may be served now
+func (l *Limiter) Allow(ip string) bool {
+	if l.cfg.PerIPRate > 0 && !l.clientLimiter(ip).Allow() {
+		return false
+	}
+	return l.global == nil || l.global.Allow()
+}
+
+func (l *Limiter) clientLimiter(ip string) *rate.Limiter {
+	now := time.Now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	// Forget clients that went quiet, so the map doesn't grow without bound
+	if now.Sub(l.lastSweep) > idleTimeout {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > idleTimeout {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(l.cfg.PerIPRate), l.cfg.PerIPBurst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// Middleware rejects HTTP requests over the limit with 429 Too Many
+// Requests. It fits server.Server.Use.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow(l.httpClientIP(r)) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *Limiter) httpClientIP(r *http.Request) string {
+	if l.cfg.TrustForwardedFor {
+		fwd := r.Header.Get("X-Forwarded-For")
+		if fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	return hostOnly(r.RemoteAddr)
+}
+
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func (l *Limiter) checkGRPC(ctx context.Context) error {
+	ip := ""
+	p, ok := peer.FromContext(ctx)
+	if ok && p.Addr != nil {
+		ip = hostOnly(p.Addr.String())
+	}
+	if !l.Allow(ip) {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return nil
+}
+
+// UnaryInterceptor rejects gRPC calls over the limit with
+// ResourceExhausted
+func (l *Limiter) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		err := l.checkGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects gRPC streams over the limit with
+// ResourceExhausted
+func (l *Limiter) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		err := l.checkGRPC(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
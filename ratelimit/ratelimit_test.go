@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestPerIP(t *testing.T) {
+	l := New(Config{PerIPRate: 0.001, PerIPBurst: 2})
+	for i := 0; i < 2; i++ {
+		if !l.Allow("1.2.3.4") {
+			t.Fatalf("request %d was limited", i)
+		}
+	}
+	if l.Allow("1.2.3.4") {
+		t.Fatal("third request was allowed")
+	}
+	if !l.Allow("5.6.7.8") {
+		t.Fatal("other client was limited")
+	}
+}
+
+func TestGlobal(t *testing.T) {
+	l := New(Config{GlobalRate: 0.001, GlobalBurst: 1})
+	if !l.Allow("1.2.3.4") {
+		t.Fatal("first request was limited")
+	}
+	if l.Allow("5.6.7.8") {
+		t.Fatal("request over the global limit was allowed")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l := New(Config{PerIPRate: 0.001, PerIPBurst: 1, TrustForwardedFor: true})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	got := make([]int, 0, 3)
+	for _, fwd := range []string{"1.2.3.4", "1.2.3.4, 10.0.0.1", "5.6.7.8"} {
+		r := httptest.NewRequest("GET", "/api/pubkey", nil)
+		r.Header.Set("X-Forwarded-For", fwd)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		got = append(got, w.Code)
+	}
+	if got[0] != http.StatusOK || got[1] != http.StatusTooManyRequests || got[2] != http.StatusOK {
+		t.Fatalf("unexpected status codes %v", got)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	l := New(Config{PerIPRate: 0.001, PerIPBurst: 1})
+	intercept := l.UnaryInterceptor()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 1234},
+	})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = intercept(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted, got %v", err)
+	}
+}
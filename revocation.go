@@ -0,0 +1,121 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRevocationMismatch is returned by VerifyRevocation when the
+// revocation is for another event or oracle than the announcement
+var ErrRevocationMismatch = errors.New("revocation is for another announcement")
+
+// revocationTag separates revocation signatures from any other message
+// signed with the oracle's key
+const revocationTag = "DLC/oracle/revocation"
+
+// Revocation is the oracle's signed promise never to attest an event it
+// announced, for instance because a match was cancelled. An announcement
+// can be superseded by one for another event, such as the rescheduled
+// match.
+type Revocation struct {
+	EventID      string
+	OraclePubKey [33]byte
+
+	// Reason tells contract participants why the event won't be attested
+	Reason string
+
+	// SupersededBy is the ID of the event replacing the revoked one, or
+	// empty
+	SupersededBy string
+
+	RevokedAt time.Time
+	Signature [65]byte
+}
+
+// SigningHash returns the digest of the revocation's contents that the
+// oracle signs. RevokedAt is committed to with a precision of seconds.
+func (r Revocation) SigningHash() [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	h.Write([]byte(revocationTag))
+	writeString(h, r.EventID)
+	h.Write(r.OraclePubKey[:])
+	writeString(h, r.Reason)
+	writeString(h, r.SupersededBy)
+	binary.BigEndian.PutUint64(buf[:], uint64(r.RevokedAt.Unix()))
+	h.Write(buf[:])
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// Verify checks the oracle's signature on the revocation
+func (r Revocation) Verify() error {
+	digest := r.SigningHash()
+	err := VerifyMessage(r.OraclePubKey, digest[:], r.Signature)
+	if err != nil {
+		return fmt.Errorf("revocation of %s: %w", r.EventID, err)
+	}
+	return nil
+}
+
+// VerifyRevocation checks that r revokes the event announced in a, signed
+// by the same oracle. The announcement itself is not checked.
+func VerifyRevocation(a Announcement, r Revocation) error {
+	if r.EventID != a.EventID || r.OraclePubKey != a.OraclePubKey {
+		return fmt.Errorf("%w: revocation of %q by %x, announcement of %q by %x",
+			ErrRevocationMismatch, r.EventID, r.OraclePubKey, a.EventID, a.OraclePubKey)
+	}
+	return r.Verify()
+}
+
+type revocationJSON struct {
+	EventID      string `json:"eventId"`
+	OraclePubKey string `json:"oraclePubKey"`
+	Reason       string `json:"reason,omitempty"`
+	SupersededBy string `json:"supersededBy,omitempty"`
+	RevokedAt    int64  `json:"revokedAt"`
+	Signature    string `json:"signature"`
+}
+
+// MarshalJSON encodes the revocation with hex encoded key and signature
+// and the revocation time as a unix timestamp in seconds
+func (r Revocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(revocationJSON{
+		EventID:      r.EventID,
+		OraclePubKey: hex.EncodeToString(r.OraclePubKey[:]),
+		Reason:       r.Reason,
+		SupersededBy: r.SupersededBy,
+		RevokedAt:    r.RevokedAt.Unix(),
+		Signature:    hex.EncodeToString(r.Signature[:]),
+	})
+}
+
+// UnmarshalJSON decodes a revocation encoded by MarshalJSON. RevokedAt is
+// returned in UTC.
+func (r *Revocation) UnmarshalJSON(b []byte) error {
+	var j revocationJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	err = decodeHexKey(&r.OraclePubKey, j.OraclePubKey)
+	if err != nil {
+		return err
+	}
+	err = decodeHexFixed(r.Signature[:], j.Signature)
+	if err != nil {
+		return err
+	}
+	r.EventID = j.EventID
+	r.Reason = j.Reason
+	r.SupersededBy = j.SupersededBy
+	r.RevokedAt = time.Unix(j.RevokedAt, 0).UTC()
+	return nil
+}
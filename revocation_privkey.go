@@ -0,0 +1,20 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import "fmt"
+
+// Sign signs the revocation with the oracle's private key, which must
+// match OraclePubKey. The options are passed on to SignMessage.
+func (r *Revocation) Sign(privKey [32]byte, opts ...SignOption) error {
+	if PublicKeyFromPrivateKey(privKey) != r.OraclePubKey {
+		return fmt.Errorf("private key does not match oracle pubkey")
+	}
+	digest := r.SigningHash()
+	sig, err := SignMessage(privKey, digest[:], opts...)
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	return nil
+}
@@ -0,0 +1,53 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRevocation(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 3
+	a := Announcement{EventID: "match", OraclePubKey: PublicKeyFromPrivateKey(priv)}
+	r := Revocation{
+		EventID:      "match",
+		OraclePubKey: a.OraclePubKey,
+		Reason:       "cancelled",
+		SupersededBy: "rematch",
+		RevokedAt:    time.Unix(1700000000, 0).UTC(),
+	}
+	err := r.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifyRevocation(a, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Revocation
+	err = json.Unmarshal(b, &decoded)
+	if err != nil || decoded != r {
+		t.Fatalf("got %+v, %v from %s", decoded, err, b)
+	}
+
+	tampered := r
+	tampered.SupersededBy = "other"
+	if VerifyRevocation(a, tampered) == nil {
+		t.Fatal("tampered revocation verified")
+	}
+	other := a
+	other.EventID = "other"
+	err = VerifyRevocation(other, r)
+	if !errors.Is(err, ErrRevocationMismatch) {
+		t.Fatalf("expected ErrRevocationMismatch, got %v", err)
+	}
+}
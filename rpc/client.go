@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// CallOption selects the JSON codec the oracle service uses. Pass it to
+// grpc.WithDefaultCallOptions when dialing an oracle.
+func CallOption() grpc.CallOption {
+	return grpc.CallContentSubtype(codecName)
+}
+
+// OracleClient is the client API of the oracle service
+type OracleClient interface {
+	CreateEvent(ctx context.Context, in *CreateEventRequest, opts ...grpc.CallOption) (*dlcoracle.Announcement, error)
+	GetAnnouncement(ctx context.Context, in *GetAnnouncementRequest, opts ...grpc.CallOption) (*dlcoracle.Announcement, error)
+	Attest(ctx context.Context, in *AttestRequest, opts ...grpc.CallOption) (*dlcoracle.Attestation, error)
+	ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error)
+	Updates(ctx context.Context, in *UpdatesRequest, opts ...grpc.CallOption) (UpdatesClient, error)
+	CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error)
+	SetAPIKeyRole(ctx context.Context, in *SetAPIKeyRoleRequest, opts ...grpc.CallOption) (*auth.APIKey, error)
+	RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error)
+	ReloadSources(ctx context.Context, in *ReloadSourcesRequest, opts ...grpc.CallOption) (*ReloadSourcesResponse, error)
+}
+
+// UpdatesClient is the client side of the Updates stream
+type UpdatesClient interface {
+	Recv() (*Update, error)
+	grpc.ClientStream
+}
+
+type oracleClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewOracleClient returns a client for the oracle service on cc
+func NewOracleClient(cc grpc.ClientConnInterface) OracleClient {
+	return &oracleClient{cc}
+}
+
+func (c *oracleClient) invoke(ctx context.Context, method string, in, out interface{}, opts []grpc.CallOption) error {
+	opts = append([]grpc.CallOption{CallOption()}, opts...)
+	return c.cc.Invoke(ctx, "/"+ServiceName+"/"+method, in, out, opts...)
+}
+
+func (c *oracleClient) CreateEvent(ctx context.Context, in *CreateEventRequest, opts ...grpc.CallOption) (*dlcoracle.Announcement, error) {
+	out := new(dlcoracle.Announcement)
+	err := c.invoke(ctx, "CreateEvent", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) GetAnnouncement(ctx context.Context, in *GetAnnouncementRequest, opts ...grpc.CallOption) (*dlcoracle.Announcement, error) {
+	out := new(dlcoracle.Announcement)
+	err := c.invoke(ctx, "GetAnnouncement", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) Attest(ctx context.Context, in *AttestRequest, opts ...grpc.CallOption) (*dlcoracle.Attestation, error) {
+	out := new(dlcoracle.Attestation)
+	err := c.invoke(ctx, "Attest", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) ListEvents(ctx context.Context, in *ListEventsRequest, opts ...grpc.CallOption) (*ListEventsResponse, error) {
+	out := new(ListEventsResponse)
+	err := c.invoke(ctx, "ListEvents", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) CreateAPIKey(ctx context.Context, in *CreateAPIKeyRequest, opts ...grpc.CallOption) (*CreateAPIKeyResponse, error) {
+	out := new(CreateAPIKeyResponse)
+	err := c.invoke(ctx, "CreateAPIKey", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) ListAPIKeys(ctx context.Context, in *ListAPIKeysRequest, opts ...grpc.CallOption) (*ListAPIKeysResponse, error) {
+	out := new(ListAPIKeysResponse)
+	err := c.invoke(ctx, "ListAPIKeys", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) SetAPIKeyRole(ctx context.Context, in *SetAPIKeyRoleRequest, opts ...grpc.CallOption) (*auth.APIKey, error) {
+	out := new(auth.APIKey)
+	err := c.invoke(ctx, "SetAPIKeyRole", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) RevokeAPIKey(ctx context.Context, in *RevokeAPIKeyRequest, opts ...grpc.CallOption) (*RevokeAPIKeyResponse, error) {
+	out := new(RevokeAPIKeyResponse)
+	err := c.invoke(ctx, "RevokeAPIKey", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) ReloadSources(ctx context.Context, in *ReloadSourcesRequest, opts ...grpc.CallOption) (*ReloadSourcesResponse, error) {
+	out := new(ReloadSourcesResponse)
+	err := c.invoke(ctx, "ReloadSources", in, out, opts)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oracleClient) Updates(ctx context.Context, in *UpdatesRequest, opts ...grpc.CallOption) (UpdatesClient, error) {
+	opts = append([]grpc.CallOption{CallOption()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Updates", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &updatesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type updatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *updatesClient) Recv() (*Update, error) {
+	m := new(Update)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AdminContext returns a context that authenticates calls with token:
+// the admin token, a token issued by an auth.Authority or the secret of
+// an API key
+func AdminContext(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the content subtype clients have to request to talk to the
+// oracle service, see CallOption
+const codecName = "json"
+
+// jsonCodec encodes the service's messages as JSON, so the service can be
+// used without a protobuf toolchain and its messages share their encoding
+// with the REST API.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+)
+
+// CreateEventRequest asks the oracle to announce a new event. Maturity is
+// a unix timestamp in seconds. Events with a MaturityHeight mature at that
+// block height instead.
+type CreateEventRequest struct {
+	EventID        string                    `json:"eventId"`
+	Maturity       int64                     `json:"maturity"`
+	MaturityHeight uint32                    `json:"maturityHeight,omitempty"`
+	Descriptor     dlcoracle.EventDescriptor `json:"descriptor"`
+	Metadata       *dlcoracle.EventMetadata  `json:"metadata,omitempty"`
+}
+
+// GetAnnouncementRequest asks for the announcement of an event
+type GetAnnouncementRequest struct {
+	EventID string `json:"eventId"`
+}
+
+// AttestRequest asks the oracle to sign the outcome of an event. Message
+// is hex encoded.
+type AttestRequest struct {
+	EventID string `json:"eventId"`
+	Message string `json:"message"`
+}
+
+// ListEventsRequest asks for the events known to the oracle, all of them
+// unless filtered. MaturesAfter and MaturesBefore are unix timestamps in
+// seconds bounding the maturity, inclusively and exclusively. Status is
+// announced, attested or revoked; Tags must all be set on the events.
+// Events are listed by maturity, then by ID. Limit caps the number of
+// events returned, and After continues after the event with that ID,
+// such as the Next of the previous response.
+type ListEventsRequest struct {
+	Unit          string                `json:"unit,omitempty"`
+	Types         []dlcoracle.EventType `json:"types,omitempty"`
+	MaturesAfter  int64                 `json:"maturesAfter,omitempty"`
+	MaturesBefore int64                 `json:"maturesBefore,omitempty"`
+	Status        string                `json:"status,omitempty"`
+	Category      string                `json:"category,omitempty"`
+	Tags          []string              `json:"tags,omitempty"`
+	After         string                `json:"after,omitempty"`
+	Limit         uint32                `json:"limit,omitempty"`
+}
+
+// Event is an announced event, along with its attestation once the
+// oracle has signed the outcome
+type Event struct {
+	Announcement dlcoracle.Announcement `json:"announcement"`
+	Attestation  *dlcoracle.Attestation `json:"attestation,omitempty"`
+}
+
+// ListEventsResponse contains the events selected by the request. Next is
+// set when the response holds Limit events, and is the ID of the last one.
+type ListEventsResponse struct {
+	Events []Event `json:"events"`
+	Next   string  `json:"next,omitempty"`
+}
+
+// UpdatesRequest subscribes to the oracle's new announcements and
+// attestations
+type UpdatesRequest struct{}
+
+// Update is streamed to subscribers for every new announcement or
+// attestation. Exactly one of the fields is set.
+type Update struct {
+	Announcement *dlcoracle.Announcement `json:"announcement,omitempty"`
+	Attestation  *dlcoracle.Attestation  `json:"attestation,omitempty"`
+}
+
+// CreateAPIKeyRequest asks for a new API key with a role
+type CreateAPIKeyRequest struct {
+	Name string    `json:"name"`
+	Role auth.Role `json:"role"`
+}
+
+// CreateAPIKeyResponse returns a new API key along with its secret, which
+// isn't shown again
+type CreateAPIKeyResponse struct {
+	Key    auth.APIKey `json:"key"`
+	Secret string      `json:"secret"`
+}
+
+// ListAPIKeysRequest asks for all API keys
+type ListAPIKeysRequest struct{}
+
+// ListAPIKeysResponse contains all API keys, without their secrets
+type ListAPIKeysResponse struct {
+	Keys []auth.APIKey `json:"keys"`
+}
+
+// SetAPIKeyRoleRequest changes the role of an API key
+type SetAPIKeyRoleRequest struct {
+	ID   string    `json:"id"`
+	Role auth.Role `json:"role"`
+}
+
+// RevokeAPIKeyRequest asks to revoke an API key
+type RevokeAPIKeyRequest struct {
+	ID string `json:"id"`
+}
+
+// RevokeAPIKeyResponse confirms a revocation
+type RevokeAPIKeyResponse struct{}
+
+// ReloadSourcesRequest asks to reload the data sources
+type ReloadSourcesRequest struct{}
+
+// ReloadSourcesResponse lists the IDs of the data sources after a reload
+type ReloadSourcesResponse struct {
+	Sources []string `json:"sources"`
+}
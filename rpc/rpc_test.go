@@ -0,0 +1,363 @@
+//go:build !verifyonly
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "secret"
+
+func newTestClient(t *testing.T) (OracleClient, *oracle.Oracle) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	srv := NewServer(o)
+	srv.SetAdminToken(testToken)
+	return dial(t, srv), o
+}
+
+// dial serves srv over an in-memory connection and returns a client
+func dial(t *testing.T, srv *Server) OracleClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterOracleServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return NewOracleClient(cc)
+}
+
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+func TestCreateAndAttest(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := AdminContext(context.Background(), testToken)
+
+	meta := &dlcoracle.EventMetadata{Category: "crypto", Tags: []string{"btc"}}
+	a, err := c.CreateEvent(ctx, &CreateEventRequest{EventID: "event", Maturity: 1000, Metadata: meta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.Verify()
+	if err != nil || a.Metadata.Category != "crypto" {
+		t.Fatalf("announcement %+v %v", a, err)
+	}
+
+	got, err := c.GetAnnouncement(context.Background(), &GetAnnouncementRequest{EventID: "event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RPoint != a.RPoint || !got.Maturity.Equal(a.Maturity) {
+		t.Fatalf("unexpected announcement %+v", got)
+	}
+
+	msg := dlcoracle.GenerateNumericMessage(42)
+	att, err := c.Attest(ctx, &AttestRequest{EventID: "event", Message: hex.EncodeToString(msg)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint, msg, att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Attest(ctx, &AttestRequest{EventID: "event", Message: "00"})
+	expectCode(t, err, codes.FailedPrecondition)
+	_, err = c.CreateEvent(ctx, &CreateEventRequest{EventID: "event", Maturity: 1000})
+	expectCode(t, err, codes.AlreadyExists)
+
+	list, err := c.ListEvents(context.Background(), &ListEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Events) != 1 || list.Events[0].Attestation == nil {
+		t.Fatalf("unexpected events %+v", list)
+	}
+	for _, f := range []struct {
+		req  ListEventsRequest
+		want int
+	}{
+		{ListEventsRequest{Status: "attested", MaturesAfter: 1000, MaturesBefore: 1001}, 1},
+		{ListEventsRequest{Status: "announced"}, 0},
+		{ListEventsRequest{Types: []dlcoracle.EventType{dlcoracle.EventTypeEnum}}, 0},
+		{ListEventsRequest{MaturesBefore: 1000}, 0},
+		{ListEventsRequest{Category: "crypto", Tags: []string{"btc"}}, 1},
+	} {
+		list, err = c.ListEvents(context.Background(), &f.req)
+		if err != nil || len(list.Events) != f.want {
+			t.Fatalf("%+v: listed %+v %v", f.req, list, err)
+		}
+	}
+	_, err = c.ListEvents(context.Background(), &ListEventsRequest{Status: "pending"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = c.ListEvents(context.Background(), &ListEventsRequest{After: "unknown"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	// A second event on a second page
+	_, err = c.CreateEvent(ctx, &CreateEventRequest{EventID: "later", Maturity: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err = c.ListEvents(context.Background(), &ListEventsRequest{Limit: 1})
+	if err != nil || len(list.Events) != 1 || list.Next != "event" {
+		t.Fatalf("first page %+v %v", list, err)
+	}
+	list, err = c.ListEvents(context.Background(), &ListEventsRequest{Limit: 1, After: list.Next})
+	if err != nil || len(list.Events) != 1 || list.Events[0].Announcement.EventID != "later" || list.Next != "later" {
+		t.Fatalf("second page %+v %v", list, err)
+	}
+	list, err = c.ListEvents(context.Background(), &ListEventsRequest{Limit: 1, After: list.Next})
+	if err != nil || len(list.Events) != 0 || list.Next != "" {
+		t.Fatalf("last page %+v %v", list, err)
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	c, _ := newTestClient(t)
+	_, err := c.CreateEvent(context.Background(), &CreateEventRequest{EventID: "event"})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = c.Attest(AdminContext(context.Background(), "wrong"),
+		&AttestRequest{EventID: "event"})
+	expectCode(t, err, codes.Unauthenticated)
+}
+
+func TestInvalidRequests(t *testing.T) {
+	c, _ := newTestClient(t)
+	ctx := AdminContext(context.Background(), testToken)
+	_, err := c.GetAnnouncement(ctx, &GetAnnouncementRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = c.Attest(ctx, &AttestRequest{Message: "00"})
+	expectCode(t, err, codes.InvalidArgument)
+	_, err = c.GetAnnouncement(ctx, &GetAnnouncementRequest{EventID: "missing"})
+	expectCode(t, err, codes.NotFound)
+
+	future := time.Now().Add(time.Hour).Unix()
+	_, err = c.CreateEvent(ctx, &CreateEventRequest{EventID: "future", Maturity: future})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Attest(ctx, &AttestRequest{EventID: "future", Message: "00"})
+	expectCode(t, err, codes.FailedPrecondition)
+}
+
+func TestUpdatesStream(t *testing.T) {
+	c, o := newTestClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Updates(ctx, &UpdatesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give the server time to subscribe
+	time.Sleep(50 * time.Millisecond)
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Announcement == nil || u.Announcement.EventID != "event" {
+		t.Fatalf("unexpected update %+v", u)
+	}
+}
+
+func TestScopedTokens(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	a := auth.NewAuthority([32]byte{7})
+	srv := NewServer(o)
+	srv.SetAuthority(a)
+	srv.SetPrivateReads(true)
+
+	createOnly, err := a.Issue(auth.ScopeCaveat(auth.ScopeCreateEvent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := AdminContext(context.Background(), createOnly)
+	_, err = srv.CreateEvent(metadataContext(ctx), &CreateEventRequest{EventID: "event", Maturity: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = srv.Attest(metadataContext(ctx), &AttestRequest{EventID: "event", Message: "00"})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = srv.ListEvents(metadataContext(ctx), &ListEventsRequest{})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = srv.ListEvents(context.Background(), &ListEventsRequest{})
+	expectCode(t, err, codes.Unauthenticated)
+
+	reader, err := a.Issue(auth.ScopeCaveat(auth.ScopeRead))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = srv.ListEvents(metadataContext(AdminContext(context.Background(), reader)), &ListEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// metadataContext turns the outgoing metadata of ctx into incoming
+// metadata, as the server sees it
+func metadataContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func TestClientCertAuth(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	srv := NewServer(oracle.New(priv, storage.NewMemoryStore()))
+	srv.SetClientCertAuth(true)
+
+	req := &CreateEventRequest{EventID: "event", Maturity: 1000}
+	unverified := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{}},
+	})
+	_, err := srv.CreateEvent(unverified, req)
+	expectCode(t, err, codes.Unauthenticated)
+
+	verified := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{{}}},
+		}},
+	})
+	_, err = srv.CreateEvent(verified, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	srv := NewServer(oracle.New(priv, storage.NewMemoryStore()))
+	srv.SetAdminToken(testToken)
+	srv.SetKeyring(auth.NewKeyring())
+	c := dial(t, srv)
+	admin := AdminContext(context.Background(), testToken)
+
+	res, err := c.CreateAPIKey(admin, &CreateAPIKeyRequest{Name: "scheduler", Role: auth.RoleEventCreator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Key.Name != "scheduler" || res.Key.CreatedAt.IsZero() {
+		t.Fatalf("unexpected key %+v", res.Key)
+	}
+	_, err = c.CreateAPIKey(admin, &CreateAPIKeyRequest{Name: "ops", Role: "root"})
+	expectCode(t, err, codes.InvalidArgument)
+
+	// The role is enforced per RPC
+	creator := AdminContext(context.Background(), res.Secret)
+	_, err = c.CreateEvent(creator, &CreateEventRequest{EventID: "event", Maturity: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Attest(creator, &AttestRequest{EventID: "event", Message: "00"})
+	expectCode(t, err, codes.PermissionDenied)
+	_, err = c.ListAPIKeys(creator, &ListAPIKeysRequest{})
+	expectCode(t, err, codes.PermissionDenied)
+
+	key, err := c.SetAPIKeyRole(admin, &SetAPIKeyRoleRequest{ID: res.Key.ID, Role: auth.RoleAttester})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Role != auth.RoleAttester {
+		t.Fatalf("unexpected key %+v", key)
+	}
+	msg := hex.EncodeToString(dlcoracle.GenerateNumericMessage(42))
+	_, err = c.Attest(creator, &AttestRequest{EventID: "event", Message: msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.ListAPIKeys(admin, &ListAPIKeysRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Keys) != 1 || list.Keys[0].ID != res.Key.ID {
+		t.Fatalf("unexpected keys %+v", list.Keys)
+	}
+	_, err = c.RevokeAPIKey(admin, &RevokeAPIKeyRequest{ID: res.Key.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetAnnouncement(creator, &GetAnnouncementRequest{EventID: "event"})
+	if err != nil {
+		t.Fatal("public read refused")
+	}
+	_, err = c.CreateEvent(creator, &CreateEventRequest{EventID: "event2", Maturity: 1000})
+	expectCode(t, err, codes.Unauthenticated)
+	_, err = c.RevokeAPIKey(admin, &RevokeAPIKeyRequest{ID: res.Key.ID})
+	expectCode(t, err, codes.NotFound)
+
+	// Without a keyring the management RPCs are unavailable
+	c2, _ := newTestClient(t)
+	_, err = c2.ListAPIKeys(admin, &ListAPIKeysRequest{})
+	expectCode(t, err, codes.FailedPrecondition)
+}
+
+func TestReloadSources(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	srv := NewServer(oracle.New(priv, storage.NewMemoryStore()))
+	srv.SetAdminToken(testToken)
+	c := dial(t, srv)
+	admin := AdminContext(context.Background(), testToken)
+
+	_, err := c.ReloadSources(admin, &ReloadSourcesRequest{})
+	expectCode(t, err, codes.FailedPrecondition)
+
+	fail := false
+	srv.SetSourceReloader(func(context.Context) ([]string, error) {
+		if fail {
+			return nil, errors.New("source btcusd: unknown exchange")
+		}
+		return []string{"btcusd", "manual"}, nil
+	})
+	_, err = c.ReloadSources(context.Background(), &ReloadSourcesRequest{})
+	expectCode(t, err, codes.Unauthenticated)
+	res, err := c.ReloadSources(admin, &ReloadSourcesRequest{})
+	if err != nil || len(res.Sources) != 2 || res.Sources[0] != "btcusd" {
+		t.Fatalf("reloaded %+v %v", res, err)
+	}
+	fail = true
+	_, err = c.ReloadSources(admin, &ReloadSourcesRequest{})
+	expectCode(t, err, codes.InvalidArgument)
+}
@@ -0,0 +1,401 @@
+//go:build !verifyonly
+
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+	"github.com/mit-dci/dlc-oracle-go/clock"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements OracleServer on top of an oracle.
+//
+// CreateEvent and Attest make the oracle sign with its private key, so
+// they are administrative RPCs: they are refused unless the caller
+// presents "authorization: Bearer <token>" metadata with the token set
+// with SetAdminToken, a token from the authority set with SetAuthority or
+// an API key from the keyring set with SetKeyring granting
+// auth.ScopeCreateEvent or auth.ScopeAttest, or, after SetClientCertAuth,
+// a verified TLS client certificate. GetAnnouncement, ListEvents and
+// Updates are public unless SetPrivateReads is enabled, in which case
+// they need auth.ScopeRead. Managing API keys and reloading data sources
+// needs auth.ScopeAdmin.
+type Server struct {
+	oracle       *oracle.Oracle
+	adminToken   string
+	authority    *auth.Authority
+	keyring      *auth.Keyring
+	privateReads bool
+	reload       SourceReloader
+
+	clientCertAuth bool
+}
+
+// SourceReloader reloads the data sources of an oracle, returning the IDs
+// of those it has afterwards
+type SourceReloader func(ctx context.Context) ([]string, error)
+
+// NewServer returns the oracle service for o. The administrative RPCs
+// stay disabled until SetAdminToken or SetAuthority is called. Even then
+// tokens are sent in the clear unless the listener uses TLS, so only
+// expose the service on a private network or behind TLS.
+func NewServer(o *oracle.Oracle) *Server {
+	return &Server{oracle: o}
+}
+
+// SetAdminToken enables all RPCs for callers presenting token
+func (s *Server) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetClientCertAuth enables all RPCs for callers presenting a client
+// certificate the TLS listener verified, as configured by the ClientCAs
+// and ClientAuth of its tls.Config. Use tls.VerifyClientCertIfGiven so
+// public reads keep working without a certificate.
+func (s *Server) SetClientCertAuth(enabled bool) {
+	s.clientCertAuth = enabled
+}
+
+// SetAuthority accepts the scoped tokens issued by a
+func (s *Server) SetAuthority(a *auth.Authority) {
+	s.authority = a
+}
+
+// SetKeyring accepts the API keys in k, with the scopes of their roles,
+// and enables the RPCs managing them
+func (s *Server) SetKeyring(k *auth.Keyring) {
+	s.keyring = k
+}
+
+// SetPrivateReads requires auth.ScopeRead for reading announcements,
+// attestations and updates
+func (s *Server) SetPrivateReads(private bool) {
+	s.privateReads = private
+}
+
+// SetSourceReloader enables ReloadSources, which calls reload
+func (s *Server) SetSourceReloader(reload SourceReloader) {
+	s.reload = reload
+}
+
+// checkRead verifies that the caller may read, if reads are private
+func (s *Server) checkRead(ctx context.Context) error {
+	if !s.privateReads {
+		return nil
+	}
+	return s.checkScope(ctx, auth.ScopeRead)
+}
+
+// checkScope verifies that the caller presented a token granting scope
+func (s *Server) checkScope(ctx context.Context, scope auth.Scope) error {
+	if s.adminToken == "" && s.authority == nil && s.keyring == nil && !s.clientCertAuth {
+		return status.Error(codes.PermissionDenied, "authenticated RPCs are disabled")
+	}
+	if s.clientCertAuth && verifiedClient(ctx) {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	denied := false
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimPrefix(v, "Bearer ")
+		if s.adminToken != "" &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+			return nil
+		}
+		var err error
+		switch {
+		case auth.IsAPIKey(token) && s.keyring != nil:
+			err = s.keyring.Authorize(token, scope)
+		case !auth.IsAPIKey(token) && s.authority != nil:
+			err = s.authority.Authorize(token, scope)
+		default:
+			continue
+		}
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, auth.ErrPermissionDenied) {
+			denied = true
+		}
+	}
+	if denied {
+		return status.Errorf(codes.PermissionDenied, "token lacks the %s scope", scope)
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+// verifiedClient reports whether the caller presented a client
+// certificate the TLS listener verified
+func verifiedClient(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) != 0
+}
+
+// CreateEvent announces a new event
+func (s *Server) CreateEvent(ctx context.Context, req *CreateEventRequest) (*dlcoracle.Announcement, error) {
+	err := s.checkScope(ctx, auth.ScopeCreateEvent)
+	if err != nil {
+		return nil, err
+	}
+	err = checkEventID(req.EventID)
+	if err != nil {
+		return nil, err
+	}
+	ev := dlcoracle.Event{
+		ID:             req.EventID,
+		Maturity:       time.Unix(req.Maturity, 0).UTC(),
+		MaturityHeight: req.MaturityHeight,
+		Descriptor:     req.Descriptor,
+	}
+	if req.Metadata != nil {
+		ev.Metadata = *req.Metadata
+	}
+	a, err := s.oracle.CreateEvent(ev)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &a, nil
+}
+
+// GetAnnouncement returns the announcement of an event
+func (s *Server) GetAnnouncement(ctx context.Context, req *GetAnnouncementRequest) (*dlcoracle.Announcement, error) {
+	err := s.checkRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = checkEventID(req.EventID)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.oracle.Store().Announcement(req.EventID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &a, nil
+}
+
+// Attest signs the outcome of an event
+func (s *Server) Attest(ctx context.Context, req *AttestRequest) (*dlcoracle.Attestation, error) {
+	err := s.checkScope(ctx, auth.ScopeAttest)
+	if err != nil {
+		return nil, err
+	}
+	err = checkEventID(req.EventID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := hex.DecodeString(req.Message)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "message: %v", err)
+	}
+	a, err := s.oracle.AttestContext(ctx, req.EventID, msg)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &a, nil
+}
+
+// ListEvents returns the announced events selected by the request along
+// with their attestations
+func (s *Server) ListEvents(ctx context.Context, req *ListEventsRequest) (*ListEventsResponse, error) {
+	err := s.checkRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := storage.Query{Unit: req.Unit, Types: req.Types, Category: req.Category, Tags: req.Tags}
+	if req.MaturesAfter != 0 {
+		q.MaturesAfter = time.Unix(req.MaturesAfter, 0)
+	}
+	if req.MaturesBefore != 0 {
+		q.MaturesBefore = time.Unix(req.MaturesBefore, 0)
+	}
+	if req.Status != "" {
+		q.Status, err = storage.ParseEventStatus(req.Status)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	q.Limit = int(req.Limit)
+	store := s.oracle.Store()
+	if req.After != "" {
+		q.After, err = storage.NewCursor(store, req.After)
+		if err == storage.ErrNotFound {
+			return nil, status.Errorf(codes.InvalidArgument, "after: unknown event %q", req.After)
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+	}
+	list, err := storage.Search(store, q)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	res := &ListEventsResponse{Events: make([]Event, len(list))}
+	for i, a := range list {
+		res.Events[i].Announcement = a
+		att, err := store.Attestation(a.EventID)
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		res.Events[i].Attestation = &att
+	}
+	if q.Limit > 0 && len(list) == q.Limit {
+		res.Next = list[len(list)-1].EventID
+	}
+	return res, nil
+}
+
+// Updates streams new announcements and attestations until the client
+// goes away. Clients that fall behind get an Aborted error and have to
+// resync with ListEvents before subscribing again.
+func (s *Server) Updates(req *UpdatesRequest, stream UpdatesServer) error {
+	err := s.checkRead(stream.Context())
+	if err != nil {
+		return err
+	}
+	updates, cancel := s.oracle.Subscribe()
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case u, ok := <-updates:
+			if !ok {
+				return status.Error(codes.Aborted, "missed updates, resync required")
+			}
+			err := stream.Send(&Update{
+				Announcement: u.Announcement,
+				Attestation:  u.Attestation,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkKeyring verifies that the caller may manage API keys
+func (s *Server) checkKeyring(ctx context.Context) error {
+	err := s.checkScope(ctx, auth.ScopeAdmin)
+	if err != nil {
+		return err
+	}
+	if s.keyring == nil {
+		return status.Error(codes.FailedPrecondition, "API keys are disabled")
+	}
+	return nil
+}
+
+// CreateAPIKey creates an API key with a role
+func (s *Server) CreateAPIKey(ctx context.Context, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	err := s.checkKeyring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, secret, err := s.keyring.Create(req.Name, req.Role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &CreateAPIKeyResponse{Key: key, Secret: secret}, nil
+}
+
+// ListAPIKeys returns all API keys
+func (s *Server) ListAPIKeys(ctx context.Context, req *ListAPIKeysRequest) (*ListAPIKeysResponse, error) {
+	err := s.checkKeyring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ListAPIKeysResponse{Keys: s.keyring.List()}, nil
+}
+
+// SetAPIKeyRole changes the role of an API key, taking effect on its next
+// call
+func (s *Server) SetAPIKeyRole(ctx context.Context, req *SetAPIKeyRoleRequest) (*auth.APIKey, error) {
+	err := s.checkKeyring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.keyring.SetRole(req.ID, req.Role)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &key, nil
+}
+
+// RevokeAPIKey revokes an API key
+func (s *Server) RevokeAPIKey(ctx context.Context, req *RevokeAPIKeyRequest) (*RevokeAPIKeyResponse, error) {
+	err := s.checkKeyring(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.keyring.Revoke(req.ID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &RevokeAPIKeyResponse{}, nil
+}
+
+// ReloadSources reloads the data sources without interrupting the
+// scheduler
+func (s *Server) ReloadSources(ctx context.Context, req *ReloadSourcesRequest) (*ReloadSourcesResponse, error) {
+	err := s.checkScope(ctx, auth.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if s.reload == nil {
+		return nil, status.Error(codes.FailedPrecondition, "reloading data sources is disabled")
+	}
+	ids, err := s.reload(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &ReloadSourcesResponse{Sources: ids}, nil
+}
+
+func checkEventID(eventID string) error {
+	if eventID == "" {
+		return status.Error(codes.InvalidArgument, "event id is required")
+	}
+	return nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case err == storage.ErrNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, oracle.ErrEventExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, oracle.ErrAlreadyAttested), errors.Is(err, oracle.ErrNotMatured),
+		errors.Is(err, oracle.ErrKeyOffline):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, clock.ErrClockDrift), errors.Is(err, oracle.ErrAttestationPending),
+		errors.Is(err, oracle.ErrStopped):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, auth.ErrUnknownKey):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, auth.ErrUnknownRole):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/auth"
+	"google.golang.org/grpc"
+)
+
+// ServiceName is the full name of the gRPC oracle service, as defined in
+// oracle.proto
+const ServiceName = "dlcoracle.Oracle"
+
+// OracleServer is the server API of the oracle service
+type OracleServer interface {
+	CreateEvent(context.Context, *CreateEventRequest) (*dlcoracle.Announcement, error)
+	GetAnnouncement(context.Context, *GetAnnouncementRequest) (*dlcoracle.Announcement, error)
+	Attest(context.Context, *AttestRequest) (*dlcoracle.Attestation, error)
+	ListEvents(context.Context, *ListEventsRequest) (*ListEventsResponse, error)
+	Updates(*UpdatesRequest, UpdatesServer) error
+	CreateAPIKey(context.Context, *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error)
+	ListAPIKeys(context.Context, *ListAPIKeysRequest) (*ListAPIKeysResponse, error)
+	SetAPIKeyRole(context.Context, *SetAPIKeyRoleRequest) (*auth.APIKey, error)
+	RevokeAPIKey(context.Context, *RevokeAPIKeyRequest) (*RevokeAPIKeyResponse, error)
+	ReloadSources(context.Context, *ReloadSourcesRequest) (*ReloadSourcesResponse, error)
+}
+
+// UpdatesServer is the server side of the Updates stream
+type UpdatesServer interface {
+	Send(*Update) error
+	grpc.ServerStream
+}
+
+type updatesServer struct {
+	grpc.ServerStream
+}
+
+func (s *updatesServer) Send(u *Update) error {
+	return s.ServerStream.SendMsg(u)
+}
+
+// RegisterOracleServer registers srv as the oracle service on s
+func RegisterOracleServer(s grpc.ServiceRegistrar, srv OracleServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*OracleServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateEvent",
+			Handler:    unaryHandler("CreateEvent", OracleServer.CreateEvent),
+		},
+		{
+			MethodName: "GetAnnouncement",
+			Handler:    unaryHandler("GetAnnouncement", OracleServer.GetAnnouncement),
+		},
+		{
+			MethodName: "Attest",
+			Handler:    unaryHandler("Attest", OracleServer.Attest),
+		},
+		{
+			MethodName: "ListEvents",
+			Handler:    unaryHandler("ListEvents", OracleServer.ListEvents),
+		},
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    unaryHandler("CreateAPIKey", OracleServer.CreateAPIKey),
+		},
+		{
+			MethodName: "ListAPIKeys",
+			Handler:    unaryHandler("ListAPIKeys", OracleServer.ListAPIKeys),
+		},
+		{
+			MethodName: "SetAPIKeyRole",
+			Handler:    unaryHandler("SetAPIKeyRole", OracleServer.SetAPIKeyRole),
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    unaryHandler("RevokeAPIKey", OracleServer.RevokeAPIKey),
+		},
+		{
+			MethodName: "ReloadSources",
+			Handler:    unaryHandler("ReloadSources", OracleServer.ReloadSources),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Updates",
+			Handler:       updatesHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+// unaryHandler adapts a method of OracleServer to a grpc method handler
+func unaryHandler[Req, Res any](method string,
+	call func(OracleServer, context.Context, *Req) (Res, error)) func(interface{},
+	context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+
+		in := new(Req)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(OracleServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + ServiceName + "/" + method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(OracleServer), ctx, req.(*Req))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+func updatesHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(UpdatesRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(OracleServer).Updates(in, &updatesServer{stream})
+}
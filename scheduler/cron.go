@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence tells when the occurrences of a template mature
+type Recurrence interface {
+	// Next returns the first maturity after t, or the zero time if
+	// there is none
+	Next(t time.Time) time.Time
+}
+
+// cronSearchDays bounds the search for the next matching day. Nine years
+// always contain a February 29 that is a given day of the week.
+const cronSearchDays = 9 * 366
+
+// cronMacros are the shorthands for common expressions
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	dayNames   = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// Cron is a cron expression evaluated in a time zone, such as
+// "0 16 * * MON-FRI" in America/New_York for every weekday at market
+// close. It implements Recurrence.
+type Cron struct {
+	expr   string
+	loc    *time.Location
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// As in cron, when both the day of the month and the day of the
+	// week are restricted a day matching either matches
+	domStar, dowStar bool
+}
+
+// ParseCron parses a cron expression of five fields: minute, hour, day of
+// the month, month and day of the week. Fields are lists of values,
+// ranges and steps such as "*/15" or "1-5"; months and days of the week
+// can be named ("JAN", "MON-FRI"), and Sunday is 0 or 7. The shorthands
+// @yearly, @monthly, @weekly, @daily and @hourly are accepted too. The
+// times are in loc, or UTC if it is nil. Local times skipped by a
+// daylight saving change match when the clocks change, and local times
+// repeated by one match once.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	spec := expr
+	if m, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q has %d fields, expected 5", expr, len(fields))
+	}
+	c := &Cron{expr: expr, loc: loc}
+	var err error
+	c.minute, err = parseCronField(fields[0], 0, 59, nil)
+	if err == nil {
+		c.hour, err = parseCronField(fields[1], 0, 23, nil)
+	}
+	if err == nil {
+		c.dom, err = parseCronField(fields[2], 1, 31, nil)
+	}
+	if err == nil {
+		c.month, err = parseCronField(fields[3], 1, 12, monthNames)
+	}
+	if err == nil {
+		c.dow, err = parseCronField(fields[4], 0, 7, dayNames)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cron expression %q: %v", expr, err)
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+	if c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, loc)).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", expr)
+	}
+	return c, nil
+}
+
+// parseCronField parses a comma separated field into a bit set of the
+// values from min to max it matches. names, if any, stand for the values
+// from min on.
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			lo, err = parseCronValue(bounds[0], min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			hi, err = parseCronValue(bounds[1], min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("empty range %q", part)
+			}
+		default:
+			var err error
+			lo, err = parseCronValue(part, min, max, names)
+			if err != nil {
+				return 0, err
+			}
+			if step == 1 {
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseCronValue(s string, min, max int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q, expected %d to %d", s, min, max)
+	}
+	return v, nil
+}
+
+// String returns the expression and time zone
+func (c *Cron) String() string {
+	return fmt.Sprintf("%s in %s", c.expr, c.loc)
+}
+
+// Location returns the time zone the expression is evaluated in
+func (c *Cron) Location() *time.Location {
+	return c.loc
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	if c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t matching the expression
+func (c *Cron) Next(t time.Time) time.Time {
+	local := t.In(c.loc)
+	y, m, d := local.Date()
+	for i := 0; i < cronSearchDays; i++ {
+		day := time.Date(y, m, d+i, 12, 0, 0, 0, c.loc)
+		if !c.matchesDay(day) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if c.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			for min := 0; min < 60; min++ {
+				if c.minute&(1<<uint(min)) == 0 {
+					continue
+				}
+				next := time.Date(day.Year(), day.Month(), day.Day(), h, min, 0, 0, c.loc)
+				if next.Hour() != h || next.Minute() != min {
+					// Skipped by the clocks going forward
+					next = time.Date(day.Year(), day.Month(), day.Day(), h+1, 0, 0, 0, c.loc)
+				}
+				if next.After(t) {
+					return next
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
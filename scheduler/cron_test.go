@@ -0,0 +1,81 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestCronNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	cases := []struct {
+		expr string
+		loc  *time.Location
+		from string
+		want string
+	}{
+		{"*/15 * * * *", time.UTC, "2030-01-01T00:07:00Z", "2030-01-01T00:15:00Z"},
+		{"@daily", time.UTC, "2030-01-01T00:00:00Z", "2030-01-02T00:00:00Z"},
+		{"0 9 1 JAN,jul *", time.UTC, "2030-02-01T00:00:00Z", "2030-07-01T09:00:00Z"},
+		// 2030-01-04 is a Friday
+		{"0 16 * * MON-FRI", ny, "2030-01-04T22:00:00Z", "2030-01-07T21:00:00Z"},
+		{"0 0 * * 7", time.UTC, "2030-01-01T00:00:00Z", "2030-01-06T00:00:00Z"},
+		// Day of the month or day of the week
+		{"0 0 13 * FRI", time.UTC, "2030-09-01T00:00:00Z", "2030-09-06T00:00:00Z"},
+		{"0 0 29 2 *", time.UTC, "2030-01-01T00:00:00Z", "2032-02-29T00:00:00Z"},
+		// New York's clocks spring forward over 2:00 to 3:00 on
+		// 2030-03-10 and fall back over 2:00 to 1:00 on 2030-11-03
+		{"30 2 * * *", ny, "2030-03-10T05:00:00Z", "2030-03-10T07:00:00Z"},
+		{"30 1 * * *", ny, "2030-11-03T05:31:00Z", "2030-11-04T06:30:00Z"},
+	}
+	for _, c := range cases {
+		cron, err := ParseCron(c.expr, c.loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, _ := time.Parse(time.RFC3339, c.from)
+		got := cron.Next(from).UTC().Format(time.RFC3339)
+		if got != c.want {
+			t.Errorf("%s after %s: got %s, want %s", cron, c.from, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "* * * * MON-XYZ", "5-1 * * * *", "*/0 * * * *", "0 0 31 2 *"} {
+		_, err := ParseCron(bad, nil)
+		if err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestCronTemplate(t *testing.T) {
+	cron, err := ParseCron("0 16 * * MON-FRI", time.FixedZone("EST", -5*3600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := Template{
+		Prefix:     "spx-",
+		Start:      time.Date(2030, 1, 4, 0, 0, 0, 0, time.UTC),
+		Recurrence: cron,
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric},
+		Ahead:      3,
+	}
+	err = tmpl.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := tmpl.Events(time.Date(2029, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(list) != 3 || list[0].ID != "spx-2030-01-04T21:00:00Z" ||
+		list[1].ID != "spx-2030-01-07T21:00:00Z" || list[2].ID != "spx-2030-01-08T21:00:00Z" {
+		t.Fatalf("unexpected events %+v", list)
+	}
+
+	tmpl.Period = time.Hour
+	if tmpl.Validate() == nil {
+		t.Fatal("template with a period and a recurrence accepted")
+	}
+}
@@ -0,0 +1,82 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DisagreementPolicy decides what the scheduler does when the data sources
+// of an event disagree, that is when fetching its outcome fails with an
+// error wrapping datasource.ErrDisagreement. An alert is raised under every
+// policy.
+type DisagreementPolicy uint8
+
+const (
+	// DisagreementRetry fetches the outcome again after the retry
+	// interval, until the sources agree
+	DisagreementRetry DisagreementPolicy = iota
+
+	// DisagreementAbort stops attempting to attest the event until Retry
+	// is called or the scheduler is restarted
+	DisagreementAbort
+
+	// DisagreementMedian attests the median of all the values the
+	// sources responded with, outliers included. Errors that don't carry
+	// the values, not being a *datasource.DisagreementError, are retried.
+	DisagreementMedian
+)
+
+var disagreementPolicyNames = []string{"retry", "abort", "median"}
+
+// String returns the name of p
+func (p DisagreementPolicy) String() string {
+	if int(p) < len(disagreementPolicyNames) {
+		return disagreementPolicyNames[p]
+	}
+	return fmt.Sprintf("DisagreementPolicy(%d)", uint8(p))
+}
+
+// MarshalText encodes p by its name
+func (p DisagreementPolicy) MarshalText() ([]byte, error) {
+	if int(p) >= len(disagreementPolicyNames) {
+		return nil, fmt.Errorf("unknown disagreement policy %d", uint8(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText parses a policy name: "retry", "abort" or "median"
+func (p *DisagreementPolicy) UnmarshalText(text []byte) error {
+	for i, name := range disagreementPolicyNames {
+		if string(text) == name {
+			*p = DisagreementPolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown disagreement policy %q, expected one of %s", text,
+		strings.Join(disagreementPolicyNames, ", "))
+}
+
+// SetDisagreementPolicy applies p to the events whose ID starts with
+// prefix, or to all events without a policy of their own if prefix is
+// empty. The longest matching prefix wins. Events default to
+// DisagreementRetry.
+func (s *Scheduler) SetDisagreementPolicy(prefix string, p DisagreementPolicy) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.policies == nil {
+		s.policies = make(map[string]DisagreementPolicy)
+	}
+	s.policies[prefix] = p
+}
+
+// disagreementPolicy returns the policy of the event with ID id. The
+// caller holds s.mtx.
+func (s *Scheduler) disagreementPolicy(id string) DisagreementPolicy {
+	policy, longest := DisagreementRetry, -1
+	for prefix, p := range s.policies {
+		if len(prefix) > longest && strings.HasPrefix(id, prefix) {
+			policy, longest = p, len(prefix)
+		}
+	}
+	return policy
+}
@@ -0,0 +1,507 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/notify"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultRetryInterval is how long the scheduler waits before fetching
+// the outcome of an event again after a failure
+const DefaultRetryInterval = time.Minute
+
+// chainTimeout bounds queries to the chain backend
+const chainTimeout = 30 * time.Second
+
+// DefaultChainPollInterval is how often the scheduler checks the chain
+// height while events maturing at a block height are due
+const DefaultChainPollInterval = 30 * time.Second
+
+// DefaultOverdueAfter is how long after its maturity an event that still
+// isn't attested raises an overdue alert
+const DefaultOverdueAfter = 15 * time.Minute
+
+// alertTimeout bounds the delivery of an alert
+const alertTimeout = time.Minute
+
+// ErrNotPending is returned by Retry for events that aren't waiting to be
+// attested
+var ErrNotPending = errors.New("event not pending")
+
+// OutcomeFetcher looks up the outcome of an event that has matured. A
+// datasource.Registry dispatches to the data source configured for each
+// event.
+type OutcomeFetcher interface {
+	FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error)
+}
+
+// ContextFetcher is implemented by OutcomeFetchers that trace their fetches
+// as part of the attempt in ctx. datasource.Registry implements it.
+type ContextFetcher interface {
+	FetchOutcomeContext(ctx context.Context, ev dlcoracle.Event) (dlcoracle.Outcome, error)
+}
+
+// OutcomeHook computes the outcome to attest from the one fetched for an
+// event, for instance to round or clamp it. script.Script implements it.
+type OutcomeHook func(ev dlcoracle.Event, o dlcoracle.Outcome) (dlcoracle.Outcome, error)
+
+// EvidenceCollector hands over the upstream responses the outcome of an
+// event was fetched from. evidence.Recorder implements it.
+type EvidenceCollector interface {
+	Take(eventID string) []dlcoracle.UpstreamResponse
+}
+
+// Job is an announced event waiting to be attested
+type Job struct {
+	Event dlcoracle.Event
+
+	// NextAttempt is when the scheduler will next try to attest the
+	// event. It starts out at the event's maturity.
+	NextAttempt time.Time
+
+	// Attempts counts the failed attempts so far, LastError holds the
+	// error of the last one
+	Attempts  int
+	LastError error
+
+	// Aborted is set when the event's data sources disagreed under
+	// DisagreementAbort; it isn't attempted again until Retry
+	Aborted bool
+
+	// alerted records the alerts raised for the job, so each is only
+	// raised once
+	alerted map[notify.Kind]bool
+}
+
+// Scheduler announces events and attests to them once they mature, using
+// an OutcomeFetcher to find out their outcome. Its pending jobs are the
+// announced events the oracle's store holds no attestation for, so no
+// state is lost when the scheduler is restarted. Maturities are compared
+// against the oracle's clock.
+type Scheduler struct {
+	oracle  *oracle.Oracle
+	fetcher OutcomeFetcher
+	logger  dlcoracle.Logger
+
+	mtx               sync.Mutex
+	notifier          notify.Notifier
+	overdueAfter      time.Duration
+	retryInterval     time.Duration
+	chainPollInterval time.Duration
+	hook              OutcomeHook
+	evidence          EvidenceCollector
+	policies          map[string]DisagreementPolicy
+	jobs              map[string]*Job
+	templates         []Template
+
+	// wake interrupts Run's wait when the earliest job may have changed
+	wake chan struct{}
+}
+
+// New returns a scheduler attesting with o to the outcomes from f
+func New(o *oracle.Oracle, f OutcomeFetcher) *Scheduler {
+	return &Scheduler{
+		oracle:            o,
+		fetcher:           f,
+		logger:            dlcoracle.NopLogger(),
+		overdueAfter:      DefaultOverdueAfter,
+		retryInterval:     DefaultRetryInterval,
+		chainPollInterval: DefaultChainPollInterval,
+		jobs:              make(map[string]*Job),
+		wake:              make(chan struct{}, 1),
+	}
+}
+
+// SetLogger sets the logger that records failed attempts
+func (s *Scheduler) SetLogger(l dlcoracle.Logger) {
+	s.logger = l
+}
+
+// SetNotifier sets the notifier alerted when an event is overdue or its
+// data sources disagree. Each alert is raised once per event.
+func (s *Scheduler) SetNotifier(n notify.Notifier) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.notifier = n
+}
+
+// SetOverdueAfter changes how long after its maturity an unattested event
+// is reported as overdue
+func (s *Scheduler) SetOverdueAfter(d time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.overdueAfter = d
+}
+
+// SetRetryInterval changes how long to wait after a failed attempt
+func (s *Scheduler) SetRetryInterval(d time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.retryInterval = d
+}
+
+// SetChainPollInterval changes how often the chain height is checked for
+// events maturing at a block height
+func (s *Scheduler) SetChainPollInterval(d time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.chainPollInterval = d
+}
+
+// SetOutcomeHook sets the hook the fetched outcomes go through before
+// they are attested. Its errors are retried like those of the data
+// sources.
+func (s *Scheduler) SetOutcomeHook(h OutcomeHook) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.hook = h
+}
+
+// SetEvidenceCollector sets where the responses each attested outcome was
+// fetched from are taken from, to be signed and stored as the event's
+// evidence. The oracle's store has to implement storage.EvidenceStore.
+func (s *Scheduler) SetEvidenceCollector(c EvidenceCollector) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.evidence = c
+}
+
+// Schedule announces ev and queues it for attestation at its maturity
+func (s *Scheduler) Schedule(ev dlcoracle.Event) (dlcoracle.Announcement, error) {
+	a, err := s.oracle.CreateEvent(ev)
+	if err != nil {
+		return a, err
+	}
+	s.add(a.Event())
+	return a, nil
+}
+
+// Retry attempts to attest a pending event right away, for instance once
+// the disagreement of its data sources that aborted it is resolved
+func (s *Scheduler) Retry(eventID string) error {
+	s.mtx.Lock()
+	j, ok := s.jobs[eventID]
+	if !ok {
+		s.mtx.Unlock()
+		return ErrNotPending
+	}
+	j.Aborted = false
+	j.NextAttempt = s.oracle.Clock().Now()
+	// Alert again if the sources still disagree
+	delete(j.alerted, notify.KindDisagreement)
+	s.mtx.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns the jobs that haven't been attested yet, ordered by
+// their next attempt
+func (s *Scheduler) Pending() []Job {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	list := make([]Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		list = append(list, *j)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].NextAttempt.Before(list[j].NextAttempt)
+	})
+	return list
+}
+
+// Run picks up the unattested events from the oracle's store and attests
+// to each of them as it matures, until ctx is cancelled. Templates are
+// expanded whenever an event matures, so their next occurrences are
+// announced in time.
+func (s *Scheduler) Run(ctx context.Context) error {
+	err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for {
+		s.expand(s.oracle.Clock().Now())
+		var timer *time.Timer
+		var fire <-chan time.Time
+		next, ok := s.nextAttempt()
+		if ok {
+			// Wait by the oracle's clock, which need not be the
+			// system clock
+			timer = time.NewTimer(next.Sub(s.oracle.Clock().Now()))
+			fire = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-s.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-fire:
+			s.attestDue(s.oracle.Clock().Now())
+		}
+	}
+}
+
+// load queues all announced events that have not been attested or
+// revoked yet
+func (s *Scheduler) load() error {
+	store := s.oracle.Store()
+	list, err := store.Announcements()
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		_, err := store.Attestation(a.EventID)
+		if err == nil {
+			continue
+		}
+		if err != storage.ErrNotFound {
+			return err
+		}
+		if rs, ok := store.(storage.RevocationStore); ok {
+			_, err = rs.Revocation(a.EventID)
+			if err == nil {
+				continue
+			}
+			if err != storage.ErrNotFound {
+				return err
+			}
+		}
+		s.add(a.Event())
+	}
+	return nil
+}
+
+func (s *Scheduler) add(ev dlcoracle.Event) {
+	s.mtx.Lock()
+	if _, ok := s.jobs[ev.ID]; !ok {
+		next := ev.Maturity
+		if next.IsZero() {
+			// Events maturing at a block height need not estimate
+			// their maturity; start watching the chain right away
+			next = s.oracle.Clock().Now()
+		}
+		s.jobs[ev.ID] = &Job{Event: ev, NextAttempt: next}
+	}
+	s.mtx.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) nextAttempt() (time.Time, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var next time.Time
+	for _, j := range s.jobs {
+		if j.Aborted {
+			continue
+		}
+		if next.IsZero() || j.NextAttempt.Before(next) {
+			next = j.NextAttempt
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// attestDue attests all events whose next attempt is due at now
+func (s *Scheduler) attestDue(now time.Time) {
+	s.mtx.Lock()
+	var due []dlcoracle.Event
+	for _, j := range s.jobs {
+		if !j.Aborted && !j.NextAttempt.After(now) {
+			due = append(due, j.Event)
+		}
+	}
+	s.mtx.Unlock()
+
+	var height uint32
+	var heightErr error
+	heightKnown := false
+	for _, ev := range due {
+		if ev.MaturityHeight > 0 && s.oracle.Chain() != nil {
+			if !heightKnown {
+				height, heightErr = s.chainHeight()
+				heightKnown = true
+			}
+			if heightErr == nil && height < ev.MaturityHeight {
+				// Not a failure, the chain just isn't there yet
+				s.mtx.Lock()
+				s.jobs[ev.ID].NextAttempt = now.Add(s.chainPollInterval)
+				s.mtx.Unlock()
+				continue
+			}
+		}
+
+		err := s.attest(ev)
+		s.putEvidence(ev.ID, err)
+		if errors.Is(err, oracle.ErrStopped) {
+			// The daemon is shutting down, the job is attested after
+			// it restarts
+			return
+		}
+
+		s.mtx.Lock()
+		j := s.jobs[ev.ID]
+		if err == nil || errors.Is(err, oracle.ErrAlreadyAttested) || errors.Is(err, oracle.ErrRevoked) {
+			delete(s.jobs, ev.ID)
+		} else if errors.Is(err, oracle.ErrAttestationPending) {
+			// The attestation comes back through ImportAttestation
+			delete(s.jobs, ev.ID)
+			s.logger.Log(dlcoracle.LevelInfo, "attestation requested", dlcoracle.F("event_id", ev.ID))
+		} else if errors.Is(err, datasource.ErrDisagreement) && s.disagreementPolicy(ev.ID) == DisagreementAbort {
+			j.Attempts++
+			j.LastError = err
+			j.Aborted = true
+			s.logger.Log(dlcoracle.LevelError, "attesting event aborted",
+				dlcoracle.F("event_id", ev.ID),
+				dlcoracle.F("error", err.Error()))
+			s.alert(j, notify.KindDisagreement, fmt.Sprintf("not attesting until retried: %v", err))
+		} else {
+			j.Attempts++
+			j.LastError = err
+			j.NextAttempt = s.oracle.Clock().Now().Add(s.retryInterval)
+			s.logger.Log(dlcoracle.LevelWarn, "attesting event failed",
+				dlcoracle.F("event_id", ev.ID),
+				dlcoracle.F("attempts", j.Attempts),
+				dlcoracle.F("error", err.Error()),
+				dlcoracle.F("retry_at", j.NextAttempt.UTC().Format(time.RFC3339)))
+			if errors.Is(err, datasource.ErrDisagreement) {
+				s.alert(j, notify.KindDisagreement, err.Error())
+			}
+			if !ev.Maturity.IsZero() && now.Sub(ev.Maturity) >= s.overdueAfter {
+				s.alert(j, notify.KindOverdue, fmt.Sprintf("not attested %s after maturity, "+
+					"%d failed attempts: %v", now.Sub(ev.Maturity).Round(time.Second), j.Attempts, err))
+			}
+		}
+		s.mtx.Unlock()
+	}
+}
+
+// putEvidence stores the responses collected for an event if attesting it
+// succeeded, and discards them otherwise
+func (s *Scheduler) putEvidence(eventID string, attestErr error) {
+	s.mtx.Lock()
+	c := s.evidence
+	s.mtx.Unlock()
+	if c == nil {
+		return
+	}
+	responses := c.Take(eventID)
+	if attestErr != nil || len(responses) == 0 {
+		return
+	}
+	_, err := s.oracle.PutEvidence(eventID, responses)
+	if err != nil {
+		s.logger.Log(dlcoracle.LevelWarn, "storing evidence failed",
+			dlcoracle.F("event_id", eventID),
+			dlcoracle.F("error", err.Error()))
+	}
+}
+
+// alert raises an alert of kind for j in the background, unless it was
+// raised before. The caller holds s.mtx.
+func (s *Scheduler) alert(j *Job, kind notify.Kind, msg string) {
+	if s.notifier == nil || j.alerted[kind] {
+		return
+	}
+	if j.alerted == nil {
+		j.alerted = make(map[notify.Kind]bool)
+	}
+	j.alerted[kind] = true
+	a := notify.Alert{
+		Kind:    kind,
+		EventID: j.Event.ID,
+		Message: msg,
+		Time:    s.oracle.Clock().Now().UTC(),
+	}
+	n := s.notifier
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		defer cancel()
+		err := n.Notify(ctx, a)
+		if err != nil {
+			s.logger.Log(dlcoracle.LevelError, "alert not delivered",
+				dlcoracle.F("kind", string(a.Kind)),
+				dlcoracle.F("event_id", a.EventID),
+				dlcoracle.F("error", err.Error()))
+		}
+	}()
+}
+
+func (s *Scheduler) chainHeight() (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), chainTimeout)
+	defer cancel()
+	return s.oracle.Chain().BlockHeight(ctx)
+}
+
+func (s *Scheduler) attest(ev dlcoracle.Event) (err error) {
+	s.mtx.Lock()
+	hook := s.hook
+	policy := s.disagreementPolicy(ev.ID)
+	attempt := 1
+	if j, ok := s.jobs[ev.ID]; ok {
+		attempt += j.Attempts
+	}
+	s.mtx.Unlock()
+
+	ctx, span := tracing.Start(context.Background(), "scheduler.attest",
+		tracing.EventID(ev.ID), attribute.Int("dlc.attempt", attempt))
+	defer func() { tracing.End(span, err) }()
+
+	var outcome dlcoracle.Outcome
+	if cf, ok := s.fetcher.(ContextFetcher); ok {
+		outcome, err = cf.FetchOutcomeContext(ctx, ev)
+	} else {
+		outcome, err = s.fetcher.FetchOutcome(ev)
+	}
+	var disagreement *datasource.DisagreementError
+	if errors.As(err, &disagreement) && policy == DisagreementMedian {
+		outcome, err = dlcoracle.Outcome{Value: disagreement.Median}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if hook != nil {
+		_, hookSpan := tracing.Start(ctx, "scheduler.outcome_hook")
+		outcome, err = hook(ev, outcome)
+		tracing.End(hookSpan, err)
+		if err != nil {
+			return err
+		}
+	}
+	// sources report values at their own precision
+	outcome = ev.Descriptor.Round(outcome)
+	_, err = s.oracle.AttestOutcomeContext(ctx, ev.ID, outcome)
+	if err == nil && disagreement != nil {
+		s.mtx.Lock()
+		if j, ok := s.jobs[ev.ID]; ok {
+			s.alert(j, notify.KindDisagreement, fmt.Sprintf("attested the median %d anyway: %v",
+				disagreement.Median, disagreement))
+		}
+		s.mtx.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,388 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/notify"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// flakyFetcher fails the first failures calls, then returns value
+type flakyFetcher struct {
+	mtx      sync.Mutex
+	failures int
+	calls    int
+	value    int64
+}
+
+func (f *flakyFetcher) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return dlcoracle.Outcome{}, errors.New("source unavailable")
+	}
+	return dlcoracle.Outcome{Value: f.value}, nil
+}
+
+func newTestOracle() *oracle.Oracle {
+	var priv [32]byte
+	priv[31] = 1
+	return oracle.New(priv, storage.NewMemoryStore())
+}
+
+func waitAttested(t *testing.T, o *oracle.Oracle, eventID string) dlcoracle.Attestation {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		a, err := o.Store().Attestation(eventID)
+		if err == nil {
+			return a
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("event %s was not attested", eventID)
+	return dlcoracle.Attestation{}
+}
+
+func TestAttestAtMaturity(t *testing.T) {
+	o := newTestOracle()
+	f := &flakyFetcher{failures: 1, value: 42}
+	s := New(o, f)
+	s.SetRetryInterval(10 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	a, err := s.Schedule(dlcoracle.Event{
+		ID:       "event",
+		Maturity: time.Now().Add(50 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Pending()) != 1 {
+		t.Fatal("event not pending")
+	}
+
+	att := waitAttested(t, o, "event")
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint,
+		dlcoracle.GenerateNumericMessage(42), att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.calls != 2 {
+		t.Fatalf("expected one retry, got %d calls", f.calls)
+	}
+}
+
+func TestOutcomeHook(t *testing.T) {
+	o := newTestOracle()
+	s := New(o, &flakyFetcher{value: 42})
+	s.SetRetryInterval(10 * time.Millisecond)
+	calls := 0
+	s.SetOutcomeHook(func(ev dlcoracle.Event, out dlcoracle.Outcome) (dlcoracle.Outcome, error) {
+		calls++
+		if calls == 1 {
+			return out, datasource.ErrNotAvailable
+		}
+		out.Value *= 10
+		return out, nil
+	})
+	a, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	att := waitAttested(t, o, "event")
+	err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint,
+		dlcoracle.GenerateNumericMessage(420), att.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResumePendingFromStore(t *testing.T) {
+	o := newTestOracle()
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(o, &flakyFetcher{value: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	waitAttested(t, o, "event")
+}
+
+func TestRevokedEventsAreDropped(t *testing.T) {
+	o := newTestOracle()
+	for _, id := range []string{"revoked", "live"} {
+		_, err := o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := o.Revoke("revoked", "cancelled", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(o, &flakyFetcher{value: 1})
+	err = s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := s.Pending()
+	if len(pending) != 1 || pending[0].Event.ID != "live" {
+		t.Fatalf("unexpected pending jobs %+v", pending)
+	}
+
+	// Events revoked while queued are dropped at maturity
+	_, err = o.Revoke("live", "cancelled", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.attestDue(time.Now().Add(2 * time.Hour))
+	if len(s.Pending()) != 0 {
+		t.Fatal("revoked event still pending")
+	}
+}
+
+// fixedCollector hands over the same responses for every event
+type fixedCollector struct {
+	responses []dlcoracle.UpstreamResponse
+	taken     []string
+}
+
+func (c *fixedCollector) Take(eventID string) []dlcoracle.UpstreamResponse {
+	c.taken = append(c.taken, eventID)
+	return c.responses
+}
+
+// failingFetcher fails for one event and returns 1 for the others
+type failingFetcher struct {
+	failing string
+}
+
+func (f failingFetcher) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	if ev.ID == f.failing {
+		return dlcoracle.Outcome{}, errors.New("source unavailable")
+	}
+	return dlcoracle.Outcome{Value: 1}, nil
+}
+
+func TestEvidence(t *testing.T) {
+	o := newTestOracle()
+	for _, id := range []string{"attested", "failing"} {
+		_, err := o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Unix(1000, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := New(o, failingFetcher{failing: "failing"})
+	c := &fixedCollector{responses: []dlcoracle.UpstreamResponse{{Source: "api",
+		URL: "https://api.example.com/attested", Status: 200, Body: []byte("1")}}}
+	s.SetEvidenceCollector(c)
+	err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.attestDue(time.Now())
+	if len(c.taken) != 2 {
+		t.Fatalf("responses taken for %v", c.taken)
+	}
+
+	es := o.Store().(storage.EvidenceStore)
+	e, err := es.Evidence("attested")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Responses) != 1 || e.Verify() != nil {
+		t.Fatalf("unexpected evidence %+v", e)
+	}
+	_, err = es.Evidence("failing")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected no evidence for a failed attempt, got %v", err)
+	}
+}
+
+// recordingNotifier collects the alerts it is sent
+type recordingNotifier struct {
+	alerts chan notify.Alert
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, a notify.Alert) error {
+	r.alerts <- a
+	return nil
+}
+
+type disagreeingFetcher struct{}
+
+func (disagreeingFetcher) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, fmt.Errorf("%w: only 1 of 3 values within 0.01 of the median",
+		datasource.ErrDisagreement)
+}
+
+func TestAlerts(t *testing.T) {
+	o := newTestOracle()
+	maturity := time.Now().Add(-time.Hour)
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: maturity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(o, disagreeingFetcher{})
+	r := &recordingNotifier{alerts: make(chan notify.Alert, 8)}
+	s.SetNotifier(r)
+	s.SetOverdueAfter(2 * time.Hour)
+	err = s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.attestDue(time.Now())
+	a := <-r.alerts
+	if a.Kind != notify.KindDisagreement || a.EventID != "event" {
+		t.Fatalf("unexpected alert %+v", a)
+	}
+
+	// Each alert is raised once, the overdue one only after overdueAfter
+	s.attestDue(time.Now())
+	s.attestDue(maturity.Add(3 * time.Hour))
+	s.attestDue(maturity.Add(4 * time.Hour))
+	a = <-r.alerts
+	if a.Kind != notify.KindOverdue || a.EventID != "event" {
+		t.Fatalf("unexpected alert %+v", a)
+	}
+	select {
+	case a := <-r.alerts:
+		t.Fatalf("alert %+v raised again", a)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// medianFetcher returns a disagreement over values
+type medianFetcher struct {
+	values []int64
+}
+
+func (f medianFetcher) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, &datasource.DisagreementError{
+		Values:       f.values,
+		Median:       datasource.MedianOf(f.values),
+		Kept:         1,
+		Quorum:       2,
+		MaxDeviation: 0.01,
+	}
+}
+
+func TestDisagreementPolicies(t *testing.T) {
+	o := newTestOracle()
+	announcements := make(map[string]dlcoracle.Announcement)
+	for _, id := range []string{"retry", "abort", "median", "median-other"} {
+		a, err := o.CreateEvent(dlcoracle.Event{ID: id, Maturity: time.Now().Add(-time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		announcements[id] = a
+	}
+	s := New(o, medianFetcher{values: []int64{100, 120, 200}})
+	r := &recordingNotifier{alerts: make(chan notify.Alert, 8)}
+	s.SetNotifier(r)
+	s.SetDisagreementPolicy("", DisagreementAbort)
+	s.SetDisagreementPolicy("retry", DisagreementRetry)
+	s.SetDisagreementPolicy("median", DisagreementMedian)
+	err := s.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.attestDue(time.Now())
+	kinds := make(map[string]notify.Kind)
+	for range announcements {
+		a := <-r.alerts
+		kinds[a.EventID] = a.Kind
+	}
+	for id := range announcements {
+		if kinds[id] != notify.KindDisagreement {
+			t.Fatalf("%s: alerts %v", id, kinds)
+		}
+	}
+
+	// The median of all values is attested under the median policy
+	for _, id := range []string{"median", "median-other"} {
+		att, err := o.Store().Attestation(id)
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		a := announcements[id]
+		err = dlcoracle.VerifySignature(a.OraclePubKey, a.RPoint,
+			dlcoracle.GenerateNumericMessage(120), att.Signature)
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+	}
+
+	// The aborted event isn't attempted again, the other one is
+	pending := s.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("unexpected pending jobs %+v", pending)
+	}
+	for _, j := range pending {
+		if j.Aborted != (j.Event.ID == "abort") {
+			t.Fatalf("%s: aborted %v", j.Event.ID, j.Aborted)
+		}
+	}
+	next, ok := s.nextAttempt()
+	if !ok || !next.Equal(s.jobs["retry"].NextAttempt) {
+		t.Fatalf("next attempt at %v", next)
+	}
+
+	// Retrying an aborted event alerts again if its sources still disagree
+	err = s.Retry("abort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.attestDue(time.Now())
+	a := <-r.alerts
+	if a.Kind != notify.KindDisagreement || a.EventID != "abort" {
+		t.Fatalf("unexpected alert %+v", a)
+	}
+	if !s.Pending()[0].Aborted && !s.Pending()[1].Aborted {
+		t.Fatal("event not aborted again")
+	}
+	err = s.Retry("median")
+	if err != ErrNotPending {
+		t.Fatalf("expected ErrNotPending, got %v", err)
+	}
+}
+
+func TestParseDisagreementPolicy(t *testing.T) {
+	for _, p := range []DisagreementPolicy{DisagreementRetry, DisagreementAbort, DisagreementMedian} {
+		text, err := p.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parsed DisagreementPolicy
+		err = parsed.UnmarshalText(text)
+		if err != nil || parsed != p {
+			t.Fatalf("%s parsed as %s, %v", text, parsed, err)
+		}
+	}
+	var p DisagreementPolicy
+	err := p.UnmarshalText([]byte("ignore"))
+	if err == nil {
+		t.Fatal("parsed an unknown policy")
+	}
+}
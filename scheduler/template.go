@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+)
+
+// Template describes a recurring event, such as the BTC/USD close daily
+// at 00:00 UTC in 6 base 10 digits. The scheduler keeps the next Ahead
+// occurrences announced; each is assigned the next nonce index like any
+// other event.
+type Template struct {
+	// Prefix starts the IDs of the events, followed by their maturity
+	// in RFC 3339, such as btcusd-2030-01-01T00:00:00Z. Data sources
+	// are routed by it.
+	Prefix string
+
+	// Start is the maturity of the first occurrence; another one
+	// matures every Period after it
+	Start  time.Time
+	Period time.Duration
+
+	// Recurrence, such as a Cron, replaces Period if set. Occurrences
+	// then mature when it says from Start on, or right away if Start
+	// is zero.
+	Recurrence Recurrence
+
+	Descriptor dlcoracle.EventDescriptor
+
+	// Metadata is given to every occurrence
+	Metadata dlcoracle.EventMetadata
+
+	// Ahead is how many occurrences are kept announced ahead of time
+	Ahead int
+}
+
+// Validate checks that the template describes valid events
+func (t Template) Validate() error {
+	if t.Prefix == "" {
+		return fmt.Errorf("template has no prefix")
+	}
+	switch {
+	case t.Recurrence != nil:
+		if t.Period != 0 {
+			return fmt.Errorf("template %s has both a period and a recurrence", t.Prefix)
+		}
+	case t.Period <= 0:
+		return fmt.Errorf("template %s has period %s", t.Prefix, t.Period)
+	case t.Start.IsZero():
+		return fmt.Errorf("template %s has no start", t.Prefix)
+	}
+	if t.Ahead < 1 {
+		return fmt.Errorf("template %s announces %d events ahead", t.Prefix, t.Ahead)
+	}
+	err := t.Descriptor.Validate()
+	if err != nil {
+		return fmt.Errorf("template %s: %w", t.Prefix, err)
+	}
+	err = t.Metadata.Validate()
+	if err != nil {
+		return fmt.Errorf("template %s: %w", t.Prefix, err)
+	}
+	return nil
+}
+
+// EventID returns the ID of the occurrence maturing at maturity
+func (t Template) EventID(maturity time.Time) string {
+	return t.Prefix + maturity.UTC().Format(time.RFC3339)
+}
+
+// Events returns the next Ahead occurrences maturing after now
+func (t Template) Events(now time.Time) []dlcoracle.Event {
+	if t.Recurrence != nil {
+		return t.recurrenceEvents(now)
+	}
+	next := t.Start
+	if now.After(next) || now.Equal(next) {
+		// Skip the periods that have passed at once
+		next = next.Add((now.Sub(next)/t.Period + 1) * t.Period)
+	}
+	list := make([]dlcoracle.Event, t.Ahead)
+	for i := range list {
+		maturity := next.Add(time.Duration(i) * t.Period)
+		list[i] = dlcoracle.Event{
+			ID:         t.EventID(maturity),
+			Maturity:   maturity,
+			Descriptor: t.Descriptor,
+			Metadata:   t.Metadata,
+		}
+	}
+	return list
+}
+
+func (t Template) recurrenceEvents(now time.Time) []dlcoracle.Event {
+	after := now
+	if t.Start.After(now) {
+		after = t.Start.Add(-time.Nanosecond)
+	}
+	var list []dlcoracle.Event
+	for len(list) < t.Ahead {
+		maturity := t.Recurrence.Next(after)
+		if maturity.IsZero() {
+			break
+		}
+		list = append(list, dlcoracle.Event{
+			ID:         t.EventID(maturity),
+			Maturity:   maturity,
+			Descriptor: t.Descriptor,
+			Metadata:   t.Metadata,
+		})
+		after = maturity
+	}
+	return list
+}
+
+// AddTemplate makes the scheduler keep the next occurrences of t
+// announced while it runs
+func (s *Scheduler) AddTemplate(t Template) error {
+	err := t.Validate()
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	s.templates = append(s.templates, t)
+	s.mtx.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Templates returns the templates added with AddTemplate
+func (s *Scheduler) Templates() []Template {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]Template(nil), s.templates...)
+}
+
+// expand announces the occurrences of all templates due by now that
+// haven't been announced yet
+func (s *Scheduler) expand(now time.Time) {
+	s.mtx.Lock()
+	var due []dlcoracle.Event
+	for _, t := range s.templates {
+		for _, ev := range t.Events(now) {
+			if _, ok := s.jobs[ev.ID]; !ok {
+				due = append(due, ev)
+			}
+		}
+	}
+	s.mtx.Unlock()
+
+	for _, ev := range due {
+		_, err := s.Schedule(ev)
+		// Occurrences attested early or revoked stay announced
+		if err != nil && !errors.Is(err, oracle.ErrEventExists) {
+			s.logger.Log(dlcoracle.LevelWarn, "announcing event failed",
+				dlcoracle.F("event_id", ev.ID),
+				dlcoracle.F("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestTemplateEvents(t *testing.T) {
+	tmpl := Template{
+		Prefix:     "btcusd-",
+		Start:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Period:     24 * time.Hour,
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 6},
+		Ahead:      2,
+	}
+	err := tmpl.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := tmpl.Events(time.Date(2029, 6, 1, 0, 0, 0, 0, time.UTC))
+	if len(list) != 2 || list[0].ID != "btcusd-2030-01-01T00:00:00Z" || list[1].ID != "btcusd-2030-01-02T00:00:00Z" {
+		t.Fatalf("unexpected events before start %+v", list)
+	}
+	list = tmpl.Events(time.Date(2030, 3, 5, 0, 0, 0, 0, time.UTC))
+	if list[0].ID != "btcusd-2030-03-06T00:00:00Z" || list[0].Descriptor.Digits != 6 {
+		t.Fatalf("unexpected events at a maturity %+v", list)
+	}
+	list = tmpl.Events(time.Date(2030, 3, 5, 12, 0, 0, 0, time.UTC))
+	if list[0].ID != "btcusd-2030-03-06T00:00:00Z" {
+		t.Fatalf("unexpected events between maturities %+v", list)
+	}
+
+	for _, bad := range []func(*Template){
+		func(t *Template) { t.Prefix = "" },
+		func(t *Template) { t.Period = 0 },
+		func(t *Template) { t.Start = time.Time{} },
+		func(t *Template) { t.Ahead = 0 },
+		func(t *Template) { t.Descriptor.Base = 1 },
+	} {
+		other := tmpl
+		bad(&other)
+		if other.Validate() == nil {
+			t.Errorf("invalid template %+v accepted", other)
+		}
+	}
+}
+
+func TestTemplatesAreExpanded(t *testing.T) {
+	o := newTestOracle()
+	s := New(o, &flakyFetcher{value: 7})
+	tmpl := Template{
+		Prefix: "tick-",
+		Start:  time.Now().Truncate(time.Second).Add(time.Second),
+		Period: time.Second,
+		Ahead:  2,
+	}
+	err := s.AddTemplate(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	// The first occurrence is attested, and the one after the announced
+	// ones gets announced
+	first := tmpl.EventID(tmpl.Start)
+	waitAttested(t, o, first)
+	third := tmpl.EventID(tmpl.Start.Add(2 * tmpl.Period))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := o.Store().Announcement(third)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was not announced", third)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(s.Templates()) != 1 {
+		t.Fatal("template not listed")
+	}
+}
@@ -0,0 +1,355 @@
+// Package script lets operators compute outcomes with Starlark, a small
+// Python dialect, instead of Go: averaging several APIs, rounding, clamping
+// to the range of the event and the like. A script defines
+//
+//	def outcome(event, value):
+//	    ...
+//
+// which the scheduler calls with every matured event and the value its
+// data source fetched, before signing. It returns the value to attest, an
+// int for numeric and digits events, a string for enum events and bytes
+// for bytes events, or None if the outcome isn't known yet.
+//
+// The event is a struct with the fields id, maturity (Unix seconds),
+// maturity_height, type, outcomes, base, digits, max (the largest value of
+// a digits event, None otherwise), unit, unit_exponent, precision,
+// rounding, category and tags. Besides Starlark's builtins and the math
+// module, scripts can call
+//
+//	fetch(source)            # the value of the event from another data source
+//	mean(values)             # the mean of numbers, as a float
+//	median(values)           # the median of ints
+//	round(x, step=1)         # x rounded half away from zero to a multiple of step
+//	clamp(x, low, high)      # x bounded by low and high
+//
+// Scripts can't read files or the network except through fetch, and are
+// stopped after a number of steps so a bug can't hang the scheduler.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	starmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// MaxSteps bounds the computation of a script's outcome
+const MaxSteps = 10000000
+
+// Sources looks up the data sources scripts fetch from. datasource.Registry
+// implements it.
+type Sources interface {
+	Get(id string) (datasource.DataSource, bool)
+}
+
+// Script is a compiled outcome script. It is safe for concurrent use.
+type Script struct {
+	name    string
+	fn      starlark.Callable
+	sources Sources
+}
+
+// Load compiles the script in the file at path
+func Load(path string, sources Sources) (*Script, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(path, src, sources)
+}
+
+// Compile compiles the script src, named name in errors, which fetches
+// from sources
+func Compile(name string, src []byte, sources Sources) (*Script, error) {
+	s := &Script{name: name, sources: sources}
+	thread := &starlark.Thread{Name: name}
+	thread.SetMaxExecutionSteps(MaxSteps)
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, name, src, s.builtins())
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+	fn, ok := globals["outcome"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%s: no outcome function", name)
+	}
+	s.fn = fn
+	return s, nil
+}
+
+// Outcome runs the script on the outcome of ev its data source fetched
+// and returns the outcome to attest. It implements scheduler.OutcomeHook.
+func (s *Script) Outcome(ev dlcoracle.Event, o dlcoracle.Outcome) (dlcoracle.Outcome, error) {
+	value, err := toValue(ev.Descriptor.Type, o)
+	if err != nil {
+		return o, err
+	}
+	thread := &starlark.Thread{Name: s.name}
+	thread.SetMaxExecutionSteps(MaxSteps)
+	thread.SetLocal("event", ev)
+	result, err := starlark.Call(thread, s.fn, starlark.Tuple{eventValue(ev), value}, nil)
+	if err != nil {
+		// A source that doesn't know the outcome yet makes the
+		// scheduler retry rather than fail
+		if fetchErr, ok := thread.Local("fetchErr").(error); ok && errors.Is(fetchErr, datasource.ErrNotAvailable) {
+			return o, fetchErr
+		}
+		return o, fmt.Errorf("%s: %v", s.name, err)
+	}
+	if result == starlark.None {
+		return o, datasource.ErrNotAvailable
+	}
+	out, err := fromValue(ev.Descriptor.Type, result)
+	if err != nil {
+		return o, fmt.Errorf("%s: outcome: %v", s.name, err)
+	}
+	return out, nil
+}
+
+// eventValue returns ev as a Starlark struct
+func eventValue(ev dlcoracle.Event) starlark.Value {
+	d := ev.Descriptor
+	var largest starlark.Value = starlark.None
+	if d.Type == dlcoracle.EventTypeDigits {
+		m := new(big.Int).Exp(big.NewInt(int64(d.Base)), big.NewInt(int64(d.Digits)), nil)
+		largest = starlark.MakeBigInt(m.Sub(m, big.NewInt(1)))
+	}
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"id":              starlark.String(ev.ID),
+		"maturity":        starlark.MakeInt64(ev.Maturity.Unix()),
+		"maturity_height": starlark.MakeUint(uint(ev.MaturityHeight)),
+		"type":            starlark.String(d.Type.String()),
+		"outcomes":        stringTuple(d.Outcomes),
+		"base":            starlark.MakeUint(uint(d.Base)),
+		"digits":          starlark.MakeUint(uint(d.Digits)),
+		"max":             largest,
+		"unit":            starlark.String(d.Unit),
+		"unit_exponent":   starlark.MakeInt(int(d.UnitExponent)),
+		"precision":       starlark.MakeUint64(d.Precision),
+		"rounding":        starlark.String(d.Rounding.String()),
+		"category":        starlark.String(ev.Metadata.Category),
+		"tags":            stringTuple(ev.Metadata.Tags),
+	})
+}
+
+func stringTuple(list []string) starlark.Tuple {
+	t := make(starlark.Tuple, len(list))
+	for i, s := range list {
+		t[i] = starlark.String(s)
+	}
+	return t
+}
+
+// toValue returns the Starlark value of an outcome of an event of type t
+func toValue(t dlcoracle.EventType, o dlcoracle.Outcome) (starlark.Value, error) {
+	switch t {
+	case dlcoracle.EventTypeNumeric, dlcoracle.EventTypeDigits:
+		return starlark.MakeInt64(o.Value), nil
+	case dlcoracle.EventTypeEnum:
+		return starlark.String(o.Label), nil
+	case dlcoracle.EventTypeBytes:
+		return starlark.Bytes(o.Bytes), nil
+	}
+	return nil, fmt.Errorf("unsupported event type %s", t)
+}
+
+// fromValue returns the outcome of an event of type t a script returned
+func fromValue(t dlcoracle.EventType, v starlark.Value) (dlcoracle.Outcome, error) {
+	var o dlcoracle.Outcome
+	switch t {
+	case dlcoracle.EventTypeNumeric, dlcoracle.EventTypeDigits:
+		i, ok := v.(starlark.Int)
+		if !ok {
+			return o, fmt.Errorf("got %s, want int", v.Type())
+		}
+		o.Value, ok = i.Int64()
+		if !ok {
+			return o, fmt.Errorf("%v out of range", i)
+		}
+	case dlcoracle.EventTypeEnum:
+		s, ok := v.(starlark.String)
+		if !ok {
+			return o, fmt.Errorf("got %s, want string", v.Type())
+		}
+		o.Label = string(s)
+	case dlcoracle.EventTypeBytes:
+		b, ok := v.(starlark.Bytes)
+		if !ok {
+			return o, fmt.Errorf("got %s, want bytes", v.Type())
+		}
+		o.Bytes = []byte(b)
+	default:
+		return o, fmt.Errorf("unsupported event type %s", t)
+	}
+	return o, nil
+}
+
+// builtins returns the functions scripts can call besides Starlark's own
+func (s *Script) builtins() starlark.StringDict {
+	return starlark.StringDict{
+		"fetch":  starlark.NewBuiltin("fetch", s.fetch),
+		"mean":   starlark.NewBuiltin("mean", mean),
+		"median": starlark.NewBuiltin("median", median),
+		"round":  starlark.NewBuiltin("round", round),
+		"clamp":  starlark.NewBuiltin("clamp", clamp),
+		"math":   starmath.Module,
+	}
+}
+
+// fetch returns the value of the event being resolved from another source
+func (s *Script) fetch(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var id string
+	err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &id)
+	if err != nil {
+		return nil, err
+	}
+	ev, ok := thread.Local("event").(dlcoracle.Event)
+	if !ok {
+		return nil, fmt.Errorf("%s: only outcome can fetch", b.Name())
+	}
+	if s.sources == nil {
+		return nil, fmt.Errorf("%s: no data sources", b.Name())
+	}
+	ds, ok := s.sources.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown data source %q", b.Name(), id)
+	}
+	o, err := ds.FetchOutcome(ev)
+	if err != nil {
+		thread.SetLocal("fetchErr", fmt.Errorf("%s: %w", id, err))
+		return nil, fmt.Errorf("%s: %s: %v", b.Name(), id, err)
+	}
+	return toValue(ev.Descriptor.Type, o)
+}
+
+// numbers unpacks the single iterable argument of a builtin as floats,
+// and as ints if they all are
+func numbers(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) ([]float64, []int64, error) {
+	var it starlark.Iterable
+	err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &it)
+	if err != nil {
+		return nil, nil, err
+	}
+	var floats []float64
+	ints := []int64{}
+	iter := it.Iterate()
+	defer iter.Done()
+	var v starlark.Value
+	for iter.Next(&v) {
+		f, ok := starlark.AsFloat(v)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: got %s, want number", b.Name(), v.Type())
+		}
+		floats = append(floats, f)
+		if i, ok := v.(starlark.Int); ok && ints != nil {
+			n, ok := i.Int64()
+			if ok {
+				ints = append(ints, n)
+				continue
+			}
+		}
+		ints = nil
+	}
+	if len(floats) == 0 {
+		return nil, nil, fmt.Errorf("%s: no values", b.Name())
+	}
+	return floats, ints, nil
+}
+
+func mean(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	floats, _, err := numbers(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	var sum float64
+	for _, f := range floats {
+		sum += f
+	}
+	return starlark.Float(sum / float64(len(floats))), nil
+}
+
+func median(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	floats, ints, err := numbers(b, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if ints != nil {
+		return starlark.MakeInt64(datasource.MedianOf(ints)), nil
+	}
+	sort.Float64s(floats)
+	n := len(floats)
+	if n%2 == 1 {
+		return starlark.Float(floats[n/2]), nil
+	}
+	return starlark.Float((floats[n/2-1] + floats[n/2]) / 2), nil
+}
+
+func round(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	step := 1
+	err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &x, "step?", &step)
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		return nil, fmt.Errorf("%s: step %d isn't positive", b.Name(), step)
+	}
+	f, ok := starlark.AsFloat(x)
+	if !ok {
+		return nil, fmt.Errorf("%s: got %s, want number", b.Name(), x.Type())
+	}
+	if i, ok := x.(starlark.Int); ok {
+		n, ok := i.Int64()
+		if ok {
+			return starlark.MakeInt64(roundInt(n, int64(step))), nil
+		}
+	}
+	r := math.Round(f/float64(step)) * float64(step)
+	if math.IsNaN(r) || math.Abs(r) >= math.MaxInt64 {
+		return nil, fmt.Errorf("%s: %v out of range", b.Name(), x)
+	}
+	return starlark.MakeInt64(int64(r)), nil
+}
+
+// roundInt rounds n half away from zero to a multiple of step
+func roundInt(n, step int64) int64 {
+	q, r := n/step, n%step
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= step {
+		if n < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q * step
+}
+
+func clamp(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x, low, high starlark.Value
+	err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 3, &x, &low, &high)
+	if err != nil {
+		return nil, err
+	}
+	less := func(a, b starlark.Value) (bool, error) {
+		return starlark.Compare(syntax.LT, a, b)
+	}
+	if lt, err := less(x, low); err != nil || lt {
+		return low, err
+	}
+	if lt, err := less(high, x); err != nil || lt {
+		return high, err
+	}
+	return x, nil
+}
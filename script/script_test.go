@@ -0,0 +1,101 @@
+package script
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+)
+
+// fixedSource returns value, or err if it is set
+type fixedSource struct {
+	id    string
+	value int64
+	err   error
+}
+
+func (s fixedSource) ID() string       { return s.id }
+func (s fixedSource) Describe() string { return s.id }
+
+func (s fixedSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{Value: s.value}, s.err
+}
+
+func newSources(t *testing.T, sources ...datasource.DataSource) *datasource.Registry {
+	t.Helper()
+	reg := datasource.NewRegistry()
+	for _, ds := range sources {
+		err := reg.Register(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reg
+}
+
+func TestAverageRoundClamp(t *testing.T) {
+	reg := newSources(t, fixedSource{id: "a", value: 1004}, fixedSource{id: "b", value: 1016},
+		fixedSource{id: "c", value: 1032})
+	s, err := Compile("average.star", []byte(`
+def outcome(event, value):
+    if not event.id.startswith("btc-"):
+        return value
+    avg = mean([value] + [fetch(id) for id in ["a", "b", "c"]])
+    return clamp(round(avg, 10), 0, event.max)
+`), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := dlcoracle.Event{ID: "btc-1", Maturity: time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 4}}
+	for _, c := range []struct{ value, want int64 }{{1000, 1010}, {99999, 9999}, {-10000, 0}} {
+		o, err := s.Outcome(ev, dlcoracle.Outcome{Value: c.value})
+		if err != nil || o.Value != c.want {
+			t.Errorf("%d: got %d %v, expected %d", c.value, o.Value, err, c.want)
+		}
+	}
+	ev.ID = "eth-1"
+	o, err := s.Outcome(ev, dlcoracle.Outcome{Value: 7})
+	if err != nil || o.Value != 7 {
+		t.Fatalf("unrelated event: %d %v", o.Value, err)
+	}
+}
+
+func TestOutcomeErrors(t *testing.T) {
+	reg := newSources(t, fixedSource{id: "late", err: datasource.ErrNotAvailable},
+		fixedSource{id: "down", err: errors.New("unreachable")})
+	enum := dlcoracle.Event{ID: "match", Descriptor: dlcoracle.EventDescriptor{
+		Type: dlcoracle.EventTypeEnum, Outcomes: []string{"home", "away"}}}
+	for _, c := range []struct {
+		src, want    string
+		notAvailable bool
+	}{
+		{"def outcome(event, value):\n    return None\n", "", true},
+		{"def outcome(event, value):\n    return fetch('late')\n", "", true},
+		{"def outcome(event, value):\n    return fetch('down')\n", "unreachable", false},
+		{"def outcome(event, value):\n    return fetch('other')\n", "unknown data source", false},
+		{"def outcome(event, value):\n    return 1\n", "want string", false},
+		{"def outcome(event, value):\n    fail('no result')\n", "no result", false},
+		{"def outcome(event, value):\n    for i in range(100000000):\n        pass\n", "too many steps", false},
+	} {
+		s, err := Compile("test.star", []byte(c.src), reg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.Outcome(enum, dlcoracle.Outcome{Label: "home"})
+		if c.notAvailable != errors.Is(err, datasource.ErrNotAvailable) || err == nil ||
+			!strings.Contains(err.Error(), c.want) {
+			t.Errorf("%q: got %v", c.src, err)
+		}
+	}
+
+	for _, src := range []string{"x = 1\n", "def outcome(event, value)\n", "fetch('late')\n"} {
+		_, err := Compile("test.star", []byte(src), reg)
+		if err == nil {
+			t.Errorf("compiled %q", src)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/bulk"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// bulkPageSize is the number of announcements read from the store at a
+// time while streaming them
+const bulkPageSize = 1000
+
+// handleBulkAnnouncements streams the announcements selected by the same
+// query parameters as listAnnouncements in the encoding of package bulk,
+// a page of the store at a time. Errors after the stream started cut it
+// off before its end, which readers report.
+func (s *Server) handleBulkAnnouncements(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(s.store, r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()})
+		return
+	}
+	limit := q.Limit
+	next := func(sent int) ([]dlcoracle.Announcement, error) {
+		q.Limit = bulkPageSize
+		if limit > 0 && limit-sent < bulkPageSize {
+			q.Limit = limit - sent
+		}
+		return storage.Search(s.store, q)
+	}
+	page, err := next(0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", bulk.ContentType)
+	w.Header().Set("Vary", "Accept-Encoding")
+	var out io.Writer = w
+	if enc := acceptedEncoding(r); enc != "" {
+		zw, err := compressor(w, enc)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer zw.Close()
+		w.Header().Set("Content-Encoding", enc)
+		out = zw
+	}
+	bw, err := bulk.NewWriter(out)
+	if err != nil {
+		return
+	}
+	sent := 0
+	for {
+		for _, a := range page {
+			err = bw.Write(a)
+			if err != nil {
+				return
+			}
+		}
+		sent += len(page)
+		if len(page) < q.Limit || sent == limit {
+			break
+		}
+		q.After = storage.CursorOf(page[len(page)-1])
+		err = bw.Flush()
+		if err != nil {
+			return
+		}
+		page, err = next(sent)
+		if err != nil {
+			return
+		}
+	}
+	bw.Close()
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/bulk"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func TestBulkAnnouncements(t *testing.T) {
+	// More than a page of the store, half of them enum events
+	store := storage.NewMemoryStore()
+	n := bulkPageSize*2 + 10
+	for i := 0; i < n; i++ {
+		a := dlcoracle.Announcement{EventID: fmt.Sprintf("ev%05d", i), Maturity: time.Unix(int64(i), 0).UTC()}
+		if i%2 == 1 {
+			a.Descriptor = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+		}
+		store.PutAnnouncement(a)
+	}
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(testPrivKey()), store))
+	defer ts.Close()
+
+	for _, c := range []struct {
+		query, enc  string
+		first, last string
+		count       int
+	}{
+		{"", "identity", "ev00000", "ev02009", n},
+		{"", "gzip", "ev00000", "ev02009", n},
+		{"", "zstd", "ev00000", "ev02009", n},
+		{"?type=enum", "zstd", "ev00001", "ev02009", n / 2},
+		{"?after=ev00999&limit=1005", "gzip", "ev01000", "ev02004", 1005},
+		{"?limit=1000", "identity", "ev00000", "ev00999", 1000},
+		{"?maturesAfter=1970-01-01T00:00:05Z&limit=2", "identity", "ev00005", "ev00006", 2},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/bulk/announcements"+c.query, nil)
+		req.Header.Set("Accept-Encoding", c.enc)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != bulk.ContentType {
+			t.Fatalf("%s: status %d, Content-Type %q", c.query, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		enc := c.enc
+		if enc == "identity" {
+			enc = ""
+		}
+		if resp.Header.Get("Content-Encoding") != enc {
+			t.Fatalf("%s: Content-Encoding %q, want %q", c.query, resp.Header.Get("Content-Encoding"), enc)
+		}
+		r, err := bulk.NewReader(bytes.NewReader(decompress(t, enc, body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for {
+			a, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", c.query, err)
+			}
+			ids = append(ids, a.EventID)
+		}
+		if len(ids) != c.count || ids[0] != c.first || ids[len(ids)-1] != c.last {
+			t.Fatalf("%s %s: streamed %d events from %s to %s", c.query, c.enc, len(ids), ids[0], ids[len(ids)-1])
+		}
+	}
+
+	var e errorJSON
+	getJSON(t, ts.URL+"/api/bulk/announcements?after=missing", http.StatusBadRequest, &e)
+}
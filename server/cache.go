@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// writeRecord writes a successful response to a GET request, compressed
+// if the client accepts it, with an ETag derived from its body and, unless
+// zero, the time the record was last modified. Requests whose
+// If-None-Match or If-Modified-Since shows the client already has the
+// record are answered 304 Not Modified without a body, so polling
+// clients transfer next to nothing until something changes.
+func writeRecord(w http.ResponseWriter, r *http.Request, v interface{}, modified time.Time) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encoding response: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+	body, enc, err := compress(r, buf.Bytes())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("compressing response: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(buf.Bytes(), enc))
+	w.Header().Set("Vary", "Accept-Encoding")
+	if enc != "" {
+		w.Header().Set("Content-Encoding", enc)
+	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+// etag returns the strong entity tag of a response body sent with the
+// content coding enc. Each coding has its own tag, as the bytes sent
+// differ.
+func etag(body []byte, enc string) string {
+	h := sha256.Sum256(body)
+	if enc != "" {
+		return `"` + hex.EncodeToString(h[:16]) + "-" + enc + `"`
+	}
+	return `"` + hex.EncodeToString(h[:16]) + `"`
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// conditionalGet requests url with the header set to value and returns
+// the status, ETag and body of the response
+func conditionalGet(t *testing.T, url, header, value string) (int, string, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, resp.Header.Get("ETag"), body
+}
+
+func TestConditionalGet(t *testing.T) {
+	ts, a := newTestServer(t)
+	for _, path := range []string{"/api/pubkey", "/api/announcements", "/api/announcements/event"} {
+		status, tag, body := conditionalGet(t, ts.URL+path, "", "")
+		if status != http.StatusOK || tag == "" || len(body) == 0 {
+			t.Fatalf("%s: status %d, ETag %q", path, status, tag)
+		}
+		status, _, body = conditionalGet(t, ts.URL+path, "If-None-Match", tag)
+		if status != http.StatusNotModified || len(body) != 0 {
+			t.Fatalf("%s: status %d with %d bytes for a matching ETag", path, status, len(body))
+		}
+		status, _, _ = conditionalGet(t, ts.URL+path, "If-None-Match", `"other", `+tag)
+		if status != http.StatusNotModified {
+			t.Fatalf("%s: status %d for a list of ETags", path, status)
+		}
+		status, _, _ = conditionalGet(t, ts.URL+path, "If-None-Match", `"other"`)
+		if status != http.StatusOK {
+			t.Fatalf("%s: status %d for another ETag", path, status)
+		}
+	}
+
+	// Listings change their tag when an event is added
+	_, before, _ := conditionalGet(t, ts.URL+"/api/announcements", "", "")
+	_, other, _ := conditionalGet(t, ts.URL+"/api/announcements?limit=1&after="+a.EventID, "", "")
+	if before == other {
+		t.Fatal("different listings have the same ETag")
+	}
+
+	// Errors aren't cached
+	status, tag, _ := conditionalGet(t, ts.URL+"/api/attestations/event", "", "")
+	if status != http.StatusNotFound || tag != "" {
+		t.Fatalf("status %d, ETag %q for a missing attestation", status, tag)
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	store := storage.NewMemoryStore()
+	revokedAt := time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC)
+	err := store.PutRevocation(dlcoracle.Revocation{EventID: "event", Reason: "cancelled", RevokedAt: revokedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(testPrivKey()), store))
+	defer ts.Close()
+
+	url := ts.URL + "/api/revocations/event"
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Last-Modified") != revokedAt.Format(http.TimeFormat) {
+		t.Fatalf("Last-Modified %q", resp.Header.Get("Last-Modified"))
+	}
+	for since, want := range map[time.Time]int{
+		revokedAt:                 http.StatusNotModified,
+		revokedAt.Add(time.Hour):  http.StatusNotModified,
+		revokedAt.Add(-time.Hour): http.StatusOK,
+	} {
+		status, _, _ := conditionalGet(t, url, "If-Modified-Since", since.Format(http.TimeFormat))
+		if status != want {
+			t.Fatalf("modified since %s: status %d, want %d", since, status, want)
+		}
+	}
+}
+
+func TestLastModifiedStored(t *testing.T) {
+	store := storage.NewMemoryStore()
+	err := store.PutAnnouncement(dlcoracle.Announcement{EventID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutAttestation(dlcoracle.Attestation{EventID: "event", Message: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(testPrivKey()), store))
+	defer ts.Close()
+
+	announced, _ := store.AnnouncementTime("event")
+	attested, _ := store.AttestationTime("event")
+	for path, stored := range map[string]time.Time{
+		"/api/announcements/event": announced,
+		"/api/attestations/event":  attested,
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if stored.IsZero() || resp.Header.Get("Last-Modified") != stored.Format(http.TimeFormat) {
+			t.Fatalf("%s: Last-Modified %q, stored at %s", path, resp.Header.Get("Last-Modified"), stored)
+		}
+		for since, want := range map[time.Time]int{
+			stored:                 http.StatusNotModified,
+			stored.Add(-time.Hour): http.StatusOK,
+		} {
+			status, _, _ := conditionalGet(t, ts.URL+path, "If-Modified-Since", since.Format(http.TimeFormat))
+			if status != want {
+				t.Fatalf("%s modified since %s: status %d, want %d", path, since, status, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"strings"
+)
+
+// libraryPath is the import path of package dlcoracle, whose types
+// responses use as is
+const libraryPath = "github.com/mit-dci/dlc-oracle-go"
+
+// GenerateClient returns the Go source of a typed client for routes, as
+// methods on a Client type declared elsewhere in package pkg. The Client
+// must have a method
+//
+//	call(ctx context.Context, method, path string, res interface{}) error
+//
+// decoding the JSON response of a request into res. Routes with query
+// parameters take them as url.Values, which may be nil. Response types of
+// package dlcoracle are used as is; other types are declared with the
+// same fields.
+func GenerateClient(pkg string, routes []Route) ([]byte, error) {
+	var decls, methods bytes.Buffer
+	declared := make(map[string]bool)
+	usesURL, usesLibrary := false, false
+
+	var goType func(t reflect.Type) (string, error)
+	goType = func(t reflect.Type) (string, error) {
+		switch {
+		case t.Kind() == reflect.Slice:
+			elem, err := goType(t.Elem())
+			return "[]" + elem, err
+		case t.PkgPath() == libraryPath:
+			usesLibrary = true
+			return "dlcoracle." + t.Name(), nil
+		case t.Kind() == reflect.Struct:
+			name := typeName(t)
+			if !declared[name] {
+				declared[name] = true
+				err := declareStruct(&decls, name, t)
+				if err != nil {
+					return "", err
+				}
+			}
+			return name, nil
+		}
+		return "", fmt.Errorf("unsupported response type %s", t)
+	}
+
+	for _, r := range routes {
+		res, err := goType(reflect.TypeOf(r.Examples[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %v", r.Method, r.Path, err)
+		}
+		name := strings.ToUpper(r.OperationID[:1]) + r.OperationID[1:]
+		params := r.Params()
+		args := "ctx context.Context"
+		if len(params) != 0 {
+			args += ", " + strings.Join(params, ", ") + " string"
+			usesURL = true
+		}
+		path := pathExpr(r.Path)
+		if len(r.Query) != 0 {
+			args += ", query url.Values"
+			usesURL = true
+		}
+
+		fmt.Fprintf(&methods, "\n// %s %s. It calls %s %s.\n", name, r.Summary, r.Method, r.Path)
+		fmt.Fprintf(&methods, "func (c *Client) %s(%s) (%s, error) {\n", name, args, res)
+		fmt.Fprintf(&methods, "\tvar res %s\n", res)
+		if len(r.Query) != 0 {
+			fmt.Fprintf(&methods, "\tpath := %s\n", path)
+			fmt.Fprintf(&methods, "\tif len(query) != 0 {\n\t\tpath += \"?\" + query.Encode()\n\t}\n")
+			path = "path"
+		}
+		fmt.Fprintf(&methods, "\terr := c.call(ctx, %q, %s, &res)\n", r.Method, path)
+		fmt.Fprintf(&methods, "\treturn res, err\n}\n")
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by server.GenerateClient. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n\t\"context\"\n", pkg)
+	if usesURL {
+		fmt.Fprintf(&src, "\t\"net/url\"\n")
+	}
+	if usesLibrary {
+		fmt.Fprintf(&src, "\n\t%q\n", libraryPath)
+	}
+	fmt.Fprintf(&src, ")\n")
+	src.Write(decls.Bytes())
+	src.Write(methods.Bytes())
+	return format.Source(src.Bytes())
+}
+
+// declareStruct writes the declaration of a struct with the fields of t
+func declareStruct(w *bytes.Buffer, name string, t reflect.Type) error {
+	fmt.Fprintf(w, "\n// %s is a response of the REST API\ntype %s struct {\n", name, name)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type.PkgPath() != "" {
+			return fmt.Errorf("field %s of %s has unsupported type %s", f.Name, t, f.Type)
+		}
+		fmt.Fprintf(w, "\t%s %s `%s`\n", f.Name, f.Type, f.Tag)
+	}
+	fmt.Fprintf(w, "}\n")
+	return nil
+}
+
+// pathExpr returns a Go expression building path, with its parameters
+// replaced by the escaped values of the variables of the same names
+func pathExpr(path string) string {
+	var parts []string
+	rest := path
+	for _, loc := range pathParam.FindAllStringSubmatchIndex(path, -1) {
+		start := loc[0] - (len(path) - len(rest))
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:start]))
+		}
+		parts = append(parts, "url.PathEscape("+path[loc[2]:loc[3]]+")")
+		rest = path[loc[1]:]
+	}
+	if rest != "" {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// minCompressSize is the size below which responses are sent as they are,
+// since compressing them saves next to nothing
+const minCompressSize = 1024
+
+// Content codings the server compresses responses with, preferred first
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+)
+
+// acceptedEncoding returns the coding to compress the response to r with,
+// zstd or gzip, or "" if the client accepts neither
+func acceptedEncoding(r *http.Request) string {
+	accepted := make(map[string]bool)
+	for _, h := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(h, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			q := 1.0
+			if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				q, _ = strconv.ParseFloat(v, 64)
+			}
+			accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+		}
+	}
+	for _, enc := range []string{encodingZstd, encodingGzip} {
+		if accepted[enc] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// zstdEncoder compresses whole responses; EncodeAll is safe for
+// concurrent use
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+// compressor returns a writer compressing a stream to w with the coding
+// enc. It must be closed to flush the compressed stream.
+func compressor(w io.Writer, enc string) (io.WriteCloser, error) {
+	if enc == encodingZstd {
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return gzip.NewWriter(w), nil
+}
+
+// compress returns body compressed with the coding the client accepts,
+// and the coding, or body itself and "" if it is too small or the client
+// accepts no coding
+func compress(r *http.Request, body []byte) ([]byte, string, error) {
+	enc := acceptedEncoding(r)
+	if enc == "" || len(body) < minCompressSize {
+		return body, "", nil
+	}
+	if enc == encodingZstd {
+		return zstdEncoder.EncodeAll(body, nil), enc, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	err := zw.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), enc, nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                        "",
+		"identity":                "",
+		"gzip":                    "gzip",
+		"gzip, deflate, br, zstd": "zstd",
+		"zstd;q=0, gzip;q=0.5":    "gzip",
+		"GZIP;q=0":                "",
+		"br":                      "",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptedEncoding(r); got != want {
+			t.Fatalf("%q: got %q, want %q", header, got, want)
+		}
+	}
+}
+
+// decompress decodes a body sent with the content coding enc
+func decompress(t *testing.T, enc string, body []byte) []byte {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(body)
+	switch enc {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "zstd":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCompression(t *testing.T) {
+	store := storage.NewMemoryStore()
+	for i := 0; i < 20; i++ {
+		store.PutAnnouncement(dlcoracle.Announcement{EventID: string(rune('a' + i)), Maturity: time.Unix(1000, 0).UTC()})
+	}
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(testPrivKey()), store))
+	defer ts.Close()
+
+	var plain []byte
+	tags := make(map[string]bool)
+	for _, enc := range []string{"", "gzip", "zstd"} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/announcements", nil)
+		// Setting the header keeps the transport from decompressing
+		req.Header.Set("Accept-Encoding", enc)
+		if enc == "" {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.Header.Get("Content-Encoding") != enc || resp.Header.Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("%q: Content-Encoding %q, Vary %q", enc, resp.Header.Get("Content-Encoding"), resp.Header.Get("Vary"))
+		}
+		tag := resp.Header.Get("ETag")
+		if tags[tag] {
+			t.Fatalf("%q: ETag %s of another coding", enc, tag)
+		}
+		tags[tag] = true
+		decoded := decompress(t, enc, body)
+		if enc == "" {
+			plain = decoded
+		} else if !bytes.Equal(decoded, plain) || len(body) >= len(plain) {
+			t.Fatalf("%q: %d bytes decompress to %d bytes, want %d", enc, len(body), len(decoded), len(plain))
+		}
+		var list []dlcoracle.Announcement
+		err = json.Unmarshal(decoded, &list)
+		if err != nil || len(list) != 20 {
+			t.Fatalf("%q: decoded %d announcements %v", enc, len(list), err)
+		}
+
+		// The tag of a coding revalidates responses in that coding
+		req.Header.Set("If-None-Match", tag)
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotModified {
+			t.Fatalf("%q: status %d revalidating", enc, resp.StatusCode)
+		}
+	}
+
+	// Small responses aren't worth compressing
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/pubkey", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" {
+		t.Fatalf("compressed the public key with %s", resp.Header.Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// listQuery is the query parameters of listAnnouncements
+var listQuery = []QueryParam{
+	{Name: "unit", Description: "keeps numeric and digits events of the unit or asset pair, such as usd/btc"},
+	{Name: "type", Description: "keeps events of any of the types: numeric, enum, bytes or digits", Repeated: true},
+	{Name: "maturesAfter", Description: "keeps events maturing at or after the RFC 3339 time"},
+	{Name: "maturesBefore", Description: "keeps events maturing before the RFC 3339 time"},
+	{Name: "status", Description: "keeps events that are announced but neither attested nor revoked, attested, or revoked"},
+	{Name: "category", Description: "keeps events of the category"},
+	{Name: "tag", Description: "keeps events with every one of the tags", Repeated: true},
+	{Name: "after", Description: "continues the listing after the event with the ID, the last one of the previous page"},
+	{Name: "limit", Description: "returns at most that many records; full pages link to the next one in a Link header"},
+}
+
+// parseQuery returns the storage query of the parameters of the listings
+// of s's announcements and attestations
+func parseQuery(s storage.Store, v url.Values) (storage.Query, error) {
+	q := storage.Query{
+		Unit:     v.Get("unit"),
+		Category: v.Get("category"),
+		Tags:     v["tag"],
+	}
+	for _, name := range v["type"] {
+		var t dlcoracle.EventType
+		err := t.UnmarshalText([]byte(name))
+		if err != nil {
+			return q, err
+		}
+		q.Types = append(q.Types, t)
+	}
+	for _, p := range []struct {
+		name string
+		t    *time.Time
+	}{{"maturesAfter", &q.MaturesAfter}, {"maturesBefore", &q.MaturesBefore}} {
+		s := v.Get(p.name)
+		if s == "" {
+			continue
+		}
+		var err error
+		*p.t, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return q, fmt.Errorf("%s: %v", p.name, err)
+		}
+	}
+	if status := v.Get("status"); status != "" {
+		var err error
+		q.Status, err = storage.ParseEventStatus(status)
+		if err != nil {
+			return q, err
+		}
+	}
+	if id := v.Get("after"); id != "" {
+		var err error
+		q.After, err = storage.NewCursor(s, id)
+		if err == storage.ErrNotFound {
+			return q, fmt.Errorf("after: unknown event %q", id)
+		}
+		if err != nil {
+			return q, err
+		}
+	}
+	if limit := v.Get("limit"); limit != "" {
+		var err error
+		q.Limit, err = strconv.Atoi(limit)
+		if err != nil || q.Limit <= 0 {
+			return q, fmt.Errorf("limit: %q is not a positive number", limit)
+		}
+	}
+	return q, nil
+}
+
+// setNextLink links a full page of a listing to the next one, which
+// continues after the event lastID
+func setNextLink(w http.ResponseWriter, r *http.Request, q storage.Query, n int, lastID string) {
+	if q.Limit <= 0 || n < q.Limit {
+		return
+	}
+	v := r.URL.Query()
+	v.Set("after", lastID)
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", r.URL.Path, v.Encode()))
+}
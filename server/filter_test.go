@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func TestSearchAnnouncements(t *testing.T) {
+	priv := testPrivKey()
+	store := storage.NewMemoryStore()
+	for i, m := range []dlcoracle.EventMetadata{
+		{Category: "crypto", Tags: []string{"btc", "usd"}},
+		{Category: "crypto", Tags: []string{"eth"}},
+		{Category: "weather", Tags: []string{"usd"}},
+		{},
+	} {
+		a := dlcoracle.Announcement{
+			EventID:      string(rune('a' + i)),
+			OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+			RPoint:       dlcoracle.PublicKeyFromPrivateKey([32]byte{31: byte(i + 2)}),
+			Maturity:     time.Unix(int64(1000*(i+1)), 0).UTC(),
+			Descriptor:   dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric},
+			Metadata:     m,
+		}
+		if i < 2 {
+			a.Descriptor.Unit = "usd/btc"
+		} else {
+			a.Descriptor = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+		}
+		err := a.Sign(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.PutAnnouncement(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.PutAttestation(dlcoracle.Attestation{EventID: "b"})
+	store.PutRevocation(dlcoracle.Revocation{EventID: "c"})
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(priv), store))
+	defer ts.Close()
+
+	for query, want := range map[string]string{
+		"":                          "abcd",
+		"?category=crypto":          "ab",
+		"?tag=usd":                  "ac",
+		"?tag=usd&tag=btc":          "a",
+		"?category=weather&tag=btc": "",
+		"?category=sports":          "",
+		"?unit=usd/btc":             "ab",
+		"?type=enum":                "cd",
+		"?type=enum&type=numeric":   "abcd",
+		"?maturesAfter=1970-01-01T00:33:20Z&maturesBefore=1970-01-01T01:06:40Z": "bc",
+		"?status=announced":                "ad",
+		"?status=attested":                 "b",
+		"?status=revoked&category=weather": "c",
+		"?limit=2":                         "ab",
+		"?after=b":                         "cd",
+		"?after=a&limit=1&type=enum":       "c",
+	} {
+		var list []dlcoracle.Announcement
+		getJSON(t, ts.URL+"/api/announcements"+query, http.StatusOK, &list)
+		got := ""
+		for _, a := range list {
+			got += a.EventID
+			if a.Verify() != nil {
+				t.Fatalf("%s: announcement %s doesn't verify", query, a.EventID)
+			}
+		}
+		if got != want {
+			t.Fatalf("%q: got events %q, want %q", query, got, want)
+		}
+	}
+
+	for _, query := range []string{"?type=float", "?status=pending", "?maturesAfter=1000", "?after=z", "?limit=0"} {
+		var e errorJSON
+		getJSON(t, ts.URL+"/api/announcements"+query, http.StatusBadRequest, &e)
+		if e.Error == "" {
+			t.Fatalf("%q: no error", query)
+		}
+	}
+}
+
+// pages follows the Link headers of a listing of the server at base from
+// its first page at path and returns the event IDs on each page
+func pages(t *testing.T, base, path string) []string {
+	t.Helper()
+	var got []string
+	for path != "" {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list []struct {
+			EventID string `json:"eventId"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := ""
+		for _, r := range list {
+			page += r.EventID
+		}
+		got = append(got, page)
+		path = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			path = strings.TrimSuffix(strings.TrimPrefix(link, "<"), `>; rel="next"`)
+		}
+	}
+	return got
+}
+
+func TestPagination(t *testing.T) {
+	priv := testPrivKey()
+	store := storage.NewMemoryStore()
+	for i := 0; i < 5; i++ {
+		a := dlcoracle.Announcement{
+			EventID:      string(rune('a' + i)),
+			OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+			RPoint:       dlcoracle.PublicKeyFromPrivateKey([32]byte{31: byte(i + 2)}),
+			// events with the same maturity are ordered by ID
+			Maturity: time.Unix(int64(1000*(i/2)), 0).UTC(),
+		}
+		a.Sign(priv)
+		store.PutAnnouncement(a)
+		if i != 2 {
+			store.PutAttestation(dlcoracle.Attestation{EventID: a.EventID})
+		}
+	}
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(priv), store))
+	defer ts.Close()
+
+	for path, want := range map[string]string{
+		"/api/announcements?limit=2":         "ab cd e",
+		"/api/announcements?limit=5":         "abcde ",
+		"/api/announcements?limit=3&after=a": "bcd e",
+		"/api/announcements":                 "abcde",
+		"/api/attestations?limit=2":          "ab de ",
+		"/api/attestations?limit=3&after=b":  "de",
+		"/api/attestations?status=attested":  "abde",
+	} {
+		got := strings.Join(pages(t, ts.URL, path), " ")
+		if got != want {
+			t.Fatalf("%s: got pages %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// historyQuery is the query parameters of listHistory and the history
+// export: those of the listings but status, as only attested events have
+// a history
+var historyQuery = func() []QueryParam {
+	var params []QueryParam
+	for _, p := range listQuery {
+		if p.Name != "status" {
+			params = append(params, p)
+		}
+	}
+	return params
+}()
+
+// historyJSON is the outcome attested for an event. Value is set for
+// numeric and digits events, Label for enum events; Outcome is the
+// readable form of either, with the value in the event's unit.
+type historyJSON struct {
+	EventID        string `json:"eventId"`
+	Maturity       int64  `json:"maturity"`
+	MaturityHeight uint32 `json:"maturityHeight,omitempty"`
+	Type           string `json:"type"`
+	Unit           string `json:"unit,omitempty"`
+	UnitExponent   int32  `json:"unitExponent,omitempty"`
+	Value          int64  `json:"value"`
+	Label          string `json:"label,omitempty"`
+	Outcome        string `json:"outcome"`
+}
+
+// historyCSVHeader names the columns of the CSV export
+var historyCSVHeader = []string{"event_id", "maturity", "maturity_height", "type", "unit",
+	"unit_exponent", "value", "label", "outcome"}
+
+// newHistory returns the history entry of an attested event
+func newHistory(a dlcoracle.Announcement, att dlcoracle.Attestation) (historyJSON, error) {
+	o, err := a.Descriptor.ParseOutcome(att.Message)
+	if err != nil {
+		return historyJSON{}, fmt.Errorf("attestation of %s: %v", a.EventID, err)
+	}
+	h := historyJSON{
+		EventID:        a.EventID,
+		Maturity:       a.Maturity.Unix(),
+		MaturityHeight: a.MaturityHeight,
+		Type:           a.Descriptor.Type.String(),
+		Unit:           a.Descriptor.Unit,
+		UnitExponent:   a.Descriptor.UnitExponent,
+		Outcome:        a.Descriptor.FormatOutcome(o),
+	}
+	switch a.Descriptor.Type {
+	case dlcoracle.EventTypeNumeric, dlcoracle.EventTypeDigits:
+		h.Value = o.Value
+	case dlcoracle.EventTypeEnum:
+		h.Label = o.Label
+	}
+	return h, nil
+}
+
+// csvRecord returns the columns of h in the order of historyCSVHeader.
+// Maturities are RFC 3339 times in UTC.
+func (h historyJSON) csvRecord() []string {
+	height := ""
+	if h.MaturityHeight != 0 {
+		height = strconv.FormatUint(uint64(h.MaturityHeight), 10)
+	}
+	value := ""
+	if h.Label == "" && h.Type != dlcoracle.EventTypeBytes.String() {
+		value = strconv.FormatInt(h.Value, 10)
+	}
+	return []string{
+		h.EventID,
+		time.Unix(h.Maturity, 0).UTC().Format(time.RFC3339),
+		height,
+		h.Type,
+		h.Unit,
+		strconv.FormatInt(int64(h.UnitExponent), 10),
+		value,
+		h.Label,
+		h.Outcome,
+	}
+}
+
+// history returns the history of the attested events selected by q, in
+// the order of the announcements, and the cursor after the last of them
+func history(s storage.Store, q storage.Query) ([]historyJSON, storage.Cursor, error) {
+	q.Status = storage.StatusAttested
+	page, err := storage.Search(s, q)
+	if err != nil {
+		return nil, q.After, err
+	}
+	list := make([]historyJSON, 0, len(page))
+	for _, a := range page {
+		att, err := s.Attestation(a.EventID)
+		if err != nil {
+			return nil, q.After, err
+		}
+		h, err := newHistory(a, att)
+		if err != nil {
+			return nil, q.After, err
+		}
+		list = append(list, h)
+		q.After = storage.CursorOf(a)
+	}
+	return list, q.After, nil
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(s.store, r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()})
+		return
+	}
+	list, _, err := history(s.store, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(list) != 0 {
+		setNextLink(w, r, q, len(list), list[len(list)-1].EventID)
+	}
+	writeRecord(w, r, list, time.Time{})
+}
+
+// handleBulkHistory streams the history selected by the query parameters
+// of listHistory, as CSV with a header row or, with format=json, as a
+// JSON array of the entries of listHistory, a page of the store at a
+// time. Errors after the stream started cut it off: a JSON export then
+// doesn't parse, a CSV export lacks its last rows.
+func (s *Server) handleBulkHistory(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: fmt.Sprintf("format: %q is neither csv nor json", format)})
+		return
+	}
+	q, err := parseQuery(s.store, r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()})
+		return
+	}
+	limit := q.Limit
+	next := func(sent int) ([]historyJSON, error) {
+		q.Limit = bulkPageSize
+		if limit > 0 && limit-sent < bulkPageSize {
+			q.Limit = limit - sent
+		}
+		var page []historyJSON
+		var err error
+		page, q.After, err = history(s.store, q)
+		return page, err
+	}
+	page, err := next(0)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Vary", "Accept-Encoding")
+	var out io.Writer = w
+	if enc := acceptedEncoding(r); enc != "" {
+		zw, err := compressor(w, enc)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer zw.Close()
+		w.Header().Set("Content-Encoding", enc)
+		out = zw
+	}
+	bw := bufio.NewWriter(out)
+	cw := csv.NewWriter(bw)
+	if format == "csv" {
+		cw.Write(historyCSVHeader)
+	} else {
+		bw.WriteString("[")
+	}
+	sent := 0
+	for {
+		for i, h := range page {
+			if format == "csv" {
+				cw.Write(h.csvRecord())
+				continue
+			}
+			if sent+i > 0 {
+				bw.WriteString(",")
+			}
+			b, err := json.Marshal(h)
+			if err != nil {
+				return
+			}
+			bw.WriteString("\n")
+			bw.Write(b)
+		}
+		sent += len(page)
+		if len(page) < q.Limit || sent == limit {
+			break
+		}
+		cw.Flush()
+		err = bw.Flush()
+		if err != nil {
+			return
+		}
+		page, err = next(sent)
+		if err != nil {
+			return
+		}
+	}
+	if format == "json" {
+		bw.WriteString("\n]\n")
+	}
+	cw.Flush()
+	bw.Flush()
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// newHistoryServer serves an oracle that attested the BTC/USD price on
+// each of the first days of 2030, an ETH/USD price and an enum event
+func newHistoryServer(t *testing.T, days int) *httptest.Server {
+	priv := testPrivKey()
+	store := storage.NewMemoryStore()
+	btc := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric, Unit: "usd/btc", UnitExponent: -2}
+	add := func(id string, maturity time.Time, d dlcoracle.EventDescriptor, o dlcoracle.Outcome, n int) {
+		nonce := [32]byte{30: byte(n >> 8), 31: byte(n)}
+		a, err := dlcoracle.NewAnnouncement(priv, nonce,
+			dlcoracle.Event{ID: id, Maturity: maturity, Descriptor: d})
+		if err != nil {
+			t.Fatal(err)
+		}
+		att, err := dlcoracle.SignOutcome(priv, nonce, a, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.PutAnnouncement(a)
+		if err == nil {
+			err = store.PutAttestation(att)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < days; i++ {
+		add(fmt.Sprintf("btcusd-%d", i), start.AddDate(0, 0, i), btc,
+			dlcoracle.Outcome{Value: int64(4000000 + i)}, i+1)
+	}
+	add("ethusd", start, dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric, Unit: "usd/eth"},
+		dlcoracle.Outcome{Value: 3000}, 5000)
+	add("rain", start, dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}},
+		dlcoracle.Outcome{Label: "no"}, 5001)
+
+	// Announced events have no history yet
+	a, err := dlcoracle.NewAnnouncement(priv, [32]byte{30: 0x13, 31: 0x8a},
+		dlcoracle.Event{ID: "btcusd-next", Maturity: start.AddDate(1, 0, 0), Descriptor: btc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(NewServer(dlcoracle.PublicKeyFromPrivateKey(priv), store))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestHistory(t *testing.T) {
+	ts := newHistoryServer(t, 3)
+
+	var list []historyJSON
+	getJSON(t, ts.URL+"/api/history", http.StatusOK, &list)
+	if len(list) != 5 {
+		t.Fatalf("unexpected history %+v", list)
+	}
+	var rain historyJSON
+	for _, h := range list {
+		if h.EventID == "rain" {
+			rain = h
+		}
+	}
+	if rain.Type != "enum" || rain.Label != "no" || rain.Outcome != "no" {
+		t.Fatalf("unexpected enum entry %+v", rain)
+	}
+
+	// Selected by asset and maturity
+	list = nil
+	getJSON(t, ts.URL+"/api/history?unit=usd/btc&maturesAfter=2030-01-02T00:00:00Z", http.StatusOK, &list)
+	if len(list) != 2 || list[0].EventID != "btcusd-1" || list[1].EventID != "btcusd-2" {
+		t.Fatalf("unexpected history %+v", list)
+	}
+	h := list[0]
+	if h.Value != 4000001 || h.Outcome != "40000.01 usd/btc" || h.UnitExponent != -2 ||
+		h.Maturity != time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Fatalf("unexpected entry %+v", h)
+	}
+
+	// Pages link to the next one
+	resp, err := http.Get(ts.URL + "/api/history?unit=usd/btc&limit=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if link := resp.Header.Get("Link"); !strings.Contains(link, "after=btcusd-1") {
+		t.Fatalf("unexpected link %q", link)
+	}
+}
+
+func TestBulkHistory(t *testing.T) {
+	days := bulkPageSize + 5
+	ts := newHistoryServer(t, days)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/bulk/history?unit=usd/btc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(zr).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != days+1 || strings.Join(records[0], ",") != strings.Join(historyCSVHeader, ",") {
+		t.Fatalf("%d records, header %v", len(records), records[0])
+	}
+	last := strings.Join(records[days], ",")
+	want := fmt.Sprintf("btcusd-%d,%s,,numeric,usd/btc,-2,%d,,%s", days-1,
+		time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, days-1).Format(time.RFC3339),
+		4000000+days-1, dlcoracle.EventDescriptor{Unit: "usd/btc", UnitExponent: -2}.FormatValue(int64(4000000+days-1)))
+	if last != want {
+		t.Fatalf("last record %s, expected %s", last, want)
+	}
+
+	// The JSON export is an array of the entries of the listing
+	resp, err = http.Get(ts.URL + "/api/bulk/history?format=json&limit=1001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []historyJSON
+	err = json.Unmarshal(body, &list)
+	if err != nil || len(list) != 1001 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("%d entries: %v", len(list), err)
+	}
+
+	var res errorJSON
+	getJSON(t, ts.URL+"/api/bulk/history?format=xml", http.StatusBadRequest, &res)
+	if !strings.Contains(res.Error, "xml") {
+		t.Fatalf("unexpected error %q", res.Error)
+	}
+}
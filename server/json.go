@@ -0,0 +1,9 @@
+package server
+
+type pubKeyJSON struct {
+	PubKey string `json:"pubKey"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
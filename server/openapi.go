@@ -0,0 +1,479 @@
+package server
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Route describes an endpoint of the REST API. The handlers, the OpenAPI
+// document and the generated client are all derived from the routes, so
+// they can't drift apart.
+type Route struct {
+	Method string
+
+	// Path is the URL path, with parameters in braces like {id}
+	Path string
+
+	// Query are the endpoint's optional query parameters
+	Query []QueryParam
+
+	// OperationID names the endpoint in the OpenAPI document; the
+	// generated client method is named after it
+	OperationID string
+
+	// Summary completes the sentence "The endpoint ..."
+	Summary string
+
+	// Examples are example responses. Their Go type is the type the
+	// endpoint returns, and the schema of the response is inferred from
+	// their JSON encoding, so they should set every optional field
+	// between them.
+	Examples []interface{}
+
+	// NotFound is set for endpoints answering 404 for unknown IDs
+	NotFound bool
+}
+
+// QueryParam is an optional query parameter of an endpoint
+type QueryParam struct {
+	Name string
+
+	// Description completes the sentence "The parameter ..."
+	Description string
+
+	// Repeated is set for parameters that may be given more than once
+	Repeated bool
+}
+
+// Params returns the names of the path parameters of r
+func (r Route) Params() []string {
+	var names []string
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+var pathParam = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+
+// apiRoute is a route with its handler
+type apiRoute struct {
+	Route
+	handle func(s *Server, w http.ResponseWriter, r *http.Request)
+
+	// revocations marks routes that need a storage.RevocationStore
+	revocations bool
+
+	// evidence marks routes that need a storage.EvidenceStore
+	evidence bool
+}
+
+var apiRoutes = []apiRoute{{
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/pubkey",
+		OperationID: "getPubKey",
+		Summary:     "returns the oracle's public key",
+		Examples:    []interface{}{pubKeyJSON{PubKey: hex.EncodeToString(examplePubKey[:])}},
+	},
+	handle: (*Server).handlePubKey,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/announcements",
+		OperationID: "listAnnouncements",
+		Summary:     "lists the announcements of the oracle selected by the query parameters, all of them by default",
+		Examples:    []interface{}{exampleAnnouncements},
+		Query:       listQuery,
+	},
+	handle: (*Server).handleAnnouncements,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/announcements/{id}",
+		OperationID: "getAnnouncement",
+		Summary:     "returns the announcement of an event",
+		Examples:    []interface{}{exampleAnnouncements[0], exampleAnnouncements[1]},
+		NotFound:    true,
+	},
+	handle: (*Server).handleAnnouncement,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/attestations",
+		OperationID: "listAttestations",
+		Summary:     "lists the attestations of the events selected by the query parameters, in the order of the announcements",
+		Examples:    []interface{}{[]dlcoracle.Attestation{exampleAttestation}},
+		Query:       listQuery,
+	},
+	handle: (*Server).handleAttestations,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/attestations/{id}",
+		OperationID: "getAttestation",
+		Summary:     "returns the attestation of an event",
+		Examples:    []interface{}{exampleAttestation},
+		NotFound:    true,
+	},
+	handle: (*Server).handleAttestation,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/history",
+		OperationID: "listHistory",
+		Summary:     "lists the outcomes attested for the events selected by the query parameters, in the order of the announcements",
+		Examples:    []interface{}{exampleHistory},
+		Query:       historyQuery,
+	},
+	handle: (*Server).handleHistory,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/revocations/{id}",
+		OperationID: "getRevocation",
+		Summary:     "returns the revocation of an event",
+		Examples:    []interface{}{exampleRevocation},
+		NotFound:    true,
+	},
+	handle:      (*Server).handleRevocation,
+	revocations: true,
+}, {
+	Route: Route{
+		Method:      http.MethodGet,
+		Path:        "/api/evidence/{id}",
+		OperationID: "getEvidence",
+		Summary:     "returns the upstream API responses an event was attested from, signed by the oracle",
+		Examples:    []interface{}{exampleEvidence},
+		NotFound:    true,
+	},
+	handle:   (*Server).handleEvidence,
+	evidence: true,
+}}
+
+// Routes returns the endpoints of the REST API, including those only
+// served by stores keeping revocations or evidence
+func Routes() []Route {
+	routes := make([]Route, len(apiRoutes))
+	for i, r := range apiRoutes {
+		routes[i] = r.Route
+	}
+	return routes
+}
+
+// The example responses are fixed, so the OpenAPI document only changes
+// along with the API
+var (
+	examplePubKey = dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 1})
+
+	exampleAnnouncements = []dlcoracle.Announcement{{
+		EventID:      "btcusd-2030-01-01",
+		OraclePubKey: examplePubKey,
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 2}),
+		RPoints: [][33]byte{
+			dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 2}),
+			dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 3}),
+		},
+		Maturity:   time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 2, Unit: "usd/btc", UnitExponent: 3},
+		Metadata: dlcoracle.EventMetadata{
+			Description: "BTC/USD close in thousands of dollars",
+			Category:    "crypto",
+			Tags:        []string{"btc", "usd"},
+		},
+	}, {
+		EventID:        "halving-5",
+		OraclePubKey:   examplePubKey,
+		RPoint:         dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 4}),
+		Maturity:       time.Date(2028, 4, 1, 0, 0, 0, 0, time.UTC),
+		MaturityHeight: 1050000,
+		Descriptor:     dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}},
+	}}
+
+	exampleAttestation = dlcoracle.Attestation{
+		EventID:    "btcusd-2030-01-01",
+		Message:    []byte("4"),
+		Signature:  [32]byte{31: 1},
+		Signatures: [][32]byte{{31: 1}, {31: 2}},
+	}
+
+	exampleHistory = []historyJSON{{
+		EventID:      "btcusd-2030-01-01",
+		Maturity:     exampleAnnouncements[0].Maturity.Unix(),
+		Type:         "digits",
+		Unit:         "usd/btc",
+		UnitExponent: 3,
+		Value:        4,
+		Outcome:      "4000 usd/btc",
+	}, {
+		EventID:        "halving-5",
+		Maturity:       exampleAnnouncements[1].Maturity.Unix(),
+		MaturityHeight: 1050000,
+		Type:           "enum",
+		Label:          "yes",
+		Outcome:        "yes",
+	}}
+
+	exampleRevocation = dlcoracle.Revocation{
+		EventID:      "btcusd-2030-01-01",
+		OraclePubKey: examplePubKey,
+		Reason:       "exchange delisted the pair",
+		SupersededBy: "btcusd-2030-01-02",
+		RevokedAt:    time.Date(2029, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	exampleEvidence = dlcoracle.Evidence{
+		EventID:      "btcusd-2030-01-01",
+		OraclePubKey: examplePubKey,
+		Responses: []dlcoracle.UpstreamResponse{{
+			Source:      "btcusd-kraken",
+			Method:      http.MethodGet,
+			URL:         "https://api.kraken.com/0/public/Ticker?pair=XBTUSD",
+			Status:      http.StatusOK,
+			Header:      map[string][]string{"Content-Type": {"application/json"}},
+			Body:        []byte(`{"error":[],"result":{"XXBTZUSD":{"c":["4123.4","0.1"]}}}`),
+			RequestedAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			ReceivedAt:  time.Date(2030, 1, 1, 0, 0, 0, 150e6, time.UTC),
+		}},
+		CapturedAt: time.Date(2030, 1, 1, 0, 0, 1, 0, time.UTC),
+	}
+)
+
+// schema is an OpenAPI schema object
+type schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Items      *schema            `json:"items,omitempty"`
+	Properties map[string]*schema `json:"properties,omitempty"`
+}
+
+// inferSchema returns the schema of a decoded JSON value
+func inferSchema(v interface{}) *schema {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s := &schema{Type: "object", Properties: make(map[string]*schema)}
+		for k, field := range v {
+			s.Properties[k] = inferSchema(field)
+		}
+		return s
+	case []interface{}:
+		s := &schema{Type: "array", Items: &schema{}}
+		for _, item := range v {
+			s.Items = mergeSchema(s.Items, inferSchema(item))
+		}
+		return s
+	case string:
+		return &schema{Type: "string"}
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return &schema{Type: "number"}
+		}
+		return &schema{Type: "integer"}
+	case bool:
+		return &schema{Type: "boolean"}
+	}
+	return &schema{Nullable: true}
+}
+
+// mergeSchema combines the schemas inferred from two examples of the
+// same type, keeping the properties of both
+func mergeSchema(a, b *schema) *schema {
+	if a.Type == "" {
+		b.Nullable = b.Nullable || a.Nullable
+		return b
+	}
+	if b.Type == "" {
+		a.Nullable = a.Nullable || b.Nullable
+		return a
+	}
+	if a.Items != nil && b.Items != nil {
+		a.Items = mergeSchema(a.Items, b.Items)
+	}
+	for k, p := range b.Properties {
+		if q, ok := a.Properties[k]; ok {
+			a.Properties[k] = mergeSchema(q, p)
+		} else {
+			a.Properties[k] = p
+		}
+	}
+	return a
+}
+
+// typeName returns the name the generated client and the OpenAPI
+// document give to the Go type t of a response: its own name, without
+// a JSON suffix and exported
+func typeName(t reflect.Type) string {
+	name := strings.TrimSuffix(t.Name(), "JSON")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+type specBuilder struct {
+	schemas map[string]*schema
+}
+
+// ref infers the schema of the named type t from examples, records it
+// as a component and returns a reference to it
+func (b *specBuilder) ref(t reflect.Type, examples []interface{}) (*schema, error) {
+	name := typeName(t)
+	s := b.schemas[name]
+	if s == nil {
+		s = &schema{}
+	}
+	for _, ex := range examples {
+		enc, err := json.Marshal(ex)
+		if err != nil {
+			return nil, err
+		}
+		dec := json.NewDecoder(bytes.NewReader(enc))
+		dec.UseNumber()
+		var v interface{}
+		err = dec.Decode(&v)
+		if err != nil {
+			return nil, err
+		}
+		s = mergeSchema(s, inferSchema(v))
+	}
+	b.schemas[name] = s
+	return &schema{Ref: "#/components/schemas/" + name}, nil
+}
+
+// response returns the schema of the examples of a route. Lists are
+// arrays of references to their element type.
+func (b *specBuilder) response(examples []interface{}) (*schema, error) {
+	t := reflect.TypeOf(examples[0])
+	if t.Kind() != reflect.Slice {
+		return b.ref(t, examples)
+	}
+	var elems []interface{}
+	for _, ex := range examples {
+		v := reflect.ValueOf(ex)
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, v.Index(i).Interface())
+		}
+	}
+	items, err := b.ref(t.Elem(), elems)
+	if err != nil {
+		return nil, err
+	}
+	return &schema{Type: "array", Items: items}, nil
+}
+
+// OpenAPI returns the OpenAPI 3.0 document describing routes, such as
+// those returned by Routes
+func OpenAPI(routes []Route) ([]byte, error) {
+	b := &specBuilder{schemas: make(map[string]*schema)}
+	errRef, err := b.ref(reflect.TypeOf(errorJSON{}), []interface{}{errorJSON{Error: "not found"}})
+	if err != nil {
+		return nil, err
+	}
+	errResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": errRef}},
+		}
+	}
+
+	paths := make(map[string]map[string]interface{})
+	for _, r := range routes {
+		s, err := b.response(r.Examples)
+		if err != nil {
+			return nil, err
+		}
+		var params []interface{}
+		for _, name := range r.Params() {
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   schema{Type: "string"},
+			})
+		}
+		for _, q := range r.Query {
+			s := &schema{Type: "string"}
+			if q.Repeated {
+				s = &schema{Type: "array", Items: s}
+			}
+			params = append(params, map[string]interface{}{
+				"name":        q.Name,
+				"in":          "query",
+				"description": strings.ToUpper(q.Description[:1]) + q.Description[1:],
+				"schema":      s,
+			})
+		}
+		ok := map[string]interface{}{
+			"description": "OK",
+			"content": map[string]interface{}{"application/json": map[string]interface{}{
+				"schema":  s,
+				"example": r.Examples[0],
+			}},
+		}
+		responses := map[string]interface{}{
+			"200": ok,
+			"500": errResponse("Internal error"),
+		}
+		if r.Method == http.MethodGet {
+			ok["headers"] = map[string]interface{}{"ETag": map[string]interface{}{
+				"description": "Tag of the response to send back in If-None-Match",
+				"schema":      schema{Type: "string"},
+			}}
+			responses["304"] = map[string]interface{}{
+				"description": "Not modified since the request's If-None-Match or If-Modified-Since",
+			}
+		}
+		if r.NotFound {
+			responses["404"] = errResponse("Not found")
+		}
+		if len(r.Query) != 0 {
+			responses["400"] = errResponse("Invalid query parameter")
+		}
+		op := map[string]interface{}{
+			"operationId": r.OperationID,
+			"summary":     strings.ToUpper(r.Summary[:1]) + r.Summary[1:],
+			"responses":   responses,
+		}
+		if len(params) != 0 {
+			op["parameters"] = params
+		}
+		if paths[r.Path] == nil {
+			paths[r.Path] = make(map[string]interface{})
+		}
+		paths[r.Path][strings.ToLower(r.Method)] = op
+	}
+
+	doc := struct {
+		OpenAPI    string                            `json:"openapi"`
+		Info       map[string]string                 `json:"info"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components map[string]interface{}            `json:"components"`
+	}{
+		OpenAPI: "3.0.3",
+		Info: map[string]string{
+			"title":       "DLC oracle",
+			"description": "Announcements and attestations of a discreet log contract oracle",
+			"version":     "1",
+		},
+		Paths:      paths,
+		Components: map[string]interface{}{"schemas": b.schemas},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// handleOpenAPI serves the document for the routes s serves
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := OpenAPI(s.routes)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
@@ -0,0 +1,195 @@
+package server
+
+//go:generate go test -run TestOpenAPI -update
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+const (
+	// readHeaderTimeout bounds how long a client may take to send its
+	// request headers
+	readHeaderTimeout = 10 * time.Second
+
+	// idleTimeout bounds how long an idle keep-alive connection is kept
+	idleTimeout = 2 * time.Minute
+)
+
+// Server exposes the oracle's public key, announcements and attestations
+// over a REST API so contract participants can obtain them. The API is
+// described by an OpenAPI document at /api/openapi.json.
+type Server struct {
+	pubKey [33]byte
+	store  storage.Store
+	mux    *http.ServeMux
+	routes []Route
+
+	// handler is mux wrapped in the middleware added with Use
+	handler http.Handler
+}
+
+// NewServer returns a server publishing the data for the oracle with
+// public key pubKey from store
+func NewServer(pubKey [33]byte, store storage.Store) *Server {
+	s := &Server{
+		pubKey: pubKey,
+		store:  store,
+		mux:    http.NewServeMux(),
+	}
+	_, revocations := store.(storage.RevocationStore)
+	_, evidence := store.(storage.EvidenceStore)
+	for _, r := range apiRoutes {
+		if r.revocations && !revocations || r.evidence && !evidence {
+			continue
+		}
+		handle := r.handle
+		s.mux.HandleFunc(r.Method+" "+r.Path, func(w http.ResponseWriter, req *http.Request) {
+			handle(s, w, req)
+		})
+		s.routes = append(s.routes, r.Route)
+	}
+	s.mux.HandleFunc("GET /api/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /api/bulk/announcements", s.handleBulkAnnouncements)
+	s.mux.HandleFunc("GET /api/bulk/history", s.handleBulkHistory)
+	s.handler = s.mux
+	return s
+}
+
+// Handle registers an additional handler, such as a /metrics endpoint,
+// next to the REST API
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// Use wraps all requests in middleware. Middleware added later runs
+// first. Use is not safe to call while the server is serving.
+func (s *Server) Use(middleware func(http.Handler) http.Handler) {
+	s.handler = middleware(s.handler)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// ListenAndServe starts serving the REST API on addr. Slow clients are
+// cut off while sending their request headers; responses have no write
+// timeout since the update streams stay open indefinitely.
+func (s *Server) ListenAndServe(addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           s,
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	return srv.ListenAndServe()
+}
+
+func (s *Server) handlePubKey(w http.ResponseWriter, r *http.Request) {
+	writeRecord(w, r, pubKeyJSON{PubKey: fmt.Sprintf("%x", s.pubKey)}, time.Time{})
+}
+
+func (s *Server) handleAnnouncements(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(s.store, r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()})
+		return
+	}
+	list, err := storage.Search(s.store, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(list) != 0 {
+		setNextLink(w, r, q, len(list), list[len(list)-1].EventID)
+	}
+	writeRecord(w, r, list, time.Time{})
+}
+
+func (s *Server) handleAttestations(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(s.store, r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: err.Error()})
+		return
+	}
+	list, err := storage.Attestations(s.store, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(list) != 0 {
+		setNextLink(w, r, q, len(list), list[len(list)-1].EventID)
+	}
+	writeRecord(w, r, list, time.Time{})
+}
+
+func (s *Server) handleAnnouncement(w http.ResponseWriter, r *http.Request) {
+	a, err := s.store.Announcement(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var modified time.Time
+	if ts, ok := s.store.(storage.TimestampStore); ok {
+		modified, _ = ts.AnnouncementTime(a.EventID)
+	}
+	writeRecord(w, r, a, modified)
+}
+
+func (s *Server) handleAttestation(w http.ResponseWriter, r *http.Request) {
+	a, err := s.store.Attestation(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var modified time.Time
+	if ts, ok := s.store.(storage.TimestampStore); ok {
+		modified, _ = ts.AttestationTime(a.EventID)
+	}
+	writeRecord(w, r, a, modified)
+}
+
+func (s *Server) handleRevocation(w http.ResponseWriter, r *http.Request) {
+	rev, err := s.store.(storage.RevocationStore).Revocation(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeRecord(w, r, rev, rev.RevokedAt)
+}
+
+func (s *Server) handleEvidence(w http.ResponseWriter, r *http.Request) {
+	e, err := s.store.(storage.EvidenceStore).Evidence(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeRecord(w, r, e, e.CapturedAt)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encoding response: %v", err),
+			http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if err == storage.ErrNotFound {
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, errorJSON{Error: err.Error()})
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, dlcoracle.Announcement) {
+	priv := testPrivKey()
+	store := storage.NewMemoryStore()
+	a := dlcoracle.Announcement{
+		EventID:      "event",
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey([32]byte{31: 2}),
+		Maturity:     time.Unix(1000, 0).UTC(),
+	}
+	err := a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(NewServer(a.OraclePubKey, store))
+	t.Cleanup(ts.Close)
+	return ts, a
+}
+
+func getJSON(t *testing.T, url string, status int, v interface{}) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s: expected status %d, got %d", url, status, resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPubKey(t *testing.T) {
+	ts, a := newTestServer(t)
+	var res pubKeyJSON
+	getJSON(t, ts.URL+"/api/pubkey", http.StatusOK, &res)
+	if res.PubKey != fmt.Sprintf("%x", a.OraclePubKey) {
+		t.Fatalf("unexpected pubkey %s", res.PubKey)
+	}
+}
+
+func TestAnnouncements(t *testing.T) {
+	ts, a := newTestServer(t)
+
+	var list []dlcoracle.Announcement
+	getJSON(t, ts.URL+"/api/announcements", http.StatusOK, &list)
+	if len(list) != 1 || !sameAnnouncement(list[0], a) {
+		t.Fatalf("unexpected announcements %+v", list)
+	}
+
+	var got dlcoracle.Announcement
+	getJSON(t, ts.URL+"/api/announcements/event", http.StatusOK, &got)
+	if !sameAnnouncement(got, a) {
+		t.Fatalf("unexpected announcement %+v", got)
+	}
+	err := got.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ts, _ := newTestServer(t)
+	for _, path := range []string{"/api/announcements/missing", "/api/attestations/event", "/api/revocations/event", "/api/evidence/event"} {
+		var res errorJSON
+		getJSON(t, ts.URL+path, http.StatusNotFound, &res)
+		if res.Error != storage.ErrNotFound.Error() {
+			t.Fatalf("%s: unexpected error %q", path, res.Error)
+		}
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	priv := testPrivKey()
+	store := storage.NewMemoryStore()
+	e := exampleEvidence
+	e.OraclePubKey = dlcoracle.PublicKeyFromPrivateKey(priv)
+	err := e.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.PutEvidence(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(NewServer(e.OraclePubKey, store))
+	defer ts.Close()
+
+	var got dlcoracle.Evidence
+	getJSON(t, ts.URL+"/api/evidence/"+e.EventID, http.StatusOK, &got)
+	err = got.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Responses) != 1 || !bytes.Equal(got.Responses[0].Body, e.Responses[0].Body) {
+		t.Fatalf("unexpected evidence %+v", got)
+	}
+}
+
+func sameAnnouncement(a, b dlcoracle.Announcement) bool {
+	return a.EventID == b.EventID && a.OraclePubKey == b.OraclePubKey &&
+		a.RPoint == b.RPoint && a.Maturity.Equal(b.Maturity) &&
+		a.Signature == b.Signature
+}
+
+var update = flag.Bool("update", false, "rewrite openapi.json")
+
+func TestOpenAPI(t *testing.T) {
+	ts, _ := newTestServer(t)
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string                     `json:"operationId"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		}
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage
+			}
+		}
+	}
+	getJSON(t, ts.URL+"/api/openapi.json", http.StatusOK, &doc)
+	op := doc.Paths["/api/announcements/{id}"]["get"]
+	if op.OperationID != "getAnnouncement" || op.Responses["404"] == nil {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+	ann := doc.Components.Schemas["Announcement"].Properties
+	for _, field := range []string{"eventId", "rPoints", "maturityHeight", "descriptor", "signature"} {
+		if ann[field] == nil {
+			t.Fatalf("announcement schema lacks %s: %s", field, ann)
+		}
+	}
+
+	// The checked in document describes all routes
+	want, err := OpenAPI(Routes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		err = os.WriteFile("openapi.json", want, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("openapi.json is out of date, run go generate")
+	}
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+)
+
+const (
+	// pingInterval is how often idle update streams are kept alive
+	pingInterval = 30 * time.Second
+
+	// pongWait is how long a WebSocket peer has to answer a ping before
+	// it is considered gone
+	pongWait = 2 * pingInterval
+
+	// writeWait is how long a single write to a WebSocket peer may take
+	writeWait = 10 * time.Second
+)
+
+// Publisher is a source of new announcements and attestations, such as
+// an oracle.Oracle. It closes a subscription's channel when the
+// subscriber missed updates.
+type Publisher interface {
+	Subscribe() (<-chan oracle.Update, func())
+}
+
+type updateJSON struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// resyncUpdate tells a client that it missed updates. The stream ends
+// after it, and the client has to refetch the announcements and
+// attestations it is interested in before subscribing again.
+var resyncUpdate = updateJSON{Type: "resync"}
+
+func newUpdateJSON(u oracle.Update) updateJSON {
+	if u.Attestation != nil {
+		return updateJSON{Type: "attestation", Data: u.Attestation}
+	}
+	return updateJSON{Type: "announcement", Data: u.Announcement}
+}
+
+var upgrader = websocket.Upgrader{
+	// Updates are public, so any origin may subscribe
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// PublishUpdates pushes the announcements and attestations from p to
+// clients as they happen, over a WebSocket at /api/updates/ws and as
+// server-sent events at /api/updates/sse. Every message is a JSON object
+// with a type of "announcement", "attestation" or "resync". A client that
+// falls behind gets a final "resync" message instead of silently missing
+// updates; it must then refetch through the REST endpoints and reconnect.
+func (s *Server) PublishUpdates(p Publisher) {
+	s.mux.HandleFunc("GET /api/updates/ws", func(w http.ResponseWriter, r *http.Request) {
+		s.handleWebSocket(p, w, r)
+	})
+	s.mux.HandleFunc("GET /api/updates/sse", func(w http.ResponseWriter, r *http.Request) {
+		s.handleSSE(p, w, r)
+	})
+}
+
+func (s *Server) handleWebSocket(p Publisher, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied to the client
+		return
+	}
+	defer conn.Close()
+
+	updates, cancel := p.Subscribe()
+	defer cancel()
+
+	// We don't expect anything from the client, but have to read to
+	// process pongs and notice it going away. A peer that stops
+	// answering pings hits the read deadline.
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case u, ok := <-updates:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				conn.WriteJSON(resyncUpdate)
+				return
+			}
+			err = conn.WriteJSON(newUpdateJSON(u))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleSSE(p Publisher, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	updates, cancel := p.Subscribe()
+	defer cancel()
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprintf(w, ": ping\n\n")
+		case u, ok := <-updates:
+			if !ok {
+				writeEvent(w, resyncUpdate)
+				flusher.Flush()
+				return
+			}
+			err = writeEvent(w, newUpdateJSON(u))
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes u as a server-sent event
+func writeEvent(w http.ResponseWriter, u updateJSON) error {
+	data := []byte("{}")
+	if u.Data != nil {
+		var err error
+		data, err = json.Marshal(u.Data)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", u.Type, data)
+	return err
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+// closedPublisher behaves like a publisher whose subscriber fell behind
+type closedPublisher struct{}
+
+func (closedPublisher) Subscribe() (<-chan oracle.Update, func()) {
+	ch := make(chan oracle.Update)
+	close(ch)
+	return ch, func() {}
+}
+
+func newUpdatesServer(t *testing.T, p Publisher) *httptest.Server {
+	s := NewServer([33]byte{}, storage.NewMemoryStore())
+	s.PublishUpdates(p)
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// waitSubscribed gives the handler time to subscribe after the
+// connection was established
+func waitSubscribed() {
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestWebSocketUpdates(t *testing.T) {
+	o := oracle.New(testPrivKey(), storage.NewMemoryStore())
+	ts := newUpdatesServer(t, o)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/updates/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitSubscribed()
+
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u struct {
+		Type string `json:"type"`
+		Data struct {
+			EventID string `json:"eventId"`
+		} `json:"data"`
+	}
+	err = conn.ReadJSON(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Type != "announcement" || u.Data.EventID != "event" {
+		t.Fatalf("unexpected update %+v", u)
+	}
+}
+
+func TestSSEUpdates(t *testing.T) {
+	o := oracle.New(testPrivKey(), storage.NewMemoryStore())
+	ts := newUpdatesServer(t, o)
+
+	resp, err := http.Get(ts.URL + "/api/updates/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	waitSubscribed()
+
+	_, err = o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "event: announcement\n" {
+		t.Fatalf("unexpected event line %q", line)
+	}
+}
+
+func TestResyncOnMissedUpdates(t *testing.T) {
+	ts := newUpdatesServer(t, closedPublisher{})
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/updates/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var u updateJSON
+	err = conn.ReadJSON(&u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Type != "resync" {
+		t.Fatalf("expected resync, got %q", u.Type)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/updates/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "event: resync\n" {
+		t.Fatalf("unexpected event line %q", line)
+	}
+}
+
+func testPrivKey() [32]byte {
+	var k [32]byte
+	k[31] = 1
+	return k
+}
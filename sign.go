@@ -0,0 +1,264 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// Sign and Verify sign and verify arbitrary messages in any of the
+// supported schemes, configured with options rather than a function per
+// combination. Without options they sign like SignMessage.
+
+// Scheme is a signature scheme Sign and Verify support
+type Scheme uint8
+
+const (
+	// SchemeLIT signs like ComputeSignature, with the challenge hash of
+	// the message and R's X coordinate. Signatures are the compressed R
+	// point followed by s, 65 bytes, as from SignMessage.
+	SchemeLIT Scheme = iota
+
+	// SchemeBIP340 makes BIP-340 Schnorr signatures of the hash of the
+	// message: R's X coordinate followed by s, 64 bytes. Keys are always
+	// normalized to an even Y.
+	SchemeBIP340
+
+	// SchemeECDSA makes low-S ECDSA signatures of the hash of the
+	// message: r followed by s, 64 bytes
+	SchemeECDSA
+)
+
+// String returns the name of the scheme
+func (s Scheme) String() string {
+	switch s {
+	case SchemeLIT:
+		return "lit"
+	case SchemeBIP340:
+		return "bip340"
+	case SchemeECDSA:
+		return "ecdsa"
+	}
+	return fmt.Sprintf("scheme(%d)", uint8(s))
+}
+
+// MarshalText encodes the scheme as its name
+func (s Scheme) MarshalText() ([]byte, error) {
+	if s > SchemeECDSA {
+		return nil, fmt.Errorf("unknown %s", s)
+	}
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a scheme from its name
+func (s *Scheme) UnmarshalText(b []byte) error {
+	for scheme := SchemeLIT; scheme <= SchemeECDSA; scheme++ {
+		if string(b) == scheme.String() {
+			*s = scheme
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown signature scheme %q", b)
+}
+
+// Normalization is a set of normalizations applied when signing and
+// required when verifying. Those implied by the scheme always apply.
+type Normalization uint8
+
+const (
+	// NormalizeEvenY negates private keys and one-time signing keys whose
+	// points have an odd Y, so Schnorr signatures verify against x-only
+	// keys, like ComputeSignatureXOnly. SchemeBIP340 implies it.
+	NormalizeEvenY Normalization = 1 << iota
+
+	// NormalizeLowS negates ECDSA signatures whose s is in the upper half
+	// of the order, as Bitcoin requires. SchemeECDSA implies it.
+	NormalizeLowS
+)
+
+// NonceDerivation returns the one-time signing key to sign message with
+type NonceDerivation func(privKey [32]byte, message []byte) ([32]byte, error)
+
+// HashFunc hashes data into 32 bytes
+type HashFunc func(data []byte) [32]byte
+
+// SHA256 is single SHA-256, the challenge hash LIT uses
+func SHA256(data []byte) [32]byte {
+	return sha256.Sum256(data)
+}
+
+// DoubleSHA256 is SHA-256 applied twice, as Bitcoin hashes transactions
+// and signed messages
+func DoubleSHA256(data []byte) [32]byte {
+	first := sha256.Sum256(data)
+	return sha256.Sum256(first[:])
+}
+
+// TaggedSHA256 returns the BIP-340 tagged hash with tag, SHA-256 of the
+// data prefixed twice with the SHA-256 of the tag, which keeps hashes of
+// different protocols apart
+func TaggedSHA256(tag string) HashFunc {
+	return func(data []byte) [32]byte {
+		return taggedHash(tag, data)
+	}
+}
+
+// WithScheme sets the signature scheme, SchemeLIT by default
+func WithScheme(s Scheme) SignOption {
+	return func(c *signConfig) {
+		c.scheme = s
+	}
+}
+
+// WithNormalization adds normalizations to those the scheme implies
+func WithNormalization(n Normalization) SignOption {
+	return func(c *signConfig) {
+		c.norm |= n
+	}
+}
+
+// WithHash sets the hash function, SHA256 by default. SchemeLIT
+// uses it for the challenge hash; SchemeBIP340 and SchemeECDSA sign the
+// hash of the message with it. Signers and verifiers have to agree on it,
+// so it is usually DoubleSHA256 or a TaggedSHA256 only when the other
+// side of a contract expects it.
+func WithHash(h HashFunc) SignOption {
+	return func(c *signConfig) {
+		c.hash = h
+	}
+}
+
+// normalization returns the normalizations that apply
+func (c signConfig) normalization() Normalization {
+	switch c.scheme {
+	case SchemeBIP340:
+		return c.norm | NormalizeEvenY
+	case SchemeECDSA:
+		return c.norm | NormalizeLowS
+	}
+	return c.norm
+}
+
+// Verify checks a signature made by Sign with the same options. Options
+// only affecting signing, such as WithNonceDerivation, are ignored.
+func Verify(pubKey [33]byte, message, sig []byte, opts ...SignOption) error {
+	c := newSignConfig(nil, opts)
+	if c.normalization()&NormalizeEvenY != 0 && c.scheme != SchemeECDSA {
+		pubKey[0] = 0x02
+	}
+	if c.strict {
+		err := checkStrictMessage(message)
+		if err != nil {
+			return err
+		}
+		_, err = parsePubKey("public key", pubKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch c.scheme {
+	case SchemeLIT:
+		if len(sig) != 65 {
+			return fmt.Errorf("%w: %d bytes, expected 65", ErrInvalidSignature, len(sig))
+		}
+		var R [33]byte
+		var s [32]byte
+		copy(R[:], sig[:33])
+		copy(s[:], sig[33:])
+		if c.normalization()&NormalizeEvenY != 0 && !HasEvenY(R) {
+			return fmt.Errorf("%w: r point has an odd Y", ErrInvalidSignature)
+		}
+		if c.strict {
+			err := checkStrict(pubKey, R, message, s)
+			if err != nil {
+				return err
+			}
+		}
+		expected, err := computeSignaturePubKey(pubKey, R, message, c.hash)
+		if err != nil {
+			return err
+		}
+		if PublicKeyFromPrivateKey(s) != expected {
+			return fmt.Errorf("%w: does not match message", ErrInvalidSignature)
+		}
+		return nil
+	case SchemeBIP340:
+		pub, err := parsePubKey("public key", pubKey)
+		if err != nil {
+			return err
+		}
+		if c.strict && len(sig) == 64 {
+			err = checkScalar("signature", [32]byte(sig[32:]))
+			if err != nil {
+				return err
+			}
+		}
+		parsed, err := schnorr.ParseSignature(sig)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		digest := c.hash(message)
+		if !parsed.Verify(digest[:], pub) {
+			return fmt.Errorf("%w: does not match message", ErrInvalidSignature)
+		}
+		return nil
+	case SchemeECDSA:
+		if len(sig) != 64 {
+			return fmt.Errorf("%w: %d bytes, expected 64", ErrInvalidSignature, len(sig))
+		}
+		return verifyECDSA(pubKey, c.hash(message), [64]byte(sig))
+	}
+	return fmt.Errorf("unknown signature scheme %s", c.scheme)
+}
+
+// bip340ChallengeTag is the tag of BIP-340's challenge hash
+const bip340ChallengeTag = "BIP0340/challenge"
+
+// AnticipationPoint is ComputeSignaturePubKey as configured by the
+// options: the point s*G of the signature of message that the oracle with
+// public key oraclePubA will make with the R point oraclePubR. It
+// supports SchemeLIT, and SchemeBIP340 for adaptor signatures, where it is
+// R + e*P.
+func AnticipationPoint(oraclePubA, oraclePubR [33]byte, message []byte, opts ...SignOption) ([33]byte, error) {
+	c := newSignConfig(nil, opts)
+	if c.normalization()&NormalizeEvenY != 0 {
+		oraclePubA[0], oraclePubR[0] = 0x02, 0x02
+	}
+	switch c.scheme {
+	case SchemeLIT:
+		return computeSignaturePubKey(oraclePubA, oraclePubR, message, c.hash)
+	case SchemeBIP340:
+		return bip340AnticipationPoint(oraclePubA, oraclePubR, c.hash(message))
+	}
+	return [33]byte{}, fmt.Errorf("%s signatures have no anticipation points", c.scheme)
+}
+
+func bip340AnticipationPoint(pubKey, rPoint [33]byte, digest [32]byte) ([33]byte, error) {
+	var point [33]byte
+	P, err := parsePubKey("oracle pubkey", pubKey)
+	if err != nil {
+		return point, err
+	}
+	R, err := parsePubKey("r point", rPoint)
+	if err != nil {
+		return point, err
+	}
+	var e btcecv2.ModNScalar
+	h := taggedHash(bip340ChallengeTag, rPoint[1:], pubKey[1:], digest[:])
+	e.SetBytes(&h)
+
+	var p, r, eP, sum btcecv2.JacobianPoint
+	P.AsJacobian(&p)
+	R.AsJacobian(&r)
+	btcecv2.ScalarMultNonConst(&e, &p, &eP)
+	btcecv2.AddNonConst(&r, &eP, &sum)
+	if (sum.X.IsZero() && sum.Y.IsZero()) || sum.Z.IsZero() {
+		return point, fmt.Errorf("anticipation point is the point at infinity")
+	}
+	sum.ToAffine()
+	copy(point[:], btcecv2.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed())
+	return point, nil
+}
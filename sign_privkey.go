@@ -0,0 +1,112 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"fmt"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// FixedNonce returns a NonceDerivation always returning k, such as the
+// one-time signing key of an announced event. Signing two messages with
+// the same k reveals the private key.
+func FixedNonce(k [32]byte) NonceDerivation {
+	return func([32]byte, []byte) ([32]byte, error) {
+		return k, nil
+	}
+}
+
+// WithNonceDerivation sets how the one-time signing key of a signature
+// is derived. By default it is synthesized from the private key, the
+// message and auxiliary randomness, see WithAuxRand.
+func WithNonceDerivation(n NonceDerivation) SignOption {
+	return func(c *signConfig) {
+		c.nonce = n
+	}
+}
+
+// Sign signs message with privKey as configured by the options
+func Sign(privKey [32]byte, message []byte, opts ...SignOption) ([]byte, error) {
+	c := newSignConfig(randReader(), opts)
+	err := checkScalar("priv", privKey)
+	if err != nil {
+		return nil, err
+	}
+	var k [32]byte
+	if c.nonce != nil {
+		k, err = c.nonce(privKey, message)
+	} else {
+		k, err = c.syntheticNonce(privKey, c.nonceDomain(), message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.normalization()&NormalizeEvenY != 0 && c.scheme != SchemeECDSA {
+		privKey, k = evenY(privKey), evenY(k)
+	}
+
+	switch c.scheme {
+	case SchemeLIT:
+		R := PublicKeyFromPrivateKey(k)
+		s, err := computeSignature(privKey, k, &R, message, c.hash)
+		if err != nil {
+			return nil, err
+		}
+		return append(R[:], s[:]...), nil
+	case SchemeBIP340:
+		return signBIP340(privKey, k, c.hash(message))
+	case SchemeECDSA:
+		return signECDSA(privKey, k, c.hash(message))
+	}
+	return nil, fmt.Errorf("unknown signature scheme %s", c.scheme)
+}
+
+// signBIP340 signs digest with private key d and one-time signing key k,
+// both normalized to an even Y: s = k + e*d
+func signBIP340(privKey, k [32]byte, digest [32]byte) ([]byte, error) {
+	var d, kk, e btcecv2.ModNScalar
+	d.SetBytes(&privKey)
+	defer d.Zero()
+	if kk.SetBytes(&k) != 0 || kk.IsZero() {
+		return nil, &ScalarError{Name: "k", Err: ErrScalarOutOfRange}
+	}
+	defer kk.Zero()
+	P := PublicKeyFromPrivateKey(privKey)
+	R := PublicKeyFromPrivateKey(k)
+	h := taggedHash(bip340ChallengeTag, R[1:], P[1:], digest[:])
+	e.SetBytes(&h)
+	s := new(btcecv2.ModNScalar).Mul2(&e, &d).Add(&kk)
+	sb := s.Bytes()
+	return append(R[1:], sb[:]...), nil
+}
+
+// signECDSA signs digest with private key d and one-time signing key k:
+// r = (kG).x, s = (z + r*d) / k, negated if in the upper half
+func signECDSA(privKey, k [32]byte, digest [32]byte) ([]byte, error) {
+	var d, kk, r, z btcecv2.ModNScalar
+	d.SetBytes(&privKey)
+	defer d.Zero()
+	if kk.SetBytes(&k) != 0 || kk.IsZero() {
+		return nil, &ScalarError{Name: "k", Err: ErrScalarOutOfRange}
+	}
+	defer kk.Zero()
+	R := PublicKeyFromPrivateKey(k)
+	var rx [32]byte
+	copy(rx[:], R[1:])
+	r.SetBytes(&rx)
+	z.SetBytes(&digest)
+	if r.IsZero() {
+		return nil, &ScalarError{Name: "r", Err: ErrZeroScalar}
+	}
+	s := new(btcecv2.ModNScalar).Mul2(&r, &d).Add(&z)
+	s.Mul(kk.InverseNonConst())
+	if s.IsZero() {
+		return nil, &ScalarError{Name: "s", Err: ErrZeroScalar}
+	}
+	if s.IsOverHalfOrder() {
+		s.Negate()
+	}
+	rb, sb := r.Bytes(), s.Bytes()
+	return append(rb[:], sb[:]...), nil
+}
@@ -0,0 +1,239 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// identity signs 32 byte messages as they are
+func identity(data []byte) [32]byte {
+	return [32]byte(data)
+}
+
+func TestSignDefaults(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	pub := PublicKeyFromPrivateKey(priv)
+	sig, err := Sign(priv, []byte("msg"), WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := SignMessage(priv, []byte("msg"), WithDeterministicNonce())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, expected[:]) {
+		t.Fatalf("got %x, SignMessage %x", sig, expected)
+	}
+	err = Verify(pub, []byte("msg"), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, []byte("other"), sig)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+// TestNonceDomains checks that signing one message in different
+// configurations never reuses a nonce, even without auxiliary randomness
+func TestNonceDomains(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 7
+	configs := map[string][]SignOption{
+		"lit":           nil,
+		"double-sha256": {WithHash(DoubleSHA256)},
+		"tagged":        {WithHash(TaggedSHA256("tag"))},
+		"even-y":        {WithNormalization(NormalizeEvenY)},
+		"bip340":        {WithScheme(SchemeBIP340)},
+		"ecdsa":         {WithScheme(SchemeECDSA)},
+	}
+	for _, aux := range []func() SignOption{
+		WithDeterministicNonce,
+		func() SignOption { return WithAuxRand(bytes.NewReader(make([]byte, 32))) },
+	} {
+		seen := make(map[string]string)
+		for name, opts := range configs {
+			sig, err := Sign(priv, []byte("msg"), append(opts, aux())...)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			// the X coordinate of R, or r
+			x := sig[:32]
+			if len(sig) == 65 {
+				x = sig[1:33]
+			}
+			if other, ok := seen[string(x)]; ok {
+				t.Fatalf("%s and %s share R %x", name, other, x)
+			}
+			seen[string(x)] = name
+		}
+	}
+}
+
+func TestSignFixedNonceEvenY(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 3, 5
+	s, err := ComputeSignatureXOnly(priv, k, []byte("msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Sign(priv, []byte("msg"), WithNonceDerivation(FixedNonce(k)), WithNormalization(NormalizeEvenY))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig[33:], s[:]) || sig[0] != 0x02 {
+		t.Fatalf("got %x, expected s %x", sig, s)
+	}
+	pub := PublicKeyFromPrivateKey(priv)
+	err = Verify(pub, []byte("msg"), sig, WithNormalization(NormalizeEvenY))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSignBIP340(t *testing.T) {
+	// Test vector 0 of BIP-340, with its nonce derivation
+	var priv, aux [32]byte
+	priv[31] = 3
+	bip340Nonce := func(d [32]byte, msg []byte) ([32]byte, error) {
+		d = evenY(d)
+		t := taggedHash("BIP0340/aux", aux[:])
+		for i := range t {
+			t[i] ^= d[i]
+		}
+		P := PublicKeyFromPrivateKey(d)
+		return taggedHash("BIP0340/nonce", t[:], P[1:], msg), nil
+	}
+	msg := make([]byte, 32)
+	sig, err := Sign(priv, msg, WithScheme(SchemeBIP340), WithHash(identity), WithNonceDerivation(bip340Nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "e907831f80848d1069a5371b402410364bdf1c5f8307b0084c55f1ce2dca8215" +
+		"25f66a4a85ea8b71e482a74f382d2ce5ebeee8fdb2172f477df4900d310536c0"
+	if hex.EncodeToString(sig) != expected {
+		t.Fatalf("got %x", sig)
+	}
+	pub := PublicKeyFromPrivateKey(priv)
+	err = Verify(pub, msg, sig, WithScheme(SchemeBIP340), WithHash(identity))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// With the default options the message is hashed first
+	sig, err = Sign(priv, []byte("msg"), WithScheme(SchemeBIP340))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, []byte("msg"), sig, WithScheme(SchemeBIP340))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, []byte("msg"), sig)
+	if err == nil {
+		t.Fatal("bip340 signature verified as lit")
+	}
+}
+
+func TestSignECDSA(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 9
+	digest := SHA256([]byte("msg"))
+	rfc6979 := func(d [32]byte, msg []byte) ([32]byte, error) {
+		return secp256k1.NonceRFC6979(d[:], digest[:], nil, nil, 0).Bytes(), nil
+	}
+	sig, err := Sign(priv, []byte("msg"), WithScheme(SchemeECDSA), WithNonceDerivation(rfc6979))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := btcecv2.PrivKeyFromBytes(priv[:])
+	compact, err := ecdsa.SignCompact(key, digest[:], true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, compact[1:]) {
+		t.Fatalf("got %x, expected %x", sig, compact[1:])
+	}
+	pub := PublicKeyFromPrivateKey(priv)
+	err = Verify(pub, []byte("msg"), sig, WithScheme(SchemeECDSA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, []byte("other"), sig, WithScheme(SchemeECDSA))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestHashes(t *testing.T) {
+	data := []byte("abc")
+	single := SHA256(data)
+	double := DoubleSHA256(data)
+	if SHA256(single[:]) != double {
+		t.Fatal("double SHA-256 is not SHA-256 of SHA-256")
+	}
+	if TaggedSHA256(bip340ChallengeTag)(data) != taggedHash(bip340ChallengeTag, data) {
+		t.Fatal("tagged hash differs")
+	}
+}
+
+func TestAnticipationPoint(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 3, 5
+	pub, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	msg := []byte("outcome")
+	schemes := [][]SignOption{
+		nil,
+		{WithHash(DoubleSHA256)},
+		{WithHash(TaggedSHA256("DLC/oracle/attestation"))},
+		{WithNormalization(NormalizeEvenY), WithHash(DoubleSHA256)},
+		{WithScheme(SchemeBIP340)},
+	}
+	for i, opts := range schemes {
+		point, err := AnticipationPoint(pub, R, msg, opts...)
+		if err != nil {
+			t.Fatalf("scheme %d: %v", i, err)
+		}
+		// The point is s*G of the signature made with the same options
+		sig, err := Sign(priv, msg, append(opts, WithNonceDerivation(FixedNonce(k)))...)
+		if err != nil {
+			t.Fatalf("scheme %d: %v", i, err)
+		}
+		var s [32]byte
+		copy(s[:], sig[len(sig)-32:])
+		if PublicKeyFromPrivateKey(s) != point {
+			t.Fatalf("scheme %d: anticipation point doesn't match the signature", i)
+		}
+		err = Verify(pub, msg, sig, opts...)
+		if err != nil {
+			t.Fatalf("scheme %d: %v", i, err)
+		}
+	}
+
+	// The default matches ComputeSignaturePubKey, and other hashes differ
+	expected, err := ComputeSignaturePubKey(pub, R, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := AnticipationPoint(pub, R, msg)
+	if err != nil || point != expected {
+		t.Fatalf("got %x, %v", point, err)
+	}
+	point, err = AnticipationPoint(pub, R, msg, WithHash(DoubleSHA256))
+	if err != nil || point == expected {
+		t.Fatalf("hash option ignored: %v", err)
+	}
+	_, err = AnticipationPoint(pub, R, msg, WithScheme(SchemeECDSA))
+	if err == nil {
+		t.Fatal("ecdsa anticipation point computed")
+	}
+}
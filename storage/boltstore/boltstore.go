@@ -0,0 +1,551 @@
+// Package boltstore implements storage.Store on top of a bbolt database,
+// so an oracle keeps its events, one-time signing keys, announcements and
+// attestations across restarts.
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket         = []byte("meta")
+	noncesBucket       = []byte("nonces")
+	announcementBucket = []byte("announcements")
+	attestationBucket  = []byte("attestations")
+	revocationBucket   = []byte("revocations")
+	assignmentBucket   = []byte("assignments")
+	evidenceBucket     = []byte("evidence")
+	reservationBucket  = []byte("reservations")
+	timeBucket         = []byte("times")
+
+	versionKey    = []byte("version")
+	nonceIndexKey = []byte("nonceindex")
+	dataKeyKey    = []byte("datakey")
+)
+
+// migrations[i] upgrades a database from schema version i to version i+1.
+// A new database starts at version 0. Migrations only ever get appended.
+var migrations = []func(tx *bolt.Tx) error{
+	migrateInitial,
+	migrateRevocations,
+	migrateAssignments,
+	migrateEvidence,
+	migrateReservations,
+	migrateTimes,
+}
+
+// schemaVersion is the schema version this package reads and writes
+var schemaVersion = uint64(len(migrations))
+
+// Store is a storage.Store backed by a bbolt database
+type Store struct {
+	db     *bolt.DB
+	sealer *seal.Sealer
+}
+
+var (
+	_ storage.Store            = (*Store)(nil)
+	_ storage.RevocationStore  = (*Store)(nil)
+	_ storage.EvidenceStore    = (*Store)(nil)
+	_ storage.LedgerStore      = (*Store)(nil)
+	_ storage.NonceIndexStore  = (*Store)(nil)
+	_ storage.EncryptingStore  = (*Store)(nil)
+	_ storage.ReservationStore = (*Store)(nil)
+	_ storage.TimestampStore   = (*Store)(nil)
+)
+
+// Open opens or creates the database at path and migrates it to the
+// current schema version. The database contains the oracle's one-time
+// signing keys, so it is created readable by the owner only.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(migrate)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Version returns the schema version stored in the database
+func (s *Store) Version() (uint64, error) {
+	var v uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v = version(tx)
+		return nil
+	})
+	return v, err
+}
+
+func version(tx *bolt.Tx) uint64 {
+	b := tx.Bucket(metaBucket)
+	if b == nil {
+		return 0
+	}
+	v := b.Get(versionKey)
+	if len(v) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(v)
+}
+
+// migrate runs all migrations the database hasn't seen yet in a single
+// transaction, so a failed migration leaves the database untouched
+func migrate(tx *bolt.Tx) error {
+	v := version(tx)
+	if v > schemaVersion {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", v, schemaVersion)
+	}
+	for ; v < schemaVersion; v++ {
+		err := migrations[v](tx)
+		if err != nil {
+			return fmt.Errorf("migrating database to version %d: %w", v+1, err)
+		}
+	}
+	meta, err := tx.CreateBucketIfNotExists(metaBucket)
+	if err != nil {
+		return err
+	}
+	return meta.Put(versionKey, uint64Bytes(v))
+}
+
+func migrateInitial(tx *bolt.Tx) error {
+	for _, name := range [][]byte{metaBucket, noncesBucket, announcementBucket, attestationBucket} {
+		_, err := tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateRevocations(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(revocationBucket)
+	return err
+}
+
+func migrateAssignments(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(assignmentBucket)
+	return err
+}
+
+func migrateEvidence(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(evidenceBucket)
+	return err
+}
+
+func migrateReservations(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(reservationBucket)
+	return err
+}
+
+func migrateTimes(tx *bolt.Tx) error {
+	_, err := tx.CreateBucketIfNotExists(timeBucket)
+	return err
+}
+
+func uint64Bytes(i uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], i)
+	return b[:]
+}
+
+// NextNonceIndex returns the next unused one-time signing key index
+func (s *Store) NextNonceIndex() (uint64, error) {
+	var i uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		v := meta.Get(nonceIndexKey)
+		if len(v) == 8 {
+			i = binary.BigEndian.Uint64(v)
+		}
+		return meta.Put(nonceIndexKey, uint64Bytes(i+1))
+	})
+	return i, err
+}
+
+// NonceIndex returns the next unused one-time signing key index without
+// using it
+func (s *Store) NonceIndex() (uint64, error) {
+	var i uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(metaBucket).Get(nonceIndexKey)
+		if len(v) == 8 {
+			i = binary.BigEndian.Uint64(v)
+		}
+		return nil
+	})
+	return i, err
+}
+
+// Ping implements storage.Pinger by reading from the database, which
+// fails once it is closed
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(metaBucket) == nil {
+			return fmt.Errorf("meta bucket missing")
+		}
+		return nil
+	})
+}
+
+// RaiseNonceIndex makes i the next unused one-time signing key index,
+// unless a later one is
+func (s *Store) RaiseNonceIndex(i uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		v := meta.Get(nonceIndexKey)
+		if len(v) == 8 && binary.BigEndian.Uint64(v) >= i {
+			return nil
+		}
+		return meta.Put(nonceIndexKey, uint64Bytes(i))
+	})
+}
+
+// Encrypt implements storage.EncryptingStore. The wrapped data key is
+// kept in the meta bucket, and signing keys stored before the first call
+// are encrypted in place.
+func (s *Store) Encrypt(ctx context.Context, w seal.KeyWrapper) error {
+	var wrapped []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(dataKeyKey); v != nil {
+			wrapped = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sealer, newWrapped, err := seal.New(ctx, w, wrapped)
+	if err != nil {
+		return fmt.Errorf("unlocking store: %w", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if wrapped == nil {
+			err := tx.Bucket(metaBucket).Put(dataKeyKey, newWrapped)
+			if err != nil {
+				return err
+			}
+		}
+		b := tx.Bucket(noncesBucket)
+		sealed := make(map[string][]byte)
+		err := b.ForEach(func(k, v []byte) error {
+			if len(v) != 32 {
+				return nil
+			}
+			enc, err := sealer.Seal(v, k)
+			if err != nil {
+				return err
+			}
+			sealed[string(k)] = enc
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range sealed {
+			err := b.Put([]byte(k), v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.sealer = sealer
+	return nil
+}
+
+// PutNonce stores the one-time signing key for an event
+func (s *Store) PutNonce(eventID string, key [32]byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(noncesBucket)
+		if b.Get([]byte(eventID)) != nil {
+			return storage.ErrExists
+		}
+		if s.sealer == nil {
+			if tx.Bucket(metaBucket).Get(dataKeyKey) != nil {
+				return storage.ErrEncrypted
+			}
+			return b.Put([]byte(eventID), key[:])
+		}
+		v, err := s.sealer.Seal(key[:], []byte(eventID))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(eventID), v)
+	})
+}
+
+// Nonce returns the one-time signing key for an event
+func (s *Store) Nonce(eventID string) ([32]byte, error) {
+	var k [32]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(noncesBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		if len(v) == seal.SealedSize(32) {
+			if s.sealer == nil {
+				return storage.ErrEncrypted
+			}
+			var err error
+			v, err = s.sealer.Open(v, []byte(eventID))
+			if err != nil {
+				return fmt.Errorf("stored nonce for %s: %w", eventID, err)
+			}
+		}
+		if len(v) != 32 {
+			return fmt.Errorf("stored nonce for %s has invalid length %d", eventID, len(v))
+		}
+		copy(k[:], v)
+		return nil
+	})
+	return k, err
+}
+
+// PutAnnouncement stores an announcement, replacing any existing
+// announcement for the same event
+func (s *Store) PutAnnouncement(a dlcoracle.Announcement) error {
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(announcementBucket).Put([]byte(a.EventID), v)
+		if err != nil {
+			return err
+		}
+		return putTime(tx, "announcement/"+a.EventID)
+	})
+}
+
+// Announcement returns the announcement for the given event
+func (s *Store) Announcement(eventID string) (dlcoracle.Announcement, error) {
+	var a dlcoracle.Announcement
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(announcementBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		return json.Unmarshal(v, &a)
+	})
+	return a, err
+}
+
+// Announcements returns all announcements ordered by maturity
+func (s *Store) Announcements() ([]dlcoracle.Announcement, error) {
+	var list []dlcoracle.Announcement
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(announcementBucket).ForEach(func(k, v []byte) error {
+			var a dlcoracle.Announcement
+			err := json.Unmarshal(v, &a)
+			if err != nil {
+				return fmt.Errorf("decoding announcement %s: %w", k, err)
+			}
+			list = append(list, a)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	storage.SortAnnouncements(list)
+	return list, nil
+}
+
+// PutAttestation stores an attestation
+func (s *Store) PutAttestation(a dlcoracle.Attestation) error {
+	v, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(attestationBucket)
+		if b.Get([]byte(a.EventID)) != nil {
+			return storage.ErrExists
+		}
+		err := b.Put([]byte(a.EventID), v)
+		if err != nil {
+			return err
+		}
+		return putTime(tx, "attestation/"+a.EventID)
+	})
+}
+
+// Attestation returns the attestation for the given event
+func (s *Store) Attestation(eventID string) (dlcoracle.Attestation, error) {
+	var a dlcoracle.Attestation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(attestationBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		return json.Unmarshal(v, &a)
+	})
+	return a, err
+}
+
+// PutRevocation stores a revocation
+func (s *Store) PutRevocation(r dlcoracle.Revocation) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(revocationBucket)
+		if b.Get([]byte(r.EventID)) != nil {
+			return storage.ErrExists
+		}
+		return b.Put([]byte(r.EventID), v)
+	})
+}
+
+// Revocation returns the revocation of the given event
+func (s *Store) Revocation(eventID string) (dlcoracle.Revocation, error) {
+	var r dlcoracle.Revocation
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(revocationBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		return json.Unmarshal(v, &r)
+	})
+	return r, err
+}
+
+// PutEvidence stores the evidence of an attestation
+func (s *Store) PutEvidence(e dlcoracle.Evidence) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(evidenceBucket)
+		if b.Get([]byte(e.EventID)) != nil {
+			return storage.ErrExists
+		}
+		return b.Put([]byte(e.EventID), v)
+	})
+}
+
+// Evidence returns the evidence of the attestation of the given event
+func (s *Store) Evidence(eventID string) (dlcoracle.Evidence, error) {
+	var e dlcoracle.Evidence
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(evidenceBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		return json.Unmarshal(v, &e)
+	})
+	return e, err
+}
+
+// PutAssignment records the event a one-time signing key was assigned to.
+// It is stored as the event commitment followed by the event ID.
+func (s *Store) PutAssignment(a storage.NonceAssignment) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(assignmentBucket)
+		if b.Get(a.RPoint[:]) != nil {
+			return storage.ErrExists
+		}
+		return b.Put(a.RPoint[:], append(a.Commitment[:], a.EventID...))
+	})
+}
+
+// Assignment returns the assignment of the one-time signing key with the
+// given point
+func (s *Store) Assignment(rPoint [33]byte) (storage.NonceAssignment, error) {
+	a := storage.NonceAssignment{RPoint: rPoint}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(assignmentBucket).Get(rPoint[:])
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		if len(v) < 32 {
+			return fmt.Errorf("stored assignment of %x has invalid length %d", rPoint, len(v))
+		}
+		copy(a.Commitment[:], v)
+		a.EventID = string(v[32:])
+		return nil
+	})
+	return a, err
+}
+
+// PutReservation reserves the outcome message of an event
+func (s *Store) PutReservation(eventID string, message []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(reservationBucket)
+		if b.Get([]byte(eventID)) != nil {
+			return storage.ErrExists
+		}
+		return b.Put([]byte(eventID), message)
+	})
+}
+
+// Reservation returns the outcome message reserved for an event
+func (s *Store) Reservation(eventID string) ([]byte, error) {
+	var m []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(reservationBucket).Get([]byte(eventID))
+		if v == nil {
+			return storage.ErrNotFound
+		}
+		m = append([]byte{}, v...)
+		return nil
+	})
+	return m, err
+}
+
+// putTime stamps the record stored under key in the times bucket, keyed
+// by the record's kind and event ID
+func putTime(tx *bolt.Tx, key string) error {
+	return tx.Bucket(timeBucket).Put([]byte(key), uint64Bytes(uint64(storage.StoredAt().Unix())))
+}
+
+// AnnouncementTime returns when the announcement of an event was stored
+func (s *Store) AnnouncementTime(eventID string) (time.Time, error) {
+	return s.storedAt(announcementBucket, "announcement/", eventID)
+}
+
+// AttestationTime returns when the attestation of an event was stored
+func (s *Store) AttestationTime(eventID string) (time.Time, error) {
+	return s.storedAt(attestationBucket, "attestation/", eventID)
+}
+
+// storedAt returns the time of a record in bucket, or the zero time if it
+// was stored before the times bucket existed
+func (s *Store) storedAt(bucket []byte, prefix, eventID string) (time.Time, error) {
+	var t time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucket).Get([]byte(eventID)) == nil {
+			return storage.ErrNotFound
+		}
+		v := tx.Bucket(timeBucket).Get([]byte(prefix + eventID))
+		if len(v) == 8 {
+			t = time.Unix(int64(binary.BigEndian.Uint64(v)), 0).UTC()
+		}
+		return nil
+	})
+	return t, err
+}
@@ -0,0 +1,364 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTemp(t *testing.T) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "oracle.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestPersistence(t *testing.T) {
+	s, path := openTemp(t)
+
+	i, err := s.NextNonceIndex()
+	if err != nil || i != 0 {
+		t.Fatalf("unexpected first nonce index %d %v", i, err)
+	}
+	err = s.PutNonce("event", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := dlcoracle.Announcement{
+		EventID:  "event",
+		Maturity: time.Unix(1700000000, 0).UTC(),
+		Descriptor: dlcoracle.EventDescriptor{
+			Type:     dlcoracle.EventTypeEnum,
+			Outcomes: []string{"yes", "no"},
+		},
+	}
+	a.RPoint[0] = 2
+	err = s.PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	att := dlcoracle.Attestation{EventID: "event", Message: []byte("yes"), Signature: [32]byte{3}}
+	err = s.PutAttestation(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	i, err = s.NextNonceIndex()
+	if err != nil || i != 1 {
+		t.Fatalf("nonce index was not persisted: %d %v", i, err)
+	}
+	k, err := s.Nonce("event")
+	if err != nil || k != [32]byte{1} {
+		t.Fatalf("unexpected nonce %x %v", k, err)
+	}
+	got, err := s.Announcement("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, a) {
+		t.Fatalf("announcement mismatch: %+v", got)
+	}
+	gotAtt, err := s.Attestation("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotAtt, att) {
+		t.Fatalf("attestation mismatch: %+v", gotAtt)
+	}
+	list, err := s.Announcements()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("unexpected announcements %v %v", list, err)
+	}
+}
+
+func TestWriteOnce(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	err := s.PutNonce("event", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutNonce("event", [32]byte{2})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	_, err = s.Announcement("missing")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	s, path := openTemp(t)
+	v, err := s.Version()
+	if err != nil || v != schemaVersion {
+		t.Fatalf("unexpected version %d %v", v, err)
+	}
+
+	// A database written by a newer version must not be opened
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(versionKey, uint64Bytes(schemaVersion+1))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	_, err = Open(path)
+	if err == nil {
+		t.Fatal("opened database with a newer schema version")
+	}
+}
+
+func TestRevocations(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	_, err := s.Revocation("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	r := dlcoracle.Revocation{
+		EventID:   "event",
+		Reason:    "cancelled",
+		RevokedAt: time.Unix(1700000000, 0).UTC(),
+	}
+	err = s.PutRevocation(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutRevocation(dlcoracle.Revocation{EventID: "event"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Revocation("event")
+	if err != nil || got != r {
+		t.Fatalf("unexpected revocation %+v %v", got, err)
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	_, err := s.Evidence("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	e := dlcoracle.Evidence{
+		EventID: "event",
+		Responses: []dlcoracle.UpstreamResponse{{
+			Source:      "kraken",
+			URL:         "https://api.kraken.com/0/public/Ticker?pair=XBTUSD",
+			Status:      200,
+			Body:        []byte(`{"result":{}}`),
+			ReceivedAt:  time.UnixMilli(1700000000123).UTC(),
+			RequestedAt: time.UnixMilli(1700000000000).UTC(),
+		}},
+		CapturedAt: time.UnixMilli(1700000001000).UTC(),
+	}
+	err = s.PutEvidence(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutEvidence(dlcoracle.Evidence{EventID: "event"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Evidence("event")
+	if err != nil || got.SigningHash() != e.SigningHash() {
+		t.Fatalf("unexpected evidence %+v %v", got, err)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	_, err := s.Assignment([33]byte{2})
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	a := storage.NonceAssignment{RPoint: [33]byte{2}, EventID: "event", Commitment: [32]byte{1}}
+	err = s.PutAssignment(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAssignment(storage.NonceAssignment{RPoint: [33]byte{2}, EventID: "other"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Assignment([33]byte{2})
+	if err != nil || got != a {
+		t.Fatalf("unexpected assignment %+v %v", got, err)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	_, err := s.Reservation("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	err = s.PutReservation("event", []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutReservation("event", []byte{3})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Reservation("event")
+	if err != nil || string(got) != string([]byte{1, 2}) {
+		t.Fatalf("unexpected reservation %x %v", got, err)
+	}
+}
+
+func TestTimes(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	_, err := s.AnnouncementTime("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	before := time.Now().Add(-time.Second)
+	err = s.PutAnnouncement(dlcoracle.Announcement{EventID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event", Message: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, get := range []func(string) (time.Time, error){s.AnnouncementTime, s.AttestationTime} {
+		stored, err := get("event")
+		if err != nil || stored.Before(before) || stored.After(time.Now()) || stored.Nanosecond() != 0 {
+			t.Fatalf("stored at %s, %v", stored, err)
+		}
+	}
+}
+
+func TestNonceIndex(t *testing.T) {
+	s, _ := openTemp(t)
+	defer s.Close()
+
+	s.NextNonceIndex()
+	err := s.RaiseNonceIndex(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RaiseNonceIndex(3)
+	i, err := s.NonceIndex()
+	if err != nil || i != 5 {
+		t.Fatalf("unexpected nonce index %d %v", i, err)
+	}
+	i, _ = s.NextNonceIndex()
+	if i != 5 {
+		t.Fatalf("raised index %d not used next", i)
+	}
+}
+
+func TestPing(t *testing.T) {
+	s, _ := openTemp(t)
+	err := s.Ping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if s.Ping(context.Background()) == nil {
+		t.Fatal("closed store reachable")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	s, path := openTemp(t)
+	legacy := [32]byte{1, 2, 3}
+	err := s.PutNonce("legacy", legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Encrypt(ctx, seal.Passphrase([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := [32]byte{4, 5, 6}
+	err = s.PutNonce("event", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string][32]byte{"legacy": legacy, "event": key} {
+		k, err := s.Nonce(id)
+		if err != nil || k != want {
+			t.Fatalf("nonce %s: %x %v", id, k, err)
+		}
+	}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(noncesBucket).ForEach(func(k, v []byte) error {
+			if bytes.Contains(v, legacy[:3]) || bytes.Contains(v, key[:3]) {
+				t.Errorf("nonce %s stored in plaintext", k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// a copy of the database doesn't reveal the keys
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Nonce("event")
+	if !errors.Is(err, storage.ErrEncrypted) {
+		t.Fatalf("read without key: %v", err)
+	}
+	err = s.PutNonce("other", key)
+	if !errors.Is(err, storage.ErrEncrypted) {
+		t.Fatalf("plaintext write to encrypted store: %v", err)
+	}
+	err = s.Encrypt(ctx, seal.Passphrase([]byte("wrong")))
+	if !errors.Is(err, seal.ErrDecrypt) {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+	err = s.Encrypt(ctx, seal.Passphrase([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := s.Nonce("event")
+	if err != nil || k != key {
+		t.Fatalf("nonce after reopening: %x %v", k, err)
+	}
+	s.Close()
+}
@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// SnapshotVersion is the version of the snapshot format Export writes
+const SnapshotVersion = 1
+
+// ErrConflict is returned when importing a record that differs from the
+// one in the store
+var ErrConflict = errors.New("conflicting record")
+
+// Snapshot is the state of an oracle exported from a store: everything it
+// published and its nonce index. It contains no keys; the one-time signing
+// keys of the events can be derived again from the oracle's private key
+// with oracle.Recover.
+type Snapshot struct {
+	Version       int                      `json:"version"`
+	NonceIndex    uint64                   `json:"nonceIndex"`
+	Announcements []dlcoracle.Announcement `json:"announcements"`
+	Attestations  []dlcoracle.Attestation  `json:"attestations"`
+	Revocations   []dlcoracle.Revocation   `json:"revocations,omitempty"`
+	Evidence      []dlcoracle.Evidence     `json:"evidence,omitempty"`
+}
+
+// ConflictError lists the records of a snapshot that differ from the ones
+// in the store it was imported into
+type ConflictError struct {
+	Records []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrConflict, strings.Join(e.Records, ", "))
+}
+
+// Unwrap returns ErrConflict
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// Export returns the state of the oracle kept in s. The store has to
+// implement NonceIndexStore.
+func Export(s Store) (Snapshot, error) {
+	snap := Snapshot{Version: SnapshotVersion}
+	ns, ok := s.(NonceIndexStore)
+	if !ok {
+		return snap, fmt.Errorf("store doesn't report its nonce index")
+	}
+	var err error
+	snap.NonceIndex, err = ns.NonceIndex()
+	if err != nil {
+		return snap, err
+	}
+	snap.Announcements, err = s.Announcements()
+	if err != nil {
+		return snap, err
+	}
+	rs, _ := s.(RevocationStore)
+	es, _ := s.(EvidenceStore)
+	for _, a := range snap.Announcements {
+		att, err := s.Attestation(a.EventID)
+		if err == nil {
+			snap.Attestations = append(snap.Attestations, att)
+		} else if err != ErrNotFound {
+			return snap, err
+		}
+		if es != nil {
+			e, err := es.Evidence(a.EventID)
+			if err == nil {
+				snap.Evidence = append(snap.Evidence, e)
+			} else if err != ErrNotFound {
+				return snap, err
+			}
+		}
+		if rs == nil {
+			continue
+		}
+		r, err := rs.Revocation(a.EventID)
+		if err == nil {
+			snap.Revocations = append(snap.Revocations, r)
+		} else if err != ErrNotFound {
+			return snap, err
+		}
+	}
+	return snap, nil
+}
+
+// Import writes the records of a snapshot missing from s to it and raises
+// its nonce index to the snapshot's. Every record is verified against its
+// announcement first, and nothing is written if any of them differs from
+// the record for the same event in s, or if an event would end up both
+// attested and revoked; the error is then a *ConflictError. The store has
+// to implement NonceIndexStore, RevocationStore if the snapshot contains
+// revocations and EvidenceStore if it contains evidence.
+func Import(s Store, snap Snapshot) error {
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	ns, ok := s.(NonceIndexStore)
+	if !ok {
+		return fmt.Errorf("store can't raise its nonce index")
+	}
+	rs, ok := s.(RevocationStore)
+	if !ok && len(snap.Revocations) != 0 {
+		return fmt.Errorf("store can't keep revocations")
+	}
+	es, ok := s.(EvidenceStore)
+	if !ok && len(snap.Evidence) != 0 {
+		return fmt.Errorf("store can't keep evidence")
+	}
+
+	var conflicts []string
+	announcements := make(map[string]dlcoracle.Announcement, len(snap.Announcements))
+	var newAnnouncements []dlcoracle.Announcement
+	for _, a := range snap.Announcements {
+		err := a.Verify()
+		if err != nil {
+			return fmt.Errorf("announcement %s: %w", a.EventID, err)
+		}
+		announcements[a.EventID] = a
+		stored, err := s.Announcement(a.EventID)
+		if err == ErrNotFound {
+			newAnnouncements = append(newAnnouncements, a)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if stored.SigningHash() != a.SigningHash() || stored.Signature != a.Signature {
+			conflicts = append(conflicts, "announcement "+a.EventID)
+		}
+	}
+
+	attested := make(map[string]bool)
+	var newAttestations []dlcoracle.Attestation
+	for _, att := range snap.Attestations {
+		a, ok := announcements[att.EventID]
+		if !ok {
+			return fmt.Errorf("attestation %s: event not in the snapshot", att.EventID)
+		}
+		_, err := dlcoracle.VerifyAttestation(a, att)
+		if err != nil {
+			return fmt.Errorf("attestation %s: %w", att.EventID, err)
+		}
+		attested[att.EventID] = true
+		stored, err := s.Attestation(att.EventID)
+		if err == ErrNotFound {
+			newAttestations = append(newAttestations, att)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !sameAttestation(stored, att) {
+			conflicts = append(conflicts, "attestation "+att.EventID)
+		}
+	}
+
+	revoked := make(map[string]bool)
+	var newRevocations []dlcoracle.Revocation
+	for _, r := range snap.Revocations {
+		a, ok := announcements[r.EventID]
+		if !ok {
+			return fmt.Errorf("revocation %s: event not in the snapshot", r.EventID)
+		}
+		err := dlcoracle.VerifyRevocation(a, r)
+		if err != nil {
+			return fmt.Errorf("revocation %s: %w", r.EventID, err)
+		}
+		revoked[r.EventID] = true
+		stored, err := rs.Revocation(r.EventID)
+		if err == ErrNotFound {
+			newRevocations = append(newRevocations, r)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if stored.SigningHash() != r.SigningHash() || stored.Signature != r.Signature {
+			conflicts = append(conflicts, "revocation "+r.EventID)
+		}
+	}
+
+	var newEvidence []dlcoracle.Evidence
+	for _, e := range snap.Evidence {
+		a, ok := announcements[e.EventID]
+		if !ok {
+			return fmt.Errorf("evidence %s: event not in the snapshot", e.EventID)
+		}
+		if e.OraclePubKey != a.OraclePubKey {
+			return fmt.Errorf("evidence %s: signed by another oracle", e.EventID)
+		}
+		err := e.Verify()
+		if err != nil {
+			return err
+		}
+		stored, err := es.Evidence(e.EventID)
+		if err == ErrNotFound {
+			newEvidence = append(newEvidence, e)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if stored.SigningHash() != e.SigningHash() || stored.Signature != e.Signature {
+			conflicts = append(conflicts, "evidence "+e.EventID)
+		}
+	}
+
+	// An event must not be attested in one store and revoked in the other
+	for _, a := range snap.Announcements {
+		if !attested[a.EventID] && !revoked[a.EventID] {
+			continue
+		}
+		_, err := s.Attestation(a.EventID)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		isAttested := attested[a.EventID] || err == nil
+		isRevoked := revoked[a.EventID]
+		if rs != nil && !isRevoked {
+			_, err = rs.Revocation(a.EventID)
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+			isRevoked = err == nil
+		}
+		if isAttested && isRevoked {
+			conflicts = append(conflicts, "event "+a.EventID+" attested and revoked")
+		}
+	}
+	if len(conflicts) != 0 {
+		return &ConflictError{Records: conflicts}
+	}
+
+	for _, a := range newAnnouncements {
+		err := s.PutAnnouncement(a)
+		if err != nil {
+			return err
+		}
+	}
+	for _, att := range newAttestations {
+		err := s.PutAttestation(att)
+		if err != nil {
+			return err
+		}
+	}
+	for _, r := range newRevocations {
+		err := rs.PutRevocation(r)
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range newEvidence {
+		err := es.PutEvidence(e)
+		if err != nil {
+			return err
+		}
+	}
+	return ns.RaiseNonceIndex(snap.NonceIndex)
+}
+
+func sameAttestation(a, b dlcoracle.Attestation) bool {
+	if !bytes.Equal(a.Message, b.Message) || a.Signature != b.Signature ||
+		len(a.Signatures) != len(b.Signatures) {
+		return false
+	}
+	for i := range a.Signatures {
+		if a.Signatures[i] != b.Signatures[i] {
+			return false
+		}
+	}
+	return true
+}
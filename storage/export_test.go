@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// testState fills a store with n signed announcements, attests to the
+// first and revokes the second
+func testState(t *testing.T, n int) *MemoryStore {
+	t.Helper()
+	var priv [32]byte
+	priv[31] = 1
+	s := NewMemoryStore()
+	for i := 0; i < n; i++ {
+		idx, _ := s.NextNonceIndex()
+		k, err := dlcoracle.DeriveOneTimeSigningKey(priv, idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a := dlcoracle.Announcement{
+			EventID:      fmt.Sprintf("event-%d", i),
+			OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(priv),
+			RPoint:       dlcoracle.PublicKeyFromPrivateKey(k),
+			Maturity:     time.Unix(int64(1000*(i+1)), 0).UTC(),
+		}
+		err = a.Sign(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.PutNonce(a.EventID, k)
+		s.PutAnnouncement(a)
+		switch i {
+		case 0:
+			att, err := dlcoracle.SignOutcome(priv, k, a, dlcoracle.Outcome{Value: 42})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.PutAttestation(att)
+		case 1:
+			r := dlcoracle.Revocation{EventID: a.EventID, OraclePubKey: a.OraclePubKey,
+				Reason: "cancelled", RevokedAt: time.Unix(500, 0).UTC()}
+			err = r.Sign(priv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.PutRevocation(r)
+		}
+	}
+	return s
+}
+
+func TestExportImport(t *testing.T) {
+	src := testState(t, 3)
+	snap, err := Export(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Snapshot
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.NonceIndex != 3 || len(decoded.Announcements) != 3 ||
+		len(decoded.Attestations) != 1 || len(decoded.Revocations) != 1 {
+		t.Fatalf("unexpected snapshot %s", b)
+	}
+
+	dst := NewMemoryStore()
+	err = Import(dst, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Importing again changes nothing
+	err = Import(dst, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Export(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, _ := json.Marshal(again)
+	if string(b) != string(b2) {
+		t.Fatalf("imported state differs:\n%s\n%s", b, b2)
+	}
+	_, err = dst.Nonce("event-2")
+	if err != ErrNotFound {
+		t.Fatal("snapshot contained a one-time signing key")
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	snap, err := Export(testState(t, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A store that replaced an announcement and revoked an attested event
+	dst := NewMemoryStore()
+	partial := snap
+	partial.Announcements = snap.Announcements[:2]
+	err = Import(dst, partial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := dst.Announcement("event-0")
+	other.Maturity = other.Maturity.Add(time.Hour)
+	var priv [32]byte
+	priv[31] = 1
+	other.Sign(priv)
+	dst.PutAnnouncement(other)
+	r := dlcoracle.Revocation{EventID: "event-0", OraclePubKey: other.OraclePubKey, RevokedAt: time.Unix(600, 0).UTC()}
+	r.Sign(priv)
+	dst.PutRevocation(r)
+
+	err = Import(dst, snap)
+	var ce *ConflictError
+	if !errors.As(err, &ce) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if len(ce.Records) != 2 {
+		t.Fatalf("unexpected conflicts %v", ce.Records)
+	}
+	_, err = dst.Announcement("event-2")
+	if err != ErrNotFound {
+		t.Fatal("conflicting import wrote to the store")
+	}
+
+	// Forged records are refused
+	snap.Attestations[0].Message = dlcoracle.GenerateNumericMessage(43)
+	err = Import(NewMemoryStore(), snap)
+	if err == nil || errors.Is(err, ErrConflict) {
+		t.Fatalf("expected an invalid attestation, got %v", err)
+	}
+}
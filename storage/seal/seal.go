@@ -0,0 +1,110 @@
+// Package seal encrypts the one-time signing keys an oracle stores, so
+// that a copy of its database alone doesn't reveal them.
+//
+// It uses envelope encryption: each store has a random data key that
+// encrypts its secrets with AES-256-GCM, and keeps that key only in
+// wrapped form. A KeyWrapper wraps it with a key encryption key held
+// elsewhere, derived from the operator's passphrase (Passphrase) or kept
+// in a key management service (VaultTransit). Changing the wrapper only
+// requires wrapping the data key again, not re-encrypting every secret.
+package seal
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// version starts every sealed value
+const version = 1
+
+// DataKeySize is the size of data keys
+const DataKeySize = 32
+
+// ErrDecrypt is returned for values that don't decrypt, because the key
+// is wrong or the value was tampered with
+var ErrDecrypt = errors.New("seal: decryption failed")
+
+// KeyWrapper protects the data key of a store
+type KeyWrapper interface {
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Sealer encrypts and decrypts secrets with a data key
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// New returns a sealer for the data key wrapped by w. If wrapped is nil it
+// generates a new data key and returns it wrapped, for the caller to store
+// alongside the secrets.
+func New(ctx context.Context, w KeyWrapper, wrapped []byte) (*Sealer, []byte, error) {
+	var dataKey []byte
+	var err error
+	if wrapped == nil {
+		dataKey = make([]byte, DataKeySize)
+		_, err = rand.Read(dataKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		wrapped, err = w.Wrap(ctx, dataKey)
+	} else {
+		dataKey, err = w.Unwrap(ctx, wrapped)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(dataKey) != DataKeySize {
+		return nil, nil, fmt.Errorf("seal: data key has %d bytes", len(dataKey))
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Sealer{aead: aead}, wrapped, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts plaintext, authenticating ad along with it. Stores pass
+// the ID of the record as ad, so sealed values can't be swapped between
+// records.
+func (s *Sealer) Seal(plaintext, ad []byte) ([]byte, error) {
+	out := make([]byte, 1+s.aead.NonceSize(), 1+s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	out[0] = version
+	_, err := rand.Read(out[1:])
+	if err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(out, out[1:], plaintext, ad), nil
+}
+
+// Open decrypts a value returned by Seal with the same ad
+func (s *Sealer) Open(sealed, ad []byte) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < 1+n+s.aead.Overhead() || sealed[0] != version {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := s.aead.Open(nil, sealed[1:1+n], sealed[1+n:], ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// SealedSize returns the size of the sealed value of an n byte secret,
+// which stores use to tell sealed values from plaintext
+func SealedSize(n int) int {
+	// version, nonce and GCM tag
+	return 1 + 12 + n + 16
+}
@@ -0,0 +1,113 @@
+package seal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSealer(t *testing.T) {
+	ctx := context.Background()
+	w := Passphrase([]byte("secret"))
+	s, wrapped, err := New(ctx, w, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := bytes.Repeat([]byte{7}, 32)
+	sealed, err := s.Seal(secret, []byte("event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) != SealedSize(32) || bytes.Contains(sealed, secret[:8]) {
+		t.Fatalf("unexpected sealed value %x", sealed)
+	}
+	if again, _ := s.Seal(secret, []byte("event")); bytes.Equal(again, sealed) {
+		t.Fatal("sealing isn't randomized")
+	}
+
+	s2, wrapped2, err := New(ctx, w, wrapped)
+	if err != nil || !bytes.Equal(wrapped2, wrapped) {
+		t.Fatalf("unwrapping: %v", err)
+	}
+	got, err := s2.Open(sealed, []byte("event"))
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("open: %x %v", got, err)
+	}
+	_, err = s2.Open(sealed, []byte("other"))
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("value opened for another record: %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	_, err = s2.Open(sealed, []byte("event"))
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("tampered value opened: %v", err)
+	}
+
+	_, _, err = New(ctx, Passphrase([]byte("wrong")), wrapped)
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+	other, _, _ := New(ctx, w, nil)
+	sealed[len(sealed)-1] ^= 1
+	_, err = other.Open(sealed, []byte("event"))
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("value opened with another data key: %v", err)
+	}
+}
+
+// fakeVault implements the encrypt and decrypt endpoints of the transit
+// engine by prefixing the plaintext
+func fakeVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		var data map[string]string
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/oracle":
+			data = map[string]string{"ciphertext": "vault:v1:" + req["plaintext"]}
+		case "/v1/transit/decrypt/oracle":
+			data = map[string]string{"plaintext": strings.TrimPrefix(req["ciphertext"], "vault:v1:")}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+}
+
+func TestVaultTransit(t *testing.T) {
+	ctx := context.Background()
+	srv := fakeVault(t)
+	defer srv.Close()
+
+	v := &VaultTransit{Addr: srv.URL, Token: "token", Key: "oracle"}
+	key := bytes.Repeat([]byte{1}, DataKeySize)
+	wrapped, err := v.Wrap(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wrapped) != "vault:v1:"+base64.StdEncoding.EncodeToString(key) {
+		t.Fatalf("unexpected wrapped key %s", wrapped)
+	}
+	got, err := v.Unwrap(ctx, wrapped)
+	if err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("unwrap: %x %v", got, err)
+	}
+
+	v.Token = "bad"
+	_, err = v.Unwrap(ctx, wrapped)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
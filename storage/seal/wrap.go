@@ -0,0 +1,183 @@
+package seal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// passphraseTag marks data keys wrapped with a passphrase, and is
+// authenticated along with them
+const passphraseTag = "DLC/oracle/seal/passphrase"
+
+// The scrypt parameters of Passphrase, stored with the wrapped key so
+// they can be raised later
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+type passphrase []byte
+
+// Passphrase returns a wrapper deriving the key encryption key from pass
+// with scrypt. The salt and parameters are stored in the wrapped key.
+func Passphrase(pass []byte) KeyWrapper {
+	return passphrase(pass)
+}
+
+// wrappedPassphrase is the stored form of a key wrapped by Passphrase
+type wrappedPassphrase struct {
+	Type  string `json:"type"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Key   []byte `json:"key"`
+}
+
+func (p passphrase) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	w := wrappedPassphrase{Type: "scrypt", N: scryptN, R: scryptR, P: scryptP,
+		Salt: make([]byte, 16), Nonce: make([]byte, 12)}
+	_, err := rand.Read(w.Salt)
+	if err == nil {
+		_, err = rand.Read(w.Nonce)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kek, err := scrypt.Key(p, w.Salt, w.N, w.R, w.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	w.Key = aead.Seal(nil, w.Nonce, dataKey, []byte(passphraseTag))
+	return json.Marshal(w)
+}
+
+func (p passphrase) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var w wrappedPassphrase
+	err := json.Unmarshal(wrapped, &w)
+	if err != nil || w.Type != "scrypt" {
+		return nil, fmt.Errorf("seal: data key isn't wrapped with a passphrase")
+	}
+	kek, err := scrypt.Key(p, w.Salt, w.N, w.R, w.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(w.Nonce) != aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := aead.Open(nil, w.Nonce, w.Key, []byte(passphraseTag))
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong passphrase", ErrDecrypt)
+	}
+	return dataKey, nil
+}
+
+// VaultTransit wraps data keys with a key of the transit secrets engine
+// of HashiCorp Vault or OpenBao, which never reveals the key itself
+type VaultTransit struct {
+	// Addr is the address of the server, such as https://vault:8200
+	Addr string
+	// Token authenticates to the server
+	Token string
+	// Key names the transit key
+	Key string
+	// Mount is where the engine is mounted, "transit" if empty
+	Mount string
+
+	// Client makes the requests; a client with a 30 second timeout if nil
+	Client *http.Client
+}
+
+// call posts req to the transit endpoint op and decodes the data of the
+// response into res
+func (v *VaultTransit) call(ctx context.Context, op string, req, res interface{}) error {
+	mount := v.Mount
+	if mount == "" {
+		mount = "transit"
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(v.Addr, "/") + "/v1/" + mount + "/" + op + "/" + url.PathEscape(v.Key)
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("X-Vault-Token", v.Token)
+	r.Header.Set("Content-Type", "application/json")
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return fmt.Errorf("vault %s: %v", op, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("vault %s: %v", op, err)
+	}
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	json.Unmarshal(b, &envelope)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault %s: HTTP %d %s", op, resp.StatusCode, strings.Join(envelope.Errors, "; "))
+	}
+	err = json.Unmarshal(envelope.Data, res)
+	if err != nil {
+		return fmt.Errorf("vault %s: %v", op, err)
+	}
+	return nil
+}
+
+func (v *VaultTransit) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var res struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	err := v.call(ctx, "encrypt", map[string]string{
+		"plaintext": base64.StdEncoding.EncodeToString(dataKey),
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Ciphertext == "" {
+		return nil, fmt.Errorf("vault encrypt: no ciphertext")
+	}
+	return []byte(res.Ciphertext), nil
+}
+
+func (v *VaultTransit) Unwrap(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var res struct {
+		Plaintext string `json:"plaintext"`
+	}
+	err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &res)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Plaintext)
+}
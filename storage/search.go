@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// EventStatus is how far an event has come since it was announced
+type EventStatus uint8
+
+const (
+	// StatusAnnounced events are neither attested nor revoked yet
+	StatusAnnounced EventStatus = iota + 1
+	StatusAttested
+	StatusRevoked
+)
+
+// String returns the name of the status
+func (s EventStatus) String() string {
+	switch s {
+	case StatusAnnounced:
+		return "announced"
+	case StatusAttested:
+		return "attested"
+	case StatusRevoked:
+		return "revoked"
+	}
+	return fmt.Sprintf("status(%d)", uint8(s))
+}
+
+// ParseEventStatus returns the status named name
+func ParseEventStatus(name string) (EventStatus, error) {
+	for s := StatusAnnounced; s <= StatusRevoked; s++ {
+		if name == s.String() {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown event status %q", name)
+}
+
+// Query selects announcements. Its zero fields select every event.
+type Query struct {
+	// Unit is the asset pair of numeric and digits events, their
+	// descriptor's unit such as usd/btc
+	Unit string
+
+	// Types keeps events of any of the types
+	Types []dlcoracle.EventType
+
+	// MaturesAfter and MaturesBefore bound the maturity of events,
+	// inclusively and exclusively
+	MaturesAfter  time.Time
+	MaturesBefore time.Time
+
+	Status EventStatus
+
+	// Category and Tags match the events' metadata; events need every
+	// one of the tags
+	Category string
+	Tags     []string
+
+	// After continues a listing after the announcement at the cursor,
+	// and Limit returns at most that many announcements if positive
+	After Cursor
+	Limit int
+}
+
+// Cursor is the position of an announcement in the order of
+// Store.Announcements: by maturity, then by event ID. The zero cursor is
+// before every announcement.
+type Cursor struct {
+	Maturity time.Time
+	EventID  string
+}
+
+// CursorOf returns the position of the announcement
+func CursorOf(a dlcoracle.Announcement) Cursor {
+	return Cursor{Maturity: a.Maturity, EventID: a.EventID}
+}
+
+// NewCursor returns the position of the announcement of an event in s,
+// so a listing can continue after the last event a client received
+func NewCursor(s Store, eventID string) (Cursor, error) {
+	a, err := s.Announcement(eventID)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return CursorOf(a), nil
+}
+
+// IsZero reports whether c is before every announcement
+func (c Cursor) IsZero() bool {
+	return c.Maturity.IsZero() && c.EventID == ""
+}
+
+// Before reports whether a comes after c
+func (c Cursor) Before(a dlcoracle.Announcement) bool {
+	if c.IsZero() {
+		return true
+	}
+	if a.Maturity.Equal(c.Maturity) {
+		return a.EventID > c.EventID
+	}
+	return a.Maturity.After(c.Maturity)
+}
+
+// Match reports whether the announcement of an event with the status
+// is selected by q, ignoring Limit
+func (q Query) Match(a dlcoracle.Announcement, status EventStatus) bool {
+	if !q.After.Before(a) {
+		return false
+	}
+	if q.Unit != "" && a.Descriptor.Unit != q.Unit {
+		return false
+	}
+	if len(q.Types) != 0 {
+		found := false
+		for _, t := range q.Types {
+			found = found || a.Descriptor.Type == t
+		}
+		if !found {
+			return false
+		}
+	}
+	if !q.MaturesAfter.IsZero() && a.Maturity.Before(q.MaturesAfter) {
+		return false
+	}
+	if !q.MaturesBefore.IsZero() && !a.Maturity.Before(q.MaturesBefore) {
+		return false
+	}
+	if q.Status != 0 && status != q.Status {
+		return false
+	}
+	if q.Category != "" && a.Metadata.Category != q.Category {
+		return false
+	}
+	for _, t := range q.Tags {
+		if !a.Metadata.HasTag(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchStore is implemented by stores that answer queries from indexes
+// of their own, without reading every announcement
+type SearchStore interface {
+	Search(q Query) ([]dlcoracle.Announcement, error)
+}
+
+// Search returns the announcements in s selected by q, in the order of
+// Store.Announcements. Stores implementing SearchStore answer from their
+// indexes; the announcements of others are all read and matched.
+func Search(s Store, q Query) ([]dlcoracle.Announcement, error) {
+	if ss, ok := s.(SearchStore); ok {
+		return ss.Search(q)
+	}
+	list, err := s.Announcements()
+	if err != nil {
+		return nil, err
+	}
+	kept := make([]dlcoracle.Announcement, 0, len(list))
+	for _, a := range list {
+		status := EventStatus(0)
+		if q.Status != 0 {
+			status, err = Status(s, a.EventID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if q.Match(a, status) {
+			kept = append(kept, a)
+		}
+		if q.Limit > 0 && len(kept) == q.Limit {
+			break
+		}
+	}
+	return kept, nil
+}
+
+// Attestations returns the attestations of the announcements in s
+// selected by q, in the same order. Limit counts attestations, and
+// announcements without one are skipped.
+func Attestations(s Store, q Query) ([]dlcoracle.Attestation, error) {
+	var list []dlcoracle.Attestation
+	for {
+		page, err := Search(s, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range page {
+			att, err := s.Attestation(a.EventID)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, att)
+			if len(list) == q.Limit {
+				return list, nil
+			}
+		}
+		if q.Limit <= 0 || len(page) < q.Limit {
+			return list, nil
+		}
+		q.After = CursorOf(page[len(page)-1])
+	}
+}
+
+// Status returns the status of an announced event in s. Revoked events
+// count as revoked even if they were attested; stores that aren't
+// RevocationStores have no revoked events.
+func Status(s Store, eventID string) (EventStatus, error) {
+	if rs, ok := s.(RevocationStore); ok {
+		_, err := rs.Revocation(eventID)
+		if err == nil {
+			return StatusRevoked, nil
+		}
+		if err != ErrNotFound {
+			return 0, err
+		}
+	}
+	_, err := s.Attestation(eventID)
+	if err == nil {
+		return StatusAttested, nil
+	}
+	if err != ErrNotFound {
+		return 0, err
+	}
+	return StatusAnnounced, nil
+}
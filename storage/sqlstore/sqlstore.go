@@ -0,0 +1,779 @@
+// Package sqlstore implements storage.Store on top of database/sql, so
+// several oracle instances can share a Postgres database and operators can
+// inspect the oracle's state with SQL. It works with SQLite and Postgres;
+// the caller opens the database with a driver of their choice.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+)
+
+// Dialect selects the SQL flavour of the database
+type Dialect int
+
+const (
+	// SQLite uses ? placeholders
+	SQLite Dialect = iota
+	// Postgres uses $1, $2, ... placeholders
+	Postgres
+)
+
+// rebind rewrites the ? placeholders in query for the dialect
+func (d Dialect) rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// migrations[i] holds the statements upgrading the schema from version i
+// to version i+1. Migrations only ever get appended.
+var migrations = [][]string{
+	{
+		`CREATE TABLE oracle_nonces (
+			event_id TEXT PRIMARY KEY,
+			signing_key TEXT NOT NULL
+		)`,
+		`CREATE TABLE oracle_announcements (
+			event_id TEXT PRIMARY KEY,
+			maturity BIGINT NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`CREATE INDEX oracle_announcements_maturity ON oracle_announcements (maturity, event_id)`,
+		`CREATE TABLE oracle_attestations (
+			event_id TEXT PRIMARY KEY,
+			message TEXT NOT NULL,
+			signature TEXT NOT NULL,
+			data TEXT NOT NULL
+		)`,
+		`INSERT INTO oracle_meta (name, value) VALUES ('nonce_index', 0)`,
+	},
+	{
+		`CREATE TABLE oracle_revocations (
+			event_id TEXT PRIMARY KEY,
+			data TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE oracle_assignments (
+			r_point TEXT PRIMARY KEY,
+			event_id TEXT NOT NULL,
+			commitment TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE oracle_secrets (
+			name TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+	},
+	{
+		`ALTER TABLE oracle_announcements ADD COLUMN unit TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE oracle_announcements ADD COLUMN event_type TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE oracle_announcements ADD COLUMN category TEXT NOT NULL DEFAULT ''`,
+		`CREATE INDEX oracle_announcements_unit ON oracle_announcements (unit, maturity)`,
+		`CREATE INDEX oracle_announcements_type ON oracle_announcements (event_type, maturity)`,
+		`CREATE INDEX oracle_announcements_category ON oracle_announcements (category, maturity)`,
+		`CREATE TABLE oracle_announcement_tags (
+			tag TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			PRIMARY KEY (tag, event_id)
+		)`,
+	},
+	{
+		`CREATE TABLE oracle_evidence (
+			event_id TEXT PRIMARY KEY,
+			data TEXT NOT NULL
+		)`,
+	},
+	{
+		`CREATE TABLE oracle_reservations (
+			event_id TEXT PRIMARY KEY,
+			message TEXT NOT NULL
+		)`,
+	},
+	{
+		`ALTER TABLE oracle_announcements ADD COLUMN stored_at BIGINT NOT NULL DEFAULT 0`,
+		`ALTER TABLE oracle_attestations ADD COLUMN stored_at BIGINT NOT NULL DEFAULT 0`,
+	},
+}
+
+// backfills[i] fills in what migrations[i] added for the rows already
+// there, where SQL alone can't
+var backfills = map[int64]func(s *Store, tx *sql.Tx) error{
+	4: (*Store).indexAnnouncements,
+}
+
+// schemaVersion is the schema version this package reads and writes
+var schemaVersion = int64(len(migrations))
+
+// Store is a storage.Store backed by a SQL database
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+	sealer  *seal.Sealer
+}
+
+var (
+	_ storage.Store            = (*Store)(nil)
+	_ storage.RevocationStore  = (*Store)(nil)
+	_ storage.EvidenceStore    = (*Store)(nil)
+	_ storage.LedgerStore      = (*Store)(nil)
+	_ storage.NonceIndexStore  = (*Store)(nil)
+	_ storage.EncryptingStore  = (*Store)(nil)
+	_ storage.SearchStore      = (*Store)(nil)
+	_ storage.ReservationStore = (*Store)(nil)
+	_ storage.TimestampStore   = (*Store)(nil)
+)
+
+// New returns a store keeping its state in db, and migrates the database
+// to the current schema version. When several instances share a
+// database, upgrade it from a single instance first.
+func New(db *sql.DB, dialect Dialect) (*Store, error) {
+	s := &Store{db: db, dialect: dialect}
+	err := s.migrate()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Version returns the schema version stored in the database
+func (s *Store) Version() (int64, error) {
+	var v int64
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT value FROM oracle_meta WHERE name = ?`), "schema_version").Scan(&v)
+	return v, err
+}
+
+// migrate applies all migrations the database hasn't seen yet in a single
+// transaction
+func (s *Store) migrate() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS oracle_meta (
+		name TEXT PRIMARY KEY,
+		value BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+	var v int64
+	err = tx.QueryRow(s.dialect.rebind(
+		`SELECT value FROM oracle_meta WHERE name = ?`), "schema_version").Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.Exec(s.dialect.rebind(
+			`INSERT INTO oracle_meta (name, value) VALUES (?, 0)`), "schema_version")
+	}
+	if err != nil {
+		return err
+	}
+	if v > schemaVersion {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", v, schemaVersion)
+	}
+	for ; v < schemaVersion; v++ {
+		for _, stmt := range migrations[v] {
+			_, err = tx.Exec(stmt)
+			if err != nil {
+				return fmt.Errorf("migrating database to version %d: %w", v+1, err)
+			}
+		}
+		if fill := backfills[v]; fill != nil {
+			err = fill(s, tx)
+			if err != nil {
+				return fmt.Errorf("migrating database to version %d: %w", v+1, err)
+			}
+		}
+	}
+	_, err = tx.Exec(s.dialect.rebind(
+		`UPDATE oracle_meta SET value = ? WHERE name = ?`), v, "schema_version")
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NextNonceIndex returns the next unused one-time signing key index. The
+// counter is incremented in a single statement, so instances sharing the
+// database never get the same index.
+func (s *Store) NextNonceIndex() (uint64, error) {
+	var next int64
+	err := s.db.QueryRow(s.dialect.rebind(
+		`UPDATE oracle_meta SET value = value + 1 WHERE name = ? RETURNING value`),
+		"nonce_index").Scan(&next)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(next - 1), nil
+}
+
+// NonceIndex returns the next unused one-time signing key index without
+// using it
+func (s *Store) NonceIndex() (uint64, error) {
+	var next int64
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT value FROM oracle_meta WHERE name = ?`), "nonce_index").Scan(&next)
+	return uint64(next), err
+}
+
+// Ping implements storage.Pinger by querying the database
+func (s *Store) Ping(ctx context.Context) error {
+	var next int64
+	return s.db.QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT value FROM oracle_meta WHERE name = ?`), "nonce_index").Scan(&next)
+}
+
+// RaiseNonceIndex makes i the next unused one-time signing key index,
+// unless a later one is
+func (s *Store) RaiseNonceIndex(i uint64) error {
+	_, err := s.db.Exec(s.dialect.rebind(
+		`UPDATE oracle_meta SET value = ? WHERE name = ? AND value < ?`),
+		int64(i), "nonce_index", int64(i))
+	return err
+}
+
+// insertOnce runs an INSERT ... ON CONFLICT DO NOTHING statement and
+// returns ErrExists if the row was already there
+func (s *Store) insertOnce(query string, args ...interface{}) error {
+	res, err := s.db.Exec(s.dialect.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrExists
+	}
+	return nil
+}
+
+// dataKey returns the wrapped data key, nil if the store isn't encrypted
+func (s *Store) dataKey() ([]byte, error) {
+	var h string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT value FROM oracle_secrets WHERE name = ?`), "data_key").Scan(&h)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(h)
+}
+
+// Encrypt implements storage.EncryptingStore. The wrapped data key is
+// kept in the oracle_secrets table. Instances sharing a database agree on
+// the data key of whichever encrypts it first, and each encrypts the
+// signing keys it finds in plaintext.
+func (s *Store) Encrypt(ctx context.Context, w seal.KeyWrapper) error {
+	wrapped, err := s.dataKey()
+	if err != nil {
+		return err
+	}
+	sealer, newWrapped, err := seal.New(ctx, w, wrapped)
+	if err != nil {
+		return fmt.Errorf("unlocking store: %w", err)
+	}
+	if wrapped == nil {
+		err = s.insertOnce(`INSERT INTO oracle_secrets (name, value) VALUES (?, ?)
+			ON CONFLICT (name) DO NOTHING`, "data_key", hex.EncodeToString(newWrapped))
+		if errors.Is(err, storage.ErrExists) {
+			// another instance got there first, use its key
+			return s.Encrypt(ctx, w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		`SELECT event_id, signing_key FROM oracle_nonces WHERE LENGTH(signing_key) = ?`), 64)
+	if err != nil {
+		return err
+	}
+	plain := make(map[string]string)
+	for rows.Next() {
+		var id, h string
+		err = rows.Scan(&id, &h)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		plain[id] = h
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for id, h := range plain {
+		k, err := hex.DecodeString(h)
+		if err != nil {
+			return fmt.Errorf("stored nonce for %s: %v", id, err)
+		}
+		v, err := sealer.Seal(k, []byte(id))
+		if err != nil {
+			return err
+		}
+		_, err = s.db.ExecContext(ctx, s.dialect.rebind(
+			`UPDATE oracle_nonces SET signing_key = ? WHERE event_id = ? AND signing_key = ?`),
+			hex.EncodeToString(v), id, h)
+		if err != nil {
+			return err
+		}
+	}
+	s.sealer = sealer
+	return nil
+}
+
+// PutNonce stores the one-time signing key for an event
+func (s *Store) PutNonce(eventID string, key [32]byte) error {
+	if s.sealer != nil {
+		v, err := s.sealer.Seal(key[:], []byte(eventID))
+		if err != nil {
+			return err
+		}
+		return s.insertOnce(`INSERT INTO oracle_nonces (event_id, signing_key) VALUES (?, ?)
+			ON CONFLICT (event_id) DO NOTHING`, eventID, hex.EncodeToString(v))
+	}
+	// only store the key in plaintext if the store isn't encrypted,
+	// checked in the same statement
+	err := s.insertOnce(`INSERT INTO oracle_nonces (event_id, signing_key)
+		SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM oracle_secrets WHERE name = ?)
+		ON CONFLICT (event_id) DO NOTHING`, eventID, hex.EncodeToString(key[:]), "data_key")
+	if errors.Is(err, storage.ErrExists) {
+		wrapped, err := s.dataKey()
+		if err != nil {
+			return err
+		}
+		if wrapped != nil {
+			return storage.ErrEncrypted
+		}
+		return storage.ErrExists
+	}
+	return err
+}
+
+// Nonce returns the one-time signing key for an event
+func (s *Store) Nonce(eventID string) ([32]byte, error) {
+	var k [32]byte
+	var h string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT signing_key FROM oracle_nonces WHERE event_id = ?`), eventID).Scan(&h)
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, storage.ErrNotFound
+	}
+	if err != nil {
+		return k, err
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return k, err
+	}
+	if len(b) == seal.SealedSize(32) {
+		if s.sealer == nil {
+			return k, storage.ErrEncrypted
+		}
+		b, err = s.sealer.Open(b, []byte(eventID))
+		if err != nil {
+			return k, fmt.Errorf("stored nonce for %s: %w", eventID, err)
+		}
+	}
+	if len(b) != 32 {
+		return k, fmt.Errorf("stored nonce for %s has invalid length %d", eventID, len(b))
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// PutAnnouncement stores an announcement, replacing any existing
+// announcement for the same event
+func (s *Store) PutAnnouncement(a dlcoracle.Announcement) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(s.dialect.rebind(
+		`INSERT INTO oracle_announcements (event_id, maturity, data, stored_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (event_id) DO UPDATE SET maturity = excluded.maturity, data = excluded.data,
+		stored_at = excluded.stored_at`),
+		a.EventID, a.Maturity.Unix(), string(data), storage.StoredAt().Unix())
+	if err != nil {
+		return err
+	}
+	err = s.index(tx, a)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// index writes the columns and tags Search looks announcements up by
+func (s *Store) index(tx *sql.Tx, a dlcoracle.Announcement) error {
+	_, err := tx.Exec(s.dialect.rebind(
+		`UPDATE oracle_announcements SET unit = ?, event_type = ?, category = ? WHERE event_id = ?`),
+		a.Descriptor.Unit, a.Descriptor.Type.String(), a.Metadata.Category, a.EventID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.dialect.rebind(
+		`DELETE FROM oracle_announcement_tags WHERE event_id = ?`), a.EventID)
+	if err != nil {
+		return err
+	}
+	for _, tag := range a.Metadata.Tags {
+		_, err = tx.Exec(s.dialect.rebind(
+			`INSERT INTO oracle_announcement_tags (tag, event_id) VALUES (?, ?)
+			ON CONFLICT (tag, event_id) DO NOTHING`), tag, a.EventID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexAnnouncements indexes the announcements stored before Search
+func (s *Store) indexAnnouncements(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT event_id, data FROM oracle_announcements`)
+	if err != nil {
+		return err
+	}
+	var list []dlcoracle.Announcement
+	for rows.Next() {
+		var id, data string
+		err = rows.Scan(&id, &data)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		var a dlcoracle.Announcement
+		err = json.Unmarshal([]byte(data), &a)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("decoding announcement %s: %w", id, err)
+		}
+		list = append(list, a)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, a := range list {
+		err = s.index(tx, a)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Announcement returns the announcement for the given event
+func (s *Store) Announcement(eventID string) (dlcoracle.Announcement, error) {
+	var a dlcoracle.Announcement
+	var data string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT data FROM oracle_announcements WHERE event_id = ?`), eventID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, storage.ErrNotFound
+	}
+	if err != nil {
+		return a, err
+	}
+	err = json.Unmarshal([]byte(data), &a)
+	return a, err
+}
+
+// Announcements returns all announcements ordered by maturity
+func (s *Store) Announcements() ([]dlcoracle.Announcement, error) {
+	return s.queryAnnouncements(
+		`SELECT event_id, data FROM oracle_announcements ORDER BY maturity, event_id`)
+}
+
+// Search implements storage.SearchStore with the indexes on the unit,
+// type, category and maturity of announcements and on their tags. The
+// maturity and event ID index also serves cursors.
+func (s *Store) Search(q storage.Query) ([]dlcoracle.Announcement, error) {
+	var where []string
+	var args []interface{}
+	cond := func(c string, a ...interface{}) {
+		where = append(where, c)
+		args = append(args, a...)
+	}
+	if q.Unit != "" {
+		cond(`a.unit = ?`, q.Unit)
+	}
+	if len(q.Types) != 0 {
+		marks := make([]string, len(q.Types))
+		for i, t := range q.Types {
+			marks[i] = "?"
+			args = append(args, t.String())
+		}
+		where = append(where, `a.event_type IN (`+strings.Join(marks, ", ")+`)`)
+	}
+	if !q.MaturesAfter.IsZero() {
+		cond(`a.maturity >= ?`, ceilUnix(q.MaturesAfter))
+	}
+	if !q.MaturesBefore.IsZero() {
+		cond(`a.maturity < ?`, ceilUnix(q.MaturesBefore))
+	}
+	const (
+		attested = `EXISTS (SELECT 1 FROM oracle_attestations t WHERE t.event_id = a.event_id)`
+		revoked  = `EXISTS (SELECT 1 FROM oracle_revocations r WHERE r.event_id = a.event_id)`
+	)
+	switch q.Status {
+	case 0:
+	case storage.StatusAnnounced:
+		cond(`NOT ` + attested + ` AND NOT ` + revoked)
+	case storage.StatusAttested:
+		cond(attested + ` AND NOT ` + revoked)
+	case storage.StatusRevoked:
+		cond(revoked)
+	default:
+		return nil, fmt.Errorf("unknown %s", q.Status)
+	}
+	if q.Category != "" {
+		cond(`a.category = ?`, q.Category)
+	}
+	for _, tag := range q.Tags {
+		cond(`EXISTS (SELECT 1 FROM oracle_announcement_tags g WHERE g.tag = ? AND g.event_id = a.event_id)`, tag)
+	}
+	if !q.After.IsZero() {
+		m := q.After.Maturity.Unix()
+		cond(`(a.maturity > ? OR a.maturity = ? AND a.event_id > ?)`, m, m, q.After.EventID)
+	}
+
+	query := `SELECT a.event_id, a.data FROM oracle_announcements a`
+	if len(where) != 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY a.maturity, a.event_id`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(` LIMIT %d`, q.Limit)
+	}
+	return s.queryAnnouncements(s.dialect.rebind(query), args...)
+}
+
+// ceilUnix returns t in unix seconds, rounded up, as announcements store
+// maturities in whole seconds
+func ceilUnix(t time.Time) int64 {
+	if t.Nanosecond() != 0 {
+		return t.Unix() + 1
+	}
+	return t.Unix()
+}
+
+// queryAnnouncements returns the announcements in the data column of
+// the rows the query returns after their event ID
+func (s *Store) queryAnnouncements(query string, args ...interface{}) ([]dlcoracle.Announcement, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []dlcoracle.Announcement
+	for rows.Next() {
+		var id, data string
+		err = rows.Scan(&id, &data)
+		if err != nil {
+			return nil, err
+		}
+		var a dlcoracle.Announcement
+		err = json.Unmarshal([]byte(data), &a)
+		if err != nil {
+			return nil, fmt.Errorf("decoding announcement %s: %w", id, err)
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// PutAttestation stores an attestation
+func (s *Store) PutAttestation(a dlcoracle.Attestation) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return s.insertOnce(`INSERT INTO oracle_attestations (event_id, message, signature, data, stored_at)
+		VALUES (?, ?, ?, ?, ?) ON CONFLICT (event_id) DO NOTHING`,
+		a.EventID, hex.EncodeToString(a.Message), hex.EncodeToString(a.Signature[:]), string(data),
+		storage.StoredAt().Unix())
+}
+
+// Attestation returns the attestation for the given event
+func (s *Store) Attestation(eventID string) (dlcoracle.Attestation, error) {
+	var a dlcoracle.Attestation
+	var data string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT data FROM oracle_attestations WHERE event_id = ?`), eventID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, storage.ErrNotFound
+	}
+	if err != nil {
+		return a, err
+	}
+	err = json.Unmarshal([]byte(data), &a)
+	return a, err
+}
+
+// PutRevocation stores a revocation
+func (s *Store) PutRevocation(r dlcoracle.Revocation) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.insertOnce(`INSERT INTO oracle_revocations (event_id, data) VALUES (?, ?)
+		ON CONFLICT (event_id) DO NOTHING`, r.EventID, string(data))
+}
+
+// Revocation returns the revocation of the given event
+func (s *Store) Revocation(eventID string) (dlcoracle.Revocation, error) {
+	var r dlcoracle.Revocation
+	var data string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT data FROM oracle_revocations WHERE event_id = ?`), eventID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, storage.ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal([]byte(data), &r)
+	return r, err
+}
+
+// PutEvidence stores the evidence of an attestation
+func (s *Store) PutEvidence(e dlcoracle.Evidence) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.insertOnce(`INSERT INTO oracle_evidence (event_id, data) VALUES (?, ?)
+		ON CONFLICT (event_id) DO NOTHING`, e.EventID, string(data))
+}
+
+// Evidence returns the evidence of the attestation of the given event
+func (s *Store) Evidence(eventID string) (dlcoracle.Evidence, error) {
+	var e dlcoracle.Evidence
+	var data string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT data FROM oracle_evidence WHERE event_id = ?`), eventID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, storage.ErrNotFound
+	}
+	if err != nil {
+		return e, err
+	}
+	err = json.Unmarshal([]byte(data), &e)
+	return e, err
+}
+
+// PutAssignment records the event a one-time signing key was assigned to
+func (s *Store) PutAssignment(a storage.NonceAssignment) error {
+	return s.insertOnce(`INSERT INTO oracle_assignments (r_point, event_id, commitment)
+		VALUES (?, ?, ?) ON CONFLICT (r_point) DO NOTHING`,
+		hex.EncodeToString(a.RPoint[:]), a.EventID, hex.EncodeToString(a.Commitment[:]))
+}
+
+// Assignment returns the assignment of the one-time signing key with the
+// given point
+func (s *Store) Assignment(rPoint [33]byte) (storage.NonceAssignment, error) {
+	a := storage.NonceAssignment{RPoint: rPoint}
+	var commitment string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT event_id, commitment FROM oracle_assignments WHERE r_point = ?`),
+		hex.EncodeToString(rPoint[:])).Scan(&a.EventID, &commitment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, storage.ErrNotFound
+	}
+	if err != nil {
+		return a, err
+	}
+	b, err := hex.DecodeString(commitment)
+	if err != nil {
+		return a, err
+	}
+	if len(b) != 32 {
+		return a, fmt.Errorf("stored assignment of %x has invalid length %d", rPoint, len(b))
+	}
+	copy(a.Commitment[:], b)
+	return a, nil
+}
+
+// PutReservation reserves the outcome message of an event. The row is
+// inserted once, so of several instances sharing the database only the
+// first to reserve an outcome signs it.
+func (s *Store) PutReservation(eventID string, message []byte) error {
+	return s.insertOnce(`INSERT INTO oracle_reservations (event_id, message) VALUES (?, ?)
+		ON CONFLICT (event_id) DO NOTHING`, eventID, hex.EncodeToString(message))
+}
+
+// Reservation returns the outcome message reserved for an event
+func (s *Store) Reservation(eventID string) ([]byte, error) {
+	var message string
+	err := s.db.QueryRow(s.dialect.rebind(
+		`SELECT message FROM oracle_reservations WHERE event_id = ?`), eventID).Scan(&message)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(message)
+}
+
+// AnnouncementTime returns when the announcement of an event was stored
+func (s *Store) AnnouncementTime(eventID string) (time.Time, error) {
+	return s.storedAt(`SELECT stored_at FROM oracle_announcements WHERE event_id = ?`, eventID)
+}
+
+// AttestationTime returns when the attestation of an event was stored
+func (s *Store) AttestationTime(eventID string) (time.Time, error) {
+	return s.storedAt(`SELECT stored_at FROM oracle_attestations WHERE event_id = ?`, eventID)
+}
+
+// storedAt reads a stored_at column, which is 0 for rows written before
+// the column existed
+func (s *Store) storedAt(query, eventID string) (time.Time, error) {
+	var t int64
+	err := s.db.QueryRow(s.dialect.rebind(query), eventID).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, storage.ErrNotFound
+	}
+	if err != nil || t == 0 {
+		return time.Time{}, err
+	}
+	return time.Unix(t, 0).UTC(), nil
+}
@@ -0,0 +1,481 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+)
+
+func openTemp(t *testing.T) (*Store, string) {
+	path := filepath.Join(t.TempDir(), "oracle.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s, err := New(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, path
+}
+
+func TestRebind(t *testing.T) {
+	q := "SELECT a FROM t WHERE b = ? AND c = ?"
+	if SQLite.rebind(q) != q {
+		t.Fatal("sqlite query was rewritten")
+	}
+	if got := Postgres.rebind(q); got != "SELECT a FROM t WHERE b = $1 AND c = $2" {
+		t.Fatalf("unexpected postgres query %s", got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, path := openTemp(t)
+
+	for expected := uint64(0); expected < 3; expected++ {
+		i, err := s.NextNonceIndex()
+		if err != nil || i != expected {
+			t.Fatalf("expected nonce index %d, got %d %v", expected, i, err)
+		}
+	}
+
+	err := s.PutNonce("event", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutNonce("event", [32]byte{2})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	k, err := s.Nonce("event")
+	if err != nil || k != [32]byte{1} {
+		t.Fatalf("unexpected nonce %x %v", k, err)
+	}
+
+	for i, id := range []string{"c", "a", "b"} {
+		err = s.PutAnnouncement(dlcoracle.Announcement{
+			EventID:  id,
+			Maturity: time.Unix(int64(3-i), 0).UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Replacing an announcement must update its maturity
+	a := dlcoracle.Announcement{
+		EventID:  "c",
+		Maturity: time.Unix(4, 0).UTC(),
+		Descriptor: dlcoracle.EventDescriptor{
+			Type:     dlcoracle.EventTypeEnum,
+			Outcomes: []string{"yes", "no"},
+		},
+	}
+	err = s.PutAnnouncement(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.Announcements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids string
+	for _, a := range list {
+		ids += a.EventID
+	}
+	if ids != "bac" {
+		t.Fatalf("unexpected order %s", ids)
+	}
+	got, err := s.Announcement("c")
+	if err != nil || !reflect.DeepEqual(got, a) {
+		t.Fatalf("announcement mismatch: %+v %v", got, err)
+	}
+
+	att := dlcoracle.Attestation{EventID: "c", Message: []byte("yes"), Signature: [32]byte{3}}
+	err = s.PutAttestation(att)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(att)
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	gotAtt, err := s.Attestation("c")
+	if err != nil || !reflect.DeepEqual(gotAtt, att) {
+		t.Fatalf("attestation mismatch: %+v %v", gotAtt, err)
+	}
+	_, err = s.Attestation("missing")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Reopening must not run the migrations again
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s, err = New(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Version()
+	if err != nil || v != schemaVersion {
+		t.Fatalf("unexpected version %d %v", v, err)
+	}
+	i, err := s.NextNonceIndex()
+	if err != nil || i != 3 {
+		t.Fatalf("nonce index was not persisted: %d %v", i, err)
+	}
+}
+
+func TestRevocations(t *testing.T) {
+	s, _ := openTemp(t)
+
+	_, err := s.Revocation("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	r := dlcoracle.Revocation{
+		EventID:   "event",
+		Reason:    "cancelled",
+		RevokedAt: time.Unix(1700000000, 0).UTC(),
+	}
+	err = s.PutRevocation(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutRevocation(dlcoracle.Revocation{EventID: "event"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Revocation("event")
+	if err != nil || got != r {
+		t.Fatalf("unexpected revocation %+v %v", got, err)
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	s, _ := openTemp(t)
+
+	_, err := s.Evidence("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	e := dlcoracle.Evidence{
+		EventID: "event",
+		Responses: []dlcoracle.UpstreamResponse{{
+			Source:      "kraken",
+			URL:         "https://api.kraken.com/0/public/Ticker?pair=XBTUSD",
+			Status:      200,
+			Body:        []byte(`{"result":{}}`),
+			ReceivedAt:  time.UnixMilli(1700000000123).UTC(),
+			RequestedAt: time.UnixMilli(1700000000000).UTC(),
+		}},
+		CapturedAt: time.UnixMilli(1700000001000).UTC(),
+	}
+	err = s.PutEvidence(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutEvidence(dlcoracle.Evidence{EventID: "event"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Evidence("event")
+	if err != nil || got.SigningHash() != e.SigningHash() {
+		t.Fatalf("unexpected evidence %+v %v", got, err)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	s, _ := openTemp(t)
+
+	_, err := s.Assignment([33]byte{2})
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	a := storage.NonceAssignment{RPoint: [33]byte{2}, EventID: "event", Commitment: [32]byte{1}}
+	err = s.PutAssignment(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAssignment(storage.NonceAssignment{RPoint: [33]byte{2}, EventID: "other"})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Assignment([33]byte{2})
+	if err != nil || got != a {
+		t.Fatalf("unexpected assignment %+v %v", got, err)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	s, _ := openTemp(t)
+
+	_, err := s.Reservation("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	err = s.PutReservation("event", []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutReservation("event", []byte{3})
+	if err != storage.ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Reservation("event")
+	if err != nil || string(got) != string([]byte{1, 2}) {
+		t.Fatalf("unexpected reservation %x %v", got, err)
+	}
+}
+
+func TestTimes(t *testing.T) {
+	s, _ := openTemp(t)
+
+	_, err := s.AnnouncementTime("event")
+	if err != storage.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	before := time.Now().Add(-time.Second)
+	err = s.PutAnnouncement(dlcoracle.Announcement{EventID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event", Message: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, get := range []func(string) (time.Time, error){s.AnnouncementTime, s.AttestationTime} {
+		stored, err := get("event")
+		if err != nil || stored.Before(before) || stored.After(time.Now()) || stored.Nanosecond() != 0 {
+			t.Fatalf("stored at %s, %v", stored, err)
+		}
+	}
+}
+
+func TestNonceIndex(t *testing.T) {
+	s, _ := openTemp(t)
+
+	s.NextNonceIndex()
+	err := s.RaiseNonceIndex(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RaiseNonceIndex(3)
+	i, err := s.NonceIndex()
+	if err != nil || i != 5 {
+		t.Fatalf("unexpected nonce index %d %v", i, err)
+	}
+	i, _ = s.NextNonceIndex()
+	if i != 5 {
+		t.Fatalf("raised index %d not used next", i)
+	}
+}
+
+func TestPing(t *testing.T) {
+	s, _ := openTemp(t)
+	err := s.Ping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.db.Close()
+	if s.Ping(context.Background()) == nil {
+		t.Fatal("closed store reachable")
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	s, path := openTemp(t)
+	legacy := [32]byte{1, 2, 3}
+	err := s.PutNonce("legacy", legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Encrypt(ctx, seal.Passphrase([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := [32]byte{4, 5, 6}
+	err = s.PutNonce("event", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PutNonce("event", key) != storage.ErrExists {
+		t.Fatal("encrypted nonce overwritten")
+	}
+	for id, want := range map[string][32]byte{"legacy": legacy, "event": key} {
+		k, err := s.Nonce(id)
+		if err != nil || k != want {
+			t.Fatalf("nonce %s: %x %v", id, k, err)
+		}
+		var h string
+		s.db.QueryRow(`SELECT signing_key FROM oracle_nonces WHERE event_id = ?`, id).Scan(&h)
+		if strings.Contains(h, hex.EncodeToString(want[:])) {
+			t.Fatalf("nonce %s stored in plaintext", id)
+		}
+	}
+
+	// a copy of the database doesn't reveal the keys
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	other, err := New(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = other.Nonce("event")
+	if !errors.Is(err, storage.ErrEncrypted) {
+		t.Fatalf("read without key: %v", err)
+	}
+	err = other.PutNonce("other", key)
+	if !errors.Is(err, storage.ErrEncrypted) {
+		t.Fatalf("plaintext write to encrypted store: %v", err)
+	}
+	err = other.Encrypt(ctx, seal.Passphrase([]byte("wrong")))
+	if !errors.Is(err, seal.ErrDecrypt) {
+		t.Fatalf("wrong passphrase: %v", err)
+	}
+	err = other.Encrypt(ctx, seal.Passphrase([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := other.Nonce("event")
+	if err != nil || k != key {
+		t.Fatalf("nonce from second instance: %x %v", k, err)
+	}
+}
+
+// scanOnly hides the Search method of a store, so storage.Search reads
+// and matches every announcement
+type scanOnly struct {
+	storage.Store
+	storage.RevocationStore
+}
+
+func TestSearch(t *testing.T) {
+	s, _ := openTemp(t)
+	units := []string{"usd/btc", "eur/btc", ""}
+	types := []dlcoracle.EventType{dlcoracle.EventTypeDigits, dlcoracle.EventTypeNumeric, dlcoracle.EventTypeEnum}
+	tags := [][]string{{"btc"}, {"btc", "usd"}, nil, {"usd"}}
+	for i := 0; i < 24; i++ {
+		a := dlcoracle.Announcement{
+			EventID:    "ev" + string(rune('a'+i)),
+			Maturity:   time.Unix(int64(1000*(i%7)), 0),
+			Descriptor: dlcoracle.EventDescriptor{Type: types[i%3], Unit: units[i%3]},
+			Metadata:   dlcoracle.EventMetadata{Category: []string{"crypto", "fx"}[i%2], Tags: tags[i%4]},
+		}
+		if a.Descriptor.Type == dlcoracle.EventTypeEnum {
+			a.Descriptor.Outcomes = []string{"yes", "no"}
+		}
+		err := s.PutAnnouncement(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch i % 5 {
+		case 1:
+			s.PutAttestation(dlcoracle.Attestation{EventID: a.EventID})
+		case 2:
+			s.PutAttestation(dlcoracle.Attestation{EventID: a.EventID})
+			s.PutRevocation(dlcoracle.Revocation{EventID: a.EventID})
+		case 3:
+			s.PutRevocation(dlcoracle.Revocation{EventID: a.EventID})
+		}
+	}
+	// replacing an announcement replaces its tags
+	a, _ := s.Announcement("eva")
+	a.Metadata.Tags = []string{"eth"}
+	s.PutAnnouncement(a)
+
+	for _, q := range []storage.Query{
+		{},
+		{Unit: "usd/btc"},
+		{Types: []dlcoracle.EventType{dlcoracle.EventTypeEnum, dlcoracle.EventTypeNumeric}},
+		{MaturesAfter: time.Unix(2000, 0), MaturesBefore: time.Unix(5000, 1)},
+		{MaturesAfter: time.Unix(1999, 5)},
+		{Status: storage.StatusAnnounced},
+		{Status: storage.StatusAttested},
+		{Status: storage.StatusRevoked, Category: "fx"},
+		{Tags: []string{"btc", "usd"}},
+		{Tags: []string{"eth"}},
+		{Unit: "usd/btc", Tags: []string{"usd"}, Status: storage.StatusAnnounced},
+		{Limit: 5},
+		{After: storage.Cursor{Maturity: time.Unix(2000, 0), EventID: "evo"}, Limit: 4},
+		{After: storage.Cursor{Maturity: time.Unix(6000, 0), EventID: "evz"}},
+		{Status: storage.StatusAttested, After: storage.Cursor{Maturity: time.Unix(3000, 0)}, Limit: 2},
+	} {
+		got, err := s.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := storage.Search(scanOnly{s, s}, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%+v: got %d announcements, want %d", q, len(got), len(want))
+		}
+		for i := range got {
+			if got[i].EventID != want[i].EventID {
+				t.Fatalf("%+v: got %s at %d, want %s", q, got[i].EventID, i, want[i].EventID)
+			}
+		}
+	}
+}
+
+func TestSearchMigration(t *testing.T) {
+	// A database from before the search indexes, with an announcement
+	path := filepath.Join(t.TempDir(), "oracle.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stmts := []string{`CREATE TABLE oracle_meta (name TEXT PRIMARY KEY, value BIGINT NOT NULL)`,
+		`INSERT INTO oracle_meta (name, value) VALUES ('schema_version', 4)`}
+	for _, m := range migrations[:4] {
+		stmts = append(stmts, m...)
+	}
+	for _, stmt := range stmts {
+		_, err = db.Exec(stmt)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := dlcoracle.Announcement{
+		EventID:    "old",
+		Maturity:   time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Unit: "usd/btc"},
+		Metadata:   dlcoracle.EventMetadata{Category: "crypto", Tags: []string{"btc"}},
+	}
+	data, _ := a.MarshalJSON()
+	_, err = db.Exec(`INSERT INTO oracle_announcements (event_id, maturity, data) VALUES (?, ?, ?)`, a.EventID, 1000, string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := New(db, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.Search(storage.Query{Unit: "usd/btc", Category: "crypto", Tags: []string{"btc"},
+		Types: []dlcoracle.EventType{dlcoracle.EventTypeDigits}})
+	if err != nil || len(list) != 1 || list[0].EventID != "old" {
+		t.Fatalf("found %+v %v", list, err)
+	}
+}
@@ -0,0 +1,402 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/storage/seal"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrExists is returned when writing a record that may only be
+	// written once
+	ErrExists = errors.New("already exists")
+
+	// ErrEncrypted is returned when reading or writing the one-time
+	// signing keys of an encrypted store that wasn't given its key
+	ErrEncrypted = errors.New("store is encrypted")
+)
+
+// Store persists the announcements and attestations an oracle has published,
+// as well as the one-time signing keys it needs to attest to its events.
+//
+// PutNonce and PutAttestation must return ErrExists if a record for the
+// event is already stored, and must never overwrite it: replacing a
+// one-time signing key or signing a second outcome with it would let
+// anyone compute the oracle's private key. Implementations have to make
+// this check atomic with the write.
+type Store interface {
+	// NextNonceIndex returns a one-time signing key index that has never
+	// been returned before
+	NextNonceIndex() (uint64, error)
+	PutNonce(eventID string, key [32]byte) error
+	Nonce(eventID string) ([32]byte, error)
+
+	PutAnnouncement(a dlcoracle.Announcement) error
+	Announcement(eventID string) (dlcoracle.Announcement, error)
+	Announcements() ([]dlcoracle.Announcement, error)
+
+	PutAttestation(a dlcoracle.Attestation) error
+	Attestation(eventID string) (dlcoracle.Attestation, error)
+}
+
+// RevocationStore is implemented by stores that keep the revocations of
+// announced events. PutRevocation must return ErrExists if the event was
+// revoked before, and never overwrite the revocation.
+type RevocationStore interface {
+	PutRevocation(r dlcoracle.Revocation) error
+	Revocation(eventID string) (dlcoracle.Revocation, error)
+}
+
+// EvidenceStore is implemented by stores that keep the upstream responses
+// events were attested from. PutEvidence must return ErrExists if
+// evidence of the event was stored before, and never overwrite it.
+type EvidenceStore interface {
+	PutEvidence(e dlcoracle.Evidence) error
+	Evidence(eventID string) (dlcoracle.Evidence, error)
+}
+
+// Pinger is implemented by stores that can check they are reachable,
+// such as stores backed by a database server
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// NonceIndexStore is implemented by stores that can report and raise
+// their nonce index, so an oracle's state can be moved to another store.
+// RaiseNonceIndex never lowers the index.
+type NonceIndexStore interface {
+	// NonceIndex returns the index NextNonceIndex will return next
+	NonceIndex() (uint64, error)
+	RaiseNonceIndex(i uint64) error
+}
+
+// EncryptingStore is implemented by stores that can encrypt the one-time
+// signing keys they hold, so a copy of the database alone doesn't reveal
+// them. Encrypt must be called before the store is used. The first call
+// creates the store's data key, wraps it with w and encrypts the keys
+// stored so far; later calls unwrap the stored data key with w. Once a
+// store is encrypted, reading or writing signing keys without calling
+// Encrypt fails with ErrEncrypted.
+type EncryptingStore interface {
+	Encrypt(ctx context.Context, w seal.KeyWrapper) error
+}
+
+// NonceAssignment records the event a one-time signing key was assigned
+// to, identified by the key's point
+type NonceAssignment struct {
+	RPoint  [33]byte
+	EventID string
+
+	// Commitment is the dlcoracle.EventCommitment of the event, binding
+	// the key to its descriptor and maturity
+	Commitment [32]byte
+}
+
+// LedgerStore is implemented by stores that keep the event each one-time
+// signing key was assigned to. PutAssignment must return ErrExists if the
+// key was assigned before, and never overwrite the assignment.
+type LedgerStore interface {
+	PutAssignment(a NonceAssignment) error
+	Assignment(rPoint [33]byte) (NonceAssignment, error)
+}
+
+// ReservationStore is implemented by stores several oracle instances can
+// share. An instance reserves the outcome it attests an event to before
+// signing it, so two instances never sign different outcomes with the
+// same one-time signing key. PutReservation must return ErrExists if an
+// outcome of the event was reserved before, and never overwrite it.
+type ReservationStore interface {
+	PutReservation(eventID string, message []byte) error
+	Reservation(eventID string) ([]byte, error)
+}
+
+// TimestampStore is implemented by stores that keep when they stored each
+// announcement and attestation, to the second, which the REST API sends
+// as their Last-Modified time. Replacing an announcement updates its time.
+// Records stored before the store kept times have the zero time.
+type TimestampStore interface {
+	AnnouncementTime(eventID string) (time.Time, error)
+	AttestationTime(eventID string) (time.Time, error)
+}
+
+// StoredAt returns the time a record stored now is stamped with
+func StoredAt() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// SortAnnouncements sorts announcements by maturity, and by event ID for
+// equal maturities, which is the order Store.Announcements returns them in
+func SortAnnouncements(list []dlcoracle.Announcement) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Maturity.Equal(list[j].Maturity) {
+			return list[i].EventID < list[j].EventID
+		}
+		return list[i].Maturity.Before(list[j].Maturity)
+	})
+}
+
+// MemoryStore is a Store that keeps everything in memory. It is useful
+// for testing and for oracles that don't need to survive a restart.
+type MemoryStore struct {
+	mtx           sync.RWMutex
+	nonceIndex    uint64
+	nonces        map[string][32]byte
+	announcements map[string]dlcoracle.Announcement
+	attestations  map[string]dlcoracle.Attestation
+	revocations   map[string]dlcoracle.Revocation
+	assignments   map[[33]byte]NonceAssignment
+	evidence      map[string]dlcoracle.Evidence
+	reservations  map[string][]byte
+	times         map[string]time.Time
+}
+
+var (
+	_ RevocationStore  = (*MemoryStore)(nil)
+	_ EvidenceStore    = (*MemoryStore)(nil)
+	_ LedgerStore      = (*MemoryStore)(nil)
+	_ NonceIndexStore  = (*MemoryStore)(nil)
+	_ ReservationStore = (*MemoryStore)(nil)
+	_ TimestampStore   = (*MemoryStore)(nil)
+)
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nonces:        make(map[string][32]byte),
+		announcements: make(map[string]dlcoracle.Announcement),
+		attestations:  make(map[string]dlcoracle.Attestation),
+		revocations:   make(map[string]dlcoracle.Revocation),
+		assignments:   make(map[[33]byte]NonceAssignment),
+		evidence:      make(map[string]dlcoracle.Evidence),
+		reservations:  make(map[string][]byte),
+		times:         make(map[string]time.Time),
+	}
+}
+
+// NextNonceIndex returns the next unused one-time signing key index
+func (s *MemoryStore) NextNonceIndex() (uint64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	i := s.nonceIndex
+	s.nonceIndex++
+	return i, nil
+}
+
+// NonceIndex returns the next unused one-time signing key index without
+// using it
+func (s *MemoryStore) NonceIndex() (uint64, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.nonceIndex, nil
+}
+
+// Ping implements Pinger. A memory store is always reachable.
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// RaiseNonceIndex makes i the next unused one-time signing key index,
+// unless a later one is
+func (s *MemoryStore) RaiseNonceIndex(i uint64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if i > s.nonceIndex {
+		s.nonceIndex = i
+	}
+	return nil
+}
+
+// PutNonce stores the one-time signing key for an event
+func (s *MemoryStore) PutNonce(eventID string, key [32]byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.nonces[eventID]; ok {
+		return ErrExists
+	}
+	s.nonces[eventID] = key
+	return nil
+}
+
+// Nonce returns the one-time signing key for an event
+func (s *MemoryStore) Nonce(eventID string) ([32]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	k, ok := s.nonces[eventID]
+	if !ok {
+		return k, ErrNotFound
+	}
+	return k, nil
+}
+
+// PutAnnouncement stores an announcement, replacing any existing
+// announcement for the same event
+func (s *MemoryStore) PutAnnouncement(a dlcoracle.Announcement) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.announcements[a.EventID] = a
+	s.times["announcement/"+a.EventID] = StoredAt()
+	return nil
+}
+
+// Announcement returns the announcement for the given event
+func (s *MemoryStore) Announcement(eventID string) (dlcoracle.Announcement, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	a, ok := s.announcements[eventID]
+	if !ok {
+		return a, ErrNotFound
+	}
+	return a, nil
+}
+
+// Announcements returns all announcements ordered by maturity
+func (s *MemoryStore) Announcements() ([]dlcoracle.Announcement, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	list := make([]dlcoracle.Announcement, 0, len(s.announcements))
+	for _, a := range s.announcements {
+		list = append(list, a)
+	}
+	SortAnnouncements(list)
+	return list, nil
+}
+
+// PutAttestation stores an attestation
+func (s *MemoryStore) PutAttestation(a dlcoracle.Attestation) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.attestations[a.EventID]; ok {
+		return ErrExists
+	}
+	s.attestations[a.EventID] = a
+	s.times["attestation/"+a.EventID] = StoredAt()
+	return nil
+}
+
+// Attestation returns the attestation for the given event
+func (s *MemoryStore) Attestation(eventID string) (dlcoracle.Attestation, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	a, ok := s.attestations[eventID]
+	if !ok {
+		return a, ErrNotFound
+	}
+	return a, nil
+}
+
+// PutRevocation stores a revocation
+func (s *MemoryStore) PutRevocation(r dlcoracle.Revocation) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.revocations[r.EventID]; ok {
+		return ErrExists
+	}
+	s.revocations[r.EventID] = r
+	return nil
+}
+
+// Revocation returns the revocation of the given event
+func (s *MemoryStore) Revocation(eventID string) (dlcoracle.Revocation, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	r, ok := s.revocations[eventID]
+	if !ok {
+		return r, ErrNotFound
+	}
+	return r, nil
+}
+
+// PutEvidence stores the evidence of an attestation
+func (s *MemoryStore) PutEvidence(e dlcoracle.Evidence) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.evidence[e.EventID]; ok {
+		return ErrExists
+	}
+	s.evidence[e.EventID] = e
+	return nil
+}
+
+// Evidence returns the evidence of the attestation of the given event
+func (s *MemoryStore) Evidence(eventID string) (dlcoracle.Evidence, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	e, ok := s.evidence[eventID]
+	if !ok {
+		return e, ErrNotFound
+	}
+	return e, nil
+}
+
+// PutAssignment records the event a one-time signing key was assigned to
+func (s *MemoryStore) PutAssignment(a NonceAssignment) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.assignments[a.RPoint]; ok {
+		return ErrExists
+	}
+	s.assignments[a.RPoint] = a
+	return nil
+}
+
+// Assignment returns the assignment of the one-time signing key with the
+// given point
+func (s *MemoryStore) Assignment(rPoint [33]byte) (NonceAssignment, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	a, ok := s.assignments[rPoint]
+	if !ok {
+		return a, ErrNotFound
+	}
+	return a, nil
+}
+
+// PutReservation reserves the outcome message of an event
+func (s *MemoryStore) PutReservation(eventID string, message []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.reservations[eventID]; ok {
+		return ErrExists
+	}
+	s.reservations[eventID] = append([]byte{}, message...)
+	return nil
+}
+
+// Reservation returns the outcome message reserved for an event
+func (s *MemoryStore) Reservation(eventID string) ([]byte, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	m, ok := s.reservations[eventID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, m...), nil
+}
+
+// AnnouncementTime returns when the announcement of an event was stored
+func (s *MemoryStore) AnnouncementTime(eventID string) (time.Time, error) {
+	return s.storedAt("announcement/" + eventID)
+}
+
+// AttestationTime returns when the attestation of an event was stored
+func (s *MemoryStore) AttestationTime(eventID string) (time.Time, error) {
+	return s.storedAt("attestation/" + eventID)
+}
+
+func (s *MemoryStore) storedAt(key string) (time.Time, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	t, ok := s.times[key]
+	if !ok {
+		return t, ErrNotFound
+	}
+	return t, nil
+}
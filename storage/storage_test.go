@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestAnnouncementsOrderedByMaturity(t *testing.T) {
+	s := NewMemoryStore()
+	for i, id := range []string{"c", "a", "b"} {
+		err := s.PutAnnouncement(dlcoracle.Announcement{
+			EventID:  id,
+			Maturity: time.Unix(int64(3-i), 0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := s.Announcements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids string
+	for _, a := range list {
+		ids += a.EventID
+	}
+	if ids != "bac" {
+		t.Fatalf("unexpected order %s", ids)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	s := NewMemoryStore()
+	_, err := s.Announcement("missing")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	_, err = s.Attestation("missing")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWriteOnce(t *testing.T) {
+	s := NewMemoryStore()
+	err := s.PutNonce("event", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutNonce("event", [32]byte{2})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	k, err := s.Nonce("event")
+	if err != nil || k != [32]byte{1} {
+		t.Fatalf("nonce was overwritten: %x %v", k, err)
+	}
+
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event"})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+}
+
+func TestRevocations(t *testing.T) {
+	s := NewMemoryStore()
+
+	_, err := s.Revocation("event")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	r := dlcoracle.Revocation{
+		EventID:   "event",
+		Reason:    "cancelled",
+		RevokedAt: time.Unix(1700000000, 0).UTC(),
+	}
+	err = s.PutRevocation(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutRevocation(dlcoracle.Revocation{EventID: "event"})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Revocation("event")
+	if err != nil || got != r {
+		t.Fatalf("unexpected revocation %+v %v", got, err)
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	s := NewMemoryStore()
+
+	_, err := s.Evidence("event")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	e := dlcoracle.Evidence{
+		EventID: "event",
+		Responses: []dlcoracle.UpstreamResponse{{
+			Source:      "kraken",
+			URL:         "https://api.kraken.com/0/public/Ticker?pair=XBTUSD",
+			Status:      200,
+			Body:        []byte(`{"result":{}}`),
+			ReceivedAt:  time.UnixMilli(1700000000123).UTC(),
+			RequestedAt: time.UnixMilli(1700000000000).UTC(),
+		}},
+		CapturedAt: time.UnixMilli(1700000001000).UTC(),
+	}
+	err = s.PutEvidence(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutEvidence(dlcoracle.Evidence{EventID: "event"})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Evidence("event")
+	if err != nil || got.SigningHash() != e.SigningHash() {
+		t.Fatalf("unexpected evidence %+v %v", got, err)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	s := NewMemoryStore()
+
+	_, err := s.Assignment([33]byte{2})
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	a := NonceAssignment{RPoint: [33]byte{2}, EventID: "event", Commitment: [32]byte{1}}
+	err = s.PutAssignment(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAssignment(NonceAssignment{RPoint: [33]byte{2}, EventID: "other"})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Assignment([33]byte{2})
+	if err != nil || got != a {
+		t.Fatalf("unexpected assignment %+v %v", got, err)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	s := NewMemoryStore()
+
+	_, err := s.Reservation("event")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	err = s.PutReservation("event", []byte{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutReservation("event", []byte{3})
+	if err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	got, err := s.Reservation("event")
+	if err != nil || string(got) != string([]byte{1, 2}) {
+		t.Fatalf("unexpected reservation %x %v", got, err)
+	}
+}
+
+func TestTimes(t *testing.T) {
+	s := NewMemoryStore()
+
+	_, err := s.AnnouncementTime("event")
+	if err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	before := time.Now().Add(-time.Second)
+	err = s.PutAnnouncement(dlcoracle.Announcement{EventID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.PutAttestation(dlcoracle.Attestation{EventID: "event", Message: []byte{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, get := range []func(string) (time.Time, error){s.AnnouncementTime, s.AttestationTime} {
+		stored, err := get("event")
+		if err != nil || stored.Before(before) || stored.After(time.Now()) || stored.Nanosecond() != 0 {
+			t.Fatalf("stored at %s, %v", stored, err)
+		}
+	}
+}
+
+func TestNonceIndex(t *testing.T) {
+	s := NewMemoryStore()
+	s.NextNonceIndex()
+	err := s.RaiseNonceIndex(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.RaiseNonceIndex(3)
+	i, err := s.NonceIndex()
+	if err != nil || i != 5 {
+		t.Fatalf("unexpected nonce index %d %v", i, err)
+	}
+	i, _ = s.NextNonceIndex()
+	if i != 5 {
+		t.Fatalf("raised index %d not used next", i)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := NewMemoryStore()
+	digits := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 5, Unit: "usd/btc"}
+	enum := dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: []string{"yes", "no"}}
+	for i, a := range []dlcoracle.Announcement{
+		{EventID: "a", Descriptor: digits, Metadata: dlcoracle.EventMetadata{Category: "crypto", Tags: []string{"btc"}}},
+		{EventID: "b", Descriptor: digits},
+		{EventID: "c", Descriptor: enum, Metadata: dlcoracle.EventMetadata{Category: "weather"}},
+		{EventID: "d", Descriptor: enum},
+	} {
+		a.Maturity = time.Unix(int64(1000*(i+1)), 0)
+		err := s.PutAnnouncement(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.PutAttestation(dlcoracle.Attestation{EventID: "b"})
+	s.PutAttestation(dlcoracle.Attestation{EventID: "c"})
+	s.PutRevocation(dlcoracle.Revocation{EventID: "c"})
+
+	for _, c := range []struct {
+		q    Query
+		want string
+	}{
+		{Query{}, "abcd"},
+		{Query{Unit: "usd/btc"}, "ab"},
+		{Query{Types: []dlcoracle.EventType{dlcoracle.EventTypeEnum}}, "cd"},
+		{Query{Types: []dlcoracle.EventType{dlcoracle.EventTypeEnum, dlcoracle.EventTypeDigits}}, "abcd"},
+		{Query{MaturesAfter: time.Unix(2000, 0), MaturesBefore: time.Unix(4000, 0)}, "bc"},
+		{Query{Status: StatusAnnounced}, "ad"},
+		{Query{Status: StatusAttested}, "b"},
+		{Query{Status: StatusRevoked}, "c"},
+		{Query{Category: "crypto", Tags: []string{"btc"}}, "a"},
+		{Query{Unit: "usd/btc", Status: StatusRevoked}, ""},
+		{Query{Limit: 3}, "abc"},
+		{Query{After: Cursor{Maturity: time.Unix(2000, 0), EventID: "b"}}, "cd"},
+		{Query{After: Cursor{Maturity: time.Unix(2000, 0), EventID: "a"}, Limit: 1}, "b"},
+		{Query{After: Cursor{Maturity: time.Unix(1000, 0)}, Types: []dlcoracle.EventType{dlcoracle.EventTypeEnum}, Limit: 1}, "c"},
+	} {
+		list, err := Search(s, c.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, a := range list {
+			got += a.EventID
+		}
+		if got != c.want {
+			t.Fatalf("%+v: got %q, want %q", c.q, got, c.want)
+		}
+	}
+	status, err := ParseEventStatus("attested")
+	if err != nil || status != StatusAttested {
+		t.Fatalf("parsed %s %v", status, err)
+	}
+
+	// Listing attestations a page at a time skips the unattested events
+	q := Query{Limit: 1}
+	got := ""
+	for {
+		page, err := Attestations(s, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		got += page[0].EventID
+		q.After, err = NewCursor(s, page[0].EventID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got != "bc" {
+		t.Fatalf("paged through attestations %q", got)
+	}
+}
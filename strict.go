@@ -0,0 +1,91 @@
+package dlcoracle
+
+import "fmt"
+
+// Strict verification rejects encodings the regular functions let
+// through because they still verify, such as signatures of s+N, so that
+// everyone validating third-party attestations accepts exactly the same
+// ones. The errors say which rule failed: a ScalarError wrapping
+// ErrScalarOutOfRange or ErrZeroScalar for s, a PubKeyError wrapping
+// ErrPointAtInfinity, ErrInvalidPrefix or ErrNotOnCurve for a key or R
+// point, and ErrMessageTooLong.
+
+// MaxMessageLength is the longest message strict verification accepts
+const MaxMessageLength = 1024
+
+// WithStrict makes Verify apply the rules of VerifySignatureStrict to the
+// public key, message and signature
+func WithStrict() SignOption {
+	return func(c *signConfig) {
+		c.strict = true
+	}
+}
+
+// VerifySignatureStrict is VerifySignature rejecting non-canonical
+// encodings: s not below the curve order or zero, a public key or R point
+// at infinity or off the curve, and messages over MaxMessageLength.
+func VerifySignatureStrict(oraclePubA, oraclePubR [33]byte, message []byte, sig [32]byte) error {
+	err := checkStrict(oraclePubA, oraclePubR, message, sig)
+	if err != nil {
+		return err
+	}
+	return VerifySignature(oraclePubA, oraclePubR, message, sig)
+}
+
+// VerifyAttestationStrict is VerifyAttestation checking the announced
+// keys and every signature with the rules of VerifySignatureStrict. The
+// errors of those rules are wrapped along with ErrInvalidAttestation.
+func VerifyAttestationStrict(a Announcement, att Attestation) (Outcome, error) {
+	err := checkStrictAttestation(a, att)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("%w: event %q: %w", ErrInvalidAttestation, a.EventID, err)
+	}
+	return VerifyAttestation(a, att)
+}
+
+// checkStrictAttestation checks the signatures of att under the keys of a.
+// Mismatched digit counts are left to VerifyAttestation.
+func checkStrictAttestation(a Announcement, att Attestation) error {
+	if a.Descriptor.Type != EventTypeDigits {
+		return checkStrict(a.OraclePubKey, a.RPoint, att.Message, att.Signature)
+	}
+	err := checkScalar("signature", att.Signature)
+	if err != nil {
+		return err
+	}
+	for i, sig := range att.Signatures {
+		if i >= len(a.RPoints) || i >= len(att.Message) {
+			break
+		}
+		err = checkStrict(a.OraclePubKey, a.RPoints[i], DigitMessage(att.Message[i]), sig)
+		if err != nil {
+			return fmt.Errorf("digit %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkStrict checks the encodings of a signature s of message with R
+// under pubKey
+func checkStrict(pubKey, R [33]byte, message []byte, s [32]byte) error {
+	err := checkStrictMessage(message)
+	if err != nil {
+		return err
+	}
+	_, err = parsePubKey("oracle pubkey", pubKey)
+	if err != nil {
+		return err
+	}
+	_, err = parsePubKey("r point", R)
+	if err != nil {
+		return err
+	}
+	return checkScalar("signature", s)
+}
+
+func checkStrictMessage(message []byte) error {
+	if len(message) > MaxMessageLength {
+		return fmt.Errorf("%w: %d bytes, at most %d", ErrMessageTooLong, len(message), MaxMessageLength)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifySignatureStrict(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	pub, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+	msg := []byte("outcome")
+	sig, err := ComputeSignature(priv, k, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignatureStrict(pub, R, msg, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var highS [32]byte
+	for i := range highS {
+		highS[i] = 0xff
+	}
+	// x = 0 is not on the curve
+	offCurve := [33]byte{0x02}
+	long := bytes.Repeat([]byte{'a'}, MaxMessageLength+1)
+	tests := []struct {
+		name    string
+		pub, R  [33]byte
+		message []byte
+		sig     [32]byte
+		want    error
+	}{
+		{"s out of range", pub, R, msg, highS, ErrScalarOutOfRange},
+		{"zero s", pub, R, msg, [32]byte{}, ErrZeroScalar},
+		{"r at infinity", pub, [33]byte{}, msg, sig, ErrPointAtInfinity},
+		{"pubkey at infinity", [33]byte{}, R, msg, sig, ErrPointAtInfinity},
+		{"pubkey off the curve", offCurve, R, msg, sig, ErrNotOnCurve},
+		{"r point prefix", pub, [33]byte{0x04, 1}, msg, sig, ErrInvalidPrefix},
+		{"long message", pub, R, long, sig, ErrMessageTooLong},
+	}
+	for _, tt := range tests {
+		err := VerifySignatureStrict(tt.pub, tt.R, tt.message, tt.sig)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	lit, err := Sign(priv, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, long, lit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(pub, long, lit, WithStrict())
+	if !errors.Is(err, ErrMessageTooLong) {
+		t.Fatalf("expected ErrMessageTooLong, got %v", err)
+	}
+	schnorr, _ := Sign(priv, msg, WithScheme(SchemeBIP340))
+	err = Verify(pub, msg, schnorr, WithScheme(SchemeBIP340), WithStrict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(schnorr[32:], highS[:])
+	err = Verify(pub, msg, schnorr, WithScheme(SchemeBIP340), WithStrict())
+	if !errors.Is(err, ErrScalarOutOfRange) {
+		t.Fatalf("expected ErrScalarOutOfRange, got %v", err)
+	}
+}
+
+func TestVerifyAttestationStrict(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	ev := Event{ID: "price", Maturity: time.Unix(1000, 0),
+		Descriptor: EventDescriptor{Type: EventTypeDigits, Base: 10, Digits: 2}}
+	a, err := NewAnnouncement(priv, k, ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	att, err := SignOutcome(priv, k, a, Outcome{Value: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := VerifyAttestationStrict(a, att)
+	if err != nil || out.Value != 42 {
+		t.Fatalf("attested %+v %v", out, err)
+	}
+
+	a.RPoints[1] = [33]byte{}
+	_, err = VerifyAttestationStrict(a, att)
+	if !errors.Is(err, ErrInvalidAttestation) || !errors.Is(err, ErrPointAtInfinity) {
+		t.Fatalf("expected ErrPointAtInfinity, got %v", err)
+	}
+	a.RPoints[1] = [33]byte{0x02}
+	_, err = VerifyAttestationStrict(a, att)
+	if !errors.Is(err, ErrInvalidPubKey) {
+		t.Fatalf("expected ErrInvalidPubKey, got %v", err)
+	}
+}
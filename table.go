@@ -0,0 +1,132 @@
+package dlcoracle
+
+import (
+	"fmt"
+	"sync"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// Batches of signature points under one public key A spend most of their
+// time multiplying A by each challenge hash. As R's coefficient is one,
+// R - eA is a single multiplication and an addition rather than two
+// multiplications a joint (Shamir) multiplication could share doublings
+// between. What can be shared is work across the batch: a table of the
+// multiples d*16^w*A of every 4-bit digit d of the hash, in affine form,
+// turns each multiplication into 64 mixed additions without doublings or
+// allocations, and the results are converted to affine form with a single
+// field inversion for the whole batch (Montgomery's trick). Building the
+// table takes about as long as 8 multiplications, so it is only built for
+// batches of at least minTableBatch messages, and tables are reused
+// through tablePool.
+
+// minTableBatch is the smallest batch a table is built for
+const minTableBatch = 16
+
+// pointTable holds d*16^w*A at [w][d]
+type pointTable [64][16]btcecv2.JacobianPoint
+
+var tablePool = sync.Pool{
+	New: func() interface{} {
+		return new(pointTable)
+	},
+}
+
+// build fills the table with the multiples of A, in affine form
+func (t *pointTable) build(A *btcecv2.JacobianPoint) {
+	var base, next btcecv2.JacobianPoint
+	base.Set(A)
+	entries := make([]*btcecv2.JacobianPoint, 0, 64*15)
+	for w := range t {
+		t[w][0] = btcecv2.JacobianPoint{}
+		t[w][1].Set(&base)
+		for d := 2; d < 16; d++ {
+			btcecv2.AddNonConst(&t[w][d-1], &base, &t[w][d])
+		}
+		btcecv2.AddNonConst(&t[w][15], &base, &next)
+		base.Set(&next)
+		for d := 1; d < 16; d++ {
+			entries = append(entries, &t[w][d])
+		}
+	}
+	normalizeBatch(entries)
+}
+
+// sum sets result to R - eA with A the table's point, in Jacobian form
+func (t *pointTable) sum(R *btcecv2.JacobianPoint, e *btcecv2.ModNScalar, result *btcecv2.JacobianPoint) {
+	var negE btcecv2.ModNScalar
+	negE.NegateVal(e)
+	digits := negE.Bytes()
+
+	var next btcecv2.JacobianPoint
+	result.Set(R)
+	for w := range t {
+		d := digits[31-w/2]
+		if w%2 == 1 {
+			d >>= 4
+		}
+		d &= 0x0f
+		if d == 0 {
+			continue
+		}
+		btcecv2.AddNonConst(result, &t[w][d], &next)
+		result.Set(&next)
+	}
+}
+
+// isInfinity reports whether p is the point at infinity
+func isInfinity(p *btcecv2.JacobianPoint) bool {
+	return (p.X.IsZero() && p.Y.IsZero()) || p.Z.IsZero()
+}
+
+// normalizeBatch converts points, none of them the point at infinity, to
+// affine form with one field inversion
+func normalizeBatch(points []*btcecv2.JacobianPoint) {
+	if len(points) == 0 {
+		return
+	}
+	// prefix[i] is the product of the Z coordinates of points[:i+1]
+	prefix := make([]btcecv2.FieldVal, len(points))
+	var acc btcecv2.FieldVal
+	acc.SetInt(1)
+	for i, p := range points {
+		p.Z.Normalize()
+		acc.Mul(&p.Z)
+		prefix[i].Set(&acc)
+	}
+	var inv, zInv, zInv2 btcecv2.FieldVal
+	inv.Set(&acc).Inverse()
+	for i := len(points) - 1; i >= 0; i-- {
+		p := points[i]
+		if i > 0 {
+			zInv.Mul2(&inv, &prefix[i-1])
+		} else {
+			zInv.Set(&inv)
+		}
+		inv.Mul(&p.Z)
+
+		zInv2.SquareVal(&zInv)
+		p.X.Mul(&zInv2)
+		p.Y.Mul(zInv2.Mul(&zInv))
+		p.Z.SetInt(1)
+		p.X.Normalize()
+		p.Y.Normalize()
+	}
+}
+
+// serializeBatch sets out to the compressed encodings of points, which it
+// converts to affine form
+func serializeBatch(points []btcecv2.JacobianPoint, out [][33]byte) error {
+	ptrs := make([]*btcecv2.JacobianPoint, len(points))
+	for i := range points {
+		if isInfinity(&points[i]) {
+			return fmt.Errorf("signature point %d: %w", i, ErrPointAtInfinity)
+		}
+		ptrs[i] = &points[i]
+	}
+	normalizeBatch(ptrs)
+	for i := range points {
+		out[i] = encodeAffine(&points[i])
+	}
+	return nil
+}
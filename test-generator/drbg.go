@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// drbgPersonalization separates the generator's output from other uses
+// of HMAC-DRBG with the same seed
+const drbgPersonalization = "dlc-oracle-go/test-generator"
+
+// drbg is HMAC-DRBG with SHA-256, as specified in NIST SP 800-90A, used
+// as a deterministic source of randomness. Output is generated in blocks
+// of 32 bytes, so it doesn't depend on how reads are split up: the same
+// seed always yields the same stream.
+//
+// It is only meant for reproducible test vectors. Seeds are passphrases,
+// not secrets.
+type drbg struct {
+	k, v []byte
+	buf  []byte
+}
+
+// newDRBG instantiates the generator with seed as its entropy input
+func newDRBG(seed []byte) *drbg {
+	d := &drbg{k: make([]byte, sha256.Size), v: make([]byte, sha256.Size)}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(append(append([]byte(nil), seed...), drbgPersonalization...))
+	return d
+}
+
+func (d *drbg) hmac(data ...[]byte) []byte {
+	h := hmac.New(sha256.New, d.k)
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum(nil)
+}
+
+// update is the HMAC_DRBG_Update function
+func (d *drbg) update(provided []byte) {
+	d.k = d.hmac(d.v, []byte{0x00}, provided)
+	d.v = d.hmac(d.v)
+	if len(provided) == 0 {
+		return
+	}
+	d.k = d.hmac(d.v, []byte{0x01}, provided)
+	d.v = d.hmac(d.v)
+}
+
+// block generates the next 32 bytes
+func (d *drbg) block() []byte {
+	d.v = d.hmac(d.v)
+	out := append([]byte(nil), d.v...)
+	d.update(nil)
+	return out
+}
+
+func (d *drbg) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.buf) == 0 {
+			d.buf = d.block()
+		}
+		m := copy(p[n:], d.buf)
+		d.buf = d.buf[m:]
+		n += m
+	}
+	return n, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDRBG(t *testing.T) {
+	// The first two blocks for the seed "vectors"; changing them changes
+	// every published seeded vector
+	expected := "91279aaa047e045306c689193ac5cf59d7c6b09487c8562155e8532b09cd1688" +
+		"75be3b639ded006f2e1e74978d76bcc8b2f6f587012bdb5c9438f557c9442f6f"
+	out := make([]byte, 64)
+	newDRBG([]byte("vectors")).Read(out)
+	if hex.EncodeToString(out) != expected {
+		t.Fatalf("got %x", out)
+	}
+
+	// Splitting reads doesn't change the stream
+	d := newDRBG([]byte("vectors"))
+	split := make([]byte, 64)
+	for _, r := range [][2]int{{0, 5}, {5, 40}, {40, 41}, {41, 64}} {
+		d.Read(split[r[0]:r[1]])
+	}
+	if !bytes.Equal(split, out) {
+		t.Fatalf("split reads gave %x", split)
+	}
+
+	other := make([]byte, 64)
+	newDRBG([]byte("vectors2")).Read(other)
+	if bytes.Equal(other, out) {
+		t.Fatal("different seeds gave the same stream")
+	}
+}
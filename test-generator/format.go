@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// conventions describes how the vectors are computed and encoded, for
+// implementers in other languages
+var conventions = map[string]string{
+	"curve":     "secp256k1",
+	"encoding":  "lowercase hex; scalars and messages are 32 bytes big endian, points 33 byte compressed",
+	"rPoint":    "R = oneTimeSigningKey * G",
+	"hash":      "e = SHA-256(message || x(R)), x(R) big endian without leading zero bytes",
+	"signature": "s = oneTimeSigningKey - e * privKey mod n",
+	"sigPoint":  "signaturePubKeyFromSig = s * G; signaturePubKeyFromMessage = R - e * pubKey, which must be equal",
+}
+
+// hexSink writes one .hex file per artifact with one value per line, the
+// original format of the vectors
+type hexSink struct {
+	dir     string
+	files   map[string]*os.File
+	writers map[string]*bufio.Writer
+}
+
+func newHexSink(dir string, selected map[string]bool, privKey [32]byte) (*hexSink, error) {
+	s := &hexSink{dir: dir, files: make(map[string]*os.File), writers: make(map[string]*bufio.Writer)}
+	for _, name := range artifacts {
+		if !selected[name] {
+			continue
+		}
+		f, err := os.Create(filepath.Join(dir, name+".hex"))
+		if err != nil {
+			s.close()
+			return nil, err
+		}
+		s.files[name] = f
+		s.writers[name] = bufio.NewWriter(f)
+	}
+	s.put("privkey", privKey[:])
+	return s, nil
+}
+
+func (s *hexSink) needs(artifact string) bool {
+	return s.writers[artifact] != nil
+}
+
+func (s *hexSink) put(artifact string, v []byte) {
+	if w, ok := s.writers[artifact]; ok {
+		fmt.Fprintf(w, "%x\n", v)
+	}
+}
+
+func (s *hexSink) write(v *vector) error {
+	s.put("one-time-signing-keys", v.OneTimeSigningKey[:])
+	s.put("messages", v.Message[:])
+	s.put("signatures", v.Signature[:])
+	s.put("signature-pubkeys-from-sig", v.SignaturePubKeyFromSig[:])
+	s.put("signature-pubkeys-from-message", v.SignaturePubKeyFromMessage[:])
+	return nil
+}
+
+func (s *hexSink) close() error {
+	var first error
+	for _, name := range artifacts {
+		f, ok := s.files[name]
+		if !ok {
+			continue
+		}
+		err := s.writers[name].Flush()
+		if err == nil {
+			err = f.Close()
+		} else {
+			f.Close()
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// vectorJSON is a row of the JSON and CSV formats
+type vectorJSON struct {
+	PrivKey                    string `json:"privKey"`
+	PubKey                     string `json:"pubKey"`
+	OneTimeSigningKey          string `json:"oneTimeSigningKey"`
+	RPoint                     string `json:"rPoint"`
+	Message                    string `json:"message"`
+	Signature                  string `json:"signature"`
+	SignaturePubKeyFromSig     string `json:"signaturePubKeyFromSig"`
+	SignaturePubKeyFromMessage string `json:"signaturePubKeyFromMessage"`
+}
+
+func (v *vector) row() vectorJSON {
+	return vectorJSON{
+		PrivKey:                    hex.EncodeToString(v.PrivKey[:]),
+		PubKey:                     hex.EncodeToString(v.PubKey[:]),
+		OneTimeSigningKey:          hex.EncodeToString(v.OneTimeSigningKey[:]),
+		RPoint:                     hex.EncodeToString(v.RPoint[:]),
+		Message:                    hex.EncodeToString(v.Message[:]),
+		Signature:                  hex.EncodeToString(v.Signature[:]),
+		SignaturePubKeyFromSig:     hex.EncodeToString(v.SignaturePubKeyFromSig[:]),
+		SignaturePubKeyFromMessage: hex.EncodeToString(v.SignaturePubKeyFromMessage[:]),
+	}
+}
+
+// jsonSink writes vectors.json: an object with the conventions and the
+// vectors as an array of rows. Rows are streamed, so large suites aren't
+// held in memory.
+type jsonSink struct {
+	f     *os.File
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONSink(dir string, count int) (*jsonSink, error) {
+	f, err := os.Create(filepath.Join(dir, "vectors.json"))
+	if err != nil {
+		return nil, err
+	}
+	s := &jsonSink{f: f, w: bufio.NewWriter(f)}
+	header, err := json.Marshal(struct {
+		Conventions map[string]string `json:"conventions"`
+		Count       int               `json:"count"`
+	}{conventions, count})
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Splice the vectors array into the header object
+	s.w.Write(header[:len(header)-1])
+	s.w.WriteString(`,"vectors":[`)
+	return s, nil
+}
+
+func (s *jsonSink) needs(artifact string) bool {
+	return true
+}
+
+func (s *jsonSink) write(v *vector) error {
+	b, err := json.Marshal(v.row())
+	if err != nil {
+		return err
+	}
+	if s.count > 0 {
+		s.w.WriteString(",")
+	}
+	s.w.WriteString("\n")
+	s.w.Write(b)
+	s.count++
+	return nil
+}
+
+func (s *jsonSink) close() error {
+	s.w.WriteString("\n]}\n")
+	err := s.w.Flush()
+	if err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// csvColumns are the columns of vectors.csv
+var csvColumns = []string{
+	"privKey", "pubKey", "oneTimeSigningKey", "rPoint", "message",
+	"signature", "signaturePubKeyFromSig", "signaturePubKeyFromMessage",
+}
+
+// csvSink writes vectors.csv: the conventions as comment lines starting
+// with #, a header row and a row per vector
+type csvSink struct {
+	f *os.File
+	w *csv.Writer
+}
+
+func newCSVSink(dir string) (*csvSink, error) {
+	f, err := os.Create(filepath.Join(dir, "vectors.csv"))
+	if err != nil {
+		return nil, err
+	}
+	b := bufio.NewWriter(f)
+	for _, key := range []string{"curve", "encoding", "rPoint", "hash", "signature", "sigPoint"} {
+		fmt.Fprintf(b, "# %s: %s\n", key, conventions[key])
+	}
+	b.Flush()
+	s := &csvSink{f: f, w: csv.NewWriter(f)}
+	s.w.Write(csvColumns)
+	return s, nil
+}
+
+func (s *csvSink) needs(artifact string) bool {
+	return true
+}
+
+func (s *csvSink) write(v *vector) error {
+	r := v.row()
+	return s.w.Write([]string{
+		r.PrivKey, r.PubKey, r.OneTimeSigningKey, r.RPoint, r.Message,
+		r.Signature, r.SignaturePubKeyFromSig, r.SignaturePubKeyFromMessage,
+	})
+}
+
+func (s *csvSink) close() error {
+	s.w.Flush()
+	err := s.w.Error()
+	if err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
@@ -1,72 +1,95 @@
+// Command test-generator writes test vectors for libraries implementing
+// the oracle's signatures in other languages, and with verify-vectors
+// checks vectors written by it or by another implementation.
 package main
 
 import (
-	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-
-	"github.com/mit-dci/dlc-oracle-go"
+	"strings"
 )
 
-var Log = log.New(os.Stdout,
-	"INFO: ",
-	log.Ldate|log.Ltime|log.Lshortfile)
-
-func main() {
-	Log.Printf("Generate files for testing on libraries in other languages.\n")
-
-	os.RemoveAll("testdata")
-	os.Mkdir("testdata", 0755)
-	var privKey [32]byte
-	rand.Read(privKey[:])
-	privKeyFile, _ := os.Create("testdata/privkey.hex")
-	defer privKeyFile.Close()
-	privKeyFile.WriteString(fmt.Sprintf("%x\n", privKey))
-
-	pubKey := dlcoracle.PublicKeyFromPrivateKey(privKey)
-
-	otsKeys, _ := os.Create("testdata/one-time-signing-keys.hex")
-	messages, _ := os.Create("testdata/messages.hex")
-	sigs, _ := os.Create("testdata/signatures.hex")
-	sGsFromSig, _ := os.Create("testdata/signature-pubkeys-from-sig.hex")
-	sGsFromMsg, _ := os.Create("testdata/signature-pubkeys-from-message.hex")
-	defer otsKeys.Close()
-	defer messages.Close()
-	defer sigs.Close()
-	defer sGsFromSig.Close()
-	defer sGsFromMsg.Close()
+// artifacts lists the files that can be written, in the order they are
+// generated in
+var artifacts = []string{
+	"privkey",
+	"one-time-signing-keys",
+	"messages",
+	"signatures",
+	"signature-pubkeys-from-sig",
+	"signature-pubkeys-from-message",
+}
 
-	for i := 0; i < 100000; i++ {
-		otsKey, _ := dlcoracle.GenerateOneTimeSigningKey()
-		otsKeys.WriteString(fmt.Sprintf("%x\n", otsKey))
+type config struct {
+	count     int
+	dir       string
+	format    string
+	artifacts map[string]bool
+	records   int
+	seed      string
+	quiet     bool
+}
 
-		rPoint := dlcoracle.PublicKeyFromPrivateKey(otsKey)
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "test-generator:", err)
+		os.Exit(1)
+	}
+}
 
-		var message [32]byte
-		rand.Read(message[:])
-		messages.WriteString(fmt.Sprintf("%x\n", message))
+func run(args []string, out io.Writer) error {
+	if len(args) != 0 && args[0] == "verify-vectors" {
+		return verifyVectors(args[1:], out)
+	}
+	fs := flag.NewFlagSet("test-generator", flag.ContinueOnError)
+	fs.SetOutput(out)
+	count := fs.Int("n", 100000, "number of vectors")
+	dir := fs.String("out", "testdata", "output directory, created if missing")
+	format := fs.String("format", "hex", "output format: hex files per artifact, or a single json or csv file")
+	list := fs.String("artifacts", strings.Join(artifacts, ","), "comma separated files to write in hex format")
+	records := fs.Int("records", 100, "number of announced and attested events written to records.json")
+	seed := fs.String("seed", "", "derive all keys, messages and nonces from this seed instead of random ones")
+	quiet := fs.Bool("quiet", false, "don't report progress")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *count < 0 {
+		return fmt.Errorf("negative vector count %d", *count)
+	}
+	if *records < 0 {
+		return fmt.Errorf("negative record count %d", *records)
+	}
 
-		sig, err := dlcoracle.ComputeSignature(privKey, otsKey, message[:])
-		if err != nil {
-			panic(err)
+	cfg := config{count: *count, dir: *dir, format: *format, records: *records, seed: *seed, quiet: *quiet, artifacts: make(map[string]bool)}
+	for _, name := range strings.Split(*list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
-		sigs.WriteString(fmt.Sprintf("%x\n", sig))
-
-		sGFromSig := dlcoracle.PublicKeyFromPrivateKey(sig)
-		sGsFromSig.WriteString(fmt.Sprintf("%x\n", sGFromSig))
-
-		sGFromMsg, err := dlcoracle.ComputeSignaturePubKey(pubKey, rPoint, message[:])
-		if err != nil {
-			panic(err)
+		if !known(name) {
+			return fmt.Errorf("unknown artifact %q, expected one of %s", name, strings.Join(artifacts, ", "))
 		}
-		sGsFromMsg.WriteString(fmt.Sprintf("%x\n", sGFromMsg))
+		cfg.artifacts[name] = true
+	}
+	if len(cfg.artifacts) == 0 {
+		return fmt.Errorf("no artifacts selected")
+	}
+	return generate(cfg, out)
+}
 
-		if i%100 == 0 {
-			fmt.Printf("\rWriting test files ... [%d/100000]", i)
+func known(name string) bool {
+	for _, a := range artifacts {
+		if a == name {
+			return true
 		}
-
 	}
-	fmt.Println("\rWriting test files ... 100% completed\nDone.")
-
+	return false
 }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func readLines(t *testing.T, dir, name string) []string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name+".hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := run([]string{"-n", "20", "-out", dir, "-seed", "vectors", "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("quiet mode wrote %q", out.String())
+	}
+	for _, name := range artifacts[1:] {
+		if n := len(readLines(t, dir, name)); n != 20 {
+			t.Fatalf("%s has %d lines", name, n)
+		}
+	}
+
+	// The vectors verify
+	var priv [32]byte
+	copy(priv[:], mustHex(t, readLines(t, dir, "privkey")[0]))
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	keys := readLines(t, dir, "one-time-signing-keys")
+	msgs := readLines(t, dir, "messages")
+	sigs := readLines(t, dir, "signatures")
+	for i := range sigs {
+		var k, sig [32]byte
+		copy(k[:], mustHex(t, keys[i]))
+		copy(sig[:], mustHex(t, sigs[i]))
+		err = dlcoracle.VerifySignature(pub, dlcoracle.PublicKeyFromPrivateKey(k), mustHex(t, msgs[i]), sig)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+	}
+
+	// Seeded runs are reproducible
+	again := t.TempDir()
+	err = run([]string{"-n", "20", "-out", again, "-seed", "vectors", "-quiet", "-artifacts", "signatures"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(readLines(t, again, "signatures"), "") != strings.Join(sigs, "") {
+		t.Fatal("seeded vectors differ")
+	}
+	_, err = os.Stat(filepath.Join(again, "messages.hex"))
+	if !os.IsNotExist(err) {
+		t.Fatal("unselected artifact written")
+	}
+}
+
+func TestInvalidArtifact(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"-out", t.TempDir(), "-artifacts", "nonces"}, &out)
+	if err == nil {
+		t.Fatal("unknown artifact accepted")
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestStructuredFormats(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := run([]string{"-n", "3", "-out", dir, "-format", "json", "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Conventions map[string]string `json:"conventions"`
+		Count       int               `json:"count"`
+		Vectors     []vectorJSON      `json:"vectors"`
+	}
+	err = json.Unmarshal(b, &suite)
+	if err != nil {
+		t.Fatalf("%v in %s", err, b)
+	}
+	if suite.Count != 3 || len(suite.Vectors) != 3 || suite.Conventions["hash"] == "" {
+		t.Fatalf("unexpected suite %s", b)
+	}
+	for i, v := range suite.Vectors {
+		if v.SignaturePubKeyFromSig != v.SignaturePubKeyFromMessage || v.PrivKey != suite.Vectors[0].PrivKey {
+			t.Fatalf("inconsistent vector %d: %+v", i, v)
+		}
+	}
+
+	err = run([]string{"-n", "3", "-out", dir, "-format", "csv", "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(dir, "vectors.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][5] != "signature" {
+		t.Fatalf("unexpected rows %v", rows)
+	}
+
+	err = run([]string{"-out", dir, "-format", "xml"}, &out)
+	if err == nil {
+		t.Fatal("unknown format accepted")
+	}
+}
+
+func TestRecords(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := run([]string{"-n", "0", "-records", "8", "-out", dir, "-seed", "records", "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "records.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suite struct {
+		Conventions map[string]string `json:"conventions"`
+		Vectors     []recordVector    `json:"vectors"`
+	}
+	err = json.Unmarshal(b, &suite)
+	if err != nil {
+		t.Fatalf("%v in %s", err, b)
+	}
+	if len(suite.Vectors) != 8 || suite.Conventions["announcement"] == "" {
+		t.Fatalf("unexpected suite %s", b)
+	}
+	types := make(map[dlcoracle.EventType]bool)
+	for i, v := range suite.Vectors {
+		types[v.Announcement.Descriptor.Type] = true
+		err = v.Announcement.Verify()
+		if err != nil {
+			t.Fatalf("announcement %d: %v", i, err)
+		}
+		outcome, err := dlcoracle.VerifyAttestation(v.Announcement, v.Attestation)
+		if err != nil {
+			t.Fatalf("attestation %d: %v", i, err)
+		}
+		msg, err := v.Announcement.Descriptor.OutcomeMessage(outcome)
+		if err != nil || hex.EncodeToString(msg) != v.OutcomeMessage {
+			t.Fatalf("vector %d: outcome %+v does not match message %s", i, outcome, v.OutcomeMessage)
+		}
+		digest := v.Announcement.SigningHash()
+		point := dlcoracle.PublicKeyFromPrivateKey(v.Attestation.Signature)
+		if hex.EncodeToString(digest[:]) != v.SigningHash || hex.EncodeToString(point[:]) != v.SignaturePoint {
+			t.Fatalf("vector %d has wrong intermediate values", i)
+		}
+	}
+	if len(types) != 4 {
+		t.Fatalf("expected all event types, got %v", types)
+	}
+
+	// Seeded records are reproducible, announcement signatures included
+	again := t.TempDir()
+	err = run([]string{"-n", "0", "-records", "8", "-out", again, "-seed", "records", "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := os.ReadFile(filepath.Join(again, "records.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, b2) {
+		t.Fatal("seeded records differ")
+	}
+}
+
+// TestGoldenVectors checks the vectors embedded in package testvectors are
+// those its go:generate directive writes
+func TestGoldenVectors(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := run([]string{"-n", "32", "-records", "8", "-format", "json", "-seed", "dlc-oracle-go golden vectors", "-out", dir, "-quiet"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"vectors.json", "records.json"} {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden, err := os.ReadFile(filepath.Join("..", "testvectors", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, golden) {
+			t.Fatalf("testvectors/%s is out of date, run go generate ./testvectors", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// recordBaseMaturity is the maturity of the first record vector; each
+// following one matures an hour later
+var recordBaseMaturity = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recordConventions describes the record vectors
+var recordConventions = map[string]string{
+	"announcement":   "JSON of the REST API; signature is R || s of SignMessage over signingHash",
+	"signingHash":    "SHA-256 of the tag, event ID, keys, maturity and descriptor, see Announcement.SigningHash",
+	"outcomeMessage": "numeric: 32 byte big endian value; enum: UTF-8 label; bytes: the bytes themselves",
+	"attestation":    "JSON of the REST API; signature is s for the announced R point",
+	"signaturePoint": "R - e * pubKey for the outcome message, equal to s * G; for digits events that of the first digit",
+	"numeric":        "numeric outcomes are signed as a whole, one R point per event",
+	"digits":         "digits events sign each digit's decimal string with the R point of rPoints at its position, most significant first; the outcome message is one byte per digit",
+}
+
+// recordVector is a full event, cycling through numeric, enum, bytes and
+// digits events: its announcement and attestation, with the intermediate values
+// needed to check them
+type recordVector struct {
+	Announcement   dlcoracle.Announcement `json:"announcement"`
+	SigningHash    string                 `json:"signingHash"`
+	Outcome        dlcoracle.Outcome      `json:"outcome"`
+	OutcomeMessage string                 `json:"outcomeMessage"`
+	SignaturePoint string                 `json:"signaturePoint"`
+	Attestation    dlcoracle.Attestation  `json:"attestation"`
+}
+
+// generateRecords writes records.json, with count announced and attested
+// events
+func generateRecords(dir string, count int, privKey [32]byte, random io.Reader) error {
+	vectors := make([]recordVector, count)
+	for i := range vectors {
+		v, err := newRecordVector(i, privKey, random)
+		if err != nil {
+			return fmt.Errorf("record vector %d: %v", i, err)
+		}
+		vectors[i] = v
+	}
+
+	f, err := os.Create(filepath.Join(dir, "records.json"))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(struct {
+		Conventions map[string]string `json:"conventions"`
+		PrivKey     string            `json:"privKey"`
+		Vectors     []recordVector    `json:"vectors"`
+	}{recordConventions, hex.EncodeToString(privKey[:]), vectors})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func newRecordVector(i int, privKey [32]byte, random io.Reader) (recordVector, error) {
+	var v recordVector
+	var pick [8]byte
+	_, err := io.ReadFull(random, pick[:])
+	if err != nil {
+		return v, err
+	}
+
+	var desc dlcoracle.EventDescriptor
+	switch i % 4 {
+	case 0:
+		desc = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric}
+		v.Outcome = dlcoracle.Outcome{Value: int64(pick[0])<<16 | int64(pick[1])<<8 | int64(pick[2])}
+	case 1:
+		outcomes := []string{"yes", "no", "draw"}
+		desc = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeEnum, Outcomes: outcomes}
+		v.Outcome = dlcoracle.Outcome{Label: outcomes[int(pick[0])%len(outcomes)]}
+	case 2:
+		desc = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeBytes}
+		v.Outcome = dlcoracle.Outcome{Bytes: pick[:]}
+	case 3:
+		desc = dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeDigits, Base: 10, Digits: 5}
+		v.Outcome = dlcoracle.Outcome{Value: (int64(pick[0])<<8 | int64(pick[1])) % 100000}
+	}
+
+	k, err := scalar(random)
+	if err != nil {
+		return v, err
+	}
+	v.Announcement = dlcoracle.Announcement{
+		EventID:      fmt.Sprintf("vector-%d", i),
+		OraclePubKey: dlcoracle.PublicKeyFromPrivateKey(privKey),
+		RPoint:       dlcoracle.PublicKeyFromPrivateKey(k),
+		Maturity:     recordBaseMaturity.Add(time.Duration(i) * time.Hour),
+		Descriptor:   desc,
+	}
+	if desc.Type == dlcoracle.EventTypeDigits {
+		v.Announcement.RPoints, err = dlcoracle.DigitRPoints(k, int(desc.Digits))
+		if err != nil {
+			return v, err
+		}
+		v.Announcement.RPoint = v.Announcement.RPoints[0]
+	}
+	err = v.Announcement.Sign(privKey, dlcoracle.WithAuxRand(random))
+	if err != nil {
+		return v, err
+	}
+	digest := v.Announcement.SigningHash()
+	v.SigningHash = hex.EncodeToString(digest[:])
+
+	msg, err := desc.OutcomeMessage(v.Outcome)
+	if err != nil {
+		return v, err
+	}
+	v.OutcomeMessage = hex.EncodeToString(msg)
+	v.Attestation, err = dlcoracle.SignOutcome(privKey, k, v.Announcement, v.Outcome)
+	if err != nil {
+		return v, err
+	}
+	signed := msg
+	if desc.Type == dlcoracle.EventTypeDigits {
+		signed = dlcoracle.DigitMessage(msg[0])
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(v.Announcement.OraclePubKey, v.Announcement.RPoint, signed)
+	if err != nil {
+		return v, err
+	}
+	v.SignaturePoint = hex.EncodeToString(point[:])
+	return v, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+
+	btcec "github.com/btcsuite/btcd/btcec/v2"
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// progressInterval is how many vectors are written between progress
+// reports
+const progressInterval = 1000
+
+// vector is one signature test vector. The private key is the same for
+// all vectors of a suite.
+type vector struct {
+	PrivKey                    [32]byte
+	PubKey                     [33]byte
+	OneTimeSigningKey          [32]byte
+	RPoint                     [33]byte
+	Message                    [32]byte
+	Signature                  [32]byte
+	SignaturePubKeyFromSig     [33]byte
+	SignaturePubKeyFromMessage [33]byte
+}
+
+// sink writes vectors in one of the output formats
+type sink interface {
+	// needs reports whether the artifact is written, so computing it
+	// can be skipped otherwise
+	needs(artifact string) bool
+	write(v *vector) error
+	close() error
+}
+
+// generate writes cfg.count vectors and cfg.records record vectors to
+// cfg.dir. Existing files are overwritten.
+func generate(cfg config, out io.Writer) error {
+	var random io.Reader = rand.Reader
+	if cfg.seed != "" {
+		random = newDRBG([]byte(cfg.seed))
+	}
+	err := os.MkdirAll(cfg.dir, 0755)
+	if err != nil {
+		return err
+	}
+	var v vector
+	v.PrivKey, err = scalar(random)
+	if err != nil {
+		return err
+	}
+	v.PubKey = dlcoracle.PublicKeyFromPrivateKey(v.PrivKey)
+
+	var s sink
+	switch cfg.format {
+	case "hex":
+		s, err = newHexSink(cfg.dir, cfg.artifacts, v.PrivKey)
+	case "json":
+		s, err = newJSONSink(cfg.dir, cfg.count)
+	case "csv":
+		s, err = newCSVSink(cfg.dir)
+	default:
+		err = fmt.Errorf("unknown format %q", cfg.format)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < cfg.count; i++ {
+		err = nextVector(&v, random, s)
+		if err == nil {
+			err = s.write(&v)
+		}
+		if err != nil {
+			s.close()
+			return fmt.Errorf("vector %d: %v", i, err)
+		}
+		if !cfg.quiet && i%progressInterval == 0 {
+			fmt.Fprintf(out, "\rWriting test files ... [%d/%d]", i, cfg.count)
+		}
+	}
+	err = s.close()
+	if err != nil {
+		return err
+	}
+	if cfg.records > 0 {
+		err = generateRecords(cfg.dir, cfg.records, v.PrivKey, random)
+		if err != nil {
+			return err
+		}
+	}
+	if !cfg.quiet {
+		fmt.Fprintf(out, "\rWriting test files ... 100%% completed\nWrote %d vectors and %d records to %s\n", cfg.count, cfg.records, cfg.dir)
+	}
+	return nil
+}
+
+// nextVector fills v with a new one-time signing key and message, and
+// the artifacts derived from them that s needs
+func nextVector(v *vector, random io.Reader, s sink) error {
+	var err error
+	v.OneTimeSigningKey, err = scalar(random)
+	if err != nil {
+		return err
+	}
+	v.RPoint = dlcoracle.PublicKeyFromPrivateKey(v.OneTimeSigningKey)
+	_, err = io.ReadFull(random, v.Message[:])
+	if err != nil {
+		return err
+	}
+	if s.needs("signatures") || s.needs("signature-pubkeys-from-sig") {
+		v.Signature, err = dlcoracle.ComputeSignature(v.PrivKey, v.OneTimeSigningKey, v.Message[:])
+		if err != nil {
+			return err
+		}
+		v.SignaturePubKeyFromSig = dlcoracle.PublicKeyFromPrivateKey(v.Signature)
+	}
+	if s.needs("signature-pubkeys-from-message") {
+		v.SignaturePubKeyFromMessage, err = dlcoracle.ComputeSignaturePubKey(v.PubKey, v.RPoint, v.Message[:])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scalar reads a valid private scalar from random
+func scalar(random io.Reader) ([32]byte, error) {
+	for {
+		var k [32]byte
+		_, err := io.ReadFull(random, k[:])
+		if err != nil {
+			return k, err
+		}
+		// Zero and values of at least N are about 2**-128 likely
+		var s btcec.ModNScalar
+		if s.SetBytes(&k) == 0 && !s.IsZero() {
+			return k, nil
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// verifyVectors checks the vectors in a directory against the library:
+// vectors.json, vectors.csv, the hex files and records.json, whichever
+// are there. It stops at the first mismatch, reported with the file and
+// line it was found on.
+func verifyVectors(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("test-generator verify-vectors", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dir := fs.String("dir", "testdata", "directory holding the vectors")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	// The hex files are checked if one-time-signing-keys.hex exists
+	checks := []struct {
+		name, file string
+		check      func(path string) (int, error)
+	}{
+		{"vectors.json", "vectors.json", verifyJSON},
+		{"vectors.csv", "vectors.csv", verifyCSV},
+		{"*.hex", "one-time-signing-keys.hex", func(string) (int, error) { return verifyHex(*dir) }},
+		{"records.json", "records.json", verifyRecords},
+	}
+	found := false
+	for _, c := range checks {
+		path := filepath.Join(*dir, c.file)
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		n, err := c.check(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s: %d vectors verified\n", c.name, n)
+	}
+	if !found {
+		return fmt.Errorf("no vectors in %s", *dir)
+	}
+	return nil
+}
+
+// lineError reports a mismatch on a line of a vector file
+func lineError(path string, line int, err error) error {
+	return fmt.Errorf("%s:%d: %v", path, line, err)
+}
+
+// fieldError is a field of a vector that doesn't match the library
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.field + ": " + e.err.Error()
+}
+
+// decodeField decodes a hex field of n bytes, or of any length if n is 0
+func decodeField(name, s string, n int) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, &fieldError{name, err}
+	}
+	if n != 0 && len(b) != n {
+		return nil, &fieldError{name, fmt.Errorf("%d bytes, expected %d", len(b), n)}
+	}
+	return b, nil
+}
+
+// compareField compares a field of a vector, if it is set, to the value
+// the library computes
+func compareField(name, s string, want []byte) error {
+	if s == "" {
+		return nil
+	}
+	if !strings.EqualFold(s, hex.EncodeToString(want)) {
+		return &fieldError{name, fmt.Errorf("%s, expected %x", s, want)}
+	}
+	return nil
+}
+
+// checkVector recomputes a signature vector from its private key,
+// one-time signing key and message, and compares the other fields that
+// are set
+func checkVector(v vectorJSON) error {
+	var priv, k [32]byte
+	b, err := decodeField("privKey", v.PrivKey, 32)
+	if err != nil {
+		return err
+	}
+	copy(priv[:], b)
+	b, err = decodeField("oneTimeSigningKey", v.OneTimeSigningKey, 32)
+	if err != nil {
+		return err
+	}
+	copy(k[:], b)
+	msg, err := decodeField("message", v.Message, 0)
+	if err != nil {
+		return err
+	}
+
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	err = compareField("pubKey", v.PubKey, pub[:])
+	if err != nil {
+		return err
+	}
+	r := dlcoracle.PublicKeyFromPrivateKey(k)
+	err = compareField("rPoint", v.RPoint, r[:])
+	if err != nil {
+		return err
+	}
+	sig, err := dlcoracle.ComputeSignature(priv, k, msg)
+	if err != nil {
+		return err
+	}
+	err = compareField("signature", v.Signature, sig[:])
+	if err != nil {
+		return err
+	}
+	fromSig := dlcoracle.PublicKeyFromPrivateKey(sig)
+	err = compareField("signaturePubKeyFromSig", v.SignaturePubKeyFromSig, fromSig[:])
+	if err != nil {
+		return err
+	}
+	fromMessage, err := dlcoracle.ComputeSignaturePubKey(pub, r, msg)
+	if err != nil {
+		return err
+	}
+	return compareField("signaturePubKeyFromMessage", v.SignaturePubKeyFromMessage, fromMessage[:])
+}
+
+// jsonArray calls each with the elements of the array under key in the
+// JSON object in b, read from path, and reports its errors with the line
+// the element starts on. It returns the number of elements.
+func jsonArray(path string, b []byte, key string, each func(raw json.RawMessage) error) (int, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	if tok != json.Delim('{') {
+		return 0, fmt.Errorf("%s: expected an object", path)
+	}
+	line, counted := 1, 0
+	lineAt := func(off int) int {
+		// skip to the start of the element
+		for off < len(b) && strings.IndexByte(" \t\r\n,", b[off]) >= 0 {
+			off++
+		}
+		line += bytes.Count(b[counted:off], []byte("\n"))
+		counted = off
+		return line
+	}
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", path, err)
+		}
+		if tok != key {
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+			if err != nil {
+				return 0, fmt.Errorf("%s: %v", path, err)
+			}
+			continue
+		}
+		tok, err = dec.Token()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", path, err)
+		}
+		if tok != json.Delim('[') {
+			return 0, fmt.Errorf("%s: %s: expected an array", path, key)
+		}
+		n := 0
+		for dec.More() {
+			l := lineAt(int(dec.InputOffset()))
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			if err == nil {
+				err = each(raw)
+			}
+			if err != nil {
+				return n, lineError(path, l, err)
+			}
+			n++
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s: no %s array", path, key)
+}
+
+// verifyJSON checks the vectors of a file in the format of vectors.json
+func verifyJSON(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return jsonArray(path, b, "vectors", func(raw json.RawMessage) error {
+		var v vectorJSON
+		err := json.Unmarshal(raw, &v)
+		if err != nil {
+			return err
+		}
+		return checkVector(v)
+	})
+}
+
+// verifyCSV checks the vectors of a file in the format of vectors.csv.
+// Columns are found by the names of the header row, in any order.
+func verifyCSV(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := csv.NewReader(bufio.NewReader(f))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%s: header: %v", path, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	headerLine, _ := r.FieldPos(0)
+	for _, name := range []string{"privKey", "oneTimeSigningKey", "message"} {
+		if _, ok := columns[name]; !ok {
+			return 0, lineError(path, headerLine, fmt.Errorf("no %s column", name))
+		}
+	}
+
+	n := 0
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return n, lineError(path, parseErr.Line, parseErr.Err)
+		}
+		if err != nil {
+			return n, err
+		}
+		line, _ := r.FieldPos(0)
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(row) {
+				return ""
+			}
+			return row[i]
+		}
+		err = checkVector(vectorJSON{
+			PrivKey:                    field("privKey"),
+			PubKey:                     field("pubKey"),
+			OneTimeSigningKey:          field("oneTimeSigningKey"),
+			RPoint:                     field("rPoint"),
+			Message:                    field("message"),
+			Signature:                  field("signature"),
+			SignaturePubKeyFromSig:     field("signaturePubKeyFromSig"),
+			SignaturePubKeyFromMessage: field("signaturePubKeyFromMessage"),
+		})
+		if err != nil {
+			return n, lineError(path, line, err)
+		}
+		n++
+	}
+}
+
+// verifyHex checks the hex files in dir, line by line: privkey.hex,
+// one-time-signing-keys.hex and messages.hex are needed, the other
+// artifacts are compared if they were written
+func verifyHex(dir string) (int, error) {
+	lines := make(map[string][]string)
+	for _, name := range artifacts {
+		b, err := os.ReadFile(filepath.Join(dir, name+".hex"))
+		if errors.Is(err, os.ErrNotExist) && name != "privkey" && name != "messages" {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		lines[name] = strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+	privPath := filepath.Join(dir, "privkey.hex")
+	if len(lines["privkey"]) != 1 {
+		return 0, lineError(privPath, 2, errors.New("expected a single private key"))
+	}
+
+	keys := lines["one-time-signing-keys"]
+	for i := range keys {
+		field := func(name string) (string, error) {
+			l, ok := lines[name]
+			if !ok {
+				return "", nil
+			}
+			if i >= len(l) {
+				return "", lineError(filepath.Join(dir, name+".hex"), i+1, errors.New("missing line"))
+			}
+			return strings.TrimSpace(l[i]), nil
+		}
+		var v vectorJSON
+		var err error
+		v.PrivKey = strings.TrimSpace(lines["privkey"][0])
+		for _, f := range []struct {
+			name string
+			dst  *string
+		}{
+			{"one-time-signing-keys", &v.OneTimeSigningKey},
+			{"messages", &v.Message},
+			{"signatures", &v.Signature},
+			{"signature-pubkeys-from-sig", &v.SignaturePubKeyFromSig},
+			{"signature-pubkeys-from-message", &v.SignaturePubKeyFromMessage},
+		} {
+			*f.dst, err = field(f.name)
+			if err != nil {
+				return i, err
+			}
+		}
+		err = checkVector(v)
+		if err != nil {
+			name, line := hexFileOf(err), i+1
+			if name == "privkey" {
+				line = 1
+			}
+			return i, lineError(filepath.Join(dir, name+".hex"), line, err)
+		}
+	}
+	for name, l := range lines {
+		if name != "privkey" && len(l) > len(keys) {
+			return len(keys), lineError(filepath.Join(dir, name+".hex"), len(keys)+1, errors.New("more lines than one-time-signing-keys.hex"))
+		}
+	}
+	return len(keys), nil
+}
+
+// hexFiles are the hex files holding the fields of a vector
+var hexFiles = map[string]string{
+	"privKey":                    "privkey",
+	"oneTimeSigningKey":          "one-time-signing-keys",
+	"message":                    "messages",
+	"signature":                  "signatures",
+	"signaturePubKeyFromSig":     "signature-pubkeys-from-sig",
+	"signaturePubKeyFromMessage": "signature-pubkeys-from-message",
+}
+
+// hexFileOf names the hex file holding the field a checkVector error is
+// about
+func hexFileOf(err error) string {
+	var fe *fieldError
+	if errors.As(err, &fe) {
+		if name, ok := hexFiles[fe.field]; ok {
+			return name
+		}
+	}
+	return "one-time-signing-keys"
+}
+
+// checkRecord checks a record vector: the announcement's signature and
+// signing hash, the attestation of the outcome message and the signature
+// point
+func checkRecord(v recordVector, pub [33]byte) error {
+	a := v.Announcement
+	if a.OraclePubKey != pub {
+		return fmt.Errorf("announcement: oracle key %x, expected %x", a.OraclePubKey, pub)
+	}
+	err := a.Verify()
+	if err != nil {
+		return fmt.Errorf("announcement: %v", err)
+	}
+	digest := a.SigningHash()
+	err = compareField("signingHash", v.SigningHash, digest[:])
+	if err != nil {
+		return err
+	}
+	msg, err := a.Descriptor.OutcomeMessage(v.Outcome)
+	if err != nil {
+		return fmt.Errorf("outcome: %v", err)
+	}
+	err = compareField("outcomeMessage", v.OutcomeMessage, msg)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(v.Attestation.Message, msg) {
+		return fmt.Errorf("attestation: message %x, expected %x", v.Attestation.Message, msg)
+	}
+	_, err = dlcoracle.VerifyAttestation(a, v.Attestation)
+	if err != nil {
+		return fmt.Errorf("attestation: %v", err)
+	}
+	signed := msg
+	if a.Descriptor.Type == dlcoracle.EventTypeDigits {
+		signed = dlcoracle.DigitMessage(msg[0])
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(pub, a.RPoint, signed)
+	if err != nil {
+		return err
+	}
+	return compareField("signaturePoint", v.SignaturePoint, point[:])
+}
+
+// verifyRecords checks the record vectors of a file in the format of
+// records.json
+func verifyRecords(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var suite struct {
+		PrivKey string `json:"privKey"`
+	}
+	err = json.Unmarshal(b, &suite)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	key, err := decodeField("privKey", suite.PrivKey, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", path, err)
+	}
+	var priv [32]byte
+	copy(priv[:], key)
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+
+	return jsonArray(path, b, "vectors", func(raw json.RawMessage) error {
+		var v recordVector
+		err := json.Unmarshal(raw, &v)
+		if err != nil {
+			return err
+		}
+		return checkRecord(v, pub)
+	})
+}
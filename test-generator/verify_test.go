@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// tamper replaces the first occurrence of old on line n of a file
+func tamper(t *testing.T, path string, n int, old, new string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if !strings.Contains(lines[n-1], old) {
+		t.Fatalf("%s:%d doesn't contain %q: %s", path, n, old, lines[n-1])
+	}
+	lines[n-1] = strings.Replace(lines[n-1], old, new, 1)
+	err = os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// field returns the value of a hex field of a vector row in JSON
+func field(t *testing.T, line, name string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(line, `"`+name+`":"`)
+	if !ok {
+		t.Fatalf("no %s in %s", name, line)
+	}
+	v, _, _ := strings.Cut(rest, `"`)
+	return v
+}
+
+// flip changes the last hex digit of s
+func flip(s string) string {
+	last := "0"
+	if strings.HasSuffix(s, "0") {
+		last = "1"
+	}
+	return s[:len(s)-1] + last
+}
+
+func TestVerifyVectors(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	for _, format := range []string{"hex", "json", "csv"} {
+		err := run([]string{"-n", "5", "-records", "4", "-out", dir, "-format", format, "-seed", "verify", "-quiet"}, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := run([]string{"verify-vectors", "-dir", dir}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"vectors.json: 5 vectors verified", "vectors.csv: 5 vectors verified",
+		"*.hex: 5 vectors verified", "records.json: 4 vectors verified"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("%q not reported:\n%s", want, out.String())
+		}
+	}
+
+	// The first mismatching line of each file is reported
+	b, err := os.ReadFile(filepath.Join(dir, "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header is on line 1, the third vector on line 4
+	sig := field(t, strings.Split(string(b), "\n")[3], "signature")
+	checks := []struct {
+		file string
+		line int
+		old  string
+		want string
+	}{
+		{"vectors.json", 4, sig, "vectors.json:4: signature: " + flip(sig) + ", expected " + sig},
+		// six lines of conventions and the header precede the vectors
+		{"vectors.csv", 10, sig, "vectors.csv:10: signature: "},
+		{"signatures.hex", 3, sig, "signatures.hex:3: signature: "},
+	}
+	for _, c := range checks {
+		path := filepath.Join(dir, c.file)
+		tamper(t, path, c.line, c.old, flip(c.old))
+		err = run([]string{"verify-vectors", "-dir", dir}, &out)
+		if err == nil || !strings.HasPrefix(err.Error(), filepath.Join(dir, c.want)) {
+			t.Fatalf("expected %s, got %v", c.want, err)
+		}
+		tamper(t, path, c.line, flip(c.old), c.old)
+	}
+
+	path := filepath.Join(dir, "records.json")
+	b, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// records are reported on the line their object starts on
+	lines := strings.Split(string(b), "\n")
+	vectorLine, hashLine := 0, 0
+	for i, l := range lines {
+		if strings.Contains(l, `"vectors": [`) {
+			vectorLine = i + 2
+		}
+		if vectorLine != 0 && strings.Contains(l, `"signingHash"`) {
+			hashLine = i + 1
+			break
+		}
+	}
+	hash := field(t, strings.Replace(lines[hashLine-1], `": "`, `":"`, 1), "signingHash")
+	tamper(t, path, hashLine, hash, flip(hash))
+	err = run([]string{"verify-vectors", "-dir", dir}, &out)
+	want := fmt.Sprintf("%s:%d: signingHash: ", path, vectorLine)
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("expected %s, got %v", want, err)
+	}
+
+	err = run([]string{"verify-vectors", "-dir", t.TempDir()}, &out)
+	if err == nil || !strings.Contains(err.Error(), "no vectors") {
+		t.Fatalf("empty directory passed: %v", err)
+	}
+}
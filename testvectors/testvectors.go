@@ -0,0 +1,170 @@
+// Package testvectors embeds a set of golden vectors, so projects wrapping
+// or reimplementing the oracle's signatures can check their code against
+// this library in their own test suites, without running test-generator.
+// The signature vectors exercise the adaptor signatures and anticipation
+// points of single messages, the record vectors whole announced and
+// attested events of every type.
+//
+// The vectors are seeded and regenerated with go generate; test-generator
+// checks they haven't drifted. Only verification is needed to parse them,
+// so the package builds with the verifyonly tag.
+package testvectors
+
+//go:generate go run ../test-generator -n 32 -records 8 -format json -seed "dlc-oracle-go golden vectors" -out . -quiet
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// SignaturesJSON is the embedded vectors.json, in the format test-generator
+// writes with -format json, for tests feeding it to code in other
+// languages, such as the WebAssembly build
+//
+//go:embed vectors.json
+var SignaturesJSON []byte
+
+// RecordsJSON is the embedded records.json, in the format test-generator
+// writes
+//
+//go:embed records.json
+var RecordsJSON []byte
+
+// Signature is a signature vector: a message signed with a one-time
+// signing key, and the signature's point computed both from the
+// signature and, as the anticipation point of the message, from the
+// public keys. The private key is the same for all vectors.
+type Signature struct {
+	PrivKey                    [32]byte
+	PubKey                     [33]byte
+	OneTimeSigningKey          [32]byte
+	RPoint                     [33]byte
+	Message                    []byte
+	Signature                  [32]byte
+	SignaturePubKeyFromSig     [33]byte
+	SignaturePubKeyFromMessage [33]byte
+}
+
+// Record is an announced and attested event. SigningHash is the hash the
+// announcement's signature signs, OutcomeMessage the message attested for
+// Outcome, and SignaturePoint the anticipation point of the attestation's
+// first signature.
+type Record struct {
+	Announcement   dlcoracle.Announcement
+	SigningHash    [32]byte
+	Outcome        dlcoracle.Outcome
+	OutcomeMessage []byte
+	SignaturePoint [33]byte
+	Attestation    dlcoracle.Attestation
+}
+
+type signatureJSON struct {
+	PrivKey                    string `json:"privKey"`
+	PubKey                     string `json:"pubKey"`
+	OneTimeSigningKey          string `json:"oneTimeSigningKey"`
+	RPoint                     string `json:"rPoint"`
+	Message                    string `json:"message"`
+	Signature                  string `json:"signature"`
+	SignaturePubKeyFromSig     string `json:"signaturePubKeyFromSig"`
+	SignaturePubKeyFromMessage string `json:"signaturePubKeyFromMessage"`
+}
+
+type recordJSON struct {
+	Announcement   dlcoracle.Announcement `json:"announcement"`
+	SigningHash    string                 `json:"signingHash"`
+	Outcome        dlcoracle.Outcome      `json:"outcome"`
+	OutcomeMessage string                 `json:"outcomeMessage"`
+	SignaturePoint string                 `json:"signaturePoint"`
+	Attestation    dlcoracle.Attestation  `json:"attestation"`
+}
+
+// decoder decodes hex fields, keeping the first error
+type decoder struct {
+	err error
+}
+
+func (d *decoder) bytes(name, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil && d.err == nil {
+		d.err = fmt.Errorf("%s: %v", name, err)
+	}
+	return b
+}
+
+func (d *decoder) fixed(dst []byte, name, s string) {
+	b := d.bytes(name, s)
+	if d.err == nil && len(b) != len(dst) {
+		d.err = fmt.Errorf("%s: %d bytes, expected %d", name, len(b), len(dst))
+	}
+	copy(dst, b)
+}
+
+// Conventions describes how the signature vectors are computed and
+// encoded
+func Conventions() map[string]string {
+	var suite struct {
+		Conventions map[string]string `json:"conventions"`
+	}
+	must(json.Unmarshal(SignaturesJSON, &suite))
+	return suite.Conventions
+}
+
+// Signatures returns the signature vectors
+func Signatures() []Signature {
+	var suite struct {
+		Vectors []signatureJSON `json:"vectors"`
+	}
+	must(json.Unmarshal(SignaturesJSON, &suite))
+	list := make([]Signature, len(suite.Vectors))
+	for i, j := range suite.Vectors {
+		var d decoder
+		v := &list[i]
+		d.fixed(v.PrivKey[:], "privKey", j.PrivKey)
+		d.fixed(v.PubKey[:], "pubKey", j.PubKey)
+		d.fixed(v.OneTimeSigningKey[:], "oneTimeSigningKey", j.OneTimeSigningKey)
+		d.fixed(v.RPoint[:], "rPoint", j.RPoint)
+		v.Message = d.bytes("message", j.Message)
+		d.fixed(v.Signature[:], "signature", j.Signature)
+		d.fixed(v.SignaturePubKeyFromSig[:], "signaturePubKeyFromSig", j.SignaturePubKeyFromSig)
+		d.fixed(v.SignaturePubKeyFromMessage[:], "signaturePubKeyFromMessage", j.SignaturePubKeyFromMessage)
+		must(d.err)
+	}
+	return list
+}
+
+// Records returns the record vectors, cycling through numeric, enum,
+// bytes and digits events, and the private key that signed them
+func Records() ([]Record, [32]byte) {
+	var suite struct {
+		PrivKey string       `json:"privKey"`
+		Vectors []recordJSON `json:"vectors"`
+	}
+	must(json.Unmarshal(RecordsJSON, &suite))
+	var d decoder
+	var priv [32]byte
+	d.fixed(priv[:], "privKey", suite.PrivKey)
+	list := make([]Record, len(suite.Vectors))
+	for i, j := range suite.Vectors {
+		v := &list[i]
+		v.Announcement = j.Announcement
+		v.Outcome = j.Outcome
+		v.Attestation = j.Attestation
+		d.fixed(v.SigningHash[:], "signingHash", j.SigningHash)
+		v.OutcomeMessage = d.bytes("outcomeMessage", j.OutcomeMessage)
+		d.fixed(v.SignaturePoint[:], "signaturePoint", j.SignaturePoint)
+	}
+	must(d.err)
+	return list, priv
+}
+
+// must panics on errors in the embedded vectors, which the package's
+// tests rule out
+func must(err error) {
+	if err != nil {
+		panic("testvectors: " + err.Error())
+	}
+}
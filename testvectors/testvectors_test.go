@@ -0,0 +1,70 @@
+package testvectors
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestSignatures(t *testing.T) {
+	list := Signatures()
+	if len(list) != 32 {
+		t.Fatalf("%d signature vectors", len(list))
+	}
+	if Conventions()["hash"] == "" {
+		t.Fatal("conventions missing")
+	}
+	for i, v := range list {
+		if dlcoracle.PublicKeyFromPrivateKey(v.PrivKey) != v.PubKey ||
+			dlcoracle.PublicKeyFromPrivateKey(v.OneTimeSigningKey) != v.RPoint {
+			t.Fatalf("vector %d: keys don't match", i)
+		}
+		err := dlcoracle.VerifySignature(v.PubKey, v.RPoint, v.Message, v.Signature)
+		if err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		point, err := dlcoracle.ComputeSignaturePubKey(v.PubKey, v.RPoint, v.Message)
+		if err != nil || point != v.SignaturePubKeyFromMessage ||
+			dlcoracle.PublicKeyFromPrivateKey(v.Signature) != v.SignaturePubKeyFromSig {
+			t.Fatalf("vector %d: signature points don't match: %v", i, err)
+		}
+	}
+}
+
+func TestRecords(t *testing.T) {
+	list, priv := Records()
+	if len(list) != 8 {
+		t.Fatalf("%d record vectors", len(list))
+	}
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	types := make(map[dlcoracle.EventType]bool)
+	for i, v := range list {
+		a := v.Announcement
+		types[a.Descriptor.Type] = true
+		if a.OraclePubKey != pub {
+			t.Fatalf("record %d: signed by another key", i)
+		}
+		err := a.Verify()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if a.SigningHash() != v.SigningHash {
+			t.Fatalf("record %d: signing hash doesn't match", i)
+		}
+		msg, err := a.Descriptor.OutcomeMessage(v.Outcome)
+		if err != nil || !bytes.Equal(msg, v.OutcomeMessage) || !bytes.Equal(msg, v.Attestation.Message) {
+			t.Fatalf("record %d: outcome message doesn't match: %v", i, err)
+		}
+		_, err = dlcoracle.VerifyAttestation(a, v.Attestation)
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if dlcoracle.PublicKeyFromPrivateKey(v.Attestation.Signature) != v.SignaturePoint {
+			t.Fatalf("record %d: signature point doesn't match", i)
+		}
+	}
+	if len(types) != 4 {
+		t.Fatalf("expected all event types, got %v", types)
+	}
+}
@@ -0,0 +1,62 @@
+package tor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// DefaultSOCKSAddr is the default SOCKS port of a Tor daemon
+const DefaultSOCKSAddr = "127.0.0.1:9050"
+
+// Dialer connects through the SOCKS port of a Tor daemon. Host names,
+// including onion addresses, are resolved by Tor rather than locally.
+//
+// Each Dialer authenticates with its own random credentials, which Tor
+// uses to isolate streams: connections of different Dialers never share
+// a circuit, so exit relays can't link the oracles they query. Use one
+// Dialer per oracle.
+type Dialer struct {
+	socks proxy.ContextDialer
+}
+
+// NewDialer returns a dialer using the SOCKS port at socksAddr, such as
+// DefaultSOCKSAddr
+func NewDialer(socksAddr string) (*Dialer, error) {
+	var b [16]byte
+	_, err := rand.Read(b[:])
+	if err != nil {
+		return nil, err
+	}
+	auth := &proxy.Auth{User: hex.EncodeToString(b[:8]), Password: hex.EncodeToString(b[8:])}
+	d, err := proxy.SOCKS5("tcp", socksAddr, auth, &net.Dialer{})
+	if err != nil {
+		return nil, err
+	}
+	return &Dialer{socks: d.(proxy.ContextDialer)}, nil
+}
+
+// DialContext connects to addr through Tor. For gRPC clients, wrap it in
+// a function dialing "tcp" for grpc.WithContextDialer.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.socks.DialContext(ctx, network, addr)
+}
+
+// HTTPClient returns an HTTP client making its requests through Tor, for
+// client.HTTP.SetHTTPClient. Unlike the default client it ignores proxy
+// settings in the environment. Onion services don't need TLS, as their
+// addresses authenticate them.
+func (d *Dialer) HTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: time.Minute,
+		Transport: &http.Transport{
+			DialContext:         d.DialContext,
+			TLSHandshakeTimeout: 30 * time.Second,
+		},
+	}
+}
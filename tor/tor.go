@@ -0,0 +1,181 @@
+// Package tor publishes an oracle as a Tor onion service and dials
+// remote oracles through Tor, so that neither the oracle's host nor the
+// oracles a user queries are revealed to the network.
+//
+// A Controller talks to a running Tor daemon over its control port and
+// asks it to forward an onion service to local listeners. A Dialer
+// connects through Tor's SOCKS port and can be used by HTTP and gRPC
+// clients.
+package tor
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// NewKey asks AddOnion for a new ED25519-V3 service key
+const NewKey = "NEW:ED25519-V3"
+
+// ErrNoAuthMethod is returned by Authenticate if Tor offers no method the
+// controller supports with the given credentials
+var ErrNoAuthMethod = errors.New("tor: no supported authentication method")
+
+// Port forwards a port of an onion service to a local address
+type Port struct {
+	// Virtual is the port clients connect to on the onion address
+	Virtual int
+
+	// Target is the address Tor forwards the connections to, such as
+	// 127.0.0.1:8080
+	Target string
+}
+
+// Onion is an onion service added by a Controller
+type Onion struct {
+	// ServiceID is the onion address without the .onion suffix
+	ServiceID string
+
+	// PrivateKey is the service key, such as "ED25519-V3:...", if Tor
+	// generated it. Passing it to AddOnion later publishes the service at
+	// the same address again.
+	PrivateKey string
+}
+
+// Address returns the onion address of the service
+func (o Onion) Address() string {
+	return o.ServiceID + ".onion"
+}
+
+// Controller is a connection to the control port of a Tor daemon. Onion
+// services it adds are removed when it is closed.
+type Controller struct {
+	conn *textproto.Conn
+}
+
+// DialController connects to the control port at addr, such as
+// 127.0.0.1:9051. The connection has to be authenticated before use.
+func DialController(ctx context.Context, addr string) (*Controller, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("tor: %v", err)
+	}
+	return &Controller{conn: textproto.NewConn(conn)}, nil
+}
+
+// Close closes the connection, which removes its onion services
+func (c *Controller) Close() error {
+	return c.conn.Close()
+}
+
+// command sends a command and returns the lines of its reply, failing
+// unless Tor answers 250
+func (c *Controller) command(format string, args ...interface{}) ([]string, error) {
+	_, err := c.conn.Cmd(format, args...)
+	if err != nil {
+		return nil, fmt.Errorf("tor: %v", err)
+	}
+	_, msg, err := c.conn.ReadResponse(250)
+	if err != nil {
+		return nil, fmt.Errorf("tor: %v", err)
+	}
+	return strings.Split(msg, "\n"), nil
+}
+
+// Authenticate authenticates the connection with password if it isn't
+// empty, and otherwise without credentials or with the cookie file Tor
+// names, depending on what it accepts
+func (c *Controller) Authenticate(password string) error {
+	lines, err := c.command("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+	methods, cookieFile := parseAuth(lines)
+
+	switch {
+	case password != "" && methods["HASHEDPASSWORD"]:
+		_, err = c.command("AUTHENTICATE %s", strconv.Quote(password))
+	case password == "" && methods["NULL"]:
+		_, err = c.command("AUTHENTICATE")
+	case password == "" && methods["COOKIE"] && cookieFile != "":
+		var cookie []byte
+		cookie, err = os.ReadFile(cookieFile)
+		if err != nil {
+			return fmt.Errorf("tor: %v", err)
+		}
+		_, err = c.command("AUTHENTICATE %s", hex.EncodeToString(cookie))
+	default:
+		return ErrNoAuthMethod
+	}
+	return err
+}
+
+// parseAuth returns the authentication methods and cookie file from a
+// PROTOCOLINFO reply
+func parseAuth(lines []string) (map[string]bool, string) {
+	methods := make(map[string]bool)
+	cookieFile := ""
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+		fields := strings.TrimPrefix(line, "AUTH ")
+		if i := strings.Index(fields, "COOKIEFILE="); i >= 0 {
+			f, err := strconv.Unquote(fields[i+len("COOKIEFILE="):])
+			if err == nil {
+				cookieFile = f
+			}
+			fields = fields[:i]
+		}
+		for _, f := range strings.Fields(fields) {
+			if m, ok := strings.CutPrefix(f, "METHODS="); ok {
+				for _, name := range strings.Split(m, ",") {
+					methods[name] = true
+				}
+			}
+		}
+	}
+	return methods, cookieFile
+}
+
+// AddOnion publishes an onion service forwarding ports, with the service
+// key key, or NewKey to have Tor generate one. The service lasts until the
+// controller is closed.
+func (c *Controller) AddOnion(key string, ports ...Port) (Onion, error) {
+	if len(ports) == 0 {
+		return Onion{}, fmt.Errorf("tor: onion service without ports")
+	}
+	cmd := "ADD_ONION " + key
+	for _, p := range ports {
+		cmd += fmt.Sprintf(" Port=%d,%s", p.Virtual, p.Target)
+	}
+	lines, err := c.command("%s", cmd)
+	if err != nil {
+		return Onion{}, err
+	}
+	var o Onion
+	for _, line := range lines {
+		if id, ok := strings.CutPrefix(line, "ServiceID="); ok {
+			o.ServiceID = id
+		} else if k, ok := strings.CutPrefix(line, "PrivateKey="); ok {
+			o.PrivateKey = k
+		}
+	}
+	if o.ServiceID == "" {
+		return Onion{}, fmt.Errorf("tor: no service ID in reply")
+	}
+	return o, nil
+}
+
+// DelOnion removes the onion service with the given ID
+func (c *Controller) DelOnion(serviceID string) error {
+	_, err := c.command("DEL_ONION %s", serviceID)
+	return err
+}
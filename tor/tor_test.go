@@ -0,0 +1,251 @@
+package tor
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeTor answers control port commands the way Tor does, recording them
+type fakeTor struct {
+	ln       net.Listener
+	methods  string
+	cookie   string
+	commands chan string
+}
+
+func newFakeTor(t *testing.T, methods, cookieFile string) *fakeTor {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeTor{ln: ln, methods: methods, cookie: cookieFile, commands: make(chan string, 10)}
+	go f.serve()
+	return f
+}
+
+func (f *fakeTor) serve() {
+	conn, err := f.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			close(f.commands)
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		f.commands <- cmd
+		switch {
+		case cmd == "PROTOCOLINFO 1":
+			auth := "250-AUTH METHODS=" + f.methods
+			if f.cookie != "" {
+				auth += fmt.Sprintf(" COOKIEFILE=%q", f.cookie)
+			}
+			fmt.Fprintf(conn, "250-PROTOCOLINFO 1\r\n%s\r\n250-VERSION Tor=\"0.4.8.9\"\r\n250 OK\r\n", auth)
+		case cmd == "AUTHENTICATE", cmd == "AUTHENTICATE \"secret\"", cmd == "AUTHENTICATE 636f6f6b6965":
+			fmt.Fprintf(conn, "250 OK\r\n")
+		case strings.HasPrefix(cmd, "AUTHENTICATE"):
+			fmt.Fprintf(conn, "515 Authentication failed\r\n")
+		case strings.HasPrefix(cmd, "ADD_ONION NEW:ED25519-V3"):
+			fmt.Fprintf(conn, "250-ServiceID=exampleonion\r\n250-PrivateKey=ED25519-V3:a2V5\r\n250 OK\r\n")
+		case strings.HasPrefix(cmd, "ADD_ONION "):
+			fmt.Fprintf(conn, "250-ServiceID=exampleonion\r\n250 OK\r\n")
+		default:
+			fmt.Fprintf(conn, "510 Unrecognized command\r\n")
+		}
+	}
+}
+
+func TestController(t *testing.T) {
+	f := newFakeTor(t, "HASHEDPASSWORD", "")
+	c, err := DialController(context.Background(), f.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	err = c.Authenticate("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := c.AddOnion(NewKey, Port{Virtual: 80, Target: "127.0.0.1:8080"}, Port{Virtual: 9090, Target: "127.0.0.1:9090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Address() != "exampleonion.onion" || o.PrivateKey != "ED25519-V3:a2V5" {
+		t.Fatalf("unexpected onion %+v", o)
+	}
+	o, err = c.AddOnion(o.PrivateKey, Port{Virtual: 80, Target: "127.0.0.1:8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ServiceID != "exampleonion" || o.PrivateKey != "" {
+		t.Fatalf("unexpected onion %+v", o)
+	}
+	_, err = c.AddOnion(NewKey)
+	if err == nil {
+		t.Fatal("onion service without ports accepted")
+	}
+	err = c.DelOnion("exampleonion")
+	if err == nil || !strings.Contains(err.Error(), "510") {
+		t.Fatalf("expected Tor's error, got %v", err)
+	}
+
+	c.Close()
+	var cmds []string
+	for cmd := range f.commands {
+		cmds = append(cmds, cmd)
+	}
+	expected := []string{
+		"PROTOCOLINFO 1",
+		`AUTHENTICATE "secret"`,
+		"ADD_ONION NEW:ED25519-V3 Port=80,127.0.0.1:8080 Port=9090,127.0.0.1:9090",
+		"ADD_ONION ED25519-V3:a2V5 Port=80,127.0.0.1:8080",
+		"DEL_ONION exampleonion",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("commands %q", cmds)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	cookieFile := filepath.Join(t.TempDir(), "control_auth_cookie")
+	os.WriteFile(cookieFile, []byte("cookie"), 0600)
+
+	for _, c := range []struct {
+		methods, cookieFile, password string
+		ok                            bool
+	}{
+		{"NULL", "", "", true},
+		{"COOKIE,SAFECOOKIE", cookieFile, "", true},
+		{"HASHEDPASSWORD", "", "wrong", false},
+		{"HASHEDPASSWORD", "", "", false},
+		{"SAFECOOKIE", cookieFile, "", false},
+	} {
+		f := newFakeTor(t, c.methods, c.cookieFile)
+		ctrl, err := DialController(context.Background(), f.ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ctrl.Authenticate(c.password)
+		ctrl.Close()
+		if (err == nil) != c.ok {
+			t.Errorf("methods %s, password %q: %v", c.methods, c.password, err)
+		}
+	}
+}
+
+// serveSOCKS accepts one SOCKS5 connection with username and password
+// authentication and connects it to target, whatever address the client
+// asked for. It reports the credentials and the requested host.
+func serveSOCKS(t *testing.T, target string) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		read := func(n int) []byte {
+			b := make([]byte, n)
+			io.ReadFull(r, b)
+			return b
+		}
+
+		greeting := read(2)
+		read(int(greeting[1]))
+		conn.Write([]byte{5, 2}) // username and password
+		read(1)
+		user := string(read(int(read(1)[0])))
+		pass := string(read(int(read(1)[0])))
+		conn.Write([]byte{1, 0})
+
+		req := read(4)
+		if req[3] != 3 {
+			got <- "address type " + fmt.Sprint(req[3])
+			return
+		}
+		host := string(read(int(read(1)[0])))
+		port := binary.BigEndian.Uint16(read(2))
+		got <- fmt.Sprintf("%s:%s %s:%d", user, pass, host, port)
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+		conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+		go io.Copy(upstream, r)
+		io.Copy(conn, upstream)
+	}()
+	return ln.Addr().String(), got
+}
+
+func TestDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Host)
+	}))
+	defer srv.Close()
+	socksAddr, got := serveSOCKS(t, srv.Listener.Addr().String())
+
+	d, err := NewDialer(socksAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := d.HTTPClient().Get("http://exampleonion.onion/api/pubkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "exampleonion.onion" {
+		t.Fatalf("body %q", body)
+	}
+
+	// Tor resolves the onion address, and the credentials isolate the
+	// dialer's streams
+	req := <-got
+	creds, addr, _ := strings.Cut(req, " ")
+	if addr != "exampleonion.onion:80" {
+		t.Fatalf("requested %q", req)
+	}
+	if len(creds) != 33 {
+		t.Fatalf("credentials %q", creds)
+	}
+	socksAddr, got = serveSOCKS(t, srv.Listener.Addr().String())
+	d, err = NewDialer(socksAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = d.HTTPClient().Get("http://exampleonion.onion/api/pubkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	other, _, _ := strings.Cut(<-got, " ")
+	if other == creds {
+		t.Fatal("dialers share credentials")
+	}
+}
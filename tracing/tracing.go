@@ -0,0 +1,190 @@
+// Package tracing traces the oracle's request path with OpenTelemetry: the
+// REST and gRPC servers, the scheduler's attempts, data source fetches,
+// signing and storage, so an operator can tell where a slow attestation
+// spent its time. Spans go to the global TracerProvider, which records
+// nothing until the daemon installs one; the servers continue traces
+// their callers propagate in W3C Trace Context headers.
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Name is the instrumentation scope of the oracle's spans
+const Name = "github.com/mit-dci/dlc-oracle-go"
+
+// Start starts a span named name as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(Name).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, recording err as its error if it isn't nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EventID is the attribute of the event a span works on
+func EventID(id string) attribute.KeyValue {
+	return attribute.String("dlc.event_id", id)
+}
+
+// SourceID is the attribute of the data source a span fetches from
+func SourceID(id string) attribute.KeyValue {
+	return attribute.String("dlc.source_id", id)
+}
+
+// statusWriter keeps the status code of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware serves each request in a server span, continuing the trace
+// of the request's headers. Spans are named after the method and the
+// route that served the request, such as "GET /api/events/{id}".
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := otel.Tracer(Name).Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path)))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		h.ServeHTTP(sw, r)
+		// the mux sets the pattern on the request it was passed
+		if r.Pattern != "" {
+			name := r.Pattern
+			if !strings.HasPrefix(name, r.Method+" ") {
+				name = r.Method + " " + name
+			}
+			span.SetName(name)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// metadataCarrier reads propagated trace context from gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	v := metadata.MD(c).Get(key)
+	if len(v) == 0 {
+		return ""
+	}
+	return v[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startRPC starts the server span of a gRPC call to method
+func startRPC(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	return otel.Tracer(Name).Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("rpc.system", "grpc")))
+}
+
+// endRPC ends the span of a gRPC call with its status code
+func endRPC(span trace.Span, err error) {
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(status.Code(err))))
+	End(span, err)
+}
+
+// UnaryServerInterceptor serves each gRPC call in a server span,
+// continuing the trace of the call's metadata
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		ctx, span := startRPC(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endRPC(span, err)
+		return resp, err
+	}
+}
+
+// tracedStream passes the context of a stream's span to its handler
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor serves each gRPC stream in a server span,
+// continuing the trace of the stream's metadata
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		ctx, span := startRPC(ss.Context(), info.FullMethod)
+		err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+		endRPC(span, err)
+		return err
+	}
+}
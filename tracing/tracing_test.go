@@ -0,0 +1,185 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/scheduler"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+	"github.com/mit-dci/dlc-oracle-go/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// traceparent is a caller's span in W3C Trace Context form
+const (
+	traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID    = "00f067aa0ba902b7"
+)
+
+// record installs a TracerProvider recording the spans of the test
+func record(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return sr
+}
+
+// byName indexes the ended spans by name
+func byName(sr *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+	}
+	return spans
+}
+
+func attr(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestAttestationTrace(t *testing.T) {
+	sr := record(t)
+
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	src := datasource.NewManual("manual")
+	src.Set("btc-1", dlcoracle.Outcome{Value: 42})
+	reg := datasource.NewRegistry()
+	reg.Register(src)
+	reg.Route("btc-", "manual")
+	s := scheduler.New(o, reg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+	_, err := s.Schedule(dlcoracle.Event{ID: "btc-1", Maturity: time.Now().Add(20 * time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spans map[string]sdktrace.ReadOnlySpan
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		spans = byName(sr)
+		if _, ok := spans["scheduler.attest"]; ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	root, ok := spans["scheduler.attest"]
+	if !ok {
+		t.Fatal("no scheduler.attest span")
+	}
+	if root.Status().Code == codes.Error {
+		t.Fatalf("attempt failed: %s", root.Status().Description)
+	}
+	if got := attr(root, "dlc.event_id").AsString(); got != "btc-1" {
+		t.Fatalf("event id %q", got)
+	}
+
+	parents := map[string]string{
+		"datasource.fetch":      "scheduler.attest",
+		"oracle.attest":         "scheduler.attest",
+		"oracle.check_maturity": "oracle.attest",
+		"oracle.sign":           "oracle.attest",
+		"oracle.store":          "oracle.attest",
+	}
+	for name, parent := range parents {
+		s, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span", name)
+		}
+		if s.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Fatalf("%s is not a child of %s", name, parent)
+		}
+		if s.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Fatalf("%s is in another trace", name)
+		}
+	}
+	if got := attr(spans["datasource.fetch"], "dlc.source_id").AsString(); got != "manual" {
+		t.Fatalf("source id %q", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	sr := record(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/events/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "handler")
+		span.End()
+		w.WriteHeader(http.StatusNotFound)
+	})
+	req := httptest.NewRequest("GET", "/api/events/btc-1", nil)
+	req.Header.Set("traceparent", traceparent)
+	tracing.Middleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := byName(sr)
+	s, ok := spans["GET /api/events/{id}"]
+	if !ok {
+		t.Fatalf("no span named after the route in %v", spans)
+	}
+	if s.SpanContext().TraceID().String() != traceID || s.Parent().SpanID().String() != parentID {
+		t.Fatal("span doesn't continue the caller's trace")
+	}
+	if got := attr(s, "http.response.status_code").AsInt64(); got != http.StatusNotFound {
+		t.Fatalf("status code %d", got)
+	}
+	if spans["handler"].Parent().SpanID() != s.SpanContext().SpanID() {
+		t.Fatal("handler span is not a child of the request span")
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	sr := record(t)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+	info := &grpc.UnaryServerInfo{FullMethod: "/rpc.Oracle/Attest"}
+	_, err := tracing.UnaryServerInterceptor()(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(grpccodes.NotFound, "no such event")
+	})
+	if status.Code(err) != grpccodes.NotFound {
+		t.Fatal(err)
+	}
+
+	s, ok := byName(sr)["rpc.Oracle/Attest"]
+	if !ok {
+		t.Fatal("no span named after the method")
+	}
+	if s.SpanContext().TraceID().String() != traceID || s.Parent().SpanID().String() != parentID {
+		t.Fatal("span doesn't continue the caller's trace")
+	}
+	if s.Status().Code != codes.Error {
+		t.Fatal("error not recorded")
+	}
+	if got := attr(s, "rpc.grpc.status_code").AsInt64(); got != int64(grpccodes.NotFound) {
+		t.Fatalf("status code %d", got)
+	}
+}
@@ -0,0 +1,122 @@
+// Package lnd is a transport.Transport delivering announcements and
+// attestations to DLC peers as Lightning custom peer messages, sent
+// through the REST API of an LND node the oracle controls. It serves as an
+// example for other node implementations; a Core Lightning plugin would
+// call sendcustommsg with the same message types and payloads.
+package lnd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// Custom message types, in the odd range above 32768 that peers not
+// understanding them ignore
+const (
+	MessageTypeAnnouncement = 55001
+	MessageTypeAttestation  = 55003
+)
+
+// Transport sends every announcement and attestation to a list of peers
+// connected to the LND node
+type Transport struct {
+	restURL  string
+	macaroon string
+	peers    [][33]byte
+	client   *http.Client
+}
+
+// New returns a transport talking to the LND REST API at restURL (for
+// instance https://localhost:8080), authenticated with a macaroon allowing
+// offchain writes, and sending to peers. The payload of every message is
+// the JSON encoding used by the REST API.
+func New(restURL string, macaroon []byte, peers [][33]byte) *Transport {
+	return &Transport{
+		restURL:  strings.TrimSuffix(restURL, "/"),
+		macaroon: hex.EncodeToString(macaroon),
+		peers:    peers,
+		client:   http.DefaultClient,
+	}
+}
+
+// SetHTTPClient replaces the HTTP client, for instance with one trusting
+// the node's self-signed TLS certificate
+func (t *Transport) SetHTTPClient(c *http.Client) {
+	t.client = c
+}
+
+// Name implements transport.Transport
+func (t *Transport) Name() string {
+	return "lnd"
+}
+
+// PublishAnnouncement implements transport.Transport
+func (t *Transport) PublishAnnouncement(ctx context.Context, a dlcoracle.Announcement) error {
+	return t.send(ctx, MessageTypeAnnouncement, a)
+}
+
+// PublishAttestation implements transport.Transport
+func (t *Transport) PublishAttestation(ctx context.Context, a dlcoracle.Attestation) error {
+	return t.send(ctx, MessageTypeAttestation, a)
+}
+
+type customMessage struct {
+	Peer string `json:"peer"`
+	Type uint32 `json:"type"`
+	Data string `json:"data"`
+}
+
+// send delivers the payload to every peer and returns the errors of the
+// peers it couldn't reach
+func (t *Transport) send(ctx context.Context, msgType uint32, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, peer := range t.peers {
+		err = t.sendToPeer(ctx, customMessage{
+			Peer: base64.StdEncoding.EncodeToString(peer[:]),
+			Type: msgType,
+			Data: base64.StdEncoding.EncodeToString(data),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("peer %x: %w", peer, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Transport) sendToPeer(ctx context.Context, msg customMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		t.restURL+"/v1/custommessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Grpc-Metadata-macaroon", t.macaroon)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("lnd returned %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package lnd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestPublishAttestation(t *testing.T) {
+	var received []customMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/custommessage" || r.Header.Get("Grpc-Metadata-macaroon") != "abcd" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var msg customMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+		peer, _ := base64.StdEncoding.DecodeString(msg.Peer)
+		if len(peer) != 33 || peer[0] == 3 {
+			http.Error(w, `{"message":"peer not connected"}`, http.StatusInternalServerError)
+			return
+		}
+		received = append(received, msg)
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	tr := New(ts.URL+"/", []byte{0xab, 0xcd}, [][33]byte{{2, 1}, {3, 1}})
+	err := tr.PublishAttestation(context.Background(), dlcoracle.Attestation{EventID: "event"})
+	if err == nil || !strings.Contains(err.Error(), "peer not connected") {
+		t.Fatalf("expected error for the unreachable peer, got %v", err)
+	}
+	if len(received) != 1 || received[0].Type != MessageTypeAttestation {
+		t.Fatalf("unexpected messages %+v", received)
+	}
+	data, err := base64.StdEncoding.DecodeString(received[0].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a dlcoracle.Attestation
+	err = json.Unmarshal(data, &a)
+	if err != nil || a.EventID != "event" {
+		t.Fatalf("unexpected payload %s %v", data, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package transport delivers an oracle's announcements and attestations
+// to DLC peers over channels other than the REST API, such as Nostr or
+// Lightning peer messages. Adapters implement Transport; Run forwards the
+// oracle's updates to all of them.
+package transport
+
+import (
+	"context"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+)
+
+// Transport delivers announcements and attestations to peers
+type Transport interface {
+	// Name identifies the transport in logs
+	Name() string
+
+	PublishAnnouncement(ctx context.Context, a dlcoracle.Announcement) error
+	PublishAttestation(ctx context.Context, a dlcoracle.Attestation) error
+}
+
+// Run forwards every announcement and attestation o publishes to all
+// transports until ctx is cancelled. Delivery failures are logged to l and
+// don't stop the other transports. If the subscription falls behind, Run
+// logs the gap and subscribes again; transports that need every record
+// have to catch up from the store themselves.
+func Run(ctx context.Context, o *oracle.Oracle, l dlcoracle.Logger, transports ...Transport) {
+	if l == nil {
+		l = dlcoracle.NopLogger()
+	}
+	for ctx.Err() == nil {
+		updates, cancel := o.Subscribe()
+		forward(ctx, updates, l, transports)
+		cancel()
+		if ctx.Err() == nil {
+			l.Log(dlcoracle.LevelWarn, "transports missed updates, resubscribing")
+		}
+	}
+}
+
+func forward(ctx context.Context, updates <-chan oracle.Update, l dlcoracle.Logger, transports []Transport) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			for _, t := range transports {
+				var err error
+				eventID := ""
+				if u.Announcement != nil {
+					eventID = u.Announcement.EventID
+					err = t.PublishAnnouncement(ctx, *u.Announcement)
+				} else {
+					eventID = u.Attestation.EventID
+					err = t.PublishAttestation(ctx, *u.Attestation)
+				}
+				if err != nil {
+					l.Log(dlcoracle.LevelError, "transport delivery failed",
+						dlcoracle.F("transport", t.Name()),
+						dlcoracle.F("event_id", eventID),
+						dlcoracle.F("error", err.Error()))
+				}
+			}
+		}
+	}
+}
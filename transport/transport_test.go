@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+type recordingTransport struct {
+	mtx  sync.Mutex
+	fail bool
+	got  []string
+}
+
+func (r *recordingTransport) Name() string { return "recording" }
+
+func (r *recordingTransport) record(s string) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.got = append(r.got, s)
+	if r.fail {
+		return errors.New("unreachable")
+	}
+	return nil
+}
+
+func (r *recordingTransport) PublishAnnouncement(ctx context.Context, a dlcoracle.Announcement) error {
+	return r.record("announcement " + a.EventID)
+}
+
+func (r *recordingTransport) PublishAttestation(ctx context.Context, a dlcoracle.Attestation) error {
+	return r.record("attestation " + a.EventID)
+}
+
+func (r *recordingTransport) count() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.got)
+}
+
+func TestRun(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	failing := &recordingTransport{fail: true}
+	working := &recordingTransport{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Run(ctx, o, nil, failing, working)
+	// Give Run time to subscribe
+	time.Sleep(50 * time.Millisecond)
+
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0),
+		Descriptor: dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeBytes}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("event", []byte("yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for working.count() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("updates were not forwarded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if working.got[0] != "announcement event" || working.got[1] != "attestation event" ||
+		failing.count() != 2 {
+		t.Fatalf("unexpected deliveries %v %v", working.got, failing.got)
+	}
+}
@@ -0,0 +1,128 @@
+package dlcoracle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
+	btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+)
+
+// Tags of the pay-to-contract hashes
+const (
+	tweakTag           = "DLC/oracle/tweak"
+	eventCommitmentTag = "DLC/oracle/event"
+)
+
+// TweakHash returns the scalar committing data into a public key or R
+// point: t = H(P, data). The tweaked key is P + tG.
+func TweakHash(pubKey [33]byte, data []byte) [32]byte {
+	return taggedHash(tweakTag, pubKey[:], data)
+}
+
+func tweakScalar(pubKey [33]byte, data []byte) (btcecv2.ModNScalar, error) {
+	var t btcecv2.ModNScalar
+	h := TweakHash(pubKey, data)
+	if t.SetBytes(&h) != 0 {
+		// Like a hash bigger than N this happens about once every 2**128
+		// tweaks
+		return t, &ScalarError{Name: "tweak", Err: ErrScalarOutOfRange}
+	}
+	return t, nil
+}
+
+// TweakPublicKey commits data into a public key or R point, returning
+// P + H(P, data)G. Whoever knows P and data can check the commitment; the
+// matching private key is TweakPrivateKey of P's private key.
+func TweakPublicKey(pubKey [33]byte, data []byte) ([33]byte, error) {
+	var tweaked [33]byte
+	P, err := parsePubKey("public key", pubKey)
+	if err != nil {
+		return tweaked, err
+	}
+	t, err := tweakScalar(pubKey, data)
+	if err != nil {
+		return tweaked, err
+	}
+	var p, tG, sum btcecv2.JacobianPoint
+	P.AsJacobian(&p)
+	btcecv2.ScalarBaseMultNonConst(&t, &tG)
+	btcecv2.AddNonConst(&p, &tG, &sum)
+	if (sum.X.IsZero() && sum.Y.IsZero()) || sum.Z.IsZero() {
+		return tweaked, fmt.Errorf("tweaked key is the point at infinity")
+	}
+	sum.ToAffine()
+	copy(tweaked[:], btcecv2.NewPublicKey(&sum.X, &sum.Y).SerializeCompressed())
+	return tweaked, nil
+}
+
+// VerifyTweak checks that tweaked is pubKey with data committed into it
+func VerifyTweak(pubKey, tweaked [33]byte, data []byte) error {
+	expected, err := TweakPublicKey(pubKey, data)
+	if err != nil {
+		return err
+	}
+	if expected != tweaked {
+		return fmt.Errorf("key does not commit to data")
+	}
+	return nil
+}
+
+// EventCommitment returns a digest of the event, to commit into the R
+// point announced for it: an oracle announcing
+// TweakPublicKey(R, EventCommitment(ev)) and revealing R proves that the
+// nonce was bound to the event before it was announced.
+func EventCommitment(ev Event) [32]byte {
+	var buf [8]byte
+	h := sha256.New()
+	tagHash := sha256.Sum256([]byte(eventCommitmentTag))
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(len(ev.ID)))
+	h.Write(buf[:])
+	h.Write([]byte(ev.ID))
+	binary.BigEndian.PutUint64(buf[:], uint64(ev.Maturity.Unix()))
+	h.Write(buf[:])
+	binary.BigEndian.PutUint32(buf[:4], ev.MaturityHeight)
+	h.Write(buf[:4])
+	writeDescriptor(h, ev.Descriptor)
+
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// writeDescriptor writes the descriptor to a hash with its outcomes
+// length prefixed
+func writeDescriptor(h hash.Hash, d EventDescriptor) {
+	var buf [8]byte
+	h.Write([]byte{byte(d.Type)})
+	binary.BigEndian.PutUint64(buf[:], uint64(len(d.Outcomes)))
+	h.Write(buf[:])
+	for _, o := range d.Outcomes {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(o)))
+		h.Write(buf[:])
+		h.Write([]byte(o))
+	}
+	if d.Type == EventTypeDigits {
+		binary.BigEndian.PutUint32(buf[:4], d.Base)
+		binary.BigEndian.PutUint32(buf[4:], d.Digits)
+		h.Write(buf[:])
+	}
+	// Only written if set, after a byte that can't start what follows
+	// descriptors otherwise, so earlier announcements keep their hashes
+	if d.monetary() {
+		h.Write([]byte{1})
+		binary.BigEndian.PutUint64(buf[:], uint64(len(d.Unit)))
+		h.Write(buf[:])
+		h.Write([]byte(d.Unit))
+		binary.BigEndian.PutUint32(buf[:4], uint32(d.UnitExponent))
+		h.Write(buf[:4])
+		binary.BigEndian.PutUint64(buf[:], d.Precision)
+		h.Write(buf[:])
+	}
+	if d.Rounding != RoundHalfUp {
+		h.Write([]byte{3, byte(d.Rounding)})
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+
+// TweakPrivateKey returns the private key of TweakPublicKey(P, data), where
+// P is the public key of privKey: privKey + H(P, data)
+func TweakPrivateKey(privKey [32]byte, data []byte) ([32]byte, error) {
+	err := checkScalar("priv", privKey)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	var k btcecv2.ModNScalar
+	k.SetBytes(&privKey)
+	defer k.Zero()
+	t, err := tweakScalar(PublicKeyFromPrivateKey(privKey), data)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	k.Add(&t)
+	if k.IsZero() {
+		return [32]byte{}, &ScalarError{Name: "tweaked key", Err: ErrZeroScalar}
+	}
+	return k.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTweak(t *testing.T) {
+	var k [32]byte
+	k[31] = 5
+	R := PublicKeyFromPrivateKey(k)
+	ev := Event{ID: "ev", Maturity: time.Unix(1000, 0), Descriptor: EventDescriptor{Type: EventTypeNumeric}}
+	commitment := EventCommitment(ev)
+
+	tweakedR, err := TweakPublicKey(R, commitment[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	tweakedK, err := TweakPrivateKey(k, commitment[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PublicKeyFromPrivateKey(tweakedK) != tweakedR {
+		t.Fatal("tweaked private key doesn't match the tweaked public key")
+	}
+	err = VerifyTweak(R, tweakedR, commitment[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The commitment covers the whole event
+	ev.Maturity = ev.Maturity.Add(time.Second)
+	other := EventCommitment(ev)
+	err = VerifyTweak(R, tweakedR, other[:])
+	if err == nil {
+		t.Fatal("commitment to another event verified")
+	}
+
+	// Signatures with the tweaked nonce verify against the tweaked R
+	var priv [32]byte
+	priv[31] = 1
+	msg := GenerateNumericMessage(3)
+	sig, err := ComputeSignature(priv, tweakedK, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = VerifySignature(PublicKeyFromPrivateKey(priv), tweakedR, msg, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
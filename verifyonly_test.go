@@ -0,0 +1,56 @@
+//go:build verifyonly
+
+package dlcoracle
+
+import (
+	"errors"
+	"testing"
+)
+
+// Announced and attested by the private key 42 with the one-time signing
+// keys of indexes 0 and 1, in a regular build
+var verifyOnlyVectors = []struct {
+	announcement, attestation string
+	outcome                   Outcome
+}{
+	{
+		`{"eventId":"rain","oraclePubKey":"02fe8d1eb1bcb3432b1db5833ff5f2226d9cb5e65cee430558c18ed3a3c86ce1af","rPoint":"03f3151a37c66e06bb3d320e4b3eceb16a78c96698065c11ee84ad2d3276db4870","maturity":1893456000,"descriptor":{"type":"enum","outcomes":["yes","no"]},"signature":"029bf78f5ea1ba63644d76b025c0bf369f00fdfefc9d106745562608dce3f30dd4639a96a7dc85977f03698c4258cfd973ddbe60c09bdc1ee63923fa82e031cddb"}`,
+		`{"eventId":"rain","message":"6e6f","signature":"c43ed77ecbd64d0e43dd7d65102abc5338864b808d56a45267608eba5e4e5d13"}`,
+		Outcome{Label: "no"},
+	},
+	{
+		`{"eventId":"price","oraclePubKey":"02fe8d1eb1bcb3432b1db5833ff5f2226d9cb5e65cee430558c18ed3a3c86ce1af","rPoint":"0270dc3d693ce42bb075a851da60767d4524d6452e9a5714a8a2cc08c3cb747536","rPoints":["0270dc3d693ce42bb075a851da60767d4524d6452e9a5714a8a2cc08c3cb747536","03507d37dcbb3ce1a9dbd3f0595d7462485e9bf35fa94a9b62df999c7033e662c6","03e43df8699b125236123e8fee87da3fcaaa42845d9e7d8d0c9a792f65bebdde34"],"maturity":1893456000,"descriptor":{"type":"digits","base":10,"digits":3},"signature":"0384267fc031c10226be98cf36ec876cf1c6e34d4cbc0f61369a462c1c7eb2f7d9dd2ab39d9868b8d8aca52bb4a80db6beaa6cc7543115958e96d9b90ce69748fb"}`,
+		`{"eventId":"price","message":"040201","signature":"dded8cd0da1ecac161ba0c745596b9b1708ce397d685230fa887f13d4e9eadc7","signatures":["dded8cd0da1ecac161ba0c745596b9b1708ce397d685230fa887f13d4e9eadc7","387917b52c2b9e3f636a4fd57eb3759a138f7d0bedeafd26c21240a82bef988b","f4954875eec2e8686cc6fcb22124ae4297613e1f129b338e83d6d187bbc31fcb"]}`,
+		Outcome{Value: 421},
+	},
+}
+
+func TestVerifyOnly(t *testing.T) {
+	for _, v := range verifyOnlyVectors {
+		a, err := ParseAnnouncement([]byte(v.announcement))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = a.Verify()
+		if err != nil {
+			t.Fatalf("%s: %v", a.EventID, err)
+		}
+		att, err := ParseAttestation([]byte(v.attestation))
+		if err != nil {
+			t.Fatal(err)
+		}
+		outcome, err := VerifyAttestation(a, att)
+		if err != nil || outcome.Label != v.outcome.Label || outcome.Value != v.outcome.Value {
+			t.Fatalf("%s: attested %+v %v", a.EventID, outcome, err)
+		}
+
+		att.Signature[0] ^= 1
+		if len(att.Signatures) != 0 {
+			att.Signatures[0] = att.Signature
+		}
+		_, err = VerifyAttestation(a, att)
+		if !errors.Is(err, ErrInvalidAttestation) && !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("%s: tampered attestation: %v", a.EventID, err)
+		}
+	}
+}
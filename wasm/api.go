@@ -0,0 +1,207 @@
+// Command wasm exposes the oracle's crypto core to JavaScript when built
+// with GOOS=js GOARCH=wasm, so browser-based DLC tools verify oracle data
+// with the same code as the Go backend. Load the result with dlcoracle.js.
+//
+// All arguments are strings: keys, points, messages and signatures are
+// hex encoded, announcements, attestations and outcomes are JSON in the
+// format of the REST API.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+// function is a function exported to JavaScript
+type function struct {
+	nargs int
+	call  func(args []string) (interface{}, error)
+}
+
+// functions are the exported functions; those taking private keys are
+// added in api_privkey.go, which verifyonly builds leave out
+var functions = map[string]function{
+	"computeSignaturePubKey": {3, computeSignaturePubKey},
+	"verifySignature":        {4, verifySignature},
+	"verifyMessage":          {3, verifyMessage},
+	"numericMessage":         {1, numericMessage},
+	"verifyAnnouncement":     {1, verifyAnnouncement},
+	"anticipationPoint":      {2, anticipationPoint},
+	"verifyAttestation":      {2, verifyAttestation},
+}
+
+// names returns the names of the exported functions, sorted
+func names() []string {
+	list := make([]string, 0, len(functions))
+	for name := range functions {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// invoke calls the exported function name
+func invoke(name string, args []string) (interface{}, error) {
+	f, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if len(args) != f.nargs {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, f.nargs, len(args))
+	}
+	return f.call(args)
+}
+
+// decodeHex decodes a hex argument into dst, which it must fill exactly
+func decodeHex(dst []byte, name, s string) error {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != len(dst) {
+		return fmt.Errorf("%s must be %d hex encoded bytes", name, len(dst))
+	}
+	copy(dst, b)
+	return nil
+}
+
+func decodeMessage(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("message must be hex encoded: %v", err)
+	}
+	return b, nil
+}
+
+// decodeAnnouncement decodes an announcement and checks its signature
+func decodeAnnouncement(s string) (dlcoracle.Announcement, error) {
+	a, err := dlcoracle.ParseAnnouncement([]byte(s))
+	if err != nil {
+		return a, err
+	}
+	return a, a.Verify()
+}
+
+func computeSignaturePubKey(args []string) (interface{}, error) {
+	var pubKey, rPoint [33]byte
+	err := decodeHex(pubKey[:], "public key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	err = decodeHex(rPoint[:], "R point", args[1])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(args[2])
+	if err != nil {
+		return nil, err
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(pubKey, rPoint, msg)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(point[:]), nil
+}
+
+func verifySignature(args []string) (interface{}, error) {
+	var pubKey, rPoint [33]byte
+	var sig [32]byte
+	err := decodeHex(pubKey[:], "public key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	err = decodeHex(rPoint[:], "R point", args[1])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(args[2])
+	if err != nil {
+		return nil, err
+	}
+	err = decodeHex(sig[:], "signature", args[3])
+	if err != nil {
+		return nil, err
+	}
+	return true, dlcoracle.VerifySignature(pubKey, rPoint, msg, sig)
+}
+
+func verifyMessage(args []string) (interface{}, error) {
+	var pubKey [33]byte
+	var sig [65]byte
+	err := decodeHex(pubKey[:], "public key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(args[1])
+	if err != nil {
+		return nil, err
+	}
+	err = decodeHex(sig[:], "signature", args[2])
+	if err != nil {
+		return nil, err
+	}
+	return true, dlcoracle.VerifyMessage(pubKey, msg, sig)
+}
+
+func numericMessage(args []string) (interface{}, error) {
+	v, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", args[0])
+	}
+	return hex.EncodeToString(dlcoracle.GenerateNumericMessage(v)), nil
+}
+
+func verifyAnnouncement(args []string) (interface{}, error) {
+	_, err := decodeAnnouncement(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+// anticipationPoint returns the signature point of an outcome of an
+// announced event, after checking the announcement
+func anticipationPoint(args []string) (interface{}, error) {
+	a, err := decodeAnnouncement(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var outcome dlcoracle.Outcome
+	err = json.Unmarshal([]byte(args[1]), &outcome)
+	if err != nil {
+		return nil, fmt.Errorf("decoding outcome: %v", err)
+	}
+	msg, err := a.Descriptor.OutcomeMessage(outcome)
+	if err != nil {
+		return nil, err
+	}
+	point, err := dlcoracle.ComputeSignaturePubKey(a.OraclePubKey, a.RPoint, msg)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(point[:]), nil
+}
+
+// verifyAttestation checks an attestation against its announcement and
+// returns the attested outcome as JSON
+func verifyAttestation(args []string) (interface{}, error) {
+	a, err := decodeAnnouncement(args[0])
+	if err != nil {
+		return nil, err
+	}
+	att, err := dlcoracle.ParseAttestation([]byte(args[1]))
+	if err != nil {
+		return nil, err
+	}
+	outcome, err := dlcoracle.VerifyAttestation(a, att)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(outcome)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
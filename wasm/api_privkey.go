@@ -0,0 +1,63 @@
+//go:build !verifyonly
+
+package main
+
+import (
+	"encoding/hex"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func init() {
+	functions["publicKey"] = function{1, publicKey}
+	functions["computeSignature"] = function{3, computeSignature}
+	functions["signMessage"] = function{2, signMessage}
+}
+
+func publicKey(args []string) (interface{}, error) {
+	var priv [32]byte
+	err := decodeHex(priv[:], "private key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	return hex.EncodeToString(pub[:]), nil
+}
+
+func computeSignature(args []string) (interface{}, error) {
+	var priv, k [32]byte
+	err := decodeHex(priv[:], "private key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	err = decodeHex(k[:], "one-time signing key", args[1])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(args[2])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := dlcoracle.ComputeSignature(priv, k, msg)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(sig[:]), nil
+}
+
+func signMessage(args []string) (interface{}, error) {
+	var priv [32]byte
+	err := decodeHex(priv[:], "private key", args[0])
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(args[1])
+	if err != nil {
+		return nil, err
+	}
+	sig, err := dlcoracle.SignMessage(priv, msg)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(sig[:]), nil
+}
@@ -0,0 +1,77 @@
+//go:build !verifyonly
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+)
+
+func TestInvoke(t *testing.T) {
+	var priv, k [32]byte
+	priv[31], k[31] = 1, 2
+	pub := dlcoracle.PublicKeyFromPrivateKey(priv)
+	rPoint := dlcoracle.PublicKeyFromPrivateKey(k)
+
+	ann := dlcoracle.Announcement{
+		EventID:      "ev",
+		OraclePubKey: pub,
+		RPoint:       rPoint,
+		Maturity:     time.Unix(1000, 0),
+		Descriptor:   dlcoracle.EventDescriptor{Type: dlcoracle.EventTypeNumeric},
+	}
+	err := ann.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annJSON, err := json.Marshal(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := invoke("numericMessage", []string{"42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := invoke("computeSignature", []string{hex.EncodeToString(priv[:]), hex.EncodeToString(k[:]), msg.(string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := invoke("anticipationPoint", []string{string(annJSON), `{"value":42}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := invoke("computeSignaturePubKey", []string{hex.EncodeToString(pub[:]), hex.EncodeToString(rPoint[:]), msg.(string)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if point != expected {
+		t.Fatalf("anticipation point %v, expected %v", point, expected)
+	}
+
+	att := `{"eventId":"ev","message":"` + msg.(string) + `","signature":"` + sig.(string) + `"}`
+	outcome, err := invoke("verifyAttestation", []string{string(annJSON), att})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome != `{"value":42}` {
+		t.Fatalf("unexpected outcome %v", outcome)
+	}
+
+	_, err = invoke("verifySignature", []string{hex.EncodeToString(pub[:]), hex.EncodeToString(rPoint[:]), "00", sig.(string)})
+	if err == nil {
+		t.Fatal("signature of another message verified")
+	}
+	_, err = invoke("publicKey", nil)
+	if err == nil {
+		t.Fatal("missing argument accepted")
+	}
+	_, err = invoke("publicKey", []string{"zz"})
+	if err == nil {
+		t.Fatal("invalid hex accepted")
+	}
+}
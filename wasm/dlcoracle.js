@@ -0,0 +1,58 @@
+// dlcoracle.js wraps the WebAssembly build of the oracle's crypto core.
+// Load wasm_exec.js from the Go distribution ($(go env GOROOT)/lib/wasm)
+// before calling load, which defines the Go class.
+//
+// Keys, points, messages and signatures are hex strings. Announcements,
+// attestations and outcomes are objects in the format of the REST API.
+// Every function throws an Error when its arguments are invalid or a
+// signature doesn't verify.
+
+// load instantiates dlcoracle.wasm from a URL, or from its bytes (as read
+// with fs.readFile under Node.js), and returns the wrapped functions
+export async function load(source) {
+  const go = new Go();
+  let result;
+  if (typeof source === "string" || source instanceof URL) {
+    result = await WebAssembly.instantiateStreaming(fetch(source), go.importObject);
+  } else {
+    result = await WebAssembly.instantiate(source, go.importObject);
+  }
+  // main registers the functions before it blocks, so they are defined
+  // once run returns control
+  go.run(result.instance);
+  return wrap(globalThis.dlcOracleGo);
+}
+
+function call(f, ...args) {
+  const res = f(...args.map(String));
+  if (res.error !== undefined) {
+    throw new Error(res.error);
+  }
+  return res.result;
+}
+
+function json(v) {
+  return typeof v === "string" ? v : JSON.stringify(v);
+}
+
+function wrap(g) {
+  return {
+    publicKey: (privKey) => call(g.publicKey, privKey),
+    computeSignaturePubKey: (pubKey, rPoint, message) =>
+      call(g.computeSignaturePubKey, pubKey, rPoint, message),
+    computeSignature: (privKey, oneTimeKey, message) =>
+      call(g.computeSignature, privKey, oneTimeKey, message),
+    verifySignature: (pubKey, rPoint, message, signature) =>
+      call(g.verifySignature, pubKey, rPoint, message, signature),
+    signMessage: (privKey, message) => call(g.signMessage, privKey, message),
+    verifyMessage: (pubKey, message, signature) =>
+      call(g.verifyMessage, pubKey, message, signature),
+    numericMessage: (value) => call(g.numericMessage, BigInt(value)),
+    verifyAnnouncement: (announcement) =>
+      call(g.verifyAnnouncement, json(announcement)),
+    anticipationPoint: (announcement, outcome) =>
+      call(g.anticipationPoint, json(announcement), json(outcome)),
+    verifyAttestation: (announcement, attestation) =>
+      JSON.parse(call(g.verifyAttestation, json(announcement), json(attestation))),
+  };
+}
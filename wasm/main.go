@@ -0,0 +1,35 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"syscall/js"
+)
+
+// export wraps an exported function for JavaScript. Go can't throw
+// JavaScript exceptions, so it returns an object with either a result or
+// an error field, which dlcoracle.js turns into a return value or throw.
+func export(name string) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			strs[i] = arg.String()
+		}
+		res, err := invoke(name, strs)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{"result": res}
+	})
+}
+
+func main() {
+	obj := js.Global().Get("Object").New()
+	for _, name := range names() {
+		obj.Set(name, export(name))
+	}
+	js.Global().Set("dlcOracleGo", obj)
+
+	// The functions stay callable as long as main runs
+	select {}
+}
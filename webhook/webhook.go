@@ -0,0 +1,358 @@
+// Package webhook notifies downstream systems of an oracle's activity by
+// POSTing JSON payloads to configured URLs: when it announces an event,
+// when it attests one and when a data source fails to resolve one. It
+// also delivers the alerts of the scheduler as a notify.Notifier.
+//
+// Every request carries the HMAC-SHA256 of its body under the endpoint's
+// secret in the X-Oracle-Signature header, as "sha256=" followed by the
+// hex encoded MAC. Receivers check it with Verify. Payloads contain their
+// time and a unique ID, so receivers can reject replays and ignore the
+// duplicates retries may cause.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/notify"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+)
+
+// Type is the kind of activity a payload reports
+type Type string
+
+// The payload types
+const (
+	TypeAnnouncement  Type = "announcement.created"
+	TypeAttestation   Type = "attestation.published"
+	TypeSourceFailure Type = "source.failed"
+	TypeAlert         Type = "alert"
+)
+
+// SignatureHeader is the header carrying the MAC of the request body
+const SignatureHeader = "X-Oracle-Signature"
+
+const (
+	// DefaultRetryInterval is how long to wait before the second attempt
+	// to deliver a payload. Each further attempt waits twice as long.
+	DefaultRetryInterval = 5 * time.Second
+
+	// DefaultMaxAttempts is how often delivering a payload is tried
+	DefaultMaxAttempts = 5
+
+	// requestTimeout bounds a single delivery attempt
+	requestTimeout = 10 * time.Second
+
+	// queueSize is the number of source failures waiting for delivery
+	queueSize = 64
+)
+
+// ErrInvalidSignature is returned by Verify when the signature doesn't
+// match the body
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Endpoint is a URL payloads are delivered to
+type Endpoint struct {
+	URL    string
+	Secret []byte
+
+	// Types are the payload types delivered to the endpoint, all if
+	// empty
+	Types []Type
+}
+
+func (e Endpoint) wants(t Type) bool {
+	if len(e.Types) == 0 {
+		return true
+	}
+	for _, w := range e.Types {
+		if w == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of a webhook request. Announcement is set for
+// TypeAnnouncement payloads, Attestation for TypeAttestation, Source and
+// Error for TypeSourceFailure and Alert for TypeAlert.
+type Payload struct {
+	ID           string                  `json:"id"`
+	Type         Type                    `json:"type"`
+	Time         time.Time               `json:"time"`
+	EventID      string                  `json:"eventId"`
+	Announcement *dlcoracle.Announcement `json:"announcement,omitempty"`
+	Attestation  *dlcoracle.Attestation  `json:"attestation,omitempty"`
+	Source       string                  `json:"source,omitempty"`
+	Error        string                  `json:"error,omitempty"`
+	Alert        *notify.Alert           `json:"alert,omitempty"`
+}
+
+// Sign returns the value of the signature header for body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header of a webhook request against its
+// body
+func Verify(secret, body []byte, signature string) error {
+	if !hmac.Equal([]byte(Sign(secret, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Notifier delivers payloads to a set of endpoints
+type Notifier struct {
+	endpoints []Endpoint
+	client    *http.Client
+	logger    dlcoracle.Logger
+
+	retryInterval time.Duration
+	maxAttempts   int
+
+	queue chan Payload
+}
+
+// New returns a notifier delivering to endpoints
+func New(endpoints ...Endpoint) *Notifier {
+	return &Notifier{
+		endpoints:     endpoints,
+		client:        http.DefaultClient,
+		logger:        dlcoracle.NopLogger(),
+		retryInterval: DefaultRetryInterval,
+		maxAttempts:   DefaultMaxAttempts,
+		queue:         make(chan Payload, queueSize),
+	}
+}
+
+// SetLogger sets the logger that records failed deliveries
+func (n *Notifier) SetLogger(l dlcoracle.Logger) {
+	n.logger = l
+}
+
+// SetRetry changes how often and how far apart delivering a payload is
+// tried. The interval doubles after every attempt.
+func (n *Notifier) SetRetry(interval time.Duration, maxAttempts int) {
+	n.retryInterval = interval
+	n.maxAttempts = maxAttempts
+}
+
+// SetHTTPClient sets the client requests are made with
+func (n *Notifier) SetHTTPClient(c *http.Client) {
+	n.client = c
+}
+
+// newPayload returns a payload of type t with a fresh ID
+func newPayload(t Type, eventID string) Payload {
+	var id [16]byte
+	rand.Read(id[:])
+	return Payload{
+		ID:      hex.EncodeToString(id[:]),
+		Type:    t,
+		Time:    time.Now().UTC(),
+		EventID: eventID,
+	}
+}
+
+// Send delivers p to every endpoint that wants its type, retrying failed
+// deliveries. It returns the errors of the endpoints that never accepted
+// it.
+func (n *Notifier) Send(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	for _, e := range n.endpoints {
+		if !e.wants(p.Type) {
+			continue
+		}
+		wg.Add(1)
+		go func(e Endpoint) {
+			defer wg.Done()
+			err := n.deliverWithRetry(ctx, e, p, body)
+			if err != nil {
+				mtx.Lock()
+				errs = append(errs, err)
+				mtx.Unlock()
+			}
+		}(e)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) deliverWithRetry(ctx context.Context, e Endpoint, p Payload, body []byte) error {
+	wait := n.retryInterval
+	var err error
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		var retry bool
+		retry, err = n.deliver(ctx, e, p, body)
+		if err == nil {
+			return nil
+		}
+		n.logger.Log(dlcoracle.LevelWarn, "webhook delivery failed",
+			dlcoracle.F("url", e.URL),
+			dlcoracle.F("type", string(p.Type)),
+			dlcoracle.F("event_id", p.EventID),
+			dlcoracle.F("attempt", attempt),
+			dlcoracle.F("error", err.Error()))
+		if !retry || attempt == n.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	return fmt.Errorf("webhook %s: %w", e.URL, err)
+}
+
+// deliver POSTs body to e once, and reports whether a failure is worth
+// retrying: client errors other than 408 and 429 are not
+func (n *Notifier) deliver(ctx context.Context, e Endpoint, p Payload, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Oracle-Event", string(p.Type))
+	req.Header.Set("X-Oracle-Delivery", p.ID)
+	req.Header.Set(SignatureHeader, Sign(e.Secret, body))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("HTTP %s", strings.TrimSpace(resp.Status))
+}
+
+// Notify delivers an alert as a TypeAlert payload. It implements
+// notify.Notifier.
+func (n *Notifier) Notify(ctx context.Context, a notify.Alert) error {
+	p := newPayload(TypeAlert, a.EventID)
+	p.Alert = &a
+	return n.Send(ctx, p)
+}
+
+// Run delivers a payload for every announcement and attestation o
+// publishes, and for every source failure reported by sources wrapped
+// with InstrumentSource, until ctx is cancelled. Deliveries run in the
+// background, so a slow endpoint doesn't hold up the others. If the
+// subscription to o falls behind, Run logs the gap and subscribes again.
+func (n *Notifier) Run(ctx context.Context, o *oracle.Oracle) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	send := func(p Payload) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := n.Send(ctx, p)
+			if err != nil {
+				n.logger.Log(dlcoracle.LevelError, "webhook not delivered",
+					dlcoracle.F("type", string(p.Type)),
+					dlcoracle.F("event_id", p.EventID),
+					dlcoracle.F("error", err.Error()))
+			}
+		}()
+	}
+	for ctx.Err() == nil {
+		updates, cancel := o.Subscribe()
+		n.forward(ctx, updates, send)
+		cancel()
+		if ctx.Err() == nil {
+			n.logger.Log(dlcoracle.LevelWarn, "webhooks missed updates, resubscribing")
+		}
+	}
+}
+
+func (n *Notifier) forward(ctx context.Context, updates <-chan oracle.Update, send func(Payload)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p := <-n.queue:
+			send(p)
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			if u.Announcement != nil {
+				p := newPayload(TypeAnnouncement, u.Announcement.EventID)
+				p.Announcement = u.Announcement
+				send(p)
+			} else {
+				p := newPayload(TypeAttestation, u.Attestation.EventID)
+				p.Attestation = u.Attestation
+				send(p)
+			}
+		}
+	}
+}
+
+// notifySource queues a source failure for Run to deliver. If the queue
+// is full the failure is only logged.
+func (n *Notifier) notifySource(sourceID string, ev dlcoracle.Event, err error) {
+	p := newPayload(TypeSourceFailure, ev.ID)
+	p.Source = sourceID
+	p.Error = err.Error()
+	select {
+	case n.queue <- p:
+	default:
+		n.logger.Log(dlcoracle.LevelWarn, "webhook queue full, dropping source failure",
+			dlcoracle.F("source", sourceID),
+			dlcoracle.F("event_id", ev.ID))
+	}
+}
+
+// notifyingSource reports the failures of a data source. An outcome that
+// isn't available yet is not a failure.
+type notifyingSource struct {
+	datasource.DataSource
+	n *Notifier
+}
+
+// InstrumentSource wraps ds so its failures are delivered as
+// TypeSourceFailure payloads. Register the returned data source instead
+// of ds.
+func (n *Notifier) InstrumentSource(ds datasource.DataSource) datasource.DataSource {
+	return &notifyingSource{DataSource: ds, n: n}
+}
+
+// FetchOutcome implements datasource.DataSource
+func (s *notifyingSource) FetchOutcome(ev dlcoracle.Event) (dlcoracle.Outcome, error) {
+	o, err := s.DataSource.FetchOutcome(ev)
+	if err != nil && !errors.Is(err, datasource.ErrNotAvailable) {
+		s.n.notifySource(s.ID(), ev, err)
+	}
+	return o, err
+}
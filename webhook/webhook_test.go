@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mit-dci/dlc-oracle-go"
+	"github.com/mit-dci/dlc-oracle-go/datasource"
+	"github.com/mit-dci/dlc-oracle-go/notify"
+	"github.com/mit-dci/dlc-oracle-go/oracle"
+	"github.com/mit-dci/dlc-oracle-go/storage"
+)
+
+var secret = []byte("hunter2")
+
+// receiver records the payloads it receives, answering the first fail
+// requests with status
+type receiver struct {
+	mtx      sync.Mutex
+	fail     int
+	status   int
+	requests int
+	payloads []Payload
+	got      chan Payload
+}
+
+func newReceiver(t *testing.T, fail, status int) (*receiver, *httptest.Server) {
+	r := &receiver{fail: fail, status: status, got: make(chan Payload, 16)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		err := Verify(secret, body, req.Header.Get(SignatureHeader))
+		if err != nil {
+			t.Errorf("request signature: %v", err)
+		}
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+		r.requests++
+		if r.requests <= r.fail {
+			w.WriteHeader(r.status)
+			return
+		}
+		var p Payload
+		json.Unmarshal(body, &p)
+		r.payloads = append(r.payloads, p)
+		r.got <- p
+	}))
+	t.Cleanup(srv.Close)
+	return r, srv
+}
+
+func TestSendRetries(t *testing.T) {
+	r, srv := newReceiver(t, 2, http.StatusServiceUnavailable)
+	n := New(Endpoint{URL: srv.URL, Secret: secret})
+	n.SetRetry(time.Millisecond, 3)
+	err := n.Send(context.Background(), newPayload(TypeAnnouncement, "event"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.requests != 3 || len(r.payloads) != 1 || r.payloads[0].EventID != "event" {
+		t.Fatalf("%d requests, payloads %+v", r.requests, r.payloads)
+	}
+
+	// Client errors are not retried
+	r, srv = newReceiver(t, 5, http.StatusBadRequest)
+	n = New(Endpoint{URL: srv.URL, Secret: secret})
+	n.SetRetry(time.Millisecond, 3)
+	err = n.Send(context.Background(), newPayload(TypeAnnouncement, "event"))
+	if err == nil || r.requests != 1 {
+		t.Fatalf("%d requests, error %v", r.requests, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"attestation.published"}`)
+	sig := Sign(secret, body)
+	if Verify(secret, body, sig) != nil {
+		t.Fatal("valid signature rejected")
+	}
+	if !errors.Is(Verify([]byte("other"), body, sig), ErrInvalidSignature) {
+		t.Fatal("signature under another secret accepted")
+	}
+	if !errors.Is(Verify(secret, append(body, ' '), sig), ErrInvalidSignature) {
+		t.Fatal("signature of another body accepted")
+	}
+}
+
+type failingSource struct{}
+
+func (failingSource) ID() string       { return "broken" }
+func (failingSource) Describe() string { return "always fails" }
+func (failingSource) FetchOutcome(dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, errors.New("exchange down")
+}
+
+func TestRun(t *testing.T) {
+	all, allSrv := newReceiver(t, 0, 0)
+	failures, failuresSrv := newReceiver(t, 0, 0)
+	n := New(Endpoint{URL: allSrv.URL, Secret: secret},
+		Endpoint{URL: failuresSrv.URL, Secret: secret, Types: []Type{TypeSourceFailure}})
+
+	var priv [32]byte
+	priv[31] = 1
+	o := oracle.New(priv, storage.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx, o)
+		close(done)
+	}()
+	// Let Run subscribe
+	time.Sleep(20 * time.Millisecond)
+
+	_, err := o.CreateEvent(dlcoracle.Event{ID: "event", Maturity: time.Unix(1000, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = o.Attest("event", dlcoracle.GenerateNumericMessage(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := n.InstrumentSource(failingSource{})
+	_, err = ds.FetchOutcome(dlcoracle.Event{ID: "other"})
+	if err == nil {
+		t.Fatal("wrapped source hid the error")
+	}
+	_, err = n.InstrumentSource(notAvailable{}).FetchOutcome(dlcoracle.Event{ID: "later"})
+	if !errors.Is(err, datasource.ErrNotAvailable) {
+		t.Fatal(err)
+	}
+
+	types := make(map[Type]Payload)
+	for i := 0; i < 3; i++ {
+		select {
+		case p := <-all.got:
+			types[p.Type] = p
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only got %v", types)
+		}
+	}
+	if types[TypeAnnouncement].Announcement == nil || types[TypeAttestation].Attestation == nil ||
+		types[TypeSourceFailure].Source != "broken" || types[TypeSourceFailure].Error != "exchange down" {
+		t.Fatalf("unexpected payloads %+v", types)
+	}
+	select {
+	case p := <-failures.got:
+		if p.Type != TypeSourceFailure || p.EventID != "other" {
+			t.Fatalf("unexpected payload %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("source failure not delivered")
+	}
+	cancel()
+	<-done
+	if len(failures.payloads) != 1 {
+		t.Fatalf("filtered endpoint got %+v", failures.payloads)
+	}
+}
+
+type notAvailable struct{ failingSource }
+
+func (notAvailable) FetchOutcome(dlcoracle.Event) (dlcoracle.Outcome, error) {
+	return dlcoracle.Outcome{}, datasource.ErrNotAvailable
+}
+
+func TestNotify(t *testing.T) {
+	r, srv := newReceiver(t, 0, 0)
+	n := New(Endpoint{URL: srv.URL, Secret: secret, Types: []Type{TypeAlert}})
+	a := notify.Alert{Kind: notify.KindOverdue, EventID: "event", Message: "late"}
+	err := n.Notify(context.Background(), a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.payloads[0]
+	if p.Type != TypeAlert || p.EventID != "event" || p.Alert == nil || *p.Alert != a {
+		t.Fatalf("unexpected payload %+v", p)
+	}
+}
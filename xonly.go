@@ -0,0 +1,69 @@
+package dlcoracle
+
+// X-only public keys and R points are the 32-byte X coordinate of a point,
+// standing for the point with that coordinate and an even Y, as in
+// BIP-340. The hash signed for a message only depends on the X coordinate
+// of R, so signatures convert between both encodings by negating the
+// private scalars of points with an odd Y.
+
+// XOnly returns the x-only encoding of a compressed public key or R
+// point. It stands for the same point only if the key's Y is even, which
+// HasEvenY tells.
+func XOnly(pubKey [33]byte) [32]byte {
+	var x [32]byte
+	copy(x[:], pubKey[1:])
+	return x
+}
+
+// HasEvenY reports whether a compressed public key has an even Y, so
+// XOnly doesn't change the point it stands for
+func HasEvenY(pubKey [33]byte) bool {
+	return pubKey[0] == 0x02
+}
+
+// ParseXOnly returns the compressed encoding of an x-only public key or R
+// point, after checking that it is on the curve
+func ParseXOnly(x [32]byte) ([33]byte, error) {
+	var pubKey [33]byte
+	pubKey[0] = 0x02
+	copy(pubKey[1:], x[:])
+	_, err := parsePoint(pubKey[:])
+	if err != nil {
+		return [33]byte{}, &PubKeyError{Name: "x-only public key", Key: x[:], Err: err}
+	}
+	return pubKey, nil
+}
+
+// XOnlyPublicKeyFromPrivateKey returns the x-only public key of a private
+// key
+func XOnlyPublicKeyFromPrivateKey(privateKey [32]byte) [32]byte {
+	return XOnly(PublicKeyFromPrivateKey(privateKey))
+}
+
+// ComputeSignaturePubKeyXOnly is ComputeSignaturePubKey for an x-only
+// public key and R point
+func ComputeSignaturePubKeyXOnly(oraclePubA, oraclePubR [32]byte, message []byte) ([33]byte, error) {
+	A, err := ParseXOnly(oraclePubA)
+	if err != nil {
+		return [33]byte{}, err
+	}
+	R, err := ParseXOnly(oraclePubR)
+	if err != nil {
+		return [33]byte{}, err
+	}
+	return ComputeSignaturePubKey(A, R, message)
+}
+
+// VerifySignatureXOnly is VerifySignature for an x-only public key and R
+// point
+func VerifySignatureXOnly(oraclePubA, oraclePubR [32]byte, message []byte, sig [32]byte) error {
+	A, err := ParseXOnly(oraclePubA)
+	if err != nil {
+		return err
+	}
+	R, err := ParseXOnly(oraclePubR)
+	if err != nil {
+		return err
+	}
+	return VerifySignature(A, R, message, sig)
+}
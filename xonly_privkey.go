@@ -0,0 +1,27 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import btcecv2 "github.com/btcsuite/btcd/btcec/v2"
+
+// evenY returns the private scalar whose point has the X coordinate of
+// key's point and an even Y. Invalid scalars are returned as is, for
+// ComputeSignature to reject.
+func evenY(key [32]byte) [32]byte {
+	var s btcecv2.ModNScalar
+	if s.SetBytes(&key) != 0 || s.IsZero() {
+		return key
+	}
+	defer s.Zero()
+	if HasEvenY(PublicKeyFromPrivateKey(key)) {
+		return key
+	}
+	return s.Negate().Bytes()
+}
+
+// ComputeSignatureXOnly computes the signature of message that verifies
+// against the x-only public key and R point of the private key and
+// one-time signing key, with VerifySignatureXOnly
+func ComputeSignatureXOnly(privKey, oneTimeSigningKey [32]byte, message []byte) ([32]byte, error) {
+	return ComputeSignature(evenY(privKey), evenY(oneTimeSigningKey), message)
+}
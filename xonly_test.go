@@ -0,0 +1,114 @@
+//go:build !verifyonly
+
+package dlcoracle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestXOnlySignature(t *testing.T) {
+	msg := GenerateNumericMessage(42)
+	// Cover every combination of even and odd Y of the key and R point
+	seen := make(map[[2]bool]bool)
+	for i := byte(1); len(seen) < 4 && i < 100; i++ {
+		var priv, k [32]byte
+		priv[31], k[31] = i, i+100
+		pub, R := PublicKeyFromPrivateKey(priv), PublicKeyFromPrivateKey(k)
+		seen[[2]bool{HasEvenY(pub), HasEvenY(R)}] = true
+
+		sig, err := ComputeSignatureXOnly(priv, k, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = VerifySignatureXOnly(XOnly(pub), XOnly(R), msg, sig)
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+		point, err := ComputeSignaturePubKeyXOnly(XOnly(pub), XOnly(R), msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if PublicKeyFromPrivateKey(sig) != point {
+			t.Fatalf("key %d: signature doesn't match its point", i)
+		}
+		if HasEvenY(pub) && HasEvenY(R) {
+			full, err := ComputeSignature(priv, k, msg)
+			if err != nil || full != sig {
+				t.Fatalf("key %d: x-only signature differs for even keys", i)
+			}
+		}
+	}
+	if len(seen) != 4 {
+		t.Fatal("not all parities covered")
+	}
+}
+
+func TestParseXOnly(t *testing.T) {
+	var priv [32]byte
+	priv[31] = 3
+	x := XOnlyPublicKeyFromPrivateKey(priv)
+	pub, err := ParseXOnly(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if XOnly(pub) != x || !HasEvenY(pub) {
+		t.Fatalf("parsed %x from %x", pub, x)
+	}
+
+	// x = 5 isn't the X coordinate of a point on the curve
+	var invalid [32]byte
+	invalid[31] = 5
+	_, err = ParseXOnly(invalid)
+	if err == nil {
+		t.Fatal("point not on the curve accepted")
+	}
+}
+
+func TestXOnlyAnnouncementJSON(t *testing.T) {
+	// Find a key and nonce with even Y
+	var priv, k [32]byte
+	for i := byte(1); ; i++ {
+		priv[31] = i
+		if HasEvenY(PublicKeyFromPrivateKey(priv)) {
+			break
+		}
+	}
+	for i := byte(1); ; i++ {
+		k[31] = i
+		if HasEvenY(PublicKeyFromPrivateKey(k)) {
+			break
+		}
+	}
+	a := Announcement{
+		EventID:      "ev",
+		OraclePubKey: PublicKeyFromPrivateKey(priv),
+		RPoint:       PublicKeyFromPrivateKey(k),
+		Maturity:     time.Unix(1000, 0).UTC(),
+		Descriptor:   EventDescriptor{Type: EventTypeNumeric},
+	}
+	err := a.Sign(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := a.MarshalXOnlyJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Announcement
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = decoded.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.RPoint[0] = 0x03
+	_, err = a.MarshalXOnlyJSON()
+	if err == nil {
+		t.Fatal("odd R point encoded as x-only")
+	}
+}